@@ -3,11 +3,16 @@ package main
 import (
 	"fmt"
 	"os"
+	// Time zone names in settings.timezone resolve without system zoneinfo
+	_ "time/tzdata"
 
 	"feedpulse/internal/cli"
+	"feedpulse/internal/sentry"
 )
 
 func main() {
+	defer sentry.Recover()
+
 	rootCmd := cli.NewRootCmd()
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
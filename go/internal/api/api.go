@@ -0,0 +1,622 @@
+// Package api serves feedpulse's HTTP API in serve mode. Every request
+// but health checks needs a bearer token: read tokens may list items,
+// stats and fetch history and read the combined RSS/Atom feed, admin
+// tokens may also add feeds, trigger fetches, prune the journal and change
+// items in bulk. Admin actions are recorded in the audit log under the token's ID.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"feedpulse/internal/clock"
+	"feedpulse/internal/config"
+	"feedpulse/internal/sentry"
+	"feedpulse/internal/storage"
+	"feedpulse/internal/syndication"
+)
+
+// Prefix is the URL path the API is served under
+const Prefix = "/api/"
+
+// maxItems bounds how many items one request returns
+const maxItems = 1000
+
+// feedItems is how many items the combined feed has by default
+const feedItems = 50
+
+// failingAfter is how many fetches in a row a feed must fail for health
+// checks to report feedpulse degraded
+const failingAfter = 3
+
+// Server handles API requests against a store
+type Server struct {
+	store   storage.Store
+	feeds   map[string]bool
+	fetches chan string
+	mux     *http.ServeMux
+	cache   *responseCache
+	version string
+	clock   clock.Clock
+	// configPath is the config file feeds are added to, "" if none
+	configPath string
+	// endpoints are the routes served, as the OpenAPI description lists them
+	endpoints []endpoint
+}
+
+// NewServer creates an API server for store. feeds are the names fetches
+// may be requested for.
+func NewServer(store storage.Store, feeds []string) *Server {
+	s := &Server{
+		store:   store,
+		feeds:   make(map[string]bool, len(feeds)),
+		fetches: make(chan string, 64),
+		mux:     http.NewServeMux(),
+		clock:   clock.System,
+	}
+	for _, name := range feeds {
+		s.feeds[name] = true
+	}
+
+	s.endpoints = s.routes()
+	for _, e := range s.endpoints {
+		var h http.Handler = e.handler
+		if e.scope != "" {
+			h = s.authorize(e.scope, e.tokenInQuery, e.handler)
+		}
+		s.mux.Handle(e.method+" "+e.path, h)
+	}
+	s.mux.HandleFunc("GET "+SpecPath, s.handleSpec)
+	s.mux.HandleFunc("GET "+DocsPath, s.handleDocs)
+	s.mux.Handle("GET "+DocsPath+"/", docsAssets)
+	return s
+}
+
+// SetClock sets the clock "since" windows and feed dates are taken from
+func (s *Server) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
+// SetConfigPath sets the config file feeds added through the API are
+// written to; without one, adding feeds is refused
+func (s *Server) SetConfigPath(path string) {
+	s.configPath = path
+}
+
+// Fetches delivers the names of feeds an admin asked to fetch. The
+// server doesn't fetch itself, so fetches are recorded one at a time
+// with everything else serve records.
+func (s *Server) Fetches() <-chan string {
+	return s.fetches
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer sentry.Recover()
+	s.mux.ServeHTTP(w, r)
+}
+
+// tokenKey is the request context key of the authenticated token
+type tokenKey struct{}
+
+// actor identifies the token that made r in the audit log
+func actor(r *http.Request) string {
+	if t, ok := r.Context().Value(tokenKey{}).(*storage.APIToken); ok {
+		return "token:" + t.ID
+	}
+	return "api"
+}
+
+// audit logs a mutating request. The action already happened, so a
+// failure to log it doesn't fail the request.
+func (s *Server) audit(r *http.Request, action string, params map[string]string) {
+	if err := s.store.RecordAudit(actor(r), action, params); err != nil {
+		log.Printf("warning: %v", err)
+	}
+}
+
+// authorize wraps h so it only runs for requests bearing a token allowed
+// scope: missing or unknown tokens get 401, tokens lacking scope 403. If
+// inQuery, the token may also be given as the access_token parameter.
+func (s *Server) authorize(scope string, inQuery bool, h http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			token = ""
+			if inQuery {
+				token = r.URL.Query().Get("access_token")
+			}
+		}
+		if token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="feedpulse"`)
+			writeError(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+
+		t, err := s.store.AuthenticateAPIToken(strings.TrimSpace(token))
+		if err != nil {
+			internalError(w, r, err)
+			return
+		}
+		if t == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="feedpulse", error="invalid_token"`)
+			writeError(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+		if !t.Allows(scope) {
+			writeError(w, http.StatusForbidden, fmt.Sprintf("token %s has %s scope; this needs %s", t.ID, t.Scope, scope))
+			return
+		}
+		h(w, r.WithContext(context.WithValue(r.Context(), tokenKey{}, t)))
+	})
+}
+
+// statsJSON is a source's fetch stats in API responses
+type statsJSON struct {
+	Source       string  `json:"source"`
+	Items        int     `json:"items"`
+	Errors       int     `json:"errors"`
+	TotalFetches int     `json:"total_fetches"`
+	LastSuccess  *string `json:"last_success"`
+}
+
+// handleStats serves GET /api/stats
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.store.GetFetchStats()
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	out := make([]statsJSON, 0, len(stats))
+	for _, st := range stats {
+		out = append(out, statsJSON{
+			Source:       st.Source,
+			Items:        st.ItemsCount,
+			Errors:       st.ErrorCount,
+			TotalFetches: st.TotalFetches,
+			LastSuccess:  st.LastSuccess,
+		})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// handleItems serves GET /api/items?since=24h&source=X&limit=N, and
+// GET /api/items/{source} with the same parameters. Pages continue with
+// ?cursor= from the previous response; sort (newest or oldest) and
+// fields narrow what is returned.
+func (s *Server) handleItems(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	source, ok := s.source(w, r)
+	if !ok {
+		return
+	}
+	since := q.Get("since")
+	if since == "" {
+		since = "24h"
+	}
+	window, err := config.ParseDuration(since)
+	if err != nil || window <= 0 {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid since: %s", since))
+		return
+	}
+	filter := storage.ItemFilter{Source: source, Since: s.clock.Now().Add(-window)}
+	fields, ok := pageParams(w, q, itemFields, &filter.Limit, &filter.Sort)
+	if !ok {
+		return
+	}
+	if v := q.Get("cursor"); v != "" {
+		at, id, err := decodeCursor(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		filter.After = &storage.ItemCursor{CreatedAt: at, ID: id}
+	}
+
+	// One more item than asked for tells whether there is another page
+	limit := filter.Limit
+	filter.Limit++
+	items, err := s.store.GetItems(filter)
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	var next string
+	if len(items) > limit {
+		items = items[:limit]
+		last := items[limit-1]
+		next = encodeCursor(last.CreatedAt, last.ID)
+	}
+	out := make([]storage.FeedItem, 0, len(items))
+	for _, item := range items {
+		item.RawData = nil
+		out = append(out, item)
+	}
+	writePage(w, r, out, fields, next)
+}
+
+// historyJSON is a fetch log entry in API responses
+type historyJSON struct {
+	ID         int       `json:"id"`
+	Source     string    `json:"source"`
+	FetchedAt  time.Time `json:"fetched_at"`
+	Status     string    `json:"status"`
+	Items      int       `json:"items"`
+	Error      *string   `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	Endpoint   string    `json:"endpoint,omitempty"`
+	Slow       bool      `json:"slow"`
+}
+
+// handleHistory serves GET /api/history?source=X&status=Y&limit=N, and
+// GET /api/history/{source}: the fetch log, paged like items
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	source, ok := s.source(w, r)
+	if !ok {
+		return
+	}
+	filter := storage.FetchLogFilter{Source: source, Status: q.Get("status")}
+	var order string
+	fields, ok := pageParams(w, q, historyFields, &filter.Limit, &order)
+	if !ok {
+		return
+	}
+	filter.Oldest = order == storage.SortOldest
+	if v := q.Get("cursor"); v != "" {
+		at, id, err := decodeCursor(v)
+		n, convErr := strconv.Atoi(id)
+		if err != nil || convErr != nil {
+			writeError(w, http.StatusBadRequest, "invalid cursor")
+			return
+		}
+		filter.After = &storage.FetchLogCursor{FetchedAt: at, ID: n}
+	}
+
+	limit := filter.Limit
+	filter.Limit++
+	logs, err := s.store.ListFetchLog(filter)
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	var next string
+	if len(logs) > limit {
+		logs = logs[:limit]
+		last := logs[limit-1]
+		next = encodeCursor(last.FetchedAt, strconv.Itoa(last.ID))
+	}
+	out := make([]historyJSON, 0, len(logs))
+	for _, log := range logs {
+		out = append(out, historyJSON{
+			ID:         log.ID,
+			Source:     log.Source,
+			FetchedAt:  log.FetchedAt,
+			Status:     log.Status,
+			Items:      log.ItemsCount,
+			Error:      log.ErrorMessage,
+			DurationMs: log.DurationMs,
+			Endpoint:   log.Endpoint,
+			Slow:       log.Slow,
+		})
+	}
+	writePage(w, r, out, fields, next)
+}
+
+// source returns the source a list request is narrowed to, by path or
+// ?source=. A source in the path must be a configured feed; otherwise r
+// is answered with 404 and ok is false.
+func (s *Server) source(w http.ResponseWriter, r *http.Request) (string, bool) {
+	source := r.PathValue("source")
+	if source == "" {
+		return r.URL.Query().Get("source"), true
+	}
+	if !s.feeds[source] {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("feed not found: %s", source))
+		return "", false
+	}
+	return source, true
+}
+
+// pageParams reads the limit, sort and fields parameters of a list
+// request, answering r with 400 and returning false if one is invalid
+func pageParams(w http.ResponseWriter, q url.Values, allowed []string, limit *int, order *string) ([]string, bool) {
+	var err error
+	if *limit, err = parseLimit(q.Get("limit"), defaultPage); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	if *order, err = parseSort(q.Get("sort")); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	fields, err := parseFields(q.Get("fields"), allowed)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return fields, true
+}
+
+// handleFeed serves GET /api/feed.rss and /api/feed.atom?source=X&tag=Y&limit=N:
+// the newest items across sources, or of one source or tag, as a feed
+func (s *Server) handleFeed(w http.ResponseWriter, r *http.Request, format string) {
+	q := r.URL.Query()
+	source, tag := q.Get("source"), q.Get("tag")
+	if source != "" && !s.feeds[source] {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("feed not found: %s", source))
+		return
+	}
+	limit, err := parseLimit(q.Get("limit"), feedItems)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	items, err := s.store.GetItems(storage.ItemFilter{Source: source, Tag: tag, Limit: limit, Sort: storage.SortNewest})
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+
+	feed := syndication.Feed{Title: syndication.Title(source, tag), SelfURL: selfURL(r), Updated: s.clock.Now()}
+
+	w.Header().Set("Content-Type", syndication.ContentType(format))
+	// Readers poll; make them revalidate rather than cache stale items
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := syndication.Write(w, format, feed, items); err != nil {
+		log.Printf("warning: %v", err)
+	}
+}
+
+// selfURL is the URL r was made to, minus any token, for a feed to refer
+// to itself; behind a proxy the scheme comes from X-Forwarded-Proto
+func selfURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	q := r.URL.Query()
+	q.Del("access_token")
+	u := url.URL{Scheme: scheme, Host: r.Host, Path: r.URL.Path, RawQuery: q.Encode()}
+	return u.String()
+}
+
+// healthJSON is the answer to a health check
+type healthJSON struct {
+	// Status is "ok", "degraded" (some feeds keep failing) or
+	// "unavailable" (the database can't be read)
+	Status       string   `json:"status"`
+	FailingFeeds []string `json:"failing_feeds,omitempty"`
+}
+
+// handleHealth serves GET /api/health for load balancers and monitors.
+// It needs no token; it answers 503 when the database can't be read.
+// Anyone may ask, so why is only logged.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	failures, err := s.store.GetConsecutiveFailures()
+	if err != nil {
+		log.Printf("warning: health check: %v", err)
+		writeJSON(w, http.StatusServiceUnavailable, healthJSON{Status: "unavailable"})
+		return
+	}
+
+	health := healthJSON{Status: "ok"}
+	for feed, n := range failures {
+		if n >= failingAfter && s.feeds[feed] {
+			health.FailingFeeds = append(health.FailingFeeds, feed)
+		}
+	}
+	if len(health.FailingFeeds) > 0 {
+		sort.Strings(health.FailingFeeds)
+		health.Status = "degraded"
+	}
+	writeJSON(w, http.StatusOK, health)
+}
+
+// handleFetch serves POST /api/fetch?feed=X, queueing a fetch of feed
+func (s *Server) handleFetch(w http.ResponseWriter, r *http.Request) {
+	feed := r.URL.Query().Get("feed")
+	if feed == "" {
+		writeError(w, http.StatusBadRequest, "feed is required")
+		return
+	}
+	if !s.feeds[feed] {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("feed not found: %s", feed))
+		return
+	}
+
+	select {
+	case s.fetches <- feed:
+		s.audit(r, storage.AuditFetchTrigger, map[string]string{"feed": feed})
+		writeJSON(w, http.StatusAccepted, map[string]string{"queued": feed})
+	default:
+		writeError(w, http.StatusServiceUnavailable, "too many fetches queued")
+	}
+}
+
+// maxFeedBody bounds the size of an add feed request
+const maxFeedBody = 16 << 10
+
+// feedJSON is a feed to add to the config file
+type feedJSON struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Type is json, ndjson, xml, rss, atom or plugin
+	Type string `json:"type"`
+	// RefreshIntervalSecs is 0 for the default interval
+	RefreshIntervalSecs int    `json:"refresh_interval_secs,omitempty"`
+	Group               string `json:"group,omitempty"`
+}
+
+// handleAddFeed serves POST /api/feeds, appending a feed to the config
+// file. The file is only rewritten if the result is a valid config, and
+// serve fetches the feed once it is restarted with it.
+func (s *Server) handleAddFeed(w http.ResponseWriter, r *http.Request) {
+	if s.configPath == "" {
+		writeError(w, http.StatusServiceUnavailable, "feeds can't be added without a config file")
+		return
+	}
+
+	var req feedJSON
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxFeedBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+
+	feed := config.Feed{Name: req.Name, URL: req.URL, FeedType: req.Type, RefreshIntervalSecs: req.RefreshIntervalSecs, Group: req.Group}
+	if err := config.AppendFeeds(s.configPath, []config.Feed{feed}); err != nil {
+		var editErr *config.EditError
+		if errors.As(err, &editErr) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		internalError(w, r, err)
+		return
+	}
+
+	s.audit(r, storage.AuditFeedAdd, map[string]string{"feed": req.Name, "feed_type": req.Type})
+	writeJSON(w, http.StatusCreated, req)
+}
+
+// handlePrune serves POST /api/prune, pruning processed journal entries
+func (s *Server) handlePrune(w http.ResponseWriter, r *http.Request) {
+	n, err := s.store.PruneJournal()
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	s.audit(r, storage.AuditPrune, map[string]string{"pruned": strconv.Itoa(n)})
+	writeJSON(w, http.StatusOK, map[string]int{"pruned": n})
+}
+
+// maxBatchBody bounds the size of an items:batch request
+const maxBatchBody = 64 << 10
+
+// batchJSON is the body of an items:batch request
+type batchJSON struct {
+	// Action is mark_read, star, tag or delete
+	Action string `json:"action"`
+	// Tag is the tag the tag action adds
+	Tag    string          `json:"tag,omitempty"`
+	Filter batchFilterJSON `json:"filter"`
+}
+
+// batchFilterJSON selects the items a batch changes; at least one field
+// must be set
+type batchFilterJSON struct {
+	Source string `json:"source,omitempty"`
+	Tag    string `json:"tag,omitempty"`
+	// Before only selects items stored before this time, RFC 3339 or
+	// YYYY-MM-DD
+	Before string `json:"before,omitempty"`
+}
+
+// batchResultJSON is the answer to an items:batch request
+type batchResultJSON struct {
+	Action  string `json:"action"`
+	Changed int    `json:"changed"`
+}
+
+// handleItemsBatch serves POST /api/items:batch, marking read, starring,
+// tagging or deleting every item its filter selects in one transaction
+func (s *Server) handleItemsBatch(w http.ResponseWriter, r *http.Request) {
+	var req batchJSON
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+
+	f := req.Filter
+	if f.Source == "" && f.Tag == "" && f.Before == "" {
+		writeError(w, http.StatusBadRequest, "filter needs a source, tag or before")
+		return
+	}
+	if f.Source != "" && !s.feeds[f.Source] {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("feed not found: %s", f.Source))
+		return
+	}
+	batch := storage.ItemBatch{Action: req.Action, Tag: req.Tag, Filter: storage.ItemFilter{Source: f.Source, Tag: f.Tag}}
+	if f.Before != "" {
+		before, err := parseBefore(f.Before)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		batch.Filter.Until = before
+	}
+	if err := batch.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	changed, err := s.store.ApplyItemBatch(batch)
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	if f.Source != "" {
+		s.Invalidate(f.Source)
+	} else {
+		s.invalidateAll()
+	}
+
+	params := map[string]string{"action": req.Action, "changed": strconv.Itoa(changed)}
+	for k, v := range map[string]string{"tag": req.Tag, "source": f.Source, "filter_tag": f.Tag, "before": f.Before} {
+		if v != "" {
+			params[k] = v
+		}
+	}
+	s.audit(r, storage.AuditItemsBatch, params)
+	writeJSON(w, http.StatusOK, batchResultJSON{Action: req.Action, Changed: changed})
+}
+
+// parseBefore reads the before of a batch filter
+func parseBefore(v string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid before: %s (use RFC 3339 or YYYY-MM-DD)", v)
+}
+
+// writeJSON writes v as the response with status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// internalError answers r with the unexpected error err, reporting it
+func internalError(w http.ResponseWriter, r *http.Request, err error) {
+	sentry.CaptureError(err, map[string]string{"endpoint": r.Method + " " + r.URL.Path})
+	writeError(w, http.StatusInternalServerError, err.Error())
+}
+
+// writeError writes an error response with status
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,590 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"feedpulse/internal/clock"
+	"feedpulse/internal/config"
+	"feedpulse/internal/storage"
+	"feedpulse/internal/testutil"
+)
+
+func newTestServer(t *testing.T) (*Server, map[string]string) {
+	t.Helper()
+	s, tokens, _ := newTestServerStore(t)
+	return s, tokens
+}
+
+func newTestServerStore(t *testing.T) (*Server, map[string]string, *testutil.MockStore) {
+	t.Helper()
+
+	store := testutil.NewMockStore()
+	if err := store.SaveItems([]storage.FeedItem{
+		{ID: "1", Title: "One", URL: "https://example.com/1", Source: "HN", CreatedAt: time.Now()},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := make(map[string]string)
+	for _, scope := range []string{storage.ScopeRead, storage.ScopeAdmin} {
+		token, _, err := store.CreateAPIToken(scope, scope)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens[scope] = token
+	}
+	return NewServer(store, []string{"HN"}), tokens, store
+}
+
+func do(s *Server, method, target, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServer_Scopes(t *testing.T) {
+	s, tokens := newTestServer(t)
+
+	tests := []struct {
+		method, target, scope string
+		want                  int
+	}{
+		{"GET", "/api/items", "", http.StatusUnauthorized},
+		{"GET", "/api/items", "bogus", http.StatusUnauthorized},
+		{"GET", "/api/items", storage.ScopeRead, http.StatusOK},
+		{"GET", "/api/stats", storage.ScopeRead, http.StatusOK},
+		{"GET", "/api/items", storage.ScopeAdmin, http.StatusOK},
+		{"POST", "/api/fetch?feed=HN", storage.ScopeRead, http.StatusForbidden},
+		{"POST", "/api/prune", storage.ScopeRead, http.StatusForbidden},
+		{"POST", "/api/fetch?feed=HN", storage.ScopeAdmin, http.StatusAccepted},
+		{"POST", "/api/fetch?feed=nope", storage.ScopeAdmin, http.StatusNotFound},
+		{"POST", "/api/prune", storage.ScopeAdmin, http.StatusOK},
+	}
+	for _, tt := range tests {
+		token := tokens[tt.scope]
+		if token == "" {
+			token = tt.scope
+		}
+		rec := do(s, tt.method, tt.target, token)
+		if rec.Code != tt.want {
+			t.Errorf("%s %s with %q token = %d, want %d (%s)", tt.method, tt.target, tt.scope, rec.Code, tt.want, rec.Body)
+		}
+	}
+
+	select {
+	case feed := <-s.Fetches():
+		if feed != "HN" {
+			t.Errorf("queued fetch for %q, want HN", feed)
+		}
+	default:
+		t.Error("expected the admin fetch to be queued")
+	}
+}
+
+func TestServer_Items(t *testing.T) {
+	s, tokens := newTestServer(t)
+
+	rec := do(s, "GET", "/api/items?since=1h", tokens[storage.ScopeRead])
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"title": "One"`) {
+		t.Errorf("unexpected response: %d %s", rec.Code, rec.Body)
+	}
+
+	rec = do(s, "GET", "/api/items?source=Lobsters", tokens[storage.ScopeRead])
+	if strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Errorf("expected no Lobsters items, got %s", rec.Body)
+	}
+
+	rec = do(s, "GET", "/api/items?since=soon", tokens[storage.ScopeRead])
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid since = %d, want 400", rec.Code)
+	}
+
+	// The window ends at the server's clock
+	s.SetClock(clock.Fixed(time.Now().Add(2 * time.Hour)))
+	rec = do(s, "GET", "/api/items?since=1h", tokens[storage.ScopeRead])
+	if strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Errorf("expected no items in the last hour, got %s", rec.Body)
+	}
+}
+
+func TestServer_ItemsBySource(t *testing.T) {
+	s, tokens := newTestServer(t)
+
+	rec := do(s, "GET", "/api/items/HN?since=1h", tokens[storage.ScopeRead])
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"title": "One"`) {
+		t.Errorf("unexpected response: %d %s", rec.Code, rec.Body)
+	}
+
+	rec = do(s, "GET", "/api/items/Lobsters", tokens[storage.ScopeRead])
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown source = %d, want 404", rec.Code)
+	}
+
+	rec = do(s, "GET", "/api/items/HN", "")
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("no token = %d, want 401", rec.Code)
+	}
+}
+
+func TestServer_Health(t *testing.T) {
+	s, _, store := newTestServerStore(t)
+
+	rec := do(s, "GET", "/api/health", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"status": "ok"`) {
+		t.Errorf("unexpected response: %d %s", rec.Code, rec.Body)
+	}
+
+	for i := 0; i < failingAfter; i++ {
+		if err := store.LogFetch(storage.FetchLog{Source: "HN", Status: "error", FetchedAt: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rec = do(s, "GET", "/api/health", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"status": "degraded"`) || !strings.Contains(rec.Body.String(), `"HN"`) {
+		t.Errorf("unexpected response: %d %s", rec.Code, rec.Body)
+	}
+
+	store.Close()
+	rec = do(s, "GET", "/api/health", "")
+	if rec.Code != http.StatusServiceUnavailable || strings.TrimSpace(rec.Body.String()) != "{\n  \"status\": \"unavailable\"\n}" {
+		t.Errorf("expected a bare unavailable status, got %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestServer_AuditsAdminActions(t *testing.T) {
+	s, tokens, store := newTestServerStore(t)
+	admin, err := store.AuthenticateAPIToken(tokens[storage.ScopeAdmin])
+	if err != nil || admin == nil {
+		t.Fatalf("admin token not found: %v", err)
+	}
+
+	do(s, "POST", "/api/fetch?feed=HN", tokens[storage.ScopeAdmin])
+	do(s, "POST", "/api/prune", tokens[storage.ScopeRead]) // refused, so not audited
+	do(s, "GET", "/api/items", tokens[storage.ScopeAdmin]) // reads aren't audited
+
+	entries, err := store.ListAudit(storage.AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 audit entry, got %+v", entries)
+	}
+	e := entries[0]
+	if e.Actor != "token:"+admin.ID || e.Action != storage.AuditFetchTrigger || e.Params["feed"] != "HN" {
+		t.Errorf("unexpected audit entry: %+v", e)
+	}
+}
+
+func TestServer_Feed(t *testing.T) {
+	s, tokens := newTestServer(t)
+
+	rec := do(s, "GET", "/api/feed.atom", tokens[storage.ScopeRead])
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/atom+xml; charset=utf-8" {
+		t.Fatalf("unexpected response: %d %s %s", rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "<title>One</title>") || !strings.Contains(body, `href="http://example.com/api/feed.atom" rel="self"`) {
+		t.Errorf("expected the item and a self link, got %s", body)
+	}
+
+	// Feed readers can pass the token in the URL; it isn't echoed back
+	rec = do(s, "GET", "/api/feed.rss?source=HN&access_token="+tokens[storage.ScopeRead], "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<dc:creator>HN</dc:creator>") {
+		t.Fatalf("unexpected response: %d %s", rec.Code, rec.Body)
+	}
+	if strings.Contains(rec.Body.String(), tokens[storage.ScopeRead]) {
+		t.Errorf("expected the token left out of the feed, got %s", rec.Body)
+	}
+
+	// Other endpoints only take the token in the header
+	if rec := do(s, "GET", "/api/items?access_token="+tokens[storage.ScopeRead], ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("token in the query of /api/items = %d, want 401", rec.Code)
+	}
+	if rec := do(s, "GET", "/api/feed.rss?source=Lobsters", tokens[storage.ScopeRead]); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown source = %d, want 404", rec.Code)
+	}
+	if rec := do(s, "GET", "/api/feed.rss?limit=0", tokens[storage.ScopeRead]); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid limit = %d, want 400", rec.Code)
+	}
+}
+
+func TestServer_Cache(t *testing.T) {
+	s, tokens, store := newTestServerStore(t)
+	s.SetCacheTTL(time.Minute)
+	now := time.Now()
+	s.cache.now = func() time.Time { return now }
+	watched := s.Watch(store)
+	read := tokens[storage.ScopeRead]
+
+	if rec := do(s, "GET", "/api/items/HN", read); rec.Header().Get("X-Cache") != "MISS" {
+		t.Fatalf("first request X-Cache = %q, want MISS", rec.Header().Get("X-Cache"))
+	}
+	do(s, "GET", "/api/stats", read)
+	do(s, "GET", "/api/items?source=Lobsters", read)
+
+	// Items stored behind the store's back aren't seen until the TTL ends
+	store.SaveItems([]storage.FeedItem{{ID: "2", Title: "Two", URL: "https://example.com/2", Source: "HN", CreatedAt: time.Now()}})
+	rec := do(s, "GET", "/api/items/HN", read)
+	if rec.Header().Get("X-Cache") != "HIT" || strings.Contains(rec.Body.String(), "Two") {
+		t.Errorf("expected the cached answer, got %s %s", rec.Header().Get("X-Cache"), rec.Body)
+	}
+	if rec := do(s, "GET", "/api/items/HN", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("cached endpoint without a token = %d, want 401", rec.Code)
+	}
+
+	// Saving through the watched store drops HN's answers and those
+	// covering every source, but not Lobsters'
+	if _, err := watched.SaveFetchResult(storage.FetchLog{Source: "HN", Status: "success", FetchedAt: time.Now()}, nil); err != nil {
+		t.Fatal(err)
+	}
+	rec = do(s, "GET", "/api/items/HN", read)
+	if rec.Header().Get("X-Cache") != "MISS" || !strings.Contains(rec.Body.String(), "Two") {
+		t.Errorf("expected a fresh answer after the save, got %s %s", rec.Header().Get("X-Cache"), rec.Body)
+	}
+	if rec := do(s, "GET", "/api/stats", read); rec.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("expected stats invalidated by the save, got %s", rec.Header().Get("X-Cache"))
+	}
+	if rec := do(s, "GET", "/api/items?source=Lobsters", read); rec.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("expected Lobsters' answer kept, got %s", rec.Header().Get("X-Cache"))
+	}
+
+	now = now.Add(time.Minute)
+	if rec := do(s, "GET", "/api/items?source=Lobsters", read); rec.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("expected the answer expired after the TTL, got %s", rec.Header().Get("X-Cache"))
+	}
+
+	// Errors aren't cached
+	do(s, "GET", "/api/items?since=soon", read)
+	if rec := do(s, "GET", "/api/items?since=soon", read); rec.Code != http.StatusBadRequest || rec.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("expected the error answered afresh, got %d %s", rec.Code, rec.Header().Get("X-Cache"))
+	}
+}
+
+func TestServer_CacheDropsStaleResponses(t *testing.T) {
+	s, _ := newTestServer(t)
+	s.SetCacheTTL(time.Minute)
+
+	// A save landing while a response is computed keeps it out of the
+	// cache, for the source saved and for answers covering every source
+	var handler http.HandlerFunc
+	for _, path := range []string{"/api/items/HN", "/api/stats"} {
+		source := requestSource
+		if path == "/api/stats" {
+			source = allSources
+		}
+		handler = s.cached(source, func(w http.ResponseWriter, r *http.Request) {
+			s.Invalidate("HN")
+			w.Write([]byte("stale"))
+		})
+		req := httptest.NewRequest("GET", path, nil)
+		req.SetPathValue("source", strings.TrimPrefix(path, "/api/items/"))
+		handler(httptest.NewRecorder(), req)
+		if len(s.cache.entries) != 0 {
+			t.Errorf("%s: expected the stale response dropped, got %d cached", path, len(s.cache.entries))
+		}
+	}
+
+	// Another source's save doesn't
+	handler = s.cached(requestSource, func(w http.ResponseWriter, r *http.Request) {
+		s.Invalidate("Lobsters")
+		w.Write([]byte("fresh"))
+	})
+	req := httptest.NewRequest("GET", "/api/items/HN", nil)
+	req.SetPathValue("source", "HN")
+	handler(httptest.NewRecorder(), req)
+	if len(s.cache.entries) != 1 {
+		t.Errorf("expected the response cached, got %d cached", len(s.cache.entries))
+	}
+}
+
+func TestServer_Pagination(t *testing.T) {
+	s, tokens, store := newTestServerStore(t)
+	read := tokens[storage.ScopeRead]
+
+	// Item 1 is stored now; 2 to 4 before it, 3 and 4 in the same second
+	now := time.Now().Truncate(time.Second)
+	if err := store.SaveItems([]storage.FeedItem{
+		{ID: "2", Title: "Two", URL: "https://example.com/2", Source: "HN", CreatedAt: now.Add(-time.Minute)},
+		{ID: "3", Title: "Three", URL: "https://example.com/3", Source: "HN", CreatedAt: now.Add(-2 * time.Minute)},
+		{ID: "4", Title: "Four", URL: "https://example.com/4", Source: "HN", Tags: []string{"go"}, CreatedAt: now.Add(-2 * time.Minute)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var titles []string
+	target := "/api/items?limit=3&fields=title"
+	for pages := 0; target != ""; pages++ {
+		if pages == 3 {
+			t.Fatal("expected the pages to end")
+		}
+		rec := do(s, "GET", target, read)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s = %d %s", target, rec.Code, rec.Body)
+		}
+		var page []map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range page {
+			if len(item) != 1 {
+				t.Errorf("expected only the title, got %v", item)
+			}
+			titles = append(titles, item["title"].(string))
+		}
+		target = ""
+		if next := rec.Header().Get("X-Next-Cursor"); next != "" {
+			if link := rec.Header().Get("Link"); !strings.Contains(link, "cursor="+next) || !strings.HasSuffix(link, `rel="next"`) {
+				t.Errorf("unexpected Link header: %s", link)
+			}
+			target = "/api/items?limit=3&fields=title&cursor=" + next
+		}
+	}
+	if got := strings.Join(titles, ","); got != "One,Two,Three,Four" {
+		t.Errorf("expected every item once, newest first, got %s", got)
+	}
+
+	rec := do(s, "GET", "/api/items?sort=oldest&limit=1&fields=id,tags", read)
+	if body := strings.Join(strings.Fields(rec.Body.String()), ""); body != `[{"id":"3"}]` || rec.Header().Get("X-Next-Cursor") == "" {
+		t.Errorf("expected the oldest item's ID and a next page, got %s", body)
+	}
+
+	for _, target := range []string{"/api/items?sort=title", "/api/items?fields=title,body", "/api/items?cursor=bogus", "/api/history?limit=5000"} {
+		if rec := do(s, "GET", target, read); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s = %d, want 400", target, rec.Code)
+		}
+	}
+
+	message := "timeout"
+	store.LogFetch(storage.FetchLog{Source: "HN", FetchedAt: now.Add(-time.Hour), Status: "success", ItemsCount: 4})
+	store.LogFetch(storage.FetchLog{Source: "HN", FetchedAt: now, Status: "error", ErrorMessage: &message})
+	store.LogFetch(storage.FetchLog{Source: "Lobsters", FetchedAt: now, Status: "success"})
+
+	rec = do(s, "GET", "/api/history/HN?limit=1", read)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"error": "timeout"`) {
+		t.Fatalf("unexpected response: %d %s", rec.Code, rec.Body)
+	}
+	rec = do(s, "GET", "/api/history/HN?limit=1&fields=status,items&cursor="+rec.Header().Get("X-Next-Cursor"), read)
+	if body := strings.Join(strings.Fields(rec.Body.String()), ""); body != `[{"items":4,"status":"success"}]` || rec.Header().Get("X-Next-Cursor") != "" {
+		t.Errorf("expected the last HN fetch and no next page, got %s", body)
+	}
+	if rec := do(s, "GET", "/api/history?status=success&sort=oldest&fields=source", read); strings.Join(strings.Fields(rec.Body.String()), "") != `[{"source":"HN"},{"source":"Lobsters"}]` {
+		t.Errorf("unexpected history: %s", rec.Body)
+	}
+}
+
+func TestServer_OpenAPI(t *testing.T) {
+	s, _ := newTestServer(t)
+	s.SetVersion("1.2.3")
+
+	rec := do(s, "GET", SpecPath, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected response: %d %s", rec.Code, rec.Body)
+	}
+	var spec struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct {
+			Version string `json:"version"`
+		} `json:"info"`
+		Paths map[string]map[string]struct {
+			OperationID string                     `json:"operationId"`
+			Security    []map[string][]string      `json:"security"`
+			Responses   map[string]json.RawMessage `json:"responses"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("invalid spec: %v", err)
+	}
+	if spec.OpenAPI != "3.0.3" || spec.Info.Version != "1.2.3" {
+		t.Errorf("unexpected header: %s %s", spec.OpenAPI, spec.Info.Version)
+	}
+
+	// Every route is described, under a distinct operation ID
+	ids := make(map[string]bool)
+	for _, e := range s.endpoints {
+		op, ok := spec.Paths[e.path][strings.ToLower(e.method)]
+		if !ok {
+			t.Errorf("%s %s is not described", e.method, e.path)
+			continue
+		}
+		if ids[op.OperationID] {
+			t.Errorf("duplicate operation ID %s", op.OperationID)
+		}
+		ids[op.OperationID] = true
+		if (e.scope == "") != (len(op.Security) == 0) {
+			t.Errorf("%s %s: security %v doesn't match scope %q", e.method, e.path, op.Security, e.scope)
+		}
+	}
+	if op := spec.Paths["/api/items/{source}"]["get"]; op.OperationID != "getItemsBySource" || op.Responses["404"] == nil {
+		t.Errorf("unexpected items operation: %+v", op)
+	}
+	if op := spec.Paths["/api/fetch"]["post"]; op.Responses["202"] == nil || op.Responses["403"] == nil {
+		t.Errorf("unexpected fetch operation: %+v", op)
+	}
+
+	var item struct {
+		Properties map[string]map[string]interface{} `json:"properties"`
+		Required   []string                          `json:"required"`
+	}
+	if err := json.Unmarshal(spec.Components.Schemas["FeedItem"], &item); err != nil {
+		t.Fatalf("invalid FeedItem schema: %v", err)
+	}
+	if item.Properties["created_at"]["format"] != "date-time" || item.Properties["tags"]["type"] != "array" || strings.Join(item.Required, ",") != "id,title,url,source,created_at" {
+		t.Errorf("unexpected FeedItem schema: %+v", item)
+	}
+	if spec.Components.Schemas["History"] == nil || spec.Components.Schemas["Stats"] == nil {
+		t.Errorf("expected History and Stats schemas, got %v", spec.Components.Schemas)
+	}
+
+	rec = do(s, "GET", DocsPath, "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `data-spec="/openapi.json"`) || strings.Contains(rec.Body.String(), "https://") {
+		t.Errorf("unexpected docs page: %d %s", rec.Code, rec.Body)
+	}
+	for _, asset := range []string{"/docs.js", "/docs.css"} {
+		if rec := do(s, "GET", DocsPath+asset, ""); rec.Code != http.StatusOK || rec.Body.Len() == 0 {
+			t.Errorf("expected %s to be served, got %d", asset, rec.Code)
+		}
+	}
+}
+
+func TestServer_ItemsBatch(t *testing.T) {
+	s, tokens, store := newTestServerStore(t)
+	store.SaveItems([]storage.FeedItem{
+		{ID: "2", Title: "Two", URL: "https://example.com/2", Source: "HN", Tags: []string{"go"}, CreatedAt: time.Now()},
+		{ID: "3", Title: "Three", URL: "https://example.com/3", Source: "Lobsters", Tags: []string{"go"}, CreatedAt: time.Now()},
+	})
+	s.SetCacheTTL(time.Minute)
+	admin := tokens[storage.ScopeAdmin]
+
+	batch := func(token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/items:batch", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name, token, body string
+		want              int
+	}{
+		{"read token", tokens[storage.ScopeRead], `{"action":"star","filter":{"source":"HN"}}`, http.StatusForbidden},
+		{"no filter", admin, `{"action":"star","filter":{}}`, http.StatusBadRequest},
+		{"unknown action", admin, `{"action":"archive","filter":{"source":"HN"}}`, http.StatusBadRequest},
+		{"tag without a tag", admin, `{"action":"tag","filter":{"source":"HN"}}`, http.StatusBadRequest},
+		{"bad before", admin, `{"action":"star","filter":{"before":"soon"}}`, http.StatusBadRequest},
+		{"unknown field", admin, `{"action":"star","filter":{"feed":"HN"}}`, http.StatusBadRequest},
+		{"unknown source", admin, `{"action":"star","filter":{"source":"Lobsters"}}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if rec := batch(tt.token, tt.body); rec.Code != tt.want {
+			t.Errorf("%s = %d, want %d (%s)", tt.name, rec.Code, tt.want, rec.Body)
+		}
+	}
+
+	read := tokens[storage.ScopeRead]
+	do(s, "GET", "/api/items?fields=id,starred", read)
+
+	rec := batch(admin, `{"action":"star","filter":{"source":"HN","tag":"go"}}`)
+	var result batchResultJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("unexpected response: %d %s", rec.Code, rec.Body)
+	}
+	if result.Action != storage.BatchStar || result.Changed != 1 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	// The change shows at once, despite the cache
+	rec = do(s, "GET", "/api/items?fields=id,starred", read)
+	if rec.Header().Get("X-Cache") != "MISS" || !strings.Contains(rec.Body.String(), `"starred": true`) {
+		t.Errorf("expected the starred item listed afresh, got %s %s", rec.Header().Get("X-Cache"), rec.Body)
+	}
+
+	before := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	rec = batch(admin, `{"action":"delete","filter":{"tag":"go","before":"`+before+`"}}`)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"changed": 2`) {
+		t.Fatalf("unexpected delete response: %d %s", rec.Code, rec.Body)
+	}
+	if count, _ := store.GetAllItemsCount(); count != 1 {
+		t.Errorf("expected 1 item left, got %d", count)
+	}
+
+	entries, err := store.ListAudit(storage.AuditFilter{Action: storage.AuditItemsBatch})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Params["action"] != storage.BatchDelete || entries[0].Params["changed"] != "2" {
+		t.Errorf("unexpected audit entries: %+v", entries)
+	}
+}
+
+func TestServer_AddFeed(t *testing.T) {
+	s, tokens, store := newTestServerStore(t)
+	admin := tokens[storage.ScopeAdmin]
+
+	add := func(token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/feeds", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := add(admin, `{"name":"Lobsters","url":"https://lobste.rs/rss","type":"rss"}`); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected feeds refused without a config file, got %d %s", rec.Code, rec.Body)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	cfg := &config.Config{
+		Settings: config.Settings{MaxConcurrency: 5, DefaultTimeoutSecs: 10, DatabasePath: "feeds.db"},
+		Feeds:    []config.Feed{{Name: "HN", URL: "https://news.ycombinator.com/rss", FeedType: "rss"}},
+	}
+	if err := config.WriteConfig(path, cfg, false); err != nil {
+		t.Fatal(err)
+	}
+	s.SetConfigPath(path)
+
+	tests := []struct {
+		name, token, body string
+		want              int
+	}{
+		{"read token", tokens[storage.ScopeRead], `{"name":"Lobsters","url":"https://lobste.rs/rss","type":"rss"}`, http.StatusForbidden},
+		{"unknown field", admin, `{"name":"Lobsters","link":"https://lobste.rs/rss","type":"rss"}`, http.StatusBadRequest},
+		{"taken name", admin, `{"name":"HN","url":"https://lobste.rs/rss","type":"rss"}`, http.StatusBadRequest},
+		{"invalid url", admin, `{"name":"Lobsters","url":"ftp://lobste.rs/rss","type":"rss"}`, http.StatusBadRequest},
+		{"unknown type", admin, `{"name":"Lobsters","url":"https://lobste.rs/rss","type":"csv"}`, http.StatusBadRequest},
+		{"added", admin, `{"name":"Lobsters","url":"https://lobste.rs/rss","type":"rss","group":"news"}`, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		if rec := add(tt.token, tt.body); rec.Code != tt.want {
+			t.Errorf("%s = %d, want %d (%s)", tt.name, rec.Code, tt.want, rec.Body)
+		}
+	}
+
+	written, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written.Feeds) != 2 || written.Feeds[1].Name != "Lobsters" || written.Feeds[1].Group != "news" {
+		t.Errorf("unexpected feeds written: %+v", written.Feeds)
+	}
+
+	entries, err := store.ListAudit(storage.AuditFilter{Action: storage.AuditFeedAdd})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Params["feed"] != "Lobsters" {
+		t.Errorf("unexpected audit entries: %+v", entries)
+	}
+}
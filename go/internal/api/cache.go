@@ -0,0 +1,249 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"time"
+
+	"feedpulse/internal/storage"
+)
+
+// maxCacheEntries bounds how many responses the cache holds; past it,
+// new responses aren't cached until entries expire
+const maxCacheEntries = 256
+
+// cachedResponse is a response kept for the requests that follow it
+type cachedResponse struct {
+	header  http.Header
+	body    []byte
+	source  string
+	expires time.Time
+}
+
+// responseCache keeps successful responses of read endpoints for a while.
+// Each is tagged with the source it covers, "" for all of them, so saving
+// a source's items drops only the responses that could show them.
+type responseCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*cachedResponse
+	// Invalidations are counted, in all, per source and for the whole
+	// cache, so a response computed before one isn't stored after it
+	all     uint64
+	resets  uint64
+	sources map[string]uint64
+}
+
+// generation returns the count of the invalidations that drop responses
+// covering source; it changes whenever one happens. The caller holds mu.
+func (c *responseCache) generation(source string) uint64 {
+	if source == "" {
+		return c.all
+	}
+	return c.resets + c.sources[source]
+}
+
+// SetCacheTTL makes the server answer repeated stats, items and feed
+// requests from memory for up to ttl, or until a store returned by Watch
+// saves items or fetches for the source they cover; 0 turns caching off
+func (s *Server) SetCacheTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		s.cache = nil
+		return
+	}
+	s.cache = &responseCache{ttl: ttl, now: time.Now, entries: make(map[string]*cachedResponse), sources: make(map[string]uint64)}
+}
+
+// Invalidate drops the cached responses that could include source's
+// items or stats
+func (s *Server) Invalidate(source string) {
+	c := s.cache
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.all++
+	c.sources[source]++
+	for key, entry := range c.entries {
+		if entry.source == "" || entry.source == source {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// invalidateAll drops every cached response
+func (s *Server) invalidateAll() {
+	c := s.cache
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.all++
+	c.resets++
+	c.entries = make(map[string]*cachedResponse)
+}
+
+// Watch returns store wrapped so that saving items or logging a fetch
+// invalidates the cached responses of the source involved
+func (s *Server) Watch(store storage.Store) storage.Store {
+	return &watchedStore{Store: store, server: s}
+}
+
+// watchedStore invalidates a server's cache as it writes
+type watchedStore struct {
+	storage.Store
+	server *Server
+}
+
+func (w *watchedStore) SaveItems(items []storage.FeedItem) error {
+	err := w.Store.SaveItems(items)
+	w.invalidate(items)
+	return err
+}
+
+func (w *watchedStore) SaveFetchResult(log storage.FetchLog, items []storage.FeedItem) (storage.SaveResult, error) {
+	result, err := w.Store.SaveFetchResult(log, items)
+	w.server.Invalidate(log.Source)
+	w.invalidate(items)
+	return result, err
+}
+
+func (w *watchedStore) SaveBackfillPage(log storage.FetchLog, items []storage.FeedItem, next storage.BackfillCursor) (storage.SaveResult, error) {
+	result, err := w.Store.SaveBackfillPage(log, items, next)
+	w.server.Invalidate(log.Source)
+	w.invalidate(items)
+	return result, err
+}
+
+func (w *watchedStore) LogFetch(log storage.FetchLog) error {
+	err := w.Store.LogFetch(log)
+	w.server.Invalidate(log.Source)
+	return err
+}
+
+// invalidate drops the responses covering the sources of items. A failed
+// write invalidates too; part of it may have been stored.
+func (w *watchedStore) invalidate(items []storage.FeedItem) {
+	seen := make(map[string]bool)
+	for _, item := range items {
+		if !seen[item.Source] {
+			seen[item.Source] = true
+			w.server.Invalidate(item.Source)
+		}
+	}
+}
+
+// cached wraps h so its successful responses are kept and replayed while
+// caching is on. source names the source a request covers, "" for all.
+func (s *Server) cached(source func(r *http.Request) string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := s.cache
+		if c == nil {
+			h(w, r)
+			return
+		}
+
+		key, covers := cacheKey(r), source(r)
+		c.mu.Lock()
+		entry, ok := c.entries[key]
+		if ok && !c.now().Before(entry.expires) {
+			delete(c.entries, key)
+			ok = false
+		}
+		generation := c.generation(covers)
+		c.mu.Unlock()
+
+		if ok {
+			for name, values := range entry.header {
+				w.Header()[name] = values
+			}
+			w.Header().Set("X-Cache", "HIT")
+			w.WriteHeader(http.StatusOK)
+			w.Write(entry.body)
+			return
+		}
+
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+		w.Header().Set("X-Cache", "MISS")
+		h(rec, r)
+		if rec.status != http.StatusOK {
+			return
+		}
+
+		header := w.Header().Clone()
+		header.Del("X-Cache")
+		c.put(key, &cachedResponse{header: header, body: rec.body.Bytes(), source: covers, expires: c.now().Add(c.ttl)}, generation)
+	}
+}
+
+// put stores entry under key, unless its source was invalidated since
+// generation was taken, while the response was being computed, or the
+// cache is full of live entries
+func (c *responseCache) put(key string, entry *cachedResponse, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation(entry.source) != generation {
+		return
+	}
+	if len(c.entries) >= maxCacheEntries {
+		now := c.now()
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxCacheEntries {
+			return
+		}
+	}
+	c.entries[key] = entry
+}
+
+// cacheKey identifies the response to r: its path and parameters, less
+// the token, and the host and scheme feeds link themselves with
+func cacheKey(r *http.Request) string {
+	q := r.URL.Query()
+	q.Del("access_token")
+	return r.Host + " " + r.Header.Get("X-Forwarded-Proto") + " " + r.URL.Path + "?" + q.Encode()
+}
+
+// recorder passes a response through while keeping a copy of it
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.body.Write(p)
+	return r.ResponseWriter.Write(p)
+}
+
+// querySource is the source a request names with ?source=, if any
+func querySource(r *http.Request) string {
+	return r.URL.Query().Get("source")
+}
+
+// requestSource is the source of an items or history request, by path
+// or query
+func requestSource(r *http.Request) string {
+	if source := r.PathValue("source"); source != "" {
+		return source
+	}
+	return querySource(r)
+}
+
+// allSources marks responses covering every source
+func allSources(*http.Request) string {
+	return ""
+}
@@ -0,0 +1,152 @@
+// Renders the API's OpenAPI description: every operation with its
+// parameters, request body and responses, then the schemas they refer to.
+// Everything from the description is added as text, never as markup.
+(function () {
+  "use strict";
+
+  var root = document.getElementById("docs");
+
+  function el(tag, attrs, children) {
+    var node = document.createElement(tag);
+    Object.keys(attrs || {}).forEach(function (name) {
+      node.setAttribute(name, attrs[name]);
+    });
+    (children || []).forEach(function (child) {
+      if (child === null || child === undefined) {
+        return;
+      }
+      node.appendChild(typeof child === "string" ? document.createTextNode(child) : child);
+    });
+    return node;
+  }
+
+  // typeName describes a schema in a few words, linking to named schemas
+  function typeName(schema) {
+    if (!schema) {
+      return "";
+    }
+    if (schema.$ref) {
+      var name = schema.$ref.replace("#/components/schemas/", "");
+      return el("a", { href: "#schema-" + name }, [name]);
+    }
+    if (schema.allOf && schema.allOf.length === 1) {
+      return el("span", {}, [typeName(schema.allOf[0]), " or null"]);
+    }
+    var text = schema.type || "any";
+    if (schema.type === "array") {
+      return el("span", {}, ["array of ", typeName(schema.items)]);
+    }
+    if (schema.type === "object" && schema.additionalProperties) {
+      return el("span", {}, ["map of ", typeName(schema.additionalProperties)]);
+    }
+    if (schema.format) {
+      text += " (" + schema.format + ")";
+    }
+    if (schema.enum) {
+      text += ": " + schema.enum.join(", ");
+    }
+    if (schema.nullable) {
+      text += " or null";
+    }
+    return text;
+  }
+
+  function table(headers, rows) {
+    return el("table", {}, [
+      el("tr", {}, headers.map(function (h) { return el("th", {}, [h]); })),
+    ].concat(rows.map(function (row) {
+      return el("tr", {}, row.map(function (cell) { return el("td", {}, [cell]); }));
+    })));
+  }
+
+  function jsonSchema(content) {
+    var json = content && content["application/json"];
+    return json ? json.schema : null;
+  }
+
+  function operation(path, method, op) {
+    var body = [];
+    if (op.description) {
+      body.push(el("p", {}, [op.description]));
+    }
+    if (op.parameters && op.parameters.length) {
+      body.push(el("h4", {}, ["Parameters"]));
+      body.push(table(["Name", "In", "Type", "Description"], op.parameters.map(function (p) {
+        return [el("code", {}, [p.name + (p.required ? " *" : "")]), p.in, typeName(p.schema), p.description || ""];
+      })));
+    }
+    if (op.requestBody) {
+      body.push(el("h4", {}, ["Request body"]));
+      body.push(el("p", {}, [typeName(jsonSchema(op.requestBody.content))]));
+    }
+    var responses = op.responses || {};
+    body.push(el("h4", {}, ["Responses"]));
+    body.push(table(["Status", "Description", "Body"], Object.keys(responses).sort().map(function (code) {
+      var r = responses[code];
+      var types = Object.keys(r.content || {});
+      var schema = jsonSchema(r.content);
+      return [code, r.description || "", schema ? typeName(schema) : types.join(", ")];
+    })));
+
+    return el("details", { id: op.operationId || "" }, [
+      el("summary", {}, [
+        el("span", { "class": "method " + method }, [method]), " ",
+        el("code", {}, [path]), " ",
+        op.summary || "",
+      ]),
+      el("div", {}, body),
+    ]);
+  }
+
+  function schemaSection(name, schema) {
+    var properties = schema.properties || {};
+    var required = schema.required || [];
+    return el("section", { id: "schema-" + name }, [
+      el("h3", {}, [name]),
+      table(["Field", "Type"], Object.keys(properties).sort().map(function (field) {
+        var label = field + (required.indexOf(field) >= 0 ? " *" : "");
+        return [el("code", {}, [label]), typeName(properties[field])];
+      })),
+    ]);
+  }
+
+  function render(spec) {
+    var info = spec.info || {};
+    var children = [el("h1", {}, [(info.title || "API") + " " + (info.version || "")])];
+    if (info.description) {
+      children.push(el("p", {}, [info.description]));
+    }
+    children.push(el("p", {}, ["Fields and parameters marked * are required. The description is at ",
+      el("a", { href: root.dataset.spec }, [root.dataset.spec]), "."]));
+
+    children.push(el("h2", {}, ["Operations"]));
+    Object.keys(spec.paths || {}).sort().forEach(function (path) {
+      var ops = spec.paths[path];
+      ["get", "post", "put", "patch", "delete"].forEach(function (method) {
+        if (ops[method]) {
+          children.push(operation(path, method, ops[method]));
+        }
+      });
+    });
+
+    var schemas = (spec.components || {}).schemas || {};
+    children.push(el("h2", {}, ["Schemas"]));
+    Object.keys(schemas).sort().forEach(function (name) {
+      children.push(schemaSection(name, schemas[name] || {}));
+    });
+
+    root.replaceChildren.apply(root, children);
+  }
+
+  fetch(root.dataset.spec)
+    .then(function (resp) {
+      if (!resp.ok) {
+        throw new Error(resp.status + " " + resp.statusText);
+      }
+      return resp.json();
+    })
+    .then(render)
+    .catch(function (err) {
+      root.replaceChildren(el("p", { "class": "error" }, ["Failed to load the API description: " + err.message]));
+    });
+})();
@@ -0,0 +1,426 @@
+package api
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"feedpulse/internal/storage"
+	"feedpulse/internal/syndication"
+)
+
+// SpecPath is where the OpenAPI description of the API is served. It sits
+// outside Prefix, where tools look for it by default.
+const SpecPath = "/openapi.json"
+
+// DocsPath is where the API documentation page is served
+const DocsPath = "/api/docs"
+
+// endpoint is a route of the API: how it is served, and how the OpenAPI
+// description documents it
+type endpoint struct {
+	method, path string
+	summary      string
+	// scope is the token scope the endpoint needs, "" for none.
+	// tokenInQuery also accepts the token as ?access_token=.
+	scope        string
+	tokenInQuery bool
+	params       []param
+	// body is a value of the JSON request body's type, if there is one
+	body interface{}
+	// response is a value of the type answered on success, with status
+	// (200 when zero); contentType is set for answers that aren't JSON
+	response    interface{}
+	status      int
+	contentType string
+	// errors are the error statuses answered besides those for tokens
+	errors []int
+	// paged lists send the cursor of the next page in headers
+	paged   bool
+	handler http.HandlerFunc
+}
+
+// param is a query or path parameter of an endpoint
+type param struct {
+	name, in    string
+	typ         string
+	description string
+	required    bool
+	enum        []string
+}
+
+func queryParam(name, typ, description string) param {
+	return param{name: name, in: "query", typ: typ, description: description}
+}
+
+// sourcePath is the {source} parameter of per-feed endpoints
+var sourcePath = param{name: "source", in: "path", typ: "string", description: "A configured feed", required: true}
+
+// listParams are the paging parameters of item and history lists
+func listParams(fields []string) []param {
+	return []param{
+		queryParam("limit", "integer", fmt.Sprintf("Most entries to return, 1 to %d; default %d", maxItems, defaultPage)),
+		{name: "sort", in: "query", typ: "string", description: "Which end to list from; default newest", enum: []string{storage.SortNewest, storage.SortOldest}},
+		queryParam("cursor", "string", "Continue after the previous page, from its X-Next-Cursor header"),
+		queryParam("fields", "string", "Comma-separated fields to return: "+strings.Join(fields, ", ")),
+	}
+}
+
+// routes lists the endpoints of the API
+func (s *Server) routes() []endpoint {
+	itemParams := append([]param{queryParam("since", "string", "How far back to list, e.g. 6h or 7d; default 24h")}, listParams(itemFields)...)
+	historyParams := append([]param{queryParam("status", "string", "Only fetches with this status, e.g. error")}, listParams(historyFields)...)
+	feedParams := []param{
+		queryParam("source", "string", "Only items of this feed"),
+		queryParam("tag", "string", "Only items with this tag"),
+		queryParam("limit", "integer", fmt.Sprintf("Most items to include, 1 to %d; default %d", maxItems, feedItems)),
+	}
+	// Feed readers often can't send headers, so the combined feed also
+	// takes the token as the access_token query parameter
+	feed := func(format string) http.HandlerFunc {
+		return s.cached(querySource, func(w http.ResponseWriter, r *http.Request) {
+			s.handleFeed(w, r, format)
+		})
+	}
+
+	return []endpoint{
+		{
+			method: "GET", path: "/api/items", summary: "Items stored within a window, newest first",
+			scope: storage.ScopeRead, params: append([]param{queryParam("source", "string", "Only items of this feed")}, itemParams...),
+			response: []storage.FeedItem{}, errors: []int{http.StatusBadRequest}, paged: true,
+			handler: s.cached(requestSource, s.handleItems),
+		},
+		{
+			method: "GET", path: "/api/items/{source}", summary: "Items of one feed stored within a window",
+			scope: storage.ScopeRead, params: append([]param{sourcePath}, itemParams...),
+			response: []storage.FeedItem{}, errors: []int{http.StatusBadRequest, http.StatusNotFound}, paged: true,
+			handler: s.cached(requestSource, s.handleItems),
+		},
+		{
+			method: "GET", path: "/api/history", summary: "The fetch log, newest first",
+			scope: storage.ScopeRead, params: append([]param{queryParam("source", "string", "Only fetches of this feed")}, historyParams...),
+			response: []historyJSON{}, errors: []int{http.StatusBadRequest}, paged: true,
+			handler: s.cached(requestSource, s.handleHistory),
+		},
+		{
+			method: "GET", path: "/api/history/{source}", summary: "The fetch log of one feed",
+			scope: storage.ScopeRead, params: append([]param{sourcePath}, historyParams...),
+			response: []historyJSON{}, errors: []int{http.StatusBadRequest, http.StatusNotFound}, paged: true,
+			handler: s.cached(requestSource, s.handleHistory),
+		},
+		{
+			method: "GET", path: "/api/stats", summary: "Per-source fetch stats",
+			scope: storage.ScopeRead, response: []statsJSON{},
+			handler: s.cached(allSources, s.handleStats),
+		},
+		{
+			method: "GET", path: "/api/health", summary: "Whether feedpulse is ok, degraded (feeds keep failing) or unavailable (503, the database can't be read)",
+			response: healthJSON{}, handler: s.handleHealth,
+		},
+		{
+			method: "GET", path: "/api/feed.rss", summary: "The newest items across sources as RSS 2.0",
+			scope: storage.ScopeRead, tokenInQuery: true, params: feedParams,
+			contentType: "application/rss+xml", errors: []int{http.StatusBadRequest, http.StatusNotFound},
+			handler: feed(syndication.FormatRSS),
+		},
+		{
+			method: "GET", path: "/api/feed.atom", summary: "The newest items across sources as Atom",
+			scope: storage.ScopeRead, tokenInQuery: true, params: feedParams,
+			contentType: "application/atom+xml", errors: []int{http.StatusBadRequest, http.StatusNotFound},
+			handler: feed(syndication.FormatAtom),
+		},
+		{
+			method: "POST", path: "/api/fetch", summary: "Queue a fetch of a configured feed",
+			scope: storage.ScopeAdmin, params: []param{{name: "feed", in: "query", typ: "string", description: "The feed to fetch", required: true}},
+			response: struct {
+				Queued string `json:"queued"`
+			}{}, status: http.StatusAccepted,
+			errors:  []int{http.StatusBadRequest, http.StatusNotFound, http.StatusServiceUnavailable},
+			handler: s.handleFetch,
+		},
+		{
+			method: "POST", path: "/api/feeds", summary: "Add a feed to the config file; serve fetches it once restarted",
+			scope: storage.ScopeAdmin, body: feedJSON{},
+			response: feedJSON{}, status: http.StatusCreated,
+			errors:  []int{http.StatusBadRequest, http.StatusServiceUnavailable},
+			handler: s.handleAddFeed,
+		},
+		{
+			method: "POST", path: "/api/items:batch", summary: "Mark read, star, tag or delete every item a filter selects",
+			scope: storage.ScopeAdmin, body: batchJSON{},
+			response: batchResultJSON{}, errors: []int{http.StatusBadRequest, http.StatusNotFound},
+			handler: s.handleItemsBatch,
+		},
+		{
+			method: "POST", path: "/api/prune", summary: "Prune processed journal entries",
+			scope: storage.ScopeAdmin,
+			response: struct {
+				Pruned int `json:"pruned"`
+			}{},
+			handler: s.handlePrune,
+		},
+	}
+}
+
+// SetVersion sets the version the OpenAPI description gives the API
+func (s *Server) SetVersion(version string) {
+	s.version = version
+}
+
+// handleSpec serves GET /openapi.json, the OpenAPI 3 description of the
+// routes the server was built with. It needs no token.
+func (s *Server) handleSpec(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.spec())
+}
+
+// spec builds the OpenAPI description of the server's endpoints
+func (s *Server) spec() map[string]interface{} {
+	schemas := map[string]interface{}{
+		"Error": map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"error": map[string]interface{}{"type": "string"}},
+			"required":   []string{"error"},
+		},
+	}
+
+	paths := make(map[string]map[string]interface{})
+	for _, e := range s.endpoints {
+		if paths[e.path] == nil {
+			paths[e.path] = make(map[string]interface{})
+		}
+		paths[e.path][strings.ToLower(e.method)] = operation(e, schemas)
+	}
+
+	version := s.version
+	if version == "" {
+		version = "unknown"
+	}
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "feedpulse API",
+			"version":     version,
+			"description": "Items, fetch history and stats collected by feedpulse serve.",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearerToken": map[string]interface{}{"type": "http", "scheme": "bearer", "description": "A token created with feedpulse token create"},
+				"accessToken": map[string]interface{}{"type": "apiKey", "in": "query", "name": "access_token"},
+			},
+		},
+	}
+}
+
+// operation describes e, adding the schemas of named types it answers
+// with to schemas
+func operation(e endpoint, schemas map[string]interface{}) map[string]interface{} {
+	op := map[string]interface{}{
+		"operationId": operationID(e.method, e.path),
+		"summary":     e.summary,
+	}
+
+	var params []interface{}
+	for _, p := range e.params {
+		schema := map[string]interface{}{"type": p.typ}
+		if len(p.enum) > 0 {
+			schema["enum"] = p.enum
+		}
+		params = append(params, map[string]interface{}{
+			"name":        p.name,
+			"in":          p.in,
+			"description": p.description,
+			"required":    p.required,
+			"schema":      schema,
+		})
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	if e.body != nil {
+		op["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": schemaOf(reflect.TypeOf(e.body), schemas)}},
+		}
+	}
+
+	if e.scope == "" {
+		op["security"] = []interface{}{}
+	} else {
+		op["description"] = fmt.Sprintf("Needs a token with %s scope.", e.scope)
+		security := []interface{}{map[string]interface{}{"bearerToken": []string{}}}
+		if e.tokenInQuery {
+			security = append(security, map[string]interface{}{"accessToken": []string{}})
+		}
+		op["security"] = security
+	}
+
+	status := e.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]interface{}{"description": http.StatusText(status)}
+	if e.contentType != "" {
+		success["content"] = map[string]interface{}{e.contentType: map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}}
+	} else if e.response != nil {
+		success["content"] = map[string]interface{}{"application/json": map[string]interface{}{"schema": schemaOf(reflect.TypeOf(e.response), schemas)}}
+	}
+	if e.paged {
+		success["headers"] = map[string]interface{}{
+			"X-Next-Cursor": map[string]interface{}{"description": "Cursor of the next page, if there is one", "schema": map[string]interface{}{"type": "string"}},
+			"Link":          map[string]interface{}{"description": `URL of the next page, as rel="next"`, "schema": map[string]interface{}{"type": "string"}},
+		}
+	}
+	responses := map[string]interface{}{fmt.Sprint(status): success}
+
+	errors := e.errors
+	if e.scope != "" {
+		errors = append(errors, http.StatusUnauthorized, http.StatusForbidden)
+	}
+	errors = append(errors, http.StatusInternalServerError)
+	for _, code := range errors {
+		responses[fmt.Sprint(code)] = map[string]interface{}{
+			"description": http.StatusText(code),
+			"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": map[string]interface{}{"$ref": "#/components/schemas/Error"}}},
+		}
+	}
+	op["responses"] = responses
+
+	return op
+}
+
+// operationID names an operation after its method and path, e.g.
+// getItemsBySource for GET /api/items/{source} or postItemsBatch for
+// POST /api/items:batch
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, part := range strings.FieldsFunc(strings.TrimPrefix(path, Prefix), func(r rune) bool { return r == '/' || r == '.' || r == ':' }) {
+		if strings.HasPrefix(part, "{") {
+			id += "By"
+			part = strings.Trim(part, "{}")
+		}
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return id
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaOf describes how values of t encode as JSON. Named structs are
+// added to schemas and referred to.
+func schemaOf(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Ptr:
+		schema := schemaOf(t.Elem(), schemas)
+		if _, ok := schema["$ref"]; ok {
+			return map[string]interface{}{"allOf": []interface{}{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+		return schema
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaOf(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaOf(t.Elem(), schemas)}
+	case reflect.Struct:
+		name := schemaName(t)
+		if name != "" {
+			if _, ok := schemas[name]; !ok {
+				// Reserve the name first, for types that refer to themselves
+				schemas[name] = nil
+				schemas[name] = structSchema(t, schemas)
+			}
+			return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+		}
+		return structSchema(t, schemas)
+	}
+	return map[string]interface{}{}
+}
+
+// structSchema describes the JSON object a struct encodes as
+func structSchema(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = schemaOf(field.Type, schemas)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// schemaName names the schema of a struct type after it, without the
+// JSON suffix of response types: historyJSON is History. Anonymous
+// structs have no name and are described in place.
+func schemaName(t reflect.Type) string {
+	name := strings.TrimSuffix(t.Name(), "JSON")
+	if name == "" {
+		return ""
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// docsFiles are the API documentation page and its script and
+// stylesheet, which render the OpenAPI description without loading
+// anything from elsewhere
+//
+//go:embed docs
+var docsFiles embed.FS
+
+// docsPage is the documentation page, given where the description and
+// the assets are served
+var docsPage = template.Must(template.ParseFS(docsFiles, "docs/index.html"))
+
+// docsAssets serves the files of the documentation page under DocsPath
+var docsAssets = func() http.Handler {
+	files, err := fs.Sub(docsFiles, "docs")
+	if err != nil {
+		panic(err)
+	}
+	return http.StripPrefix(DocsPath+"/", http.FileServerFS(files))
+}()
+
+// handleDocs serves the API documentation page, which renders the OpenAPI
+// description with the script served next to it
+func (s *Server) handleDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'self'")
+	docsPage.Execute(w, struct{ Spec, Assets string }{SpecPath, DocsPath})
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"feedpulse/internal/storage"
+)
+
+// defaultPage is how many entries a list request returns by default
+const defaultPage = 100
+
+// Fields list requests may select with ?fields=
+var (
+	itemFields    = []string{"id", "title", "url", "source", "timestamp", "tags", "created_at", "read_at", "starred", "duplicate_of"}
+	historyFields = []string{"id", "source", "fetched_at", "status", "items", "error", "duration_ms", "endpoint", "slow"}
+)
+
+// parseLimit reads a limit parameter, def when it is empty
+func parseLimit(v string, def int) (int, error) {
+	if v == "" {
+		return def, nil
+	}
+	limit, err := strconv.Atoi(v)
+	if err != nil || limit < 1 || limit > maxItems {
+		return 0, fmt.Errorf("limit must be between 1 and %d", maxItems)
+	}
+	return limit, nil
+}
+
+// parseSort reads a sort parameter of a paginated list: newest (the
+// default) or oldest. Pages follow one another by time, so no other
+// order is offered.
+func parseSort(v string) (string, error) {
+	switch v {
+	case "", storage.SortNewest:
+		return storage.SortNewest, nil
+	case storage.SortOldest:
+		return storage.SortOldest, nil
+	}
+	return "", fmt.Errorf("sort must be %s or %s", storage.SortNewest, storage.SortOldest)
+}
+
+// parseFields reads a comma-separated fields parameter, checking each
+// field is one of allowed; nil means every field
+func parseFields(v string, allowed []string) ([]string, error) {
+	if v == "" {
+		return nil, nil
+	}
+	var fields []string
+	for _, field := range strings.Split(v, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		known := false
+		for _, a := range allowed {
+			known = known || a == field
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown field '%s'; fields are: %s", field, strings.Join(allowed, ", "))
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// encodeCursor makes the opaque cursor of the entry at t with id
+func encodeCursor(t time.Time, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(t.Unix(), 10) + ":" + id))
+}
+
+// decodeCursor reads a cursor made by encodeCursor
+func decodeCursor(cursor string) (time.Time, string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("invalid cursor")
+	}
+	secs, id, ok := strings.Cut(string(b), ":")
+	unix, err := strconv.ParseInt(secs, 10, 64)
+	if !ok || err != nil || id == "" {
+		return time.Time{}, "", fmt.Errorf("invalid cursor")
+	}
+	return time.Unix(unix, 0).UTC(), id, nil
+}
+
+// writePage answers r with list, a slice of JSON objects, narrowed to
+// fields if any are given. A next cursor is sent as X-Next-Cursor and as
+// a Link header to the following page.
+func writePage(w http.ResponseWriter, r *http.Request, list interface{}, fields []string, next string) {
+	if next != "" {
+		u, _ := url.Parse(selfURL(r))
+		q := u.Query()
+		q.Set("cursor", next)
+		u.RawQuery = q.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, u))
+		w.Header().Set("X-Next-Cursor", next)
+	}
+
+	if len(fields) > 0 {
+		selected, err := selectFields(list, fields)
+		if err != nil {
+			internalError(w, r, err)
+			return
+		}
+		list = selected
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// selectFields keeps only fields of each object in list
+func selectFields(list interface{}, fields []string) ([]map[string]json.RawMessage, error) {
+	b, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+	var objects []map[string]json.RawMessage
+	if err := json.Unmarshal(b, &objects); err != nil {
+		return nil, err
+	}
+
+	out := make([]map[string]json.RawMessage, len(objects))
+	for i, object := range objects {
+		out[i] = make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			if v, ok := object[field]; ok {
+				out[i][field] = v
+			}
+		}
+	}
+	return out, nil
+}
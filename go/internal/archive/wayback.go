@@ -0,0 +1,258 @@
+// Package archive submits item URLs to the Internet Archive's Wayback
+// Machine through its Save Page Now API, working through the queue of
+// URLs fetches put in the database.
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"feedpulse/internal/storage"
+)
+
+// DefaultEndpoint is the Wayback Machine
+const DefaultEndpoint = "https://web.archive.org"
+
+// MaxAttempts is how many failed requests a URL gets before it is marked
+// failed
+const MaxAttempts = 3
+
+// ErrRateLimited means Save Page Now refused a request for going too fast;
+// the queue should be left alone for a while
+var ErrRateLimited = errors.New("rate limited by Save Page Now")
+
+// Client talks to Save Page Now. With access keys (from
+// archive.org/account/s3.php) captures are queued as jobs and checked on
+// later; without, each capture is a single, slower anonymous request.
+type Client struct {
+	http      *http.Client
+	endpoint  string
+	accessKey string
+	secretKey string
+}
+
+// NewClient creates a client, anonymous if the keys are empty
+func NewClient(accessKey, secretKey string) *Client {
+	return &Client{
+		// Anonymous captures answer once the page is archived
+		http:      &http.Client{Timeout: 2 * time.Minute},
+		endpoint:  DefaultEndpoint,
+		accessKey: accessKey,
+		secretKey: secretKey,
+	}
+}
+
+// SetEndpoint points the client at another Wayback Machine, e.g. a test
+// server
+func (c *Client) SetEndpoint(endpoint string) {
+	c.endpoint = strings.TrimSuffix(endpoint, "/")
+}
+
+// Capture is what Save Page Now reported about a page
+type Capture struct {
+	// JobID identifies a capture still in progress
+	JobID string
+	// ArchivedURL is where the capture can be read, once it is done
+	ArchivedURL string
+}
+
+// spnResponse is Save Page Now's JSON answer to a capture or status request
+type spnResponse struct {
+	JobID       string `json:"job_id"`
+	Status      string `json:"status"`
+	StatusExt   string `json:"status_ext"`
+	Message     string `json:"message"`
+	Timestamp   string `json:"timestamp"`
+	OriginalURL string `json:"original_url"`
+}
+
+// Save asks Save Page Now to capture pageURL
+func (c *Client) Save(ctx context.Context, pageURL string) (Capture, error) {
+	if c.accessKey == "" {
+		return c.saveAnonymous(ctx, pageURL)
+	}
+
+	form := url.Values{}
+	form.Set("url", pageURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/save", strings.NewReader(form.Encode()))
+	if err != nil {
+		return Capture{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var resp spnResponse
+	if err := c.doJSON(req, &resp); err != nil {
+		return Capture{}, err
+	}
+	if resp.Status == "error" || resp.JobID == "" {
+		return Capture{}, spnError(resp)
+	}
+	return Capture{JobID: resp.JobID}, nil
+}
+
+// Status checks on the capture job jobID. The capture has no ArchivedURL
+// while the job is still running.
+func (c *Client) Status(ctx context.Context, jobID string) (Capture, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+"/save/status/"+url.PathEscape(jobID), nil)
+	if err != nil {
+		return Capture{}, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	var resp spnResponse
+	if err := c.doJSON(req, &resp); err != nil {
+		return Capture{}, err
+	}
+	switch resp.Status {
+	case "success":
+		return Capture{ArchivedURL: fmt.Sprintf("%s/web/%s/%s", c.endpoint, resp.Timestamp, resp.OriginalURL)}, nil
+	case "pending":
+		return Capture{JobID: jobID}, nil
+	default:
+		return Capture{}, spnError(resp)
+	}
+}
+
+// saveAnonymous captures pageURL without keys; Save Page Now answers
+// with (or redirects to) the capture once it is done
+func (c *Client) saveAnonymous(ctx context.Context, pageURL string) (Capture, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+"/save/"+pageURL, nil)
+	if err != nil {
+		return Capture{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "feedpulse/1.0")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return Capture{}, fmt.Errorf("save page now request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	if err := checkStatus(resp); err != nil {
+		return Capture{}, err
+	}
+
+	if loc := resp.Header.Get("Content-Location"); strings.HasPrefix(loc, "/web/") {
+		return Capture{ArchivedURL: c.endpoint + loc}, nil
+	}
+	if final := resp.Request.URL; strings.HasPrefix(final.Path, "/web/") {
+		return Capture{ArchivedURL: final.String()}, nil
+	}
+	return Capture{}, fmt.Errorf("save page now didn't return a capture for %s", pageURL)
+}
+
+// doJSON sends an authenticated request and decodes its JSON answer
+func (c *Client) doJSON(req *http.Request, v interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("LOW %s:%s", c.accessKey, c.secretKey))
+	req.Header.Set("User-Agent", "feedpulse/1.0")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("save page now request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkStatus(resp); err != nil {
+		return err
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v); err != nil {
+		return fmt.Errorf("invalid save page now response: %w", err)
+	}
+	return nil
+}
+
+// checkStatus turns an unsuccessful HTTP status into an error
+func checkStatus(resp *http.Response) error {
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return fmt.Errorf("save page now returned %s", resp.Status)
+	}
+	return nil
+}
+
+// spnError describes a capture Save Page Now gave up on
+func spnError(resp spnResponse) error {
+	if resp.StatusExt == "error:too-many-daily-captures" || resp.StatusExt == "error:user-session-limit" {
+		return ErrRateLimited
+	}
+	msg := resp.Message
+	if msg == "" {
+		msg = resp.StatusExt
+	}
+	if msg == "" {
+		msg = "capture failed"
+	}
+	return fmt.Errorf("save page now: %s", msg)
+}
+
+// Process advances up to limit queued URLs: pending ones are submitted,
+// submitted ones checked on. It waits interval between requests and stops
+// early if ctx is done or Save Page Now rate limits it (returning
+// ErrRateLimited). report, if set, is called with each entry's new state.
+func Process(ctx context.Context, store storage.Store, c *Client, limit int, interval time.Duration, report func(storage.ArchiveEntry)) (int, error) {
+	due, err := store.DueArchives(limit)
+	if err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for i, entry := range due {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return processed, ctx.Err()
+			case <-time.After(interval):
+			}
+		}
+
+		var capture Capture
+		if entry.Status == storage.ArchiveSubmitted {
+			capture, err = c.Status(ctx, entry.JobID)
+		} else {
+			capture, err = c.Save(ctx, entry.URL)
+		}
+
+		switch {
+		case errors.Is(err, ErrRateLimited), ctx.Err() != nil:
+			return processed, err
+		case err != nil:
+			entry.Attempts++
+			entry.LastError = err.Error()
+			entry.JobID = ""
+			entry.Status = storage.ArchivePending
+			if entry.Attempts >= MaxAttempts {
+				entry.Status = storage.ArchiveFailed
+			}
+		case capture.ArchivedURL != "":
+			entry.Status = storage.ArchiveDone
+			entry.ArchivedURL = capture.ArchivedURL
+			entry.JobID = ""
+			entry.LastError = ""
+		default:
+			if entry.Status == storage.ArchivePending {
+				entry.Attempts++
+			}
+			entry.Status = storage.ArchiveSubmitted
+			entry.JobID = capture.JobID
+		}
+
+		if err := store.UpdateArchive(entry); err != nil {
+			return processed, err
+		}
+		processed++
+		if report != nil {
+			report(entry)
+		}
+	}
+	return processed, nil
+}
@@ -0,0 +1,158 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"feedpulse/internal/storage"
+	"feedpulse/internal/testutil"
+)
+
+// fakeWayback serves a minimal Save Page Now: authenticated captures
+// become jobs that finish on their first status check
+func fakeWayback(t *testing.T) *httptest.Server {
+	t.Helper()
+	// A plain handler rather than a ServeMux, which would clean the
+	// "https://" inside anonymous capture paths
+	mux := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/save":
+			if r.Header.Get("Authorization") != "LOW key:secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			switch r.FormValue("url") {
+			case "https://example.com/limited":
+				w.WriteHeader(http.StatusTooManyRequests)
+			case "https://example.com/broken":
+				w.Write([]byte(`{"status":"error","status_ext":"error:not-found","message":"Page not found"}`))
+			default:
+				w.Write([]byte(`{"url":"` + r.FormValue("url") + `","job_id":"job-1"}`))
+			}
+		case strings.HasPrefix(r.URL.Path, "/save/status/"):
+			w.Write([]byte(`{"status":"success","job_id":"job-1","timestamp":"20260102030405","original_url":"https://example.com/a"}`))
+		case strings.HasPrefix(r.URL.Path, "/save/"):
+			target := strings.TrimPrefix(r.URL.Path, "/save/")
+			w.Header().Set("Location", "/web/20260102030405/"+target)
+			w.WriteHeader(http.StatusFound)
+		case strings.HasPrefix(r.URL.Path, "/web/"):
+			w.Write([]byte("archived page"))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClient_SaveAndStatus(t *testing.T) {
+	srv := fakeWayback(t)
+	c := NewClient("key", "secret")
+	c.SetEndpoint(srv.URL)
+
+	capture, err := c.Save(context.Background(), "https://example.com/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if capture.JobID != "job-1" || capture.ArchivedURL != "" {
+		t.Fatalf("unexpected capture: %+v", capture)
+	}
+
+	capture, err = c.Status(context.Background(), capture.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := srv.URL + "/web/20260102030405/https://example.com/a"; capture.ArchivedURL != want {
+		t.Errorf("archived URL = %q, want %q", capture.ArchivedURL, want)
+	}
+
+	if _, err := c.Save(context.Background(), "https://example.com/limited"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected ErrRateLimited, got %v", err)
+	}
+	if _, err := c.Save(context.Background(), "https://example.com/broken"); err == nil || !strings.Contains(err.Error(), "Page not found") {
+		t.Errorf("expected capture error, got %v", err)
+	}
+}
+
+func TestClient_SaveAnonymous(t *testing.T) {
+	srv := fakeWayback(t)
+	c := NewClient("", "")
+	c.SetEndpoint(srv.URL)
+
+	capture, err := c.Save(context.Background(), "https://example.com/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := srv.URL + "/web/20260102030405/https://example.com/a"; capture.ArchivedURL != want {
+		t.Errorf("archived URL = %q, want %q", capture.ArchivedURL, want)
+	}
+}
+
+func TestProcess(t *testing.T) {
+	srv := fakeWayback(t)
+	c := NewClient("key", "secret")
+	c.SetEndpoint(srv.URL)
+
+	store := testutil.NewMockStore()
+	if _, err := store.EnqueueArchive([]storage.FeedItem{
+		{ID: "1", URL: "https://example.com/a", Source: "HN"},
+		{ID: "2", URL: "https://example.com/broken", Source: "HN"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// First pass submits, second checks the job, then the broken URL
+	// keeps failing until it runs out of attempts
+	for i := 0; i < MaxAttempts; i++ {
+		if _, err := Process(context.Background(), store, c, 10, 0, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	done, err := store.GetArchive("https://example.com/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done.Status != storage.ArchiveDone || !strings.Contains(done.ArchivedURL, "/web/20260102030405/") {
+		t.Errorf("unexpected archived entry: %+v", done)
+	}
+
+	failed, err := store.GetArchive("https://example.com/broken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed.Status != storage.ArchiveFailed || failed.Attempts != MaxAttempts || failed.LastError == "" {
+		t.Errorf("unexpected failed entry: %+v", failed)
+	}
+
+	n, err := Process(context.Background(), store, c, 10, time.Millisecond, nil)
+	if err != nil || n != 0 {
+		t.Errorf("expected an empty queue, got %d, %v", n, err)
+	}
+}
+
+func TestProcess_StopsWhenRateLimited(t *testing.T) {
+	srv := fakeWayback(t)
+	c := NewClient("key", "secret")
+	c.SetEndpoint(srv.URL)
+
+	store := testutil.NewMockStore()
+	if _, err := store.EnqueueArchive([]storage.FeedItem{{ID: "1", URL: "https://example.com/limited", Source: "HN"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := Process(context.Background(), store, c, 10, 0, nil)
+	if !errors.Is(err, ErrRateLimited) || n != 0 {
+		t.Fatalf("expected rate limit stop, got %d, %v", n, err)
+	}
+	entry, _ := store.GetArchive("https://example.com/limited")
+	if entry.Status != storage.ArchivePending || entry.Attempts != 0 {
+		t.Errorf("rate-limited entry should be untouched: %+v", entry)
+	}
+}
@@ -1,26 +1,73 @@
 package cli
 
 import (
+	"bufio"
 	"context"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"os/user"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
+	"unicode/utf8"
 
+	"feedpulse/internal/api"
+	"feedpulse/internal/archive"
+	"feedpulse/internal/clock"
 	"feedpulse/internal/config"
+	"feedpulse/internal/discover"
 	"feedpulse/internal/fetcher"
+	"feedpulse/internal/notify"
+	"feedpulse/internal/readlater"
+	"feedpulse/internal/redact"
+	"feedpulse/internal/sentry"
 	"feedpulse/internal/storage"
+	"feedpulse/internal/syndication"
+	"feedpulse/internal/websub"
 
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 )
 
+// Build metadata, injected at build time with
+// -ldflags "-X feedpulse/internal/cli.version=... -X ...commit=... -X ...buildDate=...".
+// commit and buildDate fall back to the VCS revision and commit time Go
+// embeds in the binary.
 var (
 	configPath string
-	version    = "1.0.0"
+	// displayZone is the time zone times are printed in, from the
+	// config's timezone setting once it is loaded; read it with
+	// currentZone, since a daemon reload swaps it while workers run
+	displayZone atomic.Pointer[time.Location]
+	// absoluteTimes makes tables print timestamps as dates instead of
+	// relative to now
+	absoluteTimes bool
+	// notifyDryRun makes notifiers print their messages instead of
+	// sending them
+	notifyDryRun bool
+	// commandName is the command being run, e.g. "feedpulse fetch", for
+	// error reports
+	commandName string
+	// redactor masks secrets in what is printed, from the config's redact
+	// settings once it is loaded; read it with currentRedactor
+	redactor  atomic.Pointer[redact.Redactor]
+	version   = "1.0.0"
+	commit    = ""
+	buildDate = ""
 )
 
 // NewRootCmd creates the root command
@@ -30,26 +77,94 @@ func NewRootCmd() *cobra.Command {
 		Short:   "Concurrent feed aggregator CLI",
 		Version: version,
 		Long:    `feedpulse fetches multiple data feeds, validates and normalizes the data, stores results in SQLite, and generates summary reports.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			commandName = cmd.CommandPath()
+		},
 	}
 
 	rootCmd.PersistentFlags().StringVar(&configPath, "config", "config.yaml", "path to config file")
+	rootCmd.PersistentFlags().BoolVar(&absoluteTimes, "absolute", false, "show timestamps in tables as dates instead of e.g. '3h ago'")
+	rootCmd.PersistentFlags().BoolVar(&notifyDryRun, "notify-dry-run", false, "print notifications instead of sending them")
 
 	rootCmd.AddCommand(newFetchCmd())
 	rootCmd.AddCommand(newReportCmd())
 	rootCmd.AddCommand(newSourcesCmd())
+	rootCmd.AddCommand(newRecoverCmd())
+	rootCmd.AddCommand(newTrendCmd())
+	rootCmd.AddCommand(newBlockCmd())
+	rootCmd.AddCommand(newUnblockCmd())
+	rootCmd.AddCommand(newItemsCmd())
+	rootCmd.AddCommand(newBackfillCmd())
+	rootCmd.AddCommand(newLoginCmd())
+	rootCmd.AddCommand(newServeCmd())
+	rootCmd.AddCommand(newDaemonCmd())
+	rootCmd.AddCommand(newDigestCmd())
+	rootCmd.AddCommand(newStatsCmd())
+	rootCmd.AddCommand(newVersionCmd())
+	rootCmd.AddCommand(newInitCmd())
+	rootCmd.AddCommand(newPresetsCmd())
+	rootCmd.AddCommand(newTestFeedCmd())
+	rootCmd.AddCommand(newDiscoverCmd())
+	rootCmd.AddCommand(newExplainCmd())
+	rootCmd.AddCommand(newDiffCmd())
+	rootCmd.AddCommand(newFrontPageCmd())
+	rootCmd.AddCommand(newPublishCmd())
+	rootCmd.AddCommand(newTokenCmd())
+	rootCmd.AddCommand(newAuditCmd())
+	rootCmd.AddCommand(newDoctorCmd())
+	rootCmd.AddCommand(newDBCmd())
+	rootCmd.AddCommand(newArchiveCmd())
+	rootCmd.AddCommand(newSaveCmd())
+	rootCmd.AddCommand(newNotifyCmd())
+	rootCmd.AddCommand(newFailuresCmd())
+	rootCmd.AddCommand(newErrorsCmd())
+	rootCmd.AddCommand(newExportCmd())
+	rootCmd.AddCommand(newDebugCmd())
 
 	return rootCmd
 }
 
 // newFetchCmd creates the fetch command
 func newFetchCmd() *cobra.Command {
-	return &cobra.Command{
+	var backfillAsOf string
+	var full, dryRun bool
+
+	cmd := &cobra.Command{
 		Use:   "fetch",
 		Short: "Fetch all feeds and store results",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runFetch()
+			clk, err := parseAsOf(backfillAsOf)
+			if err != nil {
+				return err
+			}
+			if dryRun {
+				if full {
+					return fmt.Errorf("--dry-run and --full can't be combined (a dry run always fetches in full)")
+				}
+				return runFetchDryRun(clk)
+			}
+			return runFetch(clk, full)
 		},
 	}
+
+	cmd.Flags().StringVar(&backfillAsOf, "backfill-as-of", "", "stamp fetched items and logs with this time instead of now (RFC3339 or YYYY-MM-DD)")
+	cmd.Flags().BoolVar(&full, "full", false, "discard stored feed state (incremental cursors, response hashes) and fetch and parse every feed from its configured URL")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "fetch and parse every feed and show what would be stored, without saving items or logging fetches")
+
+	return cmd
+}
+
+// parseAsOf returns the system clock, or a clock fixed at asOf if set
+func parseAsOf(asOf string) (clock.Clock, error) {
+	if asOf == "" {
+		return clock.System, nil
+	}
+
+	if t, err := parseDate(asOf); err == nil {
+		return clock.Fixed(t), nil
+	}
+
+	return nil, fmt.Errorf("invalid --backfill-as-of: %s (expected RFC3339 or YYYY-MM-DD)", asOf)
 }
 
 // newReportCmd creates the report command
@@ -57,322 +172,6341 @@ func newReportCmd() *cobra.Command {
 	var format string
 	var sourceName string
 	var since string
+	var exact bool
+	var diff bool
+	var sample int
+	var columns string
+	var by string
 
 	cmd := &cobra.Command{
 		Use:   "report",
 		Short: "Generate summary report",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runReport(format, sourceName, since)
+			return runReport(format, sourceName, since, exact, diff, sample, columns, by)
 		},
 	}
 
 	cmd.Flags().StringVar(&format, "format", "table", "output format (table, json, csv)")
 	cmd.Flags().StringVar(&sourceName, "source", "", "filter by source name")
-	cmd.Flags().StringVar(&since, "since", "", "filter items newer than (e.g., '24h', '7d')")
+	cmd.Flags().StringVar(&since, "since", "", "with --by tag, only count items stored within this window (e.g., '24h', '7d')")
+	cmd.Flags().BoolVar(&exact, "exact", false, "compute stats from the full fetch log instead of the materialized summary")
+	cmd.Flags().BoolVar(&diff, "diff", false, "show only what changed since the previous report")
+	cmd.Flags().IntVar(&sample, "sample", 0, "list the N most recent item titles under each source")
+	cmd.Flags().StringVar(&columns, "columns", "", fmt.Sprintf("comma-separated table columns (%s)", strings.Join(config.TableColumns["report"], ", ")))
+	cmd.Flags().StringVar(&by, "by", reportBySource, "aggregate by source, group (the feeds' group setting) or tag (item tags)")
 
 	return cmd
 }
 
 // newSourcesCmd creates the sources command
 func newSourcesCmd() *cobra.Command {
-	return &cobra.Command{
+	var format string
+
+	cmd := &cobra.Command{
 		Use:   "sources",
 		Short: "List configured sources and their status",
+		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runSources()
+			return runSources(format)
 		},
 	}
-}
-
-// runFetch executes the fetch command
-func runFetch() error {
-	// Load config
-	cfg, err := config.LoadConfig(configPath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		return fmt.Errorf("config error")
-	}
 
-	// Open database
-	store, err := storage.NewStorage(cfg.Settings.DatabasePath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: failed to open database: %v\n", err)
-		return fmt.Errorf("database error")
-	}
-	defer store.Close()
+	cmd.Flags().StringVar(&format, "format", "table", "output format (table, json, csv)")
 
-	// Set up context with cancellation
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	cmd.AddCommand(newSourcesAddCmd())
+	cmd.AddCommand(newSourcesRemoveCmd())
+	cmd.AddCommand(newSourcesEditCmd())
 
-	// Handle Ctrl+C
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	go func() {
-		<-sigChan
-		fmt.Fprintf(os.Stderr, "\nCancelling...\n")
-		cancel()
-	}()
+	return cmd
+}
 
-	// Fetch feeds
-	fmt.Printf("Fetching %d feeds (max concurrency: %d)...\n", len(cfg.Feeds), cfg.Settings.MaxConcurrency)
+// sourceFlags are the feed fields sources add and edit can set
+type sourceFlags struct {
+	url      string
+	feedType string
+	interval int
+	group    string
+}
 
-	f := fetcher.NewFetcher(cfg)
-	results := f.FetchAll(ctx)
+// register adds the flags to cmd
+func (f *sourceFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.url, "url", "", "feed URL")
+	cmd.Flags().StringVar(&f.feedType, "type", "", "feed type (json, ndjson, xml, rss, atom, plugin)")
+	cmd.Flags().IntVar(&f.interval, "interval", 0, "refresh interval in seconds (0 for the default)")
+	cmd.Flags().StringVar(&f.group, "group", "", "group the feed is reported with")
+}
 
-	// Process results
-	successCount := 0
-	errorCount := 0
-	totalItems := 0
-	totalNew := 0
+// apply sets the fields of feed whose flags were given on cmd
+func (f *sourceFlags) apply(cmd *cobra.Command, feed *config.Feed) {
+	if cmd.Flags().Changed("url") {
+		feed.URL = f.url
+	}
+	if cmd.Flags().Changed("type") {
+		feed.FeedType = f.feedType
+	}
+	if cmd.Flags().Changed("interval") {
+		feed.RefreshIntervalSecs = f.interval
+	}
+	if cmd.Flags().Changed("group") {
+		feed.Group = f.group
+	}
+}
 
-	for _, result := range results {
-		if result.Success {
-			successCount++
-			totalItems += result.ItemsCount
+// newSourcesAddCmd creates the sources add command
+func newSourcesAddCmd() *cobra.Command {
+	var name string
+	var flags sourceFlags
 
-			// Get existing count before saving
-			existingCount, err := store.GetItemCount(result.Source)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to get existing count for %s: %v\n", result.Source, err)
-			}
-
-			// Save items
-			if len(result.Items) > 0 {
-				if err := store.SaveItems(result.Items); err != nil {
-					fmt.Fprintf(os.Stderr, "Warning: failed to save items for %s: %v\n", result.Source, err)
-				} else {
-					// Calculate new items
-					newCount, _ := store.GetItemCount(result.Source)
-					result.NewItems = newCount - existingCount
-					totalNew += result.NewItems
-				}
-			}
+	cmd := &cobra.Command{
+		Use:   "add",
+		Short: "Add a feed to the config file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			feed := config.Feed{Name: name}
+			flags.apply(cmd, &feed)
+			return runSourcesAdd(feed)
+		},
+	}
 
-			// Log success
-			if err := store.LogFetch(storage.FetchLog{
-				Source:     result.Source,
-				FetchedAt:  time.Now(),
-				Status:     "success",
-				ItemsCount: result.ItemsCount,
-				DurationMs: result.DurationMs,
-			}); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to log fetch for %s: %v\n", result.Source, err)
-			}
+	cmd.Flags().StringVar(&name, "name", "", "feed name")
+	flags.register(cmd)
+	cmd.MarkFlagRequired("name")
+	cmd.MarkFlagRequired("url")
+	cmd.MarkFlagRequired("type")
 
-			fmt.Printf("  ✓ %-30s — %d items (%d new) in %dms\n", result.Source, result.ItemsCount, result.NewItems, result.DurationMs)
-		} else {
-			errorCount++
-
-			// Log error
-			if err := store.LogFetch(storage.FetchLog{
-				Source:       result.Source,
-				FetchedAt:    time.Now(),
-				Status:       "error",
-				ErrorMessage: &result.Error,
-				DurationMs:   result.DurationMs,
-			}); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to log fetch for %s: %v\n", result.Source, err)
-			}
+	return cmd
+}
 
-			fmt.Printf("  ✗ %-30s — error: %s\n", result.Source, result.Error)
-		}
+// newSourcesRemoveCmd creates the sources remove command
+func newSourcesRemoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove <name>",
+		Short: "Remove a feed from the config file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSourcesRemove(args[0])
+		},
 	}
+}
+
+// newSourcesEditCmd creates the sources edit command
+func newSourcesEditCmd() *cobra.Command {
+	var flags sourceFlags
 
-	fmt.Printf("\nDone: %d/%d succeeded, %d items (%d new)", successCount, len(results), totalItems, totalNew)
-	if errorCount > 0 {
-		fmt.Printf(", %d error(s)", errorCount)
+	cmd := &cobra.Command{
+		Use:   "edit <name>",
+		Short: "Change a feed in the config file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSourcesEdit(args[0], func(feed *config.Feed) {
+				flags.apply(cmd, feed)
+			})
+		},
 	}
-	fmt.Println()
 
-	return nil
+	flags.register(cmd)
+
+	return cmd
 }
 
-// runReport executes the report command
-func runReport(format, sourceName, since string) error {
-	// Load config
-	cfg, err := config.LoadConfig(configPath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		return fmt.Errorf("config error")
+// newRecoverCmd creates the recover command
+func newRecoverCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "recover",
+		Short: "Replay journaled payloads left unprocessed by an interrupted fetch",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRecover()
+		},
 	}
+}
 
-	// Open database
-	store, err := storage.NewStorage(cfg.Settings.DatabasePath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: failed to open database: %v\n", err)
-		return fmt.Errorf("database error")
-	}
-	defer store.Close()
+// newTrendCmd creates the trend command
+func newTrendCmd() *cobra.Command {
+	var bucket string
+	var window string
+	var sourceName string
 
-	// Get stats
-	stats, err := store.GetFetchStats()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: failed to get stats: %v\n", err)
-		return fmt.Errorf("stats error")
+	cmd := &cobra.Command{
+		Use:   "trend",
+		Short: "Show fetch activity per hour or day",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTrend(bucket, window, sourceName)
+		},
 	}
 
-	// Filter by source if requested
-	if sourceName != "" {
-		var filtered []storage.FetchStats
-		for _, stat := range stats {
-			if stat.Source == sourceName {
-				filtered = append(filtered, stat)
+	cmd.Flags().StringVar(&bucket, "bucket", storage.BucketHour, "bucket size, aligned to UTC (hour, day)")
+	cmd.Flags().StringVar(&window, "window", "24h", "how far back to look (e.g., '24h', '7d')")
+	cmd.Flags().StringVar(&sourceName, "source", "", "filter by source name")
+
+	return cmd
+}
+
+// newBlockCmd creates the block command
+func newBlockCmd() *cobra.Command {
+	var list bool
+
+	cmd := &cobra.Command{
+		Use:   "block [url|id|pattern]",
+		Short: "Never store an item ID, URL, or URL pattern (with '*') again",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if list {
+				return cobra.NoArgs(cmd, args)
 			}
-		}
-		stats = filtered
+			return cobra.ExactArgs(1)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if list {
+				return runBlockList()
+			}
+			return runBlock(args[0])
+		},
 	}
 
-	// Get total items count
-	totalItems, err := store.GetAllItemsCount()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: failed to get total items: %v\n", err)
-	}
+	cmd.Flags().BoolVar(&list, "list", false, "list blocked entries")
 
-	// Output based on format
-	switch format {
-	case "json":
-		return outputJSON(stats, totalItems)
-	case "csv":
-		return outputCSV(stats)
-	case "table":
-		return outputTable(stats, totalItems)
-	default:
-		return fmt.Errorf("unknown format: %s", format)
-	}
+	return cmd
 }
 
-// runSources executes the sources command
-func runSources() error {
-	// Load config
-	cfg, err := config.LoadConfig(configPath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		return fmt.Errorf("config error")
+// newUnblockCmd creates the unblock command
+func newUnblockCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "unblock <url|id|pattern>",
+		Short: "Remove an entry from the blocklist",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runUnblock(args[0])
+		},
 	}
+}
 
-	// Open database
-	store, err := storage.NewStorage(cfg.Settings.DatabasePath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: failed to open database: %v\n", err)
-		return fmt.Errorf("database error")
-	}
-	defer store.Close()
+// newItemsCmd creates the items command
+func newItemsCmd() *cobra.Command {
+	var history string
+	var filter itemsFlags
 
-	// Get stats
-	stats, err := store.GetFetchStats()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: failed to get stats: %v\n", err)
-		return fmt.Errorf("stats error")
+	cmd := &cobra.Command{
+		Use:   "items",
+		Short: "List, inspect and change stored items",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if history != "" {
+				return runItemHistory(history)
+			}
+			if filter.markRead || filter.star || filter.addTag != "" || filter.delete {
+				return runItemsBatch(filter)
+			}
+			return runItems(filter)
+		},
 	}
 
-	// Create a map for quick lookup
-	statsMap := make(map[string]storage.FetchStats)
-	for _, stat := range stats {
-		statsMap[stat.Source] = stat
-	}
+	cmd.Flags().StringVar(&history, "history", "", "show title/URL changes recorded for an item ID instead of listing items")
+	cmd.Flags().StringVar(&filter.source, "source", "", "only items from this source")
+	cmd.Flags().StringVar(&filter.tag, "tag", "", "only items with this tag")
+	cmd.Flags().StringVar(&filter.since, "since", "", "only items stored within this window (e.g., '24h', '7d')")
+	cmd.Flags().StringVar(&filter.until, "until", "", "only items stored before this long ago (e.g., '1h', '2d')")
+	cmd.Flags().IntVar(&filter.limit, "limit", 50, "maximum number of items (0 for all)")
+	cmd.Flags().IntVar(&filter.offset, "offset", 0, "skip this many items, to page through them")
+	cmd.Flags().StringVar(&filter.sort, "sort", storage.SortNewest, fmt.Sprintf("order (%s)", strings.Join(storage.ItemSorts, ", ")))
+	cmd.Flags().StringVar(&filter.format, "format", "table", "output format (table, json)")
+	cmd.Flags().StringVar(&filter.columns, "columns", "", fmt.Sprintf("comma-separated table columns (%s)", strings.Join(config.TableColumns["items"], ", ")))
+	cmd.Flags().StringVar(&filter.before, "before", "", "only items stored before this date (RFC3339 or YYYY-MM-DD)")
+	cmd.Flags().BoolVar(&filter.markRead, "mark-read", false, "mark the matching items read instead of listing them")
+	cmd.Flags().BoolVar(&filter.star, "star", false, "star the matching items instead of listing them")
+	cmd.Flags().StringVar(&filter.addTag, "add-tag", "", "add this tag to the matching items instead of listing them")
+	cmd.Flags().BoolVar(&filter.delete, "delete", false, "delete the matching items instead of listing them")
 
-	// Display configured sources
-	table := tablewriter.NewWriter(os.Stdout)
-	table.Header("Source", "URL", "Type", "Status")
+	return cmd
+}
 
-	for _, feed := range cfg.Feeds {
-		status := "never fetched"
-		if stat, ok := statsMap[feed.Name]; ok {
-			if stat.LastSuccess != nil {
-				status = "✓ active"
-			} else {
-				status = "✗ failing"
-			}
-		}
+// itemsFlags holds the items command's listing flags, and the bulk
+// action to take on the items instead, if any
+type itemsFlags struct {
+	source, tag  string
+	since, until string
+	before       string
+	limit        int
+	offset       int
+	sort         string
+	format       string
+	columns      string
 
-		table.Append(feed.Name, feed.URL, feed.FeedType, status)
-	}
+	markRead, star, delete bool
+	addTag                 string
+}
 
-	table.Render()
+// window narrows filter to the items stored within the flags' since,
+// until and before
+func (flags itemsFlags) window(filter *storage.ItemFilter) error {
+	now := time.Now()
+	if flags.since != "" {
+		window, err := parseWindow(flags.since)
+		if err != nil {
+			return err
+		}
+		filter.Since = now.Add(-window)
+	}
+	if flags.until != "" {
+		window, err := parseWindow(flags.until)
+		if err != nil {
+			return err
+		}
+		filter.Until = now.Add(-window)
+	}
+	if flags.before != "" {
+		before, err := parseDate(flags.before)
+		if err != nil {
+			return fmt.Errorf("invalid --before: %s (expected RFC3339 or YYYY-MM-DD)", flags.before)
+		}
+		if filter.Until.IsZero() || before.Before(filter.Until) {
+			filter.Until = before
+		}
+	}
 	return nil
 }
 
-// outputTable outputs stats in table format
-func outputTable(stats []storage.FetchStats, totalItems int) error {
-	table := tablewriter.NewWriter(os.Stdout)
-	table.Header("Source", "Items", "Errors", "Error Rate", "Last Success")
-
-	for _, stat := range stats {
-		errorRate := "0.0%"
-		if stat.TotalFetches > 0 {
-			rate := float64(stat.ErrorCount) / float64(stat.TotalFetches) * 100
-			errorRate = fmt.Sprintf("%.1f%%", rate)
+// parseDate reads an RFC3339 time or a YYYY-MM-DD date
+func parseDate(v string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
 		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date: %s", v)
+}
 
-		lastSuccess := "never"
-		if stat.LastSuccess != nil {
-			// Parse and format timestamp
-			if t, err := time.Parse(time.RFC3339, *stat.LastSuccess); err == nil {
-				lastSuccess = t.Format("2006-01-02 15:04")
-			} else {
-				lastSuccess = *stat.LastSuccess
-			}
-		}
+// newExportCmd creates the export command
+func newExportCmd() *cobra.Command {
+	var format, source, tag, since, output string
 
-		table.Append(
-			stat.Source,
-			fmt.Sprintf("%d", stat.ItemsCount),
-			fmt.Sprintf("%d", stat.ErrorCount),
-			errorRate,
-			lastSuccess,
-		)
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Dump stored items, with all their fields, for other tools",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runExport(format, source, tag, since, output)
+		},
 	}
 
-	table.Render()
-	fmt.Printf("\nTotal: %d items across %d sources\n", totalItems, len(stats))
-	return nil
+	cmd.Flags().StringVar(&format, "format", "jsonl", "output format (jsonl, csv, markdown, rss, atom)")
+	cmd.Flags().StringVar(&source, "source", "", "only items from this source")
+	cmd.Flags().StringVar(&tag, "tag", "", "only items with this tag")
+	cmd.Flags().StringVar(&since, "since", "", "only items stored within this window (e.g., '24h', '7d')")
+	cmd.Flags().StringVar(&output, "out", "", "file to write (default: stdout)")
+
+	return cmd
 }
 
-// outputJSON outputs stats in JSON format
-func outputJSON(stats []storage.FetchStats, totalItems int) error {
-	output := map[string]interface{}{
-		"sources":     stats,
-		"total_items": totalItems,
+// newDebugCmd creates the debug command
+func newDebugCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "debug",
+		Short: "Tools for reporting problems with a feed's API",
 	}
 
-	encoder := json.NewEncoder(os.Stdout)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(output)
+	var feed, output string
+	var maxBody int
+	proxy := &cobra.Command{
+		Use:   "proxy",
+		Short: "Fetch a feed once, writing every request and response to a file",
+		Long: `debug proxy fetches a configured feed once, as fetch would, and writes each
+HTTP exchange to a file: the full request, and the response's headers and body
+cut to --max-body bytes. Secret headers, query parameters and configured
+credentials are masked, so the file can be attached to a bug report. No items
+are saved.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDebugProxy(feed, output, maxBody)
+		},
+	}
+	proxy.Flags().StringVar(&feed, "feed", "", "name of the feed to fetch")
+	proxy.Flags().StringVar(&output, "out", "feedpulse-debug.txt", "file to write")
+	proxy.Flags().IntVar(&maxBody, "max-body", fetcher.DefaultCaptureBytes, "bytes of each body to keep")
+	proxy.MarkFlagRequired("feed")
+	cmd.AddCommand(proxy)
+
+	return cmd
 }
 
-// outputCSV outputs stats in CSV format
-func outputCSV(stats []storage.FetchStats) error {
-	writer := csv.NewWriter(os.Stdout)
-	defer writer.Flush()
+// newBackfillCmd creates the backfill command
+func newBackfillCmd() *cobra.Command {
+	var pages int
+	var restart bool
 
-	// Write header
-	if err := writer.Write([]string{"Source", "Items", "Errors", "Error Rate", "Last Success"}); err != nil {
-		return err
+	cmd := &cobra.Command{
+		Use:   "backfill <feed>",
+		Short: "Walk a feed's history to load older items",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runBackfill(clock.System, args[0], pages, restart)
+		},
 	}
 
-	// Write rows
-	for _, stat := range stats {
-		errorRate := "0.0"
-		if stat.TotalFetches > 0 {
-			errorRate = fmt.Sprintf("%.1f", float64(stat.ErrorCount)/float64(stat.TotalFetches)*100)
-		}
-
-		lastSuccess := "never"
-		if stat.LastSuccess != nil {
-			lastSuccess = *stat.LastSuccess
-		}
+	cmd.Flags().IntVar(&pages, "pages", 10, "maximum number of pages to fetch")
+	cmd.Flags().BoolVar(&restart, "restart", false, "ignore stored progress and start from the first page")
 
+	return cmd
+}
+
+// newLoginCmd creates the login command
+func newLoginCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "login <feed>",
+		Short: "Send a feed's login request and store its session cookies",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLogin(args[0])
+		},
+	}
+}
+
+// newServeCmd creates the serve command
+func newServeCmd() *cobra.Command {
+	var listen string
+	var callback string
+	var apiOn bool
+	var apiCache string
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Consume stream feeds, fetch feeds as soon as their WebSub hub pushes an update, and serve the HTTP API",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runServe(listen, callback, apiOn, apiCache)
+		},
+	}
+
+	cmd.Flags().StringVar(&listen, "listen", ":8080", "address to serve WebSub callbacks and the API on")
+	cmd.Flags().StringVar(&callback, "callback", "", "public base URL of the WebSub callbacks, e.g. https://feeds.example.com/websub; WebSub is off without it")
+	cmd.Flags().BoolVar(&apiOn, "api", false, "serve the HTTP API under /api/, authenticated with tokens from 'feedpulse token create'")
+	cmd.Flags().StringVar(&apiCache, "api-cache", "30s", "how long the API may answer repeated stats, items and feed requests from memory (0 to always query the database)")
+
+	return cmd
+}
+
+// newDigestCmd creates the digest command
+func newDigestCmd() *cobra.Command {
+	var since string
+	var top int
+	var format string
+	var notifyTo string
+	var templatePath string
+
+	cmd := &cobra.Command{
+		Use:   "digest",
+		Short: "Show the top recent items across sources, grouped by topic, source or score",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDigest(since, top, format, notifyTo, templatePath)
+		},
+	}
+
+	cmd.Flags().StringVar(&since, "since", "24h", "include items stored within this window (e.g., '24h', '7d')")
+	cmd.Flags().IntVar(&top, "top", 20, "number of items to include")
+	cmd.Flags().StringVar(&format, "format", "table", "output format (table, markdown, html)")
+	cmd.Flags().StringVar(&notifyTo, "notify", "", "post the digest to a configured notifier (matrix, telegram, xmpp, smtp) instead of printing it")
+	cmd.Flags().StringVar(&templatePath, "template", "", "print the digest rendered with this Go template file instead of --format")
+
+	return cmd
+}
+
+// newStatsCmd creates the stats command
+func newStatsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show statistics about stored items",
+	}
+
+	cmd.AddCommand(newStatsActivityCmd())
+
+	return cmd
+}
+
+// newStatsActivityCmd creates the stats activity command
+func newStatsActivityCmd() *cobra.Command {
+	var sourceName string
+	var days int
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "activity",
+		Short: "Show a histogram of items published per day",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStatsActivity(sourceName, days, format)
+		},
+	}
+
+	cmd.Flags().StringVar(&sourceName, "source", "", "filter by source name")
+	cmd.Flags().IntVar(&days, "days", 30, "number of days to show, ending today (UTC)")
+	cmd.Flags().StringVar(&format, "format", "table", "output format (table, json)")
+
+	return cmd
+}
+
+// newVersionCmd creates the version command
+func newVersionCmd() *cobra.Command {
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Show version and build information",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runVersion(asJSON)
+		},
+	}
+
+	cmd.Flags().BoolVar(&asJSON, "json", false, "output as JSON")
+
+	return cmd
+}
+
+// newInitCmd creates the init command
+func newInitCmd() *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Create a config file interactively",
+		Long: `init asks which preset sources to follow, where to keep the database and how many
+feeds to fetch at once, then writes a validated config to --config.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runInit(os.Stdin, force)
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "overwrite an existing config file")
+
+	return cmd
+}
+
+// newPresetsCmd creates the presets command
+func newPresetsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "presets",
+		Short: "Browse and add built-in source presets",
+	}
+
+	cmd.AddCommand(newPresetsListCmd())
+	cmd.AddCommand(newPresetsAddCmd())
+
+	return cmd
+}
+
+// newPresetsListCmd creates the presets list command
+func newPresetsListCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the built-in source presets",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPresetsList(format)
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "table", "output format (table, json)")
+
+	return cmd
+}
+
+// newPresetsAddCmd creates the presets add command
+func newPresetsAddCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "add <preset>...",
+		Short: "Add preset sources to the config file",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPresetsAdd(args)
+		},
+	}
+}
+
+// newTestFeedCmd creates the test-feed command
+func newTestFeedCmd() *cobra.Command {
+	var feedType string
+
+	cmd := &cobra.Command{
+		Use:   "test-feed <name|url>",
+		Short: "Fetch a feed once and preview what it parses to",
+		Long: `test-feed requests a configured feed (or any URL) once and prints the HTTP
+response details, the structure the parser detected, the first 5 normalized
+items and any parse warnings. Nothing is written to the database.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTestFeed(args[0], feedType)
+		},
+	}
+
+	cmd.Flags().StringVar(&feedType, "type", "json", "feed type when testing a URL that isn't configured")
+
+	return cmd
+}
+
+// newDiscoverCmd creates the discover command
+func newDiscoverCmd() *cobra.Command {
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "discover <url>",
+		Short: "Find the feeds a website advertises and add them to the config",
+		Long: `discover fetches a web page, finds the RSS, Atom and JSON Feed endpoints its
+<link rel="alternate"> tags point to, and fetches each once to check that it
+parses. It then offers to append the ones that do to --config.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDiscover(os.Stdin, args[0], yes)
+		},
+	}
+
+	cmd.Flags().BoolVar(&yes, "yes", false, "add the first feed that parses without asking")
+
+	return cmd
+}
+
+// newExplainCmd creates the explain command
+func newExplainCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "explain <item-id>",
+		Short: "Show where a stored item came from",
+		Long: `explain prints an item's provenance: the feed and fetch that stored it, how its
+ID was derived and which other items share its URL, whether the blocklist
+matches it, its recorded title/URL changes and the raw payload around it
+when one was kept.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runExplain(args[0])
+		},
+	}
+}
+
+// newDiffCmd creates the diff command
+func newDiffCmd() *cobra.Command {
+	var sourceName string
+	var runs string
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Show items added, removed and changed between two fetches of a source",
+		Long: `diff compares the items two fetch runs of a source saw. Runs are named "last",
+"previous", "last~N" (N runs before the last) or by fetch log ID; the older
+of the two is the baseline. Item lists are kept for each source's last 20 runs.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if sourceName == "" {
+				return fmt.Errorf("--source <name> is required")
+			}
+			return runDiff(sourceName, runs, format)
+		},
+	}
+
+	cmd.Flags().StringVar(&sourceName, "source", "", "source to compare runs of")
+	cmd.Flags().StringVar(&runs, "runs", "last,previous", "the two runs to compare")
+	cmd.Flags().StringVar(&format, "format", "table", "output format (table, json)")
+
+	return cmd
+}
+
+// newFrontPageCmd creates the frontpage command
+func newFrontPageCmd() *cobra.Command {
+	var since string
+	var top int
+
+	cmd := &cobra.Command{
+		Use:   "frontpage",
+		Short: "Show and curate the front page: the digest ranking with pinned and hidden items",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runFrontPageShow(since, top)
+		},
+	}
+
+	cmd.Flags().StringVar(&since, "since", "24h", "rank items stored within this window (e.g., '24h', '7d')")
+	cmd.Flags().IntVar(&top, "top", 20, "number of items on the page, pins included")
+
+	cmd.AddCommand(newFrontPagePinCmd())
+	cmd.AddCommand(&cobra.Command{
+		Use:   "hide <item-id>",
+		Short: "Keep an item, and any item sharing its URL, off the front page",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runFrontPageCurate(args[0], storage.CurationHidden, 0)
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "reset <item-id>",
+		Short: "Unpin or unhide an item",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runFrontPageReset(args[0])
+		},
+	})
+
+	return cmd
+}
+
+// newFrontPagePinCmd creates the frontpage pin command
+func newFrontPagePinCmd() *cobra.Command {
+	var at int
+
+	cmd := &cobra.Command{
+		Use:   "pin <item-id>",
+		Short: "Pin an item to the front page; pinning a pinned item again moves it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runFrontPageCurate(args[0], storage.CurationPinned, at)
+		},
+	}
+
+	cmd.Flags().IntVar(&at, "at", 0, "position among the pins, 1 being the top (default: below the last pin)")
+
+	return cmd
+}
+
+// newPublishCmd creates the publish command
+func newPublishCmd() *cobra.Command {
+	var since string
+	var top int
+	var format string
+	var output string
+	var title string
+
+	cmd := &cobra.Command{
+		Use:   "publish",
+		Short: "Write the curated front page as HTML, Markdown or JSON",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPublish(since, top, format, output, title)
+		},
+	}
+
+	cmd.Flags().StringVar(&since, "since", "24h", "rank items stored within this window (e.g., '24h', '7d')")
+	cmd.Flags().IntVar(&top, "top", 20, "number of items on the page, pins included")
+	cmd.Flags().StringVar(&format, "format", "html", "output format (html, markdown, json)")
+	cmd.Flags().StringVar(&output, "output", "", "file to write (default: stdout)")
+	cmd.Flags().StringVar(&title, "title", "Front page", "page title")
+
+	return cmd
+}
+
+// newTokenCmd creates the token command
+func newTokenCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "token",
+		Short: "Manage the bearer tokens the serve API accepts",
+	}
+
+	cmd.AddCommand(newTokenCreateCmd())
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List API tokens (the tokens themselves are not stored)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTokenList()
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "revoke <token-id>",
+		Short: "Revoke an API token",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTokenRevoke(args[0])
+		},
+	})
+
+	return cmd
+}
+
+// newTokenCreateCmd creates the token create command
+func newTokenCreateCmd() *cobra.Command {
+	var scope string
+	var name string
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create an API token; it is printed once and can't be shown again",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTokenCreate(name, scope)
+		},
+	}
+
+	cmd.Flags().StringVar(&scope, "scope", storage.ScopeRead, "what the token may do: read (list items and stats) or admin (also trigger fetches and prune)")
+	cmd.Flags().StringVar(&name, "name", "", "label to tell the token apart in 'token list'")
+
+	return cmd
+}
+
+// newAuditCmd creates the audit command
+func newAuditCmd() *cobra.Command {
+	var actor string
+	var action string
+	var since string
+	var limit int
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Show who blocked, curated, added feeds, managed tokens or triggered fetches, and when",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAudit(actor, action, since, limit, format)
+		},
+	}
+
+	cmd.Flags().StringVar(&actor, "actor", "", "only actions by this actor (e.g. 'cli:alice', 'token:2894c91a')")
+	cmd.Flags().StringVar(&action, "action", "", "only this action (e.g. 'block'), or actions under a prefix ending in '.' (e.g. 'token.')")
+	cmd.Flags().StringVar(&since, "since", "", "only actions within this window (e.g., '24h', '7d')")
+	cmd.Flags().IntVar(&limit, "limit", 50, "maximum number of entries (0 for all)")
+	cmd.Flags().StringVar(&format, "format", "table", "output format (table, json)")
+
+	return cmd
+}
+
+// newFailuresCmd creates the failures command
+func newFailuresCmd() *cobra.Command {
+	var source string
+	var limit int
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "failures",
+		Short: "List the recorded responses of fetches that failed to parse",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runFailures(source, limit, format)
+		},
+	}
+
+	cmd.Flags().StringVar(&source, "source", "", "only failures of this source")
+	cmd.Flags().IntVar(&limit, "limit", 20, "maximum number of failures (0 for all)")
+	cmd.Flags().StringVar(&format, "format", "table", "output format (table, json)")
+
+	var output string
+	show := &cobra.Command{
+		Use:   "show <failure-id>",
+		Short: "Print a recorded response as it was received",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runFailureShow(args[0], output)
+		},
+	}
+	show.Flags().StringVarP(&output, "output", "o", "", "write the response to this file instead of stdout")
+	cmd.AddCommand(show)
+
+	return cmd
+}
+
+// newErrorsCmd creates the errors command
+func newErrorsCmd() *cobra.Command {
+	var source string
+	var since string
+	var last int
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "errors",
+		Short: "List the distinct errors fetches failed with, with counts and when they were first and last seen",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runErrors(source, since, last, format)
+		},
+	}
+
+	cmd.Flags().StringVar(&source, "source", "", "only errors of this source")
+	cmd.Flags().StringVar(&since, "since", "", "only errors within this window (e.g., '24h', '7d')")
+	cmd.Flags().IntVar(&last, "last", 20, "number of most recently seen errors to show (0 for all)")
+	cmd.Flags().StringVar(&format, "format", "table", "output format (table, json)")
+
+	return cmd
+}
+
+// newDoctorCmd creates the doctor command
+func newDoctorCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the config and database for problems and suggest fixes",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDoctor()
+		},
+	}
+}
+
+// newDBCmd creates the db command
+func newDBCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Database maintenance",
+	}
+
+	var force bool
+	repair := &cobra.Command{
+		Use:   "repair",
+		Short: "Recover a corrupt database into a new file and swap it in, keeping the original as a backup",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDBRepair(force)
+		},
+	}
+	repair.Flags().BoolVar(&force, "force", false, "rebuild the database even if it passes the integrity check")
+	cmd.AddCommand(repair)
+
+	return cmd
+}
+
+// newArchiveCmd creates the archive command
+func newArchiveCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "archive",
+		Short: "Submit queued item URLs to the Wayback Machine and show their status",
+	}
+
+	var runLimit int
+	run := &cobra.Command{
+		Use:   "run",
+		Short: "Work through the archive queue now, pacing requests by archive.interval",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runArchiveRun(runLimit)
+		},
+	}
+	run.Flags().IntVar(&runLimit, "limit", 20, "maximum number of queued URLs to process")
+	cmd.AddCommand(run)
+
+	var statusLimit int
+	var format string
+	status := &cobra.Command{
+		Use:   "status",
+		Short: "Show how many URLs are queued, archived or failed, and the latest ones",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runArchiveStatus(statusLimit, format)
+		},
+	}
+	status.Flags().IntVar(&statusLimit, "limit", 20, "number of recent entries to show")
+	status.Flags().StringVar(&format, "format", "table", "output format (table, json)")
+	cmd.AddCommand(status)
+
+	return cmd
+}
+
+// newSaveCmd creates the save command
+func newSaveCmd() *cobra.Command {
+	var to string
+	var queued bool
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "save [item-id]",
+		Short: "Save an item to a read-later service or bookmark manager, or send saves queued by auto-save rules",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if queued {
+				if len(args) > 0 {
+					return fmt.Errorf("--queued takes no item ID")
+				}
+				return runSaveQueued()
+			}
+			if len(args) == 0 || to == "" {
+				return fmt.Errorf("give an item ID and --to, or --queued")
+			}
+			return runSave(args[0], to, force)
+		},
+	}
+
+	cmd.Flags().StringVar(&to, "to", "", "service to save to ("+strings.Join(config.ReadLaterServices, ", ")+")")
+	cmd.Flags().BoolVar(&queued, "queued", false, "send the saves auto-save rules queued")
+	cmd.Flags().BoolVar(&force, "force", false, "save even if the item's URL was saved to the service before")
+
+	return cmd
+}
+
+// newDaemonCmd creates the daemon command
+func newDaemonCmd() *cobra.Command {
+	var defaultInterval, attemptLog string
+
+	cmd := &cobra.Command{
+		Use:   "daemon",
+		Short: "Keep running, fetching each feed every refresh_interval_secs until stopped",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDaemon(defaultInterval, attemptLog)
+		},
+	}
+
+	cmd.Flags().StringVar(&defaultInterval, "default-interval", "5m", "how often to fetch feeds without refresh_interval_secs")
+	cmd.Flags().StringVar(&attemptLog, "attempt-log", "", "append a JSON line per HTTP request to this file ('-' for stderr)")
+
+	return cmd
+}
+
+// newNotifyCmd creates the notify command
+func newNotifyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "notify",
+		Short: "Check the configured notifiers",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "test <notifier>",
+		Short: fmt.Sprintf("Send a sample message through a notifier (%s)", strings.Join(config.Notifiers, ", ")),
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runNotifyTest(args[0])
+		},
+	})
+
+	return cmd
+}
+
+// runFetch executes the fetch command
+func runFetch(clk clock.Clock, full bool) error {
+	// Load config
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("config error")
+	}
+
+	// Open database; if it is locked or corrupt, fetch anyway and queue
+	// the results for the next run rather than lose them
+	store, err := storage.Open(cfg.Settings.DatabaseDSN())
+	if err != nil {
+		if storage.IsBusy(err) || storage.IsCorrupt(err) {
+			return runFetchQueued(cfg, clk, full, err)
+		}
+		printDatabaseError("open database", err)
+		return fmt.Errorf("database error")
+	}
+	defer store.Close()
+	store.SetClock(clk)
+
+	if err := applyUniquenessScope(store, cfg); err != nil {
+		return err
+	}
+	if err := applyCookieKey(store, cfg); err != nil {
+		return err
+	}
+
+	if full {
+		for _, feed := range cfg.Feeds {
+			if err := store.ClearFeedState(feed.Name); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return fmt.Errorf("database error")
+			}
+		}
+	}
+
+	// The marks of replayed results identify this run like those
+	// recordResult sets, so they use the real clock even with
+	// --backfill-as-of
+	replayQueue(store, cfg, clock.System)
+
+	ctx, cancel := fetchContext()
+	defer cancel()
+
+	// Fetch feeds
+	fmt.Printf("Fetching %d feeds (max concurrency: %d)...\n", len(cfg.Feeds), cfg.Settings.MaxConcurrency)
+
+	f := fetcher.NewFetcher(cfg)
+	f.SetJournal(store)
+	f.SetClock(clk)
+	f.SetState(store)
+	f.SetCookieStore(store)
+	f.SetRequestMeter(store)
+
+	// Process results stage by stage, so dependent feeds see what their
+	// upstream feeds saved
+	var summary fetchSummary
+	results := f.FetchStages(ctx, func(stage []fetcher.FetchResult) {
+		for _, result := range stage {
+			recordResult(store, cfg, clk, result, &summary)
+		}
+	})
+
+	// Drop payloads that made it to storage; anything left is for `recover`
+	if _, err := store.PruneJournal(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	printFetchSummary(summary, len(results))
+	sendQueuedSaves(ctx, store, cfg)
+	notifyNewItems(ctx, store, cfg, summary.fresh)
+	return nil
+}
+
+// dryRunSample is how many titles a dry run shows per feed
+const dryRunSample = 3
+
+// runFetchDryRun fetches and parses every feed like runFetch, but only
+// prints what would be stored: items are compared with the database, not
+// saved, and nothing is logged. Feed state isn't read or advanced, so
+// every feed is fetched in full and a later fetch is unaffected. Stored
+// cookies and request budgets are used but not updated.
+func runFetchDryRun(clk clock.Clock) error {
+	cfg, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	store.SetClock(clk)
+
+	if err := applyCookieKey(store, cfg); err != nil {
+		return err
+	}
+	if scope, err := store.UniquenessScope(); err == nil && scope != "" && scope != cfg.Settings.UniquenessScope {
+		fmt.Fprintf(os.Stderr, "Warning: the database uses uniqueness scope %q, not %q; new counts may be off until the next fetch\n", scope, cfg.Settings.UniquenessScope)
+	}
+
+	ctx, cancel := fetchContext()
+	defer cancel()
+
+	fmt.Printf("Dry run: fetching %d feeds (max concurrency: %d)...\n", len(cfg.Feeds), cfg.Settings.MaxConcurrency)
+
+	f := fetcher.NewFetcher(cfg)
+	f.SetClock(clk)
+	f.SetCookieStore(dryRunCookies{store})
+	f.SetRequestMeter(dryRunMeter{store})
+
+	var success, failed, skipped, total, fresh, duplicates, blocked, filtered int
+	results := f.FetchStages(ctx, func(stage []fetcher.FetchResult) {
+		for _, result := range stage {
+			if result.Skipped {
+				skipped++
+				fmt.Printf("  - %-30s — skipped: %s\n", result.Source, result.Error)
+				continue
+			}
+			if !result.Success {
+				failed++
+				fmt.Printf("  ✗ %-30s — error: %s\n", result.Source, result.Error)
+				// The start of a response that failed to parse usually shows why
+				if failure := result.Failure; failure != nil && len(failure.Payload) > 0 {
+					fmt.Printf("      response: %s\n", truncate(strings.Join(strings.Fields(string(failure.Payload)), " "), 120))
+				}
+				continue
+			}
+
+			preview, err := store.PreviewSave(result.Items)
+			if err != nil {
+				printDatabaseError("compare items", err)
+				failed++
+				continue
+			}
+			success++
+			total += result.ItemsCount
+			fresh += preview.Inserted
+			duplicates += preview.Updated
+			blocked += preview.Blocked
+			filtered += result.Filtered
+
+			fmt.Printf("  ✓ %-30s — %d items (%d new, %d duplicate", result.Source, result.ItemsCount, preview.Inserted, preview.Updated)
+			if preview.Blocked > 0 {
+				fmt.Printf(", %d blocked", preview.Blocked)
+			}
+			if result.Filtered > 0 {
+				fmt.Printf(", %d filtered", result.Filtered)
+			}
+			fmt.Printf(") in %dms\n", result.DurationMs)
+			printDryRunSample(result.Items, preview.New)
+
+			if result.Error != "" {
+				fmt.Fprintf(os.Stderr, "Warning: %s partially failed: %s\n", result.Source, result.Error)
+			}
+			if len(result.Violations) > 0 {
+				fmt.Fprintf(os.Stderr, "Warning: %s would be degraded: %s\n", result.Source, strings.Join(result.Violations, "; "))
+			}
+			if result.Truncated > 0 {
+				fmt.Fprintf(os.Stderr, "Warning: %s returned more than %d items; %d dropped\n", result.Source, cfg.Settings.MaxItemsPerFetch, result.Truncated)
+			}
+		}
+	})
+
+	fmt.Printf("\nDry run: %d/%d succeeded, %d items (%d new, %d duplicate", success, len(results), total, fresh, duplicates)
+	if blocked > 0 {
+		fmt.Printf(", %d blocked", blocked)
+	}
+	if filtered > 0 {
+		fmt.Printf(", %d filtered", filtered)
+	}
+	fmt.Print(")")
+	if failed > 0 {
+		fmt.Printf(", %d error(s)", failed)
+	}
+	if skipped > 0 {
+		fmt.Printf(", %d skipped", skipped)
+	}
+	fmt.Println("\nNothing was saved.")
+	return nil
+}
+
+// dryRunCookies sends the cookie jars stored with a dry run's requests,
+// but drops the cookies answered instead of storing them
+type dryRunCookies struct {
+	store storage.Store
+}
+
+func (c dryRunCookies) LoadCookies(feed string) ([]storage.Cookie, error) {
+	return c.store.LoadCookies(feed)
+}
+
+func (c dryRunCookies) SaveCookies(string, []storage.Cookie) error {
+	return nil
+}
+
+// dryRunMeter defers feeds that would overspend a request budget, going
+// by the requests stored, but doesn't count a dry run's own requests
+type dryRunMeter struct {
+	store storage.Store
+}
+
+func (m dryRunMeter) RecordHostRequest(string, time.Time) error {
+	return nil
+}
+
+func (m dryRunMeter) HostUsage(host string, now time.Time) (storage.HostUsage, error) {
+	return m.store.HostUsage(host, now)
+}
+
+func (m dryRunMeter) ReserveHostRequests(host string, n, perHour, perDay int, at time.Time) (bool, error) {
+	usage, err := m.store.HostUsage(host, at)
+	if err != nil {
+		return false, err
+	}
+	return (perHour == 0 || usage.LastHour+n <= perHour) && (perDay == 0 || usage.LastDay+n <= perDay), nil
+}
+
+func (m dryRunMeter) ReleaseHostRequests(string, int, time.Time) error {
+	return nil
+}
+
+// truncate shortens s to at most n characters, marking the cut with "…"
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n-1]) + "…"
+}
+
+// printDryRunSample prints the first titles of a feed's items, new ones
+// (those in fresh) before ones already stored
+func printDryRunSample(items []storage.FeedItem, fresh []string) {
+	isNew := make(map[string]bool, len(fresh))
+	for _, id := range fresh {
+		isNew[id] = true
+	}
+	sample := newItems(items, fresh)
+	for _, item := range items {
+		if len(sample) >= dryRunSample {
+			break
+		}
+		if !isNew[item.ID] {
+			sample = append(sample, item)
+		}
+	}
+	if len(sample) > dryRunSample {
+		sample = sample[:dryRunSample]
+	}
+
+	for _, item := range sample {
+		mark := "="
+		if isNew[item.ID] {
+			mark = "+"
+		}
+		fmt.Printf("      %s %s\n", mark, truncate(item.Title, 100))
+	}
+}
+
+// runFetchQueued fetches every feed while the database can't be opened
+// (openErr), appending results to the queue file instead of saving them.
+// Without the database, feed state, cookies and the journal are
+// unavailable, so every feed is fetched in full.
+func runFetchQueued(cfg *config.Config, clk clock.Clock, full bool, openErr error) error {
+	path := storage.QueuePath(cfg.Settings.DatabasePath)
+	fmt.Fprintf(os.Stderr, "Warning: database unavailable: %v\n", openErr)
+	if hint := databaseHint(openErr); hint != "" {
+		fmt.Fprintf(os.Stderr, "Hint: %s\n", hint)
+	}
+	fmt.Fprintf(os.Stderr, "Warning: results will be queued in %s and saved by the next fetch that can open the database\n", path)
+	if full {
+		fmt.Fprintf(os.Stderr, "Warning: --full has no stored state to discard\n")
+	}
+
+	ctx, cancel := fetchContext()
+	defer cancel()
+
+	fmt.Printf("Fetching %d feeds (max concurrency: %d)...\n", len(cfg.Feeds), cfg.Settings.MaxConcurrency)
+
+	f := fetcher.NewFetcher(cfg)
+	f.SetClock(clk)
+
+	var summary fetchSummary
+	results := f.FetchStages(ctx, func(stage []fetcher.FetchResult) {
+		for _, result := range stage {
+			queueResult(path, clk, result, &summary)
+		}
+	})
+
+	printFetchSummary(summary, len(results))
+	return nil
+}
+
+// fetchContext returns a context cancelled by Ctrl+C or SIGTERM
+func fetchContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-sigChan:
+			fmt.Fprintf(os.Stderr, "\nCancelling...\n")
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(sigChan)
+	}()
+
+	return ctx, cancel
+}
+
+// printFetchSummary prints the last line of a fetch run of total feeds
+func printFetchSummary(summary fetchSummary, total int) {
+	fmt.Printf("\nDone: %d/%d succeeded, %d items (%d new", summary.success, total, summary.items, summary.newItems)
+	if summary.filtered > 0 {
+		fmt.Printf(", %d filtered", summary.filtered)
+	}
+	fmt.Print(")")
+	if summary.errors > 0 {
+		fmt.Printf(", %d error(s)", summary.errors)
+	}
+	if summary.degraded > 0 {
+		fmt.Printf(", %d degraded", summary.degraded)
+	}
+	if summary.skipped > 0 {
+		fmt.Printf(", %d skipped", summary.skipped)
+	}
+	if summary.unchanged > 0 {
+		fmt.Printf(", %d unchanged", summary.unchanged)
+	}
+	if summary.stale > 0 {
+		fmt.Printf(", %d stale", summary.stale)
+	}
+	if summary.slow > 0 {
+		fmt.Printf(", %d slow", summary.slow)
+	}
+	if summary.queued > 0 {
+		fmt.Printf(", %d queued", summary.queued)
+	}
+	fmt.Println()
+}
+
+// fetchSummary tallies the results of a fetch run
+type fetchSummary struct {
+	success, errors, skipped, degraded int
+	unchanged, stale, queued, slow     int
+	items, newItems, filtered          int
+
+	// fresh holds the items stored for the first time, for notifiers
+	fresh []storage.FeedItem
+}
+
+// recordResult stores one fetch result (its items, fetch log entry, feed
+// state and journal bookkeeping), prints its line and counts it in summary
+func recordResult(store storage.Store, cfg *config.Config, clk clock.Clock, result fetcher.FetchResult, summary *fetchSummary) {
+	if result.Skipped {
+		summary.skipped++
+		fmt.Printf("  - %-30s — skipped: %s\n", result.Source, result.Error)
+		return
+	}
+
+	if result.Unchanged {
+		summary.success++
+		summary.unchanged++
+		slow := checkSlow(store, result, summary)
+
+		// Nothing to save, but the fetch is logged so the feed still
+		// counts as healthy
+		logFetch(store, cfg, storage.FetchLog{
+			Source:     result.Source,
+			FetchedAt:  clk.Now(),
+			Status:     "unchanged",
+			DurationMs: result.DurationMs,
+			Endpoint:   result.Endpoint,
+			Slow:       slow != "",
+		}, summary)
+
+		fmt.Printf("  = %-30s — unchanged in %dms%s\n", result.Source, result.DurationMs, slow)
+		warnIfStale(store, cfg, clk, result.Source, summary)
+		return
+	}
+
+	if result.Success {
+		summary.success++
+		summary.items += result.ItemsCount
+		summary.filtered += result.Filtered
+
+		// A fetch that broke the feed's assertions is saved but logged
+		// as degraded; truncation is only noted
+		status := "success"
+		var notes []string
+		if len(result.Violations) > 0 {
+			status = "degraded"
+			notes = append(notes, result.Violations...)
+		}
+		if result.Truncated > 0 {
+			notes = append(notes, fmt.Sprintf("truncated: %d item(s) over max_items_per_fetch dropped", result.Truncated))
+		}
+		var message *string
+		if len(notes) > 0 {
+			joined := strings.Join(notes, "; ")
+			message = &joined
+		}
+		slow := checkSlow(store, result, summary)
+
+		// Save items and log success atomically
+		log := storage.FetchLog{
+			Source:       result.Source,
+			FetchedAt:    clk.Now(),
+			Status:       status,
+			ItemsCount:   result.ItemsCount,
+			ErrorMessage: message,
+			DurationMs:   result.DurationMs,
+			Endpoint:     result.Endpoint,
+			Slow:         slow != "",
+			Failure:      result.Failure,
+			Partial:      result.Partial,
+		}
+		saveResult, err := store.SaveFetchResult(log, result.Items)
+		if err != nil {
+			if queueIfUnavailable(cfg, storage.QueuedResult{Log: log, Items: result.Items, State: result.State}, err) {
+				summary.queued++
+				fmt.Fprintf(os.Stderr, "Warning: database unavailable (%v); queued %s's items for the next fetch\n", err, result.Source)
+			} else {
+				fmt.Fprintf(os.Stderr, "Warning: failed to save items for %s: %v\n", result.Source, err)
+				reportError("save items", err)
+			}
+		} else {
+			result.NewItems = saveResult.Inserted
+			summary.newItems += result.NewItems
+			summary.fresh = append(summary.fresh, newItems(result.Items, saveResult.New)...)
+
+			// Advance the cursor only once the items it skips past are
+			// stored, and let dependent feeds know there is new data.
+			// The mark identifies this run, so it uses the real clock
+			// even with --backfill-as-of.
+			state := result.State
+			if result.NewItems > 0 {
+				state = fetcher.MarkNewItems(state, time.Now())
+			}
+			if err := store.SetFeedState(result.Source, state); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+
+			// Items are durable, so the journaled payload no longer needs replaying
+			for _, id := range result.JournalIDs {
+				if err := store.MarkJournalProcessed(id); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				}
+			}
+
+			// URLs already queued are ignored, so only new ones get archived
+			if cfg.Settings.Archive.Archives(result.Source) {
+				if _, err := store.EnqueueArchive(result.Items); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				}
+			}
+			if err := queueAutoSaves(store, cfg, result.Items, false); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+
+		mark := "✓"
+		if status == "degraded" {
+			mark = "⚠"
+			summary.degraded++
+		}
+		fmt.Printf("  %s %-30s — %d items (%d new", mark, result.Source, result.ItemsCount, result.NewItems)
+		if result.Filtered > 0 {
+			fmt.Printf(", %d filtered", result.Filtered)
+		}
+		fmt.Printf(") in %dms%s", result.DurationMs, slow)
+		if feed := findFeed(cfg, result.Source); feed != nil && len(feed.Mirrors) > 0 {
+			fmt.Printf(" via %s", result.Endpoint)
+		}
+		fmt.Println()
+		if result.Error != "" {
+			fmt.Fprintf(os.Stderr, "Warning: %s partially failed: %s\n", result.Source, result.Error)
+		}
+		if status == "degraded" {
+			fmt.Fprintf(os.Stderr, "Warning: %s degraded: %s\n", result.Source, strings.Join(result.Violations, "; "))
+		}
+		if result.Truncated > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: %s returned more than %d items; %d dropped\n", result.Source, cfg.Settings.MaxItemsPerFetch, result.Truncated)
+		}
+		if drift := result.SchemaDrift; drift != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s response structure changed (%.0f%% of fields): %s\n", result.Source, drift.Distance*100, describeDrift(drift))
+		}
+		if result.Backoff > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: %s API requested a backoff of %s; skipping it until then\n", result.Source, result.Backoff.Round(time.Second))
+		}
+
+		warnIfStale(store, cfg, clk, result.Source, summary)
+	} else {
+		summary.errors++
+
+		// Log error
+		logFetch(store, cfg, storage.FetchLog{
+			Source:       result.Source,
+			FetchedAt:    clk.Now(),
+			Status:       "error",
+			ErrorMessage: &result.Error,
+			DurationMs:   result.DurationMs,
+			Endpoint:     result.Endpoint,
+			Failure:      result.Failure,
+		}, summary)
+
+		// A journaled payload that failed to parse would only fail again
+		// on replay; what's needed to look into it is in parse_failures
+		for _, id := range result.JournalIDs {
+			if err := store.MarkJournalProcessed(id); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+
+		fmt.Printf("  ✗ %-30s — error: %s\n", result.Source, result.Error)
+		if result.Failure != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s response recorded; see 'feedpulse failures --source \"%s\"'\n", result.Source, result.Source)
+		}
+	}
+}
+
+// checkSlow compares a successful fetch's duration with the p99 of its
+// source's recent fetches. It returns the note to print after the
+// duration if the fetch was slower, counting it in summary, or "".
+func checkSlow(store storage.Store, result fetcher.FetchResult, summary *fetchSummary) string {
+	threshold, ok, err := store.SlowThreshold(result.Source)
+	if err != nil || !ok || result.DurationMs <= threshold {
+		return ""
+	}
+	summary.slow++
+	return fmt.Sprintf(" ⚠ slow (p99 %dms)", threshold)
+}
+
+// logFetch logs a fetch without items, queueing the entry if the
+// database is locked or corrupt
+func logFetch(store storage.Store, cfg *config.Config, log storage.FetchLog, summary *fetchSummary) {
+	err := store.LogFetch(log)
+	if err == nil {
+		return
+	}
+	if queueIfUnavailable(cfg, storage.QueuedResult{Log: log}, err) {
+		summary.queued++
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Warning: failed to log fetch for %s: %v\n", log.Source, err)
+	reportError("log fetch", err)
+}
+
+// queueIfUnavailable appends entry to the queue file when err is the
+// database being locked or corrupt, reporting whether it did
+func queueIfUnavailable(cfg *config.Config, entry storage.QueuedResult, err error) bool {
+	if !storage.IsBusy(err) && !storage.IsCorrupt(err) {
+		return false
+	}
+	if err := storage.AppendQueue(storage.QueuePath(cfg.Settings.DatabasePath), entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return false
+	}
+	return true
+}
+
+// queueResult queues one fetch result while the database is unavailable,
+// prints its line and counts it in summary
+func queueResult(path string, clk clock.Clock, result fetcher.FetchResult, summary *fetchSummary) {
+	if result.Skipped {
+		summary.skipped++
+		fmt.Printf("  - %-30s — skipped: %s\n", result.Source, result.Error)
+		return
+	}
+
+	entry := storage.QueuedResult{Log: storage.FetchLog{
+		Source:     result.Source,
+		FetchedAt:  clk.Now(),
+		DurationMs: result.DurationMs,
+		Endpoint:   result.Endpoint,
+		Failure:    result.Failure,
+	}}
+	switch {
+	case result.Unchanged:
+		entry.Log.Status = "unchanged"
+	case result.Success:
+		entry.Log.Status = "success"
+		if len(result.Violations) > 0 {
+			entry.Log.Status = "degraded"
+			joined := strings.Join(result.Violations, "; ")
+			entry.Log.ErrorMessage = &joined
+		}
+		entry.Log.ItemsCount = result.ItemsCount
+		entry.Log.Partial = result.Partial
+		entry.Items = result.Items
+		entry.State = result.State
+	default:
+		entry.Log.Status = "error"
+		entry.Log.ErrorMessage = &result.Error
+	}
+
+	if err := storage.AppendQueue(path, entry); err != nil {
+		summary.errors++
+		fmt.Printf("  ✗ %-30s — not saved: %v\n", result.Source, err)
+		return
+	}
+	summary.queued++
+
+	switch entry.Log.Status {
+	case "error":
+		summary.errors++
+		fmt.Printf("  ✗ %-30s — error: %s (queued)\n", result.Source, result.Error)
+	case "unchanged":
+		summary.success++
+		summary.unchanged++
+		fmt.Printf("  = %-30s — unchanged in %dms (queued)\n", result.Source, result.DurationMs)
+	default:
+		summary.success++
+		summary.items += result.ItemsCount
+		summary.filtered += result.Filtered
+		fmt.Printf("  ⧗ %-30s — %d items queued in %dms\n", result.Source, result.ItemsCount, result.DurationMs)
+	}
+}
+
+// replayQueue saves the results queued while the database was
+// unavailable, oldest first, keeping whatever can't be saved yet. It holds
+// the queue's lock throughout, so a fetch queueing results meanwhile waits
+// rather than have them overwritten. Feeds that brought new items are
+// marked at clk's time.
+func replayQueue(store storage.Store, cfg *config.Config, clk clock.Clock) {
+	path := storage.QueuePath(cfg.Settings.DatabasePath)
+	unlock, err := storage.LockQueue(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return
+	}
+	defer unlock()
+
+	queued, damaged, err := storage.ReadQueue(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return
+	}
+	if len(damaged) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: dropped %d damaged line(s) from the queue: %v\n", len(damaged), damaged)
+	}
+	if len(queued) == 0 {
+		if len(damaged) > 0 {
+			if err := storage.WriteQueue(path, nil); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+		return
+	}
+
+	saved := 0
+	for _, entry := range queued {
+		if err := saveQueued(store, cfg, clk, entry); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to save queued result for %s: %v\n", entry.Log.Source, err)
+			reportError("save queued result", err)
+			break
+		}
+		saved++
+	}
+
+	if err := storage.WriteQueue(path, queued[saved:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	fmt.Printf("Saved %d of %d result(s) queued while the database was unavailable\n", saved, len(queued))
+}
+
+// saveQueued saves one queued result the way recordResult would have
+func saveQueued(store storage.Store, cfg *config.Config, clk clock.Clock, entry storage.QueuedResult) error {
+	if entry.Log.Status != "success" && entry.Log.Status != "degraded" {
+		return store.LogFetch(entry.Log)
+	}
+
+	saveResult, err := store.SaveFetchResult(entry.Log, entry.Items)
+	if err != nil {
+		return err
+	}
+	if cfg.Settings.Archive.Archives(entry.Log.Source) {
+		if _, err := store.EnqueueArchive(entry.Items); err != nil {
+			return err
+		}
+	}
+	if err := queueAutoSaves(store, cfg, entry.Items, false); err != nil {
+		return err
+	}
+	if entry.State == nil {
+		return nil
+	}
+	state := entry.State
+	if saveResult.Inserted > 0 {
+		state = fetcher.MarkNewItems(state, clk.Now())
+	}
+	return store.SetFeedState(entry.Log.Source, state)
+}
+
+// warnIfStale warns about and counts a source that keeps fetching fine
+// while its publisher has stopped updating, or while we parse the wrong
+// date field
+func warnIfStale(store storage.Store, cfg *config.Config, clk clock.Clock, source string, summary *fetchSummary) {
+	feed := findFeed(cfg, source)
+	if feed == nil {
+		return
+	}
+
+	newest, stale, err := checkStale(store, cfg, *feed, clk.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	} else if stale {
+		summary.stale++
+		fmt.Fprintf(os.Stderr, "Warning: %s is stale: newest item is from %s, older than stale_after %s\n", source, newest.In(currentZone()).Format(time.RFC3339), cfg.StaleAfter(*feed))
+	}
+}
+
+// checkStale reports whether feed's newest stored item is older than the
+// feed's stale_after, along with when that item was published
+func checkStale(store storage.Store, cfg *config.Config, feed config.Feed, now time.Time) (time.Time, bool, error) {
+	newest, ok, err := store.NewestItemTime(feed.Name)
+	if err != nil || !ok {
+		return newest, false, err
+	}
+	limit := cfg.StaleAfter(feed)
+	return newest, limit > 0 && now.Sub(newest) > limit, nil
+}
+
+// applyUniquenessScope brings the database in line with the configured
+// item uniqueness scope, dedup mode and ID hashes, migrating stored items
+// if they changed
+func applyUniquenessScope(store storage.Store, cfg *config.Config) error {
+	removed, err := store.SetUniquenessScope(cfg.Settings.UniquenessScope)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("database error")
+	}
+	if removed > 0 {
+		fmt.Printf("Uniqueness scope is now %q: merged %d duplicate item(s)\n", cfg.Settings.UniquenessScope, removed)
+	}
+
+	removed, err = store.SetDedup(cfg.Settings.Dedup)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("database error")
+	}
+	if removed > 0 {
+		fmt.Printf("Dedup is now %q: merged %d duplicate item(s)\n", cfg.Settings.Dedup, removed)
+	}
+
+	hashes := make(map[string]string, len(cfg.Feeds))
+	for _, feed := range cfg.Feeds {
+		hashes[feed.Name] = feed.ItemIDHash()
+	}
+	removed, err = store.SetIDHashes(hashes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("database error")
+	}
+	if removed > 0 {
+		fmt.Printf("Item IDs re-keyed: merged %d duplicate item(s)\n", removed)
+	}
+	return nil
+}
+
+// describeDrift lists the fields a schema drift added (+) and removed (-),
+// abbreviated past a handful
+func describeDrift(drift *fetcher.SchemaDrift) string {
+	const maxListed = 8
+
+	var changes []string
+	for _, p := range drift.Removed {
+		changes = append(changes, "-"+p)
+	}
+	for _, p := range drift.Added {
+		changes = append(changes, "+"+p)
+	}
+
+	if len(changes) > maxListed {
+		return fmt.Sprintf("%s and %d more", strings.Join(changes[:maxListed], ", "), len(changes)-maxListed)
+	}
+	return strings.Join(changes, ", ")
+}
+
+// cookieKeyEnv names the environment variable holding the secret that
+// stored cookie jars are encrypted with
+const cookieKeyEnv = "FEEDPULSE_SECRET_KEY"
+
+// applyCookieKey sets the cookie encryption secret from the environment.
+// It is only required when a configured feed keeps a cookie jar.
+func applyCookieKey(store storage.Store, cfg *config.Config) error {
+	if err := checkCookieKey(cfg); err != nil {
+		return err
+	}
+	store.SetCookieKey(os.Getenv(cookieKeyEnv))
+	return nil
+}
+
+// checkCookieKey returns an error, having printed it, if a configured feed
+// keeps a cookie jar but the environment holds no secret to encrypt it with
+func checkCookieKey(cfg *config.Config) error {
+	if os.Getenv(cookieKeyEnv) != "" {
+		return nil
+	}
+
+	for _, feed := range cfg.Feeds {
+		if feed.UsesCookies() {
+			fmt.Fprintf(os.Stderr, "Error: feed '%s' keeps a cookie jar; set %s to encrypt it\n", feed.Name, cookieKeyEnv)
+			return fmt.Errorf("config error")
+		}
+	}
+	return nil
+}
+
+// findFeed returns the configured feed named name, or nil
+func findFeed(cfg *config.Config, name string) *config.Feed {
+	for i := range cfg.Feeds {
+		if cfg.Feeds[i].Name == name {
+			return &cfg.Feeds[i]
+		}
+	}
+	return nil
+}
+
+// runRecover executes the recover command
+func runRecover() error {
+	// Load config
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("config error")
+	}
+
+	// Open database
+	store, err := storage.Open(cfg.Settings.DatabaseDSN())
+	if err != nil {
+		printDatabaseError("open database", err)
+		return fmt.Errorf("database error")
+	}
+	defer store.Close()
+
+	if err := applyUniquenessScope(store, cfg); err != nil {
+		return err
+	}
+
+	entries, err := store.PendingJournal()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("journal error")
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("Nothing to recover.")
+		return nil
+	}
+
+	fmt.Printf("Replaying %d journaled payload(s)...\n", len(entries))
+
+	p := fetcher.NewParser(cfg)
+	filters := fetcher.NewItemFilters(cfg)
+	recovered := 0
+
+	for _, entry := range entries {
+		// The journal holds raw responses, so unwrap rules apply again
+		payload := entry.Payload
+		if feed := findFeed(cfg, entry.Source); feed != nil {
+			if payload, err = fetcher.Unwrap(*feed, payload); err != nil {
+				fmt.Printf("  ✗ %-30s — error: %v\n", entry.Source, err)
+				continue
+			}
+		}
+
+		p.SetUniquenessScope(cfg.Settings.UniquenessScope, fmt.Sprintf("recover-%d", entry.ID))
+		parseResult := p.Parse(entry.Source, entry.FeedType, payload)
+
+		// A payload that can't be decoded failed its fetch too; it is
+		// logged as a failure again and stays pending
+		if parseResult.Malformed {
+			msg := "parse error: " + strings.Join(parseResult.Errors, "; ")
+			if err := store.LogFetch(storage.FetchLog{Source: entry.Source, FetchedAt: entry.FetchedAt, Status: "error", ErrorMessage: &msg}); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+			fmt.Printf("  ✗ %-30s — %s\n", entry.Source, msg)
+			continue
+		}
+		items, _ := filters.Apply(entry.Source, parseResult.Items)
+
+		// A journaled payload may be one of several its fetch made
+		saveResult, err := store.SaveFetchResult(storage.FetchLog{
+			Source:     entry.Source,
+			FetchedAt:  entry.FetchedAt,
+			Status:     "success",
+			ItemsCount: len(items),
+			Partial:    true,
+		}, items)
+		if err != nil {
+			fmt.Printf("  ✗ %-30s — error: %v\n", entry.Source, err)
+			continue
+		}
+
+		if err := store.MarkJournalProcessed(entry.ID); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			continue
+		}
+
+		recovered++
+		fmt.Printf("  ✓ %-30s — %d items (%d new)\n", entry.Source, len(items), saveResult.Inserted)
+		for _, parseErr := range parseResult.Errors {
+			fmt.Printf("    warning: %s\n", parseErr)
+		}
+	}
+
+	if _, err := store.PruneJournal(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	recordAudit(store, storage.AuditRecover, map[string]string{"recovered": strconv.Itoa(recovered), "pending": strconv.Itoa(len(entries))})
+	fmt.Printf("\nDone: %d/%d payload(s) recovered\n", recovered, len(entries))
+	return nil
+}
+
+// runTrend executes the trend command
+func runTrend(bucket, window, sourceName string) error {
+	windowDur, err := parseWindow(window)
+	if err != nil {
+		return err
+	}
+
+	// Load config
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("config error")
+	}
+
+	// Open database
+	store, err := storage.Open(cfg.Settings.DatabaseDSN())
+	if err != nil {
+		printDatabaseError("open database", err)
+		return fmt.Errorf("database error")
+	}
+	defer store.Close()
+
+	buckets, err := store.GetStatsBuckets(sourceName, bucket, windowDur)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to get stats: %v\n", err)
+		return fmt.Errorf("stats error")
+	}
+
+	layout := "2006-01-02 15:00"
+	if bucket == storage.BucketDay {
+		layout = "2006-01-02"
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.Header("Bucket", "Fetches", "Errors", "Items", "Avg ms", "Max ms")
+
+	for _, b := range buckets {
+		table.Append(
+			b.Start.Format(layout),
+			fmt.Sprintf("%d", b.Fetches),
+			fmt.Sprintf("%d", b.Errors),
+			fmt.Sprintf("%d", b.Items),
+			fmt.Sprintf("%.0f", b.AvgDurationMs),
+			fmt.Sprintf("%d", b.MaxDurationMs),
+		)
+	}
+
+	table.Render()
+	return nil
+}
+
+// activityBarWidth is the length of the longest bar in stats activity
+const activityBarWidth = 50
+
+// activityDay is one day of the stats activity histogram
+type activityDay struct {
+	Date  string `json:"date"`
+	Items int    `json:"items"`
+}
+
+// runStatsActivity executes the stats activity command
+func runStatsActivity(sourceName string, days int, format string) error {
+	if days < 1 {
+		return fmt.Errorf("--days must be at least 1")
+	}
+	if format != "table" && format != "json" {
+		return fmt.Errorf("unknown format: %s", format)
+	}
+
+	_, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	// Start at midnight so the first day is counted in full
+	now := time.Now().UTC()
+	start := now.Truncate(24*time.Hour).AddDate(0, 0, -(days - 1))
+	buckets, err := store.GetItemBuckets(sourceName, storage.BucketDay, now.Sub(start))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to get stats: %v\n", err)
+		return fmt.Errorf("stats error")
+	}
+
+	counts := make(map[string]int, len(buckets))
+	for _, b := range buckets {
+		counts[b.Start.Format("2006-01-02")] = b.Items
+	}
+
+	// Days without items are listed too, so gaps in posting show up
+	histogram := make([]activityDay, 0, days)
+	maxItems := 0
+	for d := 0; d < days; d++ {
+		date := start.AddDate(0, 0, d).Format("2006-01-02")
+		histogram = append(histogram, activityDay{Date: date, Items: counts[date]})
+		if counts[date] > maxItems {
+			maxItems = counts[date]
+		}
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(map[string]interface{}{
+			"source": sourceName,
+			"days":   histogram,
+		})
+	}
+
+	total := 0
+	for _, day := range histogram {
+		width := 0
+		if maxItems > 0 {
+			width = day.Items * activityBarWidth / maxItems
+		}
+		if width == 0 && day.Items > 0 {
+			width = 1
+		}
+		bar := strings.Repeat("█", width)
+		if bar != "" {
+			bar += " "
+		}
+		fmt.Printf("%s │ %s%d\n", day.Date, bar, day.Items)
+		total += day.Items
+	}
+	fmt.Printf("\nTotal: %d items over %d days (%.1f per day)\n", total, days, float64(total)/float64(days))
+	return nil
+}
+
+// parseWindow parses a duration, additionally accepting a 'd' suffix for days
+func parseWindow(s string) (time.Duration, error) {
+	d, err := config.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid window: %s", s)
+	}
+	return d, nil
+}
+
+// loadConfig loads the config file and sets the zone times are shown in
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	applySettings(cfg)
+	return cfg, nil
+}
+
+// applySettings makes cfg's time zone, redaction and error reporting the
+// ones the process uses
+func applySettings(cfg *config.Config) {
+	displayZone.Store(cfg.Settings.Location())
+	redactor.Store(cfg.Redactor())
+	initErrorReporting(cfg)
+}
+
+// currentZone returns the time zone times are printed in
+func currentZone() *time.Location {
+	if zone := displayZone.Load(); zone != nil {
+		return zone
+	}
+	return time.Local
+}
+
+// currentRedactor returns the redactor masking secrets in what is printed
+func currentRedactor() *redact.Redactor {
+	if r := redactor.Load(); r != nil {
+		return r
+	}
+	return defaultRedactor
+}
+
+// defaultRedactor masks the default secrets until a config is loaded
+var defaultRedactor = redact.New(nil, nil)
+
+// sentryDSNEnv is the Sentry DSN used when error_reporting doesn't set one
+const sentryDSNEnv = "SENTRY_DSN"
+
+// initErrorReporting starts or stops forwarding unexpected errors and
+// panics to Sentry, following the config's error_reporting settings
+func initErrorReporting(cfg *config.Config) {
+	e := cfg.Settings.ErrorReporting
+	if e == nil || !e.Enabled {
+		sentry.Init(nil)
+		return
+	}
+
+	dsn := e.DSN
+	if dsn == "" {
+		dsn = os.Getenv(sentryDSNEnv)
+	}
+	if dsn == "" {
+		fmt.Fprintf(os.Stderr, "Warning: error reporting disabled: no DSN in config or %s\n", sentryDSNEnv)
+		sentry.Init(nil)
+		return
+	}
+
+	client, err := sentry.New(sentry.Options{
+		DSN:         dsn,
+		Release:     "feedpulse@" + version,
+		Environment: e.Environment,
+		Scrub:       currentRedactor().String,
+		Tags: map[string]string{
+			"config":  cfg.Fingerprint(),
+			"commit":  currentBuildInfo().Commit,
+			"command": commandName,
+		},
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: error reporting disabled: %v\n", err)
+		sentry.Init(nil)
+		return
+	}
+	sentry.Init(client)
+}
+
+// reportError forwards an unexpected error hit while doing what to the
+// error reporter, if one is configured
+func reportError(what string, err error) {
+	sentry.CaptureError(err, map[string]string{"operation": what})
+}
+
+// tableTime formats a timestamp for table output: relative to now, such as
+// "3h ago", unless --absolute is set
+func tableTime(t time.Time) string {
+	if absoluteTimes {
+		return t.In(currentZone()).Format("2006-01-02 15:04")
+	}
+	return clock.Ago(time.Now(), t)
+}
+
+// openStore loads the config and opens its database
+func openStore() (*config.Config, storage.Store, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return nil, nil, fmt.Errorf("config error")
+	}
+
+	store, err := storage.Open(cfg.Settings.DatabaseDSN())
+	if err != nil {
+		printDatabaseError("open database", err)
+		return nil, nil, fmt.Errorf("database error")
+	}
+
+	return cfg, store, nil
+}
+
+// runBlock executes the block command
+func runBlock(value string) error {
+	_, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	removed, err := store.Block(value)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("blocklist error")
+	}
+	recordAudit(store, storage.AuditBlock, map[string]string{"value": value, "removed": strconv.Itoa(removed)})
+
+	fmt.Printf("Blocked %s %q (%d stored item(s) removed)\n", storage.BlockKind(value), value, removed)
+	return nil
+}
+
+// runUnblock executes the unblock command
+func runUnblock(value string) error {
+	_, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	found, err := store.Unblock(value)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("blocklist error")
+	}
+	if !found {
+		return fmt.Errorf("not blocked: %s", value)
+	}
+	recordAudit(store, storage.AuditUnblock, map[string]string{"value": value})
+
+	fmt.Printf("Unblocked %q\n", value)
+	return nil
+}
+
+// runBlockList lists blocklist entries
+func runBlockList() error {
+	_, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	entries, err := store.ListBlocked()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("blocklist error")
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.Header("Value", "Kind", "Blocked At")
+	for _, entry := range entries {
+		table.Append(entry.Value, entry.Kind, entry.CreatedAt.In(currentZone()).Format("2006-01-02 15:04"))
+	}
+	table.Render()
+	return nil
+}
+
+// runItemHistory prints the revision history of an item
+func runItemHistory(itemID string) error {
+	_, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	revisions, err := store.GetItemHistory(itemID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("history error")
+	}
+
+	if len(revisions) == 0 {
+		fmt.Printf("No recorded changes for %s\n", itemID)
+		return nil
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.Header("Changed At", "Field", "Before", "After")
+	for _, rev := range revisions {
+		changedAt := tableTime(rev.ChangedAt)
+		if rev.OldTitle != rev.NewTitle {
+			table.Append(changedAt, "title", rev.OldTitle, rev.NewTitle)
+		}
+		if rev.OldURL != rev.NewURL {
+			table.Append(changedAt, "url", rev.OldURL, rev.NewURL)
+		}
+	}
+	table.Render()
+	return nil
+}
+
+// runDigest executes the digest command
+func runDigest(since string, top int, format, notifyTo, templatePath string) error {
+	window, err := parseWindow(since)
+	if err != nil {
+		return err
+	}
+	if top < 1 {
+		return fmt.Errorf("--top must be at least 1")
+	}
+	var templates *notify.Templates
+	if templatePath != "" {
+		text, err := os.ReadFile(templatePath)
+		if err != nil {
+			return fmt.Errorf("failed to read template: %w", err)
+		}
+		if templates, err = notify.ParseTemplates("", string(text)); err != nil {
+			return err
+		}
+	}
+
+	cfg, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	items, err := store.GetItemsSince(window)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("database error")
+	}
+	topics := storage.GroupDigest(storage.TopItems(items, top), digestLayout(cfg))
+
+	if notifyTo != "" {
+		n, err := notifier(cfg, notifyTo)
+		if err != nil {
+			return err
+		}
+		if err := n.Send(context.Background(), notify.Digest(topics, since)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return fmt.Errorf("notify error")
+		}
+		fmt.Printf("Posted the digest to %s\n", n.Name())
+		return nil
+	}
+
+	if templates != nil {
+		msg, err := templates.Apply(notify.Digest(topics, since))
+		if err != nil {
+			return err
+		}
+		fmt.Println(msg.Text())
+		return nil
+	}
+
+	switch format {
+	case "table":
+		return outputDigestTable(topics, since)
+	case "markdown":
+		return outputDigestMarkdown(topics, since)
+	case "html":
+		return outputDigestHTML(topics, since)
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+// digestLayout returns how notify.digest arranges digests
+func digestLayout(cfg *config.Config) storage.DigestLayout {
+	if cfg.Settings.Notify == nil {
+		return storage.DigestLayout{}
+	}
+	d := cfg.Settings.Notify.Digest
+	return storage.DigestLayout{GroupBy: d.GroupBy, ScoreBands: d.ScoreBands, SectionLimit: d.SectionLimit, Sort: d.Sort}
+}
+
+// runBackfill executes the backfill command
+func runBackfill(clk clock.Clock, feedName string, pages int, restart bool) error {
+	if pages < 1 {
+		return fmt.Errorf("--pages must be at least 1")
+	}
+
+	cfg, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	store.SetClock(clk)
+
+	feed := findFeed(cfg, feedName)
+	if feed == nil {
+		return fmt.Errorf("unknown feed: %s", feedName)
+	}
+	if feed.Backfill == nil {
+		return fmt.Errorf("feed '%s' has no backfill configuration", feedName)
+	}
+
+	if err := applyUniquenessScope(store, cfg); err != nil {
+		return err
+	}
+	if err := applyCookieKey(store, cfg); err != nil {
+		return err
+	}
+
+	if restart {
+		if err := store.ResetBackfillCursor(feed.Name); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return fmt.Errorf("database error")
+		}
+	}
+
+	stored, err := store.GetBackfillCursor(feed.Name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("database error")
+	}
+
+	start := storage.BackfillCursor{Feed: feed.Name, Page: feed.Backfill.StartPage}
+	if stored != nil {
+		if stored.Done {
+			fmt.Printf("Backfill of %s already reached the end; use --restart to walk it again\n", feed.Name)
+			return nil
+		}
+		start = *stored
+		fmt.Printf("Resuming backfill of %s from page %d\n", feed.Name, start.Page)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		fmt.Fprintf(os.Stderr, "\nCancelling...\n")
+		cancel()
+	}()
+
+	totalItems, totalNew := 0, 0
+	f := fetcher.NewFetcher(cfg)
+	f.SetClock(clk)
+	f.SetState(store)
+	f.SetCookieStore(store)
+	f.SetRequestMeter(store)
+	fetched, err := f.Backfill(ctx, *feed, start, pages, func(result fetcher.FetchResult, next storage.BackfillCursor) error {
+		saveResult, err := store.SaveBackfillPage(storage.FetchLog{
+			Source:     result.Source,
+			FetchedAt:  clk.Now(),
+			Status:     "success",
+			ItemsCount: result.ItemsCount,
+			DurationMs: result.DurationMs,
+		}, result.Items, next)
+		if err != nil {
+			return err
+		}
+
+		totalItems += result.ItemsCount
+		totalNew += saveResult.Inserted
+		fmt.Printf("  ✓ page %-4d — %d items (%d new) in %dms\n", next.Page-1, result.ItemsCount, saveResult.Inserted, result.DurationMs)
+		return nil
+	})
+
+	fmt.Printf("\nDone: %d page(s), %d items (%d new)\n", fetched, totalItems, totalNew)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: backfill stopped: %v\n", err)
+		return fmt.Errorf("backfill error")
+	}
+
+	return nil
+}
+
+// reportConcurrency is how many of a report's queries run at once; more
+// than the store's connection pool would only queue for a connection
+const reportConcurrency = 4
+
+// runParallel runs tasks concurrently, at most limit at a time, waits for
+// all of them and returns the first task's error, in the order given
+func runParallel(limit int, tasks ...func() error) error {
+	sem := make(chan struct{}, limit)
+	errs := make([]error, len(tasks))
+	var wg sync.WaitGroup
+	for i, task := range tasks {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			defer sentry.Recover()
+			errs[i] = task()
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runReport executes the report command
+func runReport(format, sourceName, since string, exact, diff bool, sample int, columnsFlag, by string) error {
+	if sample < 0 {
+		return fmt.Errorf("--sample must not be negative")
+	}
+	switch by {
+	case reportBySource:
+	case reportByGroup, reportByTag:
+		if diff || sample > 0 {
+			return fmt.Errorf("--diff and --sample only apply to --by %s", reportBySource)
+		}
+	default:
+		return fmt.Errorf("invalid --by: %s (must be %s, %s or %s)", by, reportBySource, reportByGroup, reportByTag)
+	}
+	// Fetch stats are kept per source over all time, tag counts are
+	// computed from the stored items
+	if by == reportByTag {
+		if exact || columnsFlag != "" {
+			return fmt.Errorf("--exact and --columns don't apply to --by %s", reportByTag)
+		}
+	} else if since != "" {
+		return fmt.Errorf("--since only applies to --by %s", reportByTag)
+	}
+	tagFilter := storage.ItemFilter{Source: sourceName}
+	if since != "" {
+		window, err := parseWindow(since)
+		if err != nil {
+			return err
+		}
+		tagFilter.Since = time.Now().Add(-window)
+	}
+
+	// Load config
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("config error")
+	}
+	columns, err := tableColumns(cfg, "report", columnsFlag)
+	if err != nil {
+		return err
+	}
+
+	// Open database
+	store, err := storage.Open(cfg.Settings.DatabaseDSN())
+	if err != nil {
+		printDatabaseError("open database", err)
+		return fmt.Errorf("database error")
+	}
+	defer store.Close()
+
+	if by == reportByTag {
+		tags, err := store.GetTagStats(tagFilter)
+		if err != nil {
+			printDatabaseError("get tag stats", err)
+			return fmt.Errorf("stats error")
+		}
+		return outputTagStats(format, tags)
+	}
+
+	// The report's queries are independent, so they run side by side on
+	// the store's connection pool
+	getStats := store.GetFetchStats
+	if exact {
+		getStats = store.GetFetchStatsExact
+	}
+	var stats []storage.FetchStats
+	var prev *storage.ReportSnapshot
+	var totalItems int
+	var recent map[string][]storage.FeedItem
+	err = runParallel(reportConcurrency,
+		func() error {
+			var err error
+			if stats, err = getStats(); err != nil {
+				printDatabaseError("get stats", err)
+				return fmt.Errorf("stats error")
+			}
+			return nil
+		},
+		func() error {
+			if !diff {
+				return nil
+			}
+			var err error
+			if prev, err = store.LatestReportSnapshot(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return fmt.Errorf("database error")
+			}
+			return nil
+		},
+		func() error {
+			if diff {
+				return nil
+			}
+			var err error
+			if totalItems, err = store.GetAllItemsCount(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to get total items: %v\n", err)
+			}
+			return nil
+		},
+		func() error {
+			if diff || sample == 0 {
+				return nil
+			}
+			var err error
+			if recent, err = store.GetRecentItems(sample); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// Every report is snapshotted, unfiltered, so the next --diff has
+	// something to compare against
+	if err := store.SaveReportSnapshot(stats); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	if diff {
+		if prev == nil {
+			fmt.Println("No previous report to compare against; saved this one as the baseline.")
+			return nil
+		}
+		var deltas []storage.SourceDelta
+		for _, d := range storage.DiffStats(prev.Stats, stats) {
+			if sourceName == "" || d.Source == sourceName {
+				deltas = append(deltas, d)
+			}
+		}
+		return outputDiff(format, prev.TakenAt, deltas)
+	}
+
+	// Filter by source if requested
+	if sourceName != "" {
+		var filtered []storage.FetchStats
+		for _, stat := range stats {
+			if stat.Source == sourceName {
+				filtered = append(filtered, stat)
+			}
+		}
+		stats = filtered
+	}
+
+	label := "Source"
+	if by == reportByGroup {
+		groups := make(map[string]string, len(cfg.Feeds))
+		for _, feed := range cfg.Feeds {
+			groups[feed.Name] = feed.Group
+		}
+		stats = storage.GroupFetchStats(stats, groups, ungroupedLabel)
+		label = "Group"
+	}
+
+	// Output based on format
+	switch format {
+	case "json":
+		return outputJSON(stats, totalItems, recent, strings.ToLower(label)+"s")
+	case "csv":
+		return outputCSV(stats, recent, label)
+	case "table":
+		return outputTable(stats, totalItems, recent, columns, label)
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+// runSources executes the sources command
+func runSources(format string) error {
+	// Load config
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("config error")
+	}
+
+	// Open database
+	store, err := storage.Open(cfg.Settings.DatabaseDSN())
+	if err != nil {
+		printDatabaseError("open database", err)
+		return fmt.Errorf("database error")
+	}
+	defer store.Close()
+
+	// Get stats
+	stats, err := store.GetFetchStats()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to get stats: %v\n", err)
+		return fmt.Errorf("stats error")
+	}
+
+	// Create a map for quick lookup
+	statsMap := make(map[string]storage.FetchStats)
+	for _, stat := range stats {
+		statsMap[stat.Source] = stat
+	}
+
+	failures, err := store.GetConsecutiveFailures()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to get stats: %v\n", err)
+		return fmt.Errorf("stats error")
+	}
+	counts, err := store.GetItemCountsBySource()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to get stats: %v\n", err)
+		return fmt.Errorf("stats error")
+	}
+	rows, err := sourceRows(store, cfg, statsMap, failures, counts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to get stats: %v\n", err)
+		return fmt.Errorf("stats error")
+	}
+
+	switch format {
+	case "table":
+	case "json":
+		return outputSourcesJSON(rows)
+	case "csv":
+		return outputSourcesCSV(rows)
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+
+	// Display configured sources
+	// The Budget column only appears once a host has a budget
+	budgeted := len(cfg.Settings.Budgets) > 0
+	table := tablewriter.NewWriter(os.Stdout)
+	if budgeted {
+		table.Header("Source", "URL", "Type", "Items", "Status", "Budget")
+	} else {
+		table.Header("Source", "URL", "Type", "Items", "Status")
+	}
+
+	for i, feed := range cfg.Feeds {
+		status := "never fetched"
+		stat, ok := statsMap[feed.Name]
+		if ok {
+			if stat.LastSuccess != nil {
+				status = "✓ active"
+			} else {
+				status = "✗ failing"
+			}
+		}
+		if rows[i].Stale && stat.LastSuccess != nil {
+			status = fmt.Sprintf("⚠ stale (newest item %s)", *rows[i].NewestItem)
+		}
+		if !rows[i].Enabled {
+			status = "disabled"
+		}
+
+		if budgeted {
+			table.Append(feed.Name, currentRedactor().String(feed.Describe()), feed.FeedType, fmt.Sprintf("%d", rows[i].Items), status, rows[i].Budget.String())
+		} else {
+			table.Append(feed.Name, currentRedactor().String(feed.Describe()), feed.FeedType, fmt.Sprintf("%d", rows[i].Items), status)
+		}
+	}
+
+	table.Render()
+	return nil
+}
+
+// runSourcesAdd appends feed to the config file
+func runSourcesAdd(feed config.Feed) error {
+	if err := config.AppendFeeds(configPath, []config.Feed{feed}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("config error")
+	}
+
+	fmt.Printf("Added %s (%s) to %s\n", feed.Name, feed.URL, configPath)
+	auditFeedAdds([]config.Feed{feed}, "feed_type", []string{feed.FeedType})
+	return nil
+}
+
+// runSourcesRemove removes the named feed from the config file. Its
+// stored items are kept.
+func runSourcesRemove(name string) error {
+	if err := config.RemoveFeed(configPath, name); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("config error")
+	}
+
+	fmt.Printf("Removed %s from %s (its stored items are kept)\n", name, configPath)
+	auditConfigChanges(storage.AuditFeedRemove, map[string]string{"feed": name})
+	return nil
+}
+
+// runSourcesEdit changes the named feed in the config file with update
+func runSourcesEdit(name string, update func(*config.Feed)) error {
+	var before, after config.Feed
+	err := config.UpdateFeed(configPath, name, func(feed *config.Feed) {
+		before = *feed
+		update(feed)
+		after = *feed
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("config error")
+	}
+
+	params := map[string]string{"feed": name}
+	if after.URL != before.URL {
+		params["url"] = currentRedactor().String(after.URL)
+	}
+	if after.FeedType != before.FeedType {
+		params["feed_type"] = after.FeedType
+	}
+	if after.RefreshIntervalSecs != before.RefreshIntervalSecs {
+		params["refresh_interval_secs"] = strconv.Itoa(after.RefreshIntervalSecs)
+	}
+	if after.Group != before.Group {
+		params["group"] = after.Group
+	}
+	if len(params) == 1 {
+		fmt.Printf("%s is unchanged\n", name)
+		return nil
+	}
+
+	fmt.Printf("Updated %s in %s\n", name, configPath)
+	auditConfigChanges(storage.AuditFeedEdit, params)
+	return nil
+}
+
+// sourceRow is a configured source as listed by sources --format json|csv
+type sourceRow struct {
+	Name                string  `json:"name"`
+	URL                 string  `json:"url"`
+	Type                string  `json:"type"`
+	Enabled             bool    `json:"enabled"`
+	Items               int     `json:"items"`
+	LastSuccess         *string `json:"last_success"`
+	ErrorRate           float64 `json:"error_rate"`
+	ConsecutiveFailures int     `json:"consecutive_failures"`
+	NewestItem          *string `json:"newest_item"`
+	Stale               bool    `json:"stale"`
+	// Budget is the request quota usage of the source's host, if it has
+	// a budget
+	Budget *budgetUsage `json:"budget"`
+}
+
+// budgetUsage is how much of a host's request budget is spent
+type budgetUsage struct {
+	Host     string `json:"host"`
+	LastHour int    `json:"last_hour"`
+	PerHour  int    `json:"per_hour,omitempty"`
+	LastDay  int    `json:"last_day"`
+	PerDay   int    `json:"per_day,omitempty"`
+}
+
+// String formats usage against each window the budget limits, e.g.
+// "312/5000 per hour"
+func (b *budgetUsage) String() string {
+	if b == nil {
+		return ""
+	}
+	var parts []string
+	if b.PerHour > 0 {
+		parts = append(parts, fmt.Sprintf("%d/%d per hour", b.LastHour, b.PerHour))
+	}
+	if b.PerDay > 0 {
+		parts = append(parts, fmt.Sprintf("%d/%d per day", b.LastDay, b.PerDay))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sourceRows builds a row per configured feed
+func sourceRows(store storage.Store, cfg *config.Config, statsMap map[string]storage.FetchStats, failures, counts map[string]int) ([]sourceRow, error) {
+	now := time.Now()
+	rows := make([]sourceRow, 0, len(cfg.Feeds))
+	for _, feed := range cfg.Feeds {
+		row := sourceRow{
+			Name:                feed.Name,
+			URL:                 currentRedactor().String(feed.URL),
+			Type:                feed.FeedType,
+			Enabled:             feed.IsEnabled(),
+			Items:               counts[feed.Name],
+			ConsecutiveFailures: failures[feed.Name],
+		}
+		if stat, ok := statsMap[feed.Name]; ok {
+			row.LastSuccess = stat.LastSuccess
+			if stat.TotalFetches > 0 {
+				row.ErrorRate = float64(stat.ErrorCount) / float64(stat.TotalFetches) * 100
+			}
+		}
+
+		newest, stale, err := checkStale(store, cfg, feed, now)
+		if err != nil {
+			return nil, err
+		}
+		if !newest.IsZero() {
+			formatted := newest.Format(time.RFC3339)
+			row.NewestItem = &formatted
+		}
+		row.Stale = stale
+
+		if u, err := url.Parse(feed.URL); err == nil {
+			host := strings.ToLower(u.Hostname())
+			if budget, ok := cfg.Settings.BudgetFor(host); ok {
+				usage, err := store.HostUsage(host, now)
+				if err != nil {
+					return nil, err
+				}
+				row.Budget = &budgetUsage{
+					Host:     host,
+					LastHour: usage.LastHour,
+					PerHour:  budget.PerHour,
+					LastDay:  usage.LastDay,
+					PerDay:   budget.PerDay,
+				}
+			}
+		}
+
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// outputSourcesJSON outputs sources in JSON format
+func outputSourcesJSON(rows []sourceRow) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]interface{}{"sources": rows})
+}
+
+// outputSourcesCSV outputs sources in CSV format
+func outputSourcesCSV(rows []sourceRow) error {
+	writer := csv.NewWriter(os.Stdout)
+	defer writer.Flush()
+
+	if err := writer.Write([]string{"name", "url", "type", "enabled", "items", "last_success", "error_rate", "consecutive_failures", "newest_item", "stale", "budget"}); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		lastSuccess, newestItem := "", ""
+		if row.LastSuccess != nil {
+			lastSuccess = *row.LastSuccess
+		}
+		if row.NewestItem != nil {
+			newestItem = *row.NewestItem
+		}
+		if err := writer.Write([]string{
+			row.Name,
+			row.URL,
+			row.Type,
+			fmt.Sprintf("%t", row.Enabled),
+			fmt.Sprintf("%d", row.Items),
+			lastSuccess,
+			fmt.Sprintf("%.1f", row.ErrorRate),
+			fmt.Sprintf("%d", row.ConsecutiveFailures),
+			newestItem,
+			fmt.Sprintf("%t", row.Stale),
+			row.Budget.String(),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runLogin executes the login command
+func runLogin(feedName string) error {
+	cfg, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	feed := findFeed(cfg, feedName)
+	if feed == nil {
+		return fmt.Errorf("unknown feed: %s", feedName)
+	}
+	if feed.Login == nil {
+		return fmt.Errorf("feed '%s' has no login configuration", feedName)
+	}
+
+	if err := applyCookieKey(store, cfg); err != nil {
+		return err
+	}
+
+	f := fetcher.NewFetcher(cfg)
+	f.SetCookieStore(store)
+
+	n, err := f.Login(context.Background(), *feed)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("login error")
+	}
+
+	fmt.Printf("Logged in to %s: %d cookie(s) stored\n", feed.Name, n)
+	return nil
+}
+
+// Feed state keys recording a feed's WebSub subscription
+const (
+	stateWebSubHub     = "websub_hub"
+	stateWebSubTopic   = "websub_topic"
+	stateWebSubSecret  = "websub_secret"
+	stateWebSubRenewAt = "websub_renew_at"
+)
+
+// webSubLease is the lease requested from hubs; leases are renewed once
+// 90% of what the hub granted has passed
+const webSubLease = 7 * 24 * time.Hour
+
+// webSubClient sends subscription requests to hubs
+var webSubClient = &http.Client{Timeout: 30 * time.Second}
+
+// runServe executes the serve command. It fetches every feed once,
+// subscribes to the WebSub hubs the feeds advertise, and then fetches a
+// feed whenever its hub reports an update, renewing leases as they near
+// expiry. Stream feeds are consumed for as long as it runs.
+func runServe(listen, callback string, apiOn bool, apiCache string) error {
+	cacheTTL := time.Duration(0)
+	if apiCache != "0" {
+		var err error
+		if cacheTTL, err = config.ParseDuration(apiCache); err != nil || cacheTTL < 0 {
+			return fmt.Errorf("--api-cache must be a duration such as 30s, or 0, got '%s'", apiCache)
+		}
+	}
+
+	var base *url.URL
+	if callback != "" {
+		var err error
+		if base, err = url.Parse(callback); err != nil || !base.IsAbs() {
+			return fmt.Errorf("--callback must be an absolute URL, got '%s'", callback)
+		}
+	}
+
+	cfg, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if err := applyUniquenessScope(store, cfg); err != nil {
+		return err
+	}
+	if err := applyCookieKey(store, cfg); err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	f := fetcher.NewFetcher(cfg)
+	f.SetJournal(store)
+	f.SetState(store)
+	f.SetCookieStore(store)
+	f.SetRequestMeter(store)
+
+	// WebSub needs a public callback; without one only streams are served
+	var handler *websub.Handler
+	var notifications <-chan string
+	mux := http.NewServeMux()
+	if base != nil {
+		handler = websub.NewHandler(base.Path, func(sub websub.Subscription) {
+			// A hub that names no lease is taken to grant the one asked for
+			lease := webSubLease
+			if !sub.LeaseUntil.IsZero() {
+				lease = time.Until(sub.LeaseUntil)
+			}
+			renewAt := time.Now().Add(lease * 9 / 10)
+			if err := store.SetFeedState(sub.Feed, map[string]string{stateWebSubRenewAt: renewAt.UTC().Format(time.RFC3339)}); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+			fmt.Printf("  ↻ %-30s — subscription verified by %s\n", sub.Feed, sub.Hub)
+		})
+		notifications = handler.Notifications()
+
+		// Keep accepting pushes for subscriptions made by an earlier run
+		for _, feed := range cfg.Feeds {
+			if sub, _, ok := storedSubscription(store, feed.Name, callback); ok {
+				handler.Expect(sub)
+			}
+		}
+
+		mux.Handle(strings.TrimSuffix(base.Path, "/")+"/", handler)
+		fmt.Printf("Serving WebSub callbacks on %s at %s\n", listen, callback)
+	}
+
+	var apiFetches <-chan string
+	if apiOn {
+		var names []string
+		for _, feed := range cfg.Feeds {
+			names = append(names, feed.Name)
+		}
+		apiServer := api.NewServer(store, names)
+		apiServer.SetVersion(version)
+		apiServer.SetConfigPath(configPath)
+		apiFetches = apiServer.Fetches()
+		mux.Handle(api.Prefix, apiServer)
+		mux.Handle(api.SpecPath, apiServer)
+
+		// Results are recorded through the watched store, so cached
+		// answers about a source are dropped as soon as it changes
+		apiServer.SetCacheTTL(cacheTTL)
+		store = apiServer.Watch(store)
+
+		if tokens, err := store.ListAPITokens(); err == nil && len(tokens) == 0 {
+			fmt.Fprintf(os.Stderr, "Warning: no API tokens exist, so every API request will be refused; create one with 'feedpulse token create'\n")
+		}
+		fmt.Printf("Serving the API on %s at %s (described at %s, docs at %s)\n", listen, api.Prefix, api.SpecPath, api.DocsPath)
+	}
+
+	serverErr := make(chan error, 1)
+	if base != nil || apiOn {
+		server := &http.Server{Addr: listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			serverErr <- server.ListenAndServe()
+		}()
+		defer server.Close()
+	}
+
+	// Stream feeds deliver results continuously; they are recorded here,
+	// one at a time, like every other result
+	streamed := make(chan fetcher.FetchResult, 64)
+	for _, feed := range cfg.Feeds {
+		if !feed.IsStream() || !feed.IsEnabled() {
+			continue
+		}
+		fmt.Printf("Streaming %s from %s\n", feed.Name, currentRedactor().String(feed.URL))
+		go func(feed config.Feed) {
+			defer sentry.Recover()
+			f.Stream(ctx, feed, func(result fetcher.FetchResult) {
+				select {
+				case streamed <- result:
+				case <-ctx.Done():
+				}
+			})
+		}(feed)
+	}
+
+	if a := cfg.Settings.Archive; a != nil && a.Enabled {
+		fmt.Printf("Archiving new item URLs to the Wayback Machine, one request every %s\n", a.ArchiveInterval())
+		go runArchiver(ctx, store, cfg)
+	}
+
+	if n := cfg.Settings.Notify; n != nil && n.Telegram != nil && n.Telegram.Commands {
+		if bot, err := telegramBot(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: telegram bot: %v\n", err)
+		} else {
+			fmt.Printf("Answering Telegram bot commands from chat %s\n", n.Telegram.ChatID)
+			go runTelegramBot(ctx, store, bot)
+		}
+	}
+
+	fmt.Printf("Fetching %d feeds...\n", len(cfg.Feeds))
+
+	var summary fetchSummary
+	results := f.FetchStages(ctx, func(stage []fetcher.FetchResult) {
+		for _, result := range stage {
+			recordResult(store, cfg, clock.System, result, &summary)
+		}
+	})
+	for _, result := range results {
+		subscribeWebSub(ctx, store, handler, result, callback)
+	}
+	if _, err := store.PruneJournal(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	sendQueuedSaves(ctx, store, cfg)
+	notifyNewItems(ctx, store, cfg, summary.fresh)
+	summary.fresh = nil
+
+	renew := time.NewTicker(time.Minute)
+	defer renew.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Fprintf(os.Stderr, "\nShutting down...\n")
+			return nil
+		case err := <-serverErr:
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return fmt.Errorf("server error")
+		case name := <-notifications:
+			// Notifications arriving during the fetch queue another one
+			handler.Done(name)
+			result, err := f.FetchFeed(ctx, name)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				continue
+			}
+			fmt.Printf("Push from hub for %s:\n", name)
+			recordResult(store, cfg, clock.System, result, &summary)
+			subscribeWebSub(ctx, store, handler, result, callback)
+			if _, err := store.PruneJournal(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		case name := <-apiFetches:
+			result, err := f.FetchFeed(ctx, name)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				continue
+			}
+			fmt.Printf("Fetch requested through the API for %s:\n", name)
+			recordResult(store, cfg, clock.System, result, &summary)
+			subscribeWebSub(ctx, store, handler, result, callback)
+			if _, err := store.PruneJournal(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		case result := <-streamed:
+			recordResult(store, cfg, clock.System, result, &summary)
+		case <-renew.C:
+			// Pushed, streamed and API-triggered fetches queue saves
+			// too, and their new items are announced a minute at a time
+			sendQueuedSaves(ctx, store, cfg)
+			notifyNewItems(ctx, store, cfg, summary.fresh)
+			summary.fresh = nil
+			if handler == nil {
+				continue
+			}
+			for _, feed := range cfg.Feeds {
+				sub, renewAt, ok := storedSubscription(store, feed.Name, callback)
+				if ok && !renewAt.IsZero() && time.Now().After(renewAt) {
+					requestSubscription(ctx, store, handler, sub)
+				}
+			}
+		}
+	}
+}
+
+// storedSubscription loads feed's WebSub subscription from its feed state,
+// with the time it is due for renewal (zero until a hub verified it)
+func storedSubscription(store storage.Store, feed, callback string) (websub.Subscription, time.Time, bool) {
+	state, err := store.GetFeedState(feed)
+	if err != nil || state[stateWebSubHub] == "" {
+		return websub.Subscription{}, time.Time{}, false
+	}
+
+	sub := websub.Subscription{
+		Feed:     feed,
+		Hub:      state[stateWebSubHub],
+		Topic:    state[stateWebSubTopic],
+		Callback: websub.CallbackURL(callback, feed),
+		Secret:   state[stateWebSubSecret],
+	}
+	renewAt, _ := time.Parse(time.RFC3339, state[stateWebSubRenewAt])
+	return sub, renewAt, true
+}
+
+// subscribeWebSub subscribes to the hub a fetch result advertised, unless
+// the feed already holds a lease there that isn't due for renewal
+func subscribeWebSub(ctx context.Context, store storage.Store, handler *websub.Handler, result fetcher.FetchResult, callback string) {
+	if handler == nil || !result.Success || result.Hub == "" {
+		return
+	}
+
+	sub, renewAt, ok := storedSubscription(store, result.Source, callback)
+	if ok && sub.Hub == result.Hub && sub.Topic == result.Topic && time.Now().Before(renewAt) {
+		return
+	}
+	if !ok || sub.Hub != result.Hub {
+		secret, err := websub.NewSecret()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			return
+		}
+		sub = websub.Subscription{Feed: result.Source, Callback: websub.CallbackURL(callback, result.Source), Secret: secret}
+	}
+	sub.Hub, sub.Topic = result.Hub, result.Topic
+
+	requestSubscription(ctx, store, handler, sub)
+}
+
+// requestSubscription records sub and asks its hub to subscribe; the hub
+// confirms through the handler
+func requestSubscription(ctx context.Context, store storage.Store, handler *websub.Handler, sub websub.Subscription) {
+	if err := store.SetFeedState(sub.Feed, map[string]string{
+		stateWebSubHub:    sub.Hub,
+		stateWebSubTopic:  sub.Topic,
+		stateWebSubSecret: sub.Secret,
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return
+	}
+
+	// Expect the verification before the hub can send it
+	handler.Pending(sub, websub.ModeSubscribe)
+	if err := websub.Request(ctx, webSubClient, sub, websub.ModeSubscribe, webSubLease); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", sub.Feed, err)
+		return
+	}
+	fmt.Printf("  ↻ %-30s — subscription requested at %s\n", sub.Feed, sub.Hub)
+}
+
+// outputTable outputs stats in table format
+func outputTable(stats []storage.FetchStats, totalItems int, recent map[string][]storage.FeedItem, columns []string, label string) error {
+	headers := columnHeaders(columns)
+	for i, column := range columns {
+		if column == "source" {
+			headers[i] = label
+		}
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.Header(headers...)
+
+	for _, stat := range stats {
+		errorRate := "0.0%"
+		if stat.TotalFetches > 0 {
+			rate := float64(stat.ErrorCount) / float64(stat.TotalFetches) * 100
+			errorRate = fmt.Sprintf("%.1f%%", rate)
+		}
+
+		lastSuccess := "never"
+		if stat.LastSuccess != nil {
+			// Parse and format timestamp
+			if t, err := time.Parse(time.RFC3339, *stat.LastSuccess); err == nil {
+				lastSuccess = tableTime(t)
+			} else {
+				lastSuccess = *stat.LastSuccess
+			}
+		}
+
+		table.Append(columnRow(columns, map[string]string{
+			"source":       stat.Source,
+			"items":        fmt.Sprintf("%d", stat.ItemsCount),
+			"errors":       fmt.Sprintf("%d", stat.ErrorCount),
+			"error_rate":   errorRate,
+			"last_success": lastSuccess,
+			"fetches":      fmt.Sprintf("%d", stat.TotalFetches),
+		})...)
+
+		// Sampled items go on indented rows under their source, in the
+		// first column
+		for _, item := range recent[stat.Source] {
+			row := columnRow(columns, nil)
+			row[0] = "↳ " + item.Title
+			table.Append(row...)
+		}
+	}
+
+	table.Render()
+	fmt.Printf("\nTotal: %d items across %d %ss\n", totalItems, len(stats), strings.ToLower(label))
+	return nil
+}
+
+// outputDiff outputs the changes since the report taken at since
+func outputDiff(format string, since time.Time, deltas []storage.SourceDelta) error {
+	switch format {
+	case "json":
+		output := map[string]interface{}{
+			"since":   since.Format(time.RFC3339),
+			"changes": deltas,
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(output)
+
+	case "csv":
+		writer := csv.NewWriter(os.Stdout)
+		defer writer.Flush()
+
+		if err := writer.Write([]string{"Source", "Change", "Items", "Fetches", "Errors", "Error Rate", "Previous Error Rate"}); err != nil {
+			return err
+		}
+		for _, d := range deltas {
+			if err := writer.Write([]string{
+				d.Source,
+				d.Status,
+				fmt.Sprintf("%+d", d.ItemsDelta),
+				fmt.Sprintf("%d", d.FetchesDelta),
+				fmt.Sprintf("%d", d.ErrorsDelta),
+				fmt.Sprintf("%.1f", d.ErrorRate),
+				fmt.Sprintf("%.1f", d.PrevErrorRate),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case "table":
+		fmt.Printf("Changes since report at %s\n\n", since.In(currentZone()).Format("2006-01-02 15:04"))
+		if len(deltas) == 0 {
+			fmt.Println("No changes.")
+			return nil
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.Header("Source", "Change", "Items", "Fetches", "Errors", "Error Rate")
+		for _, d := range deltas {
+			table.Append(
+				d.Source,
+				d.Status,
+				fmt.Sprintf("%+d", d.ItemsDelta),
+				fmt.Sprintf("%d", d.FetchesDelta),
+				fmt.Sprintf("%d", d.ErrorsDelta),
+				fmt.Sprintf("%.1f%% (was %.1f%%)", d.ErrorRate, d.PrevErrorRate),
+			)
+		}
+		table.Render()
+		return nil
+
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+// outputDigestTable outputs a digest as one table per topic
+func outputDigestTable(topics []storage.DigestTopic, since string) error {
+	fmt.Printf("Top items of the last %s\n", since)
+	if len(topics) == 0 {
+		fmt.Println("\nNo items.")
+		return nil
+	}
+
+	for _, topic := range topics {
+		fmt.Printf("\n%s\n", topic.Tag)
+		table := tablewriter.NewWriter(os.Stdout)
+		table.Header("Title", "Sources", "URL")
+		for _, item := range topic.Items {
+			table.Append(item.Title, strings.Join(item.Sources, ", "), item.URL)
+		}
+		table.Render()
+	}
+	return nil
+}
+
+// outputDigestMarkdown outputs a digest as a Markdown list per topic
+func outputDigestMarkdown(topics []storage.DigestTopic, since string) error {
+	fmt.Printf("# Top items of the last %s\n", since)
+	if len(topics) == 0 {
+		fmt.Println("\nNo items.")
+		return nil
+	}
+
+	escaper := strings.NewReplacer("[", "\\[", "]", "\\]")
+	for _, topic := range topics {
+		fmt.Printf("\n## %s\n\n", topic.Tag)
+		for _, item := range topic.Items {
+			title := escaper.Replace(item.Title)
+			if item.URL != "" {
+				title = fmt.Sprintf("[%s](<%s>)", title, item.URL)
+			}
+			fmt.Printf("- %s (%s)\n", title, strings.Join(item.Sources, ", "))
+		}
+	}
+	return nil
+}
+
+// outputDigestHTML outputs a digest as a standalone HTML document
+func outputDigestHTML(topics []storage.DigestTopic, since string) error {
+	var b strings.Builder
+	heading := html.EscapeString("Top items of the last " + since)
+
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&b, "<title>%s</title>\n</head>\n<body>\n<h1>%s</h1>\n", heading, heading)
+	if len(topics) == 0 {
+		b.WriteString("<p>No items.</p>\n")
+	}
+	for _, topic := range topics {
+		fmt.Fprintf(&b, "<h2>%s</h2>\n<ul>\n", html.EscapeString(topic.Tag))
+		for _, item := range topic.Items {
+			title := html.EscapeString(item.Title)
+			if item.URL != "" {
+				title = fmt.Sprintf("<a href=\"%s\">%s</a>", html.EscapeString(item.URL), title)
+			}
+			fmt.Fprintf(&b, "<li>%s <small>(%s)</small></li>\n", title, html.EscapeString(strings.Join(item.Sources, ", ")))
+		}
+		b.WriteString("</ul>\n")
+	}
+	b.WriteString("</body>\n</html>\n")
+
+	_, err := os.Stdout.WriteString(b.String())
+	return err
+}
+
+// reportSource is a source row of the JSON report, with the titles of
+// its sampled items
+type reportSource struct {
+	storage.FetchStats
+	Recent []string `json:",omitempty"`
+}
+
+// recentTitles returns the titles of items
+func recentTitles(items []storage.FeedItem) []string {
+	var titles []string
+	for _, item := range items {
+		titles = append(titles, item.Title)
+	}
+	return titles
+}
+
+// outputJSON outputs stats in JSON format
+func outputJSON(stats []storage.FetchStats, totalItems int, recent map[string][]storage.FeedItem, key string) error {
+	sources := make([]reportSource, 0, len(stats))
+	for _, stat := range stats {
+		sources = append(sources, reportSource{FetchStats: stat, Recent: recentTitles(recent[stat.Source])})
+	}
+
+	output := map[string]interface{}{
+		key:           sources,
+		"total_items": totalItems,
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(output)
+}
+
+// outputCSV outputs stats in CSV format
+func outputCSV(stats []storage.FetchStats, recent map[string][]storage.FeedItem, label string) error {
+	writer := csv.NewWriter(os.Stdout)
+	defer writer.Flush()
+
+	// Write header; sampled titles get a column only when requested
+	header := []string{label, "Items", "Errors", "Error Rate", "Last Success"}
+	if recent != nil {
+		header = append(header, "Recent Items")
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	// Write rows
+	for _, stat := range stats {
+		errorRate := "0.0"
+		if stat.TotalFetches > 0 {
+			errorRate = fmt.Sprintf("%.1f", float64(stat.ErrorCount)/float64(stat.TotalFetches)*100)
+		}
+
+		lastSuccess := "never"
+		if stat.LastSuccess != nil {
+			lastSuccess = *stat.LastSuccess
+		}
+
+		row := []string{
+			stat.Source,
+			fmt.Sprintf("%d", stat.ItemsCount),
+			fmt.Sprintf("%d", stat.ErrorCount),
+			errorRate,
+			lastSuccess,
+		}
+		if recent != nil {
+			row = append(row, strings.Join(recentTitles(recent[stat.Source]), "\n"))
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// buildInfo describes how this binary was built, for bug reports
+type buildInfo struct {
+	Version      string   `json:"version"`
+	Commit       string   `json:"commit"`
+	BuildDate    string   `json:"build_date"`
+	GoVersion    string   `json:"go_version"`
+	Platform     string   `json:"platform"`
+	SQLiteDriver string   `json:"sqlite_driver"`
+	Features     []string `json:"features"`
+}
+
+// currentBuildInfo collects build metadata from ldflags, falling back to
+// the VCS stamp the Go toolchain embeds
+func currentBuildInfo() buildInfo {
+	info := buildInfo{
+		Version:      version,
+		Commit:       commit,
+		BuildDate:    buildDate,
+		GoVersion:    runtime.Version(),
+		Platform:     runtime.GOOS + "/" + runtime.GOARCH,
+		SQLiteDriver: storage.SQLiteDriver,
+		Features:     []string{storage.SQLiteFeature},
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		modified := false
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = setting.Value
+				}
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+		if modified && commit == "" && info.Commit != "" {
+			info.Commit += "-dirty"
+		}
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildDate == "" {
+		info.BuildDate = "unknown"
+	}
+	return info
+}
+
+// runVersion executes the version command
+func runVersion(asJSON bool) error {
+	info := currentBuildInfo()
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(info)
+	}
+
+	fmt.Printf("feedpulse %s\n", info.Version)
+	fmt.Printf("  commit:   %s\n", info.Commit)
+	fmt.Printf("  built:    %s\n", info.BuildDate)
+	fmt.Printf("  go:       %s %s\n", info.GoVersion, info.Platform)
+	fmt.Printf("  sqlite:   %s\n", info.SQLiteDriver)
+	fmt.Printf("  features: %s\n", strings.Join(info.Features, ", "))
+	return nil
+}
+
+// initDefaultPresets are the presets init selects when the user accepts
+// the default
+var initDefaultPresets = []string{"hackernews-top", "lobsters-hottest"}
+
+// runInit executes the init command, reading answers from in
+func runInit(in io.Reader, force bool) error {
+	if _, err := os.Stat(configPath); err == nil && !force {
+		fmt.Fprintf(os.Stderr, "Error: config file already exists: %s (use --force to overwrite)\n", configPath)
+		return fmt.Errorf("config error")
+	}
+
+	r := bufio.NewReader(in)
+	presets := config.Presets()
+
+	fmt.Printf("Creating %s. Press Enter to accept the [default].\n\n", configPath)
+	fmt.Println("Preset sources:")
+	var defaults []string
+	for i, p := range presets {
+		fmt.Printf("  %d) %-20s %s\n", i+1, p.Key, p.Description)
+		for _, key := range initDefaultPresets {
+			if p.Key == key {
+				defaults = append(defaults, strconv.Itoa(i+1))
+			}
+		}
+	}
+
+	var feeds []config.Feed
+	for feeds == nil {
+		answer, err := prompt(r, "Sources to follow (numbers, comma-separated)", strings.Join(defaults, ","))
+		if err != nil {
+			return err
+		}
+		feeds, err = pickPresets(presets, answer)
+		if err != nil {
+			fmt.Printf("  %v\n", err)
+		}
+	}
+
+	dbPath, err := prompt(r, "Database path", "feedpulse.db")
+	if err != nil {
+		return err
+	}
+
+	concurrency := 0
+	for concurrency == 0 {
+		answer, err := prompt(r, "Feeds to fetch at once (1-50)", "5")
+		if err != nil {
+			return err
+		}
+		n, err := strconv.Atoi(answer)
+		if err != nil || n < 1 || n > 50 {
+			fmt.Println("  enter a number between 1 and 50")
+			continue
+		}
+		concurrency = n
+	}
+
+	cfg := &config.Config{
+		Settings: config.Settings{
+			MaxConcurrency:     concurrency,
+			DefaultTimeoutSecs: 10,
+			RetryMax:           3,
+			RetryBaseDelayMs:   500,
+			DatabasePath:       dbPath,
+		},
+		Feeds: feeds,
+	}
+	if err := config.WriteConfig(configPath, cfg, force); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("config error")
+	}
+
+	fmt.Printf("\nWrote %s with %d feed(s). Fetch them with:\n  feedpulse fetch --config %s\n", configPath, len(feeds), configPath)
+	return nil
+}
+
+// prompt asks question and returns the trimmed answer, or def if the
+// answer is empty or input has ended
+func prompt(r *bufio.Reader, question, def string) (string, error) {
+	fmt.Printf("%s [%s]: ", question, def)
+	line, err := r.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read answer: %w", err)
+	}
+	if err == io.EOF {
+		fmt.Println()
+	}
+
+	answer := strings.TrimSpace(line)
+	if answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+// pickPresets returns the feeds of the presets listed in answer, by number
+// or key
+func pickPresets(presets []config.Preset, answer string) ([]config.Feed, error) {
+	var feeds []config.Feed
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(answer, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		var preset *config.Preset
+		if n, err := strconv.Atoi(field); err == nil && n >= 1 && n <= len(presets) {
+			preset = &presets[n-1]
+		} else {
+			for i := range presets {
+				if presets[i].Key == field {
+					preset = &presets[i]
+				}
+			}
+		}
+		if preset == nil {
+			return nil, fmt.Errorf("unknown source: %s", field)
+		}
+
+		if !seen[preset.Key] {
+			seen[preset.Key] = true
+			feeds = append(feeds, preset.Feed)
+		}
+	}
+
+	if len(feeds) == 0 {
+		return nil, fmt.Errorf("choose at least one source")
+	}
+	return feeds, nil
+}
+
+// presetJSON is the JSON form of a preset in presets list
+type presetJSON struct {
+	Key         string            `json:"key"`
+	Description string            `json:"description"`
+	Notes       string            `json:"notes,omitempty"`
+	Name        string            `json:"name"`
+	URL         string            `json:"url"`
+	FeedType    string            `json:"feed_type"`
+	Headers     map[string]string `json:"headers,omitempty"`
+}
+
+// runPresetsList prints the built-in presets
+func runPresetsList(format string) error {
+	presets := config.Presets()
+
+	switch format {
+	case "table":
+	case "json":
+		out := make([]presetJSON, 0, len(presets))
+		for _, p := range presets {
+			out = append(out, presetJSON{
+				Key:         p.Key,
+				Description: p.Description,
+				Notes:       p.Notes,
+				Name:        p.Feed.Name,
+				URL:         p.Feed.URL,
+				FeedType:    p.Feed.FeedType,
+				Headers:     p.Feed.Headers,
+			})
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(out)
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.Header("Preset", "Feed Name", "Description", "Notes")
+	for _, p := range presets {
+		table.Append(p.Key, p.Feed.Name, p.Description, p.Notes)
+	}
+	table.Render()
+
+	fmt.Println("\nAdd one with: feedpulse presets add <preset>")
+	return nil
+}
+
+// runPresetsAdd appends the named presets to the config file
+func runPresetsAdd(keys []string) error {
+	var feeds []config.Feed
+	for _, key := range keys {
+		preset, ok := config.FindPreset(key)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: unknown preset: %s (see feedpulse presets list)\n", key)
+			return fmt.Errorf("config error")
+		}
+		feeds = append(feeds, preset.Feed)
+	}
+
+	if err := config.AppendFeeds(configPath, feeds); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("config error")
+	}
+
+	for _, feed := range feeds {
+		fmt.Printf("Added %s (%s) to %s\n", feed.Name, feed.URL, configPath)
+	}
+	auditFeedAdds(feeds, "preset", keys)
+	return nil
+}
+
+// auditFeedAdds records feeds added to the config file, each with key set
+// to its entry of values
+func auditFeedAdds(feeds []config.Feed, key string, values []string) {
+	params := make([]map[string]string, len(feeds))
+	for i, feed := range feeds {
+		params[i] = map[string]string{"feed": feed.Name, "url": currentRedactor().String(feed.URL), key: values[i]}
+	}
+	auditConfigChanges(storage.AuditFeedAdd, params...)
+}
+
+// auditConfigChanges records an action on the config file once per
+// entry of params. The config file is already changed, so this is best
+// effort.
+func auditConfigChanges(action string, params ...map[string]string) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return
+	}
+	store, err := storage.Open(cfg.Settings.DatabaseDSN())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: not audited: %v\n", err)
+		return
+	}
+	defer store.Close()
+	for _, p := range params {
+		recordAudit(store, action, p)
+	}
+}
+
+// testFeedPreview is how many parsed items test-feed shows
+const testFeedPreview = 5
+
+// testFeedHeaders are the response headers test-feed shows, when present
+var testFeedHeaders = []string{
+	"Content-Type", "Content-Encoding", "ETag", "Last-Modified", "Cache-Control",
+	"Retry-After", "X-RateLimit-Remaining", "X-RateLimit-Reset", "Link",
+}
+
+// runTestFeed fetches target, a configured feed name or a URL, once and
+// prints what it parses to
+func runTestFeed(target, feedType string) error {
+	isURL := strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://")
+
+	cfg, err := loadConfig()
+	if err != nil && !isURL {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("config error")
+	}
+	if err != nil {
+		// A bare URL can be tested without a config file
+		cfg = &config.Config{Settings: config.Settings{
+			DefaultTimeoutSecs: 10,
+			UniquenessScope:    storage.ScopeSource,
+		}}
+	}
+
+	var feed *config.Feed
+	for i := range cfg.Feeds {
+		if cfg.Feeds[i].Name == target || cfg.Feeds[i].URL == target {
+			feed = &cfg.Feeds[i]
+			break
+		}
+	}
+	if feed == nil {
+		if !isURL {
+			fmt.Fprintf(os.Stderr, "Error: feed not found: %s\n", target)
+			return fmt.Errorf("config error")
+		}
+		feed = &config.Feed{Name: target, URL: target, FeedType: feedType}
+		if err := feed.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return fmt.Errorf("config error")
+		}
+	}
+
+	f := fetcher.NewFetcher(cfg)
+	probe, err := f.Probe(context.Background(), *feed)
+	if probe != nil {
+		fmt.Printf("%s %s\n", probe.Method, probe.URL)
+		if probe.StatusCode != 0 {
+			fmt.Printf("HTTP %s (%d ms, %d bytes)\n", probe.Status, probe.DurationMs, probe.Bytes)
+		}
+		for _, name := range testFeedHeaders {
+			if value := probe.Header.Get(name); value != "" {
+				fmt.Printf("  %s: %s\n", name, value)
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("fetch error")
+	}
+
+	fmt.Printf("\nStructure: %s\n", probe.Structure)
+	fmt.Printf("Items: %d", len(probe.Items))
+	if probe.Truncated > 0 {
+		fmt.Printf(" (%d dropped by max_items_per_fetch)", probe.Truncated)
+	}
+	if probe.Filtered > 0 {
+		fmt.Printf(" (%d dropped by filters)", probe.Filtered)
+	}
+	fmt.Println()
+
+	if len(probe.Items) > 0 {
+		preview := probe.Items
+		if len(preview) > testFeedPreview {
+			preview = preview[:testFeedPreview]
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.Header("Title", "URL", "Timestamp", "Tags")
+		for _, item := range preview {
+			timestamp := ""
+			if item.Timestamp != nil {
+				timestamp = *item.Timestamp
+			}
+			table.Append(item.Title, item.URL, timestamp, strings.Join(item.Tags, ", "))
+		}
+		table.Render()
+	}
+
+	if len(probe.Warnings) > 0 {
+		fmt.Println("\nWarnings:")
+		for _, warning := range probe.Warnings {
+			fmt.Printf("  - %s\n", warning)
+		}
+	}
+
+	fmt.Println("\nNothing was written to the database.")
+	return nil
+}
+
+// discoverFormats names the formats of discovered feeds
+var discoverFormats = map[string]string{
+	discover.FormatRSS:      "RSS",
+	discover.FormatAtom:     "Atom",
+	discover.FormatJSONFeed: "JSON Feed",
+}
+
+// runDiscover finds the feeds the page at target advertises, checks
+// that each parses, and offers to append those that do to the config
+// file, reading the answer from in
+func runDiscover(in io.Reader, target string, yes bool) error {
+	if !strings.Contains(target, "://") {
+		target = "https://" + target
+	}
+	site, err := url.Parse(target)
+	if err != nil || (site.Scheme != "http" && site.Scheme != "https") || site.Host == "" {
+		return fmt.Errorf("invalid URL: %s", target)
+	}
+
+	// Feeds can be discovered without a config file, just not added
+	cfg, cfgErr := loadConfig()
+	if cfgErr != nil {
+		cfg = &config.Config{Settings: config.Settings{
+			DefaultTimeoutSecs: 10,
+			UniquenessScope:    storage.ScopeSource,
+		}}
+	}
+
+	ctx, cancel := fetchContext()
+	defer cancel()
+
+	client := &http.Client{Timeout: time.Duration(cfg.Settings.DefaultTimeoutSecs) * time.Second}
+	candidates, err := discover.Find(ctx, client, site.String())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("fetch error")
+	}
+	if len(candidates) == 0 {
+		fmt.Printf("No feeds advertised on %s.\n", site)
+		return nil
+	}
+
+	taken := make(map[string]bool, len(cfg.Feeds))
+	configured := make(map[string]string, len(cfg.Feeds))
+	for _, feed := range cfg.Feeds {
+		taken[feed.Name] = true
+		configured[feed.URL] = feed.Name
+	}
+	feeds := make([]config.Feed, len(candidates))
+	for i, c := range candidates {
+		if name, ok := configured[c.URL]; ok {
+			feeds[i] = c.Feed(name)
+			continue
+		}
+		feeds[i] = c.Feed(discoverName(c, site, taken))
+		taken[feeds[i].Name] = true
+	}
+
+	// Each candidate is probed as configured, mapping included
+	probeCfg := *cfg
+	probeCfg.Feeds = feeds
+	f := fetcher.NewFetcher(&probeCfg)
+
+	var working []config.Feed
+	failed := 0
+	table := tablewriter.NewWriter(os.Stdout)
+	table.Header("#", "Name", "Format", "URL", "Check")
+	for i, feed := range feeds {
+		if _, ok := configured[feed.URL]; ok {
+			table.Append("", feed.Name, discoverFormats[candidates[i].Format], feed.URL, "already configured")
+			continue
+		}
+
+		number, check := "", ""
+		probe, err := f.Probe(ctx, feed)
+		switch {
+		case err != nil:
+			check = err.Error()
+			failed++
+		case len(probe.Items) == 0 && len(probe.Warnings) > 0:
+			check = "no items: " + probe.Warnings[0]
+			failed++
+		case len(probe.Items) == 0:
+			check = "no items"
+			failed++
+		default:
+			working = append(working, feed)
+			number = strconv.Itoa(len(working))
+			check = fmt.Sprintf("%d items", len(probe.Items))
+		}
+		table.Append(number, feed.Name, discoverFormats[candidates[i].Format], feed.URL, check)
+	}
+	table.Render()
+
+	if len(working) == 0 && failed > 0 {
+		fmt.Println("\nNone of the new feeds parsed; see feedpulse test-feed for details.")
+		return nil
+	}
+	if len(working) == 0 {
+		fmt.Println("\nEvery feed found is already configured.")
+		return nil
+	}
+	if cfgErr != nil {
+		fmt.Printf("\nNot added: %v\nCreate a config with feedpulse init first.\n", cfgErr)
+		return nil
+	}
+
+	chosen := working[:1]
+	if !yes {
+		r := bufio.NewReader(in)
+		for {
+			answer, err := prompt(r, fmt.Sprintf("\nFeeds to add to %s (numbers, comma-separated, or none)", configPath), "1")
+			if err != nil {
+				return err
+			}
+			if answer == "none" {
+				fmt.Println("Nothing added.")
+				return nil
+			}
+			if chosen, err = pickFeeds(working, answer); err == nil {
+				break
+			}
+			fmt.Printf("  %v\n", err)
+		}
+	}
+
+	if err := config.AppendFeeds(configPath, chosen); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("config error")
+	}
+	sites := make([]string, len(chosen))
+	for i, feed := range chosen {
+		fmt.Printf("Added %s (%s) to %s\n", feed.Name, feed.URL, configPath)
+		sites[i] = site.String()
+	}
+	auditFeedAdds(chosen, "discovered_on", sites)
+	return nil
+}
+
+// discoverName names a discovered feed after its title, or the site's
+// host, adding its format and then a number until the name isn't taken
+func discoverName(c discover.Candidate, site *url.URL, taken map[string]bool) string {
+	name := c.Title
+	if name == "" {
+		name = strings.TrimPrefix(site.Hostname(), "www.")
+	}
+	if !taken[name] {
+		return name
+	}
+	name = fmt.Sprintf("%s (%s)", name, discoverFormats[c.Format])
+	unique := name
+	for n := 2; taken[unique]; n++ {
+		unique = fmt.Sprintf("%s %d", name, n)
+	}
+	return unique
+}
+
+// pickFeeds returns the feeds listed in answer by number
+func pickFeeds(feeds []config.Feed, answer string) ([]config.Feed, error) {
+	var picked []config.Feed
+	seen := make(map[int]bool)
+	for _, field := range strings.Split(answer, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 1 || n > len(feeds) {
+			return nil, fmt.Errorf("unknown feed: %s (enter numbers from 1 to %d)", field, len(feeds))
+		}
+		if !seen[n] {
+			seen[n] = true
+			picked = append(picked, feeds[n-1])
+		}
+	}
+	if len(picked) == 0 {
+		return nil, fmt.Errorf("choose at least one feed, or none")
+	}
+	return picked, nil
+}
+
+// runDebugProxy fetches feed once, capturing its HTTP exchanges to
+// output. Feed state isn't used, so the API answers in full rather than
+// with 304 Not Modified; cookies are, so logged-in feeds stay logged in.
+func runDebugProxy(name, output string, maxBody int) error {
+	if maxBody < 0 {
+		return fmt.Errorf("invalid --max-body: %d (must be 0 or more)", maxBody)
+	}
+
+	cfg, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	feed := findFeed(cfg, name)
+	if feed == nil {
+		fmt.Fprintf(os.Stderr, "Error: feed not found: %s\n", name)
+		return fmt.Errorf("config error")
+	}
+	if feed.IsStream() {
+		fmt.Fprintf(os.Stderr, "Error: feed '%s' is a stream; it can't be captured\n", name)
+		return fmt.Errorf("config error")
+	}
+	if err := applyCookieKey(store, cfg); err != nil {
+		return err
+	}
+
+	out, err := os.Create(output)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("debug error")
+	}
+	defer out.Close()
+
+	w := bufio.NewWriter(out)
+	fmt.Fprintf(w, "feedpulse %s debug capture of feed '%s'\n", version, feed.Name)
+	fmt.Fprintf(w, "Started %s\n\n", time.Now().UTC().Format(time.RFC3339))
+
+	ctx, cancel := fetchContext()
+	defer cancel()
+
+	f := fetcher.NewFetcher(cfg)
+	f.SetCookieStore(store)
+	f.SetCapture(w, maxBody)
+	result, err := f.FetchFeed(ctx, feed.Name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("fetch error")
+	}
+
+	if result.Success {
+		fmt.Fprintf(w, "=== Result: %d items in %d ms\n", result.ItemsCount, result.DurationMs)
+	} else {
+		fmt.Fprintf(w, "=== Result: failed after %d ms: %s\n", result.DurationMs, result.Error)
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to write %s: %v\n", output, err)
+		return fmt.Errorf("debug error")
+	}
+
+	if result.Success {
+		fmt.Printf("%s: %d items in %d ms\n", feed.Name, result.ItemsCount, result.DurationMs)
+	} else {
+		fmt.Printf("%s: failed: %s\n", feed.Name, result.Error)
+	}
+	fmt.Printf("Wrote the HTTP exchanges to %s. No items were saved.\n", output)
+	return nil
+}
+
+// runExplain prints the provenance of an item
+func runExplain(itemID string) error {
+	cfg, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	e, err := store.ExplainItem(itemID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("database error")
+	}
+	if e == nil {
+		fmt.Fprintf(os.Stderr, "Error: item not found: %s\n", itemID)
+		return fmt.Errorf("database error")
+	}
+	blocked, err := store.ListBlocked()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("database error")
+	}
+
+	item := e.Item
+	fmt.Printf("Item %s\n", item.ID)
+	fmt.Printf("  Title:      %s\n", item.Title)
+	fmt.Printf("  URL:        %s\n", item.URL)
+	if item.Timestamp != nil {
+		fmt.Printf("  Published:  %s\n", *item.Timestamp)
+	}
+	if len(item.Tags) > 0 {
+		fmt.Printf("  Tags:       %s\n", strings.Join(item.Tags, ", "))
+	}
+	fmt.Printf("  First seen: %s\n", item.CreatedAt.In(currentZone()).Format(time.RFC3339))
+	if a, err := store.GetArchive(item.URL); err == nil && a != nil {
+		if a.ArchivedURL != "" {
+			fmt.Printf("  Archived:   %s\n", a.ArchivedURL)
+		} else {
+			fmt.Printf("  Archived:   %s\n", a.Status)
+		}
+	}
+
+	fmt.Printf("\nFeed %s\n", item.Source)
+	feedFound := false
+	for _, feed := range cfg.Feeds {
+		if feed.Name == item.Source {
+			fmt.Printf("  %s (%s)\n", currentRedactor().String(feed.URL), feed.FeedType)
+			feedFound = true
+		}
+	}
+	if !feedFound {
+		fmt.Println("  no longer configured")
+	}
+
+	fmt.Println("\nStored by")
+	if run := e.FirstRun; run != nil {
+		slow := ""
+		if run.Slow {
+			slow = " (slow)"
+		}
+		fmt.Printf("  fetch #%d at %s: %s, %d item(s) in %d ms%s\n", run.ID, run.FetchedAt.In(currentZone()).Format(time.RFC3339), run.Status, run.ItemsCount, run.DurationMs, slow)
+		if run.Endpoint != "" {
+			fmt.Printf("  endpoint: %s\n", run.Endpoint)
+		}
+	} else {
+		fmt.Println("  no matching fetch logged (saved by recover or a run whose log is gone)")
+	}
+
+	fmt.Println("\nIdentity")
+	fmt.Printf("  uniqueness scope %s, %s id hash\n", e.Scope, e.IDHash)
+	if len(e.Duplicates) == 0 {
+		fmt.Println("  no other stored item has this URL")
+	} else {
+		fmt.Printf("  %d other stored item(s) share this URL (digest rank counts each source once):\n", len(e.Duplicates))
+		for _, dup := range e.Duplicates {
+			fmt.Printf("    %s from %s, first seen %s\n", dup.ID, dup.Source, dup.CreatedAt.In(currentZone()).Format(time.RFC3339))
+		}
+	}
+
+	fmt.Println("\nFilters")
+	matched := false
+	for _, entry := range blocked {
+		if storage.ItemBlocked([]storage.BlockEntry{entry}, item) {
+			fmt.Printf("  blocklist %s %s matches: new copies are skipped\n", entry.Kind, entry.Value)
+			matched = true
+		}
+	}
+	if !matched {
+		fmt.Println("  no blocklist entry matches")
+	}
+
+	fmt.Println("\nChanges")
+	if len(e.Revisions) == 0 {
+		fmt.Println("  none recorded")
+	}
+	for _, rev := range e.Revisions {
+		changedAt := rev.ChangedAt.In(currentZone()).Format(time.RFC3339)
+		if rev.OldTitle != rev.NewTitle {
+			fmt.Printf("  %s title: %q -> %q\n", changedAt, rev.OldTitle, rev.NewTitle)
+		}
+		if rev.OldURL != rev.NewURL {
+			fmt.Printf("  %s url: %s -> %s\n", changedAt, rev.OldURL, rev.NewURL)
+		}
+	}
+
+	fmt.Println("\nRaw payload")
+	if e.Payload == "" {
+		fmt.Println("  not kept (journaled payloads are pruned once a run is saved)")
+	} else {
+		fmt.Printf("  ...%s...\n", e.Payload)
+	}
+	return nil
+}
+
+// resolveRun finds the run spec names among runs (newest first)
+func resolveRun(runs []storage.FetchLog, spec string) (storage.FetchLog, error) {
+	back := -1
+	switch {
+	case spec == "last":
+		back = 0
+	case spec == "previous":
+		back = 1
+	case strings.HasPrefix(spec, "last~"):
+		n, err := strconv.Atoi(strings.TrimPrefix(spec, "last~"))
+		if err != nil || n < 0 {
+			return storage.FetchLog{}, fmt.Errorf("invalid run: %s", spec)
+		}
+		back = n
+	default:
+		id, err := strconv.Atoi(strings.TrimPrefix(spec, "#"))
+		if err != nil {
+			return storage.FetchLog{}, fmt.Errorf("invalid run: %s (use last, previous, last~N or a fetch ID)", spec)
+		}
+		for _, run := range runs {
+			if run.ID == id {
+				return run, nil
+			}
+		}
+		return storage.FetchLog{}, fmt.Errorf("no recorded items for fetch %d", id)
+	}
+
+	if back >= len(runs) {
+		return storage.FetchLog{}, fmt.Errorf("run %s not recorded: only %d run(s) of this source have item lists", spec, len(runs))
+	}
+	return runs[back], nil
+}
+
+// runDiff prints what changed between two runs of a source
+func runDiff(sourceName, runSpecs, format string) error {
+	specs := strings.Split(runSpecs, ",")
+	if len(specs) != 2 {
+		return fmt.Errorf("--runs takes two runs, e.g. last,previous")
+	}
+
+	_, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	runs, err := store.ListRuns(sourceName, storage.RunsKept)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("database error")
+	}
+
+	var pair [2]storage.FetchLog
+	for i, spec := range specs {
+		if pair[i], err = resolveRun(runs, strings.TrimSpace(spec)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", sourceName, err)
+			return fmt.Errorf("database error")
+		}
+	}
+	from, to := pair[1], pair[0]
+	if from.ID > to.ID {
+		from, to = to, from
+	}
+
+	older, err := store.GetRunItems(from.ID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("database error")
+	}
+	newer, err := store.GetRunItems(to.ID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("database error")
+	}
+	diff := storage.DiffRunItems(older, newer, to.Partial)
+	diff.From, diff.To = from, to
+
+	switch format {
+	case "table":
+	case "json":
+		return outputDiffJSON(sourceName, diff)
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+
+	fmt.Printf("%s: fetch #%d (%s) -> #%d (%s)\n", sourceName,
+		from.ID, from.FetchedAt.In(currentZone()).Format("2006-01-02 15:04"), to.ID, to.FetchedAt.In(currentZone()).Format("2006-01-02 15:04"))
+	if to.Partial {
+		fmt.Printf("Fetch #%d saw only part of the feed, so removed items aren't listed\n", to.ID)
+	}
+	if len(diff.Added)+len(diff.Removed)+len(diff.Changed) == 0 {
+		fmt.Println("No differences")
+		return nil
+	}
+
+	for _, section := range []struct {
+		name  string
+		items []storage.RunItem
+	}{{"Added", diff.Added}, {"Removed", diff.Removed}} {
+		if len(section.items) == 0 {
+			continue
+		}
+		fmt.Printf("\n%s (%d)\n", section.name, len(section.items))
+		table := tablewriter.NewWriter(os.Stdout)
+		table.Header("Title", "URL")
+		for _, item := range section.items {
+			table.Append(item.Title, item.URL)
+		}
+		table.Render()
+	}
+
+	if len(diff.Changed) > 0 {
+		fmt.Printf("\nChanged (%d)\n", len(diff.Changed))
+		table := tablewriter.NewWriter(os.Stdout)
+		table.Header("Field", "Before", "After")
+		for _, c := range diff.Changed {
+			if c.OldTitle != c.NewTitle {
+				table.Append("title", c.OldTitle, c.NewTitle)
+			}
+			if c.OldURL != c.NewURL {
+				table.Append("url", c.OldURL, c.NewURL)
+			}
+		}
+		table.Render()
+	}
+	return nil
+}
+
+// diffItemJSON is an item in diff's JSON output
+type diffItemJSON struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// diffChangeJSON is a changed item in diff's JSON output
+type diffChangeJSON struct {
+	ID       string `json:"id"`
+	OldTitle string `json:"old_title"`
+	OldURL   string `json:"old_url"`
+	NewTitle string `json:"new_title"`
+	NewURL   string `json:"new_url"`
+}
+
+// diffRunJSON identifies a run in diff's JSON output
+type diffRunJSON struct {
+	ID        int    `json:"id"`
+	FetchedAt string `json:"fetched_at"`
+	Partial   bool   `json:"partial"`
+}
+
+// outputDiffJSON prints a run diff as JSON
+func outputDiffJSON(sourceName string, diff storage.RunDiff) error {
+	items := func(in []storage.RunItem) []diffItemJSON {
+		out := make([]diffItemJSON, 0, len(in))
+		for _, item := range in {
+			out = append(out, diffItemJSON{ID: item.ItemID, Title: item.Title, URL: item.URL})
+		}
+		return out
+	}
+	changed := make([]diffChangeJSON, 0, len(diff.Changed))
+	for _, c := range diff.Changed {
+		changed = append(changed, diffChangeJSON{ID: c.ItemID, OldTitle: c.OldTitle, OldURL: c.OldURL, NewTitle: c.NewTitle, NewURL: c.NewURL})
+	}
+
+	out := struct {
+		Source  string           `json:"source"`
+		From    diffRunJSON      `json:"from"`
+		To      diffRunJSON      `json:"to"`
+		Added   []diffItemJSON   `json:"added"`
+		Removed []diffItemJSON   `json:"removed"`
+		Changed []diffChangeJSON `json:"changed"`
+	}{
+		Source:  sourceName,
+		From:    diffRunJSON{ID: diff.From.ID, FetchedAt: diff.From.FetchedAt.Format(time.RFC3339), Partial: diff.From.Partial},
+		To:      diffRunJSON{ID: diff.To.ID, FetchedAt: diff.To.FetchedAt.Format(time.RFC3339), Partial: diff.To.Partial},
+		Added:   items(diff.Added),
+		Removed: items(diff.Removed),
+		Changed: changed,
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
+
+// loadFrontPage lays out the front page from the items stored within since
+func loadFrontPage(store storage.Store, since string, top int) ([]storage.FrontPageItem, error) {
+	window, err := parseWindow(since)
+	if err != nil {
+		return nil, err
+	}
+	if top < 1 {
+		return nil, fmt.Errorf("--top must be at least 1")
+	}
+
+	items, err := store.GetItemsSince(window)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return nil, fmt.Errorf("database error")
+	}
+	curations, err := store.ListCurations()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return nil, fmt.Errorf("database error")
+	}
+	return storage.FrontPage(storage.TopItems(items, 0), curations, top), nil
+}
+
+// runFrontPageShow prints the front page with item IDs for curating
+func runFrontPageShow(since string, top int) error {
+	_, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	page, err := loadFrontPage(store, since, top)
+	if err != nil {
+		return err
+	}
+
+	if len(page) == 0 {
+		fmt.Printf("No items in the last %s\n", since)
+		return nil
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.Header("#", "Pinned", "Title", "Sources", "ID")
+	for i, item := range page {
+		pinned := ""
+		if item.Pinned {
+			pinned = "yes"
+		}
+		table.Append(strconv.Itoa(i+1), pinned, item.Title, strings.Join(item.Sources, ", "), item.ID)
+	}
+	table.Render()
+	return nil
+}
+
+// runFrontPageCurate pins (at position) or hides an item
+func runFrontPageCurate(itemID, state string, position int) error {
+	cfg, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	done := "Pinned"
+	params := map[string]string{"item": itemID}
+	if state == storage.CurationPinned {
+		err = store.Pin(itemID, position)
+		if position > 0 {
+			params["position"] = strconv.Itoa(position)
+		}
+	} else {
+		done = "Hid"
+		err = store.Hide(itemID)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("database error")
+	}
+	if state == storage.CurationPinned {
+		recordAudit(store, storage.AuditPin, params)
+	} else {
+		recordAudit(store, storage.AuditHide, params)
+	}
+
+	fmt.Printf("%s %s\n", done, itemID)
+
+	// Pinning is how items are starred for auto-save rules
+	if state == storage.CurationPinned && cfg.Settings.ReadLater != nil {
+		if e, err := store.ExplainItem(itemID); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		} else if e != nil {
+			if err := queueAutoSaves(store, cfg, []storage.FeedItem{e.Item}, true); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+			sendQueuedSaves(context.Background(), store, cfg)
+		}
+	}
+	return nil
+}
+
+// runFrontPageReset unpins or unhides an item
+func runFrontPageReset(itemID string) error {
+	_, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	removed, err := store.Uncurate(itemID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("database error")
+	}
+
+	if !removed {
+		fmt.Printf("%s was neither pinned nor hidden\n", itemID)
+		return nil
+	}
+	recordAudit(store, storage.AuditUncurate, map[string]string{"item": itemID})
+	fmt.Printf("Reset %s\n", itemID)
+	return nil
+}
+
+// runPublish writes the front page in format to output (stdout if empty)
+func runPublish(since string, top int, format, output, title string) error {
+	_, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	page, err := loadFrontPage(store, since, top)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	switch format {
+	case "html":
+		writeFrontPageHTML(&b, page, title)
+	case "markdown":
+		writeFrontPageMarkdown(&b, page, title)
+	case "json":
+		out := struct {
+			Title string                  `json:"title"`
+			Items []storage.FrontPageItem `json:"items"`
+		}{Title: title, Items: page}
+		if out.Items == nil {
+			out.Items = []storage.FrontPageItem{}
+		}
+		encoder := json.NewEncoder(&b)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(out); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+
+	if output == "" {
+		_, err := os.Stdout.WriteString(b.String())
+		return err
+	}
+	if err := os.WriteFile(output, []byte(b.String()), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to write %s: %v\n", output, err)
+		return fmt.Errorf("publish error")
+	}
+	fmt.Printf("Published %d item(s) to %s\n", len(page), output)
+	return nil
+}
+
+// writeFrontPageMarkdown renders the front page as a Markdown list
+func writeFrontPageMarkdown(w io.Writer, page []storage.FrontPageItem, title string) {
+	fmt.Fprintf(w, "# %s\n", title)
+	if len(page) == 0 {
+		fmt.Fprintln(w, "\nNo items.")
+		return
+	}
+
+	fmt.Fprintln(w)
+	escaper := strings.NewReplacer("[", "\\[", "]", "\\]")
+	for _, item := range page {
+		text := escaper.Replace(item.Title)
+		if item.URL != "" {
+			text = fmt.Sprintf("[%s](<%s>)", text, item.URL)
+		}
+		if item.Pinned {
+			text = "**" + text + "**"
+		}
+		fmt.Fprintf(w, "1. %s (%s)\n", text, strings.Join(item.Sources, ", "))
+	}
+}
+
+// writeFrontPageHTML renders the front page as a standalone HTML document
+func writeFrontPageHTML(w io.Writer, page []storage.FrontPageItem, title string) {
+	heading := html.EscapeString(title)
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(w, "<title>%s</title>\n</head>\n<body>\n<h1>%s</h1>\n", heading, heading)
+	if len(page) == 0 {
+		fmt.Fprintln(w, "<p>No items.</p>")
+	} else {
+		fmt.Fprintln(w, "<ol>")
+		for _, item := range page {
+			text := html.EscapeString(item.Title)
+			if item.URL != "" {
+				text = fmt.Sprintf("<a href=\"%s\">%s</a>", html.EscapeString(item.URL), text)
+			}
+			class := ""
+			if item.Pinned {
+				class = ` class="pinned"`
+			}
+			fmt.Fprintf(w, "<li%s>%s <small>(%s)</small></li>\n", class, text, html.EscapeString(strings.Join(item.Sources, ", ")))
+		}
+		fmt.Fprintln(w, "</ol>")
+	}
+	fmt.Fprintln(w, "</body>\n</html>")
+}
+
+// runTokenCreate creates an API token and prints it once
+func runTokenCreate(name, scope string) error {
+	_, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	token, t, err := store.CreateAPIToken(name, scope)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("token error")
+	}
+	recordAudit(store, storage.AuditTokenCreate, map[string]string{"id": t.ID, "name": t.Name, "scope": t.Scope})
+
+	fmt.Printf("Created %s token %s\n\n", t.Scope, t.ID)
+	fmt.Printf("  %s\n\n", token)
+	fmt.Println("Store it now: it can't be shown again. Send it as 'Authorization: Bearer <token>'.")
+	return nil
+}
+
+// runTokenList lists API tokens
+func runTokenList() error {
+	_, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	tokens, err := store.ListAPITokens()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("database error")
+	}
+	if len(tokens) == 0 {
+		fmt.Println("No API tokens. Create one with 'feedpulse token create --scope read'.")
+		return nil
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.Header("ID", "Name", "Scope", "Created", "Last Used")
+	for _, t := range tokens {
+		lastUsed := "never"
+		if t.LastUsedAt != nil {
+			lastUsed = t.LastUsedAt.In(currentZone()).Format("2006-01-02 15:04")
+		}
+		table.Append(t.ID, t.Name, t.Scope, t.CreatedAt.In(currentZone()).Format("2006-01-02 15:04"), lastUsed)
+	}
+	table.Render()
+	return nil
+}
+
+// runTokenRevoke revokes an API token
+func runTokenRevoke(id string) error {
+	_, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	revoked, err := store.RevokeAPIToken(id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("database error")
+	}
+	if !revoked {
+		fmt.Fprintf(os.Stderr, "Error: no token with ID %s\n", id)
+		return fmt.Errorf("token error")
+	}
+	recordAudit(store, storage.AuditTokenRevoke, map[string]string{"id": id})
+	fmt.Printf("Revoked token %s\n", id)
+	return nil
+}
+
+// cliActor identifies the user running the CLI in the audit log
+func cliActor() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return "cli:" + u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return "cli:" + name
+	}
+	return "cli"
+}
+
+// recordAudit logs a mutating CLI action; a failure to log it only warns,
+// since the action itself already happened
+func recordAudit(store storage.Store, action string, params map[string]string) {
+	if err := store.RecordAudit(cliActor(), action, params); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// runAudit lists audit log entries
+func runAudit(actor, action, since string, limit int, format string) error {
+	if format != "table" && format != "json" {
+		return fmt.Errorf("invalid format: %s (must be table or json)", format)
+	}
+
+	filter := storage.AuditFilter{Actor: actor, Action: action, Limit: limit}
+	if since != "" {
+		window, err := parseWindow(since)
+		if err != nil {
+			return err
+		}
+		filter.Since = time.Now().Add(-window)
+	}
+
+	_, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	entries, err := store.ListAudit(filter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("database error")
+	}
+
+	if format == "json" {
+		if entries == nil {
+			entries = []storage.AuditEntry{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No audited actions.")
+		return nil
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.Header("Time", "Actor", "Action", "Parameters")
+	for _, e := range entries {
+		keys := make([]string, 0, len(e.Params))
+		for k := range e.Params {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		params := make([]string, 0, len(keys))
+		for _, k := range keys {
+			params = append(params, k+"="+e.Params[k])
+		}
+		table.Append(e.At.In(currentZone()).Format("2006-01-02 15:04:05"), e.Actor, e.Action, strings.Join(params, " "))
+	}
+	table.Render()
+	return nil
+}
+
+// runErrors lists the distinct errors of failed fetches, most recently
+// seen first
+func runErrors(source, since string, last int, format string) error {
+	if format != "table" && format != "json" {
+		return fmt.Errorf("invalid format: %s (must be table or json)", format)
+	}
+
+	filter := storage.ErrorFilter{Source: source, Limit: last}
+	if since != "" {
+		window, err := parseWindow(since)
+		if err != nil {
+			return err
+		}
+		filter.Since = time.Now().Add(-window)
+	}
+
+	_, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	groups, err := store.ListErrors(filter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("database error")
+	}
+
+	if format == "json" {
+		if groups == nil {
+			groups = []storage.ErrorGroup{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(groups)
+	}
+
+	if len(groups) == 0 {
+		fmt.Println("No failed fetches.")
+		return nil
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.Header("Source", "Count", "First Seen", "Last Seen", "Error")
+	for _, g := range groups {
+		table.Append(g.Source, fmt.Sprintf("%d", g.Count), tableTime(g.FirstSeen), tableTime(g.LastSeen), g.Sample)
+	}
+	table.Render()
+	return nil
+}
+
+// runFailures lists recorded parse failures, newest first
+func runFailures(source string, limit int, format string) error {
+	if format != "table" && format != "json" {
+		return fmt.Errorf("invalid format: %s (must be table or json)", format)
+	}
+
+	_, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	failures, err := store.ListParseFailures(source, limit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("database error")
+	}
+
+	if format == "json" {
+		if failures == nil {
+			failures = []storage.ParseFailure{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(failures)
+	}
+
+	if len(failures) == 0 {
+		fmt.Println("No recorded parse failures.")
+		return nil
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.Header("ID", "Captured", "Source", "Size", "Error")
+	for _, f := range failures {
+		size := fmt.Sprintf("%d", f.Size)
+		if f.Truncated() {
+			size = fmt.Sprintf("%d (first %d kept)", f.Size, f.Stored)
+		}
+		table.Append(fmt.Sprintf("%d", f.ID), tableTime(f.CapturedAt), f.Source, size, f.Error)
+	}
+	table.Render()
+	fmt.Println("\nUse 'feedpulse failures show <id>' to see a response.")
+	return nil
+}
+
+// runFailureShow writes a recorded response to output, or stdout
+func runFailureShow(idArg, output string) error {
+	id, err := strconv.ParseInt(idArg, 10, 64)
+	if err != nil || id < 1 {
+		return fmt.Errorf("invalid failure ID: %s", idArg)
+	}
+
+	_, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	failure, err := store.GetParseFailure(id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("database error")
+	}
+	if failure == nil {
+		return fmt.Errorf("no recorded failure %d (only the last %d of each source are kept)", id, storage.FailuresKept)
+	}
+
+	if failure.Truncated() {
+		fmt.Fprintf(os.Stderr, "Note: only the first %d of %d bytes were recorded\n", failure.Stored, failure.Size)
+	}
+	if output == "" {
+		_, err := os.Stdout.Write(failure.Payload)
+		return err
+	}
+	if err := os.WriteFile(output, failure.Payload, 0644); err != nil {
+		return fmt.Errorf("failed to write response: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %d bytes of %s's response to %s\n", len(failure.Payload), failure.Source, output)
+	return nil
+}
+
+// databaseHint suggests what to do about a database error, if SQLite
+// reported the database locked or corrupt
+func databaseHint(err error) string {
+	switch {
+	case storage.IsCorrupt(err):
+		return "the database file is damaged; run 'feedpulse db repair' to recover what it holds (the original is kept as a backup)"
+	case storage.IsBusy(err):
+		return "another process holds the database lock, most likely a running 'feedpulse fetch' or 'serve'; try again once it finishes"
+	}
+	return ""
+}
+
+// printDatabaseError reports a failure to do what (e.g. "open database")
+// with a remediation hint when there is one
+func printDatabaseError(what string, err error) {
+	fmt.Fprintf(os.Stderr, "Error: failed to %s: %v\n", what, err)
+	// Another process holding the database is routine, not a bug
+	if !storage.IsBusy(err) {
+		reportError(what, err)
+	}
+	if hint := databaseHint(err); hint != "" {
+		fmt.Fprintf(os.Stderr, "Hint: %s\n", hint)
+	}
+}
+
+// runDoctor checks the config, the database file and anything left over
+// from interrupted or deferred fetches
+func runDoctor() error {
+	problems := 0
+	fail := func(format string, args ...interface{}) {
+		problems++
+		fmt.Printf("  ✗ "+format+"\n", args...)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fail("config: %v", err)
+		return fmt.Errorf("doctor found %d problem(s)", problems)
+	}
+	fmt.Printf("  ✓ config: %d feed(s) in %s\n", len(cfg.Feeds), configPath)
+
+	dbPath := cfg.Settings.DatabasePath
+	if cfg.Settings.DatabaseURL == "" {
+		if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+			fmt.Printf("  ✓ database: %s doesn't exist yet; the first fetch creates it\n", dbPath)
+			return nil
+		}
+
+		integrity, err := storage.CheckIntegrity(dbPath)
+		switch {
+		case err != nil:
+			fail("database: %v", err)
+			if hint := databaseHint(err); hint != "" {
+				fmt.Printf("      %s\n", hint)
+			}
+		case len(integrity) > 0:
+			fail("database: integrity check failed (%d problem(s)), e.g. %s", len(integrity), integrity[0])
+			fmt.Println("      run 'feedpulse db repair' to recover what it holds into a new file")
+		default:
+			fmt.Printf("  ✓ database: %s passed the integrity check\n", dbPath)
+		}
+	}
+
+	if queued, damaged, err := storage.ReadQueue(storage.QueuePath(dbPath)); err != nil {
+		fail("queue: %v", err)
+	} else {
+		if len(damaged) > 0 {
+			fail("queue: damaged line(s) %v; the next fetch drops them", damaged)
+		}
+		if len(queued) > 0 {
+			fmt.Printf("  ! queue: %d result(s) fetched while the database was unavailable; the next fetch saves them\n", len(queued))
+		}
+	}
+
+	store, err := storage.Open(cfg.Settings.DatabaseDSN())
+	if err != nil {
+		fail("database: can't be opened: %v", err)
+		if hint := databaseHint(err); hint != "" {
+			fmt.Printf("      %s\n", hint)
+		}
+	} else {
+		defer store.Close()
+		if store.Postgres() {
+			fmt.Println("  ✓ database: connected to PostgreSQL")
+		}
+		if pending, err := store.PendingJournal(); err != nil {
+			fail("journal: %v", err)
+		} else if len(pending) > 0 {
+			fmt.Printf("  ! journal: %d payload(s) from an interrupted fetch; run 'feedpulse recover'\n", len(pending))
+		}
+	}
+
+	if problems > 0 {
+		return fmt.Errorf("doctor found %d problem(s)", problems)
+	}
+	fmt.Println("\nNo problems found.")
+	return nil
+}
+
+// runDBRepair checks the database, recovers it into a new file and swaps
+// that in, keeping the original next to it
+func runDBRepair(force bool) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("config error")
+	}
+	if cfg.Settings.DatabaseURL != "" {
+		fmt.Fprintln(os.Stderr, "Error: db repair recovers SQLite files; a PostgreSQL database (database_url) has its own tools")
+		return fmt.Errorf("repair error")
+	}
+	dbPath := cfg.Settings.DatabasePath
+
+	fmt.Printf("Checking %s...\n", dbPath)
+	problems, err := storage.CheckIntegrity(dbPath)
+	if err != nil {
+		printDatabaseError("check database", err)
+		return fmt.Errorf("database error")
+	}
+	if len(problems) == 0 && !force {
+		fmt.Println("The database passed the integrity check; nothing to repair (use --force to rebuild it anyway).")
+		return nil
+	}
+	for i, problem := range problems {
+		if i == 5 {
+			fmt.Printf("  ... and %d more\n", len(problems)-i)
+			break
+		}
+		fmt.Printf("  %s\n", problem)
+	}
+
+	before, err := storage.TableCounts(dbPath)
+	if err != nil {
+		// The schema itself is unreadable; recovery may still find rows
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	repaired := dbPath + ".repaired"
+	fmt.Printf("Recovering into %s...\n", repaired)
+	method, err := storage.RecoverDatabase(dbPath, repaired)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: recovery failed: %v\n", err)
+		return fmt.Errorf("repair error")
+	}
+
+	if leftover, err := storage.CheckIntegrity(repaired); err != nil || len(leftover) > 0 {
+		fmt.Fprintf(os.Stderr, "Error: the recovered database is not sound either (%v %v); %s was left in place\n", leftover, err, dbPath)
+		return fmt.Errorf("repair error")
+	}
+	after, err := storage.TableCounts(repaired)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("repair error")
+	}
+
+	tables := make([]string, 0, len(after))
+	for table := range after {
+		tables = append(tables, table)
+	}
+	for table := range before {
+		if _, ok := after[table]; !ok {
+			tables = append(tables, table)
+		}
+	}
+	sort.Strings(tables)
+
+	count := func(counts map[string]int, table string) string {
+		n, ok := counts[table]
+		switch {
+		case !ok:
+			return "-"
+		case n < 0:
+			return "unreadable"
+		}
+		return strconv.Itoa(n)
+	}
+	fmt.Printf("\nRecovered with %s:\n", method)
+	table := tablewriter.NewWriter(os.Stdout)
+	table.Header("Table", "Before", "After")
+	for _, name := range tables {
+		table.Append(name, count(before, name), count(after, name))
+	}
+	table.Render()
+
+	backup := fmt.Sprintf("%s.corrupt-%s", dbPath, time.Now().Format("20060102-150405"))
+	if err := storage.SwapDatabase(dbPath, repaired, backup); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "The recovered database is at %s\n", repaired)
+		return fmt.Errorf("repair error")
+	}
+
+	if store, err := storage.NewStorage(dbPath); err == nil {
+		recordAudit(store, storage.AuditRepair, map[string]string{"method": method, "backup": backup, "problems": strconv.Itoa(len(problems))})
+		store.Close()
+	}
+
+	fmt.Printf("\nReplaced %s with the recovered database; the original is kept at %s\n", dbPath, backup)
+	return nil
+}
+
+// Environment variables holding Internet Archive S3-style keys; without
+// them captures are made anonymously
+const (
+	waybackAccessKeyEnv = "FEEDPULSE_WAYBACK_ACCESS_KEY"
+	waybackSecretKeyEnv = "FEEDPULSE_WAYBACK_SECRET_KEY"
+)
+
+// archiveBatch is how many queued URLs serve processes per pass
+const archiveBatch = 20
+
+// newWaybackClient creates a Save Page Now client with keys from the environment
+func newWaybackClient() *archive.Client {
+	return archive.NewClient(os.Getenv(waybackAccessKeyEnv), os.Getenv(waybackSecretKeyEnv))
+}
+
+// printArchiveEntry prints the new state of a processed archive entry
+func printArchiveEntry(entry storage.ArchiveEntry) {
+	switch {
+	case entry.Status == storage.ArchiveDone:
+		fmt.Printf("  ✓ %s — %s\n", entry.URL, entry.ArchivedURL)
+	case entry.Status == storage.ArchiveFailed:
+		fmt.Printf("  ✗ %s — gave up after %d attempts: %s\n", entry.URL, entry.Attempts, entry.LastError)
+	case entry.Status == storage.ArchivePending && entry.LastError != "":
+		fmt.Printf("  ! %s — attempt %d failed, will retry: %s\n", entry.URL, entry.Attempts, entry.LastError)
+	default:
+		fmt.Printf("  … %s — capture in progress\n", entry.URL)
+	}
+}
+
+// runArchiveRun executes the archive run command
+func runArchiveRun(limit int) error {
+	cfg, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if cfg.Settings.Archive == nil || !cfg.Settings.Archive.Enabled {
+		fmt.Fprintf(os.Stderr, "Warning: archiving is disabled in the config; only URLs already queued are processed\n")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	n, err := archive.Process(ctx, store, newWaybackClient(), limit, cfg.Settings.Archive.ArchiveInterval(), printArchiveEntry)
+	if errors.Is(err, archive.ErrRateLimited) {
+		fmt.Fprintf(os.Stderr, "Warning: %v; stopping, the rest stay queued\n", err)
+	} else if err != nil && ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("archive error")
+	}
+
+	fmt.Printf("Processed %d queued URL(s)\n", n)
+	return nil
+}
+
+// runArchiver drains the archive queue in the background until ctx is done
+func runArchiver(ctx context.Context, store storage.Store, cfg *config.Config) {
+	defer sentry.Recover()
+	client := newWaybackClient()
+	interval := cfg.Settings.Archive.ArchiveInterval()
+	for {
+		n, err := archive.Process(ctx, store, client, archiveBatch, interval, printArchiveEntry)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Back off for a while after a rate limit, and poll the queue
+		// slowly while it is empty
+		wait := interval
+		if errors.Is(err, archive.ErrRateLimited) {
+			fmt.Fprintf(os.Stderr, "Warning: %v; pausing archiving for 10 minutes\n", err)
+			wait = 10 * time.Minute
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			wait = time.Minute
+		} else if n == 0 {
+			wait = time.Minute
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// runArchiveStatus executes the archive status command
+func runArchiveStatus(limit int, format string) error {
+	if format != "table" && format != "json" {
+		return fmt.Errorf("invalid format: %s (must be table or json)", format)
+	}
+
+	_, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	counts, err := store.ArchiveCounts()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("database error")
+	}
+	recent, err := store.RecentArchives(limit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("database error")
+	}
+
+	if format == "json" {
+		if recent == nil {
+			recent = []storage.ArchiveEntry{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(map[string]interface{}{"counts": counts, "recent": recent})
+	}
+
+	fmt.Printf("Pending: %d, submitted: %d, archived: %d, failed: %d\n",
+		counts[storage.ArchivePending], counts[storage.ArchiveSubmitted], counts[storage.ArchiveDone], counts[storage.ArchiveFailed])
+	if len(recent) == 0 {
+		return nil
+	}
+
+	fmt.Println()
+	table := tablewriter.NewWriter(os.Stdout)
+	table.Header("Updated", "Source", "URL", "Status", "Archived / Error")
+	for _, e := range recent {
+		detail := e.ArchivedURL
+		if detail == "" {
+			detail = e.LastError
+		}
+		table.Append(e.UpdatedAt.In(currentZone()).Format("2006-01-02 15:04:05"), e.Source, e.URL, e.Status, detail)
+	}
+	table.Render()
+	return nil
+}
+
+// Environment variables holding read-later credentials
+const (
+	pocketConsumerKeyEnv    = "FEEDPULSE_POCKET_CONSUMER_KEY"
+	pocketAccessTokenEnv    = "FEEDPULSE_POCKET_ACCESS_TOKEN"
+	instapaperUsernameEnv   = "FEEDPULSE_INSTAPAPER_USERNAME"
+	instapaperPasswordEnv   = "FEEDPULSE_INSTAPAPER_PASSWORD"
+	wallabagClientIDEnv     = "FEEDPULSE_WALLABAG_CLIENT_ID"
+	wallabagClientSecretEnv = "FEEDPULSE_WALLABAG_CLIENT_SECRET"
+	wallabagUsernameEnv     = "FEEDPULSE_WALLABAG_USERNAME"
+	wallabagPasswordEnv     = "FEEDPULSE_WALLABAG_PASSWORD"
+	linkdingTokenEnv        = "FEEDPULSE_LINKDING_TOKEN"
+	shioriUsernameEnv       = "FEEDPULSE_SHIORI_USERNAME"
+	shioriPasswordEnv       = "FEEDPULSE_SHIORI_PASSWORD"
+)
+
+// saveBatch is how many queued saves a fetch sends
+const saveBatch = 50
+
+// readLaterService creates the read-later service or bookmark manager
+// called name, with credentials from the environment
+func readLaterService(cfg *config.Config, name string) (readlater.Service, error) {
+	var required []string
+	switch name {
+	case config.ReadLaterPocket:
+		required = []string{pocketConsumerKeyEnv, pocketAccessTokenEnv}
+	case config.ReadLaterInstapaper:
+		required = []string{instapaperUsernameEnv, instapaperPasswordEnv}
+	case config.ReadLaterWallabag:
+		required = []string{wallabagClientIDEnv, wallabagClientSecretEnv, wallabagUsernameEnv, wallabagPasswordEnv}
+	case config.ReadLaterLinkding:
+		required = []string{linkdingTokenEnv}
+	case config.ReadLaterShiori:
+		required = []string{shioriUsernameEnv, shioriPasswordEnv}
+	default:
+		return nil, fmt.Errorf("unknown read-later service: %s (must be one of: %s)", name, strings.Join(config.ReadLaterServices, ", "))
+	}
+
+	instance := cfg.Settings.ReadLater.ServiceURL(name)
+	if instance == "" && config.SelfHosted(name) {
+		return nil, fmt.Errorf("set read_later.%s_url in the config to save to %s", name, name)
+	}
+
+	for _, env := range required {
+		if os.Getenv(env) == "" {
+			return nil, fmt.Errorf("set %s to save to %s", strings.Join(required, " and "), name)
+		}
+	}
+
+	switch name {
+	case config.ReadLaterPocket:
+		return readlater.NewPocket(os.Getenv(pocketConsumerKeyEnv), os.Getenv(pocketAccessTokenEnv)), nil
+	case config.ReadLaterInstapaper:
+		return readlater.NewInstapaper(os.Getenv(instapaperUsernameEnv), os.Getenv(instapaperPasswordEnv)), nil
+	case config.ReadLaterWallabag:
+		return readlater.NewWallabag(instance, os.Getenv(wallabagClientIDEnv),
+			os.Getenv(wallabagClientSecretEnv), os.Getenv(wallabagUsernameEnv), os.Getenv(wallabagPasswordEnv)), nil
+	case config.ReadLaterLinkding:
+		return readlater.NewLinkding(instance, os.Getenv(linkdingTokenEnv)), nil
+	default:
+		return readlater.NewShiori(instance, os.Getenv(shioriUsernameEnv), os.Getenv(shioriPasswordEnv)), nil
+	}
+}
+
+// queueAutoSaves queues items matched by auto-save rules, with their
+// tags mapped by tag_map; pinned selects the rules for items being pinned
+// rather than fetched
+func queueAutoSaves(store storage.Store, cfg *config.Config, items []storage.FeedItem, pinned bool) error {
+	byService := make(map[string][]storage.FeedItem)
+	for _, item := range items {
+		targets := cfg.Settings.ReadLater.AutoSaveTargets(item.Source, item.Tags, pinned)
+		if len(targets) == 0 {
+			continue
+		}
+		item.Tags = cfg.Settings.ReadLater.MapTags(item.Tags)
+		for _, to := range targets {
+			byService[to] = append(byService[to], item)
+		}
+	}
+	for service, items := range byService {
+		if _, err := store.QueueSave(service, items); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendQueuedSaves sends up to saveBatch queued saves to the services the
+// config's rules use, warning about services without credentials
+func sendQueuedSaves(ctx context.Context, store storage.Store, cfg *config.Config) {
+	r := cfg.Settings.ReadLater
+	if r == nil || len(r.AutoSave) == 0 {
+		return
+	}
+	if queued, err := store.QueuedSaves(1); err != nil || len(queued) == 0 {
+		return
+	}
+
+	services := make(map[string]readlater.Service)
+	for _, rule := range r.AutoSave {
+		if _, ok := services[rule.To]; ok {
+			continue
+		}
+		svc, err := readLaterService(cfg, rule.To)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: auto-save: %v\n", err)
+			services[rule.To] = nil
+			continue
+		}
+		services[rule.To] = svc
+	}
+
+	if _, err := readlater.Process(ctx, store, services, saveBatch, printSaveEntry); err != nil && ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// printSaveEntry prints the new state of a sent save
+func printSaveEntry(entry storage.SaveEntry) {
+	switch entry.Status {
+	case storage.SaveDone:
+		fmt.Printf("  ✓ saved to %-10s %s\n", entry.Service, entry.URL)
+	case storage.SaveFailed:
+		fmt.Printf("  ✗ saving to %s gave up after %d attempts: %s: %s\n", entry.Service, entry.Attempts, entry.URL, entry.LastError)
+	default:
+		fmt.Printf("  ! saving to %s failed, will retry: %s: %s\n", entry.Service, entry.URL, entry.LastError)
+	}
+}
+
+// runSave executes the save command for one item
+func runSave(itemID, to string, force bool) error {
+	cfg, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	svc, err := readLaterService(cfg, to)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("config error")
+	}
+
+	e, err := store.ExplainItem(itemID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("database error")
+	}
+	if e == nil {
+		fmt.Fprintf(os.Stderr, "Error: item not found: %s\n", itemID)
+		return fmt.Errorf("database error")
+	}
+	item := e.Item
+	item.Tags = cfg.Settings.ReadLater.MapTags(item.Tags)
+
+	prev, err := store.GetSave(to, item.URL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("database error")
+	}
+	if prev != nil && prev.Status == storage.SaveDone && !force {
+		fmt.Printf("%s is already saved to %s (use --force to save it again)\n", item.URL, to)
+		return nil
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if err := svc.Save(ctx, item.URL, item.Title, item.Tags); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("save error")
+	}
+
+	// Record the save so auto-save rules don't send it again
+	entry := storage.SaveEntry{Service: to, URL: item.URL, Status: storage.SaveDone, Attempts: 1}
+	if prev != nil {
+		entry.Attempts = prev.Attempts + 1
+	}
+	if _, err := store.QueueSave(to, []storage.FeedItem{item}); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	} else if err := store.UpdateSave(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	recordAudit(store, storage.AuditSave, map[string]string{"item": itemID, "to": to})
+
+	fmt.Printf("Saved %s to %s\n", item.URL, to)
+	return nil
+}
+
+// runSaveQueued executes the save command for queued saves
+func runSaveQueued() error {
+	cfg, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if cfg.Settings.ReadLater == nil || len(cfg.Settings.ReadLater.AutoSave) == 0 {
+		fmt.Println("No auto-save rules configured.")
+		return nil
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	before, err := store.QueuedSaves(0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("database error")
+	}
+	sendQueuedSaves(ctx, store, cfg)
+	after, err := store.QueuedSaves(0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("database error")
+	}
+
+	fmt.Printf("Sent %d of %d queued save(s)\n", len(before)-len(after), len(before))
+	return nil
+}
+
+// Environment variables holding notifier credentials
+const (
+	matrixAccessTokenEnv = "FEEDPULSE_MATRIX_ACCESS_TOKEN"
+	telegramBotTokenEnv  = "FEEDPULSE_TELEGRAM_BOT_TOKEN"
+	xmppPasswordEnv      = "FEEDPULSE_XMPP_PASSWORD"
+	smtpUsernameEnv      = "FEEDPULSE_SMTP_USERNAME"
+	smtpPasswordEnv      = "FEEDPULSE_SMTP_PASSWORD"
+)
+
+// notifier creates the configured notifier called name, with credentials
+// from the environment, rendering messages with its templates. With
+// --notify-dry-run it only prints messages.
+func notifier(cfg *config.Config, name string) (notify.Notifier, error) {
+	n, err := configuredNotifier(cfg, name)
+	if err != nil {
+		return nil, err
+	}
+	if notifyDryRun {
+		n = notify.NewDryRun(n, os.Stdout)
+	}
+	if t := cfg.Settings.Notify.TemplatesFor(name); t != (config.MessageTemplates{}) {
+		templates, err := notify.ParseTemplates(t.NewItems, t.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("notify.%s: %w", name, err)
+		}
+		n = notify.NewTemplated(n, templates)
+	}
+	return n, nil
+}
+
+// configuredNotifier creates the notifier called name from the config
+func configuredNotifier(cfg *config.Config, name string) (notify.Notifier, error) {
+	n := cfg.Settings.Notify
+	switch name {
+	case config.NotifyMatrix:
+		if n == nil || n.Matrix == nil {
+			return nil, fmt.Errorf("set notify.matrix in the config to post to matrix")
+		}
+		if os.Getenv(matrixAccessTokenEnv) == "" {
+			return nil, fmt.Errorf("set %s to post to matrix", matrixAccessTokenEnv)
+		}
+		return notify.NewMatrix(n.Matrix.Homeserver, os.Getenv(matrixAccessTokenEnv), n.Matrix.RoomID), nil
+	case config.NotifyTelegram:
+		return telegramBot(cfg)
+	case config.NotifyXMPP:
+		if n == nil || n.XMPP == nil {
+			return nil, fmt.Errorf("set notify.xmpp in the config to post to xmpp")
+		}
+		if os.Getenv(xmppPasswordEnv) == "" {
+			return nil, fmt.Errorf("set %s to post to xmpp", xmppPasswordEnv)
+		}
+		return notify.NewXMPP(n.XMPP.JID, os.Getenv(xmppPasswordEnv), n.XMPP.To, n.XMPP.Server), nil
+	case config.NotifySMTP:
+		if n == nil || n.SMTP == nil {
+			return nil, fmt.Errorf("set notify.smtp in the config to post to smtp")
+		}
+		// Local relays often take mail without a login
+		m := n.SMTP
+		return notify.NewSMTP(m.Host, m.SMTPPort(), m.From, m.To, os.Getenv(smtpUsernameEnv), os.Getenv(smtpPasswordEnv), m.MaxLength), nil
+	default:
+		return nil, fmt.Errorf("unknown notifier: %s (must be one of: %s)", name, strings.Join(config.Notifiers, ", "))
+	}
+}
+
+// telegramBot creates the configured Telegram bot
+func telegramBot(cfg *config.Config) (*notify.Telegram, error) {
+	n := cfg.Settings.Notify
+	if n == nil || n.Telegram == nil {
+		return nil, fmt.Errorf("set notify.telegram in the config to post to telegram")
+	}
+	if os.Getenv(telegramBotTokenEnv) == "" {
+		return nil, fmt.Errorf("set %s to post to telegram", telegramBotTokenEnv)
+	}
+	return notify.NewTelegram(os.Getenv(telegramBotTokenEnv), n.Telegram.ChatID), nil
+}
+
+// newItems returns the items whose IDs are in ids, once each
+func newItems(items []storage.FeedItem, ids []string) []storage.FeedItem {
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	var fresh []storage.FeedItem
+	for _, item := range items {
+		if wanted[item.ID] {
+			fresh = append(fresh, item)
+			delete(wanted, item.ID)
+		}
+	}
+	return fresh
+}
+
+// notifyNewItems posts new items to the notifiers that announce them,
+// warning about failures. Each notifier is sent an item once however many
+// of its rules match, and not again within the coalescing window when
+// another feed or fetch brings the same URL.
+func notifyNewItems(ctx context.Context, store storage.Store, cfg *config.Config, items []storage.FeedItem) {
+	n := cfg.Settings.Notify
+	if n == nil || len(items) == 0 {
+		return
+	}
+
+	byNotifier := make(map[string][]storage.FeedItem)
+	for _, item := range items {
+		for _, name := range n.Announcers(item.Source, item.Tags) {
+			byNotifier[name] = append(byNotifier[name], item)
+		}
+	}
+
+	for _, name := range config.Notifiers {
+		if len(byNotifier[name]) == 0 {
+			continue
+		}
+		nt, err := notifier(cfg, name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: notify: %v\n", err)
+			continue
+		}
+		// Claimed before sending, so fetches finishing together don't
+		// both send an item, and released if the send fails, so they
+		// aren't held back for the coalescing window
+		announced, err := store.ClaimNotifications(name, byNotifier[name], n.Coalesce())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: notify: %v\n", err)
+			continue
+		}
+		if len(announced) == 0 {
+			continue
+		}
+		if err := nt.Send(ctx, notify.NewItems(announced)); err != nil {
+			if ctx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "Warning: notify: %v\n", err)
+			}
+			if err := store.ReleaseNotifications(name, announced); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: notify: %v\n", err)
+			}
+		}
+	}
+}
+
+// runTelegramBot answers the commands sent to the bot from its chat until
+// ctx is cancelled; messages from other chats are ignored
+func runTelegramBot(ctx context.Context, store storage.Store, bot *notify.Telegram) {
+	defer sentry.Recover()
+	var offset int64
+	for ctx.Err() == nil {
+		updates, err := bot.Updates(ctx, offset, 50*time.Second)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "Warning: telegram bot: %v\n", err)
+			select {
+			case <-time.After(30 * time.Second):
+			case <-ctx.Done():
+			}
+			continue
+		}
+
+		for _, u := range updates {
+			offset = u.ID + 1
+			if !bot.FromChat(u) {
+				continue
+			}
+			msg, ok, err := notify.Answer(store, u.Text, currentZone())
+			if !ok {
+				continue
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: telegram bot: %v\n", err)
+				msg = notify.Message{Title: "Sorry, that failed; see the feedpulse log."}
+			}
+			if err := bot.Reply(ctx, u.ChatID, msg); err != nil && ctx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "Warning: telegram bot: %v\n", err)
+			}
+		}
+	}
+}
+
+// startDaemonWorkers runs the archiver and Telegram bot in the background
+// if cfg enables them. The returned func stops them and waits for them to
+// exit, so a reload never has two running at once.
+func startDaemonWorkers(ctx context.Context, store storage.Store, cfg *config.Config) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	if a := cfg.Settings.Archive; a != nil && a.Enabled {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runArchiver(ctx, store, cfg)
+		}()
+	}
+	if n := cfg.Settings.Notify; n != nil && n.Telegram != nil && n.Telegram.Commands {
+		if bot, err := telegramBot(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: telegram bot: %v\n", err)
+		} else {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				runTelegramBot(ctx, store, bot)
+			}()
+		}
+	}
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// runDaemon executes the daemon command: it fetches each feed when its
+// schedule says it is due, retrying failed feeds sooner with a growing
+// backoff, until SIGTERM or Ctrl+C
+func runDaemon(defaultInterval, attemptLog string) error {
+	fallback, err := config.ParseDuration(defaultInterval)
+	if err != nil || fallback < time.Minute {
+		return fmt.Errorf("--default-interval must be a duration of at least 1m, got '%s'", defaultInterval)
+	}
+
+	var attempts io.Writer
+	switch attemptLog {
+	case "":
+	case "-":
+		attempts = os.Stderr
+	default:
+		file, err := os.OpenFile(attemptLog, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open attempt log: %w", err)
+		}
+		defer file.Close()
+		attempts = file
+	}
+
+	cfg, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if err := applyUniquenessScope(store, cfg); err != nil {
+		return err
+	}
+	if err := applyCookieKey(store, cfg); err != nil {
+		return err
+	}
+	replayQueue(store, cfg, clock.System)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	newFetcher := func(cfg *config.Config) *fetcher.Fetcher {
+		f := fetcher.NewFetcher(cfg)
+		f.SetJournal(store)
+		f.SetState(store)
+		f.SetCookieStore(store)
+		f.SetRequestMeter(store)
+		f.SetAttemptLog(attempts)
+		return f
+	}
+	f := newFetcher(cfg)
+
+	// Pick up where the last run left off rather than fetch everything now
+	schedule := fetcher.NewSchedule(cfg, fallback)
+	for _, feed := range cfg.Feeds {
+		if last, ok, err := store.LastSuccess(feed.Name); err == nil && ok {
+			schedule.Seed(feed.Name, last)
+		}
+	}
+	if len(schedule.Feeds()) == 0 {
+		return fmt.Errorf("no feeds to schedule; stream feeds are consumed by 'feedpulse serve'")
+	}
+
+	stopWorkers := startDaemonWorkers(ctx, store, cfg)
+	defer func() { stopWorkers() }()
+
+	fmt.Printf("Scheduling %d feeds; stop with Ctrl+C or SIGTERM, reload the config with SIGHUP\n", len(schedule.Feeds()))
+
+	// A reload swaps the config, fetcher and schedule together between
+	// runs, so no run sees a mix of old and new, and restarts the
+	// background workers on the new config
+	reload := func() {
+		next := reloadDaemonConfig(store, cfg, fallback)
+		if next == nil {
+			return
+		}
+		changes := schedule.Reload(next, fallback, time.Now())
+		cfg, f = next, newFetcher(next)
+		stopWorkers()
+		stopWorkers = startDaemonWorkers(ctx, store, next)
+		printScheduleChanges(changes)
+	}
+
+	for {
+		select {
+		case <-hup:
+			reload()
+		default:
+		}
+		if wait := time.Until(schedule.Next()); wait > 0 {
+			select {
+			case <-ctx.Done():
+				fmt.Fprintf(os.Stderr, "\nShutting down...\n")
+				return nil
+			case <-hup:
+				reload()
+				continue
+			case <-time.After(wait):
+			}
+		}
+
+		due := schedule.Due(time.Now())
+		fmt.Printf("[%s] Fetching %d due feed(s)...\n", time.Now().In(currentZone()).Format("2006-01-02 15:04:05"), len(due))
+
+		var summary fetchSummary
+		results := f.FetchDue(ctx, due, func(stage []fetcher.FetchResult) {
+			for _, result := range stage {
+				recordResult(store, cfg, clock.System, result, &summary)
+			}
+		})
+		if ctx.Err() != nil {
+			fmt.Fprintf(os.Stderr, "\nShutting down...\n")
+			return nil
+		}
+
+		newBySource := make(map[string]int)
+		for _, item := range summary.fresh {
+			newBySource[item.Source]++
+		}
+		for _, result := range results {
+			if result.Success {
+				if interval, changed := schedule.Adapt(result.Source, newBySource[result.Source]); changed {
+					fmt.Printf("  %s now polled every %s\n", result.Source, interval)
+				}
+			}
+			next := schedule.Record(result.Source, result.Success || result.Skipped || result.Unchanged, time.Now())
+			if !result.Success && !result.Skipped {
+				fmt.Fprintf(os.Stderr, "Warning: %s failed; retrying at %s\n", result.Source, next.Format("15:04:05"))
+			}
+		}
+
+		if _, err := store.PruneJournal(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		printFetchSummary(summary, len(results))
+		sendQueuedSaves(ctx, store, cfg)
+		notifyNewItems(ctx, store, cfg, summary.fresh)
+	}
+}
+
+// reloadDaemonConfig rereads the config file for a daemon running with
+// current, applying its uniqueness scope and item IDs to the database. It
+// returns nil, having printed why, if the new config can't be used; the
+// daemon then carries on with current. Everything is checked before
+// anything changes, and the scope, the only step that can still fail, is
+// applied before the cookie key, so a failed reload leaves current in force.
+func reloadDaemonConfig(store storage.Store, current *config.Config, defaultInterval time.Duration) *config.Config {
+	fmt.Printf("[%s] Reloading %s\n", time.Now().In(currentZone()).Format("2006-01-02 15:04:05"), configPath)
+	reject := func(reason string) *config.Config {
+		fmt.Fprintf(os.Stderr, "Warning: config not reloaded: %s\n", reason)
+		recordAudit(store, storage.AuditConfigReload, map[string]string{"config": configPath, "result": "rejected", "reason": reason})
+		return nil
+	}
+
+	// The process-wide settings only change once the config is accepted
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return reject(currentRedactor().String(err.Error()))
+	}
+	if cfg.Settings.DatabaseDSN() != current.Settings.DatabaseDSN() {
+		return reject("the database can't change without a restart")
+	}
+	if len(fetcher.NewSchedule(cfg, defaultInterval).Feeds()) == 0 {
+		return reject("no feeds to schedule")
+	}
+	if err := checkCookieKey(cfg); err != nil {
+		return reject("a feed keeps a cookie jar but " + cookieKeyEnv + " is not set")
+	}
+	if err := applyUniquenessScope(store, cfg); err != nil {
+		return reject("the uniqueness scope couldn't be applied")
+	}
+	store.SetCookieKey(os.Getenv(cookieKeyEnv))
+	applySettings(cfg)
+	recordAudit(store, storage.AuditConfigReload, map[string]string{"config": configPath, "result": "applied"})
+	return cfg
+}
+
+// printScheduleChanges reports what a config reload did to the schedule
+func printScheduleChanges(changes fetcher.ScheduleChanges) {
+	if changes.Empty() {
+		fmt.Println("  No feeds added, removed or rescheduled")
+		return
+	}
+	for _, name := range changes.Added {
+		fmt.Printf("  + %s\n", name)
+	}
+	for _, name := range changes.Removed {
+		fmt.Printf("  - %s\n", name)
+	}
+	for _, name := range changes.Changed {
+		fmt.Printf("  ~ %s (new interval)\n", name)
+	}
+}
+
+// runNotifyTest sends the sample message through the notifier called name
+func runNotifyTest(name string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("config error")
+	}
+
+	n, err := notifier(cfg, name)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	if err := n.Send(context.Background(), notify.Sample()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("notify error")
+	}
+	if notifyDryRun {
+		return nil
+	}
+	fmt.Printf("Sent a test message via %s in %s\n", n.Name(), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// runItems lists the stored items selected by the items command's flags
+func runItems(flags itemsFlags) error {
+	if flags.format != "table" && flags.format != "json" {
+		return fmt.Errorf("invalid format: %s (must be table or json)", flags.format)
+	}
+	if flags.offset < 0 {
+		return fmt.Errorf("--offset must not be negative")
+	}
+	valid := false
+	for _, sort := range storage.ItemSorts {
+		valid = valid || flags.sort == sort
+	}
+	if !valid {
+		return fmt.Errorf("invalid sort: %s (must be one of: %s)", flags.sort, strings.Join(storage.ItemSorts, ", "))
+	}
+
+	filter := storage.ItemFilter{
+		Source: flags.source,
+		Tag:    flags.tag,
+		Limit:  flags.limit,
+		Offset: flags.offset,
+		Sort:   flags.sort,
+	}
+	if err := flags.window(&filter); err != nil {
+		return err
+	}
+
+	cfg, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	columns, err := tableColumns(cfg, "items", flags.columns)
+	if err != nil {
+		return err
+	}
+
+	items, err := store.GetItems(filter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("database error")
+	}
+
+	if flags.format == "json" {
+		if items == nil {
+			items = []storage.FeedItem{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(items)
+	}
+
+	if len(items) == 0 {
+		fmt.Println("No items.")
+		return nil
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.Header(columnHeaders(columns)...)
+	for _, item := range items {
+		published := ""
+		if item.Timestamp != nil {
+			published = *item.Timestamp
+		}
+		table.Append(columnRow(columns, map[string]string{
+			"stored":    tableTime(item.CreatedAt),
+			"source":    item.Source,
+			"title":     item.Title,
+			"tags":      strings.Join(item.Tags, ", "),
+			"url":       item.URL,
+			"id":        item.ID,
+			"published": published,
+		})...)
+	}
+	table.Render()
+	return nil
+}
+
+// runItemsBatch marks read, stars, tags or deletes every item the flags
+// select, in one transaction
+func runItemsBatch(flags itemsFlags) error {
+	var batch storage.ItemBatch
+	actions := 0
+	for _, a := range []struct {
+		set    bool
+		action string
+	}{
+		{flags.markRead, storage.BatchMarkRead},
+		{flags.star, storage.BatchStar},
+		{flags.addTag != "", storage.BatchTag},
+		{flags.delete, storage.BatchDelete},
+	} {
+		if a.set {
+			batch.Action = a.action
+			actions++
+		}
+	}
+	if actions > 1 {
+		return fmt.Errorf("--mark-read, --star, --add-tag and --delete can't be combined")
+	}
+	if flags.source == "" && flags.tag == "" && flags.since == "" && flags.until == "" && flags.before == "" {
+		return fmt.Errorf("select the items to change with --source, --tag, --since, --until or --before")
+	}
+	batch.Tag = flags.addTag
+	batch.Filter = storage.ItemFilter{Source: flags.source, Tag: flags.tag}
+	if err := flags.window(&batch.Filter); err != nil {
+		return err
+	}
+	if err := batch.Validate(); err != nil {
+		return err
+	}
+
+	_, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	changed, err := store.ApplyItemBatch(batch)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("database error")
+	}
+
+	params := map[string]string{"action": batch.Action, "changed": strconv.Itoa(changed)}
+	for k, v := range map[string]string{"tag": flags.addTag, "source": flags.source, "filter_tag": flags.tag, "since": flags.since, "until": flags.until, "before": flags.before} {
+		if v != "" {
+			params[k] = v
+		}
+	}
+	recordAudit(store, storage.AuditItemsBatch, params)
+
+	switch batch.Action {
+	case storage.BatchMarkRead:
+		fmt.Printf("Marked %d item(s) read\n", changed)
+	case storage.BatchStar:
+		fmt.Printf("Starred %d item(s)\n", changed)
+	case storage.BatchTag:
+		fmt.Printf("Tagged %d item(s) '%s'\n", changed, batch.Tag)
+	case storage.BatchDelete:
+		fmt.Printf("Deleted %d item(s)\n", changed)
+	}
+	return nil
+}
+
+// exportRecord is an item as exported. Unlike FeedItem's JSON, every
+// field is always present, so consumers needn't check for missing keys.
+type exportRecord struct {
+	ID        string   `json:"id"`
+	Title     string   `json:"title"`
+	URL       string   `json:"url"`
+	Source    string   `json:"source"`
+	Timestamp *string  `json:"timestamp"`
+	Tags      []string `json:"tags"`
+	RawData   *string  `json:"raw_data"`
+	CreatedAt string   `json:"created_at"`
+}
+
+// runExport writes the stored items matching the flags, oldest first,
+// to output or stdout
+func runExport(format, source, tag, since, output string) error {
+	var write func(io.Writer, []storage.FeedItem) error
+	switch format {
+	case "jsonl":
+		write = writeItemsJSONL
+	case "csv":
+		write = writeItemsCSV
+	case "markdown":
+		write = writeItemsMarkdown
+	case syndication.FormatRSS, syndication.FormatAtom:
+		feed := syndication.Feed{Title: syndication.Title(source, tag), Updated: time.Now()}
+		write = func(w io.Writer, items []storage.FeedItem) error {
+			return syndication.Write(w, format, feed, items)
+		}
+	default:
+		return fmt.Errorf("invalid format: %s (must be jsonl, csv, markdown, rss or atom)", format)
+	}
+
+	// Feeds list the newest items first; dumps go oldest first
+	filter := storage.ItemFilter{Source: source, Tag: tag, Sort: storage.SortOldest, RawData: format == "jsonl" || format == "csv"}
+	if format == syndication.FormatRSS || format == syndication.FormatAtom {
+		filter.Sort = storage.SortNewest
+	}
+	if since != "" {
+		window, err := parseWindow(since)
+		if err != nil {
+			return err
+		}
+		filter.Since = time.Now().Add(-window)
+	}
+
+	_, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	items, err := store.GetItems(filter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return fmt.Errorf("database error")
+	}
+
+	if output == "" {
+		return write(os.Stdout, items)
+	}
+	f, err := os.Create(output)
+	if err == nil {
+		err = write(f, items)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to write %s: %v\n", output, err)
+		return fmt.Errorf("export error")
+	}
+	fmt.Printf("Exported %d item(s) to %s\n", len(items), output)
+	return nil
+}
+
+// writeItemsJSONL writes one JSON object per line per item
+func writeItemsJSONL(w io.Writer, items []storage.FeedItem) error {
+	enc := json.NewEncoder(w)
+	for _, item := range items {
+		tags := item.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		if err := enc.Encode(exportRecord{
+			ID:        item.ID,
+			Title:     item.Title,
+			URL:       item.URL,
+			Source:    item.Source,
+			Timestamp: item.Timestamp,
+			Tags:      tags,
+			RawData:   item.RawData,
+			CreatedAt: item.CreatedAt.UTC().Format(time.RFC3339),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeItemsCSV writes items as CSV with a header row; tags are joined
+// with commas
+func writeItemsCSV(w io.Writer, items []storage.FeedItem) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"id", "title", "url", "source", "timestamp", "tags", "created_at", "raw_data"}); err != nil {
+		return err
+	}
+	for _, item := range items {
+		timestamp, raw := "", ""
+		if item.Timestamp != nil {
+			timestamp = *item.Timestamp
+		}
+		if item.RawData != nil {
+			raw = *item.RawData
+		}
 		if err := writer.Write([]string{
-			stat.Source,
-			fmt.Sprintf("%d", stat.ItemsCount),
-			fmt.Sprintf("%d", stat.ErrorCount),
-			errorRate,
-			lastSuccess,
+			item.ID,
+			item.Title,
+			item.URL,
+			item.Source,
+			timestamp,
+			strings.Join(item.Tags, ","),
+			item.CreatedAt.UTC().Format(time.RFC3339),
+			raw,
 		}); err != nil {
 			return err
 		}
 	}
+	writer.Flush()
+	return writer.Error()
+}
 
-	return nil
+// writeItemsMarkdown writes items as a Markdown table, leaving out their
+// raw data
+func writeItemsMarkdown(w io.Writer, items []storage.FeedItem) error {
+	if len(items) == 0 {
+		_, err := fmt.Fprintln(w, "No items.")
+		return err
+	}
+
+	escaper := strings.NewReplacer("|", "\\|", "[", "\\[", "]", "\\]", "\n", " ")
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "| Title | Source | Tags | Published | Stored | ID |")
+	fmt.Fprintln(bw, "|-------|--------|------|-----------|--------|----|")
+	for _, item := range items {
+		title := escaper.Replace(item.Title)
+		if item.URL != "" {
+			title = fmt.Sprintf("[%s](<%s>)", title, item.URL)
+		}
+		published := ""
+		if item.Timestamp != nil {
+			published = *item.Timestamp
+		}
+		fmt.Fprintf(bw, "| %s | %s | %s | %s | %s | `%s` |\n",
+			title,
+			escaper.Replace(item.Source),
+			escaper.Replace(strings.Join(item.Tags, ", ")),
+			escaper.Replace(published),
+			item.CreatedAt.UTC().Format(time.RFC3339),
+			item.ID)
+	}
+	return bw.Flush()
+}
+
+// columnTitles are the headers of the configurable table columns
+var columnTitles = map[string]string{
+	"source":       "Source",
+	"items":        "Items",
+	"errors":       "Errors",
+	"error_rate":   "Error Rate",
+	"last_success": "Last Success",
+	"fetches":      "Fetches",
+	"stored":       "Stored",
+	"title":        "Title",
+	"tags":         "Tags",
+	"url":          "URL",
+	"id":           "ID",
+	"published":    "Published",
+}
+
+// tableColumns returns the columns a table shows: those named by its
+// comma-separated --columns flag, or else the config's
+func tableColumns(cfg *config.Config, table, flag string) ([]string, error) {
+	if flag == "" {
+		return cfg.Settings.ColumnsFor(table), nil
+	}
+	columns := strings.Split(flag, ",")
+	for i := range columns {
+		columns[i] = strings.TrimSpace(columns[i])
+	}
+	if err := config.ValidateColumns(table, columns); err != nil {
+		return nil, err
+	}
+	return columns, nil
+}
+
+// columnHeaders returns the header row for columns
+func columnHeaders(columns []string) []any {
+	headers := make([]any, len(columns))
+	for i, column := range columns {
+		headers[i] = columnTitles[column]
+	}
+	return headers
+}
+
+// columnRow picks the values of columns, in order, out of a row's values
+func columnRow(columns []string, values map[string]string) []any {
+	row := make([]any, len(columns))
+	for i, column := range columns {
+		row[i] = values[column]
+	}
+	return row
+}
+
+// Dimensions `report --by` aggregates over
+const (
+	reportBySource = "source"
+	reportByGroup  = "group"
+	reportByTag    = "tag"
+)
+
+// ungroupedLabel is the row `report --by group` puts feeds without a group in
+const ungroupedLabel = "(ungrouped)"
+
+// outputTagStats outputs `report --by tag`
+func outputTagStats(format string, tags []storage.TagStats) error {
+	switch format {
+	case "json":
+		if tags == nil {
+			tags = []storage.TagStats{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(map[string]interface{}{"tags": tags})
+	case "csv":
+		writer := csv.NewWriter(os.Stdout)
+		defer writer.Flush()
+		if err := writer.Write([]string{"Tag", "Items", "Sources", "Last Stored"}); err != nil {
+			return err
+		}
+		for _, tag := range tags {
+			row := []string{tag.Tag, fmt.Sprintf("%d", tag.ItemsCount), fmt.Sprintf("%d", tag.Sources), tag.LastStored.Format(time.RFC3339)}
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "table":
+		if len(tags) == 0 {
+			fmt.Println("No tagged items.")
+			return nil
+		}
+		table := tablewriter.NewWriter(os.Stdout)
+		table.Header("Tag", "Items", "Sources", "Last Stored")
+		for _, tag := range tags {
+			table.Append(tag.Tag, fmt.Sprintf("%d", tag.ItemsCount), fmt.Sprintf("%d", tag.Sources), tableTime(tag.LastStored))
+		}
+		table.Render()
+		return nil
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
 }
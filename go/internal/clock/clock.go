@@ -0,0 +1,59 @@
+// Package clock provides an injectable source of the current time.
+//
+// Components that stamp records with "now" take a Clock instead of calling
+// time.Now directly, so tests can pin the time and backfills can stamp
+// imported data with the moment it actually describes.
+package clock
+
+import (
+	"fmt"
+	"time"
+)
+
+// Clock reports the current time
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the real wall clock
+var System Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Fixed returns a clock that always reports t
+func Fixed(t time.Time) Clock {
+	return fixedClock{t: t}
+}
+
+type fixedClock struct {
+	t time.Time
+}
+
+func (c fixedClock) Now() time.Time {
+	return c.t
+}
+
+// Ago describes how long before now t was in its largest whole unit, such
+// as "3h ago", or how far ahead it is if it lies in the future
+func Ago(now, t time.Time) string {
+	d := now.Sub(t)
+	suffix := " ago"
+	if d < 0 {
+		d = -d
+		suffix = " from now"
+	}
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%s", int(d/time.Minute), suffix)
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh%s", int(d/time.Hour), suffix)
+	default:
+		return fmt.Sprintf("%dd%s", int(d/(24*time.Hour)), suffix)
+	}
+}
@@ -0,0 +1,49 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFixed(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	c := Fixed(at)
+
+	if !c.Now().Equal(at) {
+		t.Errorf("Fixed clock returned %v, want %v", c.Now(), at)
+	}
+	if !c.Now().Equal(c.Now()) {
+		t.Error("Fixed clock must not advance")
+	}
+}
+
+func TestSystem(t *testing.T) {
+	before := time.Now()
+	now := System.Now()
+	after := time.Now()
+
+	if now.Before(before) || now.After(after) {
+		t.Errorf("System clock returned %v, outside [%v, %v]", now, before, after)
+	}
+}
+
+func TestAgo(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		at   time.Time
+		want string
+	}{
+		{now, "just now"},
+		{now.Add(-59 * time.Second), "just now"},
+		{now.Add(-5 * time.Minute), "5m ago"},
+		{now.Add(-3*time.Hour - 20*time.Minute), "3h ago"},
+		{now.Add(-50 * time.Hour), "2d ago"},
+		{now.Add(90 * time.Minute), "1h from now"},
+	}
+
+	for _, tt := range tests {
+		if got := Ago(now, tt.at); got != tt.want {
+			t.Errorf("Ago(%v) = %q, want %q", now.Sub(tt.at), got, tt.want)
+		}
+	}
+}
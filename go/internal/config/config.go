@@ -1,109 +1,1728 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"feedpulse/internal/notify"
+	"feedpulse/internal/redact"
+	"feedpulse/internal/transform"
 )
 
 // Config represents the application configuration
 type Config struct {
 	Settings Settings `yaml:"settings"`
 	Feeds    []Feed   `yaml:"feeds"`
+
+	// Dir is the directory of the config file, which relative plugin
+	// commands are resolved against; "" for the working directory
+	Dir string `yaml:"-"`
+}
+
+// Settings contains global configuration
+type Settings struct {
+	MaxConcurrency       int     `yaml:"max_concurrency"`
+	DefaultTimeoutSecs   int     `yaml:"default_timeout_secs"`
+	RetryMax             int     `yaml:"retry_max"`
+	RetryBaseDelayMs     int     `yaml:"retry_base_delay_ms"`
+	DatabasePath         string  `yaml:"database_path"`
+	UniquenessScope      string  `yaml:"uniqueness_scope"`
+	Dedup                string  `yaml:"dedup"`
+	SchemaDriftThreshold float64 `yaml:"schema_drift_threshold"`
+	MaxItemsPerFetch     int     `yaml:"max_items_per_fetch"`
+	TruncateBy           string  `yaml:"truncate_by"`
+	StaleAfter           string  `yaml:"stale_after"`
+	// DatabaseURL is the postgres:// URL of a PostgreSQL database to use
+	// instead of the SQLite file at DatabasePath, e.g. for several
+	// instances sharing one database
+	DatabaseURL string `yaml:"database_url"`
+	// Timezone is the IANA zone (e.g. "Europe/Berlin") times are shown
+	// in; empty means the system's zone
+	Timezone string `yaml:"timezone"`
+	// Columns replaces the default columns of the report and items
+	// tables, keyed by table; --columns overrides it per run
+	Columns map[string][]string `yaml:"columns"`
+	// Budgets caps the requests sent to an API host, keyed by host name
+	// (e.g. "api.github.com")
+	Budgets map[string]HostBudget `yaml:"budgets"`
+	// MaxFailureBytes caps how much of a response that failed to parse is
+	// recorded; 0 means the default, negative records nothing
+	MaxFailureBytes int `yaml:"max_failure_bytes"`
+	// Redact names more headers and query parameters whose values are
+	// masked in output, logs and the database
+	Redact *RedactConfig `yaml:"redact"`
+	// Filters drop items of every feed before they are stored; each
+	// feed's own filters apply as well
+	Filters *FiltersConfig `yaml:"filters"`
+
+	Archive        *ArchiveConfig        `yaml:"archive"`
+	ReadLater      *ReadLaterConfig      `yaml:"read_later"`
+	Notify         *NotifyConfig         `yaml:"notify"`
+	ErrorReporting *ErrorReportingConfig `yaml:"error_reporting"`
+}
+
+// ErrorReportingConfig forwards unexpected internal errors and panics,
+// never routine feed failures, to Sentry or GlitchTip
+type ErrorReportingConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// DSN is the project's client key; when empty, the SENTRY_DSN
+	// environment variable is used
+	DSN string `yaml:"dsn"`
+	// Environment tells deployments apart in reports, e.g. "production"
+	Environment string `yaml:"environment"`
+}
+
+// RedactConfig adds to the headers and query parameters redact masks by
+// default
+type RedactConfig struct {
+	Headers     []string `yaml:"headers"`
+	QueryParams []string `yaml:"query_params"`
+}
+
+// Redactor returns a redactor masking the secret headers and query
+// parameters, plus the values the feeds set for them, so they are masked
+// even where their names don't appear
+func (c *Config) Redactor() *redact.Redactor {
+	var headers, params []string
+	if r := c.Settings.Redact; r != nil {
+		headers, params = r.Headers, r.QueryParams
+	}
+	r := redact.New(headers, params)
+
+	for _, feed := range c.Feeds {
+		requests := []Feed{feed}
+		if feed.Login != nil {
+			requests = append(requests, feed.Login.Request(feed.Name))
+		}
+		for _, req := range requests {
+			for name, value := range req.Headers {
+				if r.SecretHeader(name) {
+					r.AddSecret(value)
+					// "Bearer <token>" is often printed without its scheme
+					if _, token, ok := strings.Cut(value, " "); ok {
+						r.AddSecret(token)
+					}
+				}
+			}
+			for _, u := range append([]string{req.URL}, req.Mirrors...) {
+				r.AddURL(u)
+			}
+			for name, value := range req.Form {
+				if r.SecretParam(name) {
+					r.AddSecret(value)
+				}
+			}
+		}
+		if feed.Auth != nil {
+			if secret, err := feed.Auth.Secret(); err == nil {
+				r.AddSecret(secret)
+			}
+		}
+	}
+	return r
+}
+
+// HostBudget is a request quota for one host, over a rolling hour and
+// day; zero leaves that window unlimited. Fetches that would spend more
+// than what's left are deferred until the window frees up.
+type HostBudget struct {
+	PerHour int `yaml:"per_hour"`
+	PerDay  int `yaml:"per_day"`
+}
+
+// Validate performs validation on a host budget
+func (b HostBudget) Validate() error {
+	if b.PerHour < 0 || b.PerDay < 0 {
+		return fmt.Errorf("per_hour and per_day must be non-negative")
+	}
+	if b.PerHour == 0 && b.PerDay == 0 {
+		return fmt.Errorf("per_hour or per_day must be set")
+	}
+	return nil
+}
+
+// BudgetFor returns the request budget of host, if it has one
+func (s *Settings) BudgetFor(host string) (HostBudget, bool) {
+	for name, budget := range s.Budgets {
+		if strings.EqualFold(name, host) {
+			return budget, true
+		}
+	}
+	return HostBudget{}, false
+}
+
+// ArchiveConfig submits the URLs of new items to the Internet Archive's
+// Save Page Now, so they stay readable after link rot. New items are
+// queued by fetch and submitted by serve or `feedpulse archive run`, at
+// most one request per Interval.
+type ArchiveConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Sources limits archiving to these feeds; empty means all
+	Sources  []string `yaml:"sources"`
+	Interval string   `yaml:"interval"`
+}
+
+// Default spacing of Save Page Now requests; anonymous captures are
+// limited to a handful a minute
+const DefaultArchiveInterval = 15 * time.Second
+
+// ArchiveInterval returns the time to wait between Save Page Now requests
+func (a *ArchiveConfig) ArchiveInterval() time.Duration {
+	if a == nil || a.Interval == "" {
+		return DefaultArchiveInterval
+	}
+	d, err := ParseDuration(a.Interval)
+	if err != nil {
+		return DefaultArchiveInterval
+	}
+	return d
+}
+
+// Archives reports whether items from source are archived
+func (a *ArchiveConfig) Archives(source string) bool {
+	if a == nil || !a.Enabled {
+		return false
+	}
+	if len(a.Sources) == 0 {
+		return true
+	}
+	for _, s := range a.Sources {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
+
+// Read-later services and bookmark managers items can be saved to
+const (
+	ReadLaterPocket     = "pocket"
+	ReadLaterInstapaper = "instapaper"
+	ReadLaterWallabag   = "wallabag"
+	ReadLaterLinkding   = "linkding"
+	ReadLaterShiori     = "shiori"
+)
+
+// ReadLaterServices lists the supported services
+var ReadLaterServices = []string{ReadLaterPocket, ReadLaterInstapaper, ReadLaterWallabag, ReadLaterLinkding, ReadLaterShiori}
+
+// isReadLaterService reports whether name is a supported read-later service
+func isReadLaterService(name string) bool {
+	for _, s := range ReadLaterServices {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadLaterConfig configures saving items to read-later services and
+// bookmark managers, by hand with `feedpulse save` or automatically by
+// rule. Credentials come from the environment.
+type ReadLaterConfig struct {
+	// Self-hosted instances to save to
+	WallabagURL string `yaml:"wallabag_url"`
+	LinkdingURL string `yaml:"linkding_url"`
+	ShioriURL   string `yaml:"shiori_url"`
+
+	// TagMap renames item tags before they are sent; a tag mapped to ""
+	// is dropped
+	TagMap   map[string]string `yaml:"tag_map"`
+	AutoSave []AutoSaveRule    `yaml:"auto_save"`
+}
+
+// ServiceURL returns the configured instance of a self-hosted service, or
+// "" for hosted services and unconfigured instances
+func (r *ReadLaterConfig) ServiceURL(service string) string {
+	if r == nil {
+		return ""
+	}
+	switch service {
+	case ReadLaterWallabag:
+		return r.WallabagURL
+	case ReadLaterLinkding:
+		return r.LinkdingURL
+	case ReadLaterShiori:
+		return r.ShioriURL
+	}
+	return ""
+}
+
+// SelfHosted reports whether service runs on the user's own instance
+func SelfHosted(service string) bool {
+	return service == ReadLaterWallabag || service == ReadLaterLinkding || service == ReadLaterShiori
+}
+
+// MapTags applies TagMap to tags, dropping duplicates and tags mapped to ""
+func (r *ReadLaterConfig) MapTags(tags []string) []string {
+	var mapped []string
+	for _, tag := range tags {
+		if r != nil {
+			if to, ok := r.TagMap[tag]; ok {
+				tag = to
+			}
+		}
+		if tag != "" && !containsString(mapped, tag) {
+			mapped = append(mapped, tag)
+		}
+	}
+	return mapped
+}
+
+// AutoSaveRule saves matching items to a service. Rules with Pinned match
+// items as they are pinned to the front page; other rules match items as
+// they are fetched. Sources and Tags narrow either kind.
+type AutoSaveRule struct {
+	To      string   `yaml:"to"`
+	Sources []string `yaml:"sources"`
+	// Tags matches items with any of these tags
+	Tags   []string `yaml:"tags"`
+	Pinned bool     `yaml:"pinned"`
+}
+
+// matches reports whether the rule applies to an item from source with tags
+func (r AutoSaveRule) matches(source string, tags []string, pinned bool) bool {
+	if r.Pinned != pinned {
+		return false
+	}
+	if len(r.Sources) > 0 && !containsString(r.Sources, source) {
+		return false
+	}
+	if len(r.Tags) == 0 {
+		return true
+	}
+	for _, tag := range tags {
+		if containsString(r.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// AutoSaveTargets returns the services an item from source with tags is
+// saved to, when fetched or (with pinned) when pinned
+func (r *ReadLaterConfig) AutoSaveTargets(source string, tags []string, pinned bool) []string {
+	if r == nil {
+		return nil
+	}
+	var targets []string
+	for _, rule := range r.AutoSave {
+		if rule.matches(source, tags, pinned) && !containsString(targets, rule.To) {
+			targets = append(targets, rule.To)
+		}
+	}
+	return targets
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Notifiers new items and digests can be posted to
+const (
+	NotifyMatrix   = "matrix"
+	NotifyTelegram = "telegram"
+	NotifyXMPP     = "xmpp"
+	NotifySMTP     = "smtp"
+)
+
+// Notifiers lists the supported notifiers
+var Notifiers = []string{NotifyMatrix, NotifyTelegram, NotifyXMPP, NotifySMTP}
+
+// NotifyConfig configures the chat rooms new items and digests are
+// posted to. Credentials come from the environment.
+type NotifyConfig struct {
+	Matrix   *MatrixConfig   `yaml:"matrix"`
+	Telegram *TelegramConfig `yaml:"telegram"`
+	XMPP     *XMPPConfig     `yaml:"xmpp"`
+	SMTP     *SMTPConfig     `yaml:"smtp"`
+	// Templates apply to every notifier that doesn't override them
+	Templates MessageTemplates `yaml:"templates"`
+	// CoalesceWindow is how long an item sent to a notifier isn't sent to
+	// it again, e.g. when another feed carries the same URL; "0" only
+	// coalesces items within one message
+	CoalesceWindow string `yaml:"coalesce_window"`
+	// Digest arranges the digests `feedpulse digest` prints and posts
+	Digest DigestConfig `yaml:"digest"`
+}
+
+// DigestConfig arranges a digest's items into sections
+type DigestConfig struct {
+	// GroupBy is tag (the default), source, or score: how many sources
+	// carried an item
+	GroupBy string `yaml:"group_by"`
+	// ScoreBands are the lowest scores of the sections group_by: score
+	// makes, e.g. [5, 2] for 5+, 2-4 and 1
+	ScoreBands []int `yaml:"score_bands"`
+	// SectionLimit caps the items of each section; 0 means no cap
+	SectionLimit int `yaml:"section_limit"`
+	// Sort orders each section's items: score (the default), newest,
+	// oldest or title
+	Sort string `yaml:"sort"`
+}
+
+// Validate performs validation on a digest config
+func (d *DigestConfig) Validate() error {
+	switch d.GroupBy {
+	case "", "tag", "source", "score":
+	default:
+		return fmt.Errorf("notify.digest.group_by must be one of: tag, source, score, got '%s'", d.GroupBy)
+	}
+	for _, band := range d.ScoreBands {
+		if band < 1 {
+			return fmt.Errorf("notify.digest.score_bands must be positive, got %d", band)
+		}
+	}
+	if len(d.ScoreBands) > 0 && d.GroupBy != "score" {
+		return fmt.Errorf("notify.digest.score_bands needs group_by: score")
+	}
+	if d.SectionLimit < 0 {
+		return fmt.Errorf("notify.digest.section_limit must be non-negative, got %d", d.SectionLimit)
+	}
+	switch d.Sort {
+	case "", "score", "newest", "oldest", "title":
+	default:
+		return fmt.Errorf("notify.digest.sort must be one of: score, newest, oldest, title, got '%s'", d.Sort)
+	}
+	return nil
+}
+
+// DefaultNotifyCoalesce is how long an item sent to a notifier isn't sent
+// to it again, unless coalesce_window says otherwise
+const DefaultNotifyCoalesce = 24 * time.Hour
+
+// Coalesce returns how long an item sent to a notifier isn't sent to it
+// again
+func (n *NotifyConfig) Coalesce() time.Duration {
+	if n == nil || n.CoalesceWindow == "" {
+		return DefaultNotifyCoalesce
+	}
+	d, err := ParseDuration(n.CoalesceWindow)
+	if err != nil {
+		return DefaultNotifyCoalesce
+	}
+	return d
+}
+
+// MessageTemplates are Go templates replacing the built-in layout of
+// new-item messages and digests; an empty one keeps the built-in layout
+type MessageTemplates struct {
+	NewItems string `yaml:"new_items"`
+	Digest   string `yaml:"digest"`
+}
+
+// TemplatesFor returns the templates the notifier called name uses: its
+// own, falling back to the shared ones
+func (n *NotifyConfig) TemplatesFor(name string) MessageTemplates {
+	if n == nil {
+		return MessageTemplates{}
+	}
+	var own MessageTemplates
+	switch {
+	case name == NotifyMatrix && n.Matrix != nil:
+		own = n.Matrix.Templates
+	case name == NotifyTelegram && n.Telegram != nil:
+		own = n.Telegram.Templates
+	case name == NotifyXMPP && n.XMPP != nil:
+		own = n.XMPP.Templates
+	case name == NotifySMTP && n.SMTP != nil:
+		own = n.SMTP.Templates
+	}
+	if own.NewItems == "" {
+		own.NewItems = n.Templates.NewItems
+	}
+	if own.Digest == "" {
+		own.Digest = n.Templates.Digest
+	}
+	return own
+}
+
+// Announcers returns the notifiers a new item from source with tags is
+// posted to
+func (n *NotifyConfig) Announcers(source string, tags []string) []string {
+	if n == nil {
+		return nil
+	}
+	var names []string
+	if n.Matrix.Announces(source) {
+		names = append(names, NotifyMatrix)
+	}
+	if n.Telegram != nil && announces(n.Telegram.Rules, source, tags) {
+		names = append(names, NotifyTelegram)
+	}
+	if n.XMPP != nil && announces(n.XMPP.Rules, source, tags) {
+		names = append(names, NotifyXMPP)
+	}
+	if n.SMTP != nil && announces(n.SMTP.Rules, source, tags) {
+		names = append(names, NotifySMTP)
+	}
+	return names
+}
+
+// MatrixConfig posts to a Matrix room, by ID (!abc:example.org) or alias
+// (#news:example.org), as the user whose access token is set
+type MatrixConfig struct {
+	Homeserver string `yaml:"homeserver"`
+	RoomID     string `yaml:"room_id"`
+	// Sources limits new-item messages to these feeds; empty means all
+	Sources []string `yaml:"sources"`
+	// NewItems posts the items each fetch stores; off, only digests
+	// sent with `feedpulse digest --notify` are posted
+	NewItems  bool             `yaml:"new_items"`
+	Templates MessageTemplates `yaml:"templates"`
+}
+
+// Announces reports whether new items from source are posted
+func (m *MatrixConfig) Announces(source string) bool {
+	if m == nil || !m.NewItems {
+		return false
+	}
+	return len(m.Sources) == 0 || containsString(m.Sources, source)
+}
+
+// TelegramConfig posts to a Telegram chat through the bot whose token is
+// set. New items matching a rule are pushed; with Commands, the bot also
+// answers commands such as /latest from that chat while serve runs.
+type TelegramConfig struct {
+	// ChatID is a numeric chat ID or a public channel's @username
+	ChatID    string           `yaml:"chat_id"`
+	Rules     []NotifyRule     `yaml:"rules"`
+	Commands  bool             `yaml:"commands"`
+	Templates MessageTemplates `yaml:"templates"`
+}
+
+// NotifyRule matches new items by source and tag; an empty rule matches
+// every item
+type NotifyRule struct {
+	Sources []string `yaml:"sources"`
+	// Tags matches items with any of these tags
+	Tags []string `yaml:"tags"`
+}
+
+// matches reports whether the rule applies to an item from source with tags
+func (r NotifyRule) matches(source string, tags []string) bool {
+	if len(r.Sources) > 0 && !containsString(r.Sources, source) {
+		return false
+	}
+	if len(r.Tags) == 0 {
+		return true
+	}
+	for _, tag := range tags {
+		if containsString(r.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// announces reports whether any of rules matches a new item from source
+// with tags
+func announces(rules []NotifyRule, source string, tags []string) bool {
+	for _, rule := range rules {
+		if rule.matches(source, tags) {
+			return true
+		}
+	}
+	return false
+}
+
+// XMPPConfig sends new items and digests as XMPP chat messages from the
+// account JID, whose password is set in the environment
+type XMPPConfig struct {
+	JID string   `yaml:"jid"`
+	To  []string `yaml:"to"`
+	// Server is the host:port to connect to, if DNS doesn't say
+	Server    string           `yaml:"server"`
+	Rules     []NotifyRule     `yaml:"rules"`
+	Templates MessageTemplates `yaml:"templates"`
+}
+
+// DefaultSMTPPort is the mail submission port
+const DefaultSMTPPort = 587
+
+// SMTPConfig mails new items and digests as plain text, e.g. to
+// email-to-SMS gateways. A login, if the server needs one, is set in the
+// environment.
+type SMTPConfig struct {
+	Host string   `yaml:"host"`
+	Port int      `yaml:"port"`
+	From string   `yaml:"from"`
+	To   []string `yaml:"to"`
+	// MaxLength caps the message text, e.g. 160 for SMS; 0 means no cap
+	MaxLength int              `yaml:"max_length"`
+	Rules     []NotifyRule     `yaml:"rules"`
+	Templates MessageTemplates `yaml:"templates"`
+}
+
+// SMTPPort returns the port to connect to
+func (s *SMTPConfig) SMTPPort() int {
+	if s.Port == 0 {
+		return DefaultSMTPPort
+	}
+	return s.Port
+}
+
+// Ways to choose which items survive max_items_per_fetch
+const (
+	TruncateFirst  = "first"
+	TruncateNewest = "newest"
+)
+
+// Feed represents a single feed source
+type Feed struct {
+	Name                string             `yaml:"name"`
+	URL                 string             `yaml:"url"`
+	FeedType            string             `yaml:"feed_type"`
+	RefreshIntervalSecs int                `yaml:"refresh_interval_secs"`
+	Adaptive            *AdaptiveConfig    `yaml:"adaptive"`
+	Mode                string             `yaml:"mode"`
+	Disabled            bool               `yaml:"disabled"`
+	Headers             map[string]string  `yaml:"headers"`
+	Method              string             `yaml:"method"`
+	Body                string             `yaml:"body"`
+	Form                map[string]string  `yaml:"form"`
+	CookieJar           bool               `yaml:"cookie_jar"`
+	Login               *LoginConfig       `yaml:"login"`
+	Auth                *AuthConfig        `yaml:"auth"`
+	DependsOn           string             `yaml:"depends_on"`
+	Assertions          *AssertionsConfig  `yaml:"assertions"`
+	Filters             *FiltersConfig     `yaml:"filters"`
+	Unwrap              *UnwrapConfig      `yaml:"unwrap"`
+	XML                 *XMLConfig         `yaml:"xml"`
+	JSON                *JSONConfig        `yaml:"json"`
+	Hydrate             *HydrateConfig     `yaml:"hydrate"`
+	Plugin              *PluginConfig      `yaml:"plugin"`
+	Transform           string             `yaml:"transform"`
+	Mirrors             []string           `yaml:"mirrors"`
+	MirrorStrategy      string             `yaml:"mirror_strategy"`
+	Hedge               *HedgeConfig       `yaml:"hedge"`
+	Backfill            *BackfillConfig    `yaml:"backfill"`
+	Incremental         *IncrementalConfig `yaml:"incremental"`
+	Subreddits          []string           `yaml:"subreddits"`
+	SubredditBatch      int                `yaml:"subreddit_batch"`
+	StaleAfter          string             `yaml:"stale_after"`
+	IDHash              string             `yaml:"id_hash"`
+	// MaxItems caps the items kept from one response of this feed in
+	// place of max_items_per_fetch; 0 uses that setting
+	MaxItems int `yaml:"max_items"`
+	// Group names the set of feeds this one is reported with by
+	// `report --by group`
+	Group string `yaml:"group"`
+}
+
+// HTTPMethod returns the feed's request method, GET unless configured
+func (f *Feed) HTTPMethod() string {
+	if f.Method == "" {
+		return http.MethodGet
+	}
+	return strings.ToUpper(f.Method)
+}
+
+// ItemIDHash returns the hash the feed's item IDs are derived with,
+// sha256 unless configured
+func (f *Feed) ItemIDHash() string {
+	if f.IDHash == "" {
+		return "sha256"
+	}
+	return f.IDHash
+}
+
+// RequestBody returns the body to send and its default Content-Type, or
+// nil if the feed sends no body. Form parameters are URL-encoded.
+func (f *Feed) RequestBody() ([]byte, string) {
+	switch {
+	case len(f.Form) > 0:
+		form := url.Values{}
+		for key, value := range f.Form {
+			form.Set(key, value)
+		}
+		return []byte(form.Encode()), "application/x-www-form-urlencoded"
+	case f.Body != "":
+		if json.Valid([]byte(f.Body)) {
+			return []byte(f.Body), "application/json"
+		}
+		return []byte(f.Body), "text/plain; charset=utf-8"
+	default:
+		return nil, ""
+	}
+}
+
+// Describe summarizes the feed's request for logs and tables. The body
+// may carry credentials, so only its size is shown.
+func (f *Feed) Describe() string {
+	desc := f.URL
+	if method := f.HTTPMethod(); method != http.MethodGet {
+		desc = method + " " + desc
+	}
+	if body, _ := f.RequestBody(); body != nil {
+		desc += fmt.Sprintf(" [body redacted, %d bytes]", len(body))
+	}
+	return desc
+}
+
+// Feed modes: poll fetches the feed on each run; stream keeps a
+// connection open in serve mode and ingests events as they arrive
+const (
+	ModePoll   = "poll"
+	ModeStream = "stream"
+)
+
+// IsStream reports whether the feed is consumed as a stream
+func (f *Feed) IsStream() bool {
+	return f.Mode == ModeStream
+}
+
+// IsEnabled reports whether the feed is fetched or streamed at all; a
+// disabled feed keeps its items and history
+func (f *Feed) IsEnabled() bool {
+	return !f.Disabled
+}
+
+// Mirror strategies for feeds with mirrors
+const (
+	MirrorFailover = "failover"
+	MirrorMerge    = "merge"
+)
+
+// Endpoints returns the feed's URL followed by its mirrors
+func (f *Feed) Endpoints() []string {
+	return append([]string{f.URL}, f.Mirrors...)
+}
+
+// HedgeConfig marks a feed as flaky: when a request hasn't been answered
+// After its start, a second one is sent, to the next mirror if the feed
+// has any, and whichever succeeds first is used
+type HedgeConfig struct {
+	After string `yaml:"after"`
+}
+
+// DefaultHedgeAfter is how long a request may take before it is hedged
+const DefaultHedgeAfter = 2 * time.Second
+
+// Delay returns how long to wait for an answer before hedging
+func (h *HedgeConfig) Delay() time.Duration {
+	if h.After == "" {
+		return DefaultHedgeAfter
+	}
+	d, err := ParseDuration(h.After)
+	if err != nil {
+		return DefaultHedgeAfter
+	}
+	return d
+}
+
+// Validate performs validation on a hedge config
+func (h *HedgeConfig) Validate() error {
+	if h.After == "" {
+		return nil
+	}
+	if d, err := ParseDuration(h.After); err != nil {
+		return fmt.Errorf("hedge after: %w", err)
+	} else if d <= 0 {
+		return fmt.Errorf("hedge after must be positive, got %s", h.After)
+	}
+	return nil
+}
+
+// UsesCookies reports whether the feed keeps a persistent cookie jar
+func (f *Feed) UsesCookies() bool {
+	return f.CookieJar || f.Login != nil
+}
+
+// LoginConfig is the request `feedpulse login` sends to obtain session
+// cookies for a feed. Method defaults to POST.
+type LoginConfig struct {
+	URL     string            `yaml:"url"`
+	Method  string            `yaml:"method"`
+	Body    string            `yaml:"body"`
+	Form    map[string]string `yaml:"form"`
+	Headers map[string]string `yaml:"headers"`
+}
+
+// Request returns the login request as a feed named after the feed it
+// logs in to, so it shares that feed's cookie jar
+func (l *LoginConfig) Request(feed string) Feed {
+	method := l.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	return Feed{
+		Name:      feed,
+		URL:       l.URL,
+		Method:    method,
+		Body:      l.Body,
+		Form:      l.Form,
+		Headers:   l.Headers,
+		CookieJar: true,
+	}
+}
+
+// Feed authentication types
+const (
+	AuthBasic  = "basic"
+	AuthBearer = "bearer"
+	AuthOAuth2 = "oauth2"
+)
+
+// AuthConfig authenticates every request of a feed. Secrets aren't kept
+// in the config: each *_env field names the environment variable holding
+// one.
+type AuthConfig struct {
+	Type string `yaml:"type"`
+	// Username and PasswordEnv are the credentials of basic auth
+	Username    string `yaml:"username"`
+	PasswordEnv string `yaml:"password_env"`
+	// TokenEnv holds the token of bearer auth
+	TokenEnv string `yaml:"token_env"`
+	// TokenURL, ClientID and ClientSecretEnv get oauth2 access tokens with
+	// the client credentials grant, for Scopes
+	TokenURL        string   `yaml:"token_url"`
+	ClientID        string   `yaml:"client_id"`
+	ClientSecretEnv string   `yaml:"client_secret_env"`
+	Scopes          []string `yaml:"scopes"`
+}
+
+// Validate checks an auth config has what its type needs
+func (a *AuthConfig) Validate() error {
+	switch a.Type {
+	case AuthBasic:
+		if a.Username == "" || a.PasswordEnv == "" {
+			return fmt.Errorf("basic auth needs 'username' and 'password_env'")
+		}
+	case AuthBearer:
+		if a.TokenEnv == "" {
+			return fmt.Errorf("bearer auth needs 'token_env'")
+		}
+	case AuthOAuth2:
+		if a.TokenURL == "" || a.ClientID == "" || a.ClientSecretEnv == "" {
+			return fmt.Errorf("oauth2 auth needs 'token_url', 'client_id' and 'client_secret_env'")
+		}
+		if err := ValidateURL(a.TokenURL); err != nil {
+			return fmt.Errorf("invalid oauth2 token_url '%s'", a.TokenURL)
+		}
+	default:
+		return fmt.Errorf("auth type must be one of: %s, %s, %s, got '%s'", AuthBasic, AuthBearer, AuthOAuth2, a.Type)
+	}
+	return nil
+}
+
+// SecretEnv returns the environment variable holding the auth's secret
+func (a *AuthConfig) SecretEnv() string {
+	switch a.Type {
+	case AuthBasic:
+		return a.PasswordEnv
+	case AuthBearer:
+		return a.TokenEnv
+	case AuthOAuth2:
+		return a.ClientSecretEnv
+	}
+	return ""
+}
+
+// Secret returns the auth's password, token or client secret from the
+// environment
+func (a *AuthConfig) Secret() (string, error) {
+	env := a.SecretEnv()
+	secret := os.Getenv(env)
+	if secret == "" {
+		return "", fmt.Errorf("%s is not set", env)
+	}
+	return secret, nil
+}
+
+// SubredditPlaceholder marks where each subreddit goes in a feed URL
+const SubredditPlaceholder = "{{subreddit}}"
+
+// ExpandURLs returns the concrete URLs a feed is fetched from. A feed with
+// subreddits gets one URL per batch of SubredditBatch subreddits, joined
+// with '+' (Reddit's multireddit syntax); any other feed has just its URL.
+func (f *Feed) ExpandURLs() []string {
+	if len(f.Subreddits) == 0 {
+		return []string{f.URL}
+	}
+
+	batch := f.SubredditBatch
+	if batch < 1 {
+		batch = 1
+	}
+
+	var urls []string
+	for i := 0; i < len(f.Subreddits); i += batch {
+		end := i + batch
+		if end > len(f.Subreddits) {
+			end = len(f.Subreddits)
+		}
+		joined := strings.Join(f.Subreddits[i:end], "+")
+		urls = append(urls, strings.ReplaceAll(f.URL, SubredditPlaceholder, joined))
+	}
+	return urls
+}
+
+// BackfillConfig describes how to walk a feed's history. Exactly one of
+// PageParam (numbered pages) or CursorParam/CursorField (an opaque cursor
+// read from each response and sent with the next request) must be set.
+type BackfillConfig struct {
+	PageParam   string `yaml:"page_param"`
+	StartPage   int    `yaml:"start_page"`
+	CursorParam string `yaml:"cursor_param"`
+	CursorField string `yaml:"cursor_field"`
+	DelayMs     int    `yaml:"delay_ms"`
+}
+
+// IncrementalConfig describes how a feed picks up where its previous run
+// stopped. Either CursorParam/CursorField (a value read from each response
+// and sent as a query parameter on the next run) or LinkHeader (request the
+// rel="next" URL of the response's Link header next run) must be set.
+type IncrementalConfig struct {
+	CursorParam string `yaml:"cursor_param"`
+	CursorField string `yaml:"cursor_field"`
+	LinkHeader  bool   `yaml:"link_header"`
+}
+
+// AssertionsConfig declares what a healthy response from a feed looks
+// like. A fetch that violates it still saves its items but is logged as
+// degraded, which catches upstream breakage that parses without errors.
+type AssertionsConfig struct {
+	MinItems       int      `yaml:"min_items"`
+	RequiredFields []string `yaml:"required_fields"`
+	MaxAge         string   `yaml:"max_age"`
+}
+
+// Fields a filter rule can match
+const (
+	FilterTitle = "title"
+	FilterURL   = "url"
+	FilterTags  = "tags"
+)
+
+// FiltersConfig decides which parsed items are stored. With include rules,
+// only items matching one of them are kept; items matching an exclude rule
+// are dropped either way.
+type FiltersConfig struct {
+	Include []FilterRule `yaml:"include"`
+	Exclude []FilterRule `yaml:"exclude"`
+}
+
+// FilterRule matches an item whose field (title, url or tags; all three
+// when empty) contains Keyword, case-insensitively, or matches the regular
+// expression Regex. A tag matches a keyword only if it equals it.
+type FilterRule struct {
+	Field   string `yaml:"field"`
+	Keyword string `yaml:"keyword"`
+	Regex   string `yaml:"regex"`
+}
+
+// Validate performs validation on a filters config
+func (fc *FiltersConfig) Validate() error {
+	for i, rule := range fc.Include {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("filters include %d: %w", i, err)
+		}
+	}
+	for i, rule := range fc.Exclude {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("filters exclude %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Validate performs validation on a filter rule
+func (r *FilterRule) Validate() error {
+	switch r.Field {
+	case "", FilterTitle, FilterURL, FilterTags:
+	default:
+		return fmt.Errorf("field must be one of: %s, %s, %s, got '%s'", FilterTitle, FilterURL, FilterTags, r.Field)
+	}
+	if (r.Keyword == "") == (r.Regex == "") {
+		return fmt.Errorf("needs exactly one of 'keyword' or 'regex'")
+	}
+	if r.Regex != "" {
+		if _, err := regexp.Compile(r.Regex); err != nil {
+			return fmt.Errorf("invalid regex '%s': %w", r.Regex, err)
+		}
+	}
+	return nil
+}
+
+// UnwrapConfig describes how to dig a JSON document out of a wrapped
+// response before parsing. StripJSONP removes a "callback(...)" wrapper;
+// JSONStringField names a field (dot path) whose string value is the real,
+// escaped JSON document. When both are set, JSONP is stripped first.
+type UnwrapConfig struct {
+	StripJSONP      bool   `yaml:"strip_jsonp"`
+	JSONStringField string `yaml:"json_string_field"`
+}
+
+// XMLConfig maps an arbitrary XML document to items for feed_type xml.
+// Item is an XPath-like path from the document root; the other paths are
+// relative to each item (see parser.XMLMapping for the syntax). Prefixes
+// used in paths must be declared in Namespaces.
+type XMLConfig struct {
+	Item       string            `yaml:"item"`
+	Title      string            `yaml:"title"`
+	URL        string            `yaml:"url"`
+	Date       string            `yaml:"date"`
+	Tags       string            `yaml:"tags"`
+	Namespaces map[string]string `yaml:"namespaces"`
+}
+
+// JSONConfig maps an arbitrary JSON document to items for feed_type json,
+// replacing the built-in structure detection. Item is a JSONPath-like path
+// from the document root; the other paths are relative to each item (see
+// parser.JSONMapping for the syntax).
+type JSONConfig struct {
+	Item  string `yaml:"item"`
+	Title string `yaml:"title"`
+	URL   string `yaml:"url"`
+	Date  string `yaml:"date"`
+	Tags  string `yaml:"tags"`
+}
+
+// AdaptiveConfig lets the daemon move a feed's refresh interval between
+// MinInterval and MaxInterval to follow how often it posts: shorter while
+// fetches bring several new items, longer while they bring none
+type AdaptiveConfig struct {
+	MinInterval string `yaml:"min_interval"`
+	MaxInterval string `yaml:"max_interval"`
+}
+
+// Default bounds of an adaptive refresh interval
+const (
+	DefaultAdaptiveMin = time.Minute
+	DefaultAdaptiveMax = 24 * time.Hour
+)
+
+// Bounds returns the shortest and longest interval the daemon may use
+func (a *AdaptiveConfig) Bounds() (time.Duration, time.Duration) {
+	shortest, longest := DefaultAdaptiveMin, DefaultAdaptiveMax
+	if a.MinInterval != "" {
+		if d, err := ParseDuration(a.MinInterval); err == nil {
+			shortest = d
+		}
+	}
+	if a.MaxInterval != "" {
+		if d, err := ParseDuration(a.MaxInterval); err == nil {
+			longest = d
+		}
+	}
+	return shortest, longest
+}
+
+// Validate performs validation on an adaptive config
+func (a *AdaptiveConfig) Validate() error {
+	for _, bound := range []struct{ name, value string }{{"min_interval", a.MinInterval}, {"max_interval", a.MaxInterval}} {
+		if bound.value == "" {
+			continue
+		}
+		if _, err := ParseDuration(bound.value); err != nil {
+			return fmt.Errorf("adaptive %s: %w", bound.name, err)
+		}
+	}
+	shortest, longest := a.Bounds()
+	if shortest < time.Minute {
+		return fmt.Errorf("adaptive min_interval must be at least 1m, got %s", shortest)
+	}
+	if longest < shortest {
+		return fmt.Errorf("adaptive max_interval (%s) must not be below min_interval (%s)", longest, shortest)
+	}
+	return nil
+}
+
+// PluginConfig is the external parser of a feed_type plugin feed.
+// Command is run with Args for every response, which it reads on stdin;
+// it writes the items as JSON on stdout (see parser.Plugin). A command
+// path with a directory in it is relative to the config file.
+type PluginConfig struct {
+	Command   string   `yaml:"command"`
+	Args      []string `yaml:"args"`
+	Timeout   string   `yaml:"timeout"`
+	MaxOutput int      `yaml:"max_output"`
+}
+
+// Plugin defaults
+const (
+	DefaultPluginTimeout   = 10 * time.Second
+	DefaultPluginMaxOutput = 10 * 1024 * 1024
+)
+
+// Path returns the command to run, resolved against dir, the config
+// file's directory, if it names a relative path rather than a program to
+// find on PATH
+func (p *PluginConfig) Path(dir string) string {
+	if filepath.IsAbs(p.Command) || !strings.ContainsAny(p.Command, "/"+string(filepath.Separator)) {
+		return p.Command
+	}
+	return filepath.Join(dir, p.Command)
+}
+
+// Deadline returns how long the command may run for one response
+func (p *PluginConfig) Deadline() time.Duration {
+	if p.Timeout == "" {
+		return DefaultPluginTimeout
+	}
+	d, err := ParseDuration(p.Timeout)
+	if err != nil {
+		return DefaultPluginTimeout
+	}
+	return d
+}
+
+// OutputLimit returns how many bytes of output the command may write
+func (p *PluginConfig) OutputLimit() int {
+	if p.MaxOutput == 0 {
+		return DefaultPluginMaxOutput
+	}
+	return p.MaxOutput
+}
+
+// Validate performs validation on a plugin config
+func (p *PluginConfig) Validate() error {
+	if strings.TrimSpace(p.Command) == "" {
+		return fmt.Errorf("plugin needs a 'command'")
+	}
+	if p.Timeout != "" {
+		if d, err := ParseDuration(p.Timeout); err != nil {
+			return fmt.Errorf("plugin timeout: %w", err)
+		} else if d <= 0 {
+			return fmt.Errorf("plugin timeout must be positive, got %s", p.Timeout)
+		}
+	}
+	if p.MaxOutput < 0 {
+		return fmt.Errorf("plugin max_output must be non-negative, got %d", p.MaxOutput)
+	}
+	return nil
+}
+
+// HydrateConfig replaces the "HN Story <id>" placeholders a Hacker News
+// ID list parses to with each story's details, fetched one request per ID
+// from the item API. Only the first MaxItems IDs are kept. ItemURL is the
+// item API URL, with HNItemPlaceholder where the ID goes.
+type HydrateConfig struct {
+	MaxItems    int    `yaml:"max_items"`
+	Concurrency int    `yaml:"concurrency"`
+	ItemURL     string `yaml:"item_url"`
+}
+
+// HNItemPlaceholder marks where the story ID goes in a hydrate item_url
+const HNItemPlaceholder = "{{id}}"
+
+// Hydration defaults: the size of the Hacker News front page, and a few
+// item requests at a time
+const (
+	DefaultHydrateMaxItems    = 30
+	DefaultHydrateConcurrency = 5
+	DefaultHNItemURL          = "https://hacker-news.firebaseio.com/v0/item/" + HNItemPlaceholder + ".json"
+)
+
+// Limit returns how many stories to hydrate and keep
+func (h *HydrateConfig) Limit() int {
+	if h.MaxItems == 0 {
+		return DefaultHydrateMaxItems
+	}
+	return h.MaxItems
+}
+
+// Workers returns how many item requests may run at once
+func (h *HydrateConfig) Workers() int {
+	if h.Concurrency == 0 {
+		return DefaultHydrateConcurrency
+	}
+	return h.Concurrency
+}
+
+// StoryURL returns the item API URL for a story ID
+func (h *HydrateConfig) StoryURL(id string) string {
+	itemURL := h.ItemURL
+	if itemURL == "" {
+		itemURL = DefaultHNItemURL
+	}
+	return strings.ReplaceAll(itemURL, HNItemPlaceholder, id)
+}
+
+// Validate performs validation on a hydrate config
+func (h *HydrateConfig) Validate() error {
+	if h.MaxItems < 0 {
+		return fmt.Errorf("hydrate max_items must be non-negative, got %d", h.MaxItems)
+	}
+	if h.Concurrency < 0 || h.Concurrency > 50 {
+		return fmt.Errorf("hydrate concurrency must be between 0 and 50, got %d", h.Concurrency)
+	}
+	if h.ItemURL != "" {
+		if !strings.Contains(h.ItemURL, HNItemPlaceholder) {
+			return fmt.Errorf("hydrate item_url must contain %s", HNItemPlaceholder)
+		}
+		if err := ValidateURL(h.StoryURL("1")); err != nil {
+			return fmt.Errorf("hydrate item_url: %w", err)
+		}
+	}
+	return nil
+}
+
+// LoadConfig loads and validates the configuration file
+func LoadConfig(path string) (*Config, error) {
+	// Check if file exists
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, fmt.Errorf("config file not found: %s", path)
+	}
+
+	// Read file
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Dir = filepath.Dir(path)
+	return cfg, nil
+}
+
+// ParseConfig parses YAML config data, applies defaults and validates it
+func ParseConfig(data []byte) (*Config, error) {
+	// Parse YAML
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	// Apply defaults
+	if cfg.Settings.MaxConcurrency == 0 {
+		cfg.Settings.MaxConcurrency = 5
+	}
+	if cfg.Settings.DefaultTimeoutSecs == 0 {
+		cfg.Settings.DefaultTimeoutSecs = 10
+	}
+	if cfg.Settings.RetryMax == 0 {
+		cfg.Settings.RetryMax = 3
+	}
+	if cfg.Settings.RetryBaseDelayMs == 0 {
+		cfg.Settings.RetryBaseDelayMs = 500
+	}
+	if cfg.Settings.DatabasePath == "" {
+		cfg.Settings.DatabasePath = "feedpulse.db"
+	}
+	if cfg.Settings.UniquenessScope == "" {
+		cfg.Settings.UniquenessScope = "source"
+	}
+	if cfg.Settings.Dedup == "" {
+		cfg.Settings.Dedup = "off"
+	}
+	if cfg.Settings.SchemaDriftThreshold == 0 {
+		cfg.Settings.SchemaDriftThreshold = 0.2
+	}
+
+	// Validate
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// Validate performs validation on the configuration
+func (c *Config) Validate() error {
+	// Validate settings
+	if c.Settings.MaxConcurrency < 1 || c.Settings.MaxConcurrency > 50 {
+		return fmt.Errorf("max_concurrency must be between 1 and 50, got %d", c.Settings.MaxConcurrency)
+	}
+	if c.Settings.DefaultTimeoutSecs < 1 {
+		return fmt.Errorf("default_timeout_secs must be positive, got %d", c.Settings.DefaultTimeoutSecs)
+	}
+	if c.Settings.RetryMax < 0 {
+		return fmt.Errorf("retry_max must be non-negative, got %d", c.Settings.RetryMax)
+	}
+	if c.Settings.RetryBaseDelayMs < 0 {
+		return fmt.Errorf("retry_base_delay_ms must be non-negative, got %d", c.Settings.RetryBaseDelayMs)
+	}
+	if c.Settings.UniquenessScope != "" {
+		if err := ValidateUniquenessScope(c.Settings.UniquenessScope); err != nil {
+			return err
+		}
+	}
+	if c.Settings.Dedup != "" {
+		if err := ValidateDedup(c.Settings.Dedup); err != nil {
+			return err
+		}
+	}
+	if c.Settings.MaxItemsPerFetch < 0 {
+		return fmt.Errorf("max_items_per_fetch must be non-negative, got %d", c.Settings.MaxItemsPerFetch)
+	}
+	switch c.Settings.TruncateBy {
+	case "", TruncateFirst, TruncateNewest:
+	default:
+		return fmt.Errorf("truncate_by must be one of: %s, %s, got '%s'", TruncateFirst, TruncateNewest, c.Settings.TruncateBy)
+	}
+	if c.Settings.SchemaDriftThreshold < 0 || c.Settings.SchemaDriftThreshold > 1 {
+		return fmt.Errorf("schema_drift_threshold must be between 0 and 1, got %g", c.Settings.SchemaDriftThreshold)
+	}
+
+	if c.Settings.DatabaseURL != "" {
+		if err := ValidateDatabaseURL(c.Settings.DatabaseURL); err != nil {
+			return err
+		}
+	}
+
+	if c.Settings.StaleAfter != "" {
+		if _, err := ParseDuration(c.Settings.StaleAfter); err != nil {
+			return fmt.Errorf("stale_after: %w", err)
+		}
+	}
+	if c.Settings.Timezone != "" {
+		if _, err := time.LoadLocation(c.Settings.Timezone); err != nil {
+			return fmt.Errorf("timezone must be an IANA time zone name such as 'Europe/Berlin', got '%s'", c.Settings.Timezone)
+		}
+	}
+	for table, columns := range c.Settings.Columns {
+		if err := ValidateColumns(table, columns); err != nil {
+			return err
+		}
+	}
+	for host, budget := range c.Settings.Budgets {
+		if host == "" || strings.ContainsAny(host, "/:") {
+			return fmt.Errorf("budgets: '%s' must be a host name such as 'api.github.com'", host)
+		}
+		if err := budget.Validate(); err != nil {
+			return fmt.Errorf("budgets '%s': %w", host, err)
+		}
+	}
+
+	// Validate feeds
+	if len(c.Feeds) == 0 {
+		return fmt.Errorf("no feeds configured")
+	}
+
+	for i, feed := range c.Feeds {
+		if err := feed.Validate(); err != nil {
+			return fmt.Errorf("feed %d: %w", i, err)
+		}
+	}
+
+	// Global scope dedups URLs across feeds by ID, so every feed must
+	// derive IDs the same way
+	if c.Settings.UniquenessScope == "global" {
+		for _, feed := range c.Feeds[1:] {
+			if feed.ItemIDHash() != c.Feeds[0].ItemIDHash() {
+				return fmt.Errorf("feed '%s': id_hash must match across feeds with uniqueness_scope 'global'", feed.Name)
+			}
+		}
+	}
+
+	if err := c.validateArchive(); err != nil {
+		return err
+	}
+	if err := c.validateReadLater(); err != nil {
+		return err
+	}
+	if err := c.validateNotify(); err != nil {
+		return err
+	}
+	if err := c.validateErrorReporting(); err != nil {
+		return err
+	}
+	if err := c.validateRedact(); err != nil {
+		return err
+	}
+	if fc := c.Settings.Filters; fc != nil {
+		if err := fc.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return c.validateDependencies()
+}
+
+// validateRedact checks the extra secret headers and query parameters
+// are named
+func (c *Config) validateRedact() error {
+	r := c.Settings.Redact
+	if r == nil {
+		return nil
+	}
+	for _, name := range r.Headers {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("redact.headers must not contain empty names")
+		}
+	}
+	for _, name := range r.QueryParams {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("redact.query_params must not contain empty names")
+		}
+	}
+	return nil
+}
+
+// validateErrorReporting checks the error reporting DSN looks like one
+func (c *Config) validateErrorReporting() error {
+	e := c.Settings.ErrorReporting
+	if e == nil || e.DSN == "" {
+		return nil
+	}
+	u, err := url.Parse(e.DSN)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.User == nil || strings.Trim(u.Path, "/") == "" {
+		return fmt.Errorf("error_reporting.dsn must be a DSN such as 'https://<key>@o1.ingest.sentry.io/<project>'")
+	}
+	return nil
+}
+
+// Fingerprint identifies the config's contents, so error reports can
+// tell which version of it was running without including it
+func (c *Config) Fingerprint() string {
+	data, err := Marshal(c)
+	if err != nil {
+		return "unknown"
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:6])
+}
+
+// validateArchive checks the archive settings name configured feeds
+func (c *Config) validateArchive() error {
+	a := c.Settings.Archive
+	if a == nil {
+		return nil
+	}
+	if a.Interval != "" {
+		if d, err := ParseDuration(a.Interval); err != nil {
+			return fmt.Errorf("archive.interval: %w", err)
+		} else if d <= 0 {
+			return fmt.Errorf("archive.interval must be positive, got %s", a.Interval)
+		}
+	}
+	for _, source := range a.Sources {
+		found := false
+		for _, feed := range c.Feeds {
+			if feed.Name == source {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("archive.sources: unknown feed '%s'", source)
+		}
+	}
+	return nil
+}
+
+// validateReadLater checks auto-save rules name known services and feeds
+func (c *Config) validateReadLater() error {
+	r := c.Settings.ReadLater
+	if r == nil {
+		return nil
+	}
+	for _, service := range ReadLaterServices {
+		instance := r.ServiceURL(service)
+		if instance == "" {
+			continue
+		}
+		if u, err := url.Parse(instance); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("read_later.%s_url must be an http(s) URL, got '%s'", service, instance)
+		}
+	}
+	for i, rule := range r.AutoSave {
+		if !isReadLaterService(rule.To) {
+			return fmt.Errorf("read_later.auto_save %d: unknown service '%s' (must be one of: %s)", i, rule.To, strings.Join(ReadLaterServices, ", "))
+		}
+		if SelfHosted(rule.To) && r.ServiceURL(rule.To) == "" {
+			return fmt.Errorf("read_later.auto_save %d: saving to %s needs read_later.%s_url", i, rule.To, rule.To)
+		}
+		if !rule.Pinned && len(rule.Sources) == 0 && len(rule.Tags) == 0 {
+			return fmt.Errorf("read_later.auto_save %d: set sources, tags or pinned, or every item would be saved", i)
+		}
+		for _, source := range rule.Sources {
+			if findFeedName(c.Feeds, source) {
+				continue
+			}
+			return fmt.Errorf("read_later.auto_save %d: unknown feed '%s'", i, source)
+		}
+	}
+	return nil
+}
+
+// validateNotify checks notifiers have somewhere to post and name
+// configured feeds
+func (c *Config) validateNotify() error {
+	n := c.Settings.Notify
+	if n == nil {
+		return nil
+	}
+	if n.CoalesceWindow != "" {
+		if _, err := ParseDuration(n.CoalesceWindow); err != nil {
+			return fmt.Errorf("notify.coalesce_window: %w", err)
+		}
+	}
+	if err := n.Digest.Validate(); err != nil {
+		return err
+	}
+	if m := n.Matrix; m != nil {
+		if u, err := url.Parse(m.Homeserver); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("notify.matrix.homeserver must be an http(s) URL, got '%s'", m.Homeserver)
+		}
+		if !(strings.HasPrefix(m.RoomID, "!") || strings.HasPrefix(m.RoomID, "#")) || !strings.Contains(m.RoomID, ":") {
+			return fmt.Errorf("notify.matrix.room_id must be a room ID (!id:server) or alias (#name:server), got '%s'", m.RoomID)
+		}
+		for _, source := range m.Sources {
+			if !findFeedName(c.Feeds, source) {
+				return fmt.Errorf("notify.matrix.sources: unknown feed '%s'", source)
+			}
+		}
+	}
+	if t := n.Telegram; t != nil {
+		if _, err := strconv.ParseInt(t.ChatID, 10, 64); err != nil && (!strings.HasPrefix(t.ChatID, "@") || len(t.ChatID) < 2) {
+			return fmt.Errorf("notify.telegram.chat_id must be a numeric chat ID or @channel, got '%s'", t.ChatID)
+		}
+		if err := c.validateNotifyRules(NotifyTelegram, t.Rules); err != nil {
+			return err
+		}
+	}
+	if x := n.XMPP; x != nil {
+		if !isAddress(x.JID) {
+			return fmt.Errorf("notify.xmpp.jid must be an address like feedpulse@example.org, got '%s'", x.JID)
+		}
+		if len(x.To) == 0 {
+			return fmt.Errorf("notify.xmpp.to must list at least one address")
+		}
+		for _, to := range x.To {
+			if !isAddress(to) {
+				return fmt.Errorf("notify.xmpp.to: invalid address '%s'", to)
+			}
+		}
+		if err := c.validateNotifyRules(NotifyXMPP, x.Rules); err != nil {
+			return err
+		}
+	}
+	if m := n.SMTP; m != nil {
+		if m.Host == "" {
+			return fmt.Errorf("notify.smtp.host is required")
+		}
+		if m.Port < 0 || m.Port > 65535 {
+			return fmt.Errorf("notify.smtp.port must be between 1 and 65535, got %d", m.Port)
+		}
+		if !isAddress(m.From) {
+			return fmt.Errorf("notify.smtp.from must be an email address, got '%s'", m.From)
+		}
+		if len(m.To) == 0 {
+			return fmt.Errorf("notify.smtp.to must list at least one address")
+		}
+		for _, to := range m.To {
+			if !isAddress(to) {
+				return fmt.Errorf("notify.smtp.to: invalid address '%s'", to)
+			}
+		}
+		if m.MaxLength < 0 || (m.MaxLength > 0 && m.MaxLength < 20) {
+			return fmt.Errorf("notify.smtp.max_length must be 0 (no cap) or at least 20, got %d", m.MaxLength)
+		}
+		if err := c.validateNotifyRules(NotifySMTP, m.Rules); err != nil {
+			return err
+		}
+	}
+	if _, err := notify.ParseTemplates(n.Templates.NewItems, n.Templates.Digest); err != nil {
+		return fmt.Errorf("notify.templates: %w", err)
+	}
+	for _, name := range Notifiers {
+		t := n.TemplatesFor(name)
+		if _, err := notify.ParseTemplates(t.NewItems, t.Digest); err != nil {
+			return fmt.Errorf("notify.%s.templates: %w", name, err)
+		}
+	}
+	return nil
 }
 
-// Settings contains global configuration
-type Settings struct {
-	MaxConcurrency     int    `yaml:"max_concurrency"`
-	DefaultTimeoutSecs int    `yaml:"default_timeout_secs"`
-	RetryMax           int    `yaml:"retry_max"`
-	RetryBaseDelayMs   int    `yaml:"retry_base_delay_ms"`
-	DatabasePath       string `yaml:"database_path"`
+// validateNotifyRules checks a notifier's rules name configured feeds
+func (c *Config) validateNotifyRules(notifier string, rules []NotifyRule) error {
+	for i, rule := range rules {
+		for _, source := range rule.Sources {
+			if !findFeedName(c.Feeds, source) {
+				return fmt.Errorf("notify.%s.rules %d: unknown feed '%s'", notifier, i, source)
+			}
+		}
+	}
+	return nil
 }
 
-// Feed represents a single feed source
-type Feed struct {
-	Name                 string            `yaml:"name"`
-	URL                  string            `yaml:"url"`
-	FeedType             string            `yaml:"feed_type"`
-	RefreshIntervalSecs  int               `yaml:"refresh_interval_secs"`
-	Headers              map[string]string `yaml:"headers"`
+// isAddress reports whether s looks like local@domain, the shape of both
+// email and XMPP addresses
+func isAddress(s string) bool {
+	local, domain, ok := strings.Cut(s, "@")
+	return ok && local != "" && domain != "" && !strings.ContainsAny(s, " <>'\"\r\n")
 }
 
-// LoadConfig loads and validates the configuration file
-func LoadConfig(path string) (*Config, error) {
-	// Check if file exists
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return nil, fmt.Errorf("config file not found: %s", path)
+// findFeedName reports whether a feed called name is configured
+func findFeedName(feeds []Feed, name string) bool {
+	for _, feed := range feeds {
+		if feed.Name == name {
+			return true
+		}
 	}
+	return false
+}
 
-	// Read file
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config: %w", err)
+// validateDependencies checks that every depends_on names another
+// configured feed and that dependencies don't form a cycle
+func (c *Config) validateDependencies() error {
+	names := make(map[string]bool, len(c.Feeds))
+	for _, feed := range c.Feeds {
+		names[feed.Name] = true
 	}
 
-	// Parse YAML
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("invalid config: %w", err)
+	for _, feed := range c.Feeds {
+		if feed.DependsOn == "" {
+			continue
+		}
+		if feed.DependsOn == feed.Name {
+			return fmt.Errorf("feed '%s': cannot depend on itself", feed.Name)
+		}
+		if !names[feed.DependsOn] {
+			return fmt.Errorf("feed '%s': depends_on names unknown feed '%s'", feed.Name, feed.DependsOn)
+		}
 	}
 
-	// Apply defaults
-	if cfg.Settings.MaxConcurrency == 0 {
-		cfg.Settings.MaxConcurrency = 5
-	}
-	if cfg.Settings.DefaultTimeoutSecs == 0 {
-		cfg.Settings.DefaultTimeoutSecs = 10
+	if _, cyclic := c.stages(); len(cyclic) > 0 {
+		return fmt.Errorf("feed '%s': depends_on forms a cycle", cyclic[0].Name)
 	}
-	if cfg.Settings.RetryMax == 0 {
-		cfg.Settings.RetryMax = 3
+
+	return nil
+}
+
+// Location returns the time zone times are shown in
+func (s *Settings) Location() *time.Location {
+	if s.Timezone == "" {
+		return time.Local
 	}
-	if cfg.Settings.RetryBaseDelayMs == 0 {
-		cfg.Settings.RetryBaseDelayMs = 500
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.Local
 	}
-	if cfg.Settings.DatabasePath == "" {
-		cfg.Settings.DatabasePath = "feedpulse.db"
+	return loc
+}
+
+// DefaultMaxFailureBytes is how much of a response that failed to parse
+// is recorded unless max_failure_bytes says otherwise
+const DefaultMaxFailureBytes = 256 * 1024
+
+// FailureCap returns how many bytes of a response that failed to parse
+// to record, or 0 if none are
+func (s *Settings) FailureCap() int {
+	switch {
+	case s.MaxFailureBytes < 0:
+		return 0
+	case s.MaxFailureBytes == 0:
+		return DefaultMaxFailureBytes
 	}
+	return s.MaxFailureBytes
+}
 
-	// Validate
-	if err := cfg.Validate(); err != nil {
-		return nil, err
+// DatabaseDSN returns what to open the database with: database_url when
+// set, otherwise the SQLite file at database_path
+func (s *Settings) DatabaseDSN() string {
+	if s.DatabaseURL != "" {
+		return s.DatabaseURL
 	}
+	return s.DatabasePath
+}
 
-	return &cfg, nil
+// ValidateDatabaseURL checks that a database URL points at a PostgreSQL
+// server
+func ValidateDatabaseURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") || u.Host == "" {
+		return fmt.Errorf("database_url must be a URL such as 'postgres://user@host/feedpulse', got '%s'", redactURL(raw))
+	}
+	return nil
 }
 
-// Validate performs validation on the configuration
-func (c *Config) Validate() error {
-	// Validate settings
-	if c.Settings.MaxConcurrency < 1 || c.Settings.MaxConcurrency > 50 {
-		return fmt.Errorf("max_concurrency must be between 1 and 50, got %d", c.Settings.MaxConcurrency)
+// redactURL hides the password of a URL that has one
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
 	}
-	if c.Settings.DefaultTimeoutSecs < 1 {
-		return fmt.Errorf("default_timeout_secs must be positive, got %d", c.Settings.DefaultTimeoutSecs)
+	if _, ok := u.User.Password(); !ok {
+		return raw
 	}
-	if c.Settings.RetryMax < 0 {
-		return fmt.Errorf("retry_max must be non-negative, got %d", c.Settings.RetryMax)
+	return u.Redacted()
+}
+
+// ColumnsFor returns the columns configured for a table, or its defaults
+func (s *Settings) ColumnsFor(table string) []string {
+	if columns := s.Columns[table]; len(columns) > 0 {
+		return columns
 	}
-	if c.Settings.RetryBaseDelayMs < 0 {
-		return fmt.Errorf("retry_base_delay_ms must be non-negative, got %d", c.Settings.RetryBaseDelayMs)
+	return DefaultColumns[table]
+}
+
+// StaleAfter returns how old a feed's newest item may get before the feed
+// counts as stale: its own stale_after, else the settings default, else 0
+// (never stale). "0" on the feed turns the default off for it.
+func (c *Config) StaleAfter(feed Feed) time.Duration {
+	value := feed.StaleAfter
+	if value == "" {
+		value = c.Settings.StaleAfter
 	}
+	d, _ := ParseDuration(value)
+	return d
+}
 
-	// Validate feeds
-	if len(c.Feeds) == 0 {
-		return fmt.Errorf("no feeds configured")
+// FeedStages groups feeds into stages that can be fetched in order: each
+// feed comes in the stage after the feed it depends on, and feeds within
+// a stage keep their configured order. Feeds caught in a dependency cycle
+// all go in one final stage.
+func (c *Config) FeedStages() [][]Feed {
+	stages, cyclic := c.stages()
+	if len(cyclic) > 0 {
+		stages = append(stages, cyclic)
 	}
+	return stages
+}
 
-	for i, feed := range c.Feeds {
-		if err := feed.Validate(); err != nil {
-			return fmt.Errorf("feed %d: %w", i, err)
+// stages orders feeds by dependency, returning the feeds that could not be
+// ordered separately
+func (c *Config) stages() ([][]Feed, []Feed) {
+	placed := make(map[string]bool, len(c.Feeds))
+	remaining := c.Feeds
+	var stages [][]Feed
+
+	for len(remaining) > 0 {
+		var stage, blocked []Feed
+		for _, feed := range remaining {
+			if feed.DependsOn == "" || placed[feed.DependsOn] {
+				stage = append(stage, feed)
+			} else {
+				blocked = append(blocked, feed)
+			}
+		}
+
+		if len(stage) == 0 {
+			return stages, blocked
+		}
+		for _, feed := range stage {
+			placed[feed.Name] = true
 		}
+		stages = append(stages, stage)
+		remaining = blocked
 	}
 
-	return nil
+	return stages, nil
 }
 
 // Validate performs validation on a single feed
@@ -118,9 +1737,91 @@ func (f *Feed) Validate() error {
 		return fmt.Errorf("feed '%s': missing field 'url'", f.Name)
 	}
 
-	parsedURL, err := url.ParseRequestURI(f.URL)
-	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
-		return fmt.Errorf("feed '%s': invalid URL '%s'", f.Name, f.URL)
+	if err := validateTemplateVars(f.URL); err != nil {
+		return fmt.Errorf("feed '%s': %w", f.Name, err)
+	}
+
+	// Subreddit expansion
+	hasPlaceholder := strings.Contains(f.URL, SubredditPlaceholder)
+	if len(f.Subreddits) > 0 && !hasPlaceholder {
+		return fmt.Errorf("feed '%s': 'subreddits' requires %s in the URL", f.Name, SubredditPlaceholder)
+	}
+	if len(f.Subreddits) == 0 && hasPlaceholder {
+		return fmt.Errorf("feed '%s': URL uses %s but no 'subreddits' are listed", f.Name, SubredditPlaceholder)
+	}
+	for _, sub := range f.Subreddits {
+		if sub == "" || strings.ContainsAny(sub, "/+?#& ") {
+			return fmt.Errorf("feed '%s': invalid subreddit name '%s'", f.Name, sub)
+		}
+	}
+	if f.SubredditBatch < 0 {
+		return fmt.Errorf("feed '%s': subreddit_batch must be non-negative, got %d", f.Name, f.SubredditBatch)
+	}
+	if f.MaxItems < 0 {
+		return fmt.Errorf("feed '%s': max_items must be non-negative, got %d", f.Name, f.MaxItems)
+	}
+
+	sampleVars := URLVars(time.Now(), time.Now())
+	for _, u := range f.ExpandURLs() {
+		parsedURL, err := url.ParseRequestURI(RenderURL(u, sampleVars))
+		if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+			return fmt.Errorf("feed '%s': invalid URL '%s'", f.Name, f.URL)
+		}
+	}
+
+	if err := f.validateRequest(); err != nil {
+		return fmt.Errorf("feed '%s': %w", f.Name, err)
+	}
+
+	if f.Auth != nil {
+		if err := f.Auth.Validate(); err != nil {
+			return fmt.Errorf("feed '%s': %w", f.Name, err)
+		}
+		for name := range f.Headers {
+			if strings.EqualFold(name, "Authorization") {
+				return fmt.Errorf("feed '%s': 'auth' cannot be combined with an Authorization header", f.Name)
+			}
+		}
+	}
+
+	if f.Login != nil {
+		login := f.Login.Request(f.Name)
+		if err := ValidateURL(login.URL); err != nil {
+			return fmt.Errorf("feed '%s': login: %w", f.Name, err)
+		}
+		if err := login.validateRequest(); err != nil {
+			return fmt.Errorf("feed '%s': login: %w", f.Name, err)
+		}
+	}
+
+	// Mirrors
+	for _, mirror := range f.Mirrors {
+		if err := validateTemplateVars(mirror); err != nil {
+			return fmt.Errorf("feed '%s': mirror: %w", f.Name, err)
+		}
+		if err := ValidateURL(RenderURL(mirror, sampleVars)); err != nil {
+			return fmt.Errorf("feed '%s': invalid mirror URL '%s'", f.Name, mirror)
+		}
+	}
+	switch f.MirrorStrategy {
+	case "", MirrorFailover, MirrorMerge:
+	default:
+		return fmt.Errorf("feed '%s': mirror_strategy must be one of: %s, %s, got '%s'", f.Name, MirrorFailover, MirrorMerge, f.MirrorStrategy)
+	}
+	if len(f.Mirrors) > 0 && len(f.Subreddits) > 0 {
+		return fmt.Errorf("feed '%s': 'mirrors' cannot be combined with 'subreddits'", f.Name)
+	}
+	if len(f.Mirrors) > 0 && f.Incremental != nil {
+		return fmt.Errorf("feed '%s': 'mirrors' cannot be combined with 'incremental'", f.Name)
+	}
+	if f.Hedge != nil {
+		if err := f.Hedge.Validate(); err != nil {
+			return fmt.Errorf("feed '%s': %w", f.Name, err)
+		}
+		// Merged mirrors are all fetched anyway
+		if f.MirrorStrategy == MirrorMerge {
+			return fmt.Errorf("feed '%s': 'hedge' cannot be combined with mirror_strategy '%s'", f.Name, MirrorMerge)
+		}
 	}
 
 	// Feed type required
@@ -129,9 +1830,9 @@ func (f *Feed) Validate() error {
 	}
 
 	// Feed type must be valid
-	validTypes := map[string]bool{"json": true, "rss": true, "atom": true}
+	validTypes := map[string]bool{"json": true, "ndjson": true, "xml": true, "rss": true, "atom": true, "plugin": true}
 	if !validTypes[f.FeedType] {
-		return fmt.Errorf("feed '%s': feed_type must be one of: json, rss, atom, got '%s'", f.Name, f.FeedType)
+		return fmt.Errorf("feed '%s': feed_type must be one of: json, ndjson, xml, rss, atom, plugin, got '%s'", f.Name, f.FeedType)
 	}
 
 	// Refresh interval must be positive if set
@@ -144,5 +1845,352 @@ func (f *Feed) Validate() error {
 		f.RefreshIntervalSecs = 300
 	}
 
+	if f.Adaptive != nil {
+		if err := f.Adaptive.Validate(); err != nil {
+			return fmt.Errorf("feed '%s': %w", f.Name, err)
+		}
+	}
+
+	if f.Backfill != nil {
+		if err := f.Backfill.Validate(); err != nil {
+			return fmt.Errorf("feed '%s': %w", f.Name, err)
+		}
+	}
+
+	if f.Filters != nil {
+		if err := f.Filters.Validate(); err != nil {
+			return fmt.Errorf("feed '%s': %w", f.Name, err)
+		}
+	}
+
+	if f.Assertions != nil {
+		if err := f.Assertions.Validate(); err != nil {
+			return fmt.Errorf("feed '%s': %w", f.Name, err)
+		}
+		if len(f.Assertions.RequiredFields) > 0 && f.FeedType != "json" {
+			return fmt.Errorf("feed '%s': assertions 'required_fields' only apply to json feeds", f.Name)
+		}
+	}
+
+	if err := f.validateMode(); err != nil {
+		return fmt.Errorf("feed '%s': %w", f.Name, err)
+	}
+
+	if f.StaleAfter != "" {
+		if _, err := ParseDuration(f.StaleAfter); err != nil {
+			return fmt.Errorf("feed '%s': stale_after: %w", f.Name, err)
+		}
+	}
+
+	switch f.IDHash {
+	case "", "sha256", "xxhash64":
+	default:
+		return fmt.Errorf("feed '%s': id_hash must be one of: sha256, xxhash64, got '%s'", f.Name, f.IDHash)
+	}
+
+	if f.Unwrap != nil {
+		if err := f.Unwrap.Validate(); err != nil {
+			return fmt.Errorf("feed '%s': %w", f.Name, err)
+		}
+		if f.FeedType != "json" {
+			return fmt.Errorf("feed '%s': 'unwrap' only applies to json feeds", f.Name)
+		}
+	}
+
+	if f.FeedType == "xml" && f.XML == nil {
+		return fmt.Errorf("feed '%s': feed_type xml needs an 'xml' mapping", f.Name)
+	}
+	if f.XML != nil {
+		if f.FeedType != "xml" {
+			return fmt.Errorf("feed '%s': 'xml' mapping only applies to xml feeds", f.Name)
+		}
+		if err := f.XML.Validate(); err != nil {
+			return fmt.Errorf("feed '%s': %w", f.Name, err)
+		}
+	}
+
+	if f.JSON != nil {
+		if f.FeedType != "json" {
+			return fmt.Errorf("feed '%s': 'json' mapping only applies to json feeds", f.Name)
+		}
+		if err := f.JSON.Validate(); err != nil {
+			return fmt.Errorf("feed '%s': %w", f.Name, err)
+		}
+	}
+
+	if f.FeedType == "plugin" && f.Plugin == nil {
+		return fmt.Errorf("feed '%s': feed_type plugin needs a 'plugin' command", f.Name)
+	}
+	if f.Plugin != nil {
+		if f.FeedType != "plugin" {
+			return fmt.Errorf("feed '%s': 'plugin' only applies to plugin feeds", f.Name)
+		}
+		if err := f.Plugin.Validate(); err != nil {
+			return fmt.Errorf("feed '%s': %w", f.Name, err)
+		}
+	}
+
+	if f.Transform != "" {
+		if _, err := transform.Compile(f.Transform); err != nil {
+			return fmt.Errorf("feed '%s': transform: %w", f.Name, err)
+		}
+	}
+
+	if f.Hydrate != nil {
+		if f.FeedType != "json" || f.JSON != nil {
+			return fmt.Errorf("feed '%s': 'hydrate' only applies to json feeds of Hacker News story IDs", f.Name)
+		}
+		if err := f.Hydrate.Validate(); err != nil {
+			return fmt.Errorf("feed '%s': %w", f.Name, err)
+		}
+	}
+
+	if f.Incremental != nil {
+		if err := f.Incremental.Validate(); err != nil {
+			return fmt.Errorf("feed '%s': %w", f.Name, err)
+		}
+		// Stored cursors describe one URL's position
+		if n := len(f.ExpandURLs()); n > 1 {
+			return fmt.Errorf("feed '%s': incremental fetching needs a single URL, but the feed expands to %d", f.Name, n)
+		}
+	}
+
+	return nil
+}
+
+// validateMode checks that a stream feed only uses options that make
+// sense for a long-lived connection
+func (f *Feed) validateMode() error {
+	switch f.Mode {
+	case "", ModePoll:
+		return nil
+	case ModeStream:
+	default:
+		return fmt.Errorf("mode must be poll or stream, got '%s'", f.Mode)
+	}
+
+	if f.FeedType != "json" && f.FeedType != "ndjson" {
+		return fmt.Errorf("stream mode needs feed_type json or ndjson, got '%s'", f.FeedType)
+	}
+	if len(f.Mirrors) > 0 || len(f.Subreddits) > 0 || f.Incremental != nil || f.DependsOn != "" || f.Unwrap != nil {
+		return fmt.Errorf("stream mode cannot be combined with mirrors, subreddits, incremental, depends_on or unwrap")
+	}
+	return nil
+}
+
+// validateRequest checks the request method and body fields
+func (f *Feed) validateRequest() error {
+	switch f.HTTPMethod() {
+	case http.MethodGet, http.MethodPost:
+	default:
+		return fmt.Errorf("method must be GET or POST, got '%s'", f.Method)
+	}
+	if f.Body != "" && len(f.Form) > 0 {
+		return fmt.Errorf("'body' and 'form' cannot both be set")
+	}
+	if (f.Body != "" || len(f.Form) > 0) && f.HTTPMethod() != http.MethodPost {
+		return fmt.Errorf("'body' and 'form' require method POST")
+	}
+	return nil
+}
+
+// Validate performs validation on a backfill config and applies defaults
+func (b *BackfillConfig) Validate() error {
+	hasPage := b.PageParam != ""
+	hasCursor := b.CursorParam != "" || b.CursorField != ""
+
+	if hasPage == hasCursor {
+		return fmt.Errorf("backfill needs either 'page_param' or 'cursor_param' with 'cursor_field'")
+	}
+	if hasCursor && (b.CursorParam == "" || b.CursorField == "") {
+		return fmt.Errorf("backfill 'cursor_param' and 'cursor_field' must be set together")
+	}
+	if b.DelayMs < 0 {
+		return fmt.Errorf("backfill delay_ms must be non-negative, got %d", b.DelayMs)
+	}
+
+	// Apply defaults
+	if hasPage && b.StartPage == 0 {
+		b.StartPage = 1
+	}
+	if b.DelayMs == 0 {
+		b.DelayMs = 1000
+	}
+
+	return nil
+}
+
+// Validate performs validation on an incremental config
+func (i *IncrementalConfig) Validate() error {
+	hasCursor := i.CursorParam != "" || i.CursorField != ""
+
+	if hasCursor == i.LinkHeader {
+		return fmt.Errorf("incremental needs either 'cursor_param' with 'cursor_field' or 'link_header'")
+	}
+	if hasCursor && (i.CursorParam == "" || i.CursorField == "") {
+		return fmt.Errorf("incremental 'cursor_param' and 'cursor_field' must be set together")
+	}
+
+	return nil
+}
+
+// Validate performs validation on an assertions config
+func (a *AssertionsConfig) Validate() error {
+	if a.MinItems < 0 {
+		return fmt.Errorf("assertions min_items must be non-negative, got %d", a.MinItems)
+	}
+	for _, field := range a.RequiredFields {
+		if field == "" || strings.HasPrefix(field, ".") || strings.HasSuffix(field, ".") || strings.Contains(field, "..") {
+			return fmt.Errorf("assertions required field '%s' is not a valid dot path", field)
+		}
+	}
+	if a.MaxAge != "" {
+		if _, err := ParseDuration(a.MaxAge); err != nil {
+			return fmt.Errorf("assertions max_age: %w", err)
+		}
+	}
+	return nil
+}
+
+// Validate performs validation on an unwrap config
+func (u *UnwrapConfig) Validate() error {
+	if !u.StripJSONP && u.JSONStringField == "" {
+		return fmt.Errorf("unwrap needs 'strip_jsonp' or 'json_string_field'")
+	}
+	if field := u.JSONStringField; field != "" && (strings.HasPrefix(field, ".") || strings.HasSuffix(field, ".") || strings.Contains(field, "..")) {
+		return fmt.Errorf("unwrap json_string_field '%s' is not a valid dot path", field)
+	}
+	return nil
+}
+
+// xmlAlternatives splits an XML path at the "|" outside quotes
+func xmlAlternatives(path string) []string {
+	var alternatives []string
+	var quote rune
+	start := 0
+	for i, r := range path {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '|':
+			alternatives = append(alternatives, strings.TrimSpace(path[start:i]))
+			start = i + 1
+		}
+	}
+	return append(alternatives, strings.TrimSpace(path[start:]))
+}
+
+// xmlStepPattern matches one step of an XML path: an optionally prefixed
+// element name or "*", or a final "@attr", with an optional
+// [@attr='value'] or [not(@attr)] filter
+var xmlStepPattern = regexp.MustCompile(`^(@?([\w.-]+:)?[\w.-]+|\*)(\[@([\w.-]+:)?[\w.-]+='[^']*'\]|\[not\(@([\w.-]+:)?[\w.-]+\)\])?$`)
+
+// Validate performs validation on an XML mapping
+func (x *XMLConfig) Validate() error {
+	if x.Item == "" || x.Title == "" || x.URL == "" {
+		return fmt.Errorf("xml mapping needs 'item', 'title' and 'url'")
+	}
+
+	paths := []struct{ field, path string }{
+		{"item", x.Item}, {"title", x.Title}, {"url", x.URL}, {"date", x.Date}, {"tags", x.Tags},
+	}
+	for _, p := range paths {
+		if p.path == "" {
+			continue
+		}
+		for _, alternative := range xmlAlternatives(p.path) {
+			steps := strings.Split(strings.TrimPrefix(alternative, "//"), "/")
+			for i, step := range steps {
+				if !xmlStepPattern.MatchString(step) || (strings.HasPrefix(step, "@") && i != len(steps)-1) {
+					return fmt.Errorf("xml %s path '%s' is not valid", p.field, p.path)
+				}
+				for _, name := range xmlStepPrefixes(step) {
+					if _, ok := x.Namespaces[name]; !ok && name != "xml" {
+						return fmt.Errorf("xml %s path '%s' uses undeclared namespace prefix '%s'", p.field, p.path, name)
+					}
+				}
+			}
+		}
+	}
+	for _, alternative := range xmlAlternatives(x.Item) {
+		if steps := strings.Split(alternative, "/"); strings.HasPrefix(steps[len(steps)-1], "@") {
+			return fmt.Errorf("xml item path '%s' must select elements, not an attribute", x.Item)
+		}
+	}
+
+	return nil
+}
+
+// jsonStepPattern matches one "."-separated step of a JSON path: a key,
+// optionally followed by [N] or [*] indexes, or indexes alone
+var jsonStepPattern = regexp.MustCompile(`^([^.\[\]]+(\[(\d+|\*)\])*|(\[(\d+|\*)\])+)$`)
+
+// Validate performs validation on a JSON mapping
+func (j *JSONConfig) Validate() error {
+	if j.Item == "" || j.Title == "" || j.URL == "" {
+		return fmt.Errorf("json mapping needs 'item', 'title' and 'url'")
+	}
+
+	paths := []struct{ field, path string }{
+		{"item", j.Item}, {"title", j.Title}, {"url", j.URL}, {"date", j.Date}, {"tags", j.Tags},
+	}
+	for _, p := range paths {
+		path := strings.TrimPrefix(strings.TrimPrefix(p.path, "$"), ".")
+		// "$" alone selects the document root, e.g. an array of items
+		if path == "" && p.path != "" && p.field == "item" {
+			continue
+		}
+		if path == "" {
+			if p.path != "" {
+				return fmt.Errorf("json %s path '%s' is not valid", p.field, p.path)
+			}
+			continue
+		}
+		for _, step := range strings.Split(path, ".") {
+			if !jsonStepPattern.MatchString(step) {
+				return fmt.Errorf("json %s path '%s' is not valid", p.field, p.path)
+			}
+		}
+	}
+
 	return nil
 }
+
+// xmlStepPrefixes returns the namespace prefixes used in an XML path step
+func xmlStepPrefixes(step string) []string {
+	var prefixes []string
+	for _, part := range strings.FieldsFunc(step, func(r rune) bool { return strings.ContainsRune("[@=()]", r) }) {
+		if prefix, _, ok := strings.Cut(part, ":"); ok && !strings.HasPrefix(part, "'") {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+// MaxAgeDuration returns the parsed max_age, or 0 if it isn't set
+func (a *AssertionsConfig) MaxAgeDuration() time.Duration {
+	d, _ := ParseDuration(a.MaxAge)
+	return d
+}
+
+// ParseDuration parses a non-negative Go duration, additionally accepting
+// whole days with a "d" suffix (e.g. "7d")
+func ParseDuration(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("invalid duration: %s", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration: %s", s)
+	}
+	return d, nil
+}
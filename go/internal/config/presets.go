@@ -0,0 +1,153 @@
+package config
+
+// Preset is a ready-made feed definition for a well-known source. Notes
+// say what to expect from the source's API beyond what the feed sets.
+type Preset struct {
+	Key         string
+	Description string
+	Notes       string
+	Feed        Feed
+}
+
+// Presets returns the built-in source presets
+func Presets() []Preset {
+	return []Preset{
+		{
+			Key:         "hackernews-top",
+			Description: "Hacker News top stories",
+			Notes:       "The API returns story IDs only; each of the top 30 stories' details takes one more request",
+			Feed: Feed{
+				Name:                "HackerNews Top",
+				URL:                 "https://hacker-news.firebaseio.com/v0/topstories.json",
+				FeedType:            "json",
+				RefreshIntervalSecs: 300,
+				Hydrate:             &HydrateConfig{MaxItems: DefaultHydrateMaxItems},
+			},
+		},
+		{
+			Key:         "lobsters-hottest",
+			Description: "Lobsters hottest stories",
+			Feed: Feed{
+				Name:                "Lobsters",
+				URL:                 "https://lobste.rs/hottest.json",
+				FeedType:            "json",
+				RefreshIntervalSecs: 300,
+			},
+		},
+		{
+			Key:         "github-trending",
+			Description: "Most starred GitHub repositories",
+			Notes:       "Unauthenticated search allows 10 requests a minute",
+			Feed: Feed{
+				Name:                "GitHub Trending",
+				URL:                 "https://api.github.com/search/repositories?q=stars:>1000&sort=stars",
+				FeedType:            "json",
+				RefreshIntervalSecs: 600,
+				Headers:             map[string]string{"Accept": "application/vnd.github.v3+json"},
+			},
+		},
+		{
+			Key:         "reddit-programming",
+			Description: "r/programming hot posts",
+			Notes:       "Reddit throttles requests without a descriptive User-Agent",
+			Feed: Feed{
+				Name:                "Reddit Programming",
+				URL:                 "https://www.reddit.com/r/programming/hot.json",
+				FeedType:            "json",
+				RefreshIntervalSecs: 300,
+				Headers:             map[string]string{"User-Agent": "feedpulse/1.0"},
+			},
+		},
+		{
+			Key:         "hackernews-new",
+			Description: "Hacker News newest stories",
+			Notes:       "The API returns story IDs only; each of the top 30 stories' details takes one more request",
+			Feed: Feed{
+				Name:                "HackerNews New",
+				URL:                 "https://hacker-news.firebaseio.com/v0/newstories.json",
+				FeedType:            "json",
+				RefreshIntervalSecs: 300,
+				Hydrate:             &HydrateConfig{MaxItems: DefaultHydrateMaxItems},
+			},
+		},
+		{
+			Key:         "hackernews-best",
+			Description: "Hacker News best stories",
+			Notes:       "The API returns story IDs only; each of the top 30 stories' details takes one more request",
+			Feed: Feed{
+				Name:                "HackerNews Best",
+				URL:                 "https://hacker-news.firebaseio.com/v0/beststories.json",
+				FeedType:            "json",
+				RefreshIntervalSecs: 900,
+				Hydrate:             &HydrateConfig{MaxItems: DefaultHydrateMaxItems},
+			},
+		},
+		{
+			Key:         "lobsters-newest",
+			Description: "Lobsters newest stories",
+			Feed: Feed{
+				Name:                "Lobsters Newest",
+				URL:                 "https://lobste.rs/newest.json",
+				FeedType:            "json",
+				RefreshIntervalSecs: 300,
+			},
+		},
+		{
+			Key:         "github-trending-go",
+			Description: "Most starred Go repositories",
+			Notes:       "Unauthenticated search allows 10 requests a minute",
+			Feed: Feed{
+				Name:                "GitHub Go",
+				URL:                 "https://api.github.com/search/repositories?q=language:go&sort=stars",
+				FeedType:            "json",
+				RefreshIntervalSecs: 3600,
+				Headers:             map[string]string{"Accept": "application/vnd.github.v3+json"},
+			},
+		},
+		{
+			Key:         "reddit-golang",
+			Description: "r/golang hot posts",
+			Notes:       "Reddit throttles requests without a descriptive User-Agent",
+			Feed: Feed{
+				Name:                "Reddit Golang",
+				URL:                 "https://www.reddit.com/r/golang/hot.json",
+				FeedType:            "json",
+				RefreshIntervalSecs: 600,
+				Headers:             map[string]string{"User-Agent": "feedpulse/1.0"},
+			},
+		},
+		{
+			Key:         "reddit-rust",
+			Description: "r/rust hot posts",
+			Notes:       "Reddit throttles requests without a descriptive User-Agent",
+			Feed: Feed{
+				Name:                "Reddit Rust",
+				URL:                 "https://www.reddit.com/r/rust/hot.json",
+				FeedType:            "json",
+				RefreshIntervalSecs: 600,
+				Headers:             map[string]string{"User-Agent": "feedpulse/1.0"},
+			},
+		},
+		{
+			Key:         "stackoverflow-go",
+			Description: "Newest Stack Overflow questions tagged go",
+			Notes:       "Add &key=<api key> to the URL to raise the daily quota from 300 to 10,000 requests",
+			Feed: Feed{
+				Name:                "Stack Overflow: go",
+				URL:                 "https://api.stackexchange.com/2.3/questions?tagged=go&site=stackoverflow&order=desc&sort=creation&pagesize=50",
+				FeedType:            "json",
+				RefreshIntervalSecs: 900,
+			},
+		},
+	}
+}
+
+// FindPreset returns the preset with key
+func FindPreset(key string) (Preset, bool) {
+	for _, p := range Presets() {
+		if p.Key == key {
+			return p, true
+		}
+	}
+	return Preset{}, false
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// templateVarPattern matches {{name}} placeholders in feed URLs
+var templateVarPattern = regexp.MustCompile(`\{\{([a-z0-9_]+)\}\}`)
+
+// URL template variables substituted at request time. Dates are UTC;
+// last_fetch_* is the previous successful fetch of the feed, or 24 hours
+// before now if it has never succeeded.
+var urlTimeVars = map[string]bool{
+	"today":              true,
+	"yesterday":          true,
+	"now_rfc3339":        true,
+	"now_unix":           true,
+	"last_fetch_rfc3339": true,
+	"last_fetch_unix":    true,
+}
+
+// URLVars returns the values of the time-based URL template variables
+func URLVars(now, lastFetch time.Time) map[string]string {
+	now = now.UTC()
+	lastFetch = lastFetch.UTC()
+
+	return map[string]string{
+		"today":              now.Format("2006-01-02"),
+		"yesterday":          now.AddDate(0, 0, -1).Format("2006-01-02"),
+		"now_rfc3339":        now.Format(time.RFC3339),
+		"now_unix":           strconv.FormatInt(now.Unix(), 10),
+		"last_fetch_rfc3339": lastFetch.Format(time.RFC3339),
+		"last_fetch_unix":    strconv.FormatInt(lastFetch.Unix(), 10),
+	}
+}
+
+// RenderURL substitutes vars into raw's {{name}} placeholders, query-escaping
+// each value. Placeholders without a value are left as they are.
+func RenderURL(raw string, vars map[string]string) string {
+	return templateVarPattern.ReplaceAllStringFunc(raw, func(match string) string {
+		name := match[2 : len(match)-2]
+		if value, ok := vars[name]; ok {
+			return url.QueryEscape(value)
+		}
+		return match
+	})
+}
+
+// HasTimeVars reports whether raw uses any time-based template variable
+func HasTimeVars(raw string) bool {
+	for _, m := range templateVarPattern.FindAllStringSubmatch(raw, -1) {
+		if urlTimeVars[m[1]] {
+			return true
+		}
+	}
+	return false
+}
+
+// validateTemplateVars rejects placeholders that nothing will substitute
+func validateTemplateVars(raw string) error {
+	for _, m := range templateVarPattern.FindAllStringSubmatch(raw, -1) {
+		if !urlTimeVars[m[1]] && m[0] != SubredditPlaceholder {
+			return fmt.Errorf("unknown URL template variable '%s'", m[0])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRenderURL(t *testing.T) {
+	now := time.Date(2024, 3, 10, 15, 4, 5, 0, time.UTC)
+	last := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	vars := URLVars(now, last)
+
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"https://api.example.com/items?since={{last_fetch_rfc3339}}", "https://api.example.com/items?since=2024-03-10T12%3A00%3A00Z"},
+		{"https://api.example.com/{{today}}.json", "https://api.example.com/2024-03-10.json"},
+		{"https://api.example.com/{{yesterday}}.json", "https://api.example.com/2024-03-09.json"},
+		{"https://api.example.com/?from={{last_fetch_unix}}&to={{now_unix}}", "https://api.example.com/?from=1710072000&to=1710083045"},
+		{"https://api.example.com/r/{{subreddit}}", "https://api.example.com/r/{{subreddit}}"},
+		{"https://api.example.com/plain", "https://api.example.com/plain"},
+	}
+
+	for _, tt := range tests {
+		if got := RenderURL(tt.raw, vars); got != tt.want {
+			t.Errorf("RenderURL(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestURLVars_ConvertsToUTC(t *testing.T) {
+	loc := time.FixedZone("UTC+10", 10*60*60)
+	now := time.Date(2024, 3, 11, 5, 0, 0, 0, loc) // 2024-03-10 19:00 UTC
+
+	if got := URLVars(now, now)["today"]; got != "2024-03-10" {
+		t.Errorf("expected UTC date 2024-03-10, got %s", got)
+	}
+}
+
+func TestValidate_URLTemplateVars(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{"time var in query", "https://api.example.com/items?since={{last_fetch_rfc3339}}", false},
+		{"time var in path", "https://api.example.com/{{today}}/items", false},
+		{"unknown var", "https://api.example.com/items?since={{last_run}}", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed := Feed{Name: "Test", URL: tt.url, FeedType: "json"}
+			err := feed.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if !HasTimeVars("https://api.example.com/?d={{today}}") || HasTimeVars("https://api.example.com/r/{{subreddit}}") {
+		t.Error("HasTimeVars misclassified a template")
+	}
+}
@@ -90,7 +90,7 @@ func ValidateFeedType(feedType string) error {
 		return errors.NewValidationError("feed_type", feedType, "required", "feed_type cannot be empty")
 	}
 
-	validTypes := []string{"json", "rss", "atom"}
+	validTypes := []string{"json", "ndjson", "xml", "rss", "atom", "plugin"}
 	for _, validType := range validTypes {
 		if feedType == validType {
 			return nil
@@ -174,6 +174,69 @@ func ValidateDatabasePath(path string) error {
 	return nil
 }
 
+// ValidateUniquenessScope validates that an item uniqueness scope is supported.
+func ValidateUniquenessScope(scope string) error {
+	validScopes := []string{"global", "source", "run"}
+	for _, validScope := range validScopes {
+		if scope == validScope {
+			return nil
+		}
+	}
+
+	return errors.NewValidationError("uniqueness_scope", scope, "format",
+		fmt.Sprintf("uniqueness_scope must be one of: %s, got: %s", strings.Join(validScopes, ", "), scope))
+}
+
+// ValidateDedup validates that a deduplication mode is supported.
+func ValidateDedup(dedup string) error {
+	validModes := []string{"off", "normalized", "cross_source"}
+	for _, validMode := range validModes {
+		if dedup == validMode {
+			return nil
+		}
+	}
+
+	return errors.NewValidationError("dedup", dedup, "format",
+		fmt.Sprintf("dedup must be one of: %s, got: %s", strings.Join(validModes, ", "), dedup))
+}
+
+// TableColumns lists the columns each configurable table can show, by name
+var TableColumns = map[string][]string{
+	"report": {"source", "items", "errors", "error_rate", "last_success", "fetches"},
+	"items":  {"stored", "source", "title", "tags", "url", "id", "published"},
+}
+
+// DefaultColumns are the columns a table shows unless configured otherwise
+var DefaultColumns = map[string][]string{
+	"report": {"source", "items", "errors", "error_rate", "last_success"},
+	"items":  {"stored", "source", "title", "tags", "url"},
+}
+
+// ValidateColumns validates a column selection for one of TableColumns.
+func ValidateColumns(table string, columns []string) error {
+	known, ok := TableColumns[table]
+	if !ok {
+		return errors.NewValidationError("columns", table, "format",
+			fmt.Sprintf("columns can be set for: report, items, got: %s", table))
+	}
+	if len(columns) == 0 {
+		return errors.NewValidationError("columns", table, "required",
+			fmt.Sprintf("columns for %s cannot be empty", table))
+	}
+
+	for _, column := range columns {
+		valid := false
+		for _, name := range known {
+			valid = valid || column == name
+		}
+		if !valid {
+			return errors.NewValidationError("columns", column, "format",
+				fmt.Sprintf("unknown %s column '%s' (must be one of: %s)", table, column, strings.Join(known, ", ")))
+		}
+	}
+	return nil
+}
+
 // ValidateFeedConfig validates all aspects of a feed configuration.
 func ValidateFeedConfig(feed *Feed) error {
 	if err := ValidateFeedName(feed.Name); err != nil {
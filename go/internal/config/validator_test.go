@@ -152,8 +152,10 @@ func TestValidateFeedType(t *testing.T) {
 		{"valid json", "json", false, ""},
 		{"valid rss", "rss", false, ""},
 		{"valid atom", "atom", false, ""},
+		{"valid ndjson", "ndjson", false, ""},
 		{"empty", "", true, "cannot be empty"},
-		{"invalid xml", "xml", true, "must be one of"},
+		{"valid xml", "xml", false, ""},
+		{"invalid yaml", "yaml", true, "must be one of"},
 		{"invalid html", "html", true, "must be one of"},
 		{"case sensitive", "JSON", true, "must be one of"},
 	}
@@ -508,3 +510,76 @@ func TestValidateURL_EdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateUniquenessScope(t *testing.T) {
+	tests := []struct {
+		name    string
+		scope   string
+		wantErr bool
+	}{
+		{"global", "global", false},
+		{"source", "source", false},
+		{"run", "run", false},
+		{"empty", "", true},
+		{"unknown", "feed", true},
+		{"wrong case", "Global", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateUniquenessScope(tt.scope)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateUniquenessScope(%q) error = %v, wantErr %v", tt.scope, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDedup(t *testing.T) {
+	tests := []struct {
+		name    string
+		dedup   string
+		wantErr bool
+	}{
+		{"off", "off", false},
+		{"normalized", "normalized", false},
+		{"cross source", "cross_source", false},
+		{"empty", "", true},
+		{"unknown", "fuzzy", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDedup(tt.dedup)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateDedup(%q) error = %v, wantErr %v", tt.dedup, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		table   string
+		columns []string
+		wantErr bool
+	}{
+		{"report subset", "report", []string{"source", "items"}, false},
+		{"items extra", "items", []string{"id", "title", "published"}, false},
+		{"defaults", "report", DefaultColumns["report"], false},
+		{"unknown table", "sources", []string{"source"}, true},
+		{"empty", "items", nil, true},
+		{"unknown column", "report", []string{"source", "p99"}, true},
+		{"other table's column", "report", []string{"title"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateColumns(tt.table, tt.columns)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateColumns(%q, %v) error = %v, wantErr %v", tt.table, tt.columns, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,404 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Marshal encodes cfg as YAML, leaving out every unset field so the
+// result reads like a hand-written config
+func Marshal(cfg *Config) ([]byte, error) {
+	var doc yaml.Node
+	if err := doc.Encode(cfg); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	pruneUnset(&doc)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteConfig validates cfg and writes it to path. It refuses to replace
+// an existing file unless overwrite is set.
+func WriteConfig(path string, cfg *Config, overwrite bool) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	data, err := Marshal(cfg)
+	if err != nil {
+		return err
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !overwrite {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(path, flags, 0644)
+	if os.IsExist(err) {
+		return fmt.Errorf("config file already exists: %s", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return f.Close()
+}
+
+// AppendFeeds adds feeds to the end of the config file at path, keeping
+// its comments. The file is only rewritten if the result is a valid config
+// and no feed name is already taken.
+func AppendFeeds(path string, feeds []Feed) error {
+	return editFeeds(path, func(list *yaml.Node, current *Config) error {
+		names := make(map[string]bool, len(current.Feeds))
+		for _, feed := range current.Feeds {
+			names[feed.Name] = true
+		}
+
+		for _, feed := range feeds {
+			if names[feed.Name] {
+				return fmt.Errorf("a feed named '%s' already exists", feed.Name)
+			}
+			names[feed.Name] = true
+
+			node, err := encodeFeed(feed)
+			if err != nil {
+				return err
+			}
+			list.Content = append(list.Content, node)
+		}
+		return nil
+	})
+}
+
+// RemoveFeed removes the named feed from the config file at path, keeping
+// the comments of everything else. The file is only rewritten if the
+// result is a valid config, so a feed others depend on can't be removed.
+func RemoveFeed(path, name string) error {
+	return editFeeds(path, func(list *yaml.Node, current *Config) error {
+		i := feedIndex(current, name)
+		if i < 0 {
+			return fmt.Errorf("no feed named '%s'", name)
+		}
+		list.Content = append(list.Content[:i], list.Content[i+1:]...)
+		return nil
+	})
+}
+
+// UpdateFeed changes the named feed in the config file at path with
+// update. Only the fields update changes are rewritten; every other key,
+// including explicit zero values and merge keys, is kept as written with
+// its comments. The file is only rewritten if the result is a valid
+// config.
+func UpdateFeed(path, name string, update func(*Feed)) error {
+	return editFeeds(path, func(list *yaml.Node, current *Config) error {
+		i := feedIndex(current, name)
+		if i < 0 {
+			return fmt.Errorf("no feed named '%s'", name)
+		}
+		feed := current.Feeds[i]
+		before, err := encodeNode(feed)
+		if err != nil {
+			return err
+		}
+		update(&feed)
+		after, err := encodeNode(feed)
+		if err != nil {
+			return err
+		}
+		if list.Content[i].Kind != yaml.MappingNode {
+			return fmt.Errorf("invalid config: feed is not a mapping")
+		}
+		mergeChanges(list.Content[i], before, after)
+		return nil
+	})
+}
+
+// feedIndex returns the position of the named feed in cfg, or -1
+func feedIndex(cfg *Config, name string) int {
+	for i, feed := range cfg.Feeds {
+		if feed.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// encodeFeed encodes feed as a mapping of its set fields
+func encodeFeed(feed Feed) (*yaml.Node, error) {
+	node, err := encodeNode(feed)
+	if err != nil {
+		return nil, err
+	}
+	pruneUnset(node)
+	return node, nil
+}
+
+// encodeNode encodes feed as a mapping of all its fields
+func encodeNode(feed Feed) (*yaml.Node, error) {
+	var node yaml.Node
+	if err := node.Encode(feed); err != nil {
+		return nil, fmt.Errorf("failed to encode feed: %w", err)
+	}
+	return &node, nil
+}
+
+// mergeChanges applies to the mapping old, as written in the file, the
+// fields that differ between before and after, two full encodings of the
+// same value. Untouched keys are left alone, so merge keys and explicit
+// zero values survive. A changed nested mapping is merged the same way;
+// a changed value keeps the comment of the one it replaces. A key is only
+// dropped when the edit clears a section the mapping spells out itself.
+func mergeChanges(old, before, after *yaml.Node) {
+	for i := 0; i+1 < len(after.Content); i += 2 {
+		key, value := after.Content[i], after.Content[i+1]
+		previous := mappingValue(before, key.Value)
+		if previous != nil && sameValue(previous, value) {
+			continue
+		}
+
+		at := mappingIndex(old, key.Value)
+		if at < 0 {
+			if previous == nil || !unsetNode(previous) || !unsetNode(value) {
+				pruneUnset(value)
+				old.Content = append(old.Content, key, value)
+			}
+			continue
+		}
+		current := old.Content[at+1]
+		switch {
+		case current.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode &&
+			previous != nil && previous.Kind == yaml.MappingNode:
+			mergeChanges(current, previous, value)
+		case value.Tag == "!!null" && !hasMergeKey(old):
+			old.Content = append(old.Content[:at], old.Content[at+2:]...)
+		default:
+			pruneUnset(value)
+			value.LineComment = current.LineComment
+			old.Content[at+1] = value
+		}
+	}
+}
+
+// mappingIndex returns the position of key's key node in the mapping n,
+// or -1 if n doesn't spell it out
+func mappingIndex(n *yaml.Node, key string) int {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return -1
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// mappingValue returns the value of key in the mapping n, or nil
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if i := mappingIndex(n, key); i >= 0 {
+		return n.Content[i+1]
+	}
+	return nil
+}
+
+// hasMergeKey reports whether the mapping n inherits entries through a
+// "<<" merge key
+func hasMergeKey(n *yaml.Node) bool {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Tag == "!!merge" || n.Content[i].Value == "<<" {
+			return true
+		}
+	}
+	return false
+}
+
+// sameValue reports whether two nodes decode to the same value
+func sameValue(a, b *yaml.Node) bool {
+	var av, bv interface{}
+	if a.Decode(&av) != nil || b.Decode(&bv) != nil {
+		return false
+	}
+	return reflect.DeepEqual(av, bv)
+}
+
+// EditError is an edit of the config file that was refused: a feed name
+// is taken or unknown, or the result isn't a valid config. The file is
+// left as it was.
+type EditError struct {
+	Err error
+}
+
+func (e *EditError) Error() string { return e.Err.Error() }
+
+func (e *EditError) Unwrap() error { return e.Err }
+
+// editFeeds rewrites the config file at path after edit changes its feeds
+// list. edit is given the list node and the file as it was decoded. The
+// file is only rewritten if the result is a valid config.
+func editFeeds(path string, edit func(list *yaml.Node, current *Config) error) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	// The existing file may not validate yet (e.g. no feeds), so it is only
+	// decoded here; the result is validated before it is written
+	var current Config
+	if err := yaml.Unmarshal(data, &current); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("invalid config: top level is not a mapping")
+	}
+	root := doc.Content[0]
+
+	var list *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "feeds" {
+			list = root.Content[i+1]
+		}
+	}
+	if list == nil {
+		list = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "feeds"}, list)
+	}
+	if list.Kind != yaml.SequenceNode || len(list.Content) != len(current.Feeds) {
+		return fmt.Errorf("invalid config: feeds is not a list")
+	}
+	list.Style = 0
+
+	if err := edit(list, &current); err != nil {
+		return &EditError{Err: err}
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	if _, err := ParseConfig(buf.Bytes()); err != nil {
+		return &EditError{Err: err}
+	}
+
+	if err := replaceFile(path, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// replaceFile atomically replaces the file at path with data, keeping its
+// permissions: data is written and synced to a temporary file beside it,
+// which is then renamed over it. A crash leaves either the old or the new
+// file, and readers never see one half-written. A symlink is followed, so
+// the file it points to is replaced.
+func replaceFile(path string, data []byte) error {
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(target)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(target)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return err
+	}
+
+	// Sync the directory so the rename itself survives a crash; not every
+	// platform can, and the file is already replaced
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// pruneUnset drops mapping entries whose values are null, zero, false or
+// empty, recursively
+func pruneUnset(n *yaml.Node) {
+	for _, child := range n.Content {
+		pruneUnset(child)
+	}
+	if n.Kind != yaml.MappingNode {
+		return
+	}
+
+	kept := n.Content[:0]
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if !unsetNode(n.Content[i+1]) {
+			kept = append(kept, n.Content[i], n.Content[i+1])
+		}
+	}
+	n.Content = kept
+}
+
+// unsetNode reports whether n holds a zero value
+func unsetNode(n *yaml.Node) bool {
+	switch n.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		return len(n.Content) == 0
+	case yaml.ScalarNode:
+		switch n.Tag {
+		case "!!null":
+			return true
+		case "!!bool":
+			return n.Value == "false"
+		case "!!int", "!!float":
+			return n.Value == "0"
+		case "!!str":
+			return n.Value == ""
+		}
+	}
+	return false
+}
@@ -0,0 +1,423 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMarshal_OmitsUnsetFields(t *testing.T) {
+	cfg := &Config{
+		Settings: Settings{MaxConcurrency: 5, DefaultTimeoutSecs: 10, DatabasePath: "feeds.db"},
+		Feeds: []Feed{{
+			Name:     "GitHub",
+			URL:      "https://api.github.com/search/repositories",
+			FeedType: "json",
+			Headers:  map[string]string{"Accept": "application/vnd.github.v3+json"},
+		}},
+	}
+
+	data, err := Marshal(cfg)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	out := string(data)
+	for _, unset := range []string{"retry_max", "mode", "login", "cookie_jar", "mirrors", "null", `""`} {
+		if strings.Contains(out, unset) {
+			t.Errorf("expected %q to be omitted, got:\n%s", unset, out)
+		}
+	}
+	if !strings.Contains(out, "  database_path: feeds.db") {
+		t.Errorf("expected two-space indented settings, got:\n%s", out)
+	}
+}
+
+func TestWriteConfig_RoundTrips(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	cfg := &Config{Settings: Settings{MaxConcurrency: 7, DefaultTimeoutSecs: 10, RetryMax: 3, RetryBaseDelayMs: 500, DatabasePath: "feeds.db"}}
+	for _, p := range Presets() {
+		cfg.Feeds = append(cfg.Feeds, p.Feed)
+	}
+
+	if err := WriteConfig(path, cfg, false); err != nil {
+		t.Fatalf("WriteConfig failed: %v", err)
+	}
+
+	loaded, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("written config does not load: %v", err)
+	}
+	if loaded.Settings.MaxConcurrency != 7 || loaded.Settings.DatabasePath != "feeds.db" {
+		t.Errorf("settings not preserved: %+v", loaded.Settings)
+	}
+	if !reflect.DeepEqual(loaded.Feeds, cfg.Feeds) {
+		t.Errorf("feeds not preserved:\n got  %+v\n want %+v", loaded.Feeds, cfg.Feeds)
+	}
+}
+
+func TestWriteConfig_Overwrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte("existing"), 0644)
+
+	cfg := &Config{
+		Settings: Settings{MaxConcurrency: 5, DefaultTimeoutSecs: 10},
+		Feeds:    []Feed{Presets()[0].Feed},
+	}
+
+	if err := WriteConfig(path, cfg, false); err == nil {
+		t.Error("expected an error for an existing file")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "existing" {
+		t.Error("existing file must be left alone")
+	}
+
+	if err := WriteConfig(path, cfg, true); err != nil {
+		t.Fatalf("WriteConfig with overwrite failed: %v", err)
+	}
+	if _, err := LoadConfig(path); err != nil {
+		t.Errorf("overwritten config does not load: %v", err)
+	}
+}
+
+func TestWriteConfig_RejectsInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	if err := WriteConfig(path, &Config{Settings: Settings{MaxConcurrency: 5, DefaultTimeoutSecs: 10}}, false); err == nil {
+		t.Error("expected a config without feeds to be rejected")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("an invalid config must not be written")
+	}
+}
+
+func TestPresets_Valid(t *testing.T) {
+	keys := make(map[string]bool)
+	for _, p := range Presets() {
+		if keys[p.Key] {
+			t.Errorf("duplicate preset key %s", p.Key)
+		}
+		keys[p.Key] = true
+
+		if err := p.Feed.Validate(); err != nil {
+			t.Errorf("preset %s: %v", p.Key, err)
+		}
+		if found, ok := FindPreset(p.Key); !ok || found.Feed.Name != p.Feed.Name {
+			t.Errorf("FindPreset(%s) did not find it", p.Key)
+		}
+	}
+}
+
+func TestAppendFeeds_KeepsComments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	original := `# my feeds
+settings:
+  max_concurrency: 5
+  default_timeout_secs: 10
+  database_path: feeds.db
+
+feeds:
+  # the classic
+  - name: HackerNews
+    url: https://hacker-news.firebaseio.com/v0/topstories.json
+    feed_type: json
+`
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	preset, ok := FindPreset("reddit-golang")
+	if !ok {
+		t.Fatal("reddit-golang preset missing")
+	}
+	if err := AppendFeeds(path, []Feed{preset.Feed}); err != nil {
+		t.Fatalf("AppendFeeds failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, comment := range []string{"# my feeds", "# the classic"} {
+		if !strings.Contains(string(data), comment) {
+			t.Errorf("expected %q to be kept, got:\n%s", comment, data)
+		}
+	}
+
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		t.Fatalf("appended config does not parse: %v", err)
+	}
+	if len(cfg.Feeds) != 2 || cfg.Feeds[0].Name != "HackerNews" {
+		t.Fatalf("expected the original feed followed by the preset, got %+v", cfg.Feeds)
+	}
+	if !reflect.DeepEqual(cfg.Feeds[1].Headers, preset.Feed.Headers) {
+		t.Errorf("expected preset headers, got %v", cfg.Feeds[1].Headers)
+	}
+}
+
+func TestAppendFeeds_RejectsDuplicateName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	preset, _ := FindPreset("hackernews-top")
+	cfg := &Config{Settings: Settings{MaxConcurrency: 5, DefaultTimeoutSecs: 10, DatabasePath: "feeds.db"}, Feeds: []Feed{preset.Feed}}
+	if err := WriteConfig(path, cfg, false); err != nil {
+		t.Fatalf("WriteConfig failed: %v", err)
+	}
+	before, _ := os.ReadFile(path)
+
+	err := AppendFeeds(path, []Feed{preset.Feed})
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected a duplicate name error, got %v", err)
+	}
+	var editErr *EditError
+	if !errors.As(err, &editErr) {
+		t.Errorf("expected an EditError, got %T", err)
+	}
+
+	after, _ := os.ReadFile(path)
+	if string(after) != string(before) {
+		t.Error("expected the config file to be left unchanged")
+	}
+}
+
+// editableConfig is a config file with comments for the feed editing
+// tests
+const editableConfig = `# my feeds
+settings:
+  max_concurrency: 5
+  default_timeout_secs: 10
+  database_path: feeds.db
+
+feeds:
+  # the classic
+  - name: HackerNews
+    url: https://hacker-news.firebaseio.com/v0/topstories.json # top stories
+    feed_type: json
+    refresh_interval_secs: 300 # five minutes
+  # weekly
+  - name: Lobsters
+    url: https://lobste.rs/hottest.json
+    feed_type: json
+    depends_on: HackerNews
+`
+
+func TestUpdateFeed_KeepsComments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(editableConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := UpdateFeed(path, "HackerNews", func(feed *Feed) {
+		feed.RefreshIntervalSecs = 600
+		feed.Group = "news"
+	})
+	if err != nil {
+		t.Fatalf("UpdateFeed failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, comment := range []string{"# my feeds", "# the classic", "# top stories", "# five minutes", "# weekly"} {
+		if !strings.Contains(string(data), comment) {
+			t.Errorf("expected %q to be kept, got:\n%s", comment, data)
+		}
+	}
+
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		t.Fatalf("updated config does not parse: %v", err)
+	}
+	hn := cfg.Feeds[0]
+	if hn.RefreshIntervalSecs != 600 || hn.Group != "news" || hn.FeedType != "json" {
+		t.Errorf("expected the interval and group to change, got %+v", hn)
+	}
+	if cfg.Feeds[1].Name != "Lobsters" || cfg.Feeds[1].DependsOn != "HackerNews" {
+		t.Errorf("expected Lobsters to be unchanged, got %+v", cfg.Feeds[1])
+	}
+}
+
+func TestUpdateFeed_KeepsExplicitAndMergedKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	config := `settings:
+  database_path: feeds.db
+
+defaults: &defaults
+  feed_type: json
+  refresh_interval_secs: 300
+
+feeds:
+  - name: HackerNews
+    <<: *defaults
+    url: https://hacker-news.firebaseio.com/v0/topstories.json
+    cookie_jar: false # no cookies
+    group: ""
+    auth:
+      token_env: HN_TOKEN
+      type: bearer
+`
+	if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := UpdateFeed(path, "HackerNews", func(feed *Feed) {
+		feed.URL = "https://hacker-news.firebaseio.com/v0/beststories.json"
+		feed.Auth.TokenEnv = "HN_KEY"
+	})
+	if err != nil {
+		t.Fatalf("UpdateFeed failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, kept := range []string{"<<: *defaults", "cookie_jar: false # no cookies", `group: ""`, "type: bearer", "token_env: HN_KEY"} {
+		if !strings.Contains(string(data), kept) {
+			t.Errorf("expected %q in the file, got:\n%s", kept, data)
+		}
+	}
+	if strings.Contains(string(data), "refresh_interval_secs: 300\n    url") || strings.Count(string(data), "feed_type") != 1 {
+		t.Errorf("expected the merged fields not to be written out, got:\n%s", data)
+	}
+
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		t.Fatalf("updated config does not parse: %v", err)
+	}
+	hn := cfg.Feeds[0]
+	if hn.FeedType != "json" || hn.RefreshIntervalSecs != 300 || !strings.HasSuffix(hn.URL, "beststories.json") {
+		t.Errorf("expected the merged fields and the new URL, got %+v", hn)
+	}
+}
+
+func TestUpdateFeed_OverridesMergedValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	config := `settings:
+  database_path: feeds.db
+
+defaults: &defaults
+  feed_type: json
+  group: news
+
+feeds:
+  - name: HackerNews
+    <<: *defaults
+    url: https://hacker-news.firebaseio.com/v0/topstories.json
+`
+	if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := UpdateFeed(path, "HackerNews", func(feed *Feed) { feed.Group = "" }); err != nil {
+		t.Fatalf("UpdateFeed failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		t.Fatalf("updated config does not parse: %v", err)
+	}
+	if cfg.Feeds[0].Group != "" {
+		t.Errorf("expected the merged group to be cleared, got:\n%s", data)
+	}
+}
+
+func TestUpdateFeed_RejectsInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(editableConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := UpdateFeed(path, "HackerNews", func(feed *Feed) { feed.FeedType = "bogus" })
+	if err == nil || !strings.Contains(err.Error(), "feed_type") {
+		t.Fatalf("expected a feed_type error, got %v", err)
+	}
+	if err := UpdateFeed(path, "Nope", func(*Feed) {}); err == nil || !strings.Contains(err.Error(), "no feed named") {
+		t.Fatalf("expected an unknown feed error, got %v", err)
+	}
+
+	after, _ := os.ReadFile(path)
+	if string(after) != editableConfig {
+		t.Error("expected the config file to be left unchanged")
+	}
+}
+
+func TestRemoveFeed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(editableConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := RemoveFeed(path, "HackerNews")
+	if err == nil || !strings.Contains(err.Error(), "depends_on") {
+		t.Fatalf("expected removing a dependency to fail, got %v", err)
+	}
+	if after, _ := os.ReadFile(path); string(after) != editableConfig {
+		t.Fatal("expected the config file to be left unchanged")
+	}
+
+	if err := RemoveFeed(path, "Lobsters"); err != nil {
+		t.Fatalf("RemoveFeed failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "# weekly") || !strings.Contains(string(data), "# the classic") {
+		t.Errorf("expected only the removed feed's comment to go, got:\n%s", data)
+	}
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		t.Fatalf("config does not parse: %v", err)
+	}
+	if len(cfg.Feeds) != 1 || cfg.Feeds[0].Name != "HackerNews" {
+		t.Errorf("expected only HackerNews to be left, got %+v", cfg.Feeds)
+	}
+}
+
+func TestRemoveFeed_ReplacesFileAtomically(t *testing.T) {
+	dir := t.TempDir()
+	real := filepath.Join(dir, "feeds.yaml")
+	if err := os.WriteFile(real, []byte(editableConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "config.yaml")
+	if err := os.Symlink(real, link); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	if err := RemoveFeed(link, "Lobsters"); err != nil {
+		t.Fatalf("RemoveFeed failed: %v", err)
+	}
+
+	if fi, err := os.Lstat(link); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("expected the symlink to be kept, got %v (%v)", fi, err)
+	}
+	fi, err := os.Stat(real)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("expected permissions 0600 to be kept, got %v", fi.Mode().Perm())
+	}
+	data, _ := os.ReadFile(real)
+	if strings.Contains(string(data), "Lobsters") {
+		t.Errorf("expected the feed to be removed, got:\n%s", data)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("expected no temporary files to be left, got %v", entries)
+	}
+}
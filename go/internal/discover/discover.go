@@ -0,0 +1,192 @@
+// Package discover finds the feeds a website advertises with
+// <link rel="alternate"> tags, so they can be added without looking
+// through the page source.
+package discover
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"feedpulse/internal/config"
+)
+
+// Formats a discovered feed can have
+const (
+	FormatRSS      = "rss"
+	FormatAtom     = "atom"
+	FormatJSONFeed = "jsonfeed"
+)
+
+// maxPage bounds how much of a page is read looking for links
+const maxPage = 2 * 1024 * 1024
+
+// formats maps the link types feeds are advertised with to their format
+var formats = map[string]string{
+	"application/rss+xml":   FormatRSS,
+	"application/rdf+xml":   FormatRSS,
+	"application/atom+xml":  FormatAtom,
+	"application/feed+json": FormatJSONFeed,
+	"application/json":      FormatJSONFeed,
+}
+
+// Candidate is a feed a page links to
+type Candidate struct {
+	URL    string
+	Title  string
+	Format string
+}
+
+var (
+	tagPattern  = regexp.MustCompile(`(?is)<(link|base)\b([^>]*)>`)
+	attrPattern = regexp.MustCompile(`(?s)([a-zA-Z_:-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+)
+
+// Find fetches pageURL and returns the feeds it advertises, in page
+// order. If pageURL turns out to be a feed itself, it is the only
+// candidate.
+func Find(ctx context.Context, client *http.Client, pageURL string) ([]Candidate, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "feedpulse/1.0")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s answered %s", pageURL, resp.Status)
+	}
+
+	page, err := io.ReadAll(io.LimitReader(resp.Body, maxPage))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read page: %w", err)
+	}
+
+	// Redirects move the base relative links resolve against
+	base := resp.Request.URL
+	if format := feedFormat(resp.Header.Get("Content-Type"), page); format != "" {
+		return []Candidate{{URL: base.String(), Format: format}}, nil
+	}
+	return Links(page, base), nil
+}
+
+// feedFormat is the format of a response that is a feed rather than a
+// page, "" for pages
+func feedFormat(contentType string, body []byte) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if format, ok := formats[mediaType]; ok && mediaType != "application/json" {
+		return format
+	}
+
+	// Many feeds are served as plain XML or JSON; look at the document
+	head := bytes.TrimSpace(body)
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	switch {
+	case strings.Contains(mediaType, "xml"):
+		if bytes.Contains(head, []byte("<rss")) || bytes.Contains(head, []byte("<rdf:RDF")) {
+			return FormatRSS
+		}
+		if bytes.Contains(head, []byte("<feed")) {
+			return FormatAtom
+		}
+	case strings.Contains(mediaType, "json"):
+		if bytes.Contains(head, []byte("jsonfeed.org/version")) {
+			return FormatJSONFeed
+		}
+	}
+	return ""
+}
+
+// Links returns the feeds page advertises with <link rel="alternate">
+// tags, with their URLs resolved against base (or the page's <base>)
+func Links(page []byte, base *url.URL) []Candidate {
+	var candidates []Candidate
+	seen := make(map[string]bool)
+	for _, tag := range tagPattern.FindAllSubmatch(page, -1) {
+		attrs := make(map[string]string)
+		for _, attr := range attrPattern.FindAllSubmatch(tag[2], -1) {
+			value := string(attr[2]) + string(attr[3]) + string(attr[4])
+			attrs[strings.ToLower(string(attr[1]))] = html.UnescapeString(value)
+		}
+
+		href, ok := attrs["href"]
+		if !ok {
+			continue
+		}
+		ref, err := url.Parse(strings.TrimSpace(href))
+		if err != nil {
+			continue
+		}
+		if strings.EqualFold(string(tag[1]), "base") {
+			base = base.ResolveReference(ref)
+			continue
+		}
+
+		format, ok := formats[strings.ToLower(strings.TrimSpace(attrs["type"]))]
+		if !ok || !hasToken(attrs["rel"], "alternate") {
+			continue
+		}
+		u := base.ResolveReference(ref)
+		if u.Scheme != "http" && u.Scheme != "https" || seen[u.String()] {
+			continue
+		}
+		seen[u.String()] = true
+		candidates = append(candidates, Candidate{URL: u.String(), Title: strings.TrimSpace(attrs["title"]), Format: format})
+	}
+	return candidates
+}
+
+// hasToken reports whether the space-separated list has token
+func hasToken(list, token string) bool {
+	for _, t := range strings.Fields(list) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// Feed returns the feed entry that fetches c as name. RSS has its own
+// feed type; Atom and JSON Feed documents are read through xml and json
+// mappings.
+func (c Candidate) Feed(name string) config.Feed {
+	feed := config.Feed{Name: name, URL: c.URL}
+	switch c.Format {
+	case FormatRSS:
+		feed.FeedType = "rss"
+	case FormatAtom:
+		feed.FeedType = "xml"
+		feed.XML = &config.XMLConfig{
+			Namespaces: map[string]string{"atom": "http://www.w3.org/2005/Atom"},
+			Item:       "atom:feed/atom:entry",
+			Title:      "atom:title",
+			URL:        "atom:link[@rel='alternate']/@href | atom:link[not(@rel)]/@href",
+			Date:       "atom:updated",
+			Tags:       "atom:category/@term",
+		}
+	case FormatJSONFeed:
+		feed.FeedType = "json"
+		feed.JSON = &config.JSONConfig{
+			Item:  "items",
+			Title: "title",
+			URL:   "url",
+			Date:  "date_published",
+			Tags:  "tags",
+		}
+	}
+	return feed
+}
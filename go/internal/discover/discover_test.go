@@ -0,0 +1,101 @@
+package discover
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestLinks(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/post")
+	page := []byte(`<html><head>
+<link rel="stylesheet" href="/style.css">
+<LINK REL="alternate" TYPE="application/rss+xml" TITLE="Posts &amp; Notes" HREF="/feed.xml">
+<link rel='alternate home' type='application/atom+xml' href='atom.xml?a=1&amp;b=2'/>
+<link rel=alternate type=application/feed+json href=https://feeds.example.net/feed.json>
+<link rel="alternate" type="application/rss+xml" href="/feed.xml">
+<link rel="alternate" hreflang="de" href="/de/">
+<link rel="alternate" type="application/rss+xml" href="javascript:void(0)">
+</head></html>`)
+
+	got := Links(page, base)
+	want := []Candidate{
+		{URL: "https://example.com/feed.xml", Title: "Posts & Notes", Format: FormatRSS},
+		{URL: "https://example.com/blog/atom.xml?a=1&b=2", Format: FormatAtom},
+		{URL: "https://feeds.example.net/feed.json", Format: FormatJSONFeed},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d candidates, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("candidate %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// A <base> moves what relative links resolve against
+	got = Links([]byte(`<base href="https://cdn.example.com/site/"><link rel="alternate" type="application/atom+xml" href="atom.xml">`), base)
+	if len(got) != 1 || got[0].URL != "https://cdn.example.com/site/atom.xml" {
+		t.Errorf("expected the link resolved against <base>, got %+v", got)
+	}
+}
+
+func TestFind(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/blog/", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/blog/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<link rel="alternate" type="application/rss+xml" href="rss">`))
+	})
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(`<?xml version="1.0"?><feed xmlns="http://www.w3.org/2005/Atom"></feed>`))
+	})
+	mux.HandleFunc("/feed.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"version": "https://jsonfeed.org/version/1.1", "items": []}`))
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		path   string
+		url    string
+		format string
+	}{
+		// Links resolve against where redirects end up
+		{"/old", server.URL + "/blog/rss", FormatRSS},
+		{"/feed", server.URL + "/feed", FormatAtom},
+		{"/feed.json", server.URL + "/feed.json", FormatJSONFeed},
+	}
+	for _, tt := range tests {
+		got, err := Find(context.Background(), server.Client(), server.URL+tt.path)
+		if err != nil {
+			t.Errorf("%s: %v", tt.path, err)
+			continue
+		}
+		if len(got) != 1 || got[0].URL != tt.url || got[0].Format != tt.format {
+			t.Errorf("%s: got %+v, want %s as %s", tt.path, got, tt.url, tt.format)
+		}
+	}
+
+	if _, err := Find(context.Background(), server.Client(), server.URL+"/gone"); err == nil {
+		t.Error("expected an error for a 404 page")
+	}
+}
+
+func TestCandidateFeed(t *testing.T) {
+	for _, format := range []string{FormatRSS, FormatAtom, FormatJSONFeed} {
+		feed := Candidate{URL: "https://example.com/feed", Format: format}.Feed("Example")
+		if err := feed.Validate(); err != nil {
+			t.Errorf("%s: invalid feed: %v", format, err)
+		}
+	}
+}
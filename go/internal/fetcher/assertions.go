@@ -0,0 +1,75 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"feedpulse/internal/config"
+)
+
+// checkResponse evaluates the per-response assertions of feed (required
+// fields) against a raw payload
+func checkResponse(feed config.Feed, payload []byte) []string {
+	if feed.Assertions == nil || len(feed.Assertions.RequiredFields) == 0 {
+		return nil
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(payload, &doc); err != nil {
+		// The parser reports malformed JSON itself
+		return nil
+	}
+
+	var violations []string
+	for _, field := range feed.Assertions.RequiredFields {
+		if !hasPath(doc, strings.Split(field, ".")) {
+			violations = append(violations, fmt.Sprintf("missing required field '%s'", field))
+		}
+	}
+	return violations
+}
+
+// checkResult evaluates the per-source assertions of feed (minimum item
+// count and newest item age) against a successful result, adding any
+// violations to result.Violations
+func (f *Fetcher) checkResult(feed config.Feed, result *FetchResult) {
+	a := feed.Assertions
+	if a == nil || !result.Success || result.Unchanged {
+		return
+	}
+
+	if result.ItemsCount < a.MinItems {
+		result.Violations = append(result.Violations, fmt.Sprintf("expected at least %d items, got %d", a.MinItems, result.ItemsCount))
+	}
+
+	if maxAge := a.MaxAgeDuration(); maxAge > 0 {
+		newest, ok := newestTimestamp(result)
+		if ok && f.clock.Now().Sub(newest) > maxAge {
+			result.Violations = append(result.Violations, fmt.Sprintf("newest item is from %s, older than max_age %s", newest.UTC().Format(time.RFC3339), a.MaxAge))
+		}
+	}
+}
+
+// newestTimestamp returns the latest parseable item timestamp in result.
+// Items without timestamps are ignored, since not every feed provides them.
+func newestTimestamp(result *FetchResult) (time.Time, bool) {
+	var newest time.Time
+	found := false
+
+	for _, item := range result.Items {
+		if item.Timestamp == nil {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, *item.Timestamp)
+		if err != nil {
+			continue
+		}
+		if !found || t.After(newest) {
+			newest, found = t, true
+		}
+	}
+
+	return newest, found
+}
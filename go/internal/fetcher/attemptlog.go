@@ -0,0 +1,142 @@
+package fetcher
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Error classes of a failed attempt, coarse enough to count on a
+// dashboard
+const (
+	ClassTimeout    = "timeout"
+	ClassCancelled  = "cancelled"
+	ClassDNS        = "dns"
+	ClassTLS        = "tls"
+	ClassConnection = "connection"
+	ClassHTTP4xx    = "http_4xx"
+	ClassHTTP5xx    = "http_5xx"
+	ClassHTTP       = "http"
+	ClassRead       = "read"
+	ClassOther      = "other"
+)
+
+// errReadBody marks a response whose body couldn't be read
+var errReadBody = errors.New("failed to read response")
+
+// AttemptRecord is the log line written for one HTTP request. Attempt
+// counts from 1; retries of a fetch have higher numbers.
+type AttemptRecord struct {
+	Time       time.Time `json:"time"`
+	Feed       string    `json:"feed"`
+	URL        string    `json:"url"`
+	Attempt    int       `json:"attempt"`
+	Status     int       `json:"status,omitempty"`
+	Bytes      int       `json:"bytes"`
+	DurationMs int64     `json:"duration_ms"`
+	ErrorClass string    `json:"error_class,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// attemptLog writes AttemptRecords as JSON lines
+type attemptLog struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// SetAttemptLog makes the fetcher write one JSON line to w for every HTTP
+// request it sends; nil stops it
+func (f *Fetcher) SetAttemptLog(w io.Writer) {
+	if w == nil {
+		f.attempts = nil
+		return
+	}
+	f.attempts = &attemptLog{enc: json.NewEncoder(w)}
+}
+
+// attemptKey is the context key of the number of the attempt in progress
+type attemptKey struct{}
+
+// withAttempt numbers the requests sent with ctx as attempt n of a fetch
+func withAttempt(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, n)
+}
+
+// logAttempt records a request to rawURL for feed that started at start,
+// answered status with size bytes or failed with err
+func (f *Fetcher) logAttempt(ctx context.Context, feed, rawURL string, start time.Time, status, size int, err error) {
+	if f.attempts == nil {
+		return
+	}
+	record := AttemptRecord{
+		Time:       start.UTC(),
+		Feed:       feed,
+		URL:        f.redact.String(rawURL),
+		Attempt:    1,
+		Status:     status,
+		Bytes:      size,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if n, ok := ctx.Value(attemptKey{}).(int); ok {
+		record.Attempt = n
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		record.Status = httpErr.StatusCode
+	}
+	// A 304 answers a conditional request; it isn't a failure
+	if err != nil && !isNotModified(err) {
+		record.ErrorClass = ErrorClass(err)
+		record.Error = f.redact.String(err.Error())
+	}
+
+	f.attempts.mu.Lock()
+	defer f.attempts.mu.Unlock()
+	// A full disk shouldn't fail the fetch
+	_ = f.attempts.enc.Encode(record)
+}
+
+// ErrorClass sorts a failed request's error into one of the Class
+// constants
+func ErrorClass(err error) string {
+	var httpErr *HTTPError
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var opErr *net.OpError
+	var certErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidCert x509.CertificateInvalidError
+
+	switch {
+	case errors.As(err, &httpErr):
+		switch {
+		case httpErr.StatusCode >= 500:
+			return ClassHTTP5xx
+		case httpErr.StatusCode >= 400:
+			return ClassHTTP4xx
+		}
+		return ClassHTTP
+	case errors.Is(err, context.Canceled):
+		return ClassCancelled
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ClassTimeout
+	case errors.As(err, &dnsErr):
+		return ClassDNS
+	case errors.As(err, &certErr), errors.As(err, &recordErr), errors.As(err, &unknownAuthority),
+		errors.As(err, &hostnameErr), errors.As(err, &invalidCert):
+		return ClassTLS
+	case errors.As(err, &opErr):
+		return ClassConnection
+	case errors.Is(err, errReadBody):
+		return ClassRead
+	}
+	return ClassOther
+}
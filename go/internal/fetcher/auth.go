@@ -0,0 +1,177 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"feedpulse/internal/config"
+)
+
+// tokenMargin is how long before it expires an OAuth2 access token is
+// replaced, so it doesn't run out between being sent and checked
+const tokenMargin = time.Minute
+
+// defaultTokenLifetime is how long a token whose response gives no
+// expires_in is used
+const defaultTokenLifetime = time.Hour
+
+// accessToken is an OAuth2 access token and when to stop using it
+type accessToken struct {
+	value   string
+	expires time.Time
+}
+
+// feedToken is the cached access token of a feed. mu is held while the
+// token is replaced, so requests of that feed wait for the new one
+// without holding up other feeds.
+type feedToken struct {
+	mu    sync.Mutex
+	token accessToken
+}
+
+// authorize adds feed's auth to req. For OAuth2 it reports whether the
+// token was fetched just now rather than reused.
+func (f *Fetcher) authorize(ctx context.Context, req *http.Request, feed config.Feed) (bool, error) {
+	auth := feed.Auth
+	switch auth.Type {
+	case config.AuthBasic:
+		password, err := auth.Secret()
+		if err != nil {
+			return false, err
+		}
+		req.SetBasicAuth(auth.Username, password)
+		return false, nil
+
+	case config.AuthBearer:
+		token, err := auth.Secret()
+		if err != nil {
+			return false, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return false, nil
+
+	case config.AuthOAuth2:
+		token, fresh, err := f.oauthToken(ctx, feed)
+		if err != nil {
+			return false, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return fresh, nil
+	}
+	return false, fmt.Errorf("unknown auth type: %s", auth.Type)
+}
+
+// oauthToken returns feed's cached access token, or gets a new one if it
+// has none or it is about to expire. It reports whether the token is new.
+// Requests of the same feed wait for one another's token rather than
+// each getting their own.
+func (f *Fetcher) oauthToken(ctx context.Context, feed config.Feed) (string, bool, error) {
+	cached := f.feedToken(feed.Name)
+	cached.mu.Lock()
+	defer cached.mu.Unlock()
+
+	if cached.token.value != "" && f.clock.Now().Before(cached.token.expires) {
+		return cached.token.value, false, nil
+	}
+
+	token, err := f.requestToken(ctx, feed.Auth)
+	if err != nil {
+		return "", false, err
+	}
+	cached.token = token
+	return token.value, true, nil
+}
+
+// feedToken returns the token cache entry of feed, adding it if needed
+func (f *Fetcher) feedToken(feed string) *feedToken {
+	f.tokensMu.Lock()
+	defer f.tokensMu.Unlock()
+	cached, ok := f.tokens[feed]
+	if !ok {
+		cached = &feedToken{}
+		f.tokens[feed] = cached
+	}
+	return cached
+}
+
+// forgetToken drops feed's cached access token value, e.g. after the API
+// rejected it before it was due to expire. A token another request got
+// in the meantime is kept.
+func (f *Fetcher) forgetToken(feed, value string) {
+	cached := f.feedToken(feed)
+	cached.mu.Lock()
+	defer cached.mu.Unlock()
+	if cached.token.value == value {
+		cached.token = accessToken{}
+	}
+}
+
+// requestToken gets an access token with the client credentials grant
+func (f *Fetcher) requestToken(ctx context.Context, auth *config.AuthConfig) (accessToken, error) {
+	secret, err := auth.Secret()
+	if err != nil {
+		return accessToken{}, err
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(auth.Scopes) > 0 {
+		form.Set("scope", strings.Join(auth.Scopes, " "))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, auth.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return accessToken{}, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "feedpulse/1.0")
+	req.SetBasicAuth(url.QueryEscape(auth.ClientID), url.QueryEscape(secret))
+
+	f.meterRequest(ctx, auth.TokenURL)
+	started := f.clock.Now()
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return accessToken{}, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return accessToken{}, fmt.Errorf("failed to read token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return accessToken{}, fmt.Errorf("token request failed: %w", &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status})
+	}
+
+	var answer struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &answer); err != nil {
+		return accessToken{}, fmt.Errorf("invalid token response: %w", err)
+	}
+	if answer.AccessToken == "" {
+		return accessToken{}, fmt.Errorf("invalid token response: no access_token")
+	}
+	if answer.TokenType != "" && !strings.EqualFold(answer.TokenType, "bearer") {
+		return accessToken{}, fmt.Errorf("unsupported token type: %s", answer.TokenType)
+	}
+
+	lifetime := defaultTokenLifetime
+	if answer.ExpiresIn > 0 {
+		lifetime = time.Duration(answer.ExpiresIn) * time.Second
+	}
+	// Tokens too short-lived for the margin are used for half their life
+	margin := tokenMargin
+	if margin > lifetime/2 {
+		margin = lifetime / 2
+	}
+	return accessToken{value: answer.AccessToken, expires: started.Add(lifetime - margin)}, nil
+}
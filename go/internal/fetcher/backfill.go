@@ -0,0 +1,106 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"feedpulse/internal/config"
+	"feedpulse/internal/storage"
+)
+
+// BackfillPageFunc persists one backfilled page together with the cursor
+// for the page after it. Returning an error stops the backfill.
+type BackfillPageFunc func(result FetchResult, next storage.BackfillCursor) error
+
+// Backfill walks up to pages pages of feed's history starting at start,
+// handing each page to save. It waits feed.Backfill.DelayMs between pages
+// to stay under API rate limits, and stops early once a page comes back
+// empty or the API stops returning a cursor. It returns the number of
+// pages fetched.
+func (f *Fetcher) Backfill(ctx context.Context, feed config.Feed, start storage.BackfillCursor, pages int, save BackfillPageFunc) (int, error) {
+	if feed.Backfill == nil {
+		return 0, fmt.Errorf("feed '%s' has no backfill configuration", feed.Name)
+	}
+
+	// A cursor only describes one URL's history
+	urls := feed.ExpandURLs()
+	if len(urls) != 1 {
+		return 0, fmt.Errorf("feed '%s' expands to %d URLs; backfill needs a single URL (raise subreddit_batch to combine them)", feed.Name, len(urls))
+	}
+	feed.URL = urls[0]
+	feed.Subreddits = nil
+
+	cursor := start
+	delay := time.Duration(feed.Backfill.DelayMs) * time.Millisecond
+
+	for fetched := 0; fetched < pages; fetched++ {
+		if fetched > 0 {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return fetched, ctx.Err()
+			}
+		}
+
+		pageURL, err := backfillURL(feed, cursor)
+		if err != nil {
+			return fetched, err
+		}
+
+		pageFeed := feed
+		pageFeed.URL = f.renderURL(feed.Name, pageURL)
+
+		result := f.fetchFeed(ctx, pageFeed)
+		if !result.Success {
+			return fetched, fmt.Errorf("page %d: %s", cursor.Page, result.Error)
+		}
+
+		next := nextBackfillCursor(feed, cursor, result)
+		f.filterResult(&result)
+		if err := save(result, next); err != nil {
+			return fetched + 1, err
+		}
+
+		if next.Done {
+			return fetched + 1, nil
+		}
+		cursor = next
+	}
+
+	return pages, nil
+}
+
+// backfillURL builds the request URL for the page cursor points at
+func backfillURL(feed config.Feed, cursor storage.BackfillCursor) (string, error) {
+	u, err := url.Parse(feed.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL '%s': %w", feed.URL, err)
+	}
+
+	q := u.Query()
+	if feed.Backfill.PageParam != "" {
+		q.Set(feed.Backfill.PageParam, strconv.Itoa(cursor.Page))
+	} else if cursor.Cursor != "" {
+		q.Set(feed.Backfill.CursorParam, cursor.Cursor)
+	}
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// nextBackfillCursor works out where the page after result starts
+func nextBackfillCursor(feed config.Feed, cursor storage.BackfillCursor, result FetchResult) storage.BackfillCursor {
+	next := storage.BackfillCursor{Feed: feed.Name, Page: cursor.Page + 1}
+
+	if feed.Backfill.PageParam != "" {
+		next.Done = result.ItemsCount == 0
+		return next
+	}
+
+	next.Cursor = lookupString(result.payload, feed.Backfill.CursorField)
+	next.Done = next.Cursor == "" || result.ItemsCount == 0
+	return next
+}
@@ -0,0 +1,69 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"feedpulse/internal/config"
+)
+
+// stateBackoffUntil is the feed state key holding the time before which
+// the feed's API asked not to be called again
+const stateBackoffUntil = "backoff_until"
+
+// responseBackoff returns how long the API that sent data asked to be left
+// alone, or 0. Stack Exchange responses carry the hints in the body:
+// "backoff" is a number of seconds to wait before calling the same method
+// again, and a "quota_remaining" of 0 means the daily quota is spent until
+// it resets at midnight UTC.
+func responseBackoff(feed config.Feed, data []byte, now time.Time) time.Duration {
+	if feed.FeedType != "json" {
+		return 0
+	}
+
+	var body struct {
+		Backoff        *int `json:"backoff"`
+		QuotaRemaining *int `json:"quota_remaining"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return 0
+	}
+
+	var wait time.Duration
+	if body.Backoff != nil && *body.Backoff > 0 {
+		wait = time.Duration(*body.Backoff) * time.Second
+	}
+	if body.QuotaRemaining != nil && *body.QuotaRemaining <= 0 {
+		reset := now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+		if untilReset := reset.Sub(now); untilReset > wait {
+			wait = untilReset
+		}
+	}
+	return wait
+}
+
+// fetchUnlessBackingOff skips feed while a backoff requested by its API
+// is in effect, and otherwise fetches it, storing any new backoff in the
+// result's state. Backoffs are real-time limits, so they use the real
+// clock even when the fetcher's clock is fixed.
+func (f *Fetcher) fetchUnlessBackingOff(ctx context.Context, feed config.Feed) FetchResult {
+	if f.state != nil {
+		if st, err := f.state.GetFeedState(feed.Name); err == nil {
+			if until, err := time.Parse(time.RFC3339, st[stateBackoffUntil]); err == nil && time.Now().Before(until) {
+				return FetchResult{Source: feed.Name, Skipped: true, Error: "API requested backoff until " + until.Local().Format("15:04:05")}
+			}
+		}
+	}
+
+	result := f.fetchChained(ctx, feed)
+	if result.Success && result.Backoff > 0 {
+		state := make(map[string]string, len(result.State)+1)
+		for k, v := range result.State {
+			state[k] = v
+		}
+		state[stateBackoffUntil] = time.Now().Add(result.Backoff).UTC().Format(time.RFC3339)
+		result.State = state
+	}
+	return result
+}
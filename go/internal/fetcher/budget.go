@@ -0,0 +1,156 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"feedpulse/internal/config"
+	"feedpulse/internal/storage"
+)
+
+// RequestMeter counts the requests sent to hosts with a request budget,
+// and reserves a fetch's requests before it starts
+type RequestMeter interface {
+	RecordHostRequest(host string, at time.Time) error
+	HostUsage(host string, now time.Time) (storage.HostUsage, error)
+	ReserveHostRequests(host string, n, perHour, perDay int, at time.Time) (bool, error)
+	ReleaseHostRequests(host string, n int, at time.Time) error
+}
+
+// SetRequestMeter enables request budgets: a fetch reserves the requests
+// it plans to send to budgeted hosts before it starts, and feeds that
+// would overspend a budget are deferred. Without a meter budgets are
+// ignored.
+func (f *Fetcher) SetRequestMeter(m RequestMeter) {
+	f.meter = m
+}
+
+// requestHost returns the lowercased host name of rawURL, without port
+func requestHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// reservation is what a fetch reserved of its hosts' budgets: the
+// requests it may still send to each host without counting them again
+type reservation struct {
+	at time.Time
+
+	mu   sync.Mutex
+	left map[string]int
+}
+
+// reservationKey carries a fetch's reservation in its context
+type reservationKey struct{}
+
+// take uses up one of the requests reserved for host, if any are left
+func (r *reservation) take(host string) bool {
+	if r == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.left[host] == 0 {
+		return false
+	}
+	r.left[host]--
+	return true
+}
+
+// meterRequest counts a request to rawURL if its host has a budget,
+// unless the fetch sending it reserved it already
+func (f *Fetcher) meterRequest(ctx context.Context, rawURL string) {
+	if f.meter == nil {
+		return
+	}
+	host := requestHost(rawURL)
+	if _, ok := f.config.Settings.BudgetFor(host); !ok {
+		return
+	}
+	if r, _ := ctx.Value(reservationKey{}).(*reservation); r.take(host) {
+		return
+	}
+	// A failed count only makes the budget more lenient; the fetch goes on
+	_ = f.meter.RecordHostRequest(host, f.clock.Now())
+}
+
+// plannedRequests returns how many requests a fetch of feed sends to each
+// host, at most: one per URL it expands to, and one more if hedged, plus
+// one per story hydrated
+func plannedRequests(feed config.Feed) map[string]int {
+	planned := make(map[string]int)
+	for _, u := range feed.ExpandURLs() {
+		planned[requestHost(u)]++
+		if feed.Hedge != nil {
+			planned[requestHost(hedgeURL(feed))]++
+		}
+	}
+	if feed.Hydrate != nil {
+		planned[requestHost(feed.Hydrate.StoryURL("0"))] += feed.Hydrate.Limit()
+	}
+	return planned
+}
+
+// reserveBudget reserves the requests a fetch of feed plans to send to
+// each budgeted host, returning ctx carrying the reservation. If one of
+// the hosts can't spare them, nothing is reserved and it returns why. Each
+// host's check and count are one step in the store, so feeds fetched in
+// parallel can't overspend a budget between them.
+func (f *Fetcher) reserveBudget(ctx context.Context, feed config.Feed) (context.Context, *reservation, string) {
+	if f.meter == nil || len(f.config.Settings.Budgets) == 0 {
+		return ctx, nil, ""
+	}
+	r := &reservation{at: f.clock.Now(), left: make(map[string]int)}
+	for host, requests := range plannedRequests(feed) {
+		budget, ok := f.config.Settings.BudgetFor(host)
+		if !ok {
+			continue
+		}
+		reserved, err := f.meter.ReserveHostRequests(host, requests, budget.PerHour, budget.PerDay, r.at)
+		if err != nil {
+			// A failed reservation only makes the budget more lenient
+			continue
+		}
+		if !reserved {
+			f.releaseBudget(r)
+			return ctx, nil, f.budgetSpent(host, budget, requests)
+		}
+		r.left[host] = requests
+	}
+	return context.WithValue(ctx, reservationKey{}, r), r, ""
+}
+
+// releaseBudget gives back the requests r reserved that the fetch didn't
+// send
+func (f *Fetcher) releaseBudget(r *reservation) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for host, n := range r.left {
+		if n > 0 {
+			_ = f.meter.ReleaseHostRequests(host, n, r.at)
+		}
+		delete(r.left, host)
+	}
+}
+
+// budgetSpent describes which of host's budgets couldn't spare requests
+func (f *Fetcher) budgetSpent(host string, budget config.HostBudget, requests int) string {
+	usage, err := f.meter.HostUsage(host, f.clock.Now())
+	if err != nil {
+		return fmt.Sprintf("request budget for %s spent", host)
+	}
+	if budget.PerHour > 0 && usage.LastHour+requests > budget.PerHour {
+		return fmt.Sprintf("request budget for %s spent (%d/%d per hour)", host, usage.LastHour, budget.PerHour)
+	}
+	return fmt.Sprintf("request budget for %s spent (%d/%d per day)", host, usage.LastDay, budget.PerDay)
+}
@@ -0,0 +1,190 @@
+package fetcher
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"feedpulse/internal/redact"
+)
+
+// DefaultCaptureBytes is how much of each body a capture keeps by default
+const DefaultCaptureBytes = 64 * 1024
+
+// SetCapture makes the fetcher write every request it sends and the
+// response it gets to w, as text for a bug report: secrets are masked
+// and bodies cut to limit bytes. A response is written once the fetch has
+// read and closed its body, which streams through unchanged.
+func (f *Fetcher) SetCapture(w io.Writer, limit int) {
+	next := f.client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	client := *f.client
+	client.Transport = &captureTransport{next: next, w: w, limit: limit, redact: f.redact}
+	f.client = &client
+}
+
+// captureTransport writes the exchanges it carries to w
+type captureTransport struct {
+	next   http.RoundTripper
+	w      io.Writer
+	limit  int
+	redact *redact.Redactor
+
+	mu sync.Mutex
+	n  int
+}
+
+func (t *captureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			reqBody, _ = io.ReadAll(t.kept(body))
+			body.Close()
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	duration := time.Since(start)
+
+	t.mu.Lock()
+	t.n++
+	n := t.n
+	t.mu.Unlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "=== Exchange %d, %s (%d ms)\n\n", n, start.UTC().Format(time.RFC3339), duration.Milliseconds())
+	fmt.Fprintf(&b, "> %s %s %s\n", req.Method, t.redact.String(req.URL.String()), req.Proto)
+	fmt.Fprintf(&b, "> Host: %s\n", req.URL.Host)
+	t.writeHeader(&b, "> ", req.Header)
+	t.writeBody(&b, reqBody, req.ContentLength, true)
+
+	if err != nil {
+		fmt.Fprintf(&b, "! %s\n\n", t.redact.String(err.Error()))
+		t.write(b.String())
+		return nil, err
+	}
+
+	fmt.Fprintf(&b, "< %s %s\n", resp.Proto, resp.Status)
+	t.writeHeader(&b, "< ", resp.Header)
+	resp.Body = &captureBody{ReadCloser: resp.Body, limit: t.limit, done: func(body *captureBody) {
+		t.writeBody(&b, body.kept.Bytes(), body.total, body.eof)
+		if body.err != nil {
+			fmt.Fprintf(&b, "! failed to read response: %s\n\n", t.redact.String(body.err.Error()))
+		}
+		t.write(b.String())
+	}}
+	return resp, nil
+}
+
+// kept limits r to what the capture keeps of a body, plus a byte to tell
+// whether it was cut
+func (t *captureTransport) kept(r io.Reader) io.Reader {
+	if t.limit < 0 {
+		return r
+	}
+	return io.LimitReader(r, int64(t.limit)+1)
+}
+
+// write writes one exchange; a capture that can't be written doesn't fail
+// the fetch
+func (t *captureTransport) write(exchange string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	io.WriteString(t.w, exchange)
+}
+
+// writeHeader writes h sorted by name, masking secrets
+func (t *captureTransport) writeHeader(b *strings.Builder, prefix string, h http.Header) {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range h[name] {
+			if t.redact.SecretHeader(name) {
+				value = redact.Mask
+			}
+			fmt.Fprintf(b, "%s%s: %s\n", prefix, name, t.redact.String(value))
+		}
+	}
+	b.WriteString("\n")
+}
+
+// writeBody writes body, of total bytes (unknown if negative or complete
+// is false), up to the capture's limit, masking secrets
+func (t *captureTransport) writeBody(b *strings.Builder, body []byte, total int64, complete bool) {
+	if len(body) == 0 {
+		return
+	}
+	shown := body
+	if t.limit >= 0 && len(shown) > t.limit {
+		shown = shown[:t.limit]
+	}
+	b.WriteString(t.redact.String(string(shown)))
+	if !bytes.HasSuffix(shown, []byte("\n")) {
+		b.WriteString("\n")
+	}
+	if len(shown) < len(body) {
+		if complete && total >= int64(len(body)) {
+			fmt.Fprintf(b, "[truncated: %d of %d bytes shown]\n", len(shown), total)
+		} else {
+			fmt.Fprintf(b, "[truncated: %d bytes shown]\n", len(shown))
+		}
+	}
+	b.WriteString("\n")
+}
+
+// captureBody passes a response body through to the fetch, keeping up to
+// limit bytes of it and counting the rest, and calls done once when the
+// body is read to its end or closed
+type captureBody struct {
+	io.ReadCloser
+	limit int
+	done  func(*captureBody)
+
+	kept  bytes.Buffer
+	total int64
+	eof   bool
+	err   error
+	once  sync.Once
+}
+
+func (b *captureBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if room := b.limit + 1 - b.kept.Len(); b.limit < 0 || room > 0 {
+		if b.limit >= 0 && n > room {
+			b.kept.Write(p[:room])
+		} else {
+			b.kept.Write(p[:n])
+		}
+	}
+	b.total += int64(n)
+	switch {
+	case err == io.EOF:
+		b.eof = true
+		b.finish()
+	case err != nil:
+		b.err = err
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *captureBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.finish()
+	return err
+}
+
+func (b *captureBody) finish() {
+	b.once.Do(func() { b.done(b) })
+}
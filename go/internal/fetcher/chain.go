@@ -0,0 +1,61 @@
+package fetcher
+
+import (
+	"context"
+	"time"
+
+	"feedpulse/internal/config"
+)
+
+// Feed state keys used to chain feeds. An upstream feed records when it
+// last produced new items; a dependent feed records which of those
+// upstream runs it has already followed.
+const (
+	stateNewItemsAt   = "new_items_at"
+	stateUpstreamSeen = "upstream_seen"
+)
+
+// MarkNewItems returns state with a record that the feed produced new
+// items at at, which makes feeds depending on it due on their next stage
+func MarkNewItems(state map[string]string, at time.Time) map[string]string {
+	marked := make(map[string]string, len(state)+1)
+	for k, v := range state {
+		marked[k] = v
+	}
+	marked[stateNewItemsAt] = at.UTC().Format(time.RFC3339Nano)
+	return marked
+}
+
+// fetchChained fetches feed if it has no upstream feed, or if its upstream
+// produced new items it hasn't followed yet; otherwise the result is
+// marked Skipped. Without fetch state every feed is fetched.
+func (f *Fetcher) fetchChained(ctx context.Context, feed config.Feed) FetchResult {
+	if feed.DependsOn == "" || f.state == nil {
+		return f.fetchSource(ctx, feed)
+	}
+
+	upstream, err := f.state.GetFeedState(feed.DependsOn)
+	if err != nil {
+		return f.fetchSource(ctx, feed)
+	}
+	own, err := f.state.GetFeedState(feed.Name)
+	if err != nil {
+		return f.fetchSource(ctx, feed)
+	}
+
+	newItemsAt := upstream[stateNewItemsAt]
+	if newItemsAt == "" || newItemsAt == own[stateUpstreamSeen] {
+		return FetchResult{Source: feed.Name, Skipped: true, Error: "no new items from " + feed.DependsOn}
+	}
+
+	result := f.fetchSource(ctx, feed)
+	if result.Success {
+		state := make(map[string]string, len(result.State)+1)
+		for k, v := range result.State {
+			state[k] = v
+		}
+		state[stateUpstreamSeen] = newItemsAt
+		result.State = state
+	}
+	return result
+}
@@ -0,0 +1,179 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sync"
+	"time"
+
+	"feedpulse/internal/config"
+	"feedpulse/internal/storage"
+)
+
+// CookieStore persists per-feed cookie jars between runs
+type CookieStore interface {
+	LoadCookies(feed string) ([]storage.Cookie, error)
+	SaveCookies(feed string, cookies []storage.Cookie) error
+}
+
+// SetCookieStore enables persistent cookie jars for feeds that use them
+func (f *Fetcher) SetCookieStore(cs CookieStore) {
+	f.cookies = cs
+}
+
+// Login sends feed's configured login request with a fresh cookie jar and
+// stores the resulting jar for later fetches. It returns the number of
+// cookies stored.
+func (f *Fetcher) Login(ctx context.Context, feed config.Feed) (int, error) {
+	if feed.Login == nil {
+		return 0, fmt.Errorf("feed '%s' has no login configuration", feed.Name)
+	}
+	if f.cookies == nil {
+		return 0, fmt.Errorf("no cookie store configured")
+	}
+
+	jar := newRecordingJar(f.clock.Now)
+	f.jarsMu.Lock()
+	f.jars[feed.Name] = jar
+	f.jarsMu.Unlock()
+
+	if _, _, err := f.fetchURL(ctx, feed.Login.Request(feed.Name), nil); err != nil {
+		return 0, fmt.Errorf("login request failed: %w", err)
+	}
+
+	cookies := jar.snapshot()
+	if err := f.cookies.SaveCookies(feed.Name, cookies); err != nil {
+		return 0, err
+	}
+	return len(cookies), nil
+}
+
+// jarFor returns feed's cookie jar, loading it from the cookie store the
+// first time it is needed
+func (f *Fetcher) jarFor(feed string) (*recordingJar, error) {
+	f.jarsMu.Lock()
+	defer f.jarsMu.Unlock()
+
+	if jar, ok := f.jars[feed]; ok {
+		return jar, nil
+	}
+
+	stored, err := f.cookies.LoadCookies(feed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cookie jar: %w", err)
+	}
+
+	jar := newRecordingJar(f.clock.Now)
+	jar.load(stored)
+	f.jars[feed] = jar
+	return jar, nil
+}
+
+// saveJar persists jar if a response changed it. A failed save only costs
+// the next run this run's cookie updates, so it is not reported.
+func (f *Fetcher) saveJar(feed string, jar *recordingJar) {
+	if !jar.takeDirty() {
+		return
+	}
+	f.cookies.SaveCookies(feed, jar.snapshot())
+}
+
+// recordingJar is a cookie jar that also remembers every cookie it was
+// given, since http.CookieJar offers no way to enumerate a jar for
+// persisting it
+type recordingJar struct {
+	jar *cookiejar.Jar
+	now func() time.Time
+
+	mu      sync.Mutex
+	cookies map[string]storage.Cookie
+	dirty   bool
+}
+
+func newRecordingJar(now func() time.Time) *recordingJar {
+	// cookiejar.New only fails on invalid options
+	jar, _ := cookiejar.New(nil)
+	return &recordingJar{jar: jar, now: now, cookies: make(map[string]storage.Cookie)}
+}
+
+// SetCookies implements http.CookieJar
+func (j *recordingJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, c := range cookies {
+		key := c.Domain + "|" + c.Path + "|" + c.Name
+		expires := c.Expires
+		if c.MaxAge > 0 {
+			expires = j.now().Add(time.Duration(c.MaxAge) * time.Second)
+		}
+
+		if c.MaxAge < 0 || (!expires.IsZero() && expires.Before(j.now())) {
+			delete(j.cookies, key)
+		} else {
+			j.cookies[key] = storage.Cookie{
+				URL:      u.String(),
+				Name:     c.Name,
+				Value:    c.Value,
+				Domain:   c.Domain,
+				Path:     c.Path,
+				Expires:  expires,
+				Secure:   c.Secure,
+				HttpOnly: c.HttpOnly,
+			}
+		}
+		j.dirty = true
+	}
+}
+
+// Cookies implements http.CookieJar
+func (j *recordingJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// load restores stored cookies into the jar without marking it dirty
+func (j *recordingJar) load(stored []storage.Cookie) {
+	for _, c := range stored {
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			continue
+		}
+		j.SetCookies(u, []*http.Cookie{{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Expires:  c.Expires,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+		}})
+	}
+	j.takeDirty()
+}
+
+// snapshot returns the cookies to persist
+func (j *recordingJar) snapshot() []storage.Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	cookies := make([]storage.Cookie, 0, len(j.cookies))
+	for _, c := range j.cookies {
+		cookies = append(cookies, c)
+	}
+	return cookies
+}
+
+// takeDirty reports whether the jar changed since the last call
+func (j *recordingJar) takeDirty() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	dirty := j.dirty
+	j.dirty = false
+	return dirty
+}
@@ -1,18 +1,24 @@
 package fetcher
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"math"
 	"math/rand"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
+	"feedpulse/internal/clock"
 	"feedpulse/internal/config"
 	"feedpulse/internal/parser"
+	"feedpulse/internal/redact"
+	"feedpulse/internal/sentry"
 	"feedpulse/internal/storage"
+	"feedpulse/internal/transform"
 )
 
 // FetchResult represents the result of fetching a single feed
@@ -24,38 +30,222 @@ type FetchResult struct {
 	Error        string
 	DurationMs   int64
 	Items        []storage.FeedItem
+	JournalIDs   []int64
+	Skipped      bool
+	Endpoint     string
+	Truncated    int
+
+	// Filtered is how many parsed items the feed's filters dropped
+	Filtered int
+
+	// Unchanged is set when the response was identical to the previous
+	// run's, so it wasn't parsed and carries no items
+	Unchanged bool
+
+	// Partial is set when the items are only part of the feed: an
+	// incremental fetch from a cursor, a truncated response, or a
+	// multi-URL feed some URLs of which failed
+	Partial bool
+
+	// Violations lists the feed's assertions this fetch failed. The items
+	// are still usable, but the fetch is logged as degraded.
+	Violations []string
+
+	// SchemaDrift is set when the response's JSON structure changed
+	// significantly since the previous run
+	SchemaDrift *SchemaDrift
+
+	// Backoff is how long the feed's API asked to be left alone after
+	// this fetch; the feed is skipped until it has passed
+	Backoff time.Duration
+
+	// Hub is the WebSub hub the response advertised (Link rel="hub"), and
+	// Topic the URL to subscribe to there (rel="self", else the fetched URL)
+	Hub   string
+	Topic string
+
+	// State holds feed state (e.g. incremental cursors) to store once the
+	// items are saved, so the next run continues from here
+	State map[string]string
+
+	// Failure is the response, cut to max_failure_bytes, of a fetch (or
+	// one URL of it) that failed because it couldn't be parsed
+	Failure *storage.CapturedResponse
+
+	// payload and header are the raw response, kept for callers in this
+	// package that need more from the response than the parsed items
+	payload     []byte
+	header      http.Header
+	schema      []string
+	payloadHash string
+}
+
+// Journal persists raw payloads before they are parsed so an interrupted
+// run can be replayed without refetching
+type Journal interface {
+	AppendJournal(source, feedType string, payload []byte) (int64, error)
+}
+
+// FetchState reports stored fetch history used to render URL templates
+// and resume incremental feeds
+type FetchState interface {
+	LastSuccess(source string) (time.Time, bool, error)
+	GetFeedState(feed string) (map[string]string, error)
 }
 
 // Fetcher handles concurrent feed fetching
 type Fetcher struct {
-	config *config.Config
-	parser *parser.Parser
-	client *http.Client
+	config  *config.Config
+	parser  *parser.Parser
+	client  *http.Client
+	journal Journal
+	clock   clock.Clock
+	state   FetchState
+	cookies CookieStore
+	meter   RequestMeter
+	// attempts logs every request, when set
+	attempts *attemptLog
+	// redact masks secrets in the errors and endpoints of results
+	redact *redact.Redactor
+	// filters drop parsed items before they are stored
+	filters *ItemFilters
+
+	jarsMu sync.Mutex
+	jars   map[string]*recordingJar
+
+	// tokens caches OAuth2 access tokens by feed
+	tokensMu sync.Mutex
+	tokens   map[string]*feedToken
 }
 
 // NewFetcher creates a new fetcher instance
 func NewFetcher(cfg *config.Config) *Fetcher {
 	return &Fetcher{
 		config: cfg,
-		parser: parser.NewParser(),
+		parser: NewParser(cfg),
 		client: &http.Client{
 			Timeout: time.Duration(cfg.Settings.DefaultTimeoutSecs) * time.Second,
 		},
+		clock:   clock.System,
+		jars:    make(map[string]*recordingJar),
+		tokens:  make(map[string]*feedToken),
+		redact:  cfg.Redactor(),
+		filters: NewItemFilters(cfg),
+	}
+}
+
+// NewParser creates a parser configured for cfg's settings and feeds: the
+// item limits, the dedup mode, each xml feed's mapping, each plugin feed's
+// command and each feed's transform script
+func NewParser(cfg *config.Config) *parser.Parser {
+	p := parser.NewParser()
+	p.SetMaxItems(cfg.Settings.MaxItemsPerFetch, cfg.Settings.TruncateBy == config.TruncateNewest)
+	p.SetDedup(cfg.Settings.Dedup)
+
+	for _, feed := range cfg.Feeds {
+		if feed.XML != nil {
+			p.SetXMLMapping(feed.Name, parser.XMLMapping{
+				Item:       feed.XML.Item,
+				Title:      feed.XML.Title,
+				URL:        feed.XML.URL,
+				Date:       feed.XML.Date,
+				Tags:       feed.XML.Tags,
+				Namespaces: feed.XML.Namespaces,
+			})
+		}
+		if feed.JSON != nil {
+			p.SetJSONMapping(feed.Name, parser.JSONMapping{
+				Item:  feed.JSON.Item,
+				Title: feed.JSON.Title,
+				URL:   feed.JSON.URL,
+				Date:  feed.JSON.Date,
+				Tags:  feed.JSON.Tags,
+			})
+		}
+		if feed.IDHash != "" {
+			p.SetIDHash(feed.Name, feed.IDHash)
+		}
+		if feed.MaxItems > 0 {
+			p.SetFeedMaxItems(feed.Name, feed.MaxItems)
+		}
+		if feed.Transform != "" {
+			// Validation rejects scripts that don't compile
+			if script, err := transform.Compile(feed.Transform); err == nil {
+				p.SetTransform(feed.Name, script)
+			}
+		}
+		if feed.Plugin != nil {
+			p.SetPlugin(feed.Name, parser.Plugin{
+				Command:   feed.Plugin.Path(cfg.Dir),
+				Args:      feed.Plugin.Args,
+				Dir:       cfg.Dir,
+				Timeout:   feed.Plugin.Deadline(),
+				MaxOutput: feed.Plugin.OutputLimit(),
+			})
+		}
 	}
+	return p
 }
 
-// FetchAll fetches all configured feeds concurrently
+// SetClock sets the clock used to identify runs and stamp parsed items.
+// Durations are always measured against the real clock.
+func (f *Fetcher) SetClock(c clock.Clock) {
+	f.clock = c
+	f.parser.SetClock(c)
+}
+
+// SetJournal enables write-ahead journaling of fetched payloads
+func (f *Fetcher) SetJournal(j Journal) {
+	f.journal = j
+}
+
+// SetState provides the fetch history that {{last_fetch_*}} URL template
+// variables and incremental cursors are read from
+func (f *Fetcher) SetState(st FetchState) {
+	f.state = st
+}
+
+// FetchAll fetches all configured feeds, stage by stage
 func (f *Fetcher) FetchAll(ctx context.Context) []FetchResult {
+	return f.FetchStages(ctx, nil)
+}
+
+// StageFunc handles the results of one stage of a run before the next
+// stage starts, so feeds that depend on them see their saved state
+type StageFunc func(results []FetchResult)
+
+// FetchStages fetches all configured feeds in dependency order. Feeds in
+// the same stage are fetched concurrently; done, if set, is called with
+// each stage's results before the next stage starts. It returns the
+// results of all stages.
+func (f *Fetcher) FetchStages(ctx context.Context, done StageFunc) []FetchResult {
+	// Each call is one run; item IDs include it under the "run" scope
+	f.parser.SetUniquenessScope(f.config.Settings.UniquenessScope, f.clock.Now().UTC().Format(time.RFC3339Nano))
+
+	var all []FetchResult
+	for _, stage := range f.config.FeedStages() {
+		results := f.fetchConcurrently(ctx, stage)
+		if done != nil {
+			done(results)
+		}
+		all = append(all, results...)
+	}
+	return all
+}
+
+// fetchConcurrently fetches feeds concurrently, up to MaxConcurrency at a time
+func (f *Fetcher) fetchConcurrently(ctx context.Context, feeds []config.Feed) []FetchResult {
 	// Create a semaphore to limit concurrency
 	sem := make(chan struct{}, f.config.Settings.MaxConcurrency)
 	
 	var wg sync.WaitGroup
-	results := make([]FetchResult, len(f.config.Feeds))
+	results := make([]FetchResult, len(feeds))
 
-	for i, feed := range f.config.Feeds {
+	for i, feed := range feeds {
 		wg.Add(1)
 		go func(index int, feed config.Feed) {
 			defer wg.Done()
+			defer sentry.Recover()
 
 			// Acquire semaphore
 			select {
@@ -70,8 +260,7 @@ func (f *Fetcher) FetchAll(ctx context.Context) []FetchResult {
 				return
 			}
 
-			// Fetch the feed
-			results[index] = f.fetchFeed(ctx, feed)
+			results[index] = f.fetchOne(ctx, feed)
 		}(i, feed)
 	}
 
@@ -79,11 +268,142 @@ func (f *Fetcher) FetchAll(ctx context.Context) []FetchResult {
 	return results
 }
 
+// FetchFeed fetches the configured feed named name on its own, as a run
+// of its own, e.g. when a push notification says it has changed
+func (f *Fetcher) FetchFeed(ctx context.Context, name string) (FetchResult, error) {
+	for _, feed := range f.config.Feeds {
+		if feed.Name == name {
+			f.parser.SetUniquenessScope(f.config.Settings.UniquenessScope, f.clock.Now().UTC().Format(time.RFC3339Nano))
+			return f.fetchOne(ctx, feed), nil
+		}
+	}
+	return FetchResult{}, fmt.Errorf("feed not found: %s", name)
+}
+
+// fetchOne fetches a feed and runs the checks on its result
+func (f *Fetcher) fetchOne(ctx context.Context, feed config.Feed) FetchResult {
+	if !feed.IsEnabled() {
+		return FetchResult{Source: feed.Name, Skipped: true, Error: "disabled in the config"}
+	}
+	if feed.IsStream() {
+		return FetchResult{Source: feed.Name, Skipped: true, Error: "stream feed, consumed by feedpulse serve"}
+	}
+
+	ctx, reserved, reason := f.reserveBudget(ctx, feed)
+	if reason != "" {
+		return FetchResult{Source: feed.Name, Skipped: true, Error: reason}
+	}
+	defer f.releaseBudget(reserved)
+
+	result := f.fetchUnlessBackingOff(ctx, feed)
+	if result.Truncated > 0 {
+		result.Partial = true
+	}
+	f.hydrateHackerNews(ctx, feed, &result)
+	f.checkResult(feed, &result)
+	f.checkSchema(feed, &result)
+	f.filterResult(&result)
+	f.redactResult(&result)
+	return result
+}
+
+// redactResult masks secrets in what of result is printed and stored
+func (f *Fetcher) redactResult(result *FetchResult) {
+	result.Error = f.redact.String(result.Error)
+	result.Endpoint = f.redact.String(result.Endpoint)
+}
+
+// fetchSource fetches every URL a feed expands to and merges the results
+// into one source. A multi-URL feed succeeds if any of its URLs do; the
+// failures are reported in Error alongside the merged items.
+func (f *Fetcher) fetchSource(ctx context.Context, feed config.Feed) FetchResult {
+	if len(feed.Mirrors) > 0 {
+		return f.fetchMirrors(ctx, feed)
+	}
+
+	urls := feed.ExpandURLs()
+	for i, u := range urls {
+		urls[i] = f.renderURL(feed.Name, u)
+	}
+
+	if len(urls) == 1 {
+		feed.URL = urls[0]
+		if feed.Incremental != nil {
+			return f.fetchIncremental(ctx, feed)
+		}
+		return f.fetchUnlessUnchanged(ctx, feed)
+	}
+
+	start := time.Now()
+	merged := FetchResult{Source: feed.Name}
+	var failures []string
+
+	for _, u := range urls {
+		sub := feed
+		sub.URL = u
+		sub.Subreddits = nil
+
+		result := f.fetchFeed(ctx, sub)
+		if !result.Success {
+			failures = append(failures, fmt.Sprintf("%s: %s", u, result.Error))
+			if merged.Failure == nil {
+				merged.Failure = result.Failure
+			}
+			continue
+		}
+
+		merged.Success = true
+		merged.Items = append(merged.Items, result.Items...)
+		merged.JournalIDs = append(merged.JournalIDs, result.JournalIDs...)
+		merged.Truncated += result.Truncated
+		merged.Violations = append(merged.Violations, result.Violations...)
+		merged.schema = mergeSchema(merged.schema, result.schema)
+		if result.Backoff > merged.Backoff {
+			merged.Backoff = result.Backoff
+		}
+	}
+
+	merged.ItemsCount = len(merged.Items)
+	merged.DurationMs = time.Since(start).Milliseconds()
+	if len(failures) > 0 {
+		merged.Error = strings.Join(failures, "; ")
+		merged.Partial = merged.Success
+	}
+
+	return merged
+}
+
+// renderURL substitutes time-based template variables into u
+func (f *Fetcher) renderURL(source, u string) string {
+	if !config.HasTimeVars(u) {
+		return u
+	}
+
+	now := f.clock.Now()
+	lastFetch := now.Add(-24 * time.Hour)
+	if f.state != nil {
+		if t, ok, err := f.state.LastSuccess(source); err == nil && ok {
+			lastFetch = t
+		}
+	}
+
+	return config.RenderURL(u, config.URLVars(now, lastFetch))
+}
+
 // fetchFeed fetches a single feed with retries
 func (f *Fetcher) fetchFeed(ctx context.Context, feed config.Feed) FetchResult {
+	return f.fetchFeedUnless(ctx, feed, savedResponse{})
+}
+
+// fetchFeedUnless fetches a single feed with retries, returning an
+// Unchanged result without parsing if the server says the previous
+// response is still current or the response hashes the same
+func (f *Fetcher) fetchFeedUnless(ctx context.Context, feed config.Feed, previous savedResponse) FetchResult {
 	start := time.Now()
 
 	var lastErr error
+	var failure *storage.CapturedResponse
+	var failedJournal []int64
 	for attempt := 0; attempt <= f.config.Settings.RetryMax; attempt++ {
 		if attempt > 0 {
 			// Calculate exponential backoff with jitter
@@ -101,7 +421,17 @@ func (f *Fetcher) fetchFeed(ctx context.Context, feed config.Feed) FetchResult {
 		}
 
 		// Attempt to fetch
-		data, err := f.fetchURL(ctx, feed)
+		conditional := previous.conditional()
+		data, header, endpoint, err := f.fetchHedged(withAttempt(ctx, attempt+1), feed, conditional)
+		if err != nil && conditional != nil && isNotModified(err) {
+			return FetchResult{
+				Source:     feed.Name,
+				Success:    true,
+				Unchanged:  true,
+				DurationMs: time.Since(start).Milliseconds(),
+				Endpoint:   endpoint,
+			}
+		}
 		if err != nil {
 			lastErr = err
 			// Don't retry on 404 or client errors
@@ -111,23 +441,79 @@ func (f *Fetcher) fetchFeed(ctx context.Context, feed config.Feed) FetchResult {
 			continue
 		}
 
+		// An identical response has nothing new to parse or save
+		hash := payloadHash(data)
+		if previous.hash != "" && hash == previous.hash {
+			hub, topic := webSubLinks(feed, header)
+			return FetchResult{
+				Source:     feed.Name,
+				Success:    true,
+				Unchanged:  true,
+				DurationMs: time.Since(start).Milliseconds(),
+				Endpoint:   endpoint,
+				Hub:        hub,
+				Topic:      topic,
+			}
+		}
+
+		// Journal the raw payload before parsing; a failed write only
+		// costs crash recovery for this feed, so the fetch proceeds
+		var journalID int64
+		if f.journal != nil {
+			if id, err := f.journal.AppendJournal(feed.Name, feed.FeedType, data); err == nil {
+				journalID = id
+			}
+		}
+
+		// Everything after the journal works on the document itself. A
+		// response that doesn't match its unwrap rules won't on a retry
+		// either, so it fails the fetch immediately.
+		raw := data
+		if data, err = Unwrap(feed, data); err != nil {
+			lastErr = err
+			break
+		}
+		if feed.FeedType == "json" {
+			data = parser.TrimJSONPrefix(data)
+		}
+
 		// Parse the feed
 		parseResult := f.parser.Parse(feed.Name, feed.FeedType, data)
-		
-		// Log parse errors but don't fail
-		if len(parseResult.Errors) > 0 {
-			for _, parseErr := range parseResult.Errors {
-				fmt.Fprintf(io.Discard, "warning: %s: %s\n", feed.Name, parseErr)
-			}
+
+		// A response that couldn't be decoded fails the fetch, without
+		// retrying, and is kept as received for a post-mortem. Other
+		// parse errors only cost the items they were about.
+		if parseResult.Malformed {
+			lastErr = fmt.Errorf("parse error: %s", strings.Join(parseResult.Errors, "; "))
+			failure = f.captureFailure(raw)
+			failedJournal = journalIDs(journalID)
+			break
 		}
+		for _, parseErr := range parseResult.Errors {
+			fmt.Fprintf(io.Discard, "warning: %s: %s\n", feed.Name, parseErr)
+		}
+
+		hub, topic := webSubLinks(feed, header)
 
 		duration := time.Since(start).Milliseconds()
 		return FetchResult{
-			Source:     feed.Name,
-			Success:    true,
-			ItemsCount: len(parseResult.Items),
-			Items:      parseResult.Items,
-			DurationMs: duration,
+			Source:      feed.Name,
+			Success:     true,
+			ItemsCount:  len(parseResult.Items),
+			Items:       parseResult.Items,
+			DurationMs:  duration,
+			JournalIDs:  journalIDs(journalID),
+			Endpoint:    endpoint,
+			Truncated:   parseResult.Truncated,
+			Filtered:    parseResult.Dropped,
+			Violations:  checkResponse(feed, data),
+			Backoff:     responseBackoff(feed, data, time.Now()),
+			Hub:         hub,
+			Topic:       topic,
+			payload:     data,
+			schema:      responseSchema(feed, data),
+			header:      header,
+			payloadHash: hash,
 		}
 	}
 
@@ -143,16 +529,66 @@ func (f *Fetcher) fetchFeed(ctx context.Context, feed config.Feed) FetchResult {
 		Success:    false,
 		Error:      fmt.Sprintf("failed after %d retries: %s", f.config.Settings.RetryMax, errorMsg),
 		DurationMs: duration,
+		Endpoint:   feed.URL,
+		JournalIDs: failedJournal,
+		Failure:    failure,
+	}
+}
+
+// captureFailure keeps the start of a response that failed to parse, up
+// to max_failure_bytes, or returns nil if failures aren't recorded
+func (f *Fetcher) captureFailure(data []byte) *storage.CapturedResponse {
+	limit := f.config.Settings.FailureCap()
+	if limit == 0 {
+		return nil
+	}
+	return storage.CaptureResponse(data, limit)
+}
+
+// fetchURL performs the actual HTTP request, adding extra headers
+func (f *Fetcher) fetchURL(ctx context.Context, feed config.Feed, extra http.Header) ([]byte, http.Header, error) {
+	start := time.Now()
+	resp, err := f.send(ctx, f.client, feed, extra)
+	if err != nil {
+		f.logAttempt(ctx, feed.Name, feed.URL, start, 0, 0, err)
+		return nil, nil, err
 	}
+	defer resp.Body.Close()
+
+	// Read response body
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		err = fmt.Errorf("%w: %w", errReadBody, err)
+		f.logAttempt(ctx, feed.Name, feed.URL, start, resp.StatusCode, len(data), err)
+		return nil, nil, err
+	}
+
+	f.logAttempt(ctx, feed.Name, feed.URL, start, resp.StatusCode, len(data), nil)
+	return data, resp.Header, nil
 }
 
-// fetchURL performs the actual HTTP request
-func (f *Fetcher) fetchURL(ctx context.Context, feed config.Feed) ([]byte, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", feed.URL, nil)
+// send issues feed's request with client, adding extra headers, and
+// returns the response of a 2xx status for the caller to read and close
+func (f *Fetcher) send(ctx context.Context, client *http.Client, feed config.Feed, extra http.Header) (*http.Response, error) {
+	var body io.Reader
+	payload, contentType := feed.RequestBody()
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, feed.HTTPMethod(), feed.URL, body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
+	// Custom headers may override the body's default Content-Type
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for key, values := range extra {
+		req.Header[key] = values
+	}
+
 	// Add custom headers
 	for key, value := range feed.Headers {
 		req.Header.Set(key, value)
@@ -163,27 +599,51 @@ func (f *Fetcher) fetchURL(ctx context.Context, feed config.Feed) ([]byte, error
 		req.Header.Set("User-Agent", "feedpulse/1.0")
 	}
 
-	resp, err := f.client.Do(req)
+	freshToken := false
+	if feed.Auth != nil {
+		if freshToken, err = f.authorize(ctx, req, feed); err != nil {
+			return nil, fmt.Errorf("auth failed: %w", err)
+		}
+	}
+
+	var jar *recordingJar
+	if feed.UsesCookies() && f.cookies != nil {
+		jar, err = f.jarFor(feed.Name)
+		if err != nil {
+			return nil, err
+		}
+		withJar := *client
+		withJar.Jar = jar
+		client = &withJar
+	}
+
+	f.meterRequest(ctx, feed.URL)
+	resp, err := client.Do(req)
+	if jar != nil {
+		f.saveJar(feed.Name, jar)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("HTTP request failed: %w", err)
 	}
-	defer resp.Body.Close()
+
+	// A cached token the API no longer accepts, e.g. revoked before it
+	// expired, is replaced and the request sent once more
+	if resp.StatusCode == http.StatusUnauthorized && feed.Auth != nil && feed.Auth.Type == config.AuthOAuth2 && !freshToken {
+		resp.Body.Close()
+		f.forgetToken(feed.Name, strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "))
+		return f.send(ctx, client, feed, extra)
+	}
 
 	// Check status code
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
 		return nil, &HTTPError{
 			StatusCode: resp.StatusCode,
 			Status:     resp.Status,
 		}
 	}
 
-	// Read response body
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	return data, nil
+	return resp, nil
 }
 
 // calculateBackoff calculates exponential backoff with jitter
@@ -198,6 +658,28 @@ func (f *Fetcher) calculateBackoff(attempt int) time.Duration {
 	return time.Duration(totalDelay) * time.Millisecond
 }
 
+// journalIDs wraps a journal entry ID, if any, for FetchResult.JournalIDs
+func journalIDs(id int64) []int64 {
+	if id == 0 {
+		return nil
+	}
+	return []int64{id}
+}
+
+// webSubLinks returns the WebSub hub a response advertised and the topic
+// URL the hub knows the feed by: the rel="self" link, or the URL it was
+// fetched from. Both are "" if there is no hub.
+func webSubLinks(feed config.Feed, header http.Header) (hub, topic string) {
+	hub = linkTarget(header, "hub")
+	if hub == "" {
+		return "", ""
+	}
+	if topic = linkTarget(header, "self"); topic == "" {
+		topic = feed.URL
+	}
+	return hub, topic
+}
+
 // HTTPError represents an HTTP error response
 type HTTPError struct {
 	StatusCode int
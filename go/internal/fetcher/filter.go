@@ -0,0 +1,140 @@
+package fetcher
+
+import (
+	"regexp"
+	"strings"
+
+	"feedpulse/internal/config"
+	"feedpulse/internal/storage"
+)
+
+// ItemFilters decides which parsed items are stored, from the global
+// filters and each feed's own
+type ItemFilters struct {
+	global *filterSet
+	feeds  map[string]*filterSet
+}
+
+// filterSet is a FiltersConfig with its expressions compiled
+type filterSet struct {
+	include []filterRule
+	exclude []filterRule
+}
+
+type filterRule struct {
+	field   string
+	keyword string
+	regex   *regexp.Regexp
+}
+
+// NewItemFilters compiles the filters of cfg. Rules whose expression
+// doesn't compile, which validation rejects, are left out.
+func NewItemFilters(cfg *config.Config) *ItemFilters {
+	fs := &ItemFilters{
+		global: newFilterSet(cfg.Settings.Filters),
+		feeds:  make(map[string]*filterSet),
+	}
+	for _, feed := range cfg.Feeds {
+		if set := newFilterSet(feed.Filters); set != nil {
+			fs.feeds[feed.Name] = set
+		}
+	}
+	return fs
+}
+
+func newFilterSet(fc *config.FiltersConfig) *filterSet {
+	if fc == nil || len(fc.Include)+len(fc.Exclude) == 0 {
+		return nil
+	}
+	return &filterSet{include: newFilterRules(fc.Include), exclude: newFilterRules(fc.Exclude)}
+}
+
+func newFilterRules(rules []config.FilterRule) []filterRule {
+	compiled := make([]filterRule, 0, len(rules))
+	for _, rule := range rules {
+		r := filterRule{field: rule.Field, keyword: strings.ToLower(rule.Keyword)}
+		if rule.Regex != "" {
+			re, err := regexp.Compile(rule.Regex)
+			if err != nil {
+				continue
+			}
+			r.regex = re
+		}
+		compiled = append(compiled, r)
+	}
+	return compiled
+}
+
+// Apply returns the items of feed that pass the global filters and the
+// feed's own, and how many were dropped
+func (fs *ItemFilters) Apply(feed string, items []storage.FeedItem) ([]storage.FeedItem, int) {
+	local := fs.feeds[feed]
+	if fs.global == nil && local == nil {
+		return items, 0
+	}
+
+	kept := make([]storage.FeedItem, 0, len(items))
+	for _, item := range items {
+		if fs.global.keeps(item) && local.keeps(item) {
+			kept = append(kept, item)
+		}
+	}
+	return kept, len(items) - len(kept)
+}
+
+// keeps reports whether item passes the set; a nil set passes everything
+func (s *filterSet) keeps(item storage.FeedItem) bool {
+	if s == nil {
+		return true
+	}
+	for _, rule := range s.exclude {
+		if rule.matches(item) {
+			return false
+		}
+	}
+	if len(s.include) == 0 {
+		return true
+	}
+	for _, rule := range s.include {
+		if rule.matches(item) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r filterRule) matches(item storage.FeedItem) bool {
+	if (r.field == "" || r.field == config.FilterTitle) && r.matchesText(item.Title) {
+		return true
+	}
+	if (r.field == "" || r.field == config.FilterURL) && r.matchesText(item.URL) {
+		return true
+	}
+	if r.field == "" || r.field == config.FilterTags {
+		for _, tag := range item.Tags {
+			if r.regex != nil && r.regex.MatchString(tag) || r.regex == nil && strings.ToLower(tag) == r.keyword {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r filterRule) matchesText(s string) bool {
+	if r.regex != nil {
+		return r.regex.MatchString(s)
+	}
+	return strings.Contains(strings.ToLower(s), r.keyword)
+}
+
+// filterResult drops the items of result its feed's filters reject,
+// counting them in result.Filtered
+func (f *Fetcher) filterResult(result *FetchResult) {
+	if len(result.Items) == 0 {
+		return
+	}
+	var dropped int
+	result.Items, dropped = f.filters.Apply(result.Source, result.Items)
+	result.Filtered += dropped
+	result.ItemsCount = len(result.Items)
+}
@@ -0,0 +1,81 @@
+package fetcher
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"feedpulse/internal/config"
+	"feedpulse/internal/sentry"
+)
+
+// hedgeAnswer is the outcome of one of a hedged fetch's requests
+type hedgeAnswer struct {
+	data   []byte
+	header http.Header
+	url    string
+	err    error
+}
+
+// fetchHedged is fetchURL for one attempt at a feed. It also returns the
+// URL that answered. For a feed with a hedge, a request that hasn't been
+// answered after the hedge delay gets a second one to the feed's next
+// endpoint, and the first success wins; the other request is cancelled.
+// A request that fails before the delay isn't hedged, the retries cover
+// it.
+func (f *Fetcher) fetchHedged(ctx context.Context, feed config.Feed, extra http.Header) ([]byte, http.Header, string, error) {
+	if feed.Hedge == nil {
+		data, header, err := f.fetchURL(ctx, feed, extra)
+		return data, header, feed.URL, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	answers := make(chan hedgeAnswer, 2)
+	attempt := func(u string) {
+		defer sentry.Recover()
+		sub := feed
+		sub.URL = u
+		data, header, err := f.fetchURL(ctx, sub, extra)
+		answers <- hedgeAnswer{data: data, header: header, url: u, err: err}
+	}
+	go attempt(feed.URL)
+
+	timer := time.NewTimer(feed.Hedge.Delay())
+	defer timer.Stop()
+	hedge := timer.C
+
+	var failed *hedgeAnswer
+	for pending := 1; pending > 0; {
+		select {
+		case <-hedge:
+			hedge = nil
+			pending++
+			go attempt(hedgeURL(feed))
+		case a := <-answers:
+			pending--
+			// A 304 is as good an answer as a body
+			if a.err == nil || isNotModified(a.err) {
+				return a.data, a.header, a.url, a.err
+			}
+			if failed == nil {
+				failed = &a
+			}
+			if hedge != nil {
+				return a.data, a.header, a.url, a.err
+			}
+		}
+	}
+	return failed.data, failed.header, failed.url, failed.err
+}
+
+// hedgeURL returns where a hedged request for feed goes: the endpoint
+// after its URL, or the URL itself for a feed without mirrors. Failover
+// orders each endpoint's mirrors to start after it.
+func hedgeURL(feed config.Feed) string {
+	if len(feed.Mirrors) == 0 {
+		return feed.URL
+	}
+	return feed.Mirrors[0]
+}
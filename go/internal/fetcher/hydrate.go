@@ -0,0 +1,136 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"feedpulse/internal/config"
+	"feedpulse/internal/sentry"
+	"feedpulse/internal/storage"
+)
+
+// hnDiscussionURL is the link a Hacker News ID list item gets from the
+// parser, ahead of its story ID; stories without a URL of their own (Ask
+// HN, polls) keep it
+const hnDiscussionURL = "https://news.ycombinator.com/item?id="
+
+// hnStory is the part of an item API answer hydration uses
+type hnStory struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Time    int64  `json:"time"`
+	Deleted bool   `json:"deleted"`
+	Dead    bool   `json:"dead"`
+}
+
+// hydrateHackerNews replaces the placeholder items of a Hacker News ID
+// list with their stories' titles, URLs and dates, keeping the item API's
+// answer (which has the score) as the raw data. IDs beyond the hydrate
+// limit are dropped like truncated items, and so are deleted and dead
+// stories. Items keep their IDs, so a story's item is the same whether or
+// not it was hydrated. Stories that fail to load are left out and
+// reported in Error; the fetch still succeeds.
+func (f *Fetcher) hydrateHackerNews(ctx context.Context, feed config.Feed, result *FetchResult) {
+	if feed.Hydrate == nil || !result.Success || len(result.Items) == 0 {
+		return
+	}
+
+	items := result.Items
+	if limit := feed.Hydrate.Limit(); len(items) > limit {
+		result.Truncated += len(items) - limit
+		items = items[:limit]
+	}
+
+	hydrated := make([]*storage.FeedItem, len(items))
+	failures := make([]error, len(items))
+	sem := make(chan struct{}, feed.Hydrate.Workers())
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		go func(index int, item storage.FeedItem) {
+			defer wg.Done()
+			defer sentry.Recover()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				failures[index] = ctx.Err()
+				return
+			}
+
+			hydrated[index], failures[index] = f.hydrateStory(ctx, feed, item)
+		}(i, item)
+	}
+	wg.Wait()
+
+	kept := make([]storage.FeedItem, 0, len(items))
+	failed := 0
+	var firstErr error
+	for i, item := range hydrated {
+		if failures[i] != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = failures[i]
+			}
+			continue
+		}
+		if item != nil {
+			kept = append(kept, *item)
+		}
+	}
+
+	result.Items = kept
+	result.ItemsCount = len(kept)
+	if failed > 0 {
+		msg := fmt.Sprintf("hydration failed for %d of %d stories: %v", failed, len(items), firstErr)
+		if result.Error != "" {
+			msg = result.Error + "; " + msg
+		}
+		result.Error = msg
+	}
+}
+
+// hydrateStory fetches the story behind a placeholder item. It returns a
+// nil item for stories that were deleted or flagged dead, and items that
+// aren't placeholders unchanged.
+func (f *Fetcher) hydrateStory(ctx context.Context, feed config.Feed, item storage.FeedItem) (*storage.FeedItem, error) {
+	id, ok := strings.CutPrefix(item.URL, hnDiscussionURL)
+	if !ok {
+		return &item, nil
+	}
+
+	// The item request shares the feed's headers and auth but none of its body
+	request := config.Feed{Name: feed.Name, URL: feed.Hydrate.StoryURL(id), Headers: feed.Headers, Auth: feed.Auth}
+	data, _, err := f.fetchURL(ctx, request, nil)
+	if err != nil {
+		return nil, fmt.Errorf("story %s: %w", id, err)
+	}
+
+	// The API answers null for IDs it doesn't know
+	var story *hnStory
+	if err := json.Unmarshal(data, &story); err != nil {
+		return nil, fmt.Errorf("story %s: invalid item JSON: %w", id, err)
+	}
+	if story == nil || story.Deleted || story.Dead {
+		return nil, nil
+	}
+
+	if story.Title != "" {
+		item.Title = story.Title
+	}
+	if story.URL != "" {
+		item.URL = story.URL
+	}
+	if story.Time > 0 {
+		ts := time.Unix(story.Time, 0).UTC().Format(time.RFC3339)
+		item.Timestamp = &ts
+	}
+	raw := string(data)
+	item.RawData = &raw
+	return &item, nil
+}
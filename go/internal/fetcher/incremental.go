@@ -0,0 +1,124 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"feedpulse/internal/config"
+)
+
+// Feed state keys used by incremental fetching
+const (
+	stateCursor  = "cursor"
+	stateNextURL = "next_url"
+)
+
+// fetchIncremental fetches feed from where the previous run stopped, using
+// the cursor stored in feed state, and sets result.State to the cursor for
+// the next run. Without a stored cursor the feed is fetched from its URL.
+func (f *Fetcher) fetchIncremental(ctx context.Context, feed config.Feed) FetchResult {
+	var prev map[string]string
+	if f.state != nil {
+		// An unreadable cursor only costs this run its incrementality
+		if st, err := f.state.GetFeedState(feed.Name); err == nil {
+			prev = st
+		}
+	}
+
+	reqURL, err := incrementalURL(feed, prev)
+	if err != nil {
+		return FetchResult{Source: feed.Name, Error: err.Error()}
+	}
+	// Continuing from a cursor fetches only what came after it
+	partial := reqURL != feed.URL
+	feed.URL = reqURL
+
+	result := f.fetchFeed(ctx, feed)
+	if result.Success {
+		result.State = nextFeedState(feed, prev, result)
+		result.Partial = partial
+	}
+	return result
+}
+
+// incrementalURL builds the request URL for feed given its stored state
+func incrementalURL(feed config.Feed, state map[string]string) (string, error) {
+	inc := feed.Incremental
+
+	if inc.LinkHeader {
+		if next := state[stateNextURL]; next != "" {
+			return next, nil
+		}
+		return feed.URL, nil
+	}
+
+	cursor := state[stateCursor]
+	if cursor == "" {
+		return feed.URL, nil
+	}
+
+	u, err := url.Parse(feed.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL '%s': %w", feed.URL, err)
+	}
+	q := u.Query()
+	q.Set(inc.CursorParam, cursor)
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// nextFeedState extracts the cursor for the next run from result. It
+// returns nil when the response carries no new cursor, so the stored one
+// is kept and the next run retries from the same position.
+func nextFeedState(feed config.Feed, prev map[string]string, result FetchResult) map[string]string {
+	inc := feed.Incremental
+
+	key, value := stateCursor, ""
+	if inc.LinkHeader {
+		key, value = stateNextURL, nextLink(result.header)
+	} else {
+		value = lookupString(result.payload, inc.CursorField)
+	}
+
+	if value == "" || value == prev[key] {
+		return nil
+	}
+	return map[string]string{key: value}
+}
+
+// nextLink returns the rel="next" target of an RFC 8288 Link header, as
+// sent by paginated APIs like GitHub's, or "" if there is none
+func nextLink(header http.Header) string {
+	return linkTarget(header, "next")
+}
+
+// linkTarget returns the target of the first RFC 8288 Link header entry
+// with relation rel, or "" if there is none
+func linkTarget(header http.Header, rel string) string {
+	for _, field := range header.Values("Link") {
+		for _, link := range strings.Split(field, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+
+			for _, param := range parts[1:] {
+				name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok || !strings.EqualFold(strings.TrimSpace(name), "rel") {
+					continue
+				}
+				for _, r := range strings.Fields(strings.Trim(value, `"`)) {
+					if strings.EqualFold(r, rel) {
+						return target[1 : len(target)-1]
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
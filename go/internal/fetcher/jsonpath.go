@@ -0,0 +1,62 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// lookupString follows a dot-separated path of object keys into a JSON
+// document and returns the string or number found there, or "" if the
+// path doesn't resolve
+func lookupString(data []byte, path string) string {
+	var node interface{}
+	if err := json.Unmarshal(data, &node); err != nil {
+		return ""
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		node = obj[key]
+	}
+
+	switch v := node.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+// hasPath reports whether a dot-separated path resolves to a non-null
+// value in node. A "*" segment matches every element of an array, and
+// requires the rest of the path to resolve in each of them.
+func hasPath(node interface{}, keys []string) bool {
+	if len(keys) == 0 {
+		return node != nil
+	}
+
+	if keys[0] == "*" {
+		arr, ok := node.([]interface{})
+		if !ok || len(arr) == 0 {
+			return false
+		}
+		for _, elem := range arr {
+			if !hasPath(elem, keys[1:]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	obj, ok := node.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	return hasPath(obj[keys[0]], keys[1:])
+}
@@ -0,0 +1,137 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"feedpulse/internal/config"
+	"feedpulse/internal/sentry"
+	"feedpulse/internal/storage"
+)
+
+// fetchMirrors fetches a feed that has mirrors. Under the failover
+// strategy endpoints are tried in order until one succeeds; under merge
+// all are fetched and their items combined without duplicates. Endpoint
+// records which endpoint(s) served the result.
+func (f *Fetcher) fetchMirrors(ctx context.Context, feed config.Feed) FetchResult {
+	endpoints := feed.Endpoints()
+	for i, u := range endpoints {
+		endpoints[i] = f.renderURL(feed.Name, u)
+	}
+
+	if feed.MirrorStrategy == config.MirrorMerge {
+		return f.mergeMirrors(ctx, feed, endpoints)
+	}
+	return f.failoverMirrors(ctx, feed, endpoints)
+}
+
+// failoverMirrors returns the first successful fetch among endpoints. Any
+// failures before it are reported in Error.
+func (f *Fetcher) failoverMirrors(ctx context.Context, feed config.Feed, endpoints []string) FetchResult {
+	start := time.Now()
+	var failures []string
+	var failure *storage.CapturedResponse
+
+	for i, u := range endpoints {
+		sub := feed
+		sub.URL = u
+		// The endpoints after u come first, so a hedged request goes to
+		// the next one
+		sub.Mirrors = append(append([]string{}, endpoints[i+1:]...), endpoints[:i]...)
+
+		result := f.fetchFeed(ctx, sub)
+		if result.Success {
+			result.DurationMs = time.Since(start).Milliseconds()
+			if len(failures) > 0 {
+				result.Error = strings.Join(failures, "; ")
+				result.Failure = failure
+			}
+			return result
+		}
+		failures = append(failures, fmt.Sprintf("%s: %s", u, result.Error))
+		if failure == nil {
+			failure = result.Failure
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return FetchResult{
+		Source:     feed.Name,
+		Error:      strings.Join(failures, "; "),
+		DurationMs: time.Since(start).Milliseconds(),
+		Failure:    failure,
+	}
+}
+
+// mergeMirrors fetches every endpoint concurrently and merges the items,
+// keeping the first copy of items served by more than one endpoint. It
+// succeeds if any endpoint does.
+func (f *Fetcher) mergeMirrors(ctx context.Context, feed config.Feed, endpoints []string) FetchResult {
+	start := time.Now()
+	results := make([]FetchResult, len(endpoints))
+
+	var wg sync.WaitGroup
+	for i, u := range endpoints {
+		wg.Add(1)
+		go func(index int, u string) {
+			defer wg.Done()
+			defer sentry.Recover()
+			sub := feed
+			sub.URL = u
+			results[index] = f.fetchFeed(ctx, sub)
+		}(i, u)
+	}
+	wg.Wait()
+
+	merged := FetchResult{Source: feed.Name}
+	seen := make(map[string]bool)
+	var served, failures []string
+
+	for i, result := range results {
+		if !result.Success {
+			failures = append(failures, fmt.Sprintf("%s: %s", endpoints[i], result.Error))
+			if merged.Failure == nil {
+				merged.Failure = result.Failure
+			}
+			continue
+		}
+
+		merged.Success = true
+		served = append(served, endpoints[i])
+		merged.JournalIDs = append(merged.JournalIDs, result.JournalIDs...)
+		merged.Truncated += result.Truncated
+		merged.Violations = append(merged.Violations, result.Violations...)
+		merged.schema = mergeSchema(merged.schema, result.schema)
+		if result.Backoff > merged.Backoff {
+			merged.Backoff = result.Backoff
+		}
+		merged.Items = appendUnique(merged.Items, result.Items, seen)
+	}
+
+	merged.ItemsCount = len(merged.Items)
+	merged.DurationMs = time.Since(start).Milliseconds()
+	merged.Endpoint = strings.Join(served, ", ")
+	if len(failures) > 0 {
+		merged.Error = strings.Join(failures, "; ")
+	}
+
+	return merged
+}
+
+// appendUnique appends the items whose IDs are not yet in seen
+func appendUnique(dst, items []storage.FeedItem, seen map[string]bool) []storage.FeedItem {
+	for _, item := range items {
+		if seen[item.ID] {
+			continue
+		}
+		seen[item.ID] = true
+		dst = append(dst, item)
+	}
+	return dst
+}
@@ -0,0 +1,115 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"feedpulse/internal/config"
+	"feedpulse/internal/parser"
+	"feedpulse/internal/storage"
+)
+
+// Probe is what a single request for a feed returned, for inspecting a
+// feed definition without running a fetch
+type Probe struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Status     string
+	Header     http.Header
+	Bytes      int
+	DurationMs int64
+
+	// Structure is the payload layout the parser detected
+	Structure string
+	Items     []storage.FeedItem
+	Truncated int
+	// Filtered is how many parsed items the filters would drop; they
+	// aren't in Items
+	Filtered int
+
+	// Warnings holds parse errors, failed assertions and anything else
+	// that would make a real fetch degraded or surprising
+	Warnings []string
+}
+
+// Probe requests feed once, without retries, and parses the response.
+// Nothing is journaled or saved. The returned Probe carries whatever was
+// learned before an error, e.g. the status of a non-2xx response.
+func (f *Fetcher) Probe(ctx context.Context, feed config.Feed) (*Probe, error) {
+	if feed.IsStream() {
+		return nil, fmt.Errorf("%s is a stream feed, consumed by feedpulse serve", feed.Name)
+	}
+
+	probe := &Probe{Method: feed.HTTPMethod()}
+
+	urls := feed.ExpandURLs()
+	if len(feed.Mirrors) > 0 {
+		urls = feed.Endpoints()
+	}
+	if len(urls) > 1 {
+		probe.Warnings = append(probe.Warnings, fmt.Sprintf("feed expands to %d URLs, only the first was requested", len(urls)))
+	}
+	feed.URL = f.renderURL(feed.Name, urls[0])
+	probe.URL = feed.URL
+
+	start := f.clock.Now()
+	resp, err := f.send(ctx, f.client, feed, nil)
+	if err != nil {
+		probe.DurationMs = f.clock.Now().Sub(start).Milliseconds()
+		if httpErr, ok := err.(*HTTPError); ok {
+			probe.StatusCode = httpErr.StatusCode
+			probe.Status = httpErr.Status
+		}
+		return probe, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	probe.DurationMs = f.clock.Now().Sub(start).Milliseconds()
+	probe.StatusCode = resp.StatusCode
+	probe.Status = resp.Status
+	probe.Header = resp.Header
+	probe.Bytes = len(data)
+	if err != nil {
+		return probe, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if data, err = Unwrap(feed, data); err != nil {
+		return probe, err
+	}
+	if feed.FeedType == "json" {
+		data = parser.TrimJSONPrefix(data)
+	}
+
+	probe.Structure = parser.DetectStructure(feed.FeedType, data)
+
+	f.parser.SetUniquenessScope(f.config.Settings.UniquenessScope, f.clock.Now().UTC().Format(time.RFC3339Nano))
+	parsed := f.parser.Parse(feed.Name, feed.FeedType, data)
+	probe.Items = parsed.Items
+	probe.Truncated = parsed.Truncated
+	probe.Warnings = append(probe.Warnings, parsed.Errors...)
+
+	result := FetchResult{
+		Source:     feed.Name,
+		Success:    true,
+		ItemsCount: len(parsed.Items),
+		Items:      parsed.Items,
+		Filtered:   parsed.Dropped,
+		Violations: checkResponse(feed, data),
+	}
+	f.checkResult(feed, &result)
+	probe.Warnings = append(probe.Warnings, result.Violations...)
+	f.filterResult(&result)
+	probe.Items = result.Items
+	probe.Filtered = result.Filtered
+
+	if backoff := responseBackoff(feed, data, f.clock.Now()); backoff > 0 {
+		probe.Warnings = append(probe.Warnings, fmt.Sprintf("the API asked to back off for %s", backoff.Round(time.Second)))
+	}
+
+	return probe, nil
+}
@@ -0,0 +1,247 @@
+package fetcher
+
+import (
+	"context"
+	"time"
+
+	"feedpulse/internal/config"
+)
+
+// RetryBase is how long a daemon waits before fetching a feed again after
+// its first failure; each further failure doubles it, up to the feed's
+// refresh interval
+const RetryBase = time.Minute
+
+// Schedule decides when each feed is next due in daemon mode: every
+// refresh_interval_secs (or a default for feeds without one), or sooner
+// after a failure. Adaptive feeds move their interval within their bounds
+// as fetches bring new items or don't. Stream feeds are never due; serve
+// consumes them. Disabled feeds are never due either.
+type Schedule struct {
+	order    []string
+	interval map[string]time.Duration
+	// configured is each feed's interval as the config sets it, before
+	// adapting
+	configured map[string]time.Duration
+	bounds     map[string][2]time.Duration
+	next       map[string]time.Time
+	failures   map[string]int
+}
+
+// How far one fetch moves an adaptive interval: it shrinks while fetches
+// bring more than one new item, aiming for about one per fetch, and grows
+// more gently while they bring none
+const (
+	adaptShrink = 0.5
+	adaptGrow   = 1.5
+)
+
+// NewSchedule creates a schedule with every feed due immediately
+func NewSchedule(cfg *config.Config, defaultInterval time.Duration) *Schedule {
+	s := &Schedule{
+		interval:   make(map[string]time.Duration),
+		configured: make(map[string]time.Duration),
+		bounds:     make(map[string][2]time.Duration),
+		next:       make(map[string]time.Time),
+		failures:   make(map[string]int),
+	}
+	for _, feed := range cfg.Feeds {
+		if feed.IsStream() || !feed.IsEnabled() {
+			continue
+		}
+		interval := defaultInterval
+		if feed.RefreshIntervalSecs > 0 {
+			interval = time.Duration(feed.RefreshIntervalSecs) * time.Second
+		}
+		s.order = append(s.order, feed.Name)
+		s.interval[feed.Name] = interval
+		s.configured[feed.Name] = interval
+		if feed.Adaptive != nil {
+			shortest, longest := feed.Adaptive.Bounds()
+			s.bounds[feed.Name] = [2]time.Duration{shortest, longest}
+			s.interval[feed.Name] = clampDuration(interval, shortest, longest)
+		}
+	}
+	return s
+}
+
+// ScheduleChanges lists the feeds a reload added, removed, or gave a new
+// interval or adaptive bounds
+type ScheduleChanges struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// Empty reports whether a reload changed nothing
+func (c ScheduleChanges) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Changed) == 0
+}
+
+// Reload replaces the scheduled feeds with cfg's, as of now. New feeds
+// are due immediately and removed ones are dropped. Feeds that stay keep
+// their next fetch, failures and adapted interval, unless their interval
+// or adaptive bounds changed: then the new interval counts from their
+// last fetch, and a pending retry waits no longer than it.
+func (s *Schedule) Reload(cfg *config.Config, defaultInterval time.Duration, now time.Time) ScheduleChanges {
+	fresh := NewSchedule(cfg, defaultInterval)
+	var changes ScheduleChanges
+
+	for _, name := range s.order {
+		if _, ok := fresh.interval[name]; !ok {
+			changes.Removed = append(changes.Removed, name)
+		}
+	}
+	for _, name := range fresh.order {
+		if _, ok := s.interval[name]; !ok {
+			changes.Added = append(changes.Added, name)
+			continue
+		}
+		if fresh.configured[name] != s.configured[name] || fresh.bounds[name] != s.bounds[name] {
+			changes.Changed = append(changes.Changed, name)
+			switch {
+			case s.next[name].IsZero():
+				// Never fetched, so still due
+			case s.failures[name] > 0:
+				// A pending retry keeps its time, within the new interval
+				fresh.failures[name] = s.failures[name]
+				fresh.next[name] = s.next[name]
+				if limit := now.Add(fresh.interval[name]); fresh.next[name].After(limit) {
+					fresh.next[name] = limit
+				}
+			default:
+				last := s.next[name].Add(-s.interval[name])
+				fresh.next[name] = last.Add(fresh.interval[name])
+			}
+			continue
+		}
+		fresh.interval[name] = s.interval[name]
+		fresh.next[name] = s.next[name]
+		fresh.failures[name] = s.failures[name]
+	}
+
+	*s = *fresh
+	return changes
+}
+
+// Feeds returns the scheduled feeds, in config order
+func (s *Schedule) Feeds() []string {
+	return s.order
+}
+
+// Seed makes feed due one interval after its last successful fetch, so a
+// restarted daemon doesn't fetch every feed at once
+func (s *Schedule) Seed(feed string, lastSuccess time.Time) {
+	if _, ok := s.interval[feed]; ok {
+		s.next[feed] = lastSuccess.Add(s.interval[feed])
+	}
+}
+
+// Due returns the feeds due at now, in config order
+func (s *Schedule) Due(now time.Time) []string {
+	var due []string
+	for _, name := range s.order {
+		if !now.Before(s.next[name]) {
+			due = append(due, name)
+		}
+	}
+	return due
+}
+
+// Next returns when the next feed is due; a feed never fetched is due at
+// the zero time
+func (s *Schedule) Next() time.Time {
+	var next time.Time
+	for i, name := range s.order {
+		if i == 0 || s.next[name].Before(next) {
+			next = s.next[name]
+		}
+	}
+	return next
+}
+
+// Record schedules feed's next fetch after one finished at now, and
+// returns when that is. Failures back off from RetryBase, doubling up to
+// the feed's interval; a success resets them.
+func (s *Schedule) Record(feed string, ok bool, now time.Time) time.Time {
+	interval := s.interval[feed]
+	wait := interval
+	if ok {
+		s.failures[feed] = 0
+	} else {
+		s.failures[feed]++
+		wait = RetryBase
+		for i := 1; i < s.failures[feed] && wait < interval; i++ {
+			wait *= 2
+		}
+		if wait > interval {
+			wait = interval
+		}
+	}
+	s.next[feed] = now.Add(wait)
+	return s.next[feed]
+}
+
+// Adapt moves an adaptive feed's interval after a successful fetch that
+// stored newItems new items, and returns the interval with whether it
+// changed. Other feeds keep their interval.
+func (s *Schedule) Adapt(feed string, newItems int) (time.Duration, bool) {
+	interval := s.interval[feed]
+	bounds, ok := s.bounds[feed]
+	if !ok {
+		return interval, false
+	}
+
+	adapted := interval
+	switch {
+	case newItems == 0:
+		adapted = time.Duration(float64(interval) * adaptGrow)
+	case newItems > 1:
+		adapted = time.Duration(float64(interval) * adaptShrink)
+	}
+	adapted = clampDuration(adapted.Round(time.Second), bounds[0], bounds[1])
+	s.interval[feed] = adapted
+	return adapted, adapted != interval
+}
+
+// clampDuration limits d to [lo, hi]
+func clampDuration(d, lo, hi time.Duration) time.Duration {
+	if d < lo {
+		return lo
+	}
+	if d > hi {
+		return hi
+	}
+	return d
+}
+
+// FetchDue fetches the named feeds as one run, stage by stage like
+// FetchStages, so a feed due together with the feed it depends on sees
+// what that feed saved
+func (f *Fetcher) FetchDue(ctx context.Context, names []string, done StageFunc) []FetchResult {
+	due := make(map[string]bool, len(names))
+	for _, name := range names {
+		due[name] = true
+	}
+
+	f.parser.SetUniquenessScope(f.config.Settings.UniquenessScope, f.clock.Now().UTC().Format(time.RFC3339Nano))
+
+	var all []FetchResult
+	for _, stage := range f.config.FeedStages() {
+		var feeds []config.Feed
+		for _, feed := range stage {
+			if due[feed.Name] {
+				feeds = append(feeds, feed)
+			}
+		}
+		if len(feeds) == 0 {
+			continue
+		}
+		results := f.fetchConcurrently(ctx, feeds)
+		if done != nil {
+			done(results)
+		}
+		all = append(all, results...)
+	}
+	return all
+}
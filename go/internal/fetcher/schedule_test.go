@@ -0,0 +1,184 @@
+package fetcher
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"feedpulse/internal/clock"
+	"feedpulse/internal/config"
+)
+
+func newTestSchedule() *Schedule {
+	cfg := &config.Config{Feeds: []config.Feed{
+		{Name: "Fast", RefreshIntervalSecs: 300},
+		{Name: "Slow", RefreshIntervalSecs: 600},
+		{Name: "Default"},
+		{Name: "Stream", Mode: config.ModeStream},
+		{Name: "Off", RefreshIntervalSecs: 300, Disabled: true},
+		{Name: "Adaptive", RefreshIntervalSecs: 600, Adaptive: &config.AdaptiveConfig{MinInterval: "5m", MaxInterval: "20m"}},
+		{Name: "Capped", RefreshIntervalSecs: 7200, Adaptive: &config.AdaptiveConfig{MinInterval: "5m", MaxInterval: "1h"}},
+	}}
+	return NewSchedule(cfg, 15*time.Minute)
+}
+
+func TestSchedule_Record(t *testing.T) {
+	clk := clock.Fixed(time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC))
+	s := newTestSchedule()
+
+	// Failures back off from RetryBase, doubling up to the interval; a
+	// success resets them
+	steps := []struct {
+		ok   bool
+		want time.Duration
+	}{
+		{false, time.Minute},
+		{false, 2 * time.Minute},
+		{false, 4 * time.Minute},
+		{false, 8 * time.Minute},
+		{false, 10 * time.Minute},
+		{false, 10 * time.Minute},
+		{true, 10 * time.Minute},
+		{false, time.Minute},
+		{true, 10 * time.Minute},
+	}
+	for i, step := range steps {
+		now := clk.Now().Add(time.Duration(i) * time.Hour)
+		if next := s.Record("Slow", step.ok, now); next.Sub(now) != step.want {
+			t.Errorf("step %d (ok=%v): next fetch in %v, want %v", i, step.ok, next.Sub(now), step.want)
+		}
+	}
+
+	// A failing feed whose interval is shorter than the backoff retries
+	// within its interval
+	now := clk.Now()
+	for i := 0; i < 5; i++ {
+		s.Record("Fast", false, now)
+	}
+	if next := s.Record("Fast", false, now); next.Sub(now) != 5*time.Minute {
+		t.Errorf("expected retries capped at the 5m interval, got %v", next.Sub(now))
+	}
+}
+
+func TestSchedule_Due(t *testing.T) {
+	clk := clock.Fixed(time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC))
+	now := clk.Now()
+	s := newTestSchedule()
+
+	if want := []string{"Fast", "Slow", "Default", "Adaptive", "Capped"}; !reflect.DeepEqual(s.Feeds(), want) {
+		t.Fatalf("Feeds() = %v, want %v", s.Feeds(), want)
+	}
+	if !s.Next().IsZero() {
+		t.Errorf("expected feeds never fetched due at the zero time, got %v", s.Next())
+	}
+	for _, name := range s.Feeds() {
+		s.Record(name, true, now)
+	}
+
+	tests := []struct {
+		after time.Duration
+		want  []string
+	}{
+		{0, nil},
+		{5*time.Minute - time.Second, nil},
+		{5 * time.Minute, []string{"Fast"}},
+		{10 * time.Minute, []string{"Fast", "Slow", "Adaptive"}},
+		{15 * time.Minute, []string{"Fast", "Slow", "Default", "Adaptive"}},
+		{time.Hour, []string{"Fast", "Slow", "Default", "Adaptive", "Capped"}},
+	}
+	for _, tt := range tests {
+		if due := s.Due(now.Add(tt.after)); !reflect.DeepEqual(due, tt.want) {
+			t.Errorf("Due(+%v) = %v, want %v", tt.after, due, tt.want)
+		}
+	}
+	if want := now.Add(5 * time.Minute); !s.Next().Equal(want) {
+		t.Errorf("Next() = %v, want %v", s.Next(), want)
+	}
+}
+
+func TestSchedule_Seed(t *testing.T) {
+	clk := clock.Fixed(time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC))
+	now := clk.Now()
+
+	tests := []struct {
+		name        string
+		feed        string
+		lastSuccess time.Time
+		dueAfter    time.Duration
+	}{
+		{"recent success", "Slow", now.Add(-2 * time.Minute), 8 * time.Minute},
+		{"old success", "Slow", now.Add(-24 * time.Hour), 0},
+		{"default interval", "Default", now.Add(-5 * time.Minute), 10 * time.Minute},
+		{"clamped adaptive interval", "Capped", now.Add(-10 * time.Minute), 50 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestSchedule()
+			s.Seed(tt.feed, tt.lastSuccess)
+			if tt.dueAfter > 0 && contains(s.Due(now.Add(tt.dueAfter-time.Second)), tt.feed) {
+				t.Errorf("%s due before %v", tt.feed, tt.dueAfter)
+			}
+			if !contains(s.Due(now.Add(tt.dueAfter)), tt.feed) {
+				t.Errorf("%s not due after %v", tt.feed, tt.dueAfter)
+			}
+		})
+	}
+
+	// Feeds that aren't scheduled, like streams, stay unscheduled
+	s := newTestSchedule()
+	s.Seed("Stream", now)
+	s.Seed("Unknown", now)
+	if contains(s.Due(now.Add(time.Hour)), "Stream") || contains(s.Due(now.Add(time.Hour)), "Unknown") {
+		t.Error("expected seeding to leave unscheduled feeds out")
+	}
+}
+
+func TestSchedule_Adapt(t *testing.T) {
+	clk := clock.Fixed(time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name     string
+		feed     string
+		newItems []int
+		want     time.Duration
+		changed  bool
+	}{
+		{"shrinks with several new items", "Adaptive", []int{3}, 5 * time.Minute, true},
+		{"keeps its interval with one new item", "Adaptive", []int{1}, 10 * time.Minute, false},
+		{"grows without new items", "Adaptive", []int{0}, 15 * time.Minute, true},
+		{"stops at the shortest interval", "Adaptive", []int{5, 5}, 5 * time.Minute, false},
+		{"stops at the longest interval", "Adaptive", []int{0, 0}, 20 * time.Minute, true},
+		{"stays at the longest interval", "Adaptive", []int{0, 0, 0}, 20 * time.Minute, false},
+		{"starts within its bounds", "Capped", []int{0}, time.Hour, false},
+		{"rounds to the second", "Adaptive", []int{0, 2, 0, 2}, 5*time.Minute + 38*time.Second, true},
+		{"isn't adaptive", "Slow", []int{0, 0}, 10 * time.Minute, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestSchedule()
+			var interval time.Duration
+			var changed bool
+			for _, n := range tt.newItems {
+				interval, changed = s.Adapt(tt.feed, n)
+			}
+			if interval != tt.want || changed != tt.changed {
+				t.Errorf("Adapt() = %v, %v, want %v, %v", interval, changed, tt.want, tt.changed)
+			}
+
+			// The next fetch is one adapted interval away
+			now := clk.Now()
+			if next := s.Record(tt.feed, true, now); next.Sub(now) != tt.want {
+				t.Errorf("next fetch in %v, want %v", next.Sub(now), tt.want)
+			}
+		})
+	}
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
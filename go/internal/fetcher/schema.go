@@ -0,0 +1,177 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"feedpulse/internal/config"
+)
+
+// stateSchema is the feed state key holding a feed's last JSON shape,
+// stored as newline-separated key paths
+const stateSchema = "schema"
+
+// Bounds on how much of a document schemaPaths walks, so feeds keyed by
+// data (e.g. objects keyed by ID) can't produce unbounded fingerprints
+const (
+	maxSchemaDepth    = 8
+	maxSchemaPaths    = 500
+	maxSchemaElements = 20
+)
+
+// SchemaDrift describes how a feed's JSON structure changed since the
+// previous run
+type SchemaDrift struct {
+	Added    []string
+	Removed  []string
+	Distance float64 // share of key paths not common to both runs, 0-1
+}
+
+// responseSchema fingerprints a JSON feed's response; other feed types
+// have no schema to track
+func responseSchema(feed config.Feed, payload []byte) []string {
+	if feed.FeedType != "json" {
+		return nil
+	}
+	return schemaPaths(payload)
+}
+
+// schemaPaths returns the sorted key paths of a JSON document, with array
+// elements collapsed into "*" (e.g. "data.children.*.data.title"), or nil
+// if payload isn't JSON
+func schemaPaths(payload []byte) []string {
+	var doc interface{}
+	if err := json.Unmarshal(payload, &doc); err != nil {
+		return nil
+	}
+
+	set := make(map[string]bool)
+	walkSchema(doc, "", 0, set)
+
+	paths := make([]string, 0, len(set))
+	for p := range set {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// walkSchema adds the key paths under node to set
+func walkSchema(node interface{}, prefix string, depth int, set map[string]bool) {
+	if depth >= maxSchemaDepth {
+		return
+	}
+
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if len(set) >= maxSchemaPaths {
+				return
+			}
+			path := joinPath(prefix, key)
+			set[path] = true
+			walkSchema(child, path, depth+1, set)
+		}
+	case []interface{}:
+		path := joinPath(prefix, "*")
+		for i, elem := range v {
+			if i >= maxSchemaElements {
+				break
+			}
+			walkSchema(elem, path, depth+1, set)
+		}
+	}
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// mergeSchema returns the sorted union of two path lists
+func mergeSchema(a, b []string) []string {
+	if len(a) == 0 {
+		return b
+	}
+
+	set := make(map[string]bool, len(a)+len(b))
+	for _, p := range a {
+		set[p] = true
+	}
+	for _, p := range b {
+		set[p] = true
+	}
+
+	merged := make([]string, 0, len(set))
+	for p := range set {
+		merged = append(merged, p)
+	}
+	sort.Strings(merged)
+	return merged
+}
+
+// compareSchema reports how paths differ from the previous fingerprint
+func compareSchema(previous, paths []string) SchemaDrift {
+	prev := make(map[string]bool, len(previous))
+	for _, p := range previous {
+		prev[p] = true
+	}
+	cur := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		cur[p] = true
+	}
+
+	var drift SchemaDrift
+	for _, p := range paths {
+		if !prev[p] {
+			drift.Added = append(drift.Added, p)
+		}
+	}
+	for _, p := range previous {
+		if !cur[p] {
+			drift.Removed = append(drift.Removed, p)
+		}
+	}
+
+	union := len(prev) + len(drift.Added)
+	if union > 0 {
+		drift.Distance = float64(len(drift.Added)+len(drift.Removed)) / float64(union)
+	}
+	return drift
+}
+
+// checkSchema compares a successful JSON result's shape with the one
+// stored by the previous run. A change beyond the drift threshold is
+// reported in result.SchemaDrift; any change is stored in result.State.
+func (f *Fetcher) checkSchema(feed config.Feed, result *FetchResult) {
+	if f.state == nil || !result.Success || feed.FeedType != "json" || len(result.schema) == 0 {
+		return
+	}
+
+	stored, err := f.state.GetFeedState(feed.Name)
+	if err != nil {
+		return
+	}
+
+	fingerprint := strings.Join(result.schema, "\n")
+	if stored[stateSchema] == fingerprint {
+		return
+	}
+
+	if previous := stored[stateSchema]; previous != "" {
+		drift := compareSchema(strings.Split(previous, "\n"), result.schema)
+		if drift.Distance > f.config.Settings.SchemaDriftThreshold {
+			result.SchemaDrift = &drift
+		}
+	}
+
+	state := make(map[string]string, len(result.State)+1)
+	for k, v := range result.State {
+		state[k] = v
+	}
+	state[stateSchema] = fingerprint
+	result.State = state
+}
@@ -0,0 +1,206 @@
+package fetcher
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"feedpulse/internal/config"
+	"feedpulse/internal/parser"
+)
+
+const (
+	// maxStreamBackoff caps the delay between reconnect attempts
+	maxStreamBackoff = 5 * time.Minute
+
+	// maxStreamEvent bounds a single event or NDJSON line
+	maxStreamEvent = 4 * 1024 * 1024
+)
+
+// StreamFunc receives a result for each stream event that produced items,
+// and a failed result for each dropped connection
+type StreamFunc func(result FetchResult)
+
+// stream is the state of one stream feed across reconnects
+type stream struct {
+	feed        config.Feed
+	parser      *parser.Parser
+	emit        StreamFunc
+	lastEventID string
+	retry       time.Duration
+}
+
+// Stream consumes a stream feed until ctx is cancelled. It keeps a
+// connection open and turns every event into items as it arrives:
+// server-sent events (text/event-stream) carry a JSON document in each
+// event's data, and any other response is read as NDJSON, one document
+// per line. Documents map like NDJSON feed records. Dropped connections
+// are reopened with exponential backoff, which resets once a connection
+// delivers events; SSE streams resume from the last event ID and honor
+// the server's retry interval.
+func (f *Fetcher) Stream(ctx context.Context, feed config.Feed, emit StreamFunc) error {
+	if !feed.IsStream() {
+		return fmt.Errorf("feed '%s' is not a stream feed", feed.Name)
+	}
+	unredacted := emit
+	emit = func(result FetchResult) {
+		f.filterResult(&result)
+		f.redactResult(&result)
+		unredacted(result)
+	}
+
+	// Events are parsed concurrently with other fetches, so the stream
+	// gets a parser of its own
+	p := NewParser(f.config)
+	p.SetUniquenessScope(f.config.Settings.UniquenessScope, f.clock.Now().UTC().Format(time.RFC3339Nano))
+
+	// The connection is meant to stay open, so only connecting is bounded
+	client := *f.client
+	client.Timeout = 0
+
+	s := &stream{feed: feed, parser: p, emit: emit}
+	attempt := 0
+	for {
+		delivered, err := f.readStream(ctx, &client, s)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if delivered {
+			attempt = 0
+		}
+		attempt++
+
+		delay := f.calculateBackoff(attempt)
+		if s.retry > 0 && attempt == 1 {
+			delay = s.retry
+		}
+		if delay > maxStreamBackoff {
+			delay = maxStreamBackoff
+		}
+
+		if err != nil {
+			emit(FetchResult{
+				Source:   feed.Name,
+				Error:    fmt.Sprintf("stream disconnected: %v (reconnecting in %s)", err, delay.Round(time.Millisecond)),
+				Endpoint: feed.URL,
+			})
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// readStream reads events from one connection until it ends. It reports
+// whether any event was delivered, and the error that ended the
+// connection (nil if the server closed it cleanly).
+func (f *Fetcher) readStream(ctx context.Context, client *http.Client, s *stream) (bool, error) {
+	extra := http.Header{}
+	extra.Set("Accept", "text/event-stream, application/x-ndjson")
+	extra.Set("Cache-Control", "no-cache")
+	if s.lastEventID != "" {
+		extra.Set("Last-Event-ID", s.lastEventID)
+	}
+
+	resp, err := f.send(ctx, client, s.feed, extra)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamEvent)
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	var delivered bool
+	if mediaType == "text/event-stream" {
+		delivered = s.readEvents(scanner)
+	} else {
+		for scanner.Scan() {
+			if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+				delivered = s.event(line) || delivered
+			}
+		}
+	}
+
+	err = scanner.Err()
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	return delivered, err
+}
+
+// readEvents dispatches the server-sent events read by scanner
+func (s *stream) readEvents(scanner *bufio.Scanner) bool {
+	var delivered bool
+	var data []string
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			// A blank line ends the event
+			if len(data) > 0 {
+				delivered = s.event([]byte(strings.Join(data, "\n"))) || delivered
+				data = nil
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // comment, often a keep-alive
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "data":
+			data = append(data, value)
+		case "id":
+			s.lastEventID = value
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+				s.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+
+	return delivered
+}
+
+// event parses one JSON document from the stream and emits its items,
+// reporting whether there were any
+func (s *stream) event(doc []byte) bool {
+	// Records are parsed one per line, so a pretty-printed event is
+	// compacted first; anything that isn't JSON fails in the parser
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, doc); err == nil {
+		doc = compact.Bytes()
+	}
+
+	result := s.parser.Parse(s.feed.Name, "ndjson", doc)
+	if len(result.Items) == 0 {
+		return false
+	}
+
+	s.emit(FetchResult{
+		Source:     s.feed.Name,
+		Success:    true,
+		ItemsCount: len(result.Items),
+		Items:      result.Items,
+		Endpoint:   s.feed.URL,
+		Truncated:  result.Truncated,
+		Filtered:   result.Dropped,
+	})
+	return true
+}
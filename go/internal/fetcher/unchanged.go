@@ -0,0 +1,85 @@
+package fetcher
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+
+	"feedpulse/internal/config"
+)
+
+// Feed state keys describing the last response that was parsed and saved:
+// its hash, and the validators its server sent, which later requests send
+// back as If-None-Match and If-Modified-Since
+const (
+	statePayloadHash  = "payload_hash"
+	stateETag         = "etag"
+	stateLastModified = "last_modified"
+)
+
+// savedResponse is what is known about the last saved response
+type savedResponse struct {
+	hash         string
+	etag         string
+	lastModified string
+}
+
+// conditional returns the headers making a request conditional on the
+// saved response having changed, or nil without validators
+func (r savedResponse) conditional() http.Header {
+	if r.etag == "" && r.lastModified == "" {
+		return nil
+	}
+	header := make(http.Header)
+	if r.etag != "" {
+		header.Set("If-None-Match", r.etag)
+	}
+	if r.lastModified != "" {
+		header.Set("If-Modified-Since", r.lastModified)
+	}
+	return header
+}
+
+// isNotModified reports whether err is a 304 answer to a conditional request
+func isNotModified(err error) bool {
+	var httpErr *HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotModified
+}
+
+// payloadHash returns the hex SHA-256 of a raw response
+func payloadHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// fetchUnlessUnchanged fetches a single-URL feed, but skips journaling,
+// parsing and saving if the server answers the conditional request with
+// 304 Not Modified, or the response is byte for byte the one the previous
+// run saved. The hash and validators are only recorded in the result's
+// state, so they take effect once the caller has stored the items.
+func (f *Fetcher) fetchUnlessUnchanged(ctx context.Context, feed config.Feed) FetchResult {
+	var previous savedResponse
+	if f.state != nil {
+		if st, err := f.state.GetFeedState(feed.Name); err == nil {
+			previous = savedResponse{hash: st[statePayloadHash], etag: st[stateETag], lastModified: st[stateLastModified]}
+		}
+	}
+
+	result := f.fetchFeedUnless(ctx, feed, previous)
+	if !result.Success || result.Unchanged || result.payloadHash == "" {
+		return result
+	}
+
+	// Empty validators replace ones the server no longer sends
+	state := make(map[string]string, len(result.State)+3)
+	for k, v := range result.State {
+		state[k] = v
+	}
+	state[statePayloadHash] = result.payloadHash
+	state[stateETag] = result.header.Get("ETag")
+	state[stateLastModified] = result.header.Get("Last-Modified")
+	result.State = state
+	return result
+}
@@ -0,0 +1,29 @@
+package fetcher
+
+import (
+	"fmt"
+
+	"feedpulse/internal/config"
+	"feedpulse/internal/parser"
+)
+
+// Unwrap applies the feed's unwrap rules to a raw response, returning the
+// JSON document inside it. Feeds without rules get data back unchanged.
+func Unwrap(feed config.Feed, data []byte) ([]byte, error) {
+	if feed.Unwrap == nil {
+		return data, nil
+	}
+
+	var err error
+	if feed.Unwrap.StripJSONP {
+		if data, err = parser.StripJSONP(data); err != nil {
+			return nil, fmt.Errorf("failed to unwrap response: %w", err)
+		}
+	}
+	if field := feed.Unwrap.JSONStringField; field != "" {
+		if data, err = parser.UnwrapStringField(data, field); err != nil {
+			return nil, fmt.Errorf("failed to unwrap response: %w", err)
+		}
+	}
+	return data, nil
+}
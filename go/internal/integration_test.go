@@ -5,16 +5,24 @@
 package internal
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"feedpulse/internal/clock"
 	"feedpulse/internal/config"
+	"feedpulse/internal/fetcher"
 	"feedpulse/internal/parser"
 	"feedpulse/internal/storage"
 )
@@ -558,3 +566,1610 @@ func TestIntegration_EmptyResponses(t *testing.T) {
 		t.Errorf("Expected 3 fetch stats, got %d", len(stats))
 	}
 }
+
+// TestIntegration_BackfillResumesFromCursor tests walking a cursor-paginated
+// API across two backfill runs
+func TestIntegration_BackfillResumesFromCursor(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	pages := map[string]string{
+		"":   `{"data":{"after":"p2","children":[{"data":{"title":"One","url":"https://example.com/1"}}]}}`,
+		"p2": `{"data":{"after":"p3","children":[{"data":{"title":"Two","url":"https://example.com/2"}}]}}`,
+		"p3": `{"data":{"after":null,"children":[{"data":{"title":"Three","url":"https://example.com/3"}}]}}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(pages[r.URL.Query().Get("after")]))
+	}))
+	defer server.Close()
+
+	db, err := storage.NewStorage(filepath.Join(t.TempDir(), "backfill.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{
+		Settings: config.Settings{MaxConcurrency: 1, DefaultTimeoutSecs: 5, RetryMax: 0},
+		Feeds: []config.Feed{{
+			Name:     "Reddit",
+			URL:      server.URL + "/r/golang.json",
+			FeedType: "json",
+			Backfill: &config.BackfillConfig{CursorParam: "after", CursorField: "data.after", DelayMs: 1},
+		}},
+	}
+	feed := cfg.Feeds[0]
+	f := fetcher.NewFetcher(cfg)
+
+	save := func(result fetcher.FetchResult, next storage.BackfillCursor) error {
+		_, err := db.SaveBackfillPage(storage.FetchLog{Source: result.Source, FetchedAt: time.Now(), Status: "success"}, result.Items, next)
+		return err
+	}
+
+	// First run stops after one page
+	fetched, err := f.Backfill(context.Background(), feed, storage.BackfillCursor{Feed: feed.Name}, 1, save)
+	if err != nil || fetched != 1 {
+		t.Fatalf("first backfill: fetched=%d err=%v", fetched, err)
+	}
+
+	// Second run resumes from the stored cursor and runs to the end
+	cursor, err := db.GetBackfillCursor(feed.Name)
+	if err != nil || cursor == nil || cursor.Cursor != "p2" {
+		t.Fatalf("expected stored cursor p2, got %+v (err %v)", cursor, err)
+	}
+	fetched, err = f.Backfill(context.Background(), feed, *cursor, 10, save)
+	if err != nil || fetched != 2 {
+		t.Fatalf("second backfill: fetched=%d err=%v", fetched, err)
+	}
+
+	count, _ := db.GetItemCount("Reddit")
+	if count != 3 {
+		t.Errorf("Expected 3 backfilled items, got %d", count)
+	}
+
+	cursor, _ = db.GetBackfillCursor(feed.Name)
+	if cursor == nil || !cursor.Done {
+		t.Errorf("Expected backfill to be marked done, got %+v", cursor)
+	}
+}
+
+// TestIntegration_IncrementalLinkHeader tests that a feed follows the stored
+// rel="next" link on its next run and keeps it when no new link is sent
+func TestIntegration_IncrementalLinkHeader(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	var requested []string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.RequestURI())
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", `<`+server.URL+`/events?page=2>; rel="next", <`+server.URL+`/events?page=9>; rel="last"`)
+		}
+		w.Write([]byte(`[{"title":"Event","url":"https://example.com/` + r.URL.Query().Get("page") + `"}]`))
+	}))
+	defer server.Close()
+
+	db, err := storage.NewStorage(filepath.Join(t.TempDir(), "incremental.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{
+		Settings: config.Settings{MaxConcurrency: 1, DefaultTimeoutSecs: 5, RetryMax: 0},
+		Feeds: []config.Feed{{
+			Name:        "GitHub",
+			URL:         server.URL + "/events",
+			FeedType:    "json",
+			Incremental: &config.IncrementalConfig{LinkHeader: true},
+		}},
+	}
+	f := fetcher.NewFetcher(cfg)
+	f.SetState(db)
+
+	for run := 0; run < 2; run++ {
+		for _, result := range f.FetchAll(context.Background()) {
+			if !result.Success {
+				t.Fatalf("run %d: fetch failed: %s", run, result.Error)
+			}
+			if err := db.SetFeedState(result.Source, result.State); err != nil {
+				t.Fatalf("run %d: SetFeedState failed: %v", run, err)
+			}
+		}
+	}
+
+	want := []string{"/events", "/events?page=2"}
+	if strings.Join(requested, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected requests %v, got %v", want, requested)
+	}
+
+	state, _ := db.GetFeedState("GitHub")
+	if state["next_url"] != server.URL+"/events?page=2" {
+		t.Errorf("Expected next link to be kept, got %v", state)
+	}
+}
+
+// TestIntegration_PostBody tests that a feed configured with a method and
+// JSON body sends them with the request
+func TestIntegration_PostBody(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	var method, contentType, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		method, contentType, body = r.Method, r.Header.Get("Content-Type"), string(data)
+		w.Write([]byte(`[{"title":"Result","url":"https://example.com/r"}]`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Settings: config.Settings{MaxConcurrency: 1, DefaultTimeoutSecs: 5, RetryMax: 0},
+		Feeds: []config.Feed{{
+			Name:     "Search",
+			URL:      server.URL + "/search",
+			FeedType: "json",
+			Method:   "post",
+			Body:     `{"query":"golang"}`,
+		}},
+	}
+
+	results := fetcher.NewFetcher(cfg).FetchAll(context.Background())
+	if !results[0].Success || results[0].ItemsCount != 1 {
+		t.Fatalf("Expected 1 item, got %+v", results[0])
+	}
+
+	if method != http.MethodPost || contentType != "application/json" || body != `{"query":"golang"}` {
+		t.Errorf("Unexpected request: method=%s content-type=%s body=%s", method, contentType, body)
+	}
+}
+
+// TestIntegration_LoginCookiePersistence tests that cookies obtained by a
+// login request are stored encrypted and sent by a later fetcher
+func TestIntegration_LoginCookiePersistence(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			r.ParseForm()
+			if r.Method != http.MethodPost || r.PostForm.Get("user") != "me" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s1", Path: "/", MaxAge: 3600})
+		case "/feed.json":
+			if c, err := r.Cookie("session"); err != nil || c.Value != "s1" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`[{"title":"Private","url":"https://example.com/p"}]`))
+		}
+	}))
+	defer server.Close()
+
+	db, err := storage.NewStorage(filepath.Join(t.TempDir(), "cookies.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer db.Close()
+	db.SetCookieKey("test-secret")
+
+	cfg := &config.Config{
+		Settings: config.Settings{MaxConcurrency: 1, DefaultTimeoutSecs: 5, RetryMax: 0},
+		Feeds: []config.Feed{{
+			Name:     "Intranet",
+			URL:      server.URL + "/feed.json",
+			FeedType: "json",
+			Login:    &config.LoginConfig{URL: server.URL + "/login", Form: map[string]string{"user": "me"}},
+		}},
+	}
+
+	// Without a session the feed is forbidden
+	f := fetcher.NewFetcher(cfg)
+	f.SetCookieStore(db)
+	if results := f.FetchAll(context.Background()); results[0].Success {
+		t.Fatal("Expected fetch without login to fail")
+	}
+
+	n, err := f.Login(context.Background(), cfg.Feeds[0])
+	if err != nil || n != 1 {
+		t.Fatalf("Login: n=%d err=%v", n, err)
+	}
+
+	// A new fetcher loads the stored jar
+	f = fetcher.NewFetcher(cfg)
+	f.SetCookieStore(db)
+	if results := f.FetchAll(context.Background()); !results[0].Success {
+		t.Fatalf("Expected fetch with stored session to succeed: %s", results[0].Error)
+	}
+}
+
+// TestIntegration_ChainedFeeds tests that a dependent feed is fetched only
+// after its upstream feed produced new items
+func TestIntegration_ChainedFeeds(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	index := `[{"title":"One","url":"https://example.com/1"}]`
+	detailsFetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/details" {
+			detailsFetches++
+		}
+		w.Write([]byte(index))
+	}))
+	defer server.Close()
+
+	db, err := storage.NewStorage(filepath.Join(t.TempDir(), "chain.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{
+		Settings: config.Settings{MaxConcurrency: 2, DefaultTimeoutSecs: 5, RetryMax: 0},
+		Feeds: []config.Feed{
+			{Name: "Details", URL: server.URL + "/details", FeedType: "json", DependsOn: "Index"},
+			{Name: "Index", URL: server.URL + "/index", FeedType: "json"},
+		},
+	}
+
+	run := func() []fetcher.FetchResult {
+		f := fetcher.NewFetcher(cfg)
+		f.SetState(db)
+		return f.FetchStages(context.Background(), func(stage []fetcher.FetchResult) {
+			for _, result := range stage {
+				if !result.Success {
+					continue
+				}
+				saved, err := db.SaveFetchResult(storage.FetchLog{Source: result.Source, FetchedAt: time.Now(), Status: "success"}, result.Items)
+				if err != nil {
+					t.Fatalf("SaveFetchResult failed: %v", err)
+				}
+				state := result.State
+				if saved.Inserted > 0 {
+					state = fetcher.MarkNewItems(state, time.Now())
+				}
+				db.SetFeedState(result.Source, state)
+			}
+		})
+	}
+
+	// Index runs first and has new items, so Details follows
+	results := run()
+	if results[0].Source != "Index" || results[1].Source != "Details" || results[1].Skipped {
+		t.Fatalf("Unexpected first run: %+v", results)
+	}
+
+	// Nothing new upstream: Details is skipped
+	results = run()
+	if !results[1].Skipped || detailsFetches != 1 {
+		t.Errorf("Expected Details to be skipped, got %+v (fetches %d)", results[1], detailsFetches)
+	}
+
+	// New upstream item: Details runs again
+	index = `[{"title":"Two","url":"https://example.com/2"}]`
+	results = run()
+	if results[1].Skipped || detailsFetches != 2 {
+		t.Errorf("Expected Details to run, got %+v (fetches %d)", results[1], detailsFetches)
+	}
+}
+
+// TestIntegration_MirrorFailoverAndMerge tests that a feed falls back to a
+// mirror when its primary fails, and that merged mirrors are deduplicated
+func TestIntegration_MirrorFailoverAndMerge(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/down":
+			w.WriteHeader(http.StatusNotFound)
+		case "/a":
+			w.Write([]byte(`[{"title":"Shared","url":"https://example.com/s"},{"title":"A","url":"https://example.com/a"}]`))
+		case "/b":
+			w.Write([]byte(`[{"title":"Shared","url":"https://example.com/s"},{"title":"B","url":"https://example.com/b"}]`))
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Settings: config.Settings{MaxConcurrency: 2, DefaultTimeoutSecs: 5, RetryMax: 0},
+		Feeds: []config.Feed{
+			{Name: "Failover", URL: server.URL + "/down", Mirrors: []string{server.URL + "/a", server.URL + "/b"}, FeedType: "json"},
+			{Name: "Merged", URL: server.URL + "/a", Mirrors: []string{server.URL + "/b", server.URL + "/down"}, MirrorStrategy: config.MirrorMerge, FeedType: "json"},
+		},
+	}
+
+	results := fetcher.NewFetcher(cfg).FetchAll(context.Background())
+
+	failover := results[0]
+	if !failover.Success || failover.Endpoint != server.URL+"/a" || failover.ItemsCount != 2 {
+		t.Errorf("Expected failover to be served by /a with 2 items, got %+v", failover)
+	}
+	if !strings.Contains(failover.Error, "/down") {
+		t.Errorf("Expected the primary's failure to be reported, got %q", failover.Error)
+	}
+
+	merged := results[1]
+	if !merged.Success || merged.ItemsCount != 3 {
+		t.Errorf("Expected 3 deduplicated items from merged mirrors, got %d (%s)", merged.ItemsCount, merged.Error)
+	}
+	if merged.Endpoint != server.URL+"/a, "+server.URL+"/b" {
+		t.Errorf("Expected merged endpoints to be recorded, got %q", merged.Endpoint)
+	}
+}
+
+// TestIntegration_ResponseAssertions tests that assertion violations are
+// reported on otherwise successful fetches
+func TestIntegration_ResponseAssertions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"title":"Old","url":"https://example.com/old","created_at":"2020-01-01T00:00:00Z"}]`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Settings: config.Settings{MaxConcurrency: 2, DefaultTimeoutSecs: 5, RetryMax: 0},
+		Feeds: []config.Feed{
+			{Name: "Healthy", URL: server.URL, FeedType: "json", Assertions: &config.AssertionsConfig{
+				MinItems:       1,
+				RequiredFields: []string{"*.title", "*.created_at"},
+			}},
+			{Name: "Broken", URL: server.URL, FeedType: "json", Assertions: &config.AssertionsConfig{
+				MinItems:       5,
+				RequiredFields: []string{"*.score"},
+				MaxAge:         "7d",
+			}},
+		},
+	}
+
+	results := fetcher.NewFetcher(cfg).FetchAll(context.Background())
+
+	if !results[0].Success || len(results[0].Violations) != 0 {
+		t.Errorf("Expected healthy feed to pass its assertions, got %v", results[0].Violations)
+	}
+
+	broken := results[1]
+	if !broken.Success || broken.ItemsCount != 1 {
+		t.Fatalf("Expected broken feed to still deliver its item, got %+v", broken)
+	}
+	if len(broken.Violations) != 3 {
+		t.Errorf("Expected 3 violations (required field, min items, max age), got %v", broken.Violations)
+	}
+}
+
+// TestIntegration_SchemaDrift tests that a significant change in a JSON
+// feed's structure between runs is reported
+func TestIntegration_SchemaDrift(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	body := `[{"title":"A","url":"https://example.com/a","created_at":"2024-01-01T00:00:00Z","tags":["go"]}]`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	db, err := storage.NewStorage(filepath.Join(t.TempDir(), "drift.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{
+		Settings: config.Settings{MaxConcurrency: 1, DefaultTimeoutSecs: 5, RetryMax: 0, SchemaDriftThreshold: 0.2},
+		Feeds:    []config.Feed{{Name: "Lobsters", URL: server.URL, FeedType: "json"}},
+	}
+
+	fetch := func() fetcher.FetchResult {
+		f := fetcher.NewFetcher(cfg)
+		f.SetState(db)
+		result := f.FetchAll(context.Background())[0]
+		if err := db.SetFeedState(result.Source, result.State); err != nil {
+			t.Fatalf("SetFeedState failed: %v", err)
+		}
+		return result
+	}
+
+	// The first run only records the fingerprint
+	if result := fetch(); result.SchemaDrift != nil {
+		t.Errorf("Expected no drift on the first run, got %+v", result.SchemaDrift)
+	}
+
+	// Same structure, different values
+	body = `[{"title":"B","url":"https://example.com/b","created_at":"2024-01-02T00:00:00Z","tags":["rust"]}]`
+	if result := fetch(); result.SchemaDrift != nil {
+		t.Errorf("Expected no drift for unchanged structure, got %+v", result.SchemaDrift)
+	}
+
+	// Upstream renamed fields
+	body = `[{"title":"C","link":"https://example.com/c","published":"2024-01-03T00:00:00Z","labels":["go"]}]`
+	result := fetch()
+	if result.SchemaDrift == nil {
+		t.Fatal("Expected drift to be reported")
+	}
+	if strings.Join(result.SchemaDrift.Removed, ",") != "*.created_at,*.tags,*.url" {
+		t.Errorf("Unexpected removed fields: %v", result.SchemaDrift.Removed)
+	}
+	if strings.Join(result.SchemaDrift.Added, ",") != "*.labels,*.link,*.published" {
+		t.Errorf("Unexpected added fields: %v", result.SchemaDrift.Added)
+	}
+}
+
+// TestIntegration_UnwrapResponses tests that JSONP and string-wrapped
+// responses are unwrapped before parsing
+func TestIntegration_UnwrapResponses(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	item := `[{"title":"A","url":"https://example.com/a","created_at":"2024-01-01T00:00:00Z"}]`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/jsonp":
+			w.Write([]byte("/**/ handleFeed(" + item + ");"))
+		case "/wrapped":
+			wrapped, _ := json.Marshal(map[string]string{"d": item})
+			w.Write([]byte("cb(" + string(wrapped) + ")"))
+		default:
+			w.Write([]byte(item))
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Settings: config.Settings{MaxConcurrency: 3, DefaultTimeoutSecs: 5, RetryMax: 2, RetryBaseDelayMs: 1},
+		Feeds: []config.Feed{
+			{Name: "JSONP", URL: server.URL + "/jsonp", FeedType: "json", Unwrap: &config.UnwrapConfig{StripJSONP: true}},
+			{Name: "Wrapped", URL: server.URL + "/wrapped", FeedType: "json", Unwrap: &config.UnwrapConfig{StripJSONP: true, JSONStringField: "d"}},
+			{Name: "Mismatch", URL: server.URL + "/plain", FeedType: "json", Unwrap: &config.UnwrapConfig{StripJSONP: true}},
+		},
+	}
+
+	results := fetcher.NewFetcher(cfg).FetchAll(context.Background())
+
+	for _, result := range results[:2] {
+		if !result.Success || result.ItemsCount != 1 {
+			t.Errorf("Expected %s to yield 1 item, got %+v", result.Source, result)
+		}
+	}
+	if results[2].Success || !strings.Contains(results[2].Error, "not a JSONP callback") {
+		t.Errorf("Expected mismatched feed to fail unwrapping, got %+v", results[2])
+	}
+}
+
+// TestIntegration_StackExchangeBackoff tests that a Stack Exchange feed
+// honors the API's backoff and exhausted quota by skipping later runs
+func TestIntegration_StackExchangeBackoff(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	var mu sync.Mutex
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		quota := 250
+		backoff := ""
+		switch r.URL.Path {
+		case "/backoff":
+			backoff = `"backoff": 30,`
+		case "/exhausted":
+			quota = 0
+		}
+		fmt.Fprintf(w, `{"items":[{"tags":["go"],"question_id":1,"link":"https://stackoverflow.com/q/1","title":"Q","creation_date":1704067200}],%s"has_more":false,"quota_max":300,"quota_remaining":%d}`, backoff, quota)
+	}))
+	defer server.Close()
+
+	db, err := storage.NewStorage(filepath.Join(t.TempDir(), "backoff.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{
+		Settings: config.Settings{MaxConcurrency: 3, DefaultTimeoutSecs: 5, RetryMax: 0},
+		Feeds: []config.Feed{
+			{Name: "Normal", URL: server.URL + "/normal", FeedType: "json"},
+			{Name: "Backoff", URL: server.URL + "/backoff", FeedType: "json"},
+			{Name: "Exhausted", URL: server.URL + "/exhausted", FeedType: "json"},
+		},
+	}
+
+	run := func() []fetcher.FetchResult {
+		f := fetcher.NewFetcher(cfg)
+		f.SetState(db)
+		results := f.FetchAll(context.Background())
+		for _, result := range results {
+			if result.Success {
+				db.SetFeedState(result.Source, result.State)
+			}
+		}
+		return results
+	}
+
+	results := run()
+	if results[0].Backoff != 0 || results[1].Backoff != 30*time.Second || results[2].Backoff <= 0 {
+		t.Fatalf("Unexpected backoffs: %v, %v, %v", results[0].Backoff, results[1].Backoff, results[2].Backoff)
+	}
+	if results[0].ItemsCount != 1 || results[0].Items[0].Tags[0] != "go" {
+		t.Errorf("Expected the question to be parsed, got %+v", results[0].Items)
+	}
+
+	results = run()
+	if results[0].Skipped || !results[1].Skipped || !results[2].Skipped {
+		t.Errorf("Expected only the throttled feeds to be skipped, got %+v", results)
+	}
+	if requests["/normal"] != 2 || requests["/backoff"] != 1 || requests["/exhausted"] != 1 {
+		t.Errorf("Unexpected request counts: %v", requests)
+	}
+}
+
+// TestIntegration_WebSubDiscovery tests that hubs advertised in Link
+// headers are reported, and that a single feed can be fetched on demand
+func TestIntegration_WebSubDiscovery(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/pushed" {
+			w.Header().Add("Link", `<https://hub.example.com/>; rel="hub", <https://example.com/feed.json>; rel="self"`)
+		}
+		w.Write([]byte(`[{"title":"A","url":"https://example.com/a"}]`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Settings: config.Settings{MaxConcurrency: 2, DefaultTimeoutSecs: 5, RetryMax: 0},
+		Feeds: []config.Feed{
+			{Name: "Pushed", URL: server.URL + "/pushed", FeedType: "json"},
+			{Name: "Polled", URL: server.URL + "/polled", FeedType: "json"},
+		},
+	}
+	f := fetcher.NewFetcher(cfg)
+
+	result, err := f.FetchFeed(context.Background(), "Pushed")
+	if err != nil {
+		t.Fatalf("FetchFeed failed: %v", err)
+	}
+	if !result.Success || result.ItemsCount != 1 {
+		t.Fatalf("Expected a successful fetch, got %+v", result)
+	}
+	if result.Hub != "https://hub.example.com/" || result.Topic != "https://example.com/feed.json" {
+		t.Errorf("Expected hub and self topic, got %q and %q", result.Hub, result.Topic)
+	}
+
+	result, _ = f.FetchFeed(context.Background(), "Polled")
+	if result.Hub != "" || result.Topic != "" {
+		t.Errorf("Expected no hub for a feed without Link headers, got %+v", result)
+	}
+
+	if _, err := f.FetchFeed(context.Background(), "Missing"); err == nil {
+		t.Error("Expected error for an unknown feed")
+	}
+}
+
+// TestIntegration_StreamFeeds tests that SSE and NDJSON streams are turned
+// into items as events arrive, and that SSE streams resume after a drop
+func TestIntegration_StreamFeeds(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	var mu sync.Mutex
+	var lastEventIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sse":
+			mu.Lock()
+			lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+			resumed := len(lastEventIDs) > 1
+			mu.Unlock()
+
+			w.Header().Set("Content-Type", "text/event-stream")
+			if resumed {
+				fmt.Fprint(w, "id: 3\ndata: {\"title\":\"Three\",\"url\":\"https://example.com/3\"}\n\n")
+				return
+			}
+			fmt.Fprint(w, "retry: 10\n: keep-alive\n\n")
+			fmt.Fprint(w, "id: 1\ndata: {\"title\":\"One\",\n")
+			fmt.Fprint(w, "data:  \"url\":\"https://example.com/1\"}\n\n")
+			fmt.Fprint(w, "event: ping\ndata: {}\n\n")
+			fmt.Fprint(w, "id: 2\ndata: {\"title\":\"Two\",\"url\":\"https://example.com/2\"}\n\n")
+		case "/ndjson":
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			fmt.Fprint(w, "{\"title\":\"A\",\"url\":\"https://example.com/a\"}\n\n{\"title\":\"B\",\"url\":\"https://example.com/b\"}\n")
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Settings: config.Settings{MaxConcurrency: 2, DefaultTimeoutSecs: 5, RetryMax: 0, RetryBaseDelayMs: 10},
+		Feeds: []config.Feed{
+			{Name: "SSE", URL: server.URL + "/sse", FeedType: "json", Mode: config.ModeStream},
+			{Name: "NDJSON", URL: server.URL + "/ndjson", FeedType: "ndjson", Mode: config.ModeStream},
+		},
+	}
+	f := fetcher.NewFetcher(cfg)
+
+	// Polling runs leave stream feeds alone
+	for _, result := range f.FetchAll(context.Background()) {
+		if !result.Skipped {
+			t.Errorf("Expected stream feed %s to be skipped by FetchAll, got %+v", result.Source, result)
+		}
+	}
+
+	collect := func(feed config.Feed, want int) []string {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		var titles []string
+		f.Stream(ctx, feed, func(result fetcher.FetchResult) {
+			if !result.Success {
+				t.Errorf("Unexpected stream error: %s", result.Error)
+				return
+			}
+			for _, item := range result.Items {
+				titles = append(titles, item.Title)
+			}
+			if len(titles) >= want {
+				cancel()
+			}
+		})
+		return titles
+	}
+
+	if titles := collect(cfg.Feeds[0], 3); strings.Join(titles, ",") != "One,Two,Three" {
+		t.Errorf("Expected SSE items One,Two,Three, got %v", titles)
+	}
+	mu.Lock()
+	if len(lastEventIDs) < 2 || lastEventIDs[0] != "" || lastEventIDs[1] != "2" {
+		t.Errorf("Expected the reconnect to resume after event 2, got %q", lastEventIDs)
+	}
+	mu.Unlock()
+
+	if titles := collect(cfg.Feeds[1], 2); strings.Join(titles, ",") != "A,B" {
+		t.Errorf("Expected NDJSON items A,B, got %v", titles)
+	}
+}
+
+// TestIntegration_UnchangedPayload tests that a response identical to the
+// previous run's is neither journaled nor parsed, and that a changed one
+// is fetched normally again
+func TestIntegration_UnchangedPayload(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	var mu sync.Mutex
+	body := `[{"title":"First","url":"https://example.com/1"}]`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	db, err := storage.NewStorage(filepath.Join(t.TempDir(), "unchanged.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{
+		Settings: config.Settings{MaxConcurrency: 1, DefaultTimeoutSecs: 5, RetryMax: 0},
+		Feeds:    []config.Feed{{Name: "Hourly", URL: server.URL, FeedType: "json"}},
+	}
+	f := fetcher.NewFetcher(cfg)
+	f.SetState(db)
+	f.SetJournal(db)
+
+	fetch := func() fetcher.FetchResult {
+		t.Helper()
+		results := f.FetchAll(context.Background())
+		if len(results) != 1 || !results[0].Success {
+			t.Fatalf("Fetch failed: %+v", results)
+		}
+		result := results[0]
+		if !result.Unchanged {
+			if _, err := db.SaveFetchResult(storage.FetchLog{Source: result.Source, FetchedAt: time.Now(), Status: "success", ItemsCount: result.ItemsCount}, result.Items); err != nil {
+				t.Fatalf("SaveFetchResult failed: %v", err)
+			}
+			for _, id := range result.JournalIDs {
+				db.MarkJournalProcessed(id)
+			}
+		}
+		if err := db.SetFeedState(result.Source, result.State); err != nil {
+			t.Fatalf("SetFeedState failed: %v", err)
+		}
+		return result
+	}
+
+	if first := fetch(); first.Unchanged || first.ItemsCount != 1 {
+		t.Fatalf("Expected the first fetch to be parsed, got %+v", first)
+	}
+
+	second := fetch()
+	if !second.Unchanged || second.ItemsCount != 0 || len(second.JournalIDs) != 0 {
+		t.Errorf("Expected an identical response to be skipped, got %+v", second)
+	}
+
+	mu.Lock()
+	body = `[{"title":"First","url":"https://example.com/1"},{"title":"Second","url":"https://example.com/2"}]`
+	mu.Unlock()
+
+	if third := fetch(); third.Unchanged || third.ItemsCount != 2 {
+		t.Errorf("Expected a changed response to be parsed, got %+v", third)
+	}
+
+	count, _ := db.GetItemCount("Hourly")
+	if count != 2 {
+		t.Errorf("Expected 2 stored items, got %d", count)
+	}
+}
+
+// TestIntegration_ConditionalGet tests that a feed's ETag and Last-Modified
+// are sent back on the next fetch and a 304 is treated as no new items
+func TestIntegration_ConditionalGet(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	var mu sync.Mutex
+	etag := `"v1"`
+	var conditional []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		conditional = append(conditional, r.Header.Get("If-None-Match")+"|"+r.Header.Get("If-Modified-Since"))
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", "Mon, 02 Mar 2026 12:00:00 GMT")
+		version := strings.Trim(etag, `"`)
+		fmt.Fprintf(w, `[{"title":"Item %s","url":"https://example.com/%s"}]`, version, version)
+	}))
+	defer server.Close()
+
+	db, err := storage.NewStorage(filepath.Join(t.TempDir(), "conditional.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{
+		Settings: config.Settings{MaxConcurrency: 1, DefaultTimeoutSecs: 5, RetryMax: 2, RetryBaseDelayMs: 1},
+		Feeds:    []config.Feed{{Name: "Hourly", URL: server.URL, FeedType: "json"}},
+	}
+	f := fetcher.NewFetcher(cfg)
+	f.SetState(db)
+
+	fetch := func() fetcher.FetchResult {
+		t.Helper()
+		results := f.FetchAll(context.Background())
+		if len(results) != 1 || !results[0].Success {
+			t.Fatalf("Fetch failed: %+v", results)
+		}
+		result := results[0]
+		if !result.Unchanged {
+			if _, err := db.SaveFetchResult(storage.FetchLog{Source: result.Source, FetchedAt: time.Now(), Status: "success", ItemsCount: result.ItemsCount}, result.Items); err != nil {
+				t.Fatalf("SaveFetchResult failed: %v", err)
+			}
+		}
+		if err := db.SetFeedState(result.Source, result.State); err != nil {
+			t.Fatalf("SetFeedState failed: %v", err)
+		}
+		return result
+	}
+
+	if first := fetch(); first.Unchanged || first.ItemsCount != 1 {
+		t.Fatalf("Expected the first fetch to be parsed, got %+v", first)
+	}
+	if second := fetch(); !second.Unchanged || second.ItemsCount != 0 {
+		t.Errorf("Expected a 304 to be unchanged, got %+v", second)
+	}
+
+	mu.Lock()
+	etag = `"v2"`
+	mu.Unlock()
+	if third := fetch(); third.Unchanged || third.ItemsCount != 1 {
+		t.Errorf("Expected a changed response to be parsed, got %+v", third)
+	}
+
+	want := []string{
+		"|",
+		`"v1"|Mon, 02 Mar 2026 12:00:00 GMT`,
+		`"v1"|Mon, 02 Mar 2026 12:00:00 GMT`,
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(conditional, want) {
+		t.Errorf("Expected one request per fetch with the saved validators, got %q", conditional)
+	}
+	if count, _ := db.GetItemCount("Hourly"); count != 2 {
+		t.Errorf("Expected 2 stored items, got %d", count)
+	}
+}
+
+func TestIntegration_HackerNewsHydration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/topstories.json":
+			fmt.Fprint(w, `[101, 102, 103, 104, 105]`)
+		case "/item/101.json":
+			fmt.Fprint(w, `{"id":101,"type":"story","title":"Show HN: A thing","url":"https://example.com/thing","score":42,"time":1772366400}`)
+		case "/item/102.json":
+			fmt.Fprint(w, `{"id":102,"type":"story","title":"Ask HN: Why?","score":7,"time":1772370000}`)
+		case "/item/103.json":
+			fmt.Fprint(w, `{"id":103,"type":"story","dead":true}`)
+		default:
+			http.Error(w, "boom", http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Settings: config.Settings{MaxConcurrency: 1, DefaultTimeoutSecs: 5, RetryMax: 0, RetryBaseDelayMs: 1},
+		Feeds: []config.Feed{{
+			Name:     "HN",
+			URL:      server.URL + "/topstories.json",
+			FeedType: "json",
+			Hydrate:  &config.HydrateConfig{MaxItems: 4, Concurrency: 2, ItemURL: server.URL + "/item/{{id}}.json"},
+		}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("invalid config: %v", err)
+	}
+
+	results := fetcher.NewFetcher(cfg).FetchAll(context.Background())
+	if len(results) != 1 || !results[0].Success {
+		t.Fatalf("Fetch failed: %+v", results)
+	}
+	result := results[0]
+
+	// 105 is past max_items, 103 is dead and 104 fails to load
+	if result.ItemsCount != 2 || result.Truncated != 1 {
+		t.Fatalf("Expected 2 items and 1 truncated, got %d and %d", result.ItemsCount, result.Truncated)
+	}
+	if !strings.Contains(result.Error, "hydration failed for 1 of 4 stories") {
+		t.Errorf("Expected the failed story to be reported, got %q", result.Error)
+	}
+
+	story, ask := result.Items[0], result.Items[1]
+	if story.Title != "Show HN: A thing" || story.URL != "https://example.com/thing" {
+		t.Errorf("Story not hydrated: %+v", story)
+	}
+	if story.Timestamp == nil || *story.Timestamp != "2026-03-01T12:00:00Z" {
+		t.Errorf("Expected the story time as timestamp, got %v", story.Timestamp)
+	}
+	if story.RawData == nil || !strings.Contains(*story.RawData, `"score":42`) {
+		t.Errorf("Expected the item API answer as raw data, got %v", story.RawData)
+	}
+	if story.ID != storage.ItemID(storage.ScopeSource, "HN", "https://news.ycombinator.com/item?id=101", "") {
+		t.Errorf("Hydration must keep the item ID, got %s", story.ID)
+	}
+	if ask.Title != "Ask HN: Why?" || ask.URL != "https://news.ycombinator.com/item?id=102" {
+		t.Errorf("Expected a story without URL to keep its discussion link, got %+v", ask)
+	}
+}
+
+func TestIntegration_RequestBudget(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `[{"title": "Item", "url": "https://example.com/1"}]`)
+	}))
+	defer server.Close()
+
+	db, err := storage.NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{
+		Settings: config.Settings{
+			MaxConcurrency:     1,
+			DefaultTimeoutSecs: 5,
+			RetryBaseDelayMs:   1,
+			Budgets:            map[string]config.HostBudget{"127.0.0.1": {PerHour: 2}},
+		},
+		Feeds: []config.Feed{{Name: "Budgeted", URL: server.URL, FeedType: "json"}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("invalid config: %v", err)
+	}
+
+	f := fetcher.NewFetcher(cfg)
+	f.SetRequestMeter(db)
+
+	for i := 0; i < 2; i++ {
+		if results := f.FetchAll(context.Background()); !results[0].Success {
+			t.Fatalf("Fetch %d failed: %+v", i+1, results[0])
+		}
+	}
+
+	// The third fetch would exceed the hourly budget, so it is deferred
+	result := f.FetchAll(context.Background())[0]
+	if !result.Skipped || !strings.Contains(result.Error, "request budget for 127.0.0.1 spent (2/2 per hour)") {
+		t.Errorf("Expected the fetch to be deferred, got %+v", result)
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 requests to reach the server, got %d", requests)
+	}
+
+	usage, err := db.HostUsage("127.0.0.1", time.Now())
+	if err != nil {
+		t.Fatalf("HostUsage failed: %v", err)
+	}
+	if usage.LastHour != 2 || usage.LastDay != 2 {
+		t.Errorf("Expected 2 requests counted, got %+v", usage)
+	}
+}
+
+// TestIntegration_RequestBudgetParallel tests that feeds fetched in
+// parallel against one host don't overspend its budget between them
+func TestIntegration_RequestBudgetParallel(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		fmt.Fprint(w, `[{"title": "Item", "url": "https://example.com/1"}]`)
+	}))
+	defer server.Close()
+
+	db, err := storage.NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{
+		Settings: config.Settings{
+			MaxConcurrency:     4,
+			DefaultTimeoutSecs: 5,
+			Budgets:            map[string]config.HostBudget{"127.0.0.1": {PerHour: 2}},
+		},
+	}
+	for i := 0; i < 4; i++ {
+		cfg.Feeds = append(cfg.Feeds, config.Feed{Name: fmt.Sprintf("Feed %d", i), URL: fmt.Sprintf("%s/%d", server.URL, i), FeedType: "json"})
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("invalid config: %v", err)
+	}
+
+	f := fetcher.NewFetcher(cfg)
+	f.SetRequestMeter(db)
+	fetched, deferred := 0, 0
+	for _, result := range f.FetchAll(context.Background()) {
+		switch {
+		case result.Success:
+			fetched++
+		case result.Skipped:
+			deferred++
+		}
+	}
+	if fetched != 2 || deferred != 2 || requests.Load() != 2 {
+		t.Errorf("Expected 2 fetches within the budget and 2 deferred, got %d and %d with %d requests", fetched, deferred, requests.Load())
+	}
+
+	usage, err := db.HostUsage("127.0.0.1", time.Now())
+	if err != nil {
+		t.Fatalf("HostUsage failed: %v", err)
+	}
+	if usage.LastHour != 2 {
+		t.Errorf("Expected 2 requests counted, got %+v", usage)
+	}
+}
+
+func TestIntegration_HedgedRequests(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	var mu sync.Mutex
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		first := requests[r.URL.Path] == 1
+		mu.Unlock()
+
+		// /slow never answers in time; /flaky only stalls the first time
+		if r.URL.Path == "/slow" || (r.URL.Path == "/flaky" && first) {
+			select {
+			case <-time.After(5 * time.Second):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		fmt.Fprintf(w, `[{"title": "From %s", "url": "https://example.com%s"}]`, r.URL.Path, r.URL.Path)
+	}))
+	defer server.Close()
+
+	hedge := &config.HedgeConfig{After: "50ms"}
+	cfg := &config.Config{
+		Settings: config.Settings{MaxConcurrency: 2, DefaultTimeoutSecs: 10, RetryBaseDelayMs: 1},
+		Feeds: []config.Feed{
+			{Name: "Mirrored", URL: server.URL + "/slow", Mirrors: []string{server.URL + "/fast"}, FeedType: "json", Hedge: hedge},
+			{Name: "Flaky", URL: server.URL + "/flaky", FeedType: "json", Hedge: hedge},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("invalid config: %v", err)
+	}
+
+	start := time.Now()
+	results := fetcher.NewFetcher(cfg).FetchAll(context.Background())
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected hedged requests to finish quickly, took %s", elapsed)
+	}
+
+	mirrored, flaky := results[0], results[1]
+	if !mirrored.Success || mirrored.Endpoint != server.URL+"/fast" {
+		t.Errorf("Expected the mirror to answer the hedged request, got %+v", mirrored)
+	}
+	if !flaky.Success || flaky.ItemsCount != 1 || flaky.Endpoint != server.URL+"/flaky" {
+		t.Errorf("Expected the hedged request to the same URL to succeed, got %+v", flaky)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if requests["/slow"] != 1 || requests["/fast"] != 1 || requests["/flaky"] != 2 {
+		t.Errorf("Expected one hedged request per feed, got %v", requests)
+	}
+}
+
+func TestIntegration_ParseFailureRecorded(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	body := `{"items": [{"full_name": "a/b", "html_url": "https://github.com/a/b"},`
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	db, err := storage.NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer db.Close()
+
+	cfg := &config.Config{
+		Settings: config.Settings{MaxConcurrency: 1, DefaultTimeoutSecs: 5, RetryMax: 2, RetryBaseDelayMs: 1, MaxFailureBytes: 20},
+		Feeds:    []config.Feed{{Name: "GitHub", URL: server.URL, FeedType: "json"}},
+	}
+
+	result := fetcher.NewFetcher(cfg).FetchAll(context.Background())[0]
+	if result.Success || !strings.Contains(result.Error, "parse error: malformed JSON") {
+		t.Fatalf("Expected the fetch to fail with a parse error, got %+v", result)
+	}
+	if requests != 1 {
+		t.Errorf("Expected a response that doesn't parse not to be retried, got %d requests", requests)
+	}
+	if result.Failure == nil || result.Failure.Size != len(body) || string(result.Failure.Payload) != body[:20] {
+		t.Fatalf("Expected the first 20 bytes of the response captured, got %+v", result.Failure)
+	}
+
+	err = db.LogFetch(storage.FetchLog{
+		Source:       result.Source,
+		FetchedAt:    time.Now(),
+		Status:       "error",
+		ErrorMessage: &result.Error,
+		Failure:      result.Failure,
+	})
+	if err != nil {
+		t.Fatalf("LogFetch failed: %v", err)
+	}
+
+	failures, err := db.ListParseFailures("GitHub", 0)
+	if err != nil {
+		t.Fatalf("ListParseFailures failed: %v", err)
+	}
+	if len(failures) != 1 || failures[0].Error != result.Error {
+		t.Fatalf("Expected the failure recorded with its error, got %+v", failures)
+	}
+	failure, err := db.GetParseFailure(failures[0].ID)
+	if err != nil || failure == nil || string(failure.Payload) != body[:20] {
+		t.Errorf("Expected the captured response back, got %+v, %v", failure, err)
+	}
+
+	// Without capture, the fetch still fails but keeps nothing
+	cfg.Settings.MaxFailureBytes = -1
+	if result := fetcher.NewFetcher(cfg).FetchAll(context.Background())[0]; result.Success || result.Failure != nil {
+		t.Errorf("Expected a failed fetch without a capture, got %+v", result)
+	}
+}
+
+// TestIntegration_AttemptLog tests that every HTTP attempt of a fetch,
+// retries included, is logged as a JSON line
+func TestIntegration_AttemptLog(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	body := `[{"title":"Result","url":"https://example.com/r"}]`
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Settings: config.Settings{MaxConcurrency: 1, DefaultTimeoutSecs: 5, RetryMax: 1, RetryBaseDelayMs: 1},
+		Feeds:    []config.Feed{{Name: "Flaky", URL: server.URL, FeedType: "json"}},
+	}
+
+	var log strings.Builder
+	f := fetcher.NewFetcher(cfg)
+	f.SetAttemptLog(&log)
+	if result := f.FetchAll(context.Background())[0]; !result.Success {
+		t.Fatalf("Expected the retry to succeed, got %+v", result)
+	}
+
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 log lines, got %q", log.String())
+	}
+	var failed, succeeded fetcher.AttemptRecord
+	if err := json.Unmarshal([]byte(lines[0]), &failed); err != nil {
+		t.Fatalf("Invalid log line: %v", err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &succeeded); err != nil {
+		t.Fatalf("Invalid log line: %v", err)
+	}
+
+	if failed.Feed != "Flaky" || failed.Attempt != 1 || failed.Status != 503 || failed.ErrorClass != fetcher.ClassHTTP5xx {
+		t.Errorf("Unexpected first attempt: %+v", failed)
+	}
+	if succeeded.Attempt != 2 || succeeded.Status != 200 || succeeded.Bytes != len(body) || succeeded.ErrorClass != "" || succeeded.Error != "" {
+		t.Errorf("Unexpected second attempt: %+v", succeeded)
+	}
+}
+
+// TestIntegration_ScheduleReload tests that reloading a daemon's config
+// adds and removes feeds and applies changed intervals, keeping the
+// timing of feeds that didn't change
+func TestIntegration_ScheduleReload(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	feeds := func(intervals map[string]int) *config.Config {
+		cfg := &config.Config{}
+		for _, name := range []string{"A", "B", "C", "D"} {
+			if secs, ok := intervals[name]; ok {
+				cfg.Feeds = append(cfg.Feeds, config.Feed{Name: name, URL: "https://example.com/" + name, FeedType: "json", RefreshIntervalSecs: secs})
+			}
+		}
+		return cfg
+	}
+
+	schedule := fetcher.NewSchedule(feeds(map[string]int{"A": 600, "B": 600, "C": 600}), 5*time.Minute)
+	schedule.Record("A", true, now)
+	schedule.Record("B", true, now)
+	schedule.Record("C", false, now)
+
+	changes := schedule.Reload(feeds(map[string]int{"A": 600, "B": 1800, "C": 30, "D": 0}), 5*time.Minute, now)
+	if !reflect.DeepEqual(changes.Added, []string{"D"}) || changes.Removed != nil || !reflect.DeepEqual(changes.Changed, []string{"B", "C"}) {
+		t.Fatalf("Unexpected changes: %+v", changes)
+	}
+
+	// D is new, so due now; the others aren't
+	if due := schedule.Due(now); !reflect.DeepEqual(due, []string{"D"}) {
+		t.Errorf("Expected only D due, got %v", due)
+	}
+	// C's pending retry fits in its new 30s interval
+	if due := schedule.Due(now.Add(30 * time.Second)); !reflect.DeepEqual(due, []string{"C", "D"}) {
+		t.Errorf("Expected C and D due after 30s, got %v", due)
+	}
+	// A keeps its 10 minutes, B waits its new 30 from its last fetch
+	if due := schedule.Due(now.Add(10 * time.Minute)); !reflect.DeepEqual(due, []string{"A", "C", "D"}) {
+		t.Errorf("Expected A, C and D due after 10m, got %v", due)
+	}
+	if due := schedule.Due(now.Add(30 * time.Minute)); len(due) != 4 {
+		t.Errorf("Expected every feed due after 30m, got %v", due)
+	}
+
+	changes = schedule.Reload(feeds(map[string]int{"A": 600}), 5*time.Minute, now)
+	if !reflect.DeepEqual(changes.Removed, []string{"B", "C", "D"}) || !reflect.DeepEqual(schedule.Feeds(), []string{"A"}) {
+		t.Errorf("Expected B, C and D removed, got %+v leaving %v", changes, schedule.Feeds())
+	}
+}
+
+// TestIntegration_RedactedErrors tests that secrets in a feed's URL and
+// headers don't appear in the error and endpoint of a failed fetch
+func TestIntegration_RedactedErrors(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	// Nothing listens on the port of a closed server
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	cfg := &config.Config{
+		Settings: config.Settings{MaxConcurrency: 1, DefaultTimeoutSecs: 5, RetryMax: 0},
+		Feeds: []config.Feed{{
+			Name:     "Private",
+			URL:      server.URL + "/feed?api_key=k3y-0123456789&page=1",
+			FeedType: "json",
+			Headers:  map[string]string{"Authorization": "Bearer t0k3n-0123456789"},
+		}},
+	}
+
+	var log strings.Builder
+	f := fetcher.NewFetcher(cfg)
+	f.SetAttemptLog(&log)
+	result := f.FetchAll(context.Background())[0]
+	if result.Success {
+		t.Fatal("Expected the fetch to fail")
+	}
+
+	for what, text := range map[string]string{"error": result.Error, "endpoint": result.Endpoint, "attempt log": log.String()} {
+		if strings.Contains(text, "k3y-0123456789") || strings.Contains(text, "t0k3n-0123456789") {
+			t.Errorf("Expected the secrets masked in the %s, got %q", what, text)
+		}
+	}
+	if !strings.Contains(result.Error, "api_key=REDACTED&page=1") {
+		t.Errorf("Expected the masked URL in the error, got %q", result.Error)
+	}
+}
+
+// TestIntegration_FeedAuth tests basic, bearer and OAuth2 client
+// credentials auth, including replacing an OAuth2 token the API rejects
+func TestIntegration_FeedAuth(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	t.Setenv("TEST_FEED_PASSWORD", "hunter22")
+	t.Setenv("TEST_FEED_TOKEN", "static-token")
+	t.Setenv("TEST_CLIENT_SECRET", "client-secret")
+
+	var mu sync.Mutex
+	tokensIssued := 0
+	valid := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		ok := false
+		switch r.URL.Path {
+		case "/token":
+			id, secret, _ := r.BasicAuth()
+			r.ParseForm()
+			if id != "my-app" || secret != "client-secret" || r.Form.Get("grant_type") != "client_credentials" || r.Form.Get("scope") != "read" {
+				http.Error(w, "bad client", http.StatusUnauthorized)
+				return
+			}
+			tokensIssued++
+			token := fmt.Sprintf("token-%d", tokensIssued)
+			valid[token] = true
+			fmt.Fprintf(w, `{"access_token":%q,"token_type":"bearer","expires_in":3600}`, token)
+			return
+		case "/basic":
+			user, password, _ := r.BasicAuth()
+			ok = user == "alice" && password == "hunter22"
+		case "/bearer":
+			ok = r.Header.Get("Authorization") == "Bearer static-token"
+		case "/oauth":
+			ok = valid[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+		}
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`[{"title":"Private","url":"https://example.com/p"}]`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Settings: config.Settings{MaxConcurrency: 3, DefaultTimeoutSecs: 5, RetryMax: 0},
+		Feeds: []config.Feed{
+			{Name: "Basic", URL: server.URL + "/basic", FeedType: "json", Auth: &config.AuthConfig{Type: config.AuthBasic, Username: "alice", PasswordEnv: "TEST_FEED_PASSWORD"}},
+			{Name: "Bearer", URL: server.URL + "/bearer", FeedType: "json", Auth: &config.AuthConfig{Type: config.AuthBearer, TokenEnv: "TEST_FEED_TOKEN"}},
+			{Name: "OAuth", URL: server.URL + "/oauth", FeedType: "json", Auth: &config.AuthConfig{
+				Type: config.AuthOAuth2, TokenURL: server.URL + "/token", ClientID: "my-app", ClientSecretEnv: "TEST_CLIENT_SECRET", Scopes: []string{"read"},
+			}},
+		},
+	}
+	for i := range cfg.Feeds {
+		if err := cfg.Feeds[i].Validate(); err != nil {
+			t.Fatalf("Validate failed: %v", err)
+		}
+	}
+
+	f := fetcher.NewFetcher(cfg)
+	for _, result := range f.FetchAll(context.Background()) {
+		if !result.Success {
+			t.Errorf("Expected %s to authenticate, got %s", result.Source, result.Error)
+		}
+	}
+
+	// The token is reused until the API rejects it, then replaced
+	if result := f.FetchAll(context.Background())[2]; !result.Success || tokensIssued != 1 {
+		t.Fatalf("Expected the cached token reused, got %+v after %d tokens", result, tokensIssued)
+	}
+	mu.Lock()
+	valid["token-1"] = false
+	mu.Unlock()
+	if result := f.FetchAll(context.Background())[2]; !result.Success || tokensIssued != 2 {
+		t.Errorf("Expected a new token after the old one was rejected, got %+v after %d tokens", result, tokensIssued)
+	}
+
+	// A missing secret fails the fetch without sending anything
+	os.Unsetenv("TEST_FEED_TOKEN")
+	if result := f.FetchAll(context.Background())[1]; result.Success || !strings.Contains(result.Error, "TEST_FEED_TOKEN is not set") {
+		t.Errorf("Expected the fetch to fail for the missing token, got %+v", result)
+	}
+}
+
+// TestIntegration_OAuthTokenPerFeed tests that a slow token endpoint only
+// holds up its own feed, and that tokens expire by the fetcher's clock
+func TestIntegration_OAuthTokenPerFeed(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	t.Setenv("TEST_CLIENT_SECRET", "client-secret")
+	requested := make(chan struct{})
+	release := make(chan struct{})
+	var mu sync.Mutex
+	issued := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow/token":
+			close(requested)
+			<-release
+			fallthrough
+		case "/fast/token":
+			mu.Lock()
+			issued[r.URL.Path]++
+			mu.Unlock()
+			fmt.Fprint(w, `{"access_token":"token","token_type":"bearer","expires_in":3600}`)
+		default:
+			w.Write([]byte(`[{"title":"Private","url":"https://example.com/p"}]`))
+		}
+	}))
+	defer server.Close()
+
+	feed := func(name string) config.Feed {
+		return config.Feed{Name: name, URL: server.URL + "/" + name, FeedType: "json", Auth: &config.AuthConfig{
+			Type: config.AuthOAuth2, TokenURL: server.URL + "/" + name + "/token", ClientID: "my-app", ClientSecretEnv: "TEST_CLIENT_SECRET",
+		}}
+	}
+	cfg := &config.Config{
+		Settings: config.Settings{MaxConcurrency: 2, DefaultTimeoutSecs: 5},
+		Feeds:    []config.Feed{feed("slow"), feed("fast")},
+	}
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	f := fetcher.NewFetcher(cfg)
+	f.SetClock(clock.Fixed(now))
+
+	slow := make(chan fetcher.FetchResult)
+	go func() {
+		result, _ := f.FetchFeed(context.Background(), "slow")
+		slow <- result
+	}()
+	<-requested
+	if result, err := f.FetchFeed(context.Background(), "fast"); err != nil || !result.Success {
+		t.Fatalf("Expected the fast feed not to wait for the slow token, got %+v, %v", result, err)
+	}
+	close(release)
+	if result := <-slow; !result.Success {
+		t.Fatalf("Expected the slow feed to get its token, got %+v", result)
+	}
+
+	f.FetchFeed(context.Background(), "fast")
+	f.SetClock(clock.Fixed(now.Add(2 * time.Hour)))
+	f.FetchFeed(context.Background(), "fast")
+	mu.Lock()
+	defer mu.Unlock()
+	if issued["/fast/token"] != 2 {
+		t.Errorf("Expected the token reused until it expired by the fetcher's clock, got %d tokens", issued["/fast/token"])
+	}
+}
+
+// TestIntegration_Capture tests that a capture records every exchange with
+// secrets masked and bodies truncated, without changing what is parsed
+func TestIntegration_Capture(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	t.Setenv("TEST_CAPTURE_TOKEN", "capture-token-0123")
+	body := `[{"title":"First","url":"https://example.com/1"},{"title":"Second","url":"https://example.com/2","note":"` + strings.Repeat("x", 200) + `"}]`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer capture-token-0123" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=s3ss10n-value")
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Settings: config.Settings{MaxConcurrency: 1, DefaultTimeoutSecs: 5},
+		Feeds: []config.Feed{{
+			Name:     "Captured",
+			URL:      server.URL + "/feed?api_key=k3y-0123456789",
+			FeedType: "json",
+			Auth:     &config.AuthConfig{Type: config.AuthBearer, TokenEnv: "TEST_CAPTURE_TOKEN"},
+		}},
+	}
+
+	var capture strings.Builder
+	f := fetcher.NewFetcher(cfg)
+	f.SetCapture(&capture, 100)
+	result, err := f.FetchFeed(context.Background(), "Captured")
+	if err != nil {
+		t.Fatalf("FetchFeed failed: %v", err)
+	}
+	if !result.Success || result.ItemsCount != 2 {
+		t.Fatalf("Expected the capture not to change the fetch, got %+v", result)
+	}
+
+	text := capture.String()
+	for _, want := range []string{
+		"=== Exchange 1",
+		"> GET " + server.URL + "/feed?api_key=REDACTED",
+		"> Authorization: REDACTED",
+		"< HTTP/1.1 200 OK",
+		"< Set-Cookie: REDACTED",
+		fmt.Sprintf("[truncated: 100 of %d bytes shown]", len(body)),
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected the capture to contain %q, got:\n%s", want, text)
+		}
+	}
+	for _, secret := range []string{"k3y-0123456789", "capture-token-0123", "s3ss10n-value"} {
+		if strings.Contains(text, secret) {
+			t.Errorf("Expected %q masked in the capture, got:\n%s", secret, text)
+		}
+	}
+}
+
+// TestIntegration_CaptureOAuthToken tests that the token endpoint's answer
+// is captured with its tokens masked
+func TestIntegration_CaptureOAuthToken(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	t.Setenv("TEST_CLIENT_SECRET", "client-secret")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			fmt.Fprint(w, `{"access_token":"at-0123456789","token_type":"bearer","expires_in":3600,"refresh_token":"rt-0123456789"}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer at-0123456789" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `[{"title":"Private","url":"https://example.com/p"}]`)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Settings: config.Settings{MaxConcurrency: 1, DefaultTimeoutSecs: 5},
+		Feeds: []config.Feed{{
+			Name: "OAuth", URL: server.URL + "/feed", FeedType: "json", Auth: &config.AuthConfig{
+				Type: config.AuthOAuth2, TokenURL: server.URL + "/token", ClientID: "my-app", ClientSecretEnv: "TEST_CLIENT_SECRET",
+			},
+		}},
+	}
+
+	var capture strings.Builder
+	f := fetcher.NewFetcher(cfg)
+	f.SetCapture(&capture, 1024)
+	if result, err := f.FetchFeed(context.Background(), "OAuth"); err != nil || !result.Success {
+		t.Fatalf("Expected the fetch to succeed, got %+v, %v", result, err)
+	}
+
+	text := capture.String()
+	if !strings.Contains(text, `"access_token":"REDACTED"`) || !strings.Contains(text, `"refresh_token":"REDACTED"`) {
+		t.Errorf("Expected the token response captured with its tokens masked, got:\n%s", text)
+	}
+	for _, secret := range []string{"at-0123456789", "rt-0123456789", "client-secret"} {
+		if strings.Contains(text, secret) {
+			t.Errorf("Expected %q masked in the capture, got:\n%s", secret, text)
+		}
+	}
+}
+
+func TestIntegration_Filters(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	body := `{"title":"Go 1.30 released","url":"https://example.com/go","tags":["go"]}
+{"title":"Sponsored: buy this","url":"https://ads.example.com/1","tags":["go"]}
+{"title":"Rust in production","url":"https://example.com/rust","tags":["Rust"]}
+{"title":"Cooking pasta","url":"https://example.com/pasta","tags":["food"]}
+`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Settings: config.Settings{
+			MaxConcurrency:     1,
+			DefaultTimeoutSecs: 5,
+			Filters: &config.FiltersConfig{
+				Exclude: []config.FilterRule{{Field: config.FilterURL, Regex: `^https://ads\.`}},
+			},
+		},
+		Feeds: []config.Feed{
+			{
+				Name:     "Filtered",
+				URL:      server.URL,
+				FeedType: "ndjson",
+				Filters: &config.FiltersConfig{
+					Include: []config.FilterRule{{Field: config.FilterTags, Keyword: "rust"}, {Field: config.FilterTitle, Regex: `\bGo\b`}},
+				},
+			},
+			{Name: "Unfiltered", URL: server.URL, FeedType: "ndjson"},
+		},
+	}
+
+	f := fetcher.NewFetcher(cfg)
+	result, err := f.FetchFeed(context.Background(), "Filtered")
+	if err != nil {
+		t.Fatalf("FetchFeed failed: %v", err)
+	}
+	if result.ItemsCount != 2 || result.Filtered != 2 {
+		t.Fatalf("Expected 2 items kept and 2 filtered, got %d and %d", result.ItemsCount, result.Filtered)
+	}
+	if result.Items[0].Title != "Go 1.30 released" || result.Items[1].Title != "Rust in production" {
+		t.Errorf("Expected the Go and Rust items, got %q and %q", result.Items[0].Title, result.Items[1].Title)
+	}
+
+	// Only the global filters apply to other feeds
+	result, err = f.FetchFeed(context.Background(), "Unfiltered")
+	if err != nil {
+		t.Fatalf("FetchFeed failed: %v", err)
+	}
+	if result.ItemsCount != 3 || result.Filtered != 1 {
+		t.Errorf("Expected 3 items kept and 1 filtered, got %d and %d", result.ItemsCount, result.Filtered)
+	}
+}
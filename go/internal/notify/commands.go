@@ -0,0 +1,107 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"feedpulse/internal/storage"
+)
+
+// LatestLimit is how many items /latest lists
+const LatestLimit = 10
+
+// helpMessage lists the bot commands
+var helpMessage = Message{
+	Title: "feedpulse commands",
+	Sections: []Section{{Entries: []Entry{
+		{Title: "/latest [tag]", Detail: fmt.Sprintf("the %d newest items, optionally only those with a tag", LatestLimit)},
+		{Title: "/stats", Detail: "items and fetch errors per source"},
+	}}},
+}
+
+// Answer answers a bot command such as "/latest golang" from store, with
+// times in loc. It reports false for text that isn't a command, which
+// bots ignore.
+func Answer(store storage.Store, text string, loc *time.Location) (Message, bool, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return Message{}, false, nil
+	}
+	// In groups commands are addressed as /latest@examplebot
+	command, _, _ := strings.Cut(strings.TrimPrefix(fields[0], "/"), "@")
+	args := fields[1:]
+
+	switch strings.ToLower(command) {
+	case "latest":
+		tag := ""
+		if len(args) > 0 {
+			tag = args[0]
+		}
+		msg, err := latest(store, tag)
+		return msg, true, err
+	case "stats":
+		msg, err := stats(store, loc)
+		return msg, true, err
+	case "start", "help":
+		return helpMessage, true, nil
+	default:
+		msg := helpMessage
+		msg.Title = fmt.Sprintf("Unknown command /%s", command)
+		return msg, true, nil
+	}
+}
+
+// latest builds the answer to /latest
+func latest(store storage.Store, tag string) (Message, error) {
+	items, err := store.LatestItems(tag, LatestLimit)
+	if err != nil {
+		return Message{}, err
+	}
+
+	msg := Message{Title: "Latest items"}
+	if tag != "" {
+		msg.Title = fmt.Sprintf("Latest items tagged %s", tag)
+	}
+	if len(items) == 0 {
+		msg.Footer = "No items."
+		return msg, nil
+	}
+	var section Section
+	for _, item := range items {
+		section.Entries = append(section.Entries, Entry{Title: item.Title, URL: item.URL, Detail: item.Source})
+	}
+	msg.Sections = []Section{section}
+	return msg, nil
+}
+
+// stats builds the answer to /stats
+func stats(store storage.Store, loc *time.Location) (Message, error) {
+	all, err := store.GetFetchStats()
+	if err != nil {
+		return Message{}, err
+	}
+
+	msg := Message{Title: "Sources"}
+	if len(all) == 0 {
+		msg.Footer = "No fetches yet."
+		return msg, nil
+	}
+	var section Section
+	total := 0
+	for _, stat := range all {
+		detail := fmt.Sprintf("%d items, %d of %d fetches failed", stat.ItemsCount, stat.ErrorCount, stat.TotalFetches)
+		if stat.LastSuccess != nil {
+			if t, err := time.Parse(time.RFC3339, *stat.LastSuccess); err == nil {
+				detail += ", last success " + t.In(loc).Format("2006-01-02 15:04 MST")
+			}
+		} else {
+			detail += ", never succeeded"
+		}
+		section.Entries = append(section.Entries, Entry{Title: stat.Source, Detail: detail})
+		total += stat.ItemsCount
+	}
+	msg.Sections = []Section{section}
+	msg.Footer = fmt.Sprintf("%d items in total", total)
+	return msg, nil
+}
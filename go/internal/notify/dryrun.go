@@ -0,0 +1,43 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"feedpulse/internal/storage"
+)
+
+// DryRun wraps a notifier, writing what it would send instead of sending
+type DryRun struct {
+	notifier Notifier
+	w        io.Writer
+}
+
+// NewDryRun creates a notifier that logs n's messages to w
+func NewDryRun(n Notifier, w io.Writer) *DryRun {
+	return &DryRun{notifier: n, w: w}
+}
+
+// Name returns the wrapped notifier's name
+func (d *DryRun) Name() string { return d.notifier.Name() }
+
+// Send writes msg's text to the log
+func (d *DryRun) Send(ctx context.Context, msg Message) error {
+	_, err := fmt.Fprintf(d.w, "[dry run] %s message:\n%s\n", d.notifier.Name(), msg.Text())
+	return err
+}
+
+// Sample builds the message 'notify test' sends to check a notifier's
+// settings; templates render it like any new-item message
+func Sample() Message {
+	msg := NewItems([]storage.FeedItem{
+		{ID: "sample-1", Title: "An example item", URL: "https://example.com/items/1", Source: "Example Feed", Tags: []string{"golang"}, CreatedAt: time.Now()},
+		{ID: "sample-2", Title: "Another example item", URL: "https://example.com/items/2", Source: "Example Feed", CreatedAt: time.Now()},
+	})
+	msg.Title = "feedpulse test message"
+	msg.data.Title = msg.Title
+	msg.Footer = "If you can read this, notifications are working."
+	return msg
+}
@@ -0,0 +1,170 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxRetryAfter caps how long Send waits when the homeserver rate limits it
+const maxRetryAfter = 30 * time.Second
+
+// Matrix posts to a Matrix room as the user an access token belongs to.
+// The room is given by ID (!abc:example.org) or alias (#news:example.org);
+// the user must already have joined it.
+type Matrix struct {
+	http       *http.Client
+	homeserver string
+	token      string
+	room       string
+
+	mu     sync.Mutex
+	roomID string
+	txn    uint64
+}
+
+// NewMatrix creates a client for room on homeserver
+func NewMatrix(homeserver, accessToken, room string) *Matrix {
+	return &Matrix{
+		http:       &http.Client{Timeout: 30 * time.Second},
+		homeserver: strings.TrimSuffix(homeserver, "/"),
+		token:      accessToken,
+		room:       room,
+	}
+}
+
+// Name returns "matrix"
+func (m *Matrix) Name() string { return "matrix" }
+
+// matrixError is the homeserver's JSON answer to a failed request
+type matrixError struct {
+	ErrCode      string `json:"errcode"`
+	Error        string `json:"error"`
+	RetryAfterMs int64  `json:"retry_after_ms"`
+}
+
+// Send posts msg to the room as a notice, with an HTML body for clients
+// that render it. A rate-limited message is retried once, after the wait
+// the homeserver asks for.
+func (m *Matrix) Send(ctx context.Context, msg Message) error {
+	roomID, err := m.resolveRoom(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"msgtype":        "m.notice",
+		"body":           msg.Text(),
+		"format":         "org.matrix.custom.html",
+		"formatted_body": msg.HTML(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	// The transaction ID makes a retried request idempotent
+	txnID := fmt.Sprintf("feedpulse-%d-%d", time.Now().UnixNano(), atomic.AddUint64(&m.txn, 1))
+	path := "/_matrix/client/v3/rooms/" + url.PathEscape(roomID) + "/send/m.room.message/" + txnID
+
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := m.do(ctx, http.MethodPut, path, body, nil)
+		if retryAfter == 0 || attempt > 0 {
+			return err
+		}
+		if retryAfter > maxRetryAfter {
+			return fmt.Errorf("%w (retry after %s)", err, retryAfter.Round(time.Second))
+		}
+		select {
+		case <-time.After(retryAfter):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// resolveRoom returns the room's ID, looking up an alias on first use
+func (m *Matrix) resolveRoom(ctx context.Context) (string, error) {
+	if !strings.HasPrefix(m.room, "#") {
+		return m.room, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.roomID != "" {
+		return m.roomID, nil
+	}
+
+	var resp struct {
+		RoomID string `json:"room_id"`
+	}
+	if _, err := m.do(ctx, http.MethodGet, "/_matrix/client/v3/directory/room/"+url.PathEscape(m.room), nil, &resp); err != nil {
+		return "", fmt.Errorf("failed to resolve room %s: %w", m.room, err)
+	}
+	if resp.RoomID == "" {
+		return "", fmt.Errorf("failed to resolve room %s: no room ID returned", m.room)
+	}
+	m.roomID = resp.RoomID
+	return m.roomID, nil
+}
+
+// do performs a client API request, decoding a JSON answer into v if it
+// is set. When the homeserver rate limits the request, the returned
+// duration is how long it asked to wait.
+func (m *Matrix) do(ctx context.Context, method, path string, body []byte, v interface{}) (time.Duration, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, m.homeserver+path, reader)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+m.token)
+	req.Header.Set("User-Agent", "feedpulse/1.0")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := m.http.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("matrix request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read matrix response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var merr matrixError
+		json.Unmarshal(data, &merr)
+		err := fmt.Errorf("matrix returned %s", resp.Status)
+		if merr.ErrCode != "" {
+			err = fmt.Errorf("matrix returned %s: %s %s", resp.Status, merr.ErrCode, merr.Error)
+		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			retryAfter := time.Duration(merr.RetryAfterMs) * time.Millisecond
+			if retryAfter <= 0 {
+				retryAfter = time.Second
+			}
+			return retryAfter, err
+		}
+		return 0, err
+	}
+
+	if v != nil {
+		if err := json.Unmarshal(data, v); err != nil {
+			return 0, fmt.Errorf("invalid matrix response: %w", err)
+		}
+	}
+	return 0, nil
+}
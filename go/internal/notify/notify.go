@@ -0,0 +1,155 @@
+// Package notify posts new items and digests to chat rooms.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	"feedpulse/internal/storage"
+)
+
+// MaxNewItems is how many new items one message lists; the rest are
+// counted in its footer
+const MaxNewItems = 25
+
+// Notifier posts messages to a chat service
+type Notifier interface {
+	// Name is the service's name in the config, e.g. "matrix"
+	Name() string
+	// Send posts msg
+	Send(ctx context.Context, msg Message) error
+}
+
+// Message is a titled list of items, in sections
+type Message struct {
+	Title    string
+	Sections []Section
+	// Footer is a closing line, e.g. how many items were left out
+	Footer string
+	// Body replaces the title, sections and footer when set, e.g. with
+	// a rendered template
+	Body string
+
+	// data is what templates render
+	data *TemplateData
+}
+
+// Section is a group of entries under an optional heading
+type Section struct {
+	Heading string
+	Entries []Entry
+}
+
+// Entry is one item of a message
+type Entry struct {
+	Title string
+	URL   string
+	// Detail follows the title, e.g. the item's sources
+	Detail string
+}
+
+// NewItems builds the message announcing freshly fetched items, grouped
+// by source in the order they were given
+func NewItems(items []storage.FeedItem) Message {
+	msg := Message{Title: fmt.Sprintf("%d new item(s)", len(items))}
+	msg.data = &TemplateData{Kind: KindNewItems, Title: msg.Title}
+	if len(items) > MaxNewItems {
+		msg.Footer = fmt.Sprintf("…and %d more", len(items)-MaxNewItems)
+		msg.data.More = len(items) - MaxNewItems
+		items = items[:MaxNewItems]
+	}
+	msg.data.Items = items
+
+	for _, group := range groupBySource(items) {
+		section := Section{Heading: group.Source}
+		for _, item := range group.Items {
+			section.Entries = append(section.Entries, Entry{Title: item.Title, URL: item.URL})
+		}
+		msg.Sections = append(msg.Sections, section)
+	}
+	return msg
+}
+
+// Digest builds the message for a digest of the last since, one section
+// per topic
+func Digest(topics []storage.DigestTopic, since string) Message {
+	msg := Message{Title: "Top items of the last " + since}
+	msg.data = &TemplateData{Kind: KindDigest, Title: msg.Title, Topics: topics, Since: since}
+	if len(topics) == 0 {
+		msg.Footer = "No items."
+	}
+	for _, topic := range topics {
+		section := Section{Heading: topic.Tag}
+		for _, item := range topic.Items {
+			section.Entries = append(section.Entries, Entry{
+				Title:  item.Title,
+				URL:    item.URL,
+				Detail: strings.Join(item.Sources, ", "),
+			})
+		}
+		msg.Sections = append(msg.Sections, section)
+	}
+	return msg
+}
+
+// Text renders msg as plain text, for clients that don't show HTML
+func (m Message) Text() string {
+	if m.Body != "" {
+		return m.Body
+	}
+	var b strings.Builder
+	b.WriteString(m.Title)
+	b.WriteString("\n")
+	for _, section := range m.Sections {
+		if section.Heading != "" {
+			fmt.Fprintf(&b, "\n%s\n", section.Heading)
+		}
+		for _, e := range section.Entries {
+			b.WriteString("• " + e.Title)
+			if e.Detail != "" {
+				fmt.Fprintf(&b, " (%s)", e.Detail)
+			}
+			if e.URL != "" {
+				b.WriteString(" " + e.URL)
+			}
+			b.WriteString("\n")
+		}
+	}
+	if m.Footer != "" {
+		fmt.Fprintf(&b, "\n%s\n", m.Footer)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// HTML renders msg as an HTML fragment
+func (m Message) HTML() string {
+	if m.Body != "" {
+		return strings.ReplaceAll(html.EscapeString(m.Body), "\n", "<br>")
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "<strong>%s</strong>", html.EscapeString(m.Title))
+	for _, section := range m.Sections {
+		if section.Heading != "" {
+			fmt.Fprintf(&b, "<h4>%s</h4>", html.EscapeString(section.Heading))
+		}
+		b.WriteString("<ul>")
+		for _, e := range section.Entries {
+			title := html.EscapeString(e.Title)
+			if e.URL != "" {
+				title = fmt.Sprintf("<a href=\"%s\">%s</a>", html.EscapeString(e.URL), title)
+			}
+			b.WriteString("<li>" + title)
+			if e.Detail != "" {
+				fmt.Fprintf(&b, " <em>(%s)</em>", html.EscapeString(e.Detail))
+			}
+			b.WriteString("</li>")
+		}
+		b.WriteString("</ul>")
+	}
+	if m.Footer != "" {
+		fmt.Fprintf(&b, "<p>%s</p>", html.EscapeString(m.Footer))
+	}
+	return b.String()
+}
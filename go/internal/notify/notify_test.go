@@ -0,0 +1,531 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"feedpulse/internal/storage"
+	"feedpulse/internal/testutil"
+)
+
+func TestNewItems_GroupsBySourceAndCaps(t *testing.T) {
+	var items []storage.FeedItem
+	for i := 0; i < MaxNewItems+2; i++ {
+		source := "HN"
+		if i%2 == 1 {
+			source = "Lobsters"
+		}
+		items = append(items, storage.FeedItem{Title: "T", URL: "https://example.com", Source: source})
+	}
+
+	msg := NewItems(items)
+	if msg.Title != "27 new item(s)" || msg.Footer != "…and 2 more" {
+		t.Errorf("unexpected title or footer: %q, %q", msg.Title, msg.Footer)
+	}
+	if len(msg.Sections) != 2 || msg.Sections[0].Heading != "HN" || msg.Sections[1].Heading != "Lobsters" {
+		t.Fatalf("expected HN then Lobsters sections, got %+v", msg.Sections)
+	}
+	if n := len(msg.Sections[0].Entries) + len(msg.Sections[1].Entries); n != MaxNewItems {
+		t.Errorf("expected %d entries, got %d", MaxNewItems, n)
+	}
+}
+
+func TestMessage_Render(t *testing.T) {
+	msg := Digest([]storage.DigestTopic{{
+		Tag: "go",
+		Items: []storage.DigestItem{{
+			FeedItem: storage.FeedItem{Title: "Generics <finally>", URL: "https://example.com/a?x=1&y=2"},
+			Sources:  []string{"HN", "Lobsters"},
+		}},
+	}}, "24h")
+
+	wantText := "Top items of the last 24h\n\ngo\n• Generics <finally> (HN, Lobsters) https://example.com/a?x=1&y=2"
+	if got := msg.Text(); got != wantText {
+		t.Errorf("Text() = %q, want %q", got, wantText)
+	}
+
+	wantHTML := `<strong>Top items of the last 24h</strong><h4>go</h4><ul><li><a href="https://example.com/a?x=1&amp;y=2">Generics &lt;finally&gt;</a> <em>(HN, Lobsters)</em></li></ul>`
+	if got := msg.HTML(); got != wantHTML {
+		t.Errorf("HTML() = %q, want %q", got, wantHTML)
+	}
+}
+
+func TestMatrix_Send(t *testing.T) {
+	var sent map[string]string
+	var paths []string
+	limited := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"errcode":"M_UNKNOWN_TOKEN","error":"Invalid access token"}`))
+			return
+		}
+		paths = append(paths, r.Method+" "+r.URL.EscapedPath())
+		switch {
+		case r.Method == http.MethodGet && r.URL.EscapedPath() == "/_matrix/client/v3/directory/room/%23news:example.org":
+			w.Write([]byte(`{"room_id":"!abc:example.org","servers":["example.org"]}`))
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/_matrix/client/v3/rooms/!abc:example.org/send/m.room.message/"):
+			if limited {
+				limited = false
+				w.WriteHeader(http.StatusTooManyRequests)
+				w.Write([]byte(`{"errcode":"M_LIMIT_EXCEEDED","error":"Too many requests","retry_after_ms":10}`))
+				return
+			}
+			json.NewDecoder(r.Body).Decode(&sent)
+			w.Write([]byte(`{"event_id":"$1"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	m := NewMatrix(srv.URL+"/", "secret", "#news:example.org")
+	msg := NewItems([]storage.FeedItem{{Title: "A", URL: "https://example.com/a", Source: "HN", CreatedAt: time.Now()}})
+	if err := m.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if sent["msgtype"] != "m.notice" || sent["format"] != "org.matrix.custom.html" || sent["body"] != msg.Text() || sent["formatted_body"] != msg.HTML() {
+		t.Errorf("unexpected event: %v", sent)
+	}
+
+	// The alias is resolved once, and the retry reuses the transaction ID
+	if len(paths) != 3 || paths[1] != paths[2] {
+		t.Errorf("expected a lookup and one retried send, got %v", paths)
+	}
+	if err := m.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 4 || strings.HasPrefix(paths[3], "GET") || paths[3] == paths[2] {
+		t.Errorf("expected a new send without a lookup, got %v", paths)
+	}
+
+	bad := NewMatrix(srv.URL, "wrong", "!abc:example.org")
+	err := bad.Send(context.Background(), msg)
+	if err == nil || !strings.Contains(err.Error(), "M_UNKNOWN_TOKEN") {
+		t.Errorf("expected the homeserver's error code, got %v", err)
+	}
+}
+
+func TestTelegram_SendAndUpdates(t *testing.T) {
+	var sent []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var params map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&params)
+		switch r.URL.Path {
+		case "/botsecret/sendMessage":
+			sent = append(sent, params)
+			w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+		case "/botsecret/getUpdates":
+			if params["offset"] != float64(7) {
+				t.Errorf("unexpected offset: %v", params["offset"])
+			}
+			w.Write([]byte(`{"ok":true,"result":[
+				{"update_id":7,"message":{"text":"/latest go","chat":{"id":-100,"type":"group"}}},
+				{"update_id":8,"message":{"text":"/stats","chat":{"id":5,"type":"channel","username":"news"}}},
+				{"update_id":9,"edited_message":{}}
+			]}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"ok":false,"error_code":401,"description":"Unauthorized"}`))
+		}
+	}))
+	defer srv.Close()
+
+	tg := NewTelegram("secret", "-100")
+	tg.SetEndpoint(srv.URL)
+
+	var items []storage.FeedItem
+	for i := 0; i < MaxNewItems; i++ {
+		items = append(items, storage.FeedItem{Title: strings.Repeat("<long title> ", 20), URL: "https://example.com/a?x=1&y=2", Source: "HN"})
+	}
+	if err := tg.Send(context.Background(), NewItems(items)); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) < 2 {
+		t.Fatalf("expected the long message to be split, got %d message(s)", len(sent))
+	}
+	for _, params := range sent {
+		text, _ := params["text"].(string)
+		if params["chat_id"] != "-100" || params["parse_mode"] != "HTML" || len([]rune(text)) > telegramMaxLength {
+			t.Errorf("unexpected sendMessage: chat %v, mode %v, %d runes", params["chat_id"], params["parse_mode"], len([]rune(text)))
+		}
+	}
+	if first, _ := sent[0]["text"].(string); !strings.HasPrefix(first, "<b>25 new item(s)</b>\n\n<b>HN</b>\n• <a href=\"https://example.com/a?x=1&amp;y=2\">&lt;long title&gt;") {
+		t.Errorf("unexpected formatting: %q", first)
+	}
+
+	updates, err := tg.Updates(context.Background(), 7, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updates) != 3 || updates[0].ChatID != "-100" || updates[0].Text != "/latest go" || updates[2].Text != "" {
+		t.Fatalf("unexpected updates: %+v", updates)
+	}
+	if !tg.FromChat(updates[0]) || tg.FromChat(updates[1]) {
+		t.Error("expected only the configured chat to be trusted")
+	}
+	if channel := NewTelegram("secret", "@news"); !channel.FromChat(updates[1]) {
+		t.Error("expected a channel to be matched by @username")
+	}
+
+	bad := NewTelegram("wrong", "-100")
+	bad.SetEndpoint(srv.URL)
+	err = bad.Send(context.Background(), NewItems(items[:1]))
+	if err == nil || err.Error() != "telegram sendMessage: Unauthorized" || strings.Contains(err.Error(), "wrong") {
+		t.Errorf("expected the API's description without the token, got %v", err)
+	}
+}
+
+func TestAnswer(t *testing.T) {
+	store := testutil.NewMockStore()
+	now := time.Now()
+	store.SaveFetchResult(storage.FetchLog{Source: "HN", FetchedAt: now, Status: "success", ItemsCount: 2}, []storage.FeedItem{
+		{ID: "1", Title: "Go 2", URL: "https://example.com/1", Source: "HN", Tags: []string{"golang"}, CreatedAt: now.Add(-time.Hour)},
+		{ID: "2", Title: "Rust 2", URL: "https://example.com/2", Source: "HN", Tags: []string{"rust"}, CreatedAt: now},
+	})
+
+	tests := []struct {
+		text    string
+		ok      bool
+		title   string
+		entries int
+	}{
+		{"hello", false, "", 0},
+		{"/latest golang", true, "Latest items tagged golang", 1},
+		{"/latest@feedpulse_bot", true, "Latest items", 2},
+		{"/latest elixir", true, "Latest items tagged elixir", 0},
+		{"/stats", true, "Sources", 1},
+		{"/help", true, "feedpulse commands", 2},
+		{"/frobnicate", true, "Unknown command /frobnicate", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			msg, ok, err := Answer(store, tt.text, time.UTC)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.ok || msg.Title != tt.title {
+				t.Fatalf("Answer() = %q, %v; want %q, %v", msg.Title, ok, tt.title, tt.ok)
+			}
+			entries := 0
+			for _, section := range msg.Sections {
+				entries += len(section.Entries)
+			}
+			if entries != tt.entries {
+				t.Errorf("expected %d entries, got %d", tt.entries, entries)
+			}
+		})
+	}
+
+	tokyo := time.FixedZone("JST", 9*60*60)
+	msg, _, err := Answer(store, "/stats", tokyo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := now.In(tokyo).Format("2006-01-02 15:04 MST"); !strings.Contains(msg.Sections[0].Entries[0].Detail, want) {
+		t.Errorf("expected the last success in JST (%s), got %q", want, msg.Sections[0].Entries[0].Detail)
+	}
+}
+
+// fakeXMPPServer accepts one client on ln, requiring STARTTLS and the
+// password "pw", and returns the bodies of the messages it was sent
+func fakeXMPPServer(ln net.Listener, config *tls.Config) <-chan []string {
+	bodies := make(chan []string, 1)
+	go func() {
+		defer close(bodies)
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var rw io.ReadWriter = conn
+		dec := xml.NewDecoder(rw)
+		next := func() xml.StartElement {
+			for {
+				tok, err := dec.Token()
+				if err != nil {
+					return xml.StartElement{}
+				}
+				if el, ok := tok.(xml.StartElement); ok {
+					return el
+				}
+			}
+		}
+		restart := func(features string) {
+			next()
+			fmt.Fprintf(rw, "<stream:stream xmlns='jabber:client' xmlns:stream='http://etherx.jabber.org/streams' from='example.com' version='1.0'><stream:features>%s</stream:features>", features)
+		}
+
+		restart("<starttls xmlns='urn:ietf:params:xml:ns:xmpp-tls'><required/></starttls>")
+		if next().Name.Local != "starttls" {
+			return
+		}
+		io.WriteString(rw, "<proceed xmlns='urn:ietf:params:xml:ns:xmpp-tls'/>")
+		tlsConn := tls.Server(conn, config)
+		rw, dec = tlsConn, xml.NewDecoder(tlsConn)
+
+		restart("<mechanisms xmlns='urn:ietf:params:xml:ns:xmpp-sasl'><mechanism>SCRAM-SHA-1</mechanism><mechanism>PLAIN</mechanism></mechanisms>")
+		auth := next()
+		var credentials string
+		dec.DecodeElement(&credentials, &auth)
+		if decoded, _ := base64.StdEncoding.DecodeString(credentials); string(decoded) != "\x00feedpulse\x00pw" {
+			io.WriteString(rw, "<failure xmlns='urn:ietf:params:xml:ns:xmpp-sasl'><not-authorized/></failure>")
+			return
+		}
+		io.WriteString(rw, "<success xmlns='urn:ietf:params:xml:ns:xmpp-sasl'/>")
+
+		restart("<bind xmlns='urn:ietf:params:xml:ns:xmpp-bind'/>")
+		next()
+		dec.Skip()
+		io.WriteString(rw, "<iq type='result' id='bind'><bind xmlns='urn:ietf:params:xml:ns:xmpp-bind'><jid>feedpulse@example.com/feedpulse</jid></bind></iq>")
+
+		var got []string
+		for {
+			el := next()
+			if el.Name.Local != "message" {
+				break
+			}
+			var m struct {
+				To   string `xml:"to,attr"`
+				Body string `xml:"body"`
+			}
+			dec.DecodeElement(&m, &el)
+			got = append(got, m.To+": "+m.Body)
+		}
+		bodies <- got
+	}()
+	return bodies
+}
+
+func TestXMPP_Send(t *testing.T) {
+	// httptest's certificate is valid for example.com
+	certs := httptest.NewTLSServer(http.NotFoundHandler())
+	defer certs.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(certs.Certificate())
+
+	msg := Message{Title: "1 new item(s)", Sections: []Section{{Heading: "HN", Entries: []Entry{{Title: "Q&A <live>", URL: "https://example.com/a"}}}}}
+	for _, password := range []string{"pw", "wrong"} {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		bodies := fakeXMPPServer(ln, certs.TLS)
+
+		x := NewXMPP("feedpulse@example.com", password, []string{"alice@example.com", "bob@example.com"}, ln.Addr().String())
+		x.tlsConfig = &tls.Config{RootCAs: roots, ServerName: "example.com"}
+		err = x.Send(context.Background(), msg)
+		ln.Close()
+
+		if password == "wrong" {
+			if err == nil || err.Error() != "xmpp authentication failed: not-authorized" {
+				t.Errorf("expected the SASL failure reason, got %v", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := <-bodies
+		want := []string{"alice@example.com: " + msg.Text(), "bob@example.com: " + msg.Text()}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got messages %q, want %q", got, want)
+		}
+	}
+}
+
+func TestSMTP_Send(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// A minimal SMTP server without STARTTLS or AUTH, like a local relay
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		fmt.Fprintf(conn, "220 localhost ESMTP\r\n")
+		var lines []string
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			switch cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); cmd {
+			case "EHLO", "HELO":
+				fmt.Fprintf(conn, "250 localhost\r\n")
+			case "MAIL", "RCPT":
+				lines = append(lines, line)
+				fmt.Fprintf(conn, "250 OK\r\n")
+			case "DATA":
+				fmt.Fprintf(conn, "354 Go ahead\r\n")
+				for {
+					data, err := r.ReadString('\n')
+					if err != nil || data == ".\r\n" {
+						break
+					}
+					lines = append(lines, strings.TrimRight(data, "\r\n"))
+				}
+				fmt.Fprintf(conn, "250 Queued\r\n")
+			case "QUIT":
+				fmt.Fprintf(conn, "221 Bye\r\n")
+				received <- lines
+				return
+			default:
+				fmt.Fprintf(conn, "502 Not implemented\r\n")
+			}
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	portNum, _ := strconv.Atoi(port)
+	s := NewSMTP(host, portNum, "feedpulse@example.com", []string{"5551234567@vtext.com"}, "", "", 40)
+	msg := Message{Title: "2 new item(s)", Sections: []Section{{Heading: "HN", Entries: []Entry{
+		{Title: "A fairly long first title", URL: "https://example.com/a"},
+		{Title: "B", URL: "https://example.com/b"},
+	}}}}
+	if err := s.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := <-received
+	joined := strings.Join(lines, "\n")
+	for _, want := range []string{
+		"MAIL FROM:<feedpulse@example.com>",
+		"RCPT TO:<5551234567@vtext.com>",
+		"Subject: 2 new item(s)",
+		"Content-Type: text/plain; charset=utf-8",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("expected %q in the mail, got:\n%s", want, joined)
+		}
+	}
+	if body := strings.Join(lines[len(lines)-2:], "\n"); body != "HN\n• A fairly long first title https://…" {
+		t.Errorf("expected the body truncated to 40 characters without the title, got %q", body)
+	}
+}
+
+func TestSMTP_SendTimesOut(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// A server that accepts the connection but never greets
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(io.Discard, conn)
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	portNum, _ := strconv.Atoi(port)
+	s := NewSMTP(host, portNum, "feedpulse@example.com", []string{"5551234567@vtext.com"}, "", "", 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := s.Send(ctx, Message{Title: "test"}); err == nil {
+		t.Fatal("expected the send to fail")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the send to give up at the deadline, took %s", elapsed)
+	}
+}
+
+func TestDryRun_LogsInsteadOfSending(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("dry run sent a request: %s %s", r.Method, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	var log strings.Builder
+	d := NewDryRun(NewMatrix(srv.URL, "token", "!room:example.com"), &log)
+	if d.Name() != "matrix" {
+		t.Errorf("Name() = %q, want matrix", d.Name())
+	}
+	if err := d.Send(context.Background(), Sample()); err != nil {
+		t.Fatal(err)
+	}
+	if got := log.String(); !strings.HasPrefix(got, "[dry run] matrix message:\nfeedpulse test message\n") || !strings.Contains(got, "https://example.com/items/1") {
+		t.Errorf("unexpected log: %q", got)
+	}
+}
+
+func TestTemplates_Apply(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	tmpl, err := ParseTemplates(
+		`{{.Title}}{{range groupBySource .Items}}
+[{{.Source}}]{{range .Items}}
+- {{.Title | truncate 12}} ({{domain .URL}}, {{timeago .CreatedAt}}){{end}}{{end}}`,
+		`{{.Since}}:{{range .Topics}} #{{.Tag}}{{end}}`,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.now = func() time.Time { return now }
+
+	msg, err := tmpl.Apply(NewItems([]storage.FeedItem{
+		{Title: "A rather long title", URL: "https://www.example.com/a", Source: "HN", CreatedAt: now.Add(-3 * time.Hour)},
+		{Title: "Short", URL: "https://lobste.rs/s/1", Source: "Lobsters", CreatedAt: now.Add(-90 * time.Second)},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "2 new item(s)\n[HN]\n- A rather lo… (example.com, 3h ago)\n[Lobsters]\n- Short (lobste.rs, 1m ago)"
+	if msg.Text() != want {
+		t.Errorf("Text() = %q, want %q", msg.Text(), want)
+	}
+	if got := telegramHTML(msg); len(got) != 1 || got[0] != want {
+		t.Errorf("telegramHTML() = %q, want the rendered body", got)
+	}
+
+	msg, err = tmpl.Apply(Digest([]storage.DigestTopic{{Tag: "go"}, {Tag: "rust"}}, "24h"))
+	if err != nil || msg.Text() != "24h: #go #rust" {
+		t.Errorf("digest = %q, %v", msg.Text(), err)
+	}
+
+	// Bot answers aren't templated
+	if msg, err := tmpl.Apply(helpMessage); err != nil || msg.Body != "" {
+		t.Errorf("help message was templated: %q, %v", msg.Body, err)
+	}
+
+	if _, err := ParseTemplates("{{.Title", ""); err == nil || !strings.Contains(err.Error(), "new_items") {
+		t.Errorf("expected a new_items parse error, got %v", err)
+	}
+	empty, err := ParseTemplates("{{if false}}x{{end}}", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := empty.Apply(NewItems(nil)); err == nil {
+		t.Error("expected an error for an empty rendering")
+	}
+}
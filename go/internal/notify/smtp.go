@@ -0,0 +1,149 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// SMTP sends plain-text mail, e.g. to carriers' email-to-SMS gateways
+// (5551234567@vtext.com). Mail is sent with STARTTLS when the server
+// offers it, and authenticated when a username is set. A server that
+// stops answering fails the send after the context's deadline, or
+// 30 seconds without one.
+type SMTP struct {
+	addr     string
+	from     string
+	to       []string
+	username string
+	password string
+	// maxLength caps the body in characters; 0 means no cap
+	maxLength int
+	timeout   time.Duration
+}
+
+// NewSMTP creates a client sending through host:port from from to the
+// given addresses, truncating bodies to maxLength characters if it is set
+func NewSMTP(host string, port int, from string, to []string, username, password string, maxLength int) *SMTP {
+	return &SMTP{
+		addr:      net.JoinHostPort(host, strconv.Itoa(port)),
+		from:      from,
+		to:        to,
+		username:  username,
+		password:  password,
+		maxLength: maxLength,
+		timeout:   30 * time.Second,
+	}
+}
+
+// Name returns "smtp"
+func (s *SMTP) Name() string { return "smtp" }
+
+// Send mails msg's text, with its title as the subject
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	body := msg.Text()
+	if s.maxLength > 0 {
+		// The title is the subject, and gateways put it in the text too
+		body = strings.TrimPrefix(body, msg.Title+"\n")
+		body = truncate(strings.TrimSpace(body), s.maxLength)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(s.to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Title))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+
+	if err := s.deliver(ctx, []byte(b.String())); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	return nil
+}
+
+// deliver sends one mail the way smtp.SendMail does, over a connection
+// that times out and is closed if ctx is cancelled
+func (s *SMTP) deliver(ctx context.Context, mail []byte) error {
+	host, _, _ := net.SplitHostPort(s.addr)
+	dialer := net.Dialer{Timeout: s.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(s.timeout))
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return sendError(ctx, err)
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return sendError(ctx, err)
+		}
+	}
+	if s.username != "" {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return fmt.Errorf("server doesn't support AUTH")
+		}
+		if err := c.Auth(smtp.PlainAuth("", s.username, s.password, host)); err != nil {
+			return sendError(ctx, err)
+		}
+	}
+
+	if err := c.Mail(s.from); err != nil {
+		return sendError(ctx, err)
+	}
+	for _, to := range s.to {
+		if err := c.Rcpt(to); err != nil {
+			return sendError(ctx, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return sendError(ctx, err)
+	}
+	if _, err := w.Write(mail); err != nil {
+		return sendError(ctx, err)
+	}
+	if err := w.Close(); err != nil {
+		return sendError(ctx, err)
+	}
+	return sendError(ctx, c.Quit())
+}
+
+// sendError returns ctx's error in place of err when the connection failed
+// because ctx was cancelled
+func sendError(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// truncate shortens s to at most n characters, marking the cut with "…"
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n-1]) + "…"
+}
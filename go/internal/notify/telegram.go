@@ -0,0 +1,223 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// telegramMaxLength is the longest text one Telegram message can carry
+const telegramMaxLength = 4096
+
+// Telegram posts to a chat through a bot, and reads the commands users
+// send the bot. The chat is given by numeric ID or, for public channels,
+// @username; the bot must be a member.
+type Telegram struct {
+	http     *http.Client
+	endpoint string
+	token    string
+	chatID   string
+}
+
+// NewTelegram creates a client for the bot with token, posting to chatID
+func NewTelegram(token, chatID string) *Telegram {
+	return &Telegram{
+		// Long polls for updates hold the request open
+		http:     &http.Client{Timeout: 90 * time.Second},
+		endpoint: "https://api.telegram.org",
+		token:    token,
+		chatID:   chatID,
+	}
+}
+
+// SetEndpoint points the client at another Bot API server, e.g. a test
+// server
+func (t *Telegram) SetEndpoint(endpoint string) {
+	t.endpoint = strings.TrimSuffix(endpoint, "/")
+}
+
+// Name returns "telegram"
+func (t *Telegram) Name() string { return "telegram" }
+
+// Send posts msg to the chat, split into several messages if it is too
+// long for one
+func (t *Telegram) Send(ctx context.Context, msg Message) error {
+	return t.Reply(ctx, t.chatID, msg)
+}
+
+// Reply posts msg to chatID, e.g. the chat a command came from
+func (t *Telegram) Reply(ctx context.Context, chatID string, msg Message) error {
+	for _, text := range telegramHTML(msg) {
+		err := t.call(ctx, "sendMessage", map[string]interface{}{
+			"chat_id":                  chatID,
+			"text":                     text,
+			"parse_mode":               "HTML",
+			"disable_web_page_preview": true,
+		}, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Update is a text message sent to the bot
+type Update struct {
+	ID int64
+	// ChatID is the chat the message was sent in, to reply to
+	ChatID string
+	// ChatName is the chat's @username, if it has one
+	ChatName string
+	Text     string
+}
+
+// FromChat reports whether the update came from the chat the bot posts to
+func (t *Telegram) FromChat(u Update) bool {
+	return u.ChatID == t.chatID || (u.ChatName != "" && "@"+u.ChatName == t.chatID)
+}
+
+// Updates waits up to wait for messages sent to the bot after offset,
+// the ID after the last update handled
+func (t *Telegram) Updates(ctx context.Context, offset int64, wait time.Duration) ([]Update, error) {
+	var result []struct {
+		UpdateID int64 `json:"update_id"`
+		Message  *struct {
+			Text string `json:"text"`
+			Chat struct {
+				ID       int64  `json:"id"`
+				Username string `json:"username"`
+			} `json:"chat"`
+		} `json:"message"`
+	}
+	err := t.call(ctx, "getUpdates", map[string]interface{}{
+		"offset":          offset,
+		"timeout":         int(wait.Seconds()),
+		"allowed_updates": []string{"message"},
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	var updates []Update
+	for _, r := range result {
+		u := Update{ID: r.UpdateID}
+		if r.Message != nil {
+			u.ChatID = strconv.FormatInt(r.Message.Chat.ID, 10)
+			u.ChatName = r.Message.Chat.Username
+			u.Text = r.Message.Text
+		}
+		updates = append(updates, u)
+	}
+	return updates, nil
+}
+
+// call invokes a Bot API method, decoding its result into v if it is set
+func (t *Telegram) call(ctx context.Context, method string, params map[string]interface{}, v interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint+"/bot"+t.token+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "feedpulse/1.0")
+
+	resp, err := t.http.Do(req)
+	if err != nil {
+		// The URL carries the token; keep it out of the error
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("telegram %s request failed", method)
+	}
+	defer resp.Body.Close()
+
+	var answer struct {
+		OK          bool            `json:"ok"`
+		Result      json.RawMessage `json:"result"`
+		Description string          `json:"description"`
+		Parameters  struct {
+			RetryAfter int `json:"retry_after"`
+		} `json:"parameters"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4<<20)).Decode(&answer); err != nil {
+		return fmt.Errorf("telegram %s returned %s", method, resp.Status)
+	}
+	if !answer.OK {
+		if answer.Parameters.RetryAfter > 0 {
+			return fmt.Errorf("telegram %s: %s (retry after %ds)", method, answer.Description, answer.Parameters.RetryAfter)
+		}
+		return fmt.Errorf("telegram %s: %s", method, answer.Description)
+	}
+
+	if v != nil {
+		if err := json.Unmarshal(answer.Result, v); err != nil {
+			return fmt.Errorf("invalid telegram %s response: %w", method, err)
+		}
+	}
+	return nil
+}
+
+// telegramHTML renders msg in the HTML subset Telegram accepts, split at
+// line breaks into texts that fit a message each
+func telegramHTML(msg Message) []string {
+	var lines []string
+	if msg.Body != "" {
+		lines = strings.Split(html.EscapeString(msg.Body), "\n")
+	} else {
+		lines = telegramLines(msg)
+	}
+
+	var texts []string
+	var b strings.Builder
+	for _, line := range lines {
+		if b.Len() > 0 && utf8.RuneCountInString(b.String())+1+utf8.RuneCountInString(line) > telegramMaxLength {
+			texts = append(texts, strings.TrimSpace(b.String()))
+			b.Reset()
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(line)
+	}
+	if text := strings.TrimSpace(b.String()); text != "" {
+		texts = append(texts, text)
+	}
+	return texts
+}
+
+// telegramLines renders msg's title, sections and footer as lines of
+// Telegram HTML
+func telegramLines(msg Message) []string {
+	lines := []string{"<b>" + html.EscapeString(msg.Title) + "</b>"}
+	for _, section := range msg.Sections {
+		lines = append(lines, "")
+		if section.Heading != "" {
+			lines = append(lines, "<b>"+html.EscapeString(section.Heading)+"</b>")
+		}
+		for _, e := range section.Entries {
+			line := html.EscapeString(e.Title)
+			if e.URL != "" {
+				line = fmt.Sprintf("<a href=\"%s\">%s</a>", html.EscapeString(e.URL), line)
+			}
+			if e.Detail != "" {
+				line += " <i>(" + html.EscapeString(e.Detail) + ")</i>"
+			}
+			lines = append(lines, "• "+line)
+		}
+	}
+	if msg.Footer != "" {
+		lines = append(lines, "", html.EscapeString(msg.Footer))
+	}
+	return lines
+}
@@ -0,0 +1,168 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"feedpulse/internal/clock"
+	"feedpulse/internal/storage"
+)
+
+// Kinds of templated messages
+const (
+	KindNewItems = "new_items"
+	KindDigest   = "digest"
+)
+
+// TemplateData is what message templates render
+type TemplateData struct {
+	// Kind is KindNewItems or KindDigest
+	Kind  string
+	Title string
+	// Items are the new items, at most MaxNewItems of them; More counts
+	// the rest
+	Items []storage.FeedItem
+	More  int
+	// Topics and Since describe a digest
+	Topics []storage.DigestTopic
+	Since  string
+}
+
+// SourceGroup is a source's items, as listed by the groupBySource helper
+type SourceGroup struct {
+	Source string
+	Items  []storage.FeedItem
+}
+
+// Templates render messages with Go text/template instead of the built-in
+// layout. Besides the standard functions, templates may use:
+//
+//	truncate N s       s cut to N characters
+//	domain url         url's host, without "www."
+//	timeago t          how long ago t was, e.g. "3h ago"
+//	groupBySource items  items grouped by source, in order of appearance
+type Templates struct {
+	newItems *template.Template
+	digest   *template.Template
+	now      func() time.Time
+}
+
+// ParseTemplates parses the templates for new-item messages and digests;
+// an empty template keeps that kind's built-in layout
+func ParseTemplates(newItems, digest string) (*Templates, error) {
+	t := &Templates{now: time.Now}
+	var err error
+	if t.newItems, err = t.parse(KindNewItems, newItems); err != nil {
+		return nil, err
+	}
+	if t.digest, err = t.parse(KindDigest, digest); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// parse parses text as the template for kind, if it is set
+func (t *Templates) parse(kind, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New(kind).Funcs(t.funcs()).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", kind, err)
+	}
+	return tmpl, nil
+}
+
+// funcs returns the helper functions templates may call
+func (t *Templates) funcs() template.FuncMap {
+	return template.FuncMap{
+		"truncate": func(n int, s string) string {
+			if n < 1 {
+				return s
+			}
+			return truncate(s, n)
+		},
+		"domain": func(raw string) string {
+			u, err := url.Parse(raw)
+			if err != nil {
+				return ""
+			}
+			return strings.TrimPrefix(u.Hostname(), "www.")
+		},
+		"timeago": func(at time.Time) string {
+			return clock.Ago(t.now(), at)
+		},
+		"groupBySource": groupBySource,
+	}
+}
+
+// Apply renders msg with its kind's template, if there is one, into its
+// Body. Messages not built by NewItems or Digest are returned unchanged.
+func (t *Templates) Apply(msg Message) (Message, error) {
+	if msg.data == nil {
+		return msg, nil
+	}
+	tmpl := t.newItems
+	if msg.data.Kind == KindDigest {
+		tmpl = t.digest
+	}
+	if tmpl == nil {
+		return msg, nil
+	}
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, msg.data); err != nil {
+		return msg, fmt.Errorf("failed to render %s template: %w", msg.data.Kind, err)
+	}
+	body := strings.TrimSpace(b.String())
+	if body == "" {
+		return msg, fmt.Errorf("%s template rendered an empty message", msg.data.Kind)
+	}
+	msg.Body = body
+	return msg, nil
+}
+
+// Templated wraps a notifier, rendering messages with templates first
+type Templated struct {
+	notifier  Notifier
+	templates *Templates
+}
+
+// NewTemplated creates a notifier that renders messages with t before n
+// sends them
+func NewTemplated(n Notifier, t *Templates) *Templated {
+	return &Templated{notifier: n, templates: t}
+}
+
+// Name returns the wrapped notifier's name
+func (t *Templated) Name() string { return t.notifier.Name() }
+
+// Send renders msg and sends it
+func (t *Templated) Send(ctx context.Context, msg Message) error {
+	msg, err := t.templates.Apply(msg)
+	if err != nil {
+		return err
+	}
+	return t.notifier.Send(ctx, msg)
+}
+
+// groupBySource groups items by source, in the order sources first appear
+func groupBySource(items []storage.FeedItem) []SourceGroup {
+	var groups []SourceGroup
+	index := make(map[string]int)
+	for _, item := range items {
+		i, ok := index[item.Source]
+		if !ok {
+			i = len(groups)
+			index[item.Source] = i
+			groups = append(groups, SourceGroup{Source: item.Source})
+		}
+		groups[i].Items = append(groups[i].Items, item)
+	}
+	return groups
+}
@@ -0,0 +1,260 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// XML namespaces of the XMPP core protocol
+const (
+	nsStream   = "http://etherx.jabber.org/streams"
+	nsTLS      = "urn:ietf:params:xml:ns:xmpp-tls"
+	nsSASL     = "urn:ietf:params:xml:ns:xmpp-sasl"
+	nsBind     = "urn:ietf:params:xml:ns:xmpp-bind"
+	xmppClient = "jabber:client"
+)
+
+// XMPP sends chat messages to XMPP (Jabber) addresses. Each Send logs in,
+// upgrading the connection with STARTTLS and authenticating with SASL
+// PLAIN, delivers one message per recipient and disconnects.
+type XMPP struct {
+	jid      string
+	password string
+	to       []string
+	server   string
+
+	tlsConfig *tls.Config
+	timeout   time.Duration
+}
+
+// NewXMPP creates a client logging in as jid and sending to the given
+// addresses. server is the host:port to connect to; if empty, it is looked
+// up in DNS (_xmpp-client._tcp SRV records) or taken from jid's domain.
+func NewXMPP(jid, password string, to []string, server string) *XMPP {
+	return &XMPP{jid: jid, password: password, to: to, server: server, timeout: 30 * time.Second}
+}
+
+// Name returns "xmpp"
+func (x *XMPP) Name() string { return "xmpp" }
+
+// Send delivers msg as plain text to every recipient
+func (x *XMPP) Send(ctx context.Context, msg Message) error {
+	local, domain, ok := strings.Cut(x.jid, "@")
+	if !ok || local == "" || domain == "" {
+		return fmt.Errorf("invalid xmpp address: %s", x.jid)
+	}
+	domain, _, _ = strings.Cut(domain, "/")
+
+	addr, err := x.address(ctx, domain)
+	if err != nil {
+		return err
+	}
+	dialer := net.Dialer{Timeout: x.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to xmpp server: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(x.timeout))
+	}
+
+	s, err := x.login(conn, local, domain)
+	if err != nil {
+		return err
+	}
+
+	text := msg.Text()
+	for i, to := range x.to {
+		if err := s.send("<message to=%s type='chat' id='fp%d'><body>%s</body></message>", xmlAttr(to), i, xmlText(text)); err != nil {
+			return fmt.Errorf("failed to send xmpp message to %s: %w", to, err)
+		}
+	}
+	s.send("</stream:stream>")
+	return nil
+}
+
+// address returns the host:port to connect to for domain
+func (x *XMPP) address(ctx context.Context, domain string) (string, error) {
+	if x.server != "" {
+		return x.server, nil
+	}
+	var resolver net.Resolver
+	if _, records, err := resolver.LookupSRV(ctx, "xmpp-client", "tcp", domain); err == nil && len(records) > 0 {
+		return net.JoinHostPort(strings.TrimSuffix(records[0].Target, "."), strconv.Itoa(int(records[0].Port))), nil
+	}
+	return net.JoinHostPort(domain, "5222"), nil
+}
+
+// xmppStream is an open XML stream to the server
+type xmppStream struct {
+	w   io.Writer
+	dec *xml.Decoder
+}
+
+// send writes a formatted stanza
+func (s *xmppStream) send(format string, args ...interface{}) error {
+	_, err := fmt.Fprintf(s.w, format, args...)
+	return err
+}
+
+// next returns the next top-level element of the stream
+func (s *xmppStream) next() (xml.StartElement, error) {
+	for {
+		tok, err := s.dec.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			return t, nil
+		case xml.EndElement:
+			if t.Name.Space == nsStream && t.Name.Local == "stream" {
+				return xml.StartElement{}, fmt.Errorf("xmpp server closed the stream")
+			}
+		}
+	}
+}
+
+// streamFeatures is the <stream:features/> element
+type streamFeatures struct {
+	StartTLS   *struct{} `xml:"urn:ietf:params:xml:ns:xmpp-tls starttls"`
+	Mechanisms []string  `xml:"urn:ietf:params:xml:ns:xmpp-sasl mechanisms>mechanism"`
+	Bind       *struct{} `xml:"urn:ietf:params:xml:ns:xmpp-bind bind"`
+}
+
+// openStream starts a stream to domain over rw and reads the server's
+// features
+func openStream(rw io.ReadWriter, domain string) (*xmppStream, streamFeatures, error) {
+	s := &xmppStream{w: rw, dec: xml.NewDecoder(rw)}
+	var features streamFeatures
+	err := s.send("<?xml version='1.0'?><stream:stream to=%s xmlns='%s' xmlns:stream='%s' version='1.0'>", xmlAttr(domain), xmppClient, nsStream)
+	if err != nil {
+		return nil, features, fmt.Errorf("failed to open xmpp stream: %w", err)
+	}
+
+	start, err := s.next()
+	if err != nil || start.Name.Space != nsStream || start.Name.Local != "stream" {
+		return nil, features, fmt.Errorf("xmpp server did not open a stream: %v", err)
+	}
+	el, err := s.next()
+	if err != nil {
+		return nil, features, fmt.Errorf("failed to read xmpp stream features: %w", err)
+	}
+	if el.Name.Space != nsStream || el.Name.Local != "features" {
+		return nil, features, fmt.Errorf("xmpp server sent <%s> instead of its features", el.Name.Local)
+	}
+	if err := s.dec.DecodeElement(&features, &el); err != nil {
+		return nil, features, fmt.Errorf("invalid xmpp stream features: %w", err)
+	}
+	return s, features, nil
+}
+
+// login secures conn with STARTTLS, authenticates and binds a resource,
+// returning the stream to send on
+func (x *XMPP) login(conn net.Conn, local, domain string) (*xmppStream, error) {
+	s, features, err := openStream(conn, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	// The password is sent in the clear under SASL PLAIN, so TLS is required
+	if features.StartTLS == nil {
+		return nil, fmt.Errorf("xmpp server does not offer STARTTLS")
+	}
+	if err := s.send("<starttls xmlns='%s'/>", nsTLS); err != nil {
+		return nil, fmt.Errorf("failed to start tls: %w", err)
+	}
+	if el, err := s.next(); err != nil || el.Name.Local != "proceed" {
+		return nil, fmt.Errorf("xmpp server refused STARTTLS")
+	}
+	config := &tls.Config{ServerName: domain}
+	if x.tlsConfig != nil {
+		config = x.tlsConfig.Clone()
+	}
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, fmt.Errorf("xmpp tls handshake failed: %w", err)
+	}
+
+	if s, features, err = openStream(tlsConn, domain); err != nil {
+		return nil, err
+	}
+	plain := false
+	for _, m := range features.Mechanisms {
+		plain = plain || m == "PLAIN"
+	}
+	if !plain {
+		return nil, fmt.Errorf("xmpp server does not support PLAIN authentication (offers %s)", strings.Join(features.Mechanisms, ", "))
+	}
+	credentials := base64.StdEncoding.EncodeToString([]byte("\x00" + local + "\x00" + x.password))
+	if err := s.send("<auth xmlns='%s' mechanism='PLAIN'>%s</auth>", nsSASL, credentials); err != nil {
+		return nil, fmt.Errorf("failed to authenticate: %w", err)
+	}
+	el, err := s.next()
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate: %w", err)
+	}
+	if el.Name.Local != "success" {
+		var failure struct {
+			Reason struct {
+				XMLName xml.Name
+			} `xml:",any"`
+		}
+		s.dec.DecodeElement(&failure, &el)
+		return nil, fmt.Errorf("xmpp authentication failed: %s", failure.Reason.XMLName.Local)
+	}
+
+	if s, features, err = openStream(tlsConn, domain); err != nil {
+		return nil, err
+	}
+	if features.Bind == nil {
+		return nil, fmt.Errorf("xmpp server does not offer resource binding")
+	}
+	if err := s.send("<iq type='set' id='bind'><bind xmlns='%s'><resource>feedpulse</resource></bind></iq>", nsBind); err != nil {
+		return nil, fmt.Errorf("failed to bind resource: %w", err)
+	}
+	for {
+		el, err := s.next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to bind resource: %w", err)
+		}
+		if el.Name.Local != "iq" {
+			s.dec.Skip()
+			continue
+		}
+		typ := ""
+		for _, attr := range el.Attr {
+			if attr.Name.Local == "type" {
+				typ = attr.Value
+			}
+		}
+		s.dec.Skip()
+		if typ != "result" {
+			return nil, fmt.Errorf("xmpp server refused to bind a resource")
+		}
+		return s, nil
+	}
+}
+
+// xmlText escapes s for character data
+func xmlText(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// xmlAttr quotes s as an attribute value
+func xmlAttr(s string) string {
+	return "'" + strings.ReplaceAll(xmlText(s), "'", "&apos;") + "'"
+}
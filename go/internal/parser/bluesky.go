@@ -0,0 +1,122 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"feedpulse/internal/storage"
+)
+
+// blueskyTitleLen is the longest title taken from a post's text, in runes
+const blueskyTitleLen = 120
+
+// parseBluesky parses a Bluesky app.bsky.feed.getAuthorFeed response.
+// Each entry wraps a post view; reposts carry the original post, so they
+// normalize to the same item as the post itself. Posts have no title, so
+// the first line of the text is used, and the URL is the post's page on
+// bsky.app. Hashtags become tags.
+func (p *Parser) parseBluesky(source string, feed []interface{}) ParseResult {
+	var result ParseResult
+
+	for i, entry := range feed {
+		entryObj, ok := entry.(map[string]interface{})
+		if !ok {
+			result.Errors = append(result.Errors, fmt.Sprintf("item %d: expected object, got %T", i, entry))
+			continue
+		}
+
+		post, ok := entryObj["post"].(map[string]interface{})
+		if !ok {
+			result.Errors = append(result.Errors, fmt.Sprintf("item %d: missing post object", i))
+			continue
+		}
+		record, _ := post["record"].(map[string]interface{})
+		author, _ := post["author"].(map[string]interface{})
+
+		// Extract required fields
+		uri, uriOk := p.getString(post, "uri")
+		handle, handleOk := p.getString(author, "handle")
+		text, _ := p.getString(record, "text")
+
+		if !uriOk || !handleOk {
+			result.Errors = append(result.Errors, fmt.Sprintf("item %d: missing required field (uri or author.handle)", i))
+			continue
+		}
+
+		url := blueskyPostURL(handle, uri)
+		feedItem := storage.FeedItem{
+			ID:        p.generateID(source, url),
+			Title:     blueskyTitle(text, handle),
+			URL:       url,
+			Source:    source,
+			CreatedAt: p.clock.Now(),
+		}
+
+		// Optional: timestamp (when the author created the post)
+		if createdAt, ok := p.getString(record, "createdAt"); ok {
+			feedItem.Timestamp = &createdAt
+		}
+
+		feedItem.Tags = blueskyTags(record)
+
+		result.Items = append(result.Items, feedItem)
+	}
+
+	return result
+}
+
+// blueskyPostURL turns an at://did/app.bsky.feed.post/rkey URI into the
+// post's web URL
+func blueskyPostURL(handle, uri string) string {
+	rkey := uri[strings.LastIndex(uri, "/")+1:]
+	return fmt.Sprintf("https://bsky.app/profile/%s/post/%s", handle, rkey)
+}
+
+// blueskyTitle derives a title from the first line of a post's text
+func blueskyTitle(text, handle string) string {
+	title := strings.TrimSpace(text)
+	if line, _, ok := strings.Cut(title, "\n"); ok {
+		title = strings.TrimSpace(line)
+	}
+	if title == "" {
+		return "Post by @" + handle
+	}
+	if utf8.RuneCountInString(title) > blueskyTitleLen {
+		runes := []rune(title)
+		title = strings.TrimSpace(string(runes[:blueskyTitleLen-1])) + "…"
+	}
+	return title
+}
+
+// blueskyTags collects a post's hashtags, from rich text tag facets and
+// the record's own tags, without duplicates
+func blueskyTags(record map[string]interface{}) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	add := func(tag interface{}) {
+		if s, ok := tag.(string); ok && s != "" && !seen[s] {
+			seen[s] = true
+			tags = append(tags, s)
+		}
+	}
+
+	facets, _ := record["facets"].([]interface{})
+	for _, facet := range facets {
+		facetObj, _ := facet.(map[string]interface{})
+		features, _ := facetObj["features"].([]interface{})
+		for _, feature := range features {
+			featureObj, _ := feature.(map[string]interface{})
+			if featureObj["$type"] == "app.bsky.richtext.facet#tag" {
+				add(featureObj["tag"])
+			}
+		}
+	}
+
+	extra, _ := record["tags"].([]interface{})
+	for _, tag := range extra {
+		add(tag)
+	}
+
+	return tags
+}
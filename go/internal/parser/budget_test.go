@@ -0,0 +1,182 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"feedpulse/internal/clock"
+)
+
+// budgetItems is how many items each budget fixture holds
+const budgetItems = 10000
+
+// parseBudget bounds the cost of parsing one item on the hot path.
+// Allocations are deterministic enough to hold exactly; time depends on
+// the machine, so time budgets are generous and can be scaled with
+// FEEDPULSE_BUDGET_SCALE (e.g. 3 on a slow CI runner, 0 to skip them).
+type parseBudget struct {
+	AllocsPerItem float64
+	NsPerItem     float64
+}
+
+// budgetFixture is a generated response with budgetItems items
+type budgetFixture struct {
+	name     string
+	feedType string
+	data     []byte
+	budget   parseBudget
+}
+
+// budgetFixtures returns one fixture per parsing path worth guarding
+func budgetFixtures() []budgetFixture {
+	return []budgetFixture{
+		{"hackernews", "json", hackerNewsFixture(budgetItems), parseBudget{AllocsPerItem: 14, NsPerItem: 5000}},
+		{"lobsters", "json", lobstersFixture(budgetItems), parseBudget{AllocsPerItem: 44, NsPerItem: 20000}},
+		{"reddit", "json", redditFixture(budgetItems), parseBudget{AllocsPerItem: 38, NsPerItem: 20000}},
+		{"ndjson", "ndjson", ndjsonFixture(budgetItems), parseBudget{AllocsPerItem: 55, NsPerItem: 35000}},
+	}
+}
+
+// hackerNewsFixture returns a HackerNews top stories array of n IDs
+func hackerNewsFixture(n int) []byte {
+	var b bytes.Buffer
+	b.WriteByte('[')
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Itoa(40000000 + i))
+	}
+	b.WriteByte(']')
+	return b.Bytes()
+}
+
+// lobstersStory returns the i-th generated Lobsters story object
+func lobstersStory(i int) string {
+	return fmt.Sprintf(`{"short_id":"s%d","title":"Story number %d about Go performance","url":"https://example.com/stories/%d","created_at":"2026-03-01T12:%02d:00Z","score":%d,"tags":["go","performance"]}`, i, i, i, i%60, i%500)
+}
+
+// lobstersFixture returns a Lobsters array of n stories
+func lobstersFixture(n int) []byte {
+	var b bytes.Buffer
+	b.WriteByte('[')
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(lobstersStory(i))
+	}
+	b.WriteByte(']')
+	return b.Bytes()
+}
+
+// redditFixture returns a Reddit listing of n posts
+func redditFixture(n int) []byte {
+	var b bytes.Buffer
+	b.WriteString(`{"kind":"Listing","data":{"children":[`)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `{"kind":"t3","data":{"title":"Post number %d","url":"https://example.com/posts/%d","created_utc":%d,"subreddit":"golang","score":%d}}`, i, i, 1772366400+i, i%1000)
+	}
+	b.WriteString(`]}}`)
+	return b.Bytes()
+}
+
+// ndjsonFixture returns n Lobsters stories, one per line
+func ndjsonFixture(n int) []byte {
+	var b bytes.Buffer
+	for i := 0; i < n; i++ {
+		b.WriteString(lobstersStory(i))
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+// budgetScale returns the factor applied to time budgets
+func budgetScale(t *testing.T) float64 {
+	value := os.Getenv("FEEDPULSE_BUDGET_SCALE")
+	if value == "" {
+		return 1
+	}
+	scale, err := strconv.ParseFloat(value, 64)
+	if err != nil || scale < 0 {
+		t.Fatalf("invalid FEEDPULSE_BUDGET_SCALE %q", value)
+	}
+	return scale
+}
+
+// budgetClock stamps the items budget checks parse, so they don't depend
+// on when the test runs
+var budgetClock = clock.Fixed(time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC))
+
+// budgetRuns is how many parses a budget check averages allocations
+// over and takes the fastest time of
+const budgetRuns = 3
+
+// checkParseBudget parses data a few times and fails t if a parse exceeds
+// budget per item, or doesn't produce wantItems items. Time is the
+// fastest run, so a busy machine is less likely to fail it.
+func checkParseBudget(t *testing.T, feedType string, data []byte, wantItems int, budget parseBudget) {
+	t.Helper()
+
+	p := NewParser()
+	p.SetClock(budgetClock)
+	if result := p.Parse("Budget", feedType, data); len(result.Items) != wantItems {
+		t.Fatalf("expected %d items, got %d (errors: %v)", wantItems, len(result.Items), result.Errors)
+	}
+
+	allocs := testing.AllocsPerRun(budgetRuns, func() {
+		p.Parse("Budget", feedType, data)
+	}) / float64(wantItems)
+
+	fastest := time.Duration(-1)
+	for i := 0; i < budgetRuns; i++ {
+		start := time.Now()
+		p.Parse("Budget", feedType, data)
+		if elapsed := time.Since(start); fastest < 0 || elapsed < fastest {
+			fastest = elapsed
+		}
+	}
+	ns := float64(fastest.Nanoseconds()) / float64(wantItems)
+	t.Logf("%.1f allocs/item, %s/item", allocs, time.Duration(ns))
+
+	if allocs > budget.AllocsPerItem {
+		t.Errorf("parsing allocates %.1f times per item, over the budget of %.0f", allocs, budget.AllocsPerItem)
+	}
+	if scale := budgetScale(t); scale > 0 && ns > budget.NsPerItem*scale {
+		t.Errorf("parsing takes %s per item, over the budget of %s", time.Duration(ns), time.Duration(budget.NsPerItem*scale))
+	}
+}
+
+func TestParseBudget(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping parse budgets in short mode")
+	}
+
+	for _, fx := range budgetFixtures() {
+		t.Run(fx.name, func(t *testing.T) {
+			checkParseBudget(t, fx.feedType, fx.data, budgetItems, fx.budget)
+		})
+	}
+}
+
+func BenchmarkParse_10kItems(b *testing.B) {
+	for _, fx := range budgetFixtures() {
+		b.Run(fx.name, func(b *testing.B) {
+			p := NewParser()
+			p.SetClock(budgetClock)
+			b.ReportAllocs()
+			b.SetBytes(int64(len(fx.data)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				p.Parse("Budget", fx.feedType, fx.data)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+)
+
+// DetectStructure describes which layout Parse will treat data as, e.g.
+// "hackernews (array of item IDs)", so a feed that yields no items can be
+// told apart from one whose shape wasn't recognized
+func DetectStructure(feedType string, data []byte) string {
+	switch feedType {
+	case "ndjson":
+		return "ndjson (one record per line)"
+	case "xml":
+		return "xml (mapped by the feed's xml settings)"
+	case "plugin":
+		return "plugin (parsed by the feed's plugin command)"
+	case "rss":
+		return detectRSS(data)
+	case "json":
+	default:
+		return "unsupported feed type: " + feedType
+	}
+
+	var rawJSON interface{}
+	if err := json.Unmarshal(TrimJSONPrefix(data), &rawJSON); err != nil {
+		return "malformed JSON"
+	}
+
+	switch v := rawJSON.(type) {
+	case []interface{}:
+		if len(v) == 0 {
+			return "empty array"
+		}
+		if _, ok := v[0].(float64); ok {
+			return "hackernews (array of item IDs)"
+		}
+		if _, ok := v[0].(map[string]interface{}); ok {
+			return "lobsters (array of story objects)"
+		}
+	case map[string]interface{}:
+		if _, ok := v["items"].([]interface{}); ok && isStackExchange(v) {
+			return "stackexchange (items with quota fields)"
+		}
+		if _, ok := v["items"].([]interface{}); ok {
+			return "github (items array)"
+		}
+		if data, ok := v["data"].(map[string]interface{}); ok {
+			if _, ok := data["children"].([]interface{}); ok {
+				return "reddit (data.children listing)"
+			}
+		}
+		if _, ok := v["feed"].([]interface{}); ok {
+			return "bluesky (feed of post views)"
+		}
+	}
+	return "unrecognized"
+}
+
+// detectRSS names the RSS version of data by its root element
+func detectRSS(data []byte) string {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	decoder.CharsetReader = rssCharsetReader
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return "malformed RSS"
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			switch start.Name.Local {
+			case "rss":
+				return "rss 2.0 (channel items)"
+			case "RDF":
+				return "rss 1.0 (RDF items)"
+			}
+			return "not an RSS feed (root element <" + start.Name.Local + ">)"
+		}
+	}
+}
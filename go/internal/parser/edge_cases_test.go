@@ -279,6 +279,39 @@ func TestParse_MalformedJSONEdgeCases(t *testing.T) {
 			if !strings.Contains(result.Errors[0], "malformed JSON") {
 				t.Errorf("Expected 'malformed JSON' error, got: %s", result.Errors[0])
 			}
+			if !result.Malformed {
+				t.Error("Expected the result to be marked malformed")
+			}
+		})
+	}
+}
+
+// Test that only payloads that can't be decoded are marked malformed, not
+// ones with unusable items
+func TestParse_Malformed(t *testing.T) {
+	tests := []struct {
+		name      string
+		feedType  string
+		data      string
+		malformed bool
+	}{
+		{"json", "json", `{"items": [`, true},
+		{"rss", "rss", `<rss><channel><item>`, true},
+		{"not rss", "rss", `<html><body></body></html>`, true},
+		{"unusable items", "json", `{"items": [{"name": "no url"}]}`, false},
+		{"unknown structure", "json", `{"foo": "bar"}`, false},
+	}
+
+	parser := NewParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parser.Parse("Test", tt.feedType, []byte(tt.data))
+			if len(result.Errors) == 0 {
+				t.Fatal("Expected parse errors")
+			}
+			if result.Malformed != tt.malformed {
+				t.Errorf("Expected Malformed %v, got %v (%v)", tt.malformed, result.Malformed, result.Errors)
+			}
 		})
 	}
 }
@@ -501,3 +534,256 @@ func TestParse_DuplicateItems(t *testing.T) {
 		}
 	}
 }
+
+// Test BOMs and junk before the JSON document
+func TestParse_LeadingGarbage(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"utf-8 BOM", "\xEF\xBB\xBF[1, 2]"},
+		{"BOM and whitespace", "\xEF\xBB\xBF \r\n [1, 2]"},
+		{"xml prolog", `<?xml version="1.0"?>` + "\n[1, 2]"},
+		{"anti-XSSI guard", ")]}'\n[1, 2]"},
+		{"while loop guard", "while(1);[1, 2]"},
+	}
+
+	parser := NewParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parser.Parse("HackerNews", "json", []byte(tt.data))
+			if len(result.Errors) > 0 {
+				t.Errorf("Expected no errors, got: %v", result.Errors)
+			}
+			if len(result.Items) != 2 {
+				t.Errorf("Expected 2 items, got %d", len(result.Items))
+			}
+		})
+	}
+}
+
+// Test that payloads without any JSON document still fail as malformed
+func TestTrimJSONPrefix_NoDocument(t *testing.T) {
+	data := []byte("\xEF\xBB\xBFnot json at all")
+	if got := TrimJSONPrefix(data); string(got) != string(data) {
+		t.Errorf("Expected payload without JSON to be returned unchanged, got %q", got)
+	}
+
+	result := NewParser().Parse("Test", "json", data)
+	if len(result.Errors) == 0 || !strings.Contains(result.Errors[0], "malformed JSON") {
+		t.Errorf("Expected 'malformed JSON' error, got: %v", result.Errors)
+	}
+}
+
+// Test stripping JSONP callbacks in the shapes servers produce
+func TestStripJSONP(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{"plain", `cb({"items": []})`, `{"items": []}`, false},
+		{"semicolon and whitespace", "  cb( {\"a\": 1} );\n", `{"a": 1}`, false},
+		{"guard comment", `/**/ jQuery123_456({"a": 1})`, `{"a": 1}`, false},
+		{"dotted name", `window.feeds.load([1, 2])`, `[1, 2]`, false},
+		{"bom", "\xEF\xBB\xBFcb({})", `{}`, false},
+		{"plain json", `{"a": 1}`, "", true},
+		{"missing paren", `cb({"a": 1}`, "", true},
+		{"bad name", `1cb({})`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := StripJSONP([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("StripJSONP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("StripJSONP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Test decoding JSON carried as an escaped string field
+func TestUnwrapStringField(t *testing.T) {
+	data := []byte(`{"result": {"d": "{\"items\": [{\"id\": 1}]}"}, "n": 3}`)
+
+	got, err := UnwrapStringField(data, "result.d")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(got) != `{"items": [{"id": 1}]}` {
+		t.Errorf("Unexpected unwrapped payload: %q", got)
+	}
+
+	if _, err := UnwrapStringField(data, "result.missing"); err == nil {
+		t.Error("Expected error for missing field")
+	}
+	if _, err := UnwrapStringField(data, "n"); err == nil {
+		t.Error("Expected error for non-string field")
+	}
+	if _, err := UnwrapStringField([]byte("not json"), "d"); err == nil {
+		t.Error("Expected error for malformed wrapper")
+	}
+}
+
+// Test the generic XML mapper on an arXiv-style Atom response
+func TestParseXML_Namespaced(t *testing.T) {
+	data := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:arxiv="http://arxiv.org/schemas/atom">
+  <title>query results</title>
+  <entry>
+    <title> Attention Is All You Need </title>
+    <link rel="related" href="https://arxiv.org/pdf/1706.03762"/>
+    <link rel="alternate" href="https://arxiv.org/abs/1706.03762"/>
+    <published>2017-06-12T17:57:34Z</published>
+    <category term="cs.CL"/>
+    <category term="cs.LG"/>
+    <arxiv:primary_category term="cs.CL"/>
+  </entry>
+  <entry>
+    <title>No link</title>
+  </entry>
+  <entry>
+    <title><![CDATA[Second <paper>]]></title>
+    <link rel="alternate" href="https://arxiv.org/abs/2401.00001"/>
+    <published>Mon, 01 Jan 2024 10:00:00 +0000</published>
+  </entry>
+</feed>`)
+
+	p := NewParser()
+	p.SetXMLMapping("arXiv", XMLMapping{
+		Item:       "atom:feed/atom:entry",
+		Title:      "atom:title",
+		URL:        "atom:link[@rel='alternate']/@href",
+		Date:       "published",
+		Tags:       "category/@term",
+		Namespaces: map[string]string{"atom": "http://www.w3.org/2005/Atom"},
+	})
+
+	result := p.Parse("arXiv", "xml", data)
+
+	if len(result.Items) != 2 || len(result.Errors) != 1 {
+		t.Fatalf("Expected 2 items and 1 error, got %d items and errors %v", len(result.Items), result.Errors)
+	}
+	first := result.Items[0]
+	if first.Title != "Attention Is All You Need" || first.URL != "https://arxiv.org/abs/1706.03762" {
+		t.Errorf("Unexpected first item: %+v", first)
+	}
+	if len(first.Tags) != 2 || first.Tags[1] != "cs.LG" {
+		t.Errorf("Expected 2 category tags, got %v", first.Tags)
+	}
+	second := result.Items[1]
+	if second.Title != "Second <paper>" || second.Timestamp == nil || *second.Timestamp != "2024-01-01T10:00:00Z" {
+		t.Errorf("Expected CDATA title and normalized date, got %+v", second)
+	}
+}
+
+// Test that an Atom link without rel counts as the alternate link
+func TestParseXML_Alternatives(t *testing.T) {
+	data := []byte(`<feed xmlns="http://www.w3.org/2005/Atom">
+  <entry><title>Plain</title><link href="https://example.com/plain"/></entry>
+  <entry><title>Both</title><link rel="self" href="https://example.com/self"/><link rel="alternate" href="https://example.com/both"/></entry>
+  <entry><title>Self only</title><link rel="self" href="https://example.com/self"/></entry>
+</feed>`)
+
+	p := NewParser()
+	p.SetXMLMapping("Atom", XMLMapping{
+		Item:       "atom:feed/atom:entry",
+		Title:      "atom:title",
+		URL:        "atom:link[@rel='alternate']/@href | atom:link[not(@rel)]/@href",
+		Namespaces: map[string]string{"atom": "http://www.w3.org/2005/Atom"},
+	})
+
+	result := p.Parse("Atom", "xml", data)
+	if len(result.Items) != 2 || len(result.Errors) != 1 {
+		t.Fatalf("Expected 2 items and 1 error, got %+v and errors %v", result.Items, result.Errors)
+	}
+	if result.Items[0].URL != "https://example.com/plain" || result.Items[1].URL != "https://example.com/both" {
+		t.Errorf("Unexpected links: %+v", result.Items)
+	}
+}
+
+// Test that namespaces must match when a prefix is given
+func TestParseXML_NamespaceMismatch(t *testing.T) {
+	data := []byte(`<root xmlns:a="urn:a" xmlns:b="urn:b"><a:item><title>A</title><url>https://example.com/a</url></a:item><b:item><title>B</title><url>https://example.com/b</url></b:item></root>`)
+
+	p := NewParser()
+	p.SetXMLMapping("Test", XMLMapping{Item: "//x:item", Title: "title", URL: "url", Namespaces: map[string]string{"x": "urn:b"}})
+
+	result := p.Parse("Test", "xml", data)
+	if len(result.Items) != 1 || result.Items[0].Title != "B" {
+		t.Errorf("Expected only the urn:b item, got %+v", result.Items)
+	}
+}
+
+// Test XML error reporting
+func TestParseXML_Errors(t *testing.T) {
+	p := NewParser()
+
+	if result := p.Parse("Unmapped", "xml", []byte(`<a/>`)); len(result.Errors) == 0 {
+		t.Error("Expected error for a source without a mapping")
+	}
+
+	p.SetXMLMapping("Test", XMLMapping{Item: "items/item", Title: "title", URL: "url"})
+	if result := p.Parse("Test", "xml", []byte(`not xml`)); len(result.Errors) == 0 || !strings.Contains(result.Errors[0], "malformed XML") {
+		t.Errorf("Expected 'malformed XML' error, got %v", result.Errors)
+	}
+	if result := p.Parse("Test", "xml", []byte(`<other/>`)); len(result.Errors) == 0 || !strings.Contains(result.Errors[0], "no elements match") {
+		t.Errorf("Expected 'no elements match' error, got %v", result.Errors)
+	}
+}
+
+// Test the generic JSON mapper on a nested API response
+func TestParseJSON_Mapping(t *testing.T) {
+	data := []byte(`{
+  "meta": {"count": 3},
+  "data": {"results": [
+    {"name": " Release 1.0 ", "links": [{"href": "https://example.com/1"}], "published": 1709380800, "labels": [{"name": "go"}, {"name": "release"}]},
+    {"name": "No link", "links": []},
+    {"name": "Release 2.0", "links": [{"href": "https://example.com/2"}], "published": "2024-03-03T10:00:00Z", "labels": [{"name": "go"}]}
+  ]}
+}`)
+
+	p := NewParser()
+	p.SetJSONMapping("Releases", JSONMapping{
+		Item:  "$.data.results",
+		Title: "name",
+		URL:   "links[0].href",
+		Date:  "published",
+		Tags:  "labels[*].name",
+	})
+
+	result := p.Parse("Releases", "json", data)
+	if len(result.Items) != 2 || len(result.Errors) != 1 {
+		t.Fatalf("Expected 2 items and 1 error, got %d items and errors %v", len(result.Items), result.Errors)
+	}
+	first := result.Items[0]
+	if first.Title != "Release 1.0" || first.URL != "https://example.com/1" {
+		t.Errorf("Unexpected first item: %+v", first)
+	}
+	if first.Timestamp == nil || *first.Timestamp != "2024-03-02T12:00:00Z" {
+		t.Errorf("Expected the Unix timestamp as RFC 3339, got %v", first.Timestamp)
+	}
+	if len(first.Tags) != 2 || first.Tags[1] != "release" {
+		t.Errorf("Expected 2 label tags, got %v", first.Tags)
+	}
+
+	// A mapping overrides detection, even for shapes the parser knows
+	p.SetJSONMapping("Lobsters", JSONMapping{Item: "$", Title: "headline", URL: "link", Tags: "topics"})
+	result = p.Parse("Lobsters", "json", []byte(`[{"headline": "A", "link": "https://example.com/a", "topics": ["x", "y"]}, {"headline": "B", "link": "https://example.com/b"}]`))
+	if len(result.Items) != 2 || result.Items[0].Title != "A" || len(result.Items[0].Tags) != 2 {
+		t.Errorf("Expected 2 mapped items, got %+v (errors %v)", result.Items, result.Errors)
+	}
+
+	p.SetMaxItems(1, false)
+	if result := p.Parse("Lobsters", "json", []byte(`[{"headline": "A", "link": "https://example.com/a"}, {"headline": "B", "link": "https://example.com/b"}]`)); len(result.Items) != 1 || result.Truncated != 1 {
+		t.Errorf("Expected 1 item and 1 truncated, got %d and %d", len(result.Items), result.Truncated)
+	}
+
+	if result := p.Parse("Releases", "json", []byte(`{"data": {}}`)); len(result.Errors) == 0 || !strings.Contains(result.Errors[0], "nothing matches") {
+		t.Errorf("Expected 'nothing matches' error, got %v", result.Errors)
+	}
+}
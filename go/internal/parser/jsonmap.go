@@ -0,0 +1,222 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"feedpulse/internal/storage"
+)
+
+// JSONMapping tells the generic JSON mapper where a source's items and
+// their fields live, overriding structure detection. Paths are JSONPath-
+// like: object keys separated by ".", each optionally followed by "[N]"
+// for an array element or "[*]" for every element, and an optional
+// leading "$". Item is evaluated from the document root and each match is
+// an item, except that a path ending at an array selects its elements.
+// The other paths are evaluated from each item.
+type JSONMapping struct {
+	Item  string
+	Title string
+	URL   string
+	Date  string
+	Tags  string
+}
+
+// SetJSONMapping registers the mapping used for the json feed named source
+func (p *Parser) SetJSONMapping(source string, m JSONMapping) {
+	if p.jsonMappings == nil {
+		p.jsonMappings = make(map[string]JSONMapping)
+	}
+	p.jsonMappings[source] = m
+}
+
+// jsonStepPattern matches one "."-separated segment of a JSON path
+var jsonStepPattern = regexp.MustCompile(`^([^.\[\]]*)((?:\[(?:\d+|\*)\])*)$`)
+
+// jsonStep is one compiled step of a JSON path: an object key, or an array
+// index (-1 for every element)
+type jsonStep struct {
+	key   string
+	index int
+	array bool
+}
+
+// compileJSONPath parses a JSON path as JSONMapping describes it
+func compileJSONPath(path string) ([]jsonStep, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return nil, nil
+	}
+
+	var steps []jsonStep
+	for _, segment := range strings.Split(path, ".") {
+		m := jsonStepPattern.FindStringSubmatch(segment)
+		if m == nil || (m[1] == "" && m[2] == "") {
+			return nil, fmt.Errorf("invalid step %q in path '%s'", segment, path)
+		}
+		if m[1] != "" {
+			steps = append(steps, jsonStep{key: m[1]})
+		}
+		for _, index := range strings.Split(strings.Trim(m[2], "[]"), "][") {
+			if index == "" {
+				continue
+			}
+			step := jsonStep{array: true, index: -1}
+			if index != "*" {
+				step.index, _ = strconv.Atoi(index)
+			}
+			steps = append(steps, step)
+		}
+	}
+	return steps, nil
+}
+
+// selectJSON returns the values reached by steps from node
+func selectJSON(node interface{}, steps []jsonStep) []interface{} {
+	current := []interface{}{node}
+	for _, step := range steps {
+		var next []interface{}
+		for _, n := range current {
+			if !step.array {
+				if obj, ok := n.(map[string]interface{}); ok {
+					if v, ok := obj[step.key]; ok && v != nil {
+						next = append(next, v)
+					}
+				}
+				continue
+			}
+			arr, ok := n.([]interface{})
+			if !ok {
+				continue
+			}
+			if step.index < 0 {
+				next = append(next, arr...)
+			} else if step.index < len(arr) {
+				next = append(next, arr[step.index])
+			}
+		}
+		current = next
+	}
+	return current
+}
+
+// jsonScalar returns a string or number as text
+func jsonScalar(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	default:
+		return "", false
+	}
+}
+
+// jsonValue returns the first non-empty scalar reached by steps from node
+func jsonValue(node interface{}, steps []jsonStep) string {
+	if steps == nil {
+		return ""
+	}
+	for _, v := range selectJSON(node, steps) {
+		if s, ok := jsonScalar(v); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// jsonDate normalizes a timestamp to RFC 3339: numbers are Unix seconds
+// (or milliseconds, if too large for seconds), strings are parsed like
+// XML dates
+func jsonDate(node interface{}, steps []jsonStep) string {
+	for _, v := range selectJSON(node, steps) {
+		switch v := v.(type) {
+		case float64:
+			if v > 1e11 {
+				return time.UnixMilli(int64(v)).UTC().Format(time.RFC3339)
+			}
+			return time.Unix(int64(v), 0).UTC().Format(time.RFC3339)
+		case string:
+			if v = strings.TrimSpace(v); v != "" {
+				return normalizeXMLDate(v)
+			}
+		}
+	}
+	return ""
+}
+
+// parseMappedJSON maps a JSON document to items using mapping
+func (p *Parser) parseMappedJSON(source string, doc interface{}, mapping JSONMapping) ParseResult {
+	var result ParseResult
+
+	paths := make(map[string][]jsonStep)
+	for field, path := range map[string]string{"item": mapping.Item, "title": mapping.Title, "url": mapping.URL, "date": mapping.Date, "tags": mapping.Tags} {
+		if path == "" {
+			continue
+		}
+		steps, err := compileJSONPath(path)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("json mapping %s: %v", field, err))
+			return result
+		}
+		paths[field] = steps
+	}
+
+	nodes := selectJSON(doc, paths["item"])
+	if len(nodes) == 1 {
+		if arr, ok := nodes[0].([]interface{}); ok {
+			nodes = arr
+		}
+	}
+	if len(nodes) == 0 {
+		result.Errors = append(result.Errors, fmt.Sprintf("nothing matches item path '%s'", mapping.Item))
+		return result
+	}
+	nodes, result.Truncated = p.limitEntries(source, nodes)
+
+	for i, node := range nodes {
+		title := jsonValue(node, paths["title"])
+		url := jsonValue(node, paths["url"])
+		if title == "" || url == "" {
+			result.Errors = append(result.Errors, fmt.Sprintf("item %d: missing required field (title or url)", i))
+			continue
+		}
+
+		feedItem := storage.FeedItem{
+			ID:        p.generateID(source, url),
+			Title:     title,
+			URL:       url,
+			Source:    source,
+			CreatedAt: p.clock.Now(),
+		}
+
+		// Optional: timestamp, normalized to RFC 3339 when recognized
+		if steps, ok := paths["date"]; ok {
+			if timestamp := jsonDate(node, steps); timestamp != "" {
+				feedItem.Timestamp = &timestamp
+			}
+		}
+
+		// Optional: tags, from strings or arrays of strings
+		if steps, ok := paths["tags"]; ok {
+			for _, v := range selectJSON(node, steps) {
+				values, ok := v.([]interface{})
+				if !ok {
+					values = []interface{}{v}
+				}
+				for _, value := range values {
+					if tag, ok := jsonScalar(value); ok && tag != "" {
+						feedItem.Tags = append(feedItem.Tags, tag)
+					}
+				}
+			}
+		}
+
+		result.Items = append(result.Items, feedItem)
+	}
+
+	return result
+}
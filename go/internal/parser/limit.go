@@ -0,0 +1,83 @@
+package parser
+
+import (
+	"sort"
+	"time"
+
+	"feedpulse/internal/storage"
+)
+
+// SetMaxItems caps how many items a single Parse returns; 0 means no
+// limit. By default the first n entries of the response are parsed and
+// the rest skipped unparsed. With newest set, everything is parsed and
+// the n items with the latest timestamps are kept instead.
+func (p *Parser) SetMaxItems(n int, newest bool) {
+	p.maxItems = n
+	p.keepNewest = newest
+}
+
+// SetFeedMaxItems caps how many items a single Parse of source returns,
+// in place of the SetMaxItems limit; 0 leaves source under that limit
+func (p *Parser) SetFeedMaxItems(source string, n int) {
+	if p.feedMaxItems == nil {
+		p.feedMaxItems = make(map[string]int)
+	}
+	p.feedMaxItems[source] = n
+}
+
+// limit is the most items a Parse of source returns, 0 for no limit
+func (p *Parser) limit(source string) int {
+	if n := p.feedMaxItems[source]; n > 0 {
+		return n
+	}
+	return p.maxItems
+}
+
+// limitEntries returns the raw entries to parse under the "first" limit,
+// and how many were dropped
+func (p *Parser) limitEntries(source string, entries []interface{}) ([]interface{}, int) {
+	max := p.limit(source)
+	if max <= 0 || p.keepNewest || len(entries) <= max {
+		return entries, 0
+	}
+	return entries[:max], len(entries) - max
+}
+
+// keepNewestItems applies the "newest" limit to a parse result. Items
+// without a parseable timestamp rank below all dated items.
+func (p *Parser) keepNewestItems(source string, result *ParseResult) {
+	max := p.limit(source)
+	if max <= 0 || !p.keepNewest || len(result.Items) <= max {
+		return
+	}
+
+	type ranked struct {
+		item  storage.FeedItem
+		at    time.Time
+		dated bool
+	}
+	items := make([]ranked, len(result.Items))
+	for i, item := range result.Items {
+		items[i].item = item
+		if item.Timestamp != nil {
+			if t, err := time.Parse(time.RFC3339, *item.Timestamp); err == nil {
+				items[i].at, items[i].dated = t, true
+			}
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].dated != items[j].dated {
+			return items[i].dated
+		}
+		return items[i].at.After(items[j].at)
+	})
+
+	kept := make([]storage.FeedItem, max)
+	for i := range kept {
+		kept[i] = items[i].item
+	}
+
+	result.Truncated += len(result.Items) - max
+	result.Items = kept
+}
@@ -0,0 +1,66 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// maxNDJSONLine bounds a single NDJSON record; longer lines are an error
+const maxNDJSONLine = 4 * 1024 * 1024
+
+// parseNDJSON parses newline-delimited JSON (application/x-ndjson): one
+// document per line, read line by line rather than as one value. A line
+// may also hold several concatenated documents, and a document that is an
+// array contributes each of its elements. Records map through the same
+// generic fields as Lobsters-style arrays (title, url or comments_url,
+// created_at, tags). A malformed line is reported and skipped.
+func (p *Parser) parseNDJSON(source string, data []byte) ParseResult {
+	var result ParseResult
+	var entries []interface{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxNDJSONLine)
+
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Bytes()
+		if lineNum == 1 {
+			line = bytes.TrimPrefix(line, utf8BOM)
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		for {
+			var doc interface{}
+			err := decoder.Decode(&doc)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("line %d: malformed JSON: %v", lineNum, err))
+				break
+			}
+
+			if docs, ok := doc.([]interface{}); ok {
+				entries = append(entries, docs...)
+			} else {
+				entries = append(entries, doc)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("failed to read NDJSON: %v", err))
+	}
+
+	entries, dropped := p.limitEntries(source, entries)
+	mapped := p.parseLobsters(source, entries)
+	result.Items = mapped.Items
+	result.Errors = append(result.Errors, mapped.Errors...)
+	result.Truncated = dropped
+
+	return result
+}
@@ -1,29 +1,88 @@
 package parser
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"html"
 	"log"
 	"strconv"
 	"time"
 
+	"feedpulse/internal/clock"
 	"feedpulse/internal/storage"
+	"feedpulse/internal/transform"
 )
 
 // ParseResult represents the result of parsing a feed
 type ParseResult struct {
 	Items  []storage.FeedItem
 	Errors []string
+
+	// Truncated counts items dropped by the max items limit
+	Truncated int
+
+	// Dropped counts items dropped by the source's transform script
+	Dropped int
+
+	// Malformed is set when the payload couldn't be decoded as its feed
+	// type at all, as opposed to some of its items being unusable
+	Malformed bool
 }
 
 // Parser handles feed parsing and normalization
-type Parser struct{}
+type Parser struct {
+	scope      string
+	runID      string
+	dedup      string
+	clock      clock.Clock
+	maxItems   int
+	keepNewest bool
+
+	// xmlMappings holds the XML mapping of each xml source
+	xmlMappings map[string]XMLMapping
+	// jsonMappings holds the JSON mapping of json sources not using
+	// structure detection
+	jsonMappings map[string]JSONMapping
+	// idHashes holds the ID hash of sources not using the default
+	idHashes map[string]string
+	// feedMaxItems holds the item limit of sources not using maxItems
+	feedMaxItems map[string]int
+	// plugins holds the external parser of each plugin source
+	plugins map[string]Plugin
+	// transforms holds the transform script of sources having one
+	transforms map[string]*transform.Script
+}
 
 // NewParser creates a new parser instance
 func NewParser() *Parser {
-	return &Parser{}
+	return &Parser{scope: storage.ScopeSource, clock: clock.System}
+}
+
+// SetClock sets the clock used to stamp items' CreatedAt
+func (p *Parser) SetClock(c clock.Clock) {
+	p.clock = c
+}
+
+// SetUniquenessScope sets the scope item IDs are generated for. runID
+// identifies the current fetch run and only matters for storage.ScopeRun.
+func (p *Parser) SetUniquenessScope(scope, runID string) {
+	p.scope = scope
+	p.runID = runID
+}
+
+// SetDedup sets the deduplication mode item IDs are generated for (see
+// storage.KeyURL)
+func (p *Parser) SetDedup(dedup string) {
+	p.dedup = dedup
+}
+
+// SetIDHash sets the hash source's item IDs are derived with (see
+// storage.ItemIDWith)
+func (p *Parser) SetIDHash(source, idHash string) {
+	if p.idHashes == nil {
+		p.idHashes = make(map[string]string)
+	}
+	p.idHashes[source] = idHash
 }
 
 // Parse parses raw feed data and returns normalized items
@@ -33,53 +92,94 @@ func (p *Parser) Parse(source string, feedType string, data []byte) ParseResult
 	switch feedType {
 	case "json":
 		result = p.parseJSON(source, data)
+	case "ndjson":
+		result = p.parseNDJSON(source, data)
+	case "xml":
+		result = p.parseXML(source, data)
 	case "rss":
-		result.Errors = append(result.Errors, "RSS parsing not implemented in this version")
+		result = p.parseRSS(source, data)
+	case "plugin":
+		result = p.parsePlugin(source, data)
 	case "atom":
 		result.Errors = append(result.Errors, "Atom parsing not implemented in this version")
 	default:
 		result.Errors = append(result.Errors, fmt.Sprintf("unknown feed type: %s", feedType))
 	}
 
+	p.keepNewestItems(source, &result)
+	p.transformItems(source, &result)
 	return result
 }
 
-// parseJSON parses JSON feeds (HackerNews, GitHub, Reddit, Lobsters)
+// parseJSON parses JSON feeds (HackerNews, GitHub, Reddit, Lobsters,
+// Stack Exchange, Bluesky)
 func (p *Parser) parseJSON(source string, data []byte) ParseResult {
 	var result ParseResult
 
-	// Try to parse as generic JSON first
-	var rawJSON interface{}
-	if err := json.Unmarshal(data, &rawJSON); err != nil {
-		result.Errors = append(result.Errors, fmt.Sprintf("malformed JSON: %v", err))
+	data = TrimJSONPrefix(data)
+	mapping, mapped := p.jsonMappings[source]
+	if !mapped && len(data) > 0 && data[0] == '[' {
+		// HackerNews or Lobsters: arrays, the largest responses, are
+		// decoded an entry at a time
+		return p.parseJSONArray(source, data)
+	}
+
+	// The item array is streamed too, wherever it sits: the mapping's
+	// item path, or the places structure detection looks at
+	paths := jsonItemArrays
+	if mapped {
+		paths = nil
+		if keys, ok := mappedJSONArray(mapping.Item); ok {
+			paths = [][]string{keys}
+		}
+	}
+	max := p.limit(source)
+	if p.keepNewest {
+		max = 0
+	}
+	rawJSON, skipped, err := decodeJSONStream(data, paths, max)
+	if err != nil {
+		return malformedJSON(err)
+	}
+
+	if mapped {
+		result := p.parseMappedJSON(source, rawJSON, mapping)
+		for _, n := range skipped {
+			result.Truncated += n
+		}
 		return result
 	}
 
 	// Detect feed structure and parse accordingly
+	dropped := 0
 	switch v := rawJSON.(type) {
-	case []interface{}:
-		// Could be HackerNews (array of IDs) or Lobsters (array of objects)
-		if len(v) > 0 {
-			if _, ok := v[0].(float64); ok {
-				// HackerNews: array of numeric IDs
-				result = p.parseHackerNews(source, v)
-			} else if _, ok := v[0].(map[string]interface{}); ok {
-				// Lobsters: array of story objects
-				result = p.parseLobsters(source, v)
-			}
-		}
 	case map[string]interface{}:
-		// Could be GitHub or Reddit (both have nested structure)
-		if items, ok := v["items"].([]interface{}); ok {
+		// Could be Stack Exchange, GitHub or Reddit (all have nested structure)
+		if items, ok := v["items"].([]interface{}); ok && isStackExchange(v) {
+			// Stack Exchange: "items" wrapped with quota fields
+			items, dropped = p.limitEntries(source, items)
+			dropped += skipped["items"]
+			result = p.parseStackExchange(source, items)
+		} else if items, ok := v["items"].([]interface{}); ok {
 			// GitHub: has "items" array
+			items, dropped = p.limitEntries(source, items)
+			dropped += skipped["items"]
 			result = p.parseGitHub(source, items)
 		} else if data, ok := v["data"].(map[string]interface{}); ok {
 			// Reddit: has "data" object
 			if children, ok := data["children"].([]interface{}); ok {
+				children, dropped = p.limitEntries(source, children)
+				dropped += skipped["data.children"]
 				result = p.parseReddit(source, children)
 			}
+		} else if feed, ok := v["feed"].([]interface{}); ok {
+			// Bluesky: getAuthorFeed "feed" of post views
+			feed, dropped = p.limitEntries(source, feed)
+			dropped += skipped["feed"]
+			result = p.parseBluesky(source, feed)
 		}
 	}
+	result.Truncated = dropped
 
 	if len(result.Items) == 0 && len(result.Errors) == 0 {
 		result.Errors = append(result.Errors, "unrecognized feed structure")
@@ -88,33 +188,25 @@ func (p *Parser) parseJSON(source string, data []byte) ParseResult {
 	return result
 }
 
-// parseHackerNews parses HackerNews top stories (array of IDs)
-func (p *Parser) parseHackerNews(source string, items []interface{}) ParseResult {
-	var result ParseResult
-
-	for i, item := range items {
-		id, ok := item.(float64)
-		if !ok {
-			result.Errors = append(result.Errors, fmt.Sprintf("item %d: expected numeric ID, got %T", i, item))
-			continue
-		}
-
-		idStr := strconv.Itoa(int(id))
-		title := fmt.Sprintf("HN Story %s", idStr)
-		url := fmt.Sprintf("https://news.ycombinator.com/item?id=%s", idStr)
-
-		feedItem := storage.FeedItem{
-			ID:        p.generateID(source, url),
-			Title:     title,
-			URL:       url,
-			Source:    source,
-			CreatedAt: time.Now(),
-		}
-
-		result.Items = append(result.Items, feedItem)
+// hackerNewsItem parses an entry of HackerNews top stories (an array of
+// IDs)
+func (p *Parser) hackerNewsItem(source string, item interface{}) (storage.FeedItem, error) {
+	id, ok := item.(float64)
+	if !ok {
+		return storage.FeedItem{}, fmt.Errorf("expected numeric ID, got %T", item)
 	}
 
-	return result
+	idStr := strconv.Itoa(int(id))
+	title := fmt.Sprintf("HN Story %s", idStr)
+	url := fmt.Sprintf("https://news.ycombinator.com/item?id=%s", idStr)
+
+	return storage.FeedItem{
+		ID:        p.generateID(source, url),
+		Title:     title,
+		URL:       url,
+		Source:    source,
+		CreatedAt: p.clock.Now(),
+	}, nil
 }
 
 // parseGitHub parses GitHub API response
@@ -142,7 +234,7 @@ func (p *Parser) parseGitHub(source string, items []interface{}) ParseResult {
 			Title:     title,
 			URL:       url,
 			Source:    source,
-			CreatedAt: time.Now(),
+			CreatedAt: p.clock.Now(),
 		}
 
 		// Optional: timestamp
@@ -196,7 +288,7 @@ func (p *Parser) parseReddit(source string, children []interface{}) ParseResult
 			Title:     title,
 			URL:       url,
 			Source:    source,
-			CreatedAt: time.Now(),
+			CreatedAt: p.clock.Now(),
 		}
 
 		// Optional: timestamp (created_utc is Unix timestamp)
@@ -216,8 +308,16 @@ func (p *Parser) parseReddit(source string, children []interface{}) ParseResult
 	return result
 }
 
-// parseLobsters parses Lobsters API response
-func (p *Parser) parseLobsters(source string, items []interface{}) ParseResult {
+// isStackExchange reports whether a response uses the Stack Exchange API
+// wrapper, which always reports the caller's quota
+func isStackExchange(obj map[string]interface{}) bool {
+	_, hasMax := obj["quota_max"]
+	_, hasRemaining := obj["quota_remaining"]
+	return hasMax && hasRemaining
+}
+
+// parseStackExchange parses Stack Exchange API questions
+func (p *Parser) parseStackExchange(source string, items []interface{}) ParseResult {
 	var result ParseResult
 
 	for i, item := range items {
@@ -229,32 +329,28 @@ func (p *Parser) parseLobsters(source string, items []interface{}) ParseResult {
 
 		// Extract required fields
 		title, titleOk := p.getString(obj, "title")
-		url, urlOk := p.getString(obj, "url")
-
-		// Fall back to comments_url if url is not present
-		if !urlOk {
-			url, urlOk = p.getString(obj, "comments_url")
-		}
+		url, urlOk := p.getString(obj, "link")
 
 		if !titleOk || !urlOk {
-			result.Errors = append(result.Errors, fmt.Sprintf("item %d: missing required field (title or url)", i))
+			result.Errors = append(result.Errors, fmt.Sprintf("item %d: missing required field (title or link)", i))
 			continue
 		}
 
 		feedItem := storage.FeedItem{
 			ID:        p.generateID(source, url),
-			Title:     title,
+			Title:     html.UnescapeString(title), // the API HTML-encodes titles
 			URL:       url,
 			Source:    source,
-			CreatedAt: time.Now(),
+			CreatedAt: p.clock.Now(),
 		}
 
-		// Optional: timestamp
-		if timestamp, ok := p.getString(obj, "created_at"); ok {
+		// Optional: timestamp (creation_date is Unix timestamp)
+		if created, ok := obj["creation_date"].(float64); ok {
+			timestamp := time.Unix(int64(created), 0).Format(time.RFC3339)
 			feedItem.Timestamp = &timestamp
 		}
 
-		// Optional: tags
+		// Optional: tags (question tags)
 		if tags, ok := obj["tags"].([]interface{}); ok {
 			for _, tag := range tags {
 				if tagStr, ok := tag.(string); ok {
@@ -269,6 +365,67 @@ func (p *Parser) parseLobsters(source string, items []interface{}) ParseResult {
 	return result
 }
 
+// parseLobsters parses Lobsters API response
+func (p *Parser) parseLobsters(source string, items []interface{}) ParseResult {
+	var result ParseResult
+
+	for i, item := range items {
+		feedItem, err := p.lobstersItem(source, item)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("item %d: %v", i, err))
+			continue
+		}
+		result.Items = append(result.Items, feedItem)
+	}
+
+	return result
+}
+
+// lobstersItem parses a Lobsters story object
+func (p *Parser) lobstersItem(source string, item interface{}) (storage.FeedItem, error) {
+	obj, ok := item.(map[string]interface{})
+	if !ok {
+		return storage.FeedItem{}, fmt.Errorf("expected object, got %T", item)
+	}
+
+	// Extract required fields
+	title, titleOk := p.getString(obj, "title")
+	url, urlOk := p.getString(obj, "url")
+
+	// Fall back to comments_url if url is not present
+	if !urlOk {
+		url, urlOk = p.getString(obj, "comments_url")
+	}
+
+	if !titleOk || !urlOk {
+		return storage.FeedItem{}, fmt.Errorf("missing required field (title or url)")
+	}
+
+	feedItem := storage.FeedItem{
+		ID:        p.generateID(source, url),
+		Title:     title,
+		URL:       url,
+		Source:    source,
+		CreatedAt: p.clock.Now(),
+	}
+
+	// Optional: timestamp
+	if timestamp, ok := p.getString(obj, "created_at"); ok {
+		feedItem.Timestamp = &timestamp
+	}
+
+	// Optional: tags
+	if tags, ok := obj["tags"].([]interface{}); ok {
+		for _, tag := range tags {
+			if tagStr, ok := tag.(string); ok {
+				feedItem.Tags = append(feedItem.Tags, tagStr)
+			}
+		}
+	}
+
+	return feedItem, nil
+}
+
 // getString attempts to extract a string value from a map
 // It handles type coercion for common cases
 func (p *Parser) getString(obj map[string]interface{}, key string) (string, bool) {
@@ -296,8 +453,8 @@ func (p *Parser) getString(obj map[string]interface{}, key string) (string, bool
 	}
 }
 
-// generateID creates a deterministic ID from source name and URL
+// generateID creates a deterministic ID from source name and URL,
+// according to the parser's uniqueness scope
 func (p *Parser) generateID(source, url string) string {
-	hash := sha256.Sum256([]byte(source + url))
-	return hex.EncodeToString(hash[:])
+	return storage.ItemIDWith(p.idHashes[source], p.scope, source, storage.KeyURL(p.dedup, url), p.runID)
 }
@@ -2,7 +2,14 @@ package parser
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
+
+	"feedpulse/internal/clock"
+	"feedpulse/internal/storage"
+	"feedpulse/internal/transform"
 )
 
 func TestParse_HackerNews(t *testing.T) {
@@ -87,6 +94,42 @@ func TestParse_Reddit(t *testing.T) {
 	}
 }
 
+func TestParse_StackExchange(t *testing.T) {
+	p := NewParser()
+	data := []byte(`{
+		"items": [
+			{
+				"tags": ["go", "concurrency"],
+				"question_id": 123,
+				"link": "https://stackoverflow.com/questions/123/why-isn-t-my-channel-closed",
+				"title": "Why isn&#39;t my channel &quot;closed&quot;?",
+				"creation_date": 1704067200
+			}
+		],
+		"has_more": true,
+		"quota_max": 300,
+		"quota_remaining": 297
+	}`)
+
+	result := p.Parse("StackOverflow", "json", data)
+
+	if len(result.Errors) > 0 {
+		t.Errorf("unexpected errors: %v", result.Errors)
+	}
+	if len(result.Items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(result.Items))
+	}
+	if result.Items[0].Title != `Why isn't my channel "closed"?` {
+		t.Errorf("unexpected title: %s", result.Items[0].Title)
+	}
+	if result.Items[0].Timestamp == nil {
+		t.Error("expected timestamp from creation_date")
+	}
+	if len(result.Items[0].Tags) != 2 || result.Items[0].Tags[1] != "concurrency" {
+		t.Errorf("unexpected tags: %v", result.Items[0].Tags)
+	}
+}
+
 func TestParse_Lobsters(t *testing.T) {
 	p := NewParser()
 	data := []byte(`[
@@ -257,14 +300,166 @@ func TestParse_UnknownFeedType(t *testing.T) {
 	}
 }
 
-func TestParse_RSSNotImplemented(t *testing.T) {
+func TestParse_RSS2(t *testing.T) {
 	p := NewParser()
-	data := []byte(`<rss></rss>`)
-	
+	data := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel>
+    <title>Example Blog</title>
+    <link>https://blog.example.com</link>
+    <item>
+      <title><![CDATA[Go & <generics>]]></title>
+      <link>https://blog.example.com/generics</link>
+      <pubDate>Mon, 1 Jan 2024 12:00:00 GMT</pubDate>
+      <category>Go</category>
+      <category><![CDATA[Programming]]></category>
+      <description><![CDATA[<p>Body</p>]]></description>
+    </item>
+    <item>
+      <title>Caf&#233; &amp;amp; more</title>
+      <guid isPermaLink="true">https://blog.example.com/cafe</guid>
+      <dc:date>2024-01-02T08:30:00Z</dc:date>
+      <dc:subject>food</dc:subject>
+    </item>
+    <item>
+      <title>Opaque guid</title>
+      <guid isPermaLink="false">https://blog.example.com/ignored</guid>
+    </item>
+  </channel>
+</rss>`)
+
+	result := p.Parse("Blog", "rss", data)
+
+	if len(result.Items) != 2 {
+		t.Fatalf("expected 2 items, got %d (errors: %v)", len(result.Items), result.Errors)
+	}
+	if len(result.Errors) != 1 || !strings.Contains(result.Errors[0], "item 2") {
+		t.Errorf("expected the item without a link to be reported, got %v", result.Errors)
+	}
+
+	first := result.Items[0]
+	if first.Title != "Go & <generics>" || first.URL != "https://blog.example.com/generics" {
+		t.Errorf("unexpected first item: %+v", first)
+	}
+	if first.Timestamp == nil || *first.Timestamp != "2024-01-01T12:00:00Z" {
+		t.Errorf("expected pubDate normalized to RFC 3339, got %v", first.Timestamp)
+	}
+	if len(first.Tags) != 2 || first.Tags[0] != "Go" || first.Tags[1] != "Programming" {
+		t.Errorf("expected categories as tags, got %v", first.Tags)
+	}
+
+	second := result.Items[1]
+	if second.Title != "Café & more" || second.URL != "https://blog.example.com/cafe" {
+		t.Errorf("unexpected second item: %+v", second)
+	}
+	if second.Timestamp == nil || *second.Timestamp != "2024-01-02T08:30:00Z" {
+		t.Errorf("expected dc:date as timestamp, got %v", second.Timestamp)
+	}
+	if len(second.Tags) != 1 || second.Tags[0] != "food" {
+		t.Errorf("expected dc:subject as a tag, got %v", second.Tags)
+	}
+}
+
+func TestParse_RSS1(t *testing.T) {
+	p := NewParser()
+	data := []byte(`<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel rdf:about="https://example.org/">
+    <title>Example</title>
+  </channel>
+  <item rdf:about="https://example.org/one">
+    <title>One</title>
+    <link>https://example.org/one</link>
+    <dc:date>2024-03-01T10:00:00+01:00</dc:date>
+  </item>
+  <item rdf:about="https://example.org/two">
+    <title>Two</title>
+  </item>
+</rdf:RDF>`)
+
+	result := p.Parse("RDF", "rss", data)
+
+	if len(result.Errors) != 0 || len(result.Items) != 2 {
+		t.Fatalf("expected 2 items, got %d (errors: %v)", len(result.Items), result.Errors)
+	}
+	if result.Items[0].Timestamp == nil || *result.Items[0].Timestamp != "2024-03-01T10:00:00+01:00" {
+		t.Errorf("unexpected timestamp: %v", result.Items[0].Timestamp)
+	}
+	if result.Items[1].URL != "https://example.org/two" {
+		t.Errorf("expected the rdf:about URI without a link, got %q", result.Items[1].URL)
+	}
+}
+
+func TestParse_RSSDateFormats(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Mon, 01 Jan 2024 12:00:00 +0000", "2024-01-01T12:00:00Z"},
+		{"Mon, 1 Jan 2024 12:00:00 -0500", "2024-01-01T12:00:00-05:00"},
+		{"Mon, 01 Jan 2024 12:00 +0000", "2024-01-01T12:00:00Z"},
+		{"01 Jan 2024 12:00:00 +0000", "2024-01-01T12:00:00Z"},
+		{"2024-01-01T12:00:00Z", "2024-01-01T12:00:00Z"},
+		{"Mon, 01 Jan 2024 12:00:00 GMT", "2024-01-01T12:00:00Z"},
+		{"Mon, 01 Jan 2024 12:00:00 EST", "2024-01-01T12:00:00-05:00"},
+		{"Mon, 1 Jul 2024 12:00:00 PDT", "2024-07-01T12:00:00-07:00"},
+		{"Mon, 1 Jul 2024 12:00 CEST", "2024-07-01T12:00:00+02:00"},
+		{"Mon, 1 Jul 2024 12:00:00 GMT+3", "Mon, 1 Jul 2024 12:00:00 GMT+3"},
+		{"Mon, 1 Jul 2024 12:00:00 XYZT", "Mon, 1 Jul 2024 12:00:00 XYZT"},
+		{"sometime last week", "sometime last week"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeRSSDate(tt.in); got != tt.want {
+			t.Errorf("normalizeRSSDate(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParse_RSSErrors(t *testing.T) {
+	p := NewParser()
+
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"malformed", `<rss><channel><item>`, "malformed RSS"},
+		{"not rss", `<feed xmlns="http://www.w3.org/2005/Atom"></feed>`, "not an RSS feed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := p.Parse("Test", "rss", []byte(tt.data))
+			if len(result.Errors) == 0 || !strings.Contains(result.Errors[0], tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, result.Errors)
+			}
+		})
+	}
+}
+
+func TestParse_RSSLatin1(t *testing.T) {
+	p := NewParser()
+	data := append([]byte(`<?xml version="1.0" encoding="ISO-8859-1"?><rss><channel><item><title>Caf`), 0xe9)
+	data = append(data, []byte(`</title><link>https://example.com/cafe</link></item></channel></rss>`)...)
+
 	result := p.Parse("Test", "rss", data)
-	
-	if len(result.Errors) == 0 {
-		t.Error("expected error for RSS (not implemented)")
+	if len(result.Items) != 1 || result.Items[0].Title != "Café" {
+		t.Errorf("expected a Latin-1 title decoded, got %+v (errors: %v)", result.Items, result.Errors)
+	}
+}
+
+func TestParse_RSSMaxItems(t *testing.T) {
+	p := NewParser()
+	p.SetMaxItems(1, false)
+	data := []byte(`<rss><channel>
+<item><title>A</title><link>https://example.com/a</link></item>
+<item><title>B</title><link>https://example.com/b</link></item>
+</channel></rss>`)
+
+	result := p.Parse("Test", "rss", data)
+	if len(result.Items) != 1 || result.Truncated != 1 {
+		t.Errorf("expected 1 item and 1 truncated, got %d and %d", len(result.Items), result.Truncated)
 	}
 }
 
@@ -289,3 +484,350 @@ func BenchmarkGenerateID(b *testing.B) {
 		p.generateID("source", "https://example.com/test")
 	}
 }
+
+func TestParse_UsesInjectedClock(t *testing.T) {
+	at := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	p := NewParser()
+	p.SetClock(clock.Fixed(at))
+
+	result := p.Parse("HackerNews", "json", []byte(`[1, 2]`))
+	if len(result.Items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(result.Items))
+	}
+	for _, item := range result.Items {
+		if !item.CreatedAt.Equal(at) {
+			t.Errorf("expected CreatedAt %v, got %v", at, item.CreatedAt)
+		}
+	}
+}
+
+func TestParse_MaxItemsFirst(t *testing.T) {
+	p := NewParser()
+	p.SetMaxItems(3, false)
+
+	result := p.Parse("HackerNews", "json", []byte(`[1, 2, 3, 4, 5]`))
+
+	if len(result.Items) != 3 || result.Truncated != 2 {
+		t.Fatalf("expected 3 items and 2 truncated, got %d and %d", len(result.Items), result.Truncated)
+	}
+	if result.Items[2].URL != "https://news.ycombinator.com/item?id=3" {
+		t.Errorf("expected the first items to be kept, got %s", result.Items[2].URL)
+	}
+}
+
+func TestParse_MaxItemsNewest(t *testing.T) {
+	p := NewParser()
+	p.SetMaxItems(2, true)
+	data := []byte(`[
+		{"title": "Old", "url": "https://example.com/old", "created_at": "2024-01-01T00:00:00Z"},
+		{"title": "Undated", "url": "https://example.com/undated"},
+		{"title": "Newest", "url": "https://example.com/newest", "created_at": "2024-03-01T00:00:00Z"},
+		{"title": "Middle", "url": "https://example.com/middle", "created_at": "2024-02-01T00:00:00-05:00"}
+	]`)
+
+	result := p.Parse("Lobsters", "json", data)
+
+	if result.Truncated != 2 || len(result.Items) != 2 {
+		t.Fatalf("expected 2 items and 2 truncated, got %d and %d", len(result.Items), result.Truncated)
+	}
+	if result.Items[0].Title != "Newest" || result.Items[1].Title != "Middle" {
+		t.Errorf("expected the newest items, got %s and %s", result.Items[0].Title, result.Items[1].Title)
+	}
+}
+
+func TestParse_MaxItemsUnderLimit(t *testing.T) {
+	p := NewParser()
+	p.SetMaxItems(10, false)
+
+	result := p.Parse("HackerNews", "json", []byte(`[1, 2]`))
+
+	if len(result.Items) != 2 || result.Truncated != 0 {
+		t.Errorf("expected nothing truncated, got %d items and %d truncated", len(result.Items), result.Truncated)
+	}
+}
+
+func TestParse_FeedMaxItems(t *testing.T) {
+	p := NewParser()
+	p.SetMaxItems(10, false)
+	p.SetFeedMaxItems("Small", 1)
+
+	if result := p.Parse("Small", "json", []byte(`[1, 2, 3]`)); len(result.Items) != 1 || result.Truncated != 2 {
+		t.Errorf("expected the feed's limit, got %d items and %d truncated", len(result.Items), result.Truncated)
+	}
+	if result := p.Parse("HackerNews", "json", []byte(`[1, 2, 3]`)); len(result.Items) != 3 || result.Truncated != 0 {
+		t.Errorf("expected other feeds under the default limit, got %d items and %d truncated", len(result.Items), result.Truncated)
+	}
+}
+
+func TestParse_StreamedArray(t *testing.T) {
+	// Entries past the limit are skipped unparsed, however they nest
+	p := NewParser()
+	p.SetMaxItems(2, false)
+	data := []byte(`[
+		{"title": "A", "url": "https://example.com/a", "tags": ["go", {"x": [1]}]},
+		{"title": "No URL"},
+		{"title": "C", "url": "https://example.com/c"},
+		[{"deep": [[], {}]}], "text", 3
+	]`)
+
+	result := p.Parse("Lobsters", "json", data)
+
+	if len(result.Items) != 1 || result.Items[0].Title != "A" || result.Truncated != 4 {
+		t.Fatalf("expected A kept and 4 truncated, got %+v and %d", result.Items, result.Truncated)
+	}
+	if len(result.Errors) != 1 || result.Errors[0] != "item 1: missing required field (title or url)" {
+		t.Errorf("expected the missing URL reported, got %v", result.Errors)
+	}
+
+	tests := []struct {
+		name, data string
+	}{
+		{"truncated", `[1, 2`},
+		{"truncated past the limit", `[1, 2, {"a": [3`},
+		{"invalid past the limit", `[1, 2, {"a" 3}]`},
+		{"trailing data", `[1, 2] [3]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := p.Parse("HackerNews", "json", []byte(tt.data))
+			if !result.Malformed || len(result.Items) != 0 || !strings.Contains(result.Errors[0], "malformed JSON") {
+				t.Errorf("expected malformed JSON, got %+v", result)
+			}
+		})
+	}
+
+	if result := NewParser().Parse("Mixed", "json", []byte(`["a", 1]`)); len(result.Errors) != 1 || result.Errors[0] != "unrecognized feed structure" {
+		t.Errorf("expected an unrecognized structure, got %v", result.Errors)
+	}
+}
+
+func TestParse_StreamedWrappedArray(t *testing.T) {
+	// Item arrays inside an object are streamed too: entries past the
+	// limit are skipped unparsed and the rest of the document is kept
+	p := NewParser()
+	p.SetMaxItems(1, false)
+	data := []byte(`{"kind": "Listing", "data": {"after": "t3_b", "children": [
+		{"kind": "t3", "data": {"title": "A", "url": "https://example.com/a"}},
+		{"kind": "t3", "data": {"title": "B", "url": "https://example.com/b", "x": [[{}]]}},
+		[{"deep": [[], {}]}], "text"
+	], "dist": 4}}`)
+
+	result := p.Parse("Reddit", "json", data)
+	if len(result.Items) != 1 || result.Items[0].Title != "A" || result.Truncated != 3 {
+		t.Fatalf("expected A kept and 3 truncated, got %+v and %d", result.Items, result.Truncated)
+	}
+
+	p.SetJSONMapping("Mapped", JSONMapping{Item: "response.posts[*].post", Title: "title", URL: "url"})
+	data = []byte(`{"response": {"posts": [
+		{"post": {"title": "A", "url": "https://example.com/a"}},
+		{"post": {"title": "B", "url": "https://example.com/b"}},
+		{"post": {"title": "C", "url": "https://example.com/c"}}
+	]}}`)
+	result = p.Parse("Mapped", "json", data)
+	if len(result.Items) != 1 || result.Items[0].Title != "A" || result.Truncated != 2 {
+		t.Fatalf("expected A kept and 2 truncated, got %+v and %d", result.Items, result.Truncated)
+	}
+
+	tests := []struct {
+		name, data string
+	}{
+		{"truncated past the limit", `{"items": [{}, {"a": [3`},
+		{"invalid past the limit", `{"items": [{}, {"a" 3}]}`},
+		{"trailing data", `{"items": []} {}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := p.Parse("GitHub", "json", []byte(tt.data))
+			if !result.Malformed || len(result.Items) != 0 || !strings.Contains(result.Errors[0], "malformed JSON") {
+				t.Errorf("expected malformed JSON, got %+v", result)
+			}
+		})
+	}
+}
+
+func TestParse_NDJSON(t *testing.T) {
+	data := []byte("\xEF\xBB\xBF" + `{"title": "A", "url": "https://example.com/a", "created_at": "2024-01-01T00:00:00Z", "tags": ["logs"]}
+
+{"title": "B", "comments_url": "https://example.com/b"}{"title": "C", "url": "https://example.com/c"}
+{"title": "broken",
+[{"title": "D", "url": "https://example.com/d"}]
+{"title": "No URL"}
+`)
+
+	result := NewParser().Parse("Export", "ndjson", data)
+
+	if len(result.Items) != 4 {
+		t.Fatalf("expected 4 items, got %d (errors: %v)", len(result.Items), result.Errors)
+	}
+	titles := []string{"A", "B", "C", "D"}
+	for i, item := range result.Items {
+		if item.Title != titles[i] {
+			t.Errorf("item %d: expected title %s, got %s", i, titles[i], item.Title)
+		}
+	}
+	if result.Items[0].Timestamp == nil || len(result.Items[0].Tags) != 1 {
+		t.Errorf("expected timestamp and tags on the first item, got %+v", result.Items[0])
+	}
+	if len(result.Errors) != 2 || !strings.Contains(result.Errors[0], "line 4: malformed JSON") {
+		t.Errorf("expected a malformed line and a missing field error, got %v", result.Errors)
+	}
+}
+
+func TestParse_NDJSONMaxItems(t *testing.T) {
+	p := NewParser()
+	p.SetMaxItems(2, false)
+	data := []byte(`{"title": "A", "url": "https://example.com/a"}
+{"title": "B", "url": "https://example.com/b"}
+{"title": "C", "url": "https://example.com/c"}
+`)
+
+	result := p.Parse("Export", "ndjson", data)
+
+	if len(result.Items) != 2 || result.Truncated != 1 {
+		t.Errorf("expected 2 items and 1 truncated, got %d and %d", len(result.Items), result.Truncated)
+	}
+}
+
+func TestParse_Bluesky(t *testing.T) {
+	p := NewParser()
+	data := []byte(`{
+		"feed": [
+			{
+				"post": {
+					"uri": "at://did:plc:abc123/app.bsky.feed.post/3kxyz",
+					"author": {"did": "did:plc:abc123", "handle": "gopher.bsky.social"},
+					"record": {
+						"$type": "app.bsky.feed.post",
+						"text": "Go 1.22 is out! #golang\nRange over ints and more.",
+						"createdAt": "2024-02-06T18:00:00.000Z",
+						"facets": [
+							{"features": [{"$type": "app.bsky.richtext.facet#tag", "tag": "golang"}]},
+							{"features": [{"$type": "app.bsky.richtext.facet#link", "uri": "https://go.dev"}]}
+						],
+						"tags": ["golang", "release"]
+					}
+				}
+			},
+			{
+				"post": {
+					"uri": "at://did:plc:abc123/app.bsky.feed.post/3kabc",
+					"author": {"handle": "gopher.bsky.social"},
+					"record": {"text": ""}
+				},
+				"reason": {"$type": "app.bsky.feed.defs#reasonRepost"}
+			},
+			{"post": {"record": {"text": "no uri"}}}
+		],
+		"cursor": "2024-02-06T18:00:00.000Z"
+	}`)
+
+	result := p.Parse("Bluesky", "json", data)
+
+	if len(result.Items) != 2 || len(result.Errors) != 1 {
+		t.Fatalf("expected 2 items and 1 error, got %d and %v", len(result.Items), result.Errors)
+	}
+	post := result.Items[0]
+	if post.Title != "Go 1.22 is out! #golang" {
+		t.Errorf("unexpected title: %s", post.Title)
+	}
+	if post.URL != "https://bsky.app/profile/gopher.bsky.social/post/3kxyz" {
+		t.Errorf("unexpected url: %s", post.URL)
+	}
+	if post.Timestamp == nil || len(post.Tags) != 2 || post.Tags[1] != "release" {
+		t.Errorf("unexpected timestamp or tags: %v %v", post.Timestamp, post.Tags)
+	}
+	if result.Items[1].Title != "Post by @gopher.bsky.social" {
+		t.Errorf("unexpected fallback title: %s", result.Items[1].Title)
+	}
+}
+
+func TestBlueskyTitle_Truncates(t *testing.T) {
+	title := blueskyTitle(strings.Repeat("é", 200), "x")
+	if utf8.RuneCountInString(title) != blueskyTitleLen || !strings.HasSuffix(title, "…") {
+		t.Errorf("expected a %d-rune title ending in an ellipsis, got %q", blueskyTitleLen, title)
+	}
+}
+
+func TestDetectStructure(t *testing.T) {
+	tests := []struct {
+		feedType string
+		data     string
+		want     string
+	}{
+		{"json", `[1, 2, 3]`, "hackernews"},
+		{"json", `[{"title": "a"}]`, "lobsters"},
+		{"json", `{"items": [], "quota_max": 300, "quota_remaining": 299}`, "stackexchange"},
+		{"json", `{"items": []}`, "github"},
+		{"json", `{"data": {"children": []}}`, "reddit"},
+		{"json", `{"feed": []}`, "bluesky"},
+		{"json", `{"entries": []}`, "unrecognized"},
+		{"json", `{"broken"`, "malformed JSON"},
+		{"ndjson", `{"title": "a"}`, "ndjson"},
+		{"rss", `<rss version="2.0"/>`, "rss 2.0"},
+		{"rss", `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"/>`, "rss 1.0"},
+		{"rss", `<feed/>`, "not an RSS feed"},
+		{"atom", `<feed/>`, "unsupported feed type"},
+	}
+
+	for _, tt := range tests {
+		if got := DetectStructure(tt.feedType, []byte(tt.data)); !strings.HasPrefix(got, tt.want) {
+			t.Errorf("DetectStructure(%s, %s) = %q, want prefix %q", tt.feedType, tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestParse_DedupNormalizesIDs(t *testing.T) {
+	data := []byte(`{"items": [
+		{"title": "A", "url": "https://example.com/a"},
+		{"title": "A again", "url": "https://Example.com/a/?utm_source=rss"}
+	]}`)
+
+	p := NewParser()
+	p.SetJSONMapping("Mapped", JSONMapping{Item: "items", Title: "title", URL: "url"})
+	result := p.Parse("Mapped", "json", data)
+	if len(result.Items) != 2 || result.Items[0].ID == result.Items[1].ID {
+		t.Fatalf("expected distinct IDs without dedup, got %+v", result.Items)
+	}
+
+	p.SetDedup(storage.DedupNormalized)
+	result = p.Parse("Mapped", "json", data)
+	if len(result.Items) != 2 || result.Items[0].ID != result.Items[1].ID {
+		t.Fatalf("expected one ID for both URLs, got %+v", result.Items)
+	}
+	if result.Items[1].URL != "https://Example.com/a/?utm_source=rss" {
+		t.Errorf("expected the URL to be kept as fetched, got %s", result.Items[1].URL)
+	}
+}
+
+func TestParse_Transform(t *testing.T) {
+	script, err := transform.Compile("drop if title contains \"sponsored\"\nset url = sub(url, \"^http://\", \"https://\")\nset title = \"\" if url contains \"broken\"")
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	p := NewParser()
+	p.SetJSONMapping("Mapped", JSONMapping{Item: "items", Title: "title", URL: "url"})
+	p.SetTransform("Mapped", script)
+
+	result := p.Parse("Mapped", "json", []byte(`{"items": [
+		{"title": "Sponsored: buy now", "url": "https://example.com/ad"},
+		{"title": "Kept", "url": "http://example.com/a"},
+		{"title": "Broken", "url": "https://example.com/broken"}
+	]}`))
+
+	if result.Dropped != 1 {
+		t.Errorf("expected 1 dropped item, got %d", result.Dropped)
+	}
+	if len(result.Items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(result.Items))
+	}
+	if kept := result.Items[0]; kept.URL != "https://example.com/a" || kept.ID != p.generateID("Mapped", "https://example.com/a") {
+		t.Errorf("expected the rewritten URL and its ID, got %+v", kept)
+	}
+	if broken := result.Items[1]; broken.Title != "Broken" {
+		t.Errorf("expected the failing item to be kept as parsed, got %+v", broken)
+	}
+	if len(result.Errors) != 1 || !strings.Contains(result.Errors[0], "transform: item 2") {
+		t.Errorf("unexpected errors: %v", result.Errors)
+	}
+}
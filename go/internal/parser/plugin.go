@@ -0,0 +1,173 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"feedpulse/internal/storage"
+)
+
+// PluginProtocol is the version of the plugin protocol, passed to plugins
+// as FEEDPULSE_PLUGIN_PROTOCOL
+const PluginProtocol = "1"
+
+// maxPluginStderr bounds how much of a plugin's stderr is kept for its
+// error message
+const maxPluginStderr = 4096
+
+// Plugin is an external parser for feed_type plugin. Command is run with
+// Args in Dir for every response, with the body on stdin and a minimal
+// environment: PATH, HOME, FEEDPULSE_FEED (the feed's name) and
+// FEEDPULSE_PLUGIN_PROTOCOL. It must exit 0 having written a JSON object
+// on stdout:
+//
+//	{"items": [{"title": "...", "url": "...", "timestamp": "...", "tags": ["..."]}],
+//	 "errors": ["item 3: no link"]}
+//
+// timestamp and tags are optional, and errors reports entries it had to
+// skip. A bare array of items is accepted too. Item IDs are derived from
+// the URL like every other feed's. A plugin that runs past Timeout is
+// killed; one writing more than MaxOutput bytes fails the parse.
+type Plugin struct {
+	Command   string
+	Args      []string
+	Dir       string
+	Timeout   time.Duration
+	MaxOutput int
+}
+
+// pluginOutput is what a plugin writes on stdout
+type pluginOutput struct {
+	Items  []pluginItem `json:"items"`
+	Errors []string     `json:"errors"`
+}
+
+// pluginItem is an item as a plugin writes it
+type pluginItem struct {
+	Title     string   `json:"title"`
+	URL       string   `json:"url"`
+	Timestamp string   `json:"timestamp"`
+	Tags      []string `json:"tags"`
+}
+
+// SetPlugin sets the external parser of the plugin feed source
+func (p *Parser) SetPlugin(source string, plugin Plugin) {
+	if p.plugins == nil {
+		p.plugins = make(map[string]Plugin)
+	}
+	p.plugins[source] = plugin
+}
+
+// parsePlugin runs source's plugin on data. A plugin that fails, or
+// whose output can't be decoded, leaves the payload malformed.
+func (p *Parser) parsePlugin(source string, data []byte) ParseResult {
+	var result ParseResult
+	fail := func(format string, args ...interface{}) ParseResult {
+		result.Errors = append(result.Errors, "plugin "+fmt.Sprintf(format, args...))
+		result.Malformed = true
+		return result
+	}
+
+	plugin, ok := p.plugins[source]
+	if !ok {
+		return fail("not configured for %s", source)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), plugin.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, plugin.Command, plugin.Args...)
+	cmd.Dir = plugin.Dir
+	cmd.Env = []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + os.Getenv("HOME"),
+		"FEEDPULSE_FEED=" + source,
+		"FEEDPULSE_PLUGIN_PROTOCOL=" + PluginProtocol,
+	}
+	cmd.Stdin = bytes.NewReader(data)
+	stdout := &cappedBuffer{max: plugin.MaxOutput}
+	stderr := &cappedBuffer{max: maxPluginStderr}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	// Don't wait on children the plugin left holding its output open
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return fail("timed out after %s", plugin.Timeout)
+	case err != nil:
+		if msg := strings.TrimSpace(stderr.buf.String()); msg != "" {
+			return fail("failed: %v: %s", err, msg)
+		}
+		return fail("failed: %v", err)
+	case stdout.over:
+		return fail("output exceeds %d bytes", plugin.MaxOutput)
+	}
+
+	var out pluginOutput
+	trimmed := bytes.TrimSpace(stdout.buf.Bytes())
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &out.Items)
+	} else {
+		err = json.Unmarshal(trimmed, &out)
+	}
+	if err != nil {
+		return fail("output is not valid JSON: %v", err)
+	}
+
+	items := out.Items
+	if max := p.limit(source); max > 0 && !p.keepNewest && len(items) > max {
+		result.Truncated = len(items) - max
+		items = items[:max]
+	}
+
+	result.Errors = append(result.Errors, out.Errors...)
+	for i, item := range items {
+		title, url := strings.TrimSpace(item.Title), strings.TrimSpace(item.URL)
+		if title == "" || url == "" {
+			result.Errors = append(result.Errors, fmt.Sprintf("item %d: missing required field (title or url)", i))
+			continue
+		}
+
+		feedItem := storage.FeedItem{
+			ID:        p.generateID(source, url),
+			Title:     title,
+			URL:       url,
+			Source:    source,
+			Tags:      item.Tags,
+			CreatedAt: p.clock.Now(),
+		}
+		if ts := strings.TrimSpace(item.Timestamp); ts != "" {
+			ts = normalizeXMLDate(ts)
+			feedItem.Timestamp = &ts
+		}
+		result.Items = append(result.Items, feedItem)
+	}
+
+	return result
+}
+
+// cappedBuffer keeps the first max bytes written to it and discards the
+// rest, so a plugin writing too much runs to its end rather than block
+type cappedBuffer struct {
+	buf  bytes.Buffer
+	max  int
+	over bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); len(p) > room {
+		b.over = true
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
@@ -0,0 +1,116 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writePlugin writes a shell script plugin to a temporary directory
+func writePlugin(t *testing.T, script string) Plugin {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "plugin.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return Plugin{Command: path, Dir: dir, Timeout: 5 * time.Second, MaxOutput: 1 << 20}
+}
+
+func TestParse_Plugin(t *testing.T) {
+	p := NewParser()
+	p.SetPlugin("Exotic", writePlugin(t, `
+read line
+echo "{\"items\": [
+  {\"title\": \"$line\", \"url\": \"https://example.com/1\", \"timestamp\": \"Mon, 02 Jan 2006 15:04:05 GMT\", \"tags\": [\"$FEEDPULSE_FEED\"]},
+  {\"title\": \"no link\"}
+], \"errors\": [\"entry 7: unreadable\"]}"
+`))
+
+	result := p.Parse("Exotic", "plugin", []byte("hello\n"))
+
+	if result.Malformed {
+		t.Fatalf("unexpected malformed result: %v", result.Errors)
+	}
+	if len(result.Items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(result.Items))
+	}
+	item := result.Items[0]
+	if item.Title != "hello" || item.URL != "https://example.com/1" || item.Source != "Exotic" {
+		t.Errorf("unexpected item: %+v", item)
+	}
+	if item.ID == "" {
+		t.Error("expected an item ID")
+	}
+	if item.Timestamp == nil || *item.Timestamp != "2006-01-02T15:04:05Z" {
+		t.Errorf("expected normalized timestamp, got %v", item.Timestamp)
+	}
+	if len(item.Tags) != 1 || item.Tags[0] != "Exotic" {
+		t.Errorf("expected the feed name as tag, got %v", item.Tags)
+	}
+	if len(result.Errors) != 2 || result.Errors[0] != "entry 7: unreadable" || !strings.Contains(result.Errors[1], "missing required field") {
+		t.Errorf("unexpected errors: %v", result.Errors)
+	}
+}
+
+func TestParse_PluginBareArray(t *testing.T) {
+	p := NewParser()
+	p.SetPlugin("Exotic", writePlugin(t, `echo '[{"title": "A", "url": "https://example.com/a"}, {"title": "B", "url": "https://example.com/b"}]'`))
+	p.SetFeedMaxItems("Exotic", 1)
+
+	result := p.Parse("Exotic", "plugin", nil)
+
+	if len(result.Items) != 1 || result.Items[0].Title != "A" {
+		t.Fatalf("expected only the first item, got %+v", result.Items)
+	}
+	if result.Truncated != 1 {
+		t.Errorf("expected 1 truncated item, got %d", result.Truncated)
+	}
+}
+
+func TestParse_PluginFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		timeout time.Duration
+		want    string
+	}{
+		{"exit status", "echo 'cannot parse' >&2; exit 3", 0, "cannot parse"},
+		{"timeout", "sleep 5", 100 * time.Millisecond, "timed out"},
+		{"oversize output", "yes '[1,2,3]' | head -c 4096", 0, "exceeds 1024 bytes"},
+		{"invalid json", "echo 'not json'", 0, "not valid JSON"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := writePlugin(t, tt.script)
+			plugin.MaxOutput = 1024
+			if tt.timeout > 0 {
+				plugin.Timeout = tt.timeout
+			}
+			p := NewParser()
+			p.SetPlugin("Exotic", plugin)
+
+			result := p.Parse("Exotic", "plugin", []byte("payload"))
+
+			if !result.Malformed {
+				t.Error("expected a malformed result")
+			}
+			if len(result.Items) != 0 {
+				t.Errorf("expected no items, got %d", len(result.Items))
+			}
+			if len(result.Errors) != 1 || !strings.Contains(result.Errors[0], tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, result.Errors)
+			}
+		})
+	}
+}
+
+func TestParse_PluginNotConfigured(t *testing.T) {
+	result := NewParser().Parse("Exotic", "plugin", []byte("payload"))
+	if !result.Malformed || len(result.Errors) != 1 {
+		t.Errorf("expected a malformed result, got %+v", result)
+	}
+}
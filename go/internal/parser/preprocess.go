@@ -0,0 +1,28 @@
+package parser
+
+import "bytes"
+
+// utf8BOM is the UTF-8 encoding of U+FEFF
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// TrimJSONPrefix strips what some servers put in front of a JSON document:
+// byte-order marks, whitespace, an XML prolog, or stray characters such as
+// anti-XSSI guards (")]}'"). Everything before the first '{' or '[' is
+// dropped. Data with no object or array in it is returned unchanged, so
+// the JSON error still describes the original payload.
+func TrimJSONPrefix(data []byte) []byte {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	for bytes.HasPrefix(trimmed, utf8BOM) {
+		trimmed = bytes.TrimLeft(trimmed[len(utf8BOM):], " \t\r\n")
+	}
+
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return trimmed
+	}
+
+	start := bytes.IndexAny(trimmed, "{[")
+	if start < 0 {
+		return data
+	}
+	return trimmed[start:]
+}
@@ -1,53 +1,168 @@
 package parser
 
-import "fmt"
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
 
-// parseRSS parses RSS feed format.
-//
-// Status: NOT IMPLEMENTED IN v1.0
-//
-// RSS (Really Simple Syndication) parsing is deferred to v2.0.
-// This function returns an error indicating RSS is not yet supported.
-//
-// Rationale:
-// - Current implementation focuses on JSON feeds (HackerNews, GitHub, Reddit, Lobsters)
-// - RSS requires XML parsing with namespace handling, CDATA sections, and format variations
-// - Most modern services provide JSON APIs which are preferred
-//
-// Future Implementation Notes:
-// - Will use encoding/xml from Go standard library
-// - Need to handle RSS 2.0 and RSS 1.0 (RDF) formats
-// - Should support:
-//   * <title>, <link>, <description>, <pubDate>
-//   * <category> for tags
-//   * Media RSS extensions
-//   * Dublin Core extensions
-//
-// Example RSS structure:
-//   <?xml version="1.0"?>
-//   <rss version="2.0">
-//     <channel>
-//       <title>Feed Title</title>
-//       <item>
-//         <title>Item Title</title>
-//         <link>https://example.com/item</link>
-//         <pubDate>Mon, 01 Jan 2024 12:00:00 GMT</pubDate>
-//         <category>Technology</category>
-//       </item>
-//     </channel>
-//   </rss>
+	"feedpulse/internal/storage"
+)
+
+// rssDocument is an RSS 2.0 <rss> or RSS 1.0 <rdf:RDF> document. RSS 2.0
+// nests items in the channel; RSS 1.0 puts them next to it.
+type rssDocument struct {
+	XMLName xml.Name
+	Channel struct {
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items []rssItem `xml:"item"`
+}
+
+// rssItem is an <item> of either RSS version
+type rssItem struct {
+	Title string `xml:"title"`
+	Link  string `xml:"link"`
+	GUID  struct {
+		Value       string `xml:",chardata"`
+		IsPermaLink string `xml:"isPermaLink,attr"`
+	} `xml:"guid"`
+	About      string   `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	PubDate    string   `xml:"pubDate"`
+	DCDate     string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Categories []string `xml:"category"`
+	DCSubjects []string `xml:"http://purl.org/dc/elements/1.1/ subject"`
+}
+
+// rssDateLayouts are the pubDate variants seen in the wild on top of
+// RFC 822: single-digit days, no weekday, no seconds, and RFC 850
+var rssDateLayouts = []string{
+	"Mon, _2 Jan 2006 15:04:05 -0700",
+	"Mon, _2 Jan 2006 15:04:05 MST",
+	"Mon, _2 Jan 2006 15:04 -0700",
+	"Mon, _2 Jan 2006 15:04 MST",
+	"_2 Jan 2006 15:04:05 -0700",
+	"_2 Jan 2006 15:04:05 MST",
+	"Mon, _2 January 2006 15:04:05 -0700",
+	"Mon, _2 January 2006 15:04:05 MST",
+	time.RFC850,
+}
+
+// parseRSS parses RSS 2.0 and RSS 1.0 (RDF) feeds. Items need a title and
+// a link; without a <link>, a permalink <guid> or the RDF about URI is
+// used. <category> and dc:subject become tags, and pubDate (or dc:date)
+// the timestamp.
 //
-// See: https://www.rssboard.org/rss-specification
+// See: https://www.rssboard.org/rss-specification and
+// https://web.resource.org/rss/1.0/spec
 func (p *Parser) parseRSS(source string, data []byte) ParseResult {
 	var result ParseResult
-	result.Errors = append(result.Errors, 
-		fmt.Sprintf("RSS parsing not implemented in this version. "+
-			"RSS support is planned for v2.0. "+
-			"Source: %s. "+
-			"For now, please use JSON feeds where available.", source))
+
+	var doc rssDocument
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	decoder.CharsetReader = rssCharsetReader
+	if err := decoder.Decode(&doc); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("malformed RSS: %v", err))
+		result.Malformed = true
+		return result
+	}
+	if doc.XMLName.Local != "rss" && doc.XMLName.Local != "RDF" {
+		result.Errors = append(result.Errors, fmt.Sprintf("not an RSS feed: root element is <%s>", doc.XMLName.Local))
+		result.Malformed = true
+		return result
+	}
+
+	items := append(doc.Channel.Items, doc.Items...)
+	if max := p.limit(source); max > 0 && !p.keepNewest && len(items) > max {
+		result.Truncated = len(items) - max
+		items = items[:max]
+	}
+
+	for i, item := range items {
+		title := strings.TrimSpace(html.UnescapeString(item.Title))
+		url := rssItemURL(item)
+		if title == "" || url == "" {
+			result.Errors = append(result.Errors, fmt.Sprintf("item %d: missing required field (title or link)", i))
+			continue
+		}
+
+		feedItem := storage.FeedItem{
+			ID:        p.generateID(source, url),
+			Title:     title,
+			URL:       url,
+			Source:    source,
+			CreatedAt: p.clock.Now(),
+		}
+
+		// Optional: timestamp, normalized to RFC 3339 when recognized
+		date := strings.TrimSpace(item.PubDate)
+		if date == "" {
+			date = strings.TrimSpace(item.DCDate)
+		}
+		if date != "" {
+			timestamp := normalizeRSSDate(date)
+			feedItem.Timestamp = &timestamp
+		}
+
+		// Optional: tags
+		for _, category := range append(item.Categories, item.DCSubjects...) {
+			if tag := strings.TrimSpace(html.UnescapeString(category)); tag != "" {
+				feedItem.Tags = append(feedItem.Tags, tag)
+			}
+		}
+
+		result.Items = append(result.Items, feedItem)
+	}
+
 	return result
 }
 
+// rssItemURL returns the item's link, falling back to a permalink guid and
+// then the RDF about URI
+func rssItemURL(item rssItem) string {
+	if link := strings.TrimSpace(item.Link); link != "" {
+		return link
+	}
+	guid := strings.TrimSpace(item.GUID.Value)
+	if guid != "" && item.GUID.IsPermaLink != "false" && (strings.HasPrefix(guid, "http://") || strings.HasPrefix(guid, "https://")) {
+		return guid
+	}
+	return strings.TrimSpace(item.About)
+}
+
+// rssCharsetReader decodes the legacy single-byte encodings older feeds
+// declare. Windows-1252 is read as Latin-1, which only differs in
+// punctuation.
+func rssCharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "latin-1", "windows-1252", "cp1252", "us-ascii", "ascii":
+		raw, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		runes := make([]rune, len(raw))
+		for i, b := range raw {
+			runes[i] = rune(b)
+		}
+		return strings.NewReader(string(runes)), nil
+	}
+	return nil, fmt.Errorf("unsupported encoding: %s", charset)
+}
+
+// normalizeRSSDate converts a pubDate to RFC 3339, trying the RSS variants
+// before the layouts generic XML dates use
+func normalizeRSSDate(value string) string {
+	if date := normalizeDate(rssDateLayouts, value); date != value {
+		return date
+	}
+	return normalizeXMLDate(value)
+}
+
 // parseAtom parses Atom feed format.
 //
 // Status: NOT IMPLEMENTED IN v1.0
@@ -64,28 +179,29 @@ func (p *Parser) parseRSS(source string, data []byte) ParseResult {
 // - Will use encoding/xml from Go standard library
 // - Atom is more structured than RSS with required namespaces
 // - Should support:
-//   * <entry> elements (similar to RSS <item>)
-//   * <title>, <link rel="alternate">, <updated>, <published>
-//   * <category term="..."> for tags
-//   * <author> information
-//   * <content type="html|text|xhtml">
+//   - <entry> elements (similar to RSS <item>)
+//   - <title>, <link rel="alternate">, <updated>, <published>
+//   - <category term="..."> for tags
+//   - <author> information
+//   - <content type="html|text|xhtml">
 //
 // Example Atom structure:
-//   <?xml version="1.0" encoding="utf-8"?>
-//   <feed xmlns="http://www.w3.org/2005/Atom">
-//     <title>Feed Title</title>
-//     <entry>
-//       <title>Entry Title</title>
-//       <link href="https://example.com/entry"/>
-//       <updated>2024-01-01T12:00:00Z</updated>
-//       <category term="technology"/>
-//     </entry>
-//   </feed>
+//
+//	<?xml version="1.0" encoding="utf-8"?>
+//	<feed xmlns="http://www.w3.org/2005/Atom">
+//	  <title>Feed Title</title>
+//	  <entry>
+//	    <title>Entry Title</title>
+//	    <link href="https://example.com/entry"/>
+//	    <updated>2024-01-01T12:00:00Z</updated>
+//	    <category term="technology"/>
+//	  </entry>
+//	</feed>
 //
 // See: https://datatracker.ietf.org/doc/html/rfc4287
 func (p *Parser) parseAtom(source string, data []byte) ParseResult {
 	var result ParseResult
-	result.Errors = append(result.Errors, 
+	result.Errors = append(result.Errors,
 		fmt.Sprintf("Atom parsing not implemented in this version. "+
 			"Atom support is planned for v2.0. "+
 			"Source: %s. "+
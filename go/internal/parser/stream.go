@@ -0,0 +1,251 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"feedpulse/internal/storage"
+)
+
+// parseJSONArray parses a JSON feed whose document is an array, decoding
+// one entry at a time rather than the whole document into one value, so a
+// response of many megabytes is never held as a tree of maps: each entry
+// is decoded, turned into an item and dropped. Under the "first" limit,
+// entries past it are skipped token by token without being decoded. The
+// first entry decides the structure: numbers are HackerNews story IDs,
+// objects Lobsters-style stories.
+func (p *Parser) parseJSONArray(source string, data []byte) ParseResult {
+	var result ParseResult
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	// The opening '[', checked by the caller
+	if _, err := dec.Token(); err != nil {
+		return malformedJSON(err)
+	}
+
+	max := p.limit(source)
+	if p.keepNewest {
+		max = 0
+	}
+	var parse func(source string, entry interface{}) (storage.FeedItem, error)
+	unrecognized := false
+	for i := 0; dec.More(); i++ {
+		if unrecognized || max > 0 && i >= max {
+			if err := skipValue(dec); err != nil {
+				return malformedJSON(err)
+			}
+			if max > 0 && i >= max {
+				result.Truncated++
+			}
+			continue
+		}
+
+		var entry interface{}
+		if err := dec.Decode(&entry); err != nil {
+			return malformedJSON(err)
+		}
+		if parse == nil {
+			switch entry.(type) {
+			case float64:
+				parse = p.hackerNewsItem
+			case map[string]interface{}:
+				parse = p.lobstersItem
+			default:
+				unrecognized = true
+				continue
+			}
+		}
+
+		item, err := parse(source, entry)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("item %d: %v", i, err))
+			continue
+		}
+		result.Items = append(result.Items, item)
+	}
+
+	// The closing ']', and nothing after it
+	if _, err := dec.Token(); err != nil {
+		return malformedJSON(err)
+	}
+	if tok, err := dec.Token(); err != io.EOF {
+		if err == nil {
+			err = fmt.Errorf("unexpected %v after top-level value", tok)
+		}
+		return malformedJSON(err)
+	}
+
+	if len(result.Items) == 0 && len(result.Errors) == 0 {
+		result.Errors = append(result.Errors, "unrecognized feed structure")
+	}
+	return result
+}
+
+// malformedJSON is the result of a JSON document that can't be decoded
+func malformedJSON(err error) ParseResult {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return ParseResult{Errors: []string{fmt.Sprintf("malformed JSON: %v", err)}, Malformed: true}
+}
+
+// skipValue reads past the next value of dec without building it
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('['), json.Delim('{'):
+			depth++
+		case json.Delim(']'), json.Delim('}'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// jsonItemArrays are the key paths of the item arrays structure detection
+// looks for: Stack Exchange and GitHub "items", Reddit "data.children"
+// and Bluesky "feed"
+var jsonItemArrays = [][]string{{"items"}, {"data", "children"}, {"feed"}}
+
+// mappedJSONArray returns the key path of the array a mapping's item path
+// selects the entries of, or false if it doesn't select them one per
+// entry, e.g. because it picks one element by index
+func mappedJSONArray(item string) ([]string, bool) {
+	steps, err := compileJSONPath(item)
+	if err != nil {
+		return nil, false
+	}
+	var keys []string
+	for i, step := range steps {
+		if !step.array {
+			keys = append(keys, step.key)
+			continue
+		}
+		if step.index >= 0 {
+			return nil, false
+		}
+		// Past the entries only keys may follow, selecting one value each
+		for _, rest := range steps[i+1:] {
+			if rest.array {
+				return nil, false
+			}
+		}
+		return keys, true
+	}
+	// A path ending at an array selects its elements
+	return keys, true
+}
+
+// jsonStream decodes a JSON document like json.Unmarshal into an
+// interface{}, except for the arrays at its key paths: those are read an
+// entry at a time, and entries past max are skipped token by token
+// without being decoded
+type jsonStream struct {
+	dec   *json.Decoder
+	paths [][]string
+	max   int
+	// skipped counts the entries skipped by the joined key path
+	skipped map[string]int
+}
+
+// decodeJSONStream decodes data, streaming the arrays at paths
+func decodeJSONStream(data []byte, paths [][]string, max int) (interface{}, map[string]int, error) {
+	s := &jsonStream{dec: json.NewDecoder(bytes.NewReader(data)), paths: paths, max: max, skipped: make(map[string]int)}
+	doc, err := s.value(nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tok, err := s.dec.Token(); err != io.EOF {
+		if err == nil {
+			err = fmt.Errorf("unexpected %v after top-level value", tok)
+		}
+		return nil, nil, err
+	}
+	return doc, s.skipped, nil
+}
+
+// onPath reports whether the value at keys is on the way to, or is, one
+// of the streamed arrays; other values are decoded whole
+func (s *jsonStream) onPath(keys []string) (on, target bool) {
+	for _, path := range s.paths {
+		if len(keys) > len(path) {
+			continue
+		}
+		prefix := true
+		for i, key := range keys {
+			if path[i] != key {
+				prefix = false
+				break
+			}
+		}
+		if prefix {
+			on = true
+			target = target || len(keys) == len(path)
+		}
+	}
+	return on, target
+}
+
+// value reads the value at keys
+func (s *jsonStream) value(keys []string) (interface{}, error) {
+	if on, _ := s.onPath(keys); !on {
+		var v interface{}
+		err := s.dec.Decode(&v)
+		return v, err
+	}
+
+	tok, err := s.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		obj := make(map[string]interface{})
+		for s.dec.More() {
+			tok, err := s.dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, ok := tok.(string)
+			if !ok {
+				return nil, fmt.Errorf("unexpected %v as object key", tok)
+			}
+			if obj[key], err = s.value(append(keys[:len(keys):len(keys)], key)); err != nil {
+				return nil, err
+			}
+		}
+		_, err := s.dec.Token()
+		return obj, err
+
+	case json.Delim('['):
+		_, target := s.onPath(keys)
+		arr := []interface{}{}
+		for i := 0; s.dec.More(); i++ {
+			if target && s.max > 0 && i >= s.max {
+				if err := skipValue(s.dec); err != nil {
+					return nil, err
+				}
+				s.skipped[strings.Join(keys, ".")]++
+				continue
+			}
+			var entry interface{}
+			if err := s.dec.Decode(&entry); err != nil {
+				return nil, err
+			}
+			arr = append(arr, entry)
+		}
+		_, err := s.dec.Token()
+		return arr, err
+	}
+	return tok, nil
+}
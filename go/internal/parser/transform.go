@@ -0,0 +1,45 @@
+package parser
+
+import (
+	"fmt"
+
+	"feedpulse/internal/transform"
+)
+
+// SetTransform sets the script source's items are run through after
+// parsing
+func (p *Parser) SetTransform(source string, script *transform.Script) {
+	if p.transforms == nil {
+		p.transforms = make(map[string]*transform.Script)
+	}
+	p.transforms[source] = script
+}
+
+// transformItems runs result's items through source's transform script.
+// Dropped items are counted in result.Dropped; an item the script fails
+// on is kept as parsed. Items whose URL changed get the ID of their new
+// URL.
+func (p *Parser) transformItems(source string, result *ParseResult) {
+	script, ok := p.transforms[source]
+	if !ok {
+		return
+	}
+
+	kept := result.Items[:0]
+	for i, item := range result.Items {
+		out, keep, err := script.Apply(item)
+		switch {
+		case err != nil:
+			result.Errors = append(result.Errors, fmt.Sprintf("transform: item %d: %v", i, err))
+			kept = append(kept, item)
+		case !keep:
+			result.Dropped++
+		default:
+			if out.URL != item.URL {
+				out.ID = p.generateID(source, out.URL)
+			}
+			kept = append(kept, out)
+		}
+	}
+	result.Items = kept
+}
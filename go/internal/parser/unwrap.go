@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// StripJSONP returns the argument of a JSONP response such as
+// "callback({...});". A leading "/**/" guard and a trailing semicolon are
+// allowed. Data that isn't a single function call is an error.
+func StripJSONP(data []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(data)
+	for bytes.HasPrefix(trimmed, utf8BOM) {
+		trimmed = bytes.TrimSpace(trimmed[len(utf8BOM):])
+	}
+	trimmed = bytes.TrimSpace(bytes.TrimPrefix(trimmed, []byte("/**/")))
+	trimmed = bytes.TrimSpace(bytes.TrimSuffix(trimmed, []byte(";")))
+
+	open := bytes.IndexByte(trimmed, '(')
+	if open <= 0 || trimmed[len(trimmed)-1] != ')' {
+		return nil, fmt.Errorf("response is not a JSONP callback")
+	}
+	if !isCallbackName(bytes.TrimSpace(trimmed[:open])) {
+		return nil, fmt.Errorf("response is not a JSONP callback: invalid function name %q", trimmed[:open])
+	}
+
+	return bytes.TrimSpace(trimmed[open+1 : len(trimmed)-1]), nil
+}
+
+// isCallbackName reports whether name looks like a JavaScript function
+// reference, e.g. "cb", "jQuery123_456" or "window.handlers.feed"
+func isCallbackName(name []byte) bool {
+	if len(name) == 0 {
+		return false
+	}
+	for _, part := range bytes.Split(name, []byte(".")) {
+		if len(part) == 0 || (part[0] >= '0' && part[0] <= '9') {
+			return false
+		}
+		for _, c := range part {
+			switch {
+			case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '_', c == '$':
+			default:
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// UnwrapStringField decodes a JSON document that carries the real payload
+// as an escaped JSON string, such as {"d": "{\"items\": []}"}. field is a
+// dot path to the string, e.g. "d" or "result.payload".
+func UnwrapStringField(data []byte, field string) ([]byte, error) {
+	var value interface{}
+	if err := json.Unmarshal(TrimJSONPrefix(data), &value); err != nil {
+		return nil, fmt.Errorf("failed to parse wrapper: %w", err)
+	}
+
+	for _, key := range strings.Split(field, ".") {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("wrapper field '%s' not found", field)
+		}
+		if value, ok = obj[key]; !ok {
+			return nil, fmt.Errorf("wrapper field '%s' not found", field)
+		}
+	}
+
+	inner, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("wrapper field '%s' is not a string", field)
+	}
+	return []byte(inner), nil
+}
@@ -0,0 +1,435 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"feedpulse/internal/storage"
+)
+
+// xmlNamespace is the namespace bound to the reserved "xml" prefix
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// XMLMapping tells the generic XML mapper where a source's items and their
+// fields live. Paths are XPath-like: element names separated by "/", an
+// optional leading "//" to match the first element at any depth, "*" for
+// any element, a final "@attr" to read an attribute instead of text, and
+// an optional "[@attr='value']" or "[not(@attr)]" filter on any element.
+// Alternatives are separated by "|"; the first with a value is used.
+// Names may carry a prefix declared in Namespaces (prefix to URI);
+// unprefixed names match any namespace. Item is evaluated from the
+// document root, the other paths from each item element.
+type XMLMapping struct {
+	Item       string
+	Title      string
+	URL        string
+	Date       string
+	Tags       string
+	Namespaces map[string]string
+}
+
+// SetXMLMapping registers the mapping used for the xml feed named source
+func (p *Parser) SetXMLMapping(source string, m XMLMapping) {
+	if p.xmlMappings == nil {
+		p.xmlMappings = make(map[string]XMLMapping)
+	}
+	p.xmlMappings[source] = m
+}
+
+// xmlNode is an element of a parsed XML document
+type xmlNode struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	text     strings.Builder
+	children []*xmlNode
+}
+
+// xmlStep is one compiled segment of an XML path
+type xmlStep struct {
+	space, local string
+	descendant   bool
+	attr         bool
+	filter       *xml.Name
+	filterValue  string
+	// filterAbsent makes the filter match elements without the attribute
+	filterAbsent bool
+}
+
+// xmlPath is a compiled XML path: its "|"-separated alternatives
+type xmlPath [][]xmlStep
+
+// xmlDateLayouts are the timestamp formats XML APIs commonly use
+var xmlDateLayouts = []string{
+	time.RFC3339,
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC822Z,
+	time.RFC822,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// parseXML maps an arbitrary XML document to items using the source's
+// registered XMLMapping
+func (p *Parser) parseXML(source string, data []byte) ParseResult {
+	var result ParseResult
+
+	mapping, ok := p.xmlMappings[source]
+	if !ok {
+		result.Errors = append(result.Errors, fmt.Sprintf("no xml mapping configured for %s", source))
+		return result
+	}
+
+	paths := make(map[string]xmlPath)
+	for field, path := range map[string]string{"item": mapping.Item, "title": mapping.Title, "url": mapping.URL, "date": mapping.Date, "tags": mapping.Tags} {
+		if path == "" {
+			continue
+		}
+		for _, alternative := range xmlAlternatives(path) {
+			steps, err := compileXMLPath(alternative, mapping.Namespaces)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("xml mapping %s: %v", field, err))
+				return result
+			}
+			paths[field] = append(paths[field], steps)
+		}
+	}
+
+	doc, err := parseXMLTree(data)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("malformed XML: %v", err))
+		result.Malformed = true
+		return result
+	}
+
+	var nodes []*xmlNode
+	for _, steps := range paths["item"] {
+		nodes = append(nodes, selectXML(doc, steps)...)
+	}
+	if max := p.limit(source); max > 0 && !p.keepNewest && len(nodes) > max {
+		result.Truncated = len(nodes) - max
+		nodes = nodes[:max]
+	}
+
+	for i, node := range nodes {
+		title := xmlValue(node, paths["title"])
+		url := xmlValue(node, paths["url"])
+		if title == "" || url == "" {
+			result.Errors = append(result.Errors, fmt.Sprintf("item %d: missing required field (title or url)", i))
+			continue
+		}
+
+		feedItem := storage.FeedItem{
+			ID:        p.generateID(source, url),
+			Title:     title,
+			URL:       url,
+			Source:    source,
+			CreatedAt: p.clock.Now(),
+		}
+
+		// Optional: timestamp, normalized to RFC 3339 when recognized
+		if date := xmlValue(node, paths["date"]); date != "" {
+			timestamp := normalizeXMLDate(date)
+			feedItem.Timestamp = &timestamp
+		}
+
+		// Optional: tags
+		for _, steps := range paths["tags"] {
+			for _, match := range selectXML(node, steps) {
+				if tag := strings.TrimSpace(xmlText(match, steps)); tag != "" {
+					feedItem.Tags = append(feedItem.Tags, tag)
+				}
+			}
+		}
+
+		result.Items = append(result.Items, feedItem)
+	}
+
+	if len(result.Items) == 0 && len(result.Errors) == 0 && len(nodes) == 0 {
+		result.Errors = append(result.Errors, fmt.Sprintf("no elements match item path '%s'", mapping.Item))
+	}
+
+	return result
+}
+
+// parseXMLTree reads data into an element tree. The returned node is a
+// nameless document node whose only child is the root element.
+func parseXMLTree(data []byte) (*xmlNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+
+	doc := &xmlNode{}
+	stack := []*xmlNode{doc}
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: t.Name, attrs: t.Attr}
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			stack[len(stack)-1].text.Write(t)
+		}
+	}
+
+	if len(doc.children) == 0 {
+		return nil, fmt.Errorf("no root element")
+	}
+	return doc, nil
+}
+
+// xmlAlternatives splits path at the "|" outside quotes
+func xmlAlternatives(path string) []string {
+	var alternatives []string
+	var quote rune
+	start := 0
+	for i, r := range path {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '|':
+			alternatives = append(alternatives, strings.TrimSpace(path[start:i]))
+			start = i + 1
+		}
+	}
+	return append(alternatives, strings.TrimSpace(path[start:]))
+}
+
+// compileXMLPath parses an XML path, resolving prefixes to namespace URIs
+func compileXMLPath(path string, namespaces map[string]string) ([]xmlStep, error) {
+	descendant := strings.HasPrefix(path, "//")
+	segments := strings.Split(strings.TrimPrefix(path, "//"), "/")
+
+	steps := make([]xmlStep, 0, len(segments))
+	for i, segment := range segments {
+		step := xmlStep{descendant: descendant && i == 0}
+
+		if open := strings.IndexByte(segment, '['); open >= 0 {
+			filter := segment[open:]
+			segment = segment[:open]
+			var name string
+			switch {
+			case strings.HasPrefix(filter, "[not(@") && strings.HasSuffix(filter, ")]"):
+				name = filter[len("[not(@") : len(filter)-2]
+				step.filterAbsent = true
+			case strings.HasPrefix(filter, "[@") && strings.HasSuffix(filter, "']") && strings.Contains(filter, "='"):
+				eq := strings.Index(filter, "='")
+				name = filter[2:eq]
+				step.filterValue = filter[eq+2 : len(filter)-2]
+			default:
+				return nil, fmt.Errorf("invalid filter %q in path '%s'", filter, path)
+			}
+			space, local, err := resolveXMLName(name, namespaces)
+			if err != nil {
+				return nil, err
+			}
+			step.filter = &xml.Name{Space: space, Local: local}
+		}
+
+		if strings.HasPrefix(segment, "@") {
+			if i != len(segments)-1 {
+				return nil, fmt.Errorf("attribute must be the last step of path '%s'", path)
+			}
+			step.attr = true
+			segment = segment[1:]
+		}
+		if segment == "" {
+			return nil, fmt.Errorf("empty step in path '%s'", path)
+		}
+
+		space, local, err := resolveXMLName(segment, namespaces)
+		if err != nil {
+			return nil, err
+		}
+		step.space, step.local = space, local
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+// resolveXMLName splits a "prefix:local" name and resolves the prefix
+func resolveXMLName(name string, namespaces map[string]string) (string, string, error) {
+	prefix, local, ok := strings.Cut(name, ":")
+	if !ok {
+		return "", name, nil
+	}
+	if prefix == "xml" {
+		return xmlNamespace, local, nil
+	}
+	uri, ok := namespaces[prefix]
+	if !ok {
+		return "", "", fmt.Errorf("undeclared namespace prefix '%s'", prefix)
+	}
+	return uri, local, nil
+}
+
+// selectXML returns the elements reached by steps from node. A trailing
+// attribute step is not applied; xmlText reads it from each match.
+func selectXML(node *xmlNode, steps []xmlStep) []*xmlNode {
+	if len(steps) > 0 && steps[len(steps)-1].attr {
+		steps = steps[:len(steps)-1]
+	}
+
+	current := []*xmlNode{node}
+	for _, step := range steps {
+		var next []*xmlNode
+		for _, n := range current {
+			if step.descendant {
+				next = appendDescendants(next, n, step)
+				continue
+			}
+			for _, child := range n.children {
+				if step.matches(child) {
+					next = append(next, child)
+				}
+			}
+		}
+		current = next
+	}
+	return current
+}
+
+// appendDescendants appends every element below n that matches step
+func appendDescendants(matches []*xmlNode, n *xmlNode, step xmlStep) []*xmlNode {
+	for _, child := range n.children {
+		if step.matches(child) {
+			matches = append(matches, child)
+		}
+		matches = appendDescendants(matches, child, step)
+	}
+	return matches
+}
+
+// matches reports whether an element satisfies the step's name and filter
+func (s xmlStep) matches(n *xmlNode) bool {
+	if !matchXMLName(s.space, s.local, n.name) {
+		return false
+	}
+	if s.filter == nil {
+		return true
+	}
+	value, ok := xmlAttr(n, s.filter.Space, s.filter.Local)
+	if s.filterAbsent {
+		return !ok
+	}
+	return ok && value == s.filterValue
+}
+
+// matchXMLName compares a name against a step; empty space matches any
+// namespace and "*" any local name
+func matchXMLName(space, local string, name xml.Name) bool {
+	return (space == "" || space == name.Space) && (local == "*" || local == name.Local)
+}
+
+// xmlAttr returns the value of n's attribute with the given name
+func xmlAttr(n *xmlNode, space, local string) (string, bool) {
+	for _, attr := range n.attrs {
+		if matchXMLName(space, local, attr.Name) {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// xmlText returns the text or, for attribute paths, the attribute value
+// of an element selected by steps
+func xmlText(n *xmlNode, steps []xmlStep) string {
+	if len(steps) > 0 && steps[len(steps)-1].attr {
+		last := steps[len(steps)-1]
+		value, _ := xmlAttr(n, last.space, last.local)
+		return value
+	}
+	return n.text.String()
+}
+
+// xmlValue returns the trimmed value of the first match below n of the
+// first alternative of path that has one, or "" if nothing matches
+func xmlValue(n *xmlNode, path xmlPath) string {
+	for _, steps := range path {
+		if len(steps) == 0 {
+			continue
+		}
+		for _, match := range selectXML(n, steps) {
+			if value := strings.TrimSpace(xmlText(match, steps)); value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// normalizeXMLDate converts a recognized timestamp to RFC 3339, returning
+// anything else unchanged
+func normalizeXMLDate(value string) string {
+	return normalizeDate(xmlDateLayouts, value)
+}
+
+// zoneOffsets are the offsets, in seconds east of UTC, of the zone
+// abbreviations of RFC 822 and the ones feeds commonly use besides.
+// time.Parse only knows the abbreviations of the local zone and reads any
+// other as UTC.
+var zoneOffsets = map[string]int{
+	"UTC": 0, "GMT": 0,
+	"EST": -5 * 3600, "EDT": -4 * 3600,
+	"CST": -6 * 3600, "CDT": -5 * 3600,
+	"MST": -7 * 3600, "MDT": -6 * 3600,
+	"PST": -8 * 3600, "PDT": -7 * 3600,
+	"AKST": -9 * 3600, "AKDT": -8 * 3600,
+	"HST": -10 * 3600,
+	"WET": 0, "WEST": 1 * 3600,
+	"BST": 1 * 3600,
+	"CET": 1 * 3600, "CEST": 2 * 3600,
+	"MET": 1 * 3600, "MEST": 2 * 3600,
+	"EET": 2 * 3600, "EEST": 3 * 3600,
+	"MSK": 3 * 3600,
+	"JST": 9 * 3600, "KST": 9 * 3600,
+	"AEST": 10 * 3600, "AEDT": 11 * 3600,
+	"NZST": 12 * 3600, "NZDT": 13 * 3600,
+}
+
+// normalizeDate converts value to RFC 3339 with the first of layouts it
+// matches. A zone abbreviation is given its offset from zoneOffsets;
+// a value with one that isn't listed there is returned unchanged rather
+// than read as UTC.
+func normalizeDate(layouts []string, value string) string {
+	for _, layout := range layouts {
+		t, err := time.Parse(layout, value)
+		if err != nil {
+			continue
+		}
+		if strings.HasSuffix(layout, "MST") {
+			name, _ := t.Zone()
+			offset, ok := zoneOffsets[name]
+			if !ok {
+				return value
+			}
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.FixedZone(name, offset))
+		}
+		return t.Format(time.RFC3339)
+	}
+	return value
+}
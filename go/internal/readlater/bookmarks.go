@@ -0,0 +1,153 @@
+package readlater
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Linkding saves bookmarks to a linkding instance with a REST API token
+type Linkding struct {
+	endpoint string
+	token    string
+}
+
+// NewLinkding creates a client for the linkding instance at endpoint
+func NewLinkding(endpoint, token string) *Linkding {
+	return &Linkding{endpoint: strings.TrimSuffix(endpoint, "/"), token: token}
+}
+
+// Name returns "linkding"
+func (l *Linkding) Name() string { return "linkding" }
+
+// Save bookmarks pageURL with tags, unless linkding already has a
+// bookmark for it
+func (l *Linkding) Save(ctx context.Context, pageURL, title string, tags []string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.endpoint+"/api/bookmarks/check/?url="+url.QueryEscape(pageURL), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Token "+l.token)
+
+	var check struct {
+		Bookmark json.RawMessage `json:"bookmark"`
+	}
+	if err := send(req, "linkding", &check); err != nil {
+		return err
+	}
+	if len(check.Bookmark) > 0 && string(check.Bookmark) != "null" {
+		return nil
+	}
+
+	if tags == nil {
+		tags = []string{}
+	}
+	body, err := json.Marshal(map[string]interface{}{"url": pageURL, "title": title, "tag_names": tags})
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err = http.NewRequestWithContext(ctx, http.MethodPost, l.endpoint+"/api/bookmarks/", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Token "+l.token)
+	return send(req, "linkding", nil)
+}
+
+// Shiori saves bookmarks to a Shiori instance, logging in with the user's
+// account
+type Shiori struct {
+	endpoint string
+	username string
+	password string
+
+	mu      sync.Mutex
+	token   string
+	session string
+}
+
+// NewShiori creates a client for the Shiori instance at endpoint
+func NewShiori(endpoint, username, password string) *Shiori {
+	return &Shiori{endpoint: strings.TrimSuffix(endpoint, "/"), username: username, password: password}
+}
+
+// Name returns "shiori"
+func (s *Shiori) Name() string { return "shiori" }
+
+// Save bookmarks pageURL with tags
+func (s *Shiori) Save(ctx context.Context, pageURL, title string, tags []string) error {
+	token, session, err := s.login(ctx)
+	if err != nil {
+		return err
+	}
+
+	type tag struct {
+		Name string `json:"name"`
+	}
+	tagList := make([]tag, 0, len(tags))
+	for _, name := range tags {
+		tagList = append(tagList, tag{Name: name})
+	}
+	body, err := json.Marshal(map[string]interface{}{"url": pageURL, "title": title, "tags": tagList, "createArchive": false})
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint+"/api/bookmarks", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Session-Id", session)
+
+	err = send(req, "shiori", nil)
+	var se *statusError
+	if errors.As(err, &se) && se.code == http.StatusUnauthorized {
+		// The session expired; the next save logs in again
+		s.mu.Lock()
+		s.token, s.session = "", ""
+		s.mu.Unlock()
+	}
+	return err
+}
+
+// login returns the session token, logging in on first use
+func (s *Shiori) login(ctx context.Context) (string, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" {
+		return s.token, s.session, nil
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"username": s.username, "password": s.password, "remember_me": false})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint+"/api/v1/auth/login", bytes.NewReader(body))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var resp struct {
+		Message struct {
+			Token   string `json:"token"`
+			Session string `json:"session"`
+		} `json:"message"`
+	}
+	if err := send(req, "shiori login", &resp); err != nil {
+		return "", "", err
+	}
+	if resp.Message.Token == "" {
+		return "", "", fmt.Errorf("shiori login returned no token")
+	}
+	s.token, s.session = resp.Message.Token, resp.Message.Session
+	return s.token, s.session, nil
+}
@@ -0,0 +1,295 @@
+// Package readlater saves items to read-later services (Pocket,
+// Instapaper and Wallabag) and bookmark managers (linkding and Shiori),
+// and works through the queue of saves that auto-save rules put in the
+// database.
+package readlater
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"feedpulse/internal/storage"
+)
+
+// MaxAttempts is how many failed saves a URL gets before it is marked failed
+const MaxAttempts = 3
+
+// ErrRateLimited means a service refused a save for going too fast; the
+// rest of its queue waits for a later run
+var ErrRateLimited = errors.New("rate limited")
+
+// Service is a read-later service or bookmark manager
+type Service interface {
+	// Name is the service's name in the config, e.g. "pocket"
+	Name() string
+	// Save adds a page to the user's reading list
+	Save(ctx context.Context, pageURL, title string, tags []string) error
+}
+
+// httpClient is shared by the services
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// statusError is an unsuccessful HTTP response from a service
+type statusError struct {
+	what   string
+	status string
+	code   int
+	reason string
+}
+
+func (e *statusError) Error() string {
+	if e.reason != "" {
+		return fmt.Sprintf("%s returned %s: %s", e.what, e.status, e.reason)
+	}
+	return fmt.Sprintf("%s returned %s", e.what, e.status)
+}
+
+// Pocket saves to Pocket with an app's consumer key and a user's OAuth
+// access token
+type Pocket struct {
+	endpoint    string
+	consumerKey string
+	accessToken string
+}
+
+// NewPocket creates a Pocket client
+func NewPocket(consumerKey, accessToken string) *Pocket {
+	return &Pocket{endpoint: "https://getpocket.com", consumerKey: consumerKey, accessToken: accessToken}
+}
+
+// SetEndpoint points the client at another server, e.g. a test server
+func (p *Pocket) SetEndpoint(endpoint string) {
+	p.endpoint = strings.TrimSuffix(endpoint, "/")
+}
+
+// Name returns "pocket"
+func (p *Pocket) Name() string { return "pocket" }
+
+// Save adds pageURL to the user's Pocket list
+func (p *Pocket) Save(ctx context.Context, pageURL, title string, tags []string) error {
+	body, err := json.Marshal(map[string]string{
+		"url":          pageURL,
+		"title":        title,
+		"tags":         strings.Join(tags, ","),
+		"consumer_key": p.consumerKey,
+		"access_token": p.accessToken,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+"/v3/add", strings.NewReader(string(body)))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	req.Header.Set("X-Accept", "application/json")
+	return send(req, "pocket", nil)
+}
+
+// Instapaper saves to Instapaper with the user's login, through its
+// simple API
+type Instapaper struct {
+	endpoint string
+	username string
+	password string
+}
+
+// NewInstapaper creates an Instapaper client
+func NewInstapaper(username, password string) *Instapaper {
+	return &Instapaper{endpoint: "https://www.instapaper.com", username: username, password: password}
+}
+
+// SetEndpoint points the client at another server, e.g. a test server
+func (i *Instapaper) SetEndpoint(endpoint string) {
+	i.endpoint = strings.TrimSuffix(endpoint, "/")
+}
+
+// Name returns "instapaper"
+func (i *Instapaper) Name() string { return "instapaper" }
+
+// Save adds pageURL to the user's unread list. Instapaper has no tags.
+func (i *Instapaper) Save(ctx context.Context, pageURL, title string, tags []string) error {
+	form := url.Values{}
+	form.Set("url", pageURL)
+	form.Set("title", title)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.endpoint+"/api/add", strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(i.username, i.password)
+	return send(req, "instapaper", nil)
+}
+
+// Wallabag saves to a Wallabag instance, with an API client's ID and
+// secret and the user's login, exchanged for an OAuth token
+type Wallabag struct {
+	endpoint     string
+	clientID     string
+	clientSecret string
+	username     string
+	password     string
+
+	mu    sync.Mutex
+	token string
+}
+
+// NewWallabag creates a client for the Wallabag instance at endpoint
+func NewWallabag(endpoint, clientID, clientSecret, username, password string) *Wallabag {
+	return &Wallabag{
+		endpoint:     strings.TrimSuffix(endpoint, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		username:     username,
+		password:     password,
+	}
+}
+
+// Name returns "wallabag"
+func (w *Wallabag) Name() string { return "wallabag" }
+
+// Save adds pageURL as an entry with tags
+func (w *Wallabag) Save(ctx context.Context, pageURL, title string, tags []string) error {
+	token, err := w.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{}
+	form.Set("url", pageURL)
+	form.Set("title", title)
+	if len(tags) > 0 {
+		form.Set("tags", strings.Join(tags, ","))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.endpoint+"/api/entries.json", strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	err = send(req, "wallabag", nil)
+	var se *statusError
+	if errors.As(err, &se) && se.code == http.StatusUnauthorized {
+		// Tokens expire after an hour; the next save logs in again
+		w.mu.Lock()
+		w.token = ""
+		w.mu.Unlock()
+	}
+	return err
+}
+
+// accessToken returns the OAuth token, logging in on first use
+func (w *Wallabag) accessToken(ctx context.Context) (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.token != "" {
+		return w.token, nil
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "password")
+	form.Set("client_id", w.clientID)
+	form.Set("client_secret", w.clientSecret)
+	form.Set("username", w.username)
+	form.Set("password", w.password)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.endpoint+"/oauth/v2/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var resp struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := send(req, "wallabag login", &resp); err != nil {
+		return "", err
+	}
+	if resp.AccessToken == "" {
+		return "", fmt.Errorf("wallabag login returned no access token")
+	}
+	w.token = resp.AccessToken
+	return w.token, nil
+}
+
+// send performs req, decoding a JSON answer into v if it is set
+func send(req *http.Request, what string, v interface{}) error {
+	req.Header.Set("User-Agent", "feedpulse/1.0")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", what, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return fmt.Errorf("%s: %w", what, ErrRateLimited)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// Pocket explains failures in a header
+		return &statusError{what: what, status: resp.Status, code: resp.StatusCode, reason: resp.Header.Get("X-Error")}
+	}
+
+	if v == nil {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+		return nil
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v); err != nil {
+		return fmt.Errorf("invalid %s response: %w", what, err)
+	}
+	return nil
+}
+
+// Process sends up to limit queued saves to their services. Saves for
+// services missing from services, or that rate limit it, stay queued.
+// report, if set, is called with each entry's new state.
+func Process(ctx context.Context, store storage.Store, services map[string]Service, limit int, report func(storage.SaveEntry)) (int, error) {
+	queued, err := store.QueuedSaves(limit)
+	if err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	limited := make(map[string]bool)
+	for _, entry := range queued {
+		svc := services[entry.Service]
+		if svc == nil || limited[entry.Service] {
+			continue
+		}
+
+		err := svc.Save(ctx, entry.URL, entry.Title, entry.Tags)
+		switch {
+		case ctx.Err() != nil:
+			return processed, ctx.Err()
+		case errors.Is(err, ErrRateLimited):
+			limited[entry.Service] = true
+			continue
+		case err != nil:
+			entry.Attempts++
+			entry.LastError = err.Error()
+			if entry.Attempts >= MaxAttempts {
+				entry.Status = storage.SaveFailed
+			}
+		default:
+			entry.Attempts++
+			entry.Status = storage.SaveDone
+			entry.LastError = ""
+		}
+
+		if err := store.UpdateSave(entry); err != nil {
+			return processed, err
+		}
+		processed++
+		if report != nil {
+			report(entry)
+		}
+	}
+	return processed, nil
+}
@@ -0,0 +1,245 @@
+package readlater
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"feedpulse/internal/storage"
+	"feedpulse/internal/testutil"
+)
+
+func TestPocket_Save(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/add" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		if got["access_token"] != "token" {
+			w.Header().Set("X-Error", "Invalid access token")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"status":1}`))
+	}))
+	defer srv.Close()
+
+	p := NewPocket("consumer", "token")
+	p.SetEndpoint(srv.URL)
+	if err := p.Save(context.Background(), "https://example.com/a", "A", []string{"go", "db"}); err != nil {
+		t.Fatal(err)
+	}
+	if got["url"] != "https://example.com/a" || got["tags"] != "go,db" || got["consumer_key"] != "consumer" {
+		t.Errorf("unexpected request: %v", got)
+	}
+
+	p = NewPocket("consumer", "expired")
+	p.SetEndpoint(srv.URL)
+	err := p.Save(context.Background(), "https://example.com/a", "A", nil)
+	if err == nil || err.Error() != "pocket returned 401 Unauthorized: Invalid access token" {
+		t.Errorf("expected Pocket's reason in the error, got %v", err)
+	}
+}
+
+func TestInstapaper_Save(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "alice" || pass != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.FormValue("url") == "https://example.com/limited" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	i := NewInstapaper("alice", "secret")
+	i.SetEndpoint(srv.URL)
+	if err := i.Save(context.Background(), "https://example.com/a", "A", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.Save(context.Background(), "https://example.com/limited", "", nil); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected ErrRateLimited, got %v", err)
+	}
+}
+
+func TestWallabag_SaveLogsInOnce(t *testing.T) {
+	logins := 0
+	var tags string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth/v2/token":
+			if r.FormValue("grant_type") != "password" || r.FormValue("client_secret") != "secret" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			logins++
+			w.Write([]byte(`{"access_token":"abc","expires_in":3600}`))
+		case "/api/entries.json":
+			if r.Header.Get("Authorization") != "Bearer abc" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			tags = r.FormValue("tags")
+			w.Write([]byte(`{"id":1}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	wb := NewWallabag(srv.URL, "id", "secret", "alice", "pw")
+	for i := 0; i < 2; i++ {
+		if err := wb.Save(context.Background(), "https://example.com/a", "A", []string{"go"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if logins != 1 || tags != "go" {
+		t.Errorf("logins = %d, tags = %q; want 1 login and tags sent", logins, tags)
+	}
+}
+
+// fakeService saves everything except failURL
+type fakeService struct {
+	name    string
+	failURL string
+	limited bool
+	saved   []string
+}
+
+func (f *fakeService) Name() string { return f.name }
+
+func (f *fakeService) Save(ctx context.Context, pageURL, title string, tags []string) error {
+	if f.limited {
+		return ErrRateLimited
+	}
+	if pageURL == f.failURL {
+		return errors.New("server error")
+	}
+	f.saved = append(f.saved, pageURL)
+	return nil
+}
+
+func TestProcess(t *testing.T) {
+	store := testutil.NewMockStore()
+	items := []storage.FeedItem{
+		{ID: "1", Title: "A", URL: "https://example.com/a", Source: "HN"},
+		{ID: "2", Title: "B", URL: "https://example.com/b", Source: "HN"},
+	}
+	for _, service := range []string{"pocket", "instapaper", "wallabag"} {
+		if _, err := store.QueueSave(service, items); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pocket := &fakeService{name: "pocket", failURL: "https://example.com/b"}
+	instapaper := &fakeService{name: "instapaper", limited: true}
+	services := map[string]Service{"pocket": pocket, "instapaper": instapaper}
+
+	for i := 0; i < MaxAttempts; i++ {
+		if _, err := Process(context.Background(), store, services, 0, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(pocket.saved) != 1 {
+		t.Errorf("expected one Pocket save, got %v", pocket.saved)
+	}
+	failed, _ := store.GetSave("pocket", "https://example.com/b")
+	if failed.Status != storage.SaveFailed || failed.Attempts != MaxAttempts || failed.LastError == "" {
+		t.Errorf("unexpected failed save: %+v", failed)
+	}
+
+	// Rate-limited and unconfigured services keep their queue
+	queued, err := store.QueuedSaves(0)
+	if err != nil || len(queued) != 4 {
+		t.Errorf("expected instapaper and wallabag saves to stay queued, got %+v (%v)", queued, err)
+	}
+}
+
+func TestLinkding_SaveSkipsBookmarkedURLs(t *testing.T) {
+	var created []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/bookmarks/check/":
+			if r.URL.Query().Get("url") == "https://example.com/old" {
+				w.Write([]byte(`{"bookmark":{"id":7,"url":"https://example.com/old"},"metadata":{}}`))
+				return
+			}
+			w.Write([]byte(`{"bookmark":null,"metadata":{}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/bookmarks/":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			created = append(created, body)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":8}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	l := NewLinkding(srv.URL+"/", "secret")
+	if err := l.Save(context.Background(), "https://example.com/new", "New", []string{"go"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Save(context.Background(), "https://example.com/old", "Old", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(created) != 1 || created[0]["url"] != "https://example.com/new" {
+		t.Fatalf("expected only the new URL bookmarked, got %v", created)
+	}
+	if tags, _ := created[0]["tag_names"].([]interface{}); len(tags) != 1 || tags[0] != "go" {
+		t.Errorf("unexpected tag_names: %v", created[0]["tag_names"])
+	}
+}
+
+func TestShiori_Save(t *testing.T) {
+	logins := 0
+	var body struct {
+		URL  string `json:"url"`
+		Tags []struct {
+			Name string `json:"name"`
+		} `json:"tags"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/auth/login":
+			logins++
+			w.Write([]byte(`{"ok":true,"message":{"token":"tok","session":"sess","expires":0}}`))
+		case "/api/bookmarks":
+			if r.Header.Get("Authorization") != "Bearer tok" || r.Header.Get("X-Session-Id") != "sess" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			w.Write([]byte(`{"id":1}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	s := NewShiori(srv.URL, "alice", "pw")
+	for i := 0; i < 2; i++ {
+		if err := s.Save(context.Background(), "https://example.com/a", "A", []string{"go"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if logins != 1 || body.URL != "https://example.com/a" || len(body.Tags) != 1 || body.Tags[0].Name != "go" {
+		t.Errorf("logins = %d, body = %+v; want 1 login and the tagged URL", logins, body)
+	}
+}
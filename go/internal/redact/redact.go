@@ -0,0 +1,188 @@
+// Package redact masks secrets, such as API keys in headers and URL query
+// strings, in text that is printed, logged or stored: fetch errors, the
+// endpoints of the fetch log, error reports.
+package redact
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Mask replaces every secret
+const Mask = "REDACTED"
+
+// minSecretLen is the shortest value masked wherever it appears; shorter
+// ones would mask ordinary words
+const minSecretLen = 6
+
+// DefaultHeaders are the request headers whose values are always secret
+var DefaultHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+	"X-Auth-Token",
+}
+
+// DefaultParams are the URL query parameters whose values are always
+// secret
+var DefaultParams = []string{
+	"access_token",
+	"api_key",
+	"apikey",
+	"auth",
+	"client_secret",
+	"key",
+	"password",
+	"secret",
+	"sig",
+	"signature",
+	"token",
+}
+
+// DefaultFields are the JSON fields whose string values are always
+// secret, such as the tokens an OAuth token endpoint answers with
+var DefaultFields = []string{
+	"access_token",
+	"client_secret",
+	"id_token",
+	"refresh_token",
+}
+
+// Redactor masks the values of secret headers, query parameters and JSON
+// fields, and known secret values wherever they appear. A nil Redactor masks
+// nothing. Add secrets before sharing it between goroutines.
+type Redactor struct {
+	headers map[string]bool
+	params  map[string]bool
+	secrets []string
+
+	headerPattern *regexp.Regexp
+	paramPattern  *regexp.Regexp
+	fieldPattern  *regexp.Regexp
+}
+
+// New creates a redactor for DefaultHeaders and DefaultParams plus the
+// given header names and query parameters, compared case-insensitively
+func New(headers, params []string) *Redactor {
+	r := &Redactor{
+		headers: make(map[string]bool),
+		params:  make(map[string]bool),
+	}
+	for _, h := range append(append([]string{}, DefaultHeaders...), headers...) {
+		r.headers[strings.ToLower(h)] = true
+	}
+	for _, p := range append(append([]string{}, DefaultParams...), params...) {
+		r.params[strings.ToLower(p)] = true
+	}
+
+	// "Authorization: Bearer x", "authorization=x" and JSON's
+	// "Authorization":"x" all mask the value up to the end of the line,
+	// field or string
+	r.headerPattern = regexp.MustCompile(`(?i)(\b(?:` + alternatives(r.headers) + `)"?\s*[:=]\s*"?)([^"\r\n,}\]]+)`)
+	// The value runs until the next parameter, fragment, or the end of
+	// the URL inside a message
+	r.paramPattern = regexp.MustCompile(`(?i)([?&;](?:` + alternatives(r.params) + `)=)([^&#\s"'<>]+)`)
+	// A JSON string value runs to its closing quote, skipping escapes
+	fields := make(map[string]bool)
+	for _, f := range DefaultFields {
+		fields[f] = true
+	}
+	r.fieldPattern = regexp.MustCompile(`(?i)("(?:` + alternatives(fields) + `)"\s*:\s*")((?:[^"\\]|\\.)*)`)
+	return r
+}
+
+// alternatives joins names into a regexp alternation, longest first so a
+// name that is a prefix of another doesn't win
+func alternatives(names map[string]bool) string {
+	var quoted []string
+	for name := range names {
+		quoted = append(quoted, regexp.QuoteMeta(name))
+	}
+	sort.Slice(quoted, func(i, j int) bool {
+		if len(quoted[i]) != len(quoted[j]) {
+			return len(quoted[i]) > len(quoted[j])
+		}
+		return quoted[i] < quoted[j]
+	})
+	return strings.Join(quoted, "|")
+}
+
+// SecretHeader reports whether the value of header name is secret
+func (r *Redactor) SecretHeader(name string) bool {
+	return r != nil && r.headers[strings.ToLower(name)]
+}
+
+// SecretParam reports whether the value of query parameter name is
+// secret
+func (r *Redactor) SecretParam(name string) bool {
+	return r != nil && r.params[strings.ToLower(name)]
+}
+
+// AddSecret masks value wherever it appears from now on, e.g. a token
+// the config sets; values shorter than six characters are ignored
+func (r *Redactor) AddSecret(value string) {
+	value = strings.TrimSpace(value)
+	if r == nil || len(value) < minSecretLen {
+		return
+	}
+	for _, s := range r.secrets {
+		if s == value {
+			return
+		}
+	}
+	r.secrets = append(r.secrets, value)
+	// Longest first, so a secret containing another is masked whole
+	sort.Slice(r.secrets, func(i, j int) bool { return len(r.secrets[i]) > len(r.secrets[j]) })
+}
+
+// AddURL masks the values of the secret query parameters of rawURL
+// wherever they appear, in case they are printed without their names
+func (r *Redactor) AddURL(rawURL string) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return
+	}
+	for name, values := range u.Query() {
+		if r.SecretParam(name) {
+			for _, v := range values {
+				r.AddSecret(v)
+			}
+		}
+	}
+}
+
+// String returns s with every secret masked
+func (r *Redactor) String(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, Mask)
+		// URLs in messages carry the secret escaped
+		if escaped := url.QueryEscape(secret); escaped != secret {
+			s = strings.ReplaceAll(s, escaped, Mask)
+		}
+	}
+	s = r.paramPattern.ReplaceAllString(s, "${1}"+Mask)
+	s = r.fieldPattern.ReplaceAllString(s, "${1}"+Mask)
+	return r.headerPattern.ReplaceAllString(s, "${1}"+Mask)
+}
+
+// Header returns a copy of h with the values of secret headers masked
+func (r *Redactor) Header(h map[string]string) map[string]string {
+	if h == nil {
+		return nil
+	}
+	masked := make(map[string]string, len(h))
+	for name, value := range h {
+		if r.SecretHeader(name) {
+			value = Mask
+		}
+		masked[name] = value
+	}
+	return masked
+}
@@ -0,0 +1,103 @@
+package redact
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	r := New([]string{"X-Custom-Secret"}, []string{"client_id"})
+	r.AddSecret("ghp_abcdef123456")
+	r.AddSecret("short")
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			"query parameter",
+			`Get "https://api.example.com/v1?q=go&api_key=abc123&page=2": dial tcp: timeout`,
+			`Get "https://api.example.com/v1?q=go&api_key=REDACTED&page=2": dial tcp: timeout`,
+		},
+		{
+			"configured parameter, any case",
+			"https://example.com/feed?CLIENT_ID=xyz#top",
+			"https://example.com/feed?CLIENT_ID=REDACTED#top",
+		},
+		{
+			"header line",
+			"request failed: Authorization: Bearer eyJhbGciOi\nnext line",
+			"request failed: Authorization: REDACTED\nnext line",
+		},
+		{
+			"JSON header",
+			`{"X-Custom-Secret":"s3cr3t","Accept":"application/json"}`,
+			`{"X-Custom-Secret":"REDACTED","Accept":"application/json"}`,
+		},
+		{
+			"known secret anywhere",
+			"token ghp_abcdef123456 was rejected",
+			"token REDACTED was rejected",
+		},
+		{
+			"escaped secret",
+			"https://example.com/?q=" + url.QueryEscape("ghp_abcdef123456"),
+			"https://example.com/?q=REDACTED",
+		},
+		{
+			"short values aren't secrets",
+			"a short answer",
+			"a short answer",
+		},
+		{
+			"nothing secret",
+			"HTTP 404: 404 Not Found",
+			"HTTP 404: 404 Not Found",
+		},
+		{
+			"token response",
+			`{"access_token": "eyJ0\"x", "token_type": "bearer", "refresh_token":"r-123"}`,
+			`{"access_token": "REDACTED", "token_type": "bearer", "refresh_token":"REDACTED"}`,
+		},
+		{
+			"parameter names alone",
+			"the keyword and the token were fine",
+			"the keyword and the token were fine",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := r.String(tt.in); got != tt.want {
+			t.Errorf("%s: String(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAddURL(t *testing.T) {
+	r := New(nil, nil)
+	r.AddURL("https://example.com/feed?token=tok_0123456789&page=1")
+
+	err := fmt.Errorf("wrapped: %w", errors.New("bad credentials tok_0123456789"))
+	if got := r.String(err.Error()); got != "wrapped: bad credentials REDACTED" {
+		t.Errorf("expected the URL's token masked on its own, got %q", got)
+	}
+}
+
+func TestHeader(t *testing.T) {
+	r := New(nil, nil)
+	got := r.Header(map[string]string{"authorization": "Bearer x", "User-Agent": "feedpulse"})
+	if got["authorization"] != Mask || got["User-Agent"] != "feedpulse" {
+		t.Errorf("unexpected headers: %v", got)
+	}
+}
+
+func TestNil(t *testing.T) {
+	var r *Redactor
+	if got := r.String("api_key=abc"); got != "api_key=abc" {
+		t.Errorf("expected a nil redactor to mask nothing, got %q", got)
+	}
+	r.AddSecret("ignored-secret")
+}
@@ -0,0 +1,351 @@
+// Package sentry forwards unexpected internal errors and panics to Sentry,
+// or a service speaking its protocol such as GlitchTip. Routine failures,
+// like a feed timing out or serving something unparseable, are not its
+// business: callers only report what points at a bug or a broken
+// installation.
+package sentry
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RepeatInterval is how long an event is not sent again after it was,
+// so a daemon hitting the same error every poll reports it once an hour
+const RepeatInterval = time.Hour
+
+// Levels of the events sent
+const (
+	LevelError = "error"
+	LevelFatal = "fatal"
+)
+
+// DSN is a parsed client key, e.g. https://<key>@o1.ingest.sentry.io/42
+type DSN struct {
+	PublicKey string
+	ProjectID string
+	// StoreURL is where events are posted
+	StoreURL string
+}
+
+// ParseDSN parses a Sentry DSN
+func ParseDSN(raw string) (*DSN, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DSN: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid DSN: scheme must be http or https")
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, fmt.Errorf("invalid DSN: missing public key")
+	}
+
+	path := strings.TrimSuffix(u.Path, "/")
+	i := strings.LastIndex(path, "/")
+	if i < 0 || path[i+1:] == "" {
+		return nil, fmt.Errorf("invalid DSN: missing project ID")
+	}
+	prefix, project := path[:i], path[i+1:]
+
+	return &DSN{
+		PublicKey: u.User.Username(),
+		ProjectID: project,
+		StoreURL:  fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, prefix, project),
+	}, nil
+}
+
+// Options configures a client
+type Options struct {
+	DSN         string
+	Release     string
+	Environment string
+	// Tags are sent with every event, e.g. the config fingerprint
+	Tags map[string]string
+	// Scrub, when set, masks secrets in the messages and extra values
+	// of events before they are sent
+	Scrub func(string) string
+}
+
+// Client sends events to one project
+type Client struct {
+	http       *http.Client
+	dsn        *DSN
+	opts       Options
+	serverName string
+
+	mu   sync.Mutex
+	sent map[string]time.Time
+}
+
+// New creates a client sending to the project opts.DSN names
+func New(opts Options) (*Client, error) {
+	dsn, err := ParseDSN(opts.DSN)
+	if err != nil {
+		return nil, err
+	}
+	hostname, _ := os.Hostname()
+	return &Client{
+		http:       &http.Client{Timeout: 5 * time.Second},
+		dsn:        dsn,
+		opts:       opts,
+		serverName: hostname,
+		sent:       make(map[string]time.Time),
+	}, nil
+}
+
+// Event is what is sent to Sentry for one error or panic
+type Event struct {
+	EventID     string                       `json:"event_id"`
+	Timestamp   string                       `json:"timestamp"`
+	Level       string                       `json:"level"`
+	Platform    string                       `json:"platform"`
+	Logger      string                       `json:"logger"`
+	Release     string                       `json:"release,omitempty"`
+	Environment string                       `json:"environment,omitempty"`
+	ServerName  string                       `json:"server_name,omitempty"`
+	Exception   Exceptions                   `json:"exception"`
+	Tags        map[string]string            `json:"tags,omitempty"`
+	Extra       map[string]string            `json:"extra,omitempty"`
+	Contexts    map[string]map[string]string `json:"contexts,omitempty"`
+}
+
+// Exceptions wraps the exceptions of an event
+type Exceptions struct {
+	Values []Exception `json:"values"`
+}
+
+// Exception is an error or panic, with where it was raised
+type Exception struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value"`
+	Stacktrace *Stacktrace `json:"stacktrace,omitempty"`
+}
+
+// Stacktrace lists frames oldest call first, as Sentry expects
+type Stacktrace struct {
+	Frames []Frame `json:"frames"`
+}
+
+// Frame is one function call of a stack trace
+type Frame struct {
+	Function string `json:"function"`
+	Module   string `json:"module,omitempty"`
+	Filename string `json:"filename"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+// CaptureError reports err, with extra describing what was being done.
+// skip is how many of the callers' frames to leave out of the stack trace.
+func (c *Client) CaptureError(err error, extra map[string]string, skip int) error {
+	typ := fmt.Sprintf("%T", err)
+	// Wrapping with fmt.Errorf hides the type that says what went wrong
+	for inner := errors.Unwrap(err); inner != nil; inner = errors.Unwrap(inner) {
+		typ = fmt.Sprintf("%T", inner)
+	}
+	return c.send(c.newEvent(LevelError, typ, err.Error(), extra, skip+1))
+}
+
+// CapturePanic reports a recovered panic value; call it from the deferred
+// function that recovered it, so the stack trace reaches the panic
+func (c *Client) CapturePanic(v interface{}, skip int) error {
+	return c.send(c.newEvent(LevelFatal, "panic", fmt.Sprint(v), nil, skip+1))
+}
+
+// newEvent builds an event for an exception raised skip frames above it
+func (c *Client) newEvent(level, typ, value string, extra map[string]string, skip int) Event {
+	return Event{
+		EventID:     newEventID(),
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		Level:       level,
+		Platform:    "go",
+		Logger:      "feedpulse",
+		Release:     c.opts.Release,
+		Environment: c.opts.Environment,
+		ServerName:  c.serverName,
+		Exception: Exceptions{Values: []Exception{{
+			Type:       typ,
+			Value:      value,
+			Stacktrace: stacktrace(skip + 1),
+		}}},
+		Tags:  c.opts.Tags,
+		Extra: extra,
+		Contexts: map[string]map[string]string{
+			"runtime": {"name": "go", "version": runtime.Version()},
+			"os":      {"name": runtime.GOOS},
+		},
+	}
+}
+
+// scrub masks secrets in what of event describes the error
+func (c *Client) scrub(event *Event) {
+	if c.opts.Scrub == nil {
+		return
+	}
+	for i := range event.Exception.Values {
+		event.Exception.Values[i].Value = c.opts.Scrub(event.Exception.Values[i].Value)
+	}
+	if event.Extra != nil {
+		extra := make(map[string]string, len(event.Extra))
+		for k, v := range event.Extra {
+			extra[k] = c.opts.Scrub(v)
+		}
+		event.Extra = extra
+	}
+}
+
+// send posts event unless the same exception was sent in the last
+// RepeatInterval
+func (c *Client) send(event Event) error {
+	c.scrub(&event)
+	exc := event.Exception.Values[0]
+	key := exc.Type + "\x00" + exc.Value
+	c.mu.Lock()
+	if last, ok := c.sent[key]; ok && time.Since(last) < RepeatInterval {
+		c.mu.Unlock()
+		return nil
+	}
+	c.sent[key] = time.Now()
+	c.mu.Unlock()
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.dsn.StoreURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", fmt.Sprintf("Sentry sentry_version=7, sentry_client=feedpulse/%s, sentry_key=%s",
+		c.opts.Release, c.dsn.PublicKey))
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send event: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to send event: HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// stacktrace returns the calling goroutine's stack, skip frames above
+// its caller
+func stacktrace(skip int) *Stacktrace {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(skip+2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var trace []Frame
+	for {
+		f, more := frames.Next()
+		module, function := splitFunction(f.Function)
+		trace = append(trace, Frame{
+			Function: function,
+			Module:   module,
+			Filename: shortFile(f.File),
+			AbsPath:  f.File,
+			Lineno:   f.Line,
+			InApp:    strings.HasPrefix(module, "feedpulse/") || module == "main",
+		})
+		if !more {
+			break
+		}
+	}
+
+	for i, j := 0, len(trace)-1; i < j; i, j = i+1, j-1 {
+		trace[i], trace[j] = trace[j], trace[i]
+	}
+	return &Stacktrace{Frames: trace}
+}
+
+// splitFunction splits "feedpulse/internal/cli.runFetch.func1" into its
+// package and function
+func splitFunction(name string) (string, string) {
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return "", name
+	}
+	return name[:slash+1+dot], name[slash+1+dot+1:]
+}
+
+// shortFile keeps the last two elements of a source path
+func shortFile(path string) string {
+	parts := strings.Split(path, "/")
+	if len(parts) > 2 {
+		parts = parts[len(parts)-2:]
+	}
+	return strings.Join(parts, "/")
+}
+
+// newEventID returns a random 32 hex digit event ID
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// current is the client the package-level functions report to
+var current atomic.Pointer[Client]
+
+// Init makes c the client CaptureError and Recover report to; nil turns
+// reporting off
+func Init(c *Client) {
+	current.Store(c)
+}
+
+// Enabled reports whether Init set a client
+func Enabled() bool {
+	return current.Load() != nil
+}
+
+// CaptureError reports err to the client set by Init, if any, printing a
+// warning when that fails
+func CaptureError(err error, extra map[string]string) {
+	c := current.Load()
+	if c == nil || err == nil {
+		return
+	}
+	if sendErr := c.CaptureError(err, extra, 1); sendErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to report error: %v\n", sendErr)
+	}
+}
+
+// Recover reports a panic to the client set by Init, then panics again
+// with the same value so the program fails as it would have. Defer it
+// directly, first thing in main and in every goroutine:
+//
+//	defer sentry.Recover()
+func Recover() {
+	v := recover()
+	if v == nil {
+		return
+	}
+	if c := current.Load(); c != nil {
+		if err := c.CapturePanic(v, 1); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to report panic: %v\n", err)
+		}
+	}
+	panic(v)
+}
@@ -0,0 +1,177 @@
+package sentry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseDSN(t *testing.T) {
+	tests := []struct {
+		dsn     string
+		want    string
+		wantErr bool
+	}{
+		{"https://abc123@o1.ingest.sentry.io/42", "https://o1.ingest.sentry.io/api/42/store/", false},
+		{"http://key@glitchtip.internal:8000/7", "http://glitchtip.internal:8000/api/7/store/", false},
+		{"https://key@example.com/sentry/3", "https://example.com/sentry/api/3/store/", false},
+		{"https://o1.ingest.sentry.io/42", "", true},
+		{"https://key@o1.ingest.sentry.io/", "", true},
+		{"ftp://key@example.com/1", "", true},
+	}
+
+	for _, tt := range tests {
+		dsn, err := ParseDSN(tt.dsn)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseDSN(%q) error = %v, wantErr %v", tt.dsn, err, tt.wantErr)
+			continue
+		}
+		if err == nil && dsn.StoreURL != tt.want {
+			t.Errorf("ParseDSN(%q).StoreURL = %q, want %q", tt.dsn, dsn.StoreURL, tt.want)
+		}
+	}
+}
+
+// collector is a fake Sentry keeping the events it receives
+type collector struct {
+	mu     sync.Mutex
+	events []Event
+	auth   []string
+}
+
+func newCollector(t *testing.T) (*collector, *Client) {
+	col := &collector{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/1/store/" {
+			http.NotFound(w, r)
+			return
+		}
+		var event Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		col.mu.Lock()
+		col.events = append(col.events, event)
+		col.auth = append(col.auth, r.Header.Get("X-Sentry-Auth"))
+		col.mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+
+	c, err := New(Options{
+		DSN:         strings.Replace(srv.URL, "://", "://pubkey@", 1) + "/1",
+		Release:     "1.2.3",
+		Environment: "test",
+		Tags:        map[string]string{"config": "abc123"},
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return col, c
+}
+
+func TestCaptureError(t *testing.T) {
+	col, c := newCollector(t)
+
+	err := fmt.Errorf("failed to save items: %w", os.ErrPermission)
+	if err := c.CaptureError(err, map[string]string{"operation": "save"}, 0); err != nil {
+		t.Fatalf("CaptureError failed: %v", err)
+	}
+	// The same error again within RepeatInterval is not sent
+	if err := c.CaptureError(err, nil, 0); err != nil {
+		t.Fatalf("CaptureError failed: %v", err)
+	}
+
+	if len(col.events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(col.events))
+	}
+	event := col.events[0]
+	if event.Level != LevelError || event.Release != "1.2.3" || event.Environment != "test" {
+		t.Errorf("unexpected event: %+v", event)
+	}
+	if event.Tags["config"] != "abc123" || event.Extra["operation"] != "save" {
+		t.Errorf("expected tags and extra, got %v and %v", event.Tags, event.Extra)
+	}
+	if len(event.EventID) != 32 {
+		t.Errorf("expected a 32 digit event ID, got %q", event.EventID)
+	}
+	if !strings.Contains(col.auth[0], "sentry_key=pubkey") {
+		t.Errorf("expected the public key in the auth header, got %q", col.auth[0])
+	}
+
+	exc := event.Exception.Values[0]
+	if exc.Type != "*errors.errorString" || exc.Value != err.Error() {
+		t.Errorf("expected the wrapped error's type and the full message, got %s: %s", exc.Type, exc.Value)
+	}
+	frames := exc.Stacktrace.Frames
+	if last := frames[len(frames)-1]; last.Function != "TestCaptureError" || !last.InApp {
+		t.Errorf("expected the stack trace to end in the caller, got %+v", last)
+	}
+}
+
+func TestCaptureError_Scrub(t *testing.T) {
+	col, c := newCollector(t)
+	c.opts.Scrub = func(s string) string { return strings.ReplaceAll(s, "s3cret", "REDACTED") }
+
+	err := fmt.Errorf("login failed for password s3cret")
+	if err := c.CaptureError(err, map[string]string{"url": "https://example.com/?password=s3cret"}, 0); err != nil {
+		t.Fatalf("CaptureError failed: %v", err)
+	}
+
+	event := col.events[0]
+	if event.Exception.Values[0].Value != "login failed for password REDACTED" || event.Extra["url"] != "https://example.com/?password=REDACTED" {
+		t.Errorf("expected the secret scrubbed, got %q and %v", event.Exception.Values[0].Value, event.Extra)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	col, c := newCollector(t)
+	Init(c)
+	defer Init(nil)
+
+	var repanicked interface{}
+	func() {
+		defer func() { repanicked = recover() }()
+		defer Recover()
+		panic("boom")
+	}()
+
+	if repanicked != "boom" {
+		t.Errorf("expected Recover to panic again with the same value, got %v", repanicked)
+	}
+	if len(col.events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(col.events))
+	}
+	exc := col.events[0].Exception.Values[0]
+	if col.events[0].Level != LevelFatal || exc.Type != "panic" || exc.Value != "boom" {
+		t.Errorf("unexpected panic event: %+v", col.events[0])
+	}
+	found := false
+	for _, f := range exc.Stacktrace.Frames {
+		if strings.HasPrefix(f.Function, "TestRecover") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected the stack trace to reach the panic, got %+v", exc.Stacktrace.Frames)
+	}
+}
+
+func TestCaptureError_Disabled(t *testing.T) {
+	Init(nil)
+	if Enabled() {
+		t.Fatal("expected reporting to be off")
+	}
+	// Without a client, reporting does nothing
+	CaptureError(fmt.Errorf("ignored"), nil)
+	func() {
+		defer func() { recover() }()
+		defer Recover()
+		panic("ignored")
+	}()
+}
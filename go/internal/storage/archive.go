@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Archive states. Entries go from pending to submitted (when the archive
+// captures asynchronously) to archived, or to failed once attempts run out.
+const (
+	ArchivePending   = "pending"
+	ArchiveSubmitted = "submitted"
+	ArchiveDone      = "archived"
+	ArchiveFailed    = "failed"
+)
+
+// ArchiveEntry is a URL queued for the Internet Archive, and what came of
+// it. URLs are archived once, whichever items share them.
+type ArchiveEntry struct {
+	URL         string    `json:"url"`
+	Source      string    `json:"source"`
+	Status      string    `json:"status"`
+	JobID       string    `json:"job_id,omitempty"`
+	ArchivedURL string    `json:"archived_url,omitempty"`
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"last_error,omitempty"`
+	RequestedAt time.Time `json:"requested_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// EnqueueArchive queues the URLs of items for archiving, returning how
+// many weren't queued before
+func (s *Storage) EnqueueArchive(items []FeedItem) (int, error) {
+	if len(items) == 0 {
+		return 0, nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := s.clock.Now().Format(time.RFC3339)
+	queued := 0
+	for _, item := range items {
+		if item.URL == "" {
+			continue
+		}
+		res, err := tx.Exec(`
+			INSERT INTO archives (url, source, status, attempts, requested_at, updated_at)
+			VALUES (?, ?, ?, 0, ?, ?)
+			ON CONFLICT DO NOTHING
+		`, item.URL, item.Source, ArchivePending, now, now)
+		if err != nil {
+			return 0, fmt.Errorf("failed to queue archive: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to queue archive: %w", err)
+		}
+		queued += int(n)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return queued, nil
+}
+
+// DueArchives returns up to limit entries still to submit or check on,
+// least recently touched first
+func (s *Storage) DueArchives(limit int) ([]ArchiveEntry, error) {
+	return s.queryArchives(`
+		WHERE status IN ('pending', 'submitted')
+		ORDER BY julianday(updated_at), url
+		LIMIT ?
+	`, noLimit(limit))
+}
+
+// RecentArchives returns up to limit entries, most recently updated first
+func (s *Storage) RecentArchives(limit int) ([]ArchiveEntry, error) {
+	return s.queryArchives(`
+		ORDER BY julianday(updated_at) DESC, url
+		LIMIT ?
+	`, noLimit(limit))
+}
+
+// GetArchive returns the entry for url, or nil if it was never queued
+func (s *Storage) GetArchive(url string) (*ArchiveEntry, error) {
+	entries, err := s.queryArchives("WHERE url = ?", url)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	return &entries[0], nil
+}
+
+// UpdateArchive stores the new state of entry
+func (s *Storage) UpdateArchive(entry ArchiveEntry) error {
+	res, err := s.db.Exec(`
+		UPDATE archives SET status = ?, job_id = ?, archived_url = ?, attempts = ?, last_error = ?, updated_at = ?
+		WHERE url = ?
+	`, entry.Status, nullString(entry.JobID), nullString(entry.ArchivedURL), entry.Attempts,
+		nullString(entry.LastError), s.clock.Now().Format(time.RFC3339), entry.URL)
+	if err != nil {
+		return fmt.Errorf("failed to update archive: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("archive not queued: %s", entry.URL)
+	}
+	return nil
+}
+
+// ArchiveCounts returns how many entries are in each state
+func (s *Storage) ArchiveCounts() (map[string]int, error) {
+	rows, err := s.db.Query("SELECT status, COUNT(*) FROM archives GROUP BY status")
+	if err != nil {
+		return nil, fmt.Errorf("failed to count archives: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, fmt.Errorf("failed to scan archive count: %w", err)
+		}
+		counts[status] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating archive counts: %w", err)
+	}
+	return counts, nil
+}
+
+// noLimit turns a limit of 0 (or less) into one no query reaches. SQLite
+// reads a negative LIMIT as none, but PostgreSQL rejects it.
+func noLimit(limit int) int {
+	if limit <= 0 {
+		return math.MaxInt
+	}
+	return limit
+}
+
+// queryArchives returns the archive entries selected by clause
+func (s *Storage) queryArchives(clause string, args ...interface{}) ([]ArchiveEntry, error) {
+	rows, err := s.db.Query(`
+		SELECT url, source, status, job_id, archived_url, attempts, last_error, requested_at, updated_at
+		FROM archives
+	`+clause, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query archives: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []ArchiveEntry
+	for rows.Next() {
+		var e ArchiveEntry
+		var jobID, archivedURL, lastError sql.NullString
+		var requestedAt, updatedAt string
+		if err := rows.Scan(&e.URL, &e.Source, &e.Status, &jobID, &archivedURL, &e.Attempts, &lastError, &requestedAt, &updatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan archive: %w", err)
+		}
+		e.JobID, e.ArchivedURL, e.LastError = jobID.String, archivedURL.String, lastError.String
+		if t, err := time.Parse(time.RFC3339, requestedAt); err == nil {
+			e.RequestedAt = t
+		}
+		if t, err := time.Parse(time.RFC3339, updatedAt); err == nil {
+			e.UpdatedAt = t
+		}
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating archives: %w", err)
+	}
+
+	return entries, nil
+}
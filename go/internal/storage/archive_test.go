@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"feedpulse/internal/clock"
+)
+
+func TestArchives_QueueAndUpdate(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	store.SetClock(clock.Fixed(now))
+
+	items := []FeedItem{
+		{ID: "1", URL: "https://example.com/a", Source: "HN"},
+		{ID: "2", URL: "https://example.com/b", Source: "HN"},
+		{ID: "3", URL: "https://example.com/a", Source: "Lobsters"},
+	}
+	queued, err := store.EnqueueArchive(items)
+	if err != nil || queued != 2 {
+		t.Fatalf("EnqueueArchive = %d, %v; want 2 URLs queued", queued, err)
+	}
+	if queued, _ := store.EnqueueArchive(items); queued != 0 {
+		t.Errorf("queueing again added %d", queued)
+	}
+
+	// Touching an entry moves it to the back of the due list
+	store.SetClock(clock.Fixed(now.Add(time.Minute)))
+	if err := store.UpdateArchive(ArchiveEntry{URL: "https://example.com/a", Status: ArchiveSubmitted, JobID: "job-1", Attempts: 1}); err != nil {
+		t.Fatalf("UpdateArchive failed: %v", err)
+	}
+	due, err := store.DueArchives(10)
+	if err != nil || len(due) != 2 || due[0].URL != "https://example.com/b" || due[1].JobID != "job-1" {
+		t.Fatalf("unexpected due archives: %+v (%v)", due, err)
+	}
+
+	if err := store.UpdateArchive(ArchiveEntry{URL: "https://example.com/a", Status: ArchiveDone, ArchivedURL: "https://web.archive.org/web/1/https://example.com/a", Attempts: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if due, _ := store.DueArchives(10); len(due) != 1 {
+		t.Errorf("expected the archived URL to leave the due list, got %+v", due)
+	}
+
+	got, err := store.GetArchive("https://example.com/a")
+	if err != nil || got == nil || got.Status != ArchiveDone || got.Source != "HN" || got.JobID != "" || !got.UpdatedAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("unexpected archive: %+v (%v)", got, err)
+	}
+	counts, err := store.ArchiveCounts()
+	if err != nil || counts[ArchiveDone] != 1 || counts[ArchivePending] != 1 {
+		t.Errorf("unexpected counts: %v (%v)", counts, err)
+	}
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Audited actions
+const (
+	AuditBlock        = "block"
+	AuditUnblock      = "unblock"
+	AuditFeedAdd      = "feed.add"
+	AuditFeedRemove   = "feed.remove"
+	AuditFeedEdit     = "feed.edit"
+	AuditPin          = "frontpage.pin"
+	AuditHide         = "frontpage.hide"
+	AuditUncurate     = "frontpage.reset"
+	AuditTokenCreate  = "token.create"
+	AuditTokenRevoke  = "token.revoke"
+	AuditFetchTrigger = "fetch.trigger"
+	AuditPrune        = "journal.prune"
+	AuditRecover      = "recover"
+	AuditRepair       = "db.repair"
+	AuditSave         = "item.save"
+	AuditItemsBatch   = "items.batch"
+	AuditConfigReload = "config.reload"
+)
+
+// AuditEntry records one mutating action
+type AuditEntry struct {
+	ID     int               `json:"id"`
+	At     time.Time         `json:"at"`
+	Actor  string            `json:"actor"`
+	Action string            `json:"action"`
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// AuditFilter selects audit entries; zero fields match everything
+type AuditFilter struct {
+	Actor  string
+	Action string
+	Since  time.Time
+	Limit  int
+}
+
+// RecordAudit logs that actor (e.g. "cli:alice" or "token:2894c91a")
+// performed action with params
+func (s *Storage) RecordAudit(actor, action string, params map[string]string) error {
+	var encoded *string
+	if len(params) > 0 {
+		b, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("failed to encode audit params: %w", err)
+		}
+		v := string(b)
+		encoded = &v
+	}
+
+	_, err := s.db.Exec(`
+		INSERT INTO audit_log (at, actor, action, params) VALUES (?, ?, ?, ?)
+	`, s.clock.Now().Format(time.RFC3339), actor, action, encoded)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return nil
+}
+
+// ListAudit returns the audit entries matching filter, newest first. An
+// Action ending in "." matches every action under it (e.g. "token.").
+func (s *Storage) ListAudit(filter AuditFilter) ([]AuditEntry, error) {
+	query := "SELECT id, at, actor, action, params FROM audit_log WHERE 1 = 1"
+	var args []interface{}
+	if filter.Actor != "" {
+		query += " AND actor = ?"
+		args = append(args, filter.Actor)
+	}
+	if strings.HasSuffix(filter.Action, ".") {
+		query += " AND substr(action, 1, ?) = ?"
+		args = append(args, len(filter.Action), filter.Action)
+	} else if filter.Action != "" {
+		query += " AND action = ?"
+		args = append(args, filter.Action)
+	}
+	if !filter.Since.IsZero() {
+		query += " AND julianday(at) >= julianday(?)"
+		args = append(args, filter.Since.UTC().Format(time.RFC3339))
+	}
+	query += " ORDER BY id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		var at string
+		var params *string
+		if err := rows.Scan(&e.ID, &at, &e.Actor, &e.Action, &params); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		if t, err := time.Parse(time.RFC3339, at); err == nil {
+			e.At = t
+		}
+		if params != nil {
+			if err := json.Unmarshal([]byte(*params), &e.Params); err != nil {
+				return nil, fmt.Errorf("invalid params for audit entry %d: %w", e.ID, err)
+			}
+		}
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit log: %w", err)
+	}
+
+	return entries, nil
+}
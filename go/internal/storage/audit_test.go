@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"feedpulse/internal/clock"
+)
+
+func TestAudit_RecordAndFilter(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	store.SetClock(clock.Fixed(now.Add(-2 * time.Hour)))
+	if err := store.RecordAudit("cli:alice", AuditBlock, map[string]string{"value": "spam.example"}); err != nil {
+		t.Fatalf("RecordAudit failed: %v", err)
+	}
+	store.SetClock(clock.Fixed(now))
+	if err := store.RecordAudit("token:ops", AuditTokenCreate, map[string]string{"id": "ab12", "scope": "read"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RecordAudit("token:ops", AuditTokenRevoke, map[string]string{"id": "ab12"}); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := store.ListAudit(AuditFilter{})
+	if err != nil {
+		t.Fatalf("ListAudit failed: %v", err)
+	}
+	if len(all) != 3 || all[0].Action != AuditTokenRevoke || all[2].Action != AuditBlock {
+		t.Fatalf("expected newest first, got %+v", all)
+	}
+	if !reflect.DeepEqual(all[2].Params, map[string]string{"value": "spam.example"}) || !all[2].At.Equal(now.Add(-2*time.Hour)) {
+		t.Errorf("unexpected entry: %+v", all[2])
+	}
+
+	tests := []struct {
+		name   string
+		filter AuditFilter
+		want   []string
+	}{
+		{"actor", AuditFilter{Actor: "cli:alice"}, []string{AuditBlock}},
+		{"action", AuditFilter{Action: AuditTokenCreate}, []string{AuditTokenCreate}},
+		{"action prefix", AuditFilter{Action: "token."}, []string{AuditTokenRevoke, AuditTokenCreate}},
+		{"since", AuditFilter{Since: now.Add(-time.Hour)}, []string{AuditTokenRevoke, AuditTokenCreate}},
+		{"limit", AuditFilter{Limit: 1}, []string{AuditTokenRevoke}},
+	}
+	for _, tt := range tests {
+		entries, err := store.ListAudit(tt.filter)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got []string
+		for _, e := range entries {
+			got = append(got, e.Action)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
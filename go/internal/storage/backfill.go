@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// BackfillCursor records how far a feed's history has been walked
+type BackfillCursor struct {
+	Feed      string
+	Page      int
+	Cursor    string
+	Done      bool
+	UpdatedAt time.Time
+}
+
+// GetBackfillCursor returns the stored cursor for feed, or nil if the
+// feed has never been backfilled
+func (s *Storage) GetBackfillCursor(feed string) (*BackfillCursor, error) {
+	c := BackfillCursor{Feed: feed}
+	var updatedAt string
+
+	err := s.db.QueryRow(`
+		SELECT page, cursor, done, updated_at FROM backfill_cursors WHERE feed = ?
+	`, feed).Scan(&c.Page, &c.Cursor, &c.Done, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backfill cursor: %w", err)
+	}
+
+	if t, err := time.Parse(time.RFC3339, updatedAt); err == nil {
+		c.UpdatedAt = t
+	}
+
+	return &c, nil
+}
+
+// ResetBackfillCursor forgets a feed's backfill progress
+func (s *Storage) ResetBackfillCursor(feed string) error {
+	if _, err := s.db.Exec("DELETE FROM backfill_cursors WHERE feed = ?", feed); err != nil {
+		return fmt.Errorf("failed to reset backfill cursor: %w", err)
+	}
+	return nil
+}
+
+// SaveBackfillPage stores one page of backfilled items, its fetch log
+// entry and the cursor for the next page in a single transaction, so an
+// interrupted backfill resumes exactly after the last saved page.
+func (s *Storage) SaveBackfillPage(log FetchLog, items []FeedItem, next BackfillCursor) (SaveResult, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return SaveResult{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	inserted, blocked, err := s.saveItemsTx(tx, items)
+	if err != nil {
+		return SaveResult{}, err
+	}
+
+	if _, err := s.logFetchTx(tx, log); err != nil {
+		return SaveResult{}, err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO backfill_cursors (feed, page, cursor, done, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(feed) DO UPDATE SET
+			page = excluded.page,
+			cursor = excluded.cursor,
+			done = excluded.done,
+			updated_at = excluded.updated_at
+	`, next.Feed, next.Page, next.Cursor, next.Done, s.clock.Now().Format(time.RFC3339))
+	if err != nil {
+		return SaveResult{}, fmt.Errorf("failed to save backfill cursor: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return SaveResult{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return newSaveResult(len(items), inserted, blocked), nil
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackfillCursor_SaveAndResume(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	cursor, err := store.GetBackfillCursor("Reddit")
+	if err != nil {
+		t.Fatalf("GetBackfillCursor failed: %v", err)
+	}
+	if cursor != nil {
+		t.Fatalf("expected no cursor for a fresh feed, got %+v", cursor)
+	}
+
+	now := time.Now()
+	result, err := store.SaveBackfillPage(
+		FetchLog{Source: "Reddit", FetchedAt: now, Status: "success", ItemsCount: 1},
+		[]FeedItem{{ID: "1", Title: "T", URL: "u1", Source: "Reddit", CreatedAt: now}},
+		BackfillCursor{Feed: "Reddit", Page: 2, Cursor: "t3_abc"},
+	)
+	if err != nil {
+		t.Fatalf("SaveBackfillPage failed: %v", err)
+	}
+	if result.Inserted != 1 {
+		t.Errorf("expected 1 inserted, got %d", result.Inserted)
+	}
+
+	cursor, err = store.GetBackfillCursor("Reddit")
+	if err != nil {
+		t.Fatalf("GetBackfillCursor failed: %v", err)
+	}
+	if cursor == nil || cursor.Page != 2 || cursor.Cursor != "t3_abc" || cursor.Done {
+		t.Errorf("unexpected cursor: %+v", cursor)
+	}
+
+	if err := store.ResetBackfillCursor("Reddit"); err != nil {
+		t.Fatalf("ResetBackfillCursor failed: %v", err)
+	}
+	if cursor, _ := store.GetBackfillCursor("Reddit"); cursor != nil {
+		t.Errorf("expected cursor to be reset, got %+v", cursor)
+	}
+}
@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Changes ApplyItemBatch can make
+const (
+	BatchMarkRead = "mark_read"
+	BatchStar     = "star"
+	BatchTag      = "tag"
+	BatchDelete   = "delete"
+)
+
+// BatchActions lists the valid ItemBatch.Action values
+var BatchActions = []string{BatchMarkRead, BatchStar, BatchTag, BatchDelete}
+
+// ItemBatch is a change made to every item Filter selects by source, tag
+// and when they were stored; its paging and sorting fields are ignored
+type ItemBatch struct {
+	Action string
+	// Tag is the tag BatchTag adds. It is kept when the item is fetched
+	// again.
+	Tag    string
+	Filter ItemFilter
+}
+
+// Validate checks that batch names a known action and has what it needs
+func (b ItemBatch) Validate() error {
+	switch b.Action {
+	case BatchMarkRead, BatchStar, BatchDelete:
+	case BatchTag:
+		if strings.TrimSpace(b.Tag) == "" {
+			return fmt.Errorf("the %s action needs a tag", BatchTag)
+		}
+	default:
+		return fmt.Errorf("unknown batch action: %s (must be one of: %s)", b.Action, strings.Join(BatchActions, ", "))
+	}
+	return nil
+}
+
+// ApplyItemBatch makes batch's change in one transaction and returns how
+// many items it changed: items already read, starred or tagged don't
+// count
+func (s *Storage) ApplyItemBatch(batch ItemBatch) (int, error) {
+	if err := batch.Validate(); err != nil {
+		return 0, err
+	}
+	where, args := itemConditions(batch.Filter)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var changed int
+	switch batch.Action {
+	case BatchMarkRead:
+		changed, err = execCount(tx, "UPDATE feed_items SET read_at = ? WHERE read_at IS NULL AND "+where, append([]interface{}{s.clock.Now().UTC().Format(time.RFC3339)}, args...)...)
+	case BatchStar:
+		changed, err = execCount(tx, "UPDATE feed_items SET starred = 1 WHERE starred = 0 AND "+where, args...)
+	case BatchTag:
+		changed, err = tagItems(tx, strings.TrimSpace(batch.Tag), where, args)
+	case BatchDelete:
+		changed, err = deleteItems(tx, where, args, s.clock.Now())
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if batch.Action == BatchDelete && changed > 0 {
+		if err := s.RebuildSourceStats(); err != nil {
+			return changed, err
+		}
+	}
+	return changed, nil
+}
+
+// execCount runs a statement and returns how many rows it affected
+func execCount(tx *sql.Tx, query string, args ...interface{}) (int, error) {
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update items: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count updated items: %w", err)
+	}
+	return int(n), nil
+}
+
+// tagItems adds tag to the items matching where, to both their tags and
+// the user tags that are merged back in when they are fetched again
+func tagItems(tx *sql.Tx, tag, where string, args []interface{}) (int, error) {
+	rows, err := tx.Query("SELECT id, tags, user_tags FROM feed_items WHERE "+where, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query items: %w", err)
+	}
+	type tagged struct {
+		id             string
+		tags, userTags []string
+	}
+	var updates []tagged
+	for rows.Next() {
+		var id string
+		var tags, userTags *string
+		if err := rows.Scan(&id, &tags, &userTags); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan item: %w", err)
+		}
+		var t tagged
+		t.id = id
+		for _, field := range []struct {
+			raw *string
+			out *[]string
+		}{{tags, &t.tags}, {userTags, &t.userTags}} {
+			if field.raw == nil {
+				continue
+			}
+			if err := json.Unmarshal([]byte(*field.raw), field.out); err != nil {
+				rows.Close()
+				return 0, fmt.Errorf("invalid tags for item %s: %w", id, err)
+			}
+		}
+		if hasTag(t.tags, tag) {
+			continue
+		}
+		t.tags = append(t.tags, tag)
+		t.userTags = mergeTags(t.userTags, []string{tag})
+		updates = append(updates, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating items: %w", err)
+	}
+
+	for _, t := range updates {
+		tags, _ := json.Marshal(t.tags)
+		userTags, _ := json.Marshal(t.userTags)
+		if _, err := tx.Exec("UPDATE feed_items SET tags = ?, user_tags = ? WHERE id = ?", string(tags), string(userTags), t.id); err != nil {
+			return 0, fmt.Errorf("failed to tag item: %w", err)
+		}
+	}
+	return len(updates), nil
+}
+
+// deleteItems deletes the items matching where along with their history,
+// runs and curation, unlinking duplicates of them. Their IDs are kept in
+// deleted_items so that fetching them again doesn't bring them back.
+func deleteItems(tx *sql.Tx, where string, args []interface{}, now time.Time) (int, error) {
+	_, err := tx.Exec(`
+		INSERT INTO deleted_items (item_id, deleted_at)
+		SELECT id, ? FROM feed_items WHERE `+where+`
+		ON CONFLICT(item_id) DO NOTHING
+	`, append([]interface{}{now.UTC().Format(time.RFC3339)}, args...)...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record deleted items: %w", err)
+	}
+	for _, table := range []string{"item_revisions", "run_items", "curations"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE item_id IN (SELECT id FROM feed_items WHERE "+where+")", args...); err != nil {
+			return 0, fmt.Errorf("failed to remove item records from %s: %w", table, err)
+		}
+	}
+	res, err := tx.Exec("DELETE FROM feed_items WHERE "+where, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete items: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count deleted items: %w", err)
+	}
+	if err := unlinkOrphans(tx); err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// itemDeleted reports whether the item id was deleted by ApplyItemBatch
+func itemDeleted(tx *sql.Tx, id string) (bool, error) {
+	var one int
+	switch err := tx.QueryRow("SELECT 1 FROM deleted_items WHERE item_id = ?", id).Scan(&one); err {
+	case nil:
+		return true, nil
+	case sql.ErrNoRows:
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to check deleted items: %w", err)
+	}
+}
+
+// hasTag reports whether tags has tag, ignoring case like tag filters
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeTags returns tags followed by those of extra it lacks
+func mergeTags(tags, extra []string) []string {
+	merged := append([]string(nil), tags...)
+	for _, tag := range extra {
+		if !hasTag(merged, tag) {
+			merged = append(merged, tag)
+		}
+	}
+	return merged
+}
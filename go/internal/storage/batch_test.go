@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyItemBatch(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	items := []FeedItem{
+		{ID: "1", Title: "Old", URL: "https://example.com/1", Source: "HN", Tags: []string{"go"}, CreatedAt: now.Add(-48 * time.Hour)},
+		{ID: "2", Title: "New", URL: "https://example.com/2", Source: "HN", CreatedAt: now},
+		{ID: "3", Title: "Other", URL: "https://example.com/3", Source: "Lobsters", Tags: []string{"Go"}, CreatedAt: now},
+	}
+	if err := store.SaveItems(items); err != nil {
+		t.Fatalf("SaveItems failed: %v", err)
+	}
+
+	apply := func(batch ItemBatch) int {
+		t.Helper()
+		n, err := store.ApplyItemBatch(batch)
+		if err != nil {
+			t.Fatalf("ApplyItemBatch(%s) failed: %v", batch.Action, err)
+		}
+		return n
+	}
+	get := func(id string) FeedItem {
+		t.Helper()
+		all, err := store.GetItems(ItemFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range all {
+			if item.ID == id {
+				return item
+			}
+		}
+		t.Fatalf("item %s not found", id)
+		return FeedItem{}
+	}
+
+	if n := apply(ItemBatch{Action: BatchMarkRead, Filter: ItemFilter{Source: "HN"}}); n != 2 {
+		t.Errorf("mark_read changed %d items, want 2", n)
+	}
+	if n := apply(ItemBatch{Action: BatchMarkRead, Filter: ItemFilter{Source: "HN"}}); n != 0 {
+		t.Errorf("marking read items again changed %d, want 0", n)
+	}
+	if get("1").ReadAt == nil || get("3").ReadAt != nil {
+		t.Error("expected only HN's items read")
+	}
+
+	if n := apply(ItemBatch{Action: BatchStar, Filter: ItemFilter{Tag: "GO"}}); n != 2 {
+		t.Errorf("star changed %d items, want 2", n)
+	}
+	if !get("1").Starred || get("2").Starred {
+		t.Error("expected only the go items starred")
+	}
+
+	if n := apply(ItemBatch{Action: BatchTag, Tag: "later", Filter: ItemFilter{Until: now.Add(-time.Hour)}}); n != 1 {
+		t.Errorf("tag changed %d items, want 1", n)
+	}
+	// A tag added by hand survives the item being fetched again
+	if err := store.SaveItems(items[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if tags := strings.Join(get("1").Tags, ","); tags != "go,later" {
+		t.Errorf("expected the added tag kept, got %s", tags)
+	}
+	if !get("1").Starred || get("1").ReadAt == nil {
+		t.Error("expected the item to stay starred and read after a refetch")
+	}
+
+	if n := apply(ItemBatch{Action: BatchDelete, Filter: ItemFilter{Source: "Lobsters"}}); n != 1 {
+		t.Errorf("delete changed %d items, want 1", n)
+	}
+	if count, _ := store.GetAllItemsCount(); count != 2 {
+		t.Errorf("expected 2 items left, got %d", count)
+	}
+	stats, err := store.GetFetchStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, st := range stats {
+		if st.Source == "Lobsters" && st.ItemsCount != 0 {
+			t.Errorf("expected Lobsters' stats updated, got %d items", st.ItemsCount)
+		}
+	}
+	// A deleted item stays deleted when it is fetched again
+	result, err := store.SaveFetchResult(FetchLog{Source: "Lobsters", FetchedAt: now, Status: "success", ItemsCount: 1}, items[2:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Inserted != 0 || result.Blocked != 1 {
+		t.Errorf("expected the deleted item skipped, got %+v", result)
+	}
+	if count, _ := store.GetAllItemsCount(); count != 2 {
+		t.Errorf("expected the deleted item not restored, got %d items", count)
+	}
+
+	if _, err := store.ApplyItemBatch(ItemBatch{Action: BatchTag}); err == nil {
+		t.Error("expected an error for a tag action without a tag")
+	}
+	if _, err := store.ApplyItemBatch(ItemBatch{Action: "archive"}); err == nil {
+		t.Error("expected an error for an unknown action")
+	}
+}
@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Blocklist entry kinds
+const (
+	BlockID      = "id"
+	BlockURL     = "url"
+	BlockPattern = "pattern"
+)
+
+// BlockEntry represents an item ID, URL or URL pattern that must never be stored
+type BlockEntry struct {
+	Value     string
+	Kind      string
+	CreatedAt time.Time
+}
+
+// BlockKind infers the kind of a blocklist value: anything containing '*'
+// is a URL pattern, anything with a scheme is a URL, the rest are item IDs
+func BlockKind(value string) string {
+	switch {
+	case strings.Contains(value, "*"):
+		return BlockPattern
+	case strings.Contains(value, "://"):
+		return BlockURL
+	default:
+		return BlockID
+	}
+}
+
+// Block adds value to the blocklist and deletes any stored items it
+// matches. It returns the number of items removed.
+func (s *Storage) Block(value string) (int, error) {
+	kind := BlockKind(value)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO blocklist (value, kind, created_at) VALUES (?, ?, ?)
+		ON CONFLICT(value) DO NOTHING
+	`, value, kind, s.clock.Now().Format(time.RFC3339))
+	if err != nil {
+		return 0, fmt.Errorf("failed to add to blocklist: %w", err)
+	}
+
+	var res sql.Result
+	switch kind {
+	case BlockID:
+		res, err = tx.Exec("DELETE FROM feed_items WHERE id = ?", value)
+	case BlockURL:
+		res, err = tx.Exec("DELETE FROM feed_items WHERE url = ?", value)
+	case BlockPattern:
+		if s.postgres {
+			res, err = tx.Exec(`DELETE FROM feed_items WHERE url LIKE ? ESCAPE '\'`, likePattern(value))
+		} else {
+			res, err = tx.Exec("DELETE FROM feed_items WHERE url GLOB ?", sqliteGlob(value))
+		}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove blocked items: %w", err)
+	}
+
+	removed, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count removed items: %w", err)
+	}
+	if err := unlinkOrphans(tx); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if removed > 0 {
+		if err := s.RebuildSourceStats(); err != nil {
+			return int(removed), err
+		}
+	}
+
+	return int(removed), nil
+}
+
+// Unblock removes value from the blocklist. Items deleted when it was
+// blocked are not restored; they return on the next fetch.
+func (s *Storage) Unblock(value string) (bool, error) {
+	res, err := s.db.Exec("DELETE FROM blocklist WHERE value = ?", value)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove from blocklist: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove from blocklist: %w", err)
+	}
+
+	return n > 0, nil
+}
+
+// ListBlocked returns all blocklist entries, oldest first
+func (s *Storage) ListBlocked() ([]BlockEntry, error) {
+	rows, err := s.db.Query("SELECT value, kind, created_at FROM blocklist ORDER BY created_at, value")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query blocklist: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []BlockEntry
+	for rows.Next() {
+		var entry BlockEntry
+		var createdAt string
+		if err := rows.Scan(&entry.Value, &entry.Kind, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan blocklist entry: %w", err)
+		}
+		if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
+			entry.CreatedAt = t
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating blocklist: %w", err)
+	}
+
+	return entries, nil
+}
+
+// blocklist is an in-memory snapshot of the blocklist table
+type blocklist struct {
+	ids      map[string]bool
+	urls     map[string]bool
+	patterns []*regexp.Regexp
+}
+
+// loadBlocklist reads the blocklist within tx
+func loadBlocklist(tx *sql.Tx) (*blocklist, error) {
+	rows, err := tx.Query("SELECT value, kind FROM blocklist")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load blocklist: %w", err)
+	}
+	defer rows.Close()
+
+	bl := &blocklist{ids: make(map[string]bool), urls: make(map[string]bool)}
+	for rows.Next() {
+		var value, kind string
+		if err := rows.Scan(&value, &kind); err != nil {
+			return nil, fmt.Errorf("failed to scan blocklist entry: %w", err)
+		}
+
+		switch kind {
+		case BlockID:
+			bl.ids[value] = true
+		case BlockURL:
+			bl.urls[value] = true
+		case BlockPattern:
+			bl.patterns = append(bl.patterns, globToRegexp(value))
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating blocklist: %w", err)
+	}
+
+	return bl, nil
+}
+
+// blocked reports whether item matches any blocklist entry
+func (bl *blocklist) blocked(item FeedItem) bool {
+	if bl.ids[item.ID] || bl.urls[item.URL] {
+		return true
+	}
+	for _, re := range bl.patterns {
+		if re.MatchString(item.URL) {
+			return true
+		}
+	}
+	return false
+}
+
+// sqliteGlob escapes GLOB metacharacters other than '*' so patterns mean
+// the same thing in SQL as they do to globToRegexp
+func sqliteGlob(pattern string) string {
+	return strings.NewReplacer("[", "[[]", "?", "[?]").Replace(pattern)
+}
+
+// likePattern turns a '*' wildcard pattern into a LIKE pattern escaped
+// with '\', for databases without GLOB
+func likePattern(pattern string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(pattern)
+	return strings.ReplaceAll(escaped, "*", "%")
+}
+
+// globToRegexp compiles a '*' wildcard pattern for matching incoming items
+func globToRegexp(pattern string) *regexp.Regexp {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBlockKind(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"abc123", BlockID},
+		{"https://example.com/a", BlockURL},
+		{"https://spam.example.com/*", BlockPattern},
+	}
+
+	for _, tt := range tests {
+		if got := BlockKind(tt.value); got != tt.want {
+			t.Errorf("BlockKind(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestBlock_RemovesAndRejectsItems(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	items := []FeedItem{
+		{ID: "keep", Title: "Keep", URL: "https://good.example.com/1", Source: "S", CreatedAt: now},
+		{ID: "by-id", Title: "By ID", URL: "https://good.example.com/2", Source: "S", CreatedAt: now},
+		{ID: "by-url", Title: "By URL", URL: "https://good.example.com/3", Source: "S", CreatedAt: now},
+		{ID: "by-pattern", Title: "By pattern", URL: "https://spam.example.com/x?y=[1]", Source: "S", CreatedAt: now},
+	}
+	store.SaveItems(items)
+
+	for _, value := range []string{"by-id", "https://good.example.com/3", "https://spam.example.com/*"} {
+		removed, err := store.Block(value)
+		if err != nil {
+			t.Fatalf("Block(%q) failed: %v", value, err)
+		}
+		if removed != 1 {
+			t.Errorf("Block(%q) removed %d items, want 1", value, removed)
+		}
+	}
+
+	result, err := store.SaveFetchResult(FetchLog{Source: "S", FetchedAt: now, Status: "success"}, items)
+	if err != nil {
+		t.Fatalf("SaveFetchResult failed: %v", err)
+	}
+	if result.Blocked != 3 || result.Inserted != 0 || result.Updated != 1 {
+		t.Errorf("unexpected save result: %+v", result)
+	}
+
+	count, _ := store.GetItemCount("S")
+	if count != 1 {
+		t.Errorf("expected only the unblocked item stored, got %d", count)
+	}
+
+	entries, err := store.ListBlocked()
+	if err != nil {
+		t.Fatalf("ListBlocked failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("expected 3 blocklist entries, got %d", len(entries))
+	}
+}
+
+func TestUnblock(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	store.Block("blocked-id")
+
+	found, err := store.Unblock("blocked-id")
+	if err != nil || !found {
+		t.Fatalf("Unblock() = %v, %v; want true, nil", found, err)
+	}
+
+	found, _ = store.Unblock("blocked-id")
+	if found {
+		t.Error("expected second Unblock to report not found")
+	}
+
+	store.SaveItems([]FeedItem{{ID: "blocked-id", Title: "T", URL: "u", Source: "S", CreatedAt: time.Now()}})
+	if count, _ := store.GetItemCount("S"); count != 1 {
+		t.Errorf("expected unblocked item to be stored, got %d", count)
+	}
+}
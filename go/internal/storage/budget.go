@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// host_requests counts the requests sent to each budgeted API host per
+// minute, for the last day, so usage over a rolling hour or day is a sum
+// of at most a day's worth of rows
+
+// HostUsage is how many requests were sent to a host recently
+type HostUsage struct {
+	Host     string `json:"host"`
+	LastHour int    `json:"last_hour"`
+	LastDay  int    `json:"last_day"`
+}
+
+// RecordHostRequest counts one request to host, sent at at, and forgets
+// the host's requests older than a day
+func (s *Storage) RecordHostRequest(host string, at time.Time) error {
+	minute := at.UTC().Truncate(time.Minute)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO host_requests (host, minute, requests) VALUES (?, ?, 1)
+		ON CONFLICT(host, minute) DO UPDATE SET requests = host_requests.requests + 1
+	`, host, minute.Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("failed to record request: %w", err)
+	}
+	_, err = tx.Exec("DELETE FROM host_requests WHERE host = ? AND minute < ?",
+		host, minute.Add(-24*time.Hour).Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("failed to prune requests: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// HostUsage returns how many requests were sent to host in the hour and
+// the day before now, counted in whole minutes
+func (s *Storage) HostUsage(host string, now time.Time) (HostUsage, error) {
+	minute := now.UTC().Truncate(time.Minute)
+	usage := HostUsage{Host: host}
+	err := s.db.QueryRow(`
+		SELECT
+			COALESCE(SUM(CASE WHEN minute > ? THEN requests END), 0),
+			COALESCE(SUM(requests), 0)
+		FROM host_requests
+		WHERE host = ? AND minute > ?
+	`, minute.Add(-time.Hour).Format(time.RFC3339), host, minute.Add(-24*time.Hour).Format(time.RFC3339)).Scan(&usage.LastHour, &usage.LastDay)
+	if err != nil {
+		return HostUsage{}, fmt.Errorf("failed to query request usage: %w", err)
+	}
+	return usage, nil
+}
+
+// ReserveHostRequests counts n requests to host at at, unless that would
+// take the host past perHour requests in the last hour or perDay in the
+// last day (0 is no limit), and reports whether it did. The check and the
+// count are one statement, so fetches reserving in parallel can't
+// overspend the budget between them.
+func (s *Storage) ReserveHostRequests(host string, n, perHour, perDay int, at time.Time) (bool, error) {
+	minute := at.UTC().Truncate(time.Minute)
+	res, err := s.db.Exec(`
+		INSERT INTO host_requests (host, minute, requests)
+		SELECT ?, ?, CAST(? AS INTEGER)
+		WHERE (? = 0 OR (SELECT COALESCE(SUM(requests), 0) FROM host_requests WHERE host = ? AND minute > ?) + ? <= ?)
+			AND (? = 0 OR (SELECT COALESCE(SUM(requests), 0) FROM host_requests WHERE host = ? AND minute > ?) + ? <= ?)
+		ON CONFLICT(host, minute) DO UPDATE SET requests = host_requests.requests + excluded.requests
+	`, host, minute.Format(time.RFC3339), n,
+		perHour, host, minute.Add(-time.Hour).Format(time.RFC3339), n, perHour,
+		perDay, host, minute.Add(-24*time.Hour).Format(time.RFC3339), n, perDay)
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve requests: %w", err)
+	}
+	reserved, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve requests: %w", err)
+	}
+	return reserved > 0, nil
+}
+
+// ReleaseHostRequests gives back n requests to host reserved at at but
+// not sent
+func (s *Storage) ReleaseHostRequests(host string, n int, at time.Time) error {
+	_, err := s.db.Exec(`
+		UPDATE host_requests SET requests = CASE WHEN requests > ? THEN requests - ? ELSE 0 END
+		WHERE host = ? AND minute = ?
+	`, n, n, host, at.UTC().Truncate(time.Minute).Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("failed to release requests: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHostUsage(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Date(2026, 3, 2, 12, 30, 15, 0, time.UTC)
+	for _, at := range []time.Time{
+		now.Add(-30 * time.Hour),
+		now.Add(-2 * time.Hour),
+		now.Add(-59 * time.Minute),
+		now.Add(-time.Minute),
+		now,
+		now,
+	} {
+		if err := store.RecordHostRequest("api.github.com", at); err != nil {
+			t.Fatalf("RecordHostRequest failed: %v", err)
+		}
+	}
+	if err := store.RecordHostRequest("api.example.com", now); err != nil {
+		t.Fatalf("RecordHostRequest failed: %v", err)
+	}
+
+	usage, err := store.HostUsage("api.github.com", now)
+	if err != nil {
+		t.Fatalf("HostUsage failed: %v", err)
+	}
+	want := HostUsage{Host: "api.github.com", LastHour: 4, LastDay: 5}
+	if usage != want {
+		t.Errorf("got %+v, want %+v", usage, want)
+	}
+
+	// Requests older than a day are pruned, not just left uncounted
+	var rows int
+	if err := store.db.QueryRow("SELECT COUNT(*) FROM host_requests WHERE host = 'api.github.com'").Scan(&rows); err != nil {
+		t.Fatalf("failed to count rows: %v", err)
+	}
+	if rows != 4 {
+		t.Errorf("expected 4 minute rows after pruning, got %d", rows)
+	}
+
+	// Half an hour later, the requests from before noon leave the hour
+	later, err := store.HostUsage("api.github.com", now.Add(30*time.Minute))
+	if err != nil {
+		t.Fatalf("HostUsage failed: %v", err)
+	}
+	if later.LastHour != 3 || later.LastDay != 5 {
+		t.Errorf("expected 3 in the last hour and 5 in the day, got %+v", later)
+	}
+}
+
+func TestReserveHostRequests(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Date(2026, 3, 2, 12, 30, 15, 0, time.UTC)
+	if err := store.RecordHostRequest("api.github.com", now.Add(-2*time.Hour)); err != nil {
+		t.Fatalf("RecordHostRequest failed: %v", err)
+	}
+
+	tests := []struct {
+		n, perHour, perDay int
+		want               bool
+	}{
+		{3, 5, 10, true},
+		{3, 5, 10, false}, // 6 in the hour
+		{2, 5, 10, true},
+		{5, 0, 10, false}, // 11 in the day
+		{4, 0, 10, true},
+		{1, 0, 0, true},
+	}
+	for i, tt := range tests {
+		got, err := store.ReserveHostRequests("api.github.com", tt.n, tt.perHour, tt.perDay, now)
+		if err != nil {
+			t.Fatalf("ReserveHostRequests failed: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("reservation %d of %d: got %v, want %v", i, tt.n, got, tt.want)
+		}
+	}
+
+	if err := store.ReleaseHostRequests("api.github.com", 3, now); err != nil {
+		t.Fatalf("ReleaseHostRequests failed: %v", err)
+	}
+	usage, err := store.HostUsage("api.github.com", now)
+	if err != nil {
+		t.Fatalf("HostUsage failed: %v", err)
+	}
+	if usage.LastHour != 7 || usage.LastDay != 8 {
+		t.Errorf("expected 7 in the hour and 8 in the day after the release, got %+v", usage)
+	}
+}
+
+func TestReserveHostRequests_Concurrent(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	reserved := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := store.ReserveHostRequests("api.github.com", 1, 5, 0, now)
+			if err != nil {
+				t.Errorf("ReserveHostRequests failed: %v", err)
+				return
+			}
+			if ok {
+				mu.Lock()
+				reserved++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if reserved != 5 {
+		t.Errorf("expected exactly the budget of 5 reserved, got %d", reserved)
+	}
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Cookie is a persisted cookie together with the URL that set it, which
+// is needed to load it back into a cookie jar
+type Cookie struct {
+	URL      string    `json:"url"`
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain,omitempty"`
+	Path     string    `json:"path,omitempty"`
+	Expires  time.Time `json:"expires,omitempty"`
+	Secure   bool      `json:"secure,omitempty"`
+	HttpOnly bool      `json:"http_only,omitempty"`
+}
+
+// SetCookieKey sets the secret cookie jars are encrypted with. Jars can
+// only be read back with the same secret.
+func (s *Storage) SetCookieKey(secret string) {
+	if secret == "" {
+		s.cookieKey = nil
+		return
+	}
+	key := sha256.Sum256([]byte(secret))
+	s.cookieKey = key[:]
+}
+
+// SaveCookies encrypts and stores feed's cookie jar, replacing any
+// previous jar
+func (s *Storage) SaveCookies(feed string, cookies []Cookie) error {
+	plain, err := json.Marshal(cookies)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cookies: %w", err)
+	}
+
+	sealed, err := s.sealCookies(plain)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO cookie_jars (feed, sealed, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(feed) DO UPDATE SET
+			sealed = excluded.sealed,
+			updated_at = excluded.updated_at
+	`, feed, sealed, s.clock.Now().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("failed to save cookies: %w", err)
+	}
+
+	return nil
+}
+
+// LoadCookies decrypts and returns feed's stored cookie jar, or nil if
+// the feed has none
+func (s *Storage) LoadCookies(feed string) ([]Cookie, error) {
+	var sealed []byte
+	err := s.db.QueryRow("SELECT sealed FROM cookie_jars WHERE feed = ?", feed).Scan(&sealed)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cookies: %w", err)
+	}
+
+	plain, err := s.openCookies(sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	var cookies []Cookie
+	if err := json.Unmarshal(plain, &cookies); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cookies: %w", err)
+	}
+
+	return cookies, nil
+}
+
+// ClearCookies deletes feed's stored cookie jar
+func (s *Storage) ClearCookies(feed string) error {
+	if _, err := s.db.Exec("DELETE FROM cookie_jars WHERE feed = ?", feed); err != nil {
+		return fmt.Errorf("failed to clear cookies: %w", err)
+	}
+	return nil
+}
+
+// sealCookies encrypts plain with AES-GCM, prefixing the random nonce
+func (s *Storage) sealCookies(plain []byte) ([]byte, error) {
+	gcm, err := s.cookieCipher()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return gcm.Seal(nonce, nonce, plain, nil), nil
+}
+
+// openCookies reverses sealCookies
+func (s *Storage) openCookies(sealed []byte) ([]byte, error) {
+	gcm, err := s.cookieCipher()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("stored cookie jar is corrupt")
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt cookies (was the secret changed?): %w", err)
+	}
+
+	return plain, nil
+}
+
+// cookieCipher returns the AEAD for the configured cookie key
+func (s *Storage) cookieCipher() (cipher.AEAD, error) {
+	if s.cookieKey == nil {
+		return nil, fmt.Errorf("no cookie encryption secret set")
+	}
+
+	block, err := aes.NewCipher(s.cookieKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	return gcm, nil
+}
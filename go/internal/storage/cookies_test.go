@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCookies_EncryptedRoundTrip(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+	store.SetCookieKey("s3cret")
+
+	cookies, err := store.LoadCookies("Intranet")
+	if err != nil || cookies != nil {
+		t.Fatalf("expected no cookies for a fresh feed, got %v (err %v)", cookies, err)
+	}
+
+	expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	want := []Cookie{{URL: "https://example.com/login", Name: "session", Value: "abc123", Path: "/", Expires: expires, HttpOnly: true}}
+	if err := store.SaveCookies("Intranet", want); err != nil {
+		t.Fatalf("SaveCookies failed: %v", err)
+	}
+
+	// The session value must not be stored in the clear
+	var sealed []byte
+	if err := store.db.QueryRow("SELECT sealed FROM cookie_jars WHERE feed = ?", "Intranet").Scan(&sealed); err != nil {
+		t.Fatalf("failed to read sealed jar: %v", err)
+	}
+	if bytes.Contains(sealed, []byte("abc123")) {
+		t.Error("cookie value stored unencrypted")
+	}
+
+	got, err := store.LoadCookies("Intranet")
+	if err != nil {
+		t.Fatalf("LoadCookies failed: %v", err)
+	}
+	if len(got) != 1 || got[0].Value != "abc123" || !got[0].Expires.Equal(expires) || !got[0].HttpOnly {
+		t.Errorf("unexpected cookies: %+v", got)
+	}
+
+	if err := store.ClearCookies("Intranet"); err != nil {
+		t.Fatalf("ClearCookies failed: %v", err)
+	}
+	if got, _ := store.LoadCookies("Intranet"); got != nil {
+		t.Errorf("expected cookies to be cleared, got %+v", got)
+	}
+}
+
+func TestCookies_WrongOrMissingKey(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	if err := store.SaveCookies("Intranet", []Cookie{{Name: "a", Value: "b"}}); err == nil {
+		t.Error("expected SaveCookies to fail without a secret")
+	}
+
+	store.SetCookieKey("right")
+	if err := store.SaveCookies("Intranet", []Cookie{{Name: "a", Value: "b"}}); err != nil {
+		t.Fatalf("SaveCookies failed: %v", err)
+	}
+
+	store.SetCookieKey("wrong")
+	if _, err := store.LoadCookies("Intranet"); err == nil {
+		t.Error("expected LoadCookies to fail with the wrong secret")
+	}
+}
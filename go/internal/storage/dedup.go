@@ -0,0 +1,279 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Deduplication modes. The mode decides which URL an item's ID is derived
+// from, and whether items other sources already carry are linked to them.
+const (
+	// DedupOff derives IDs from URLs as fetched (the default)
+	DedupOff = "off"
+	// DedupNormalized derives IDs from normalized URLs, so a source
+	// listing an article under several URLs stores it once
+	DedupNormalized = "normalized"
+	// DedupCrossSource normalizes like DedupNormalized and links a new
+	// item to the one another source stored first with the same
+	// normalized URL
+	DedupCrossSource = "cross_source"
+)
+
+// NormalizeURL returns the form of rawURL duplicates are recognized by:
+// the scheme and host lowercased, a default port, the fragment, trailing
+// slashes and utm_* tracking parameters dropped, and the remaining query
+// parameters sorted. Anything that isn't an absolute URL is returned
+// trimmed.
+func NormalizeURL(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return rawURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); u.Scheme == "http" && port == "80" || u.Scheme == "https" && port == "443" {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+	u.Fragment, u.RawFragment = "", ""
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = strings.TrimRight(u.RawPath, "/")
+
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key := range query {
+			if strings.HasPrefix(strings.ToLower(key), "utm_") {
+				query.Del(key)
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+	u.ForceQuery = false
+
+	return u.String()
+}
+
+// KeyURL returns the URL an item's ID is derived from under dedup
+func KeyURL(dedup, rawURL string) string {
+	if normalizes(dedup) {
+		return NormalizeURL(rawURL)
+	}
+	return rawURL
+}
+
+// normalizes reports whether dedup derives IDs from normalized URLs
+func normalizes(dedup string) bool {
+	return dedup == DedupNormalized || dedup == DedupCrossSource
+}
+
+// storedDedup returns the deduplication mode the stored items were
+// written under
+func storedDedup(q interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}) (string, error) {
+	var dedup string
+	err := q.QueryRow("SELECT value FROM meta WHERE key = 'dedup'").Scan(&dedup)
+	if err == sql.ErrNoRows {
+		return DedupOff, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read dedup mode: %w", err)
+	}
+	return dedup, nil
+}
+
+// Dedup returns the deduplication mode the stored items were written
+// under
+func (s *Storage) Dedup() (string, error) {
+	return storedDedup(s.db)
+}
+
+// SetDedup switches the database to the deduplication mode dedup.
+// Turning normalization on or off re-keys stored items, collapsing rows
+// that now share an ID and keeping the oldest; items stored under the
+// run scope keep their IDs. Turning DedupCrossSource on links the stored
+// duplicates, and turning it off unlinks them. It returns the number of
+// rows removed as duplicates.
+func (s *Storage) SetDedup(dedup string) (int, error) {
+	switch dedup {
+	case DedupOff, DedupNormalized, DedupCrossSource:
+	default:
+		return 0, fmt.Errorf("unknown dedup mode: %s", dedup)
+	}
+
+	scope, err := s.UniquenessScope()
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	current, err := storedDedup(tx)
+	if err != nil {
+		return 0, err
+	}
+	if current == dedup {
+		return 0, nil
+	}
+
+	removed := 0
+	if normalizes(current) != normalizes(dedup) && scope != ScopeRun {
+		ids := newIDDeriver(tx, scope, dedup)
+		if removed, err = rekeyItems(tx, ids.id, ""); err == nil {
+			err = ids.err
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	switch {
+	case dedup == DedupCrossSource:
+		err = linkDuplicates(tx)
+	case current == DedupCrossSource:
+		_, err = tx.Exec("UPDATE feed_items SET url_key = NULL, duplicate_of = NULL")
+		if err != nil {
+			err = fmt.Errorf("failed to unlink duplicates: %w", err)
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO meta (key, value) VALUES ('dedup', ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value
+	`, dedup)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record dedup mode: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if removed > 0 {
+		if err := s.RebuildSourceStats(); err != nil {
+			return removed, err
+		}
+	}
+
+	return removed, nil
+}
+
+// linkDuplicates records every stored item's normalized URL and links
+// each to the oldest item of another source sharing it
+func linkDuplicates(tx *sql.Tx) error {
+	if _, err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_feed_items_url_key ON feed_items(url_key)"); err != nil {
+		return fmt.Errorf("failed to index normalized URLs: %w", err)
+	}
+
+	rows, err := tx.Query("SELECT id, source, url FROM feed_items ORDER BY julianday(created_at), id")
+	if err != nil {
+		return fmt.Errorf("failed to query items: %w", err)
+	}
+
+	type link struct {
+		id, key     string
+		duplicateOf interface{}
+	}
+	type first struct{ id, source string }
+	var links []link
+	firsts := make(map[string]first)
+	for rows.Next() {
+		var id, source, rawURL string
+		if err := rows.Scan(&id, &source, &rawURL); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan item: %w", err)
+		}
+		l := link{id: id, key: NormalizeURL(rawURL)}
+		if f, ok := firsts[l.key]; !ok {
+			firsts[l.key] = first{id, source}
+		} else if f.source != source {
+			l.duplicateOf = f.id
+		}
+		links = append(links, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating items: %w", err)
+	}
+
+	for _, l := range links {
+		if _, err := tx.Exec("UPDATE feed_items SET url_key = ?, duplicate_of = ? WHERE id = ?", l.key, l.duplicateOf, l.id); err != nil {
+			return fmt.Errorf("failed to link item: %w", err)
+		}
+	}
+	return nil
+}
+
+// linkItem records item's normalized URL and, if it was just inserted,
+// links it to the oldest item of another source sharing it
+func linkItem(tx *sql.Tx, item FeedItem, inserted bool) error {
+	key := NormalizeURL(item.URL)
+	var err error
+	if inserted {
+		_, err = tx.Exec(`
+			UPDATE feed_items SET url_key = ?, duplicate_of = (
+				SELECT first.id FROM feed_items AS first
+				WHERE first.url_key = ? AND first.source <> ? AND first.duplicate_of IS NULL
+				ORDER BY julianday(first.created_at), first.id
+				LIMIT 1
+			)
+			WHERE id = ?
+		`, key, key, item.Source, item.ID)
+	} else {
+		_, err = tx.Exec("UPDATE feed_items SET url_key = ? WHERE id = ?", key, item.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to link item: %w", err)
+	}
+	return nil
+}
+
+// unlinkOrphans unlinks the items whose original was deleted, so they
+// count again
+func unlinkOrphans(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		UPDATE feed_items SET duplicate_of = NULL
+		WHERE duplicate_of IS NOT NULL AND duplicate_of NOT IN (SELECT id FROM feed_items)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to unlink duplicates: %w", err)
+	}
+	return nil
+}
+
+// idDeriver derives the IDs of stored items under a scope and dedup mode,
+// looking up each source's ID hash once. err holds the first lookup that
+// failed.
+type idDeriver struct {
+	tx     *sql.Tx
+	scope  string
+	dedup  string
+	hashes map[string]string
+	err    error
+}
+
+func newIDDeriver(tx *sql.Tx, scope, dedup string) *idDeriver {
+	return &idDeriver{tx: tx, scope: scope, dedup: dedup, hashes: make(map[string]string)}
+}
+
+func (d *idDeriver) id(source, rawURL string) string {
+	idHash, ok := d.hashes[source]
+	if !ok {
+		var err error
+		if idHash, err = storedIDHash(d.tx, source); err != nil && d.err == nil {
+			d.err = err
+		}
+		d.hashes[source] = idHash
+	}
+	return ItemIDWith(idHash, d.scope, source, KeyURL(d.dedup, rawURL), "")
+}
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"https://example.com/post/", "https://example.com/post"},
+		{"HTTPS://Example.COM/Post", "https://example.com/Post"},
+		{"https://example.com:443/a", "https://example.com/a"},
+		{"http://example.com:8080/a", "http://example.com:8080/a"},
+		{"https://example.com/a?utm_source=hn&utm_Medium=x&id=3", "https://example.com/a?id=3"},
+		{"https://example.com/a?b=2&a=1", "https://example.com/a?a=1&b=2"},
+		{"https://example.com/a?utm_source=hn", "https://example.com/a"},
+		{"https://example.com/a#comments", "https://example.com/a"},
+		{"https://example.com/", "https://example.com"},
+		{"  item?id=3 ", "item?id=3"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeURL(tt.in); got != tt.want {
+			t.Errorf("NormalizeURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSetDedup_NormalizedMergesVariants(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	plain, tracked := "https://example.com/a", "https://example.com/a/?utm_source=rss"
+	store.SaveItems([]FeedItem{
+		{ID: ItemID(ScopeSource, "A", plain, ""), Title: "plain", URL: plain, Source: "A", CreatedAt: now},
+		{ID: ItemID(ScopeSource, "A", tracked, ""), Title: "tracked", URL: tracked, Source: "A", CreatedAt: now.Add(time.Second)},
+	})
+
+	removed, err := store.SetDedup(DedupNormalized)
+	if err != nil {
+		t.Fatalf("SetDedup failed: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("expected 1 duplicate removed, got %d", removed)
+	}
+
+	items, err := store.GetItems(ItemFilter{})
+	if err != nil {
+		t.Fatalf("GetItems failed: %v", err)
+	}
+	if len(items) != 1 || items[0].Title != "plain" {
+		t.Fatalf("expected the oldest item to remain, got %+v", items)
+	}
+	if want := ItemID(ScopeSource, "A", NormalizeURL(tracked), ""); items[0].ID != want {
+		t.Errorf("expected ID %s, got %s", want, items[0].ID)
+	}
+
+	if dedup, _ := store.Dedup(); dedup != DedupNormalized {
+		t.Errorf("expected dedup %q to be recorded, got %q", DedupNormalized, dedup)
+	}
+}
+
+func TestSetDedup_CrossSourceLinks(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	idOf := func(source, url string) string {
+		return ItemID(ScopeSource, source, NormalizeURL(url), "")
+	}
+	now := time.Now()
+	store.SaveItems([]FeedItem{
+		{ID: idOf("HN", "https://example.com/a"), Title: "a", URL: "https://example.com/a", Source: "HN", Tags: []string{"go"}, CreatedAt: now},
+		{ID: idOf("Lobsters", "https://example.com/a?utm_source=lobsters"), Title: "a", URL: "https://example.com/a?utm_source=lobsters", Source: "Lobsters", Tags: []string{"go"}, CreatedAt: now.Add(time.Second)},
+	})
+
+	if _, err := store.SetDedup(DedupCrossSource); err != nil {
+		t.Fatalf("SetDedup failed: %v", err)
+	}
+
+	// A later item from a third source links to the first original
+	store.SaveItems([]FeedItem{
+		{ID: idOf("Reddit", "https://EXAMPLE.com/a/"), Title: "a", URL: "https://EXAMPLE.com/a/", Source: "Reddit", Tags: []string{"go"}, CreatedAt: now.Add(2 * time.Second)},
+		{ID: idOf("Reddit", "https://example.com/b"), Title: "b", URL: "https://example.com/b", Source: "Reddit", CreatedAt: now.Add(2 * time.Second)},
+	})
+
+	original := idOf("HN", "https://example.com/a")
+	items, err := store.GetItems(ItemFilter{})
+	if err != nil {
+		t.Fatalf("GetItems failed: %v", err)
+	}
+	for _, item := range items {
+		want := ""
+		if item.URL != "https://example.com/b" && item.Source != "HN" {
+			want = original
+		}
+		if item.DuplicateOf != want {
+			t.Errorf("%s item: expected duplicate_of %q, got %q", item.Source, want, item.DuplicateOf)
+		}
+	}
+
+	if total, _ := store.GetAllItemsCount(); total != 2 {
+		t.Errorf("expected 2 distinct items, got %d", total)
+	}
+	if count, _ := store.GetItemCount("Lobsters"); count != 1 {
+		t.Errorf("expected Lobsters to keep its item, got %d", count)
+	}
+	tags, _ := store.GetTagStats(ItemFilter{})
+	if len(tags) != 1 || tags[0].ItemsCount != 1 || tags[0].Sources != 3 {
+		t.Errorf("expected go counted once across 3 sources, got %+v", tags)
+	}
+
+	// Deleting the original unlinks its duplicates
+	if _, err := store.ApplyItemBatch(ItemBatch{Action: BatchDelete, Filter: ItemFilter{Source: "HN"}}); err != nil {
+		t.Fatalf("ApplyItemBatch failed: %v", err)
+	}
+	if total, _ := store.GetAllItemsCount(); total != 3 {
+		t.Errorf("expected 3 items after the original was deleted, got %d", total)
+	}
+
+	// Turning cross-source dedup off unlinks every item
+	if _, err := store.SetDedup(DedupNormalized); err != nil {
+		t.Fatalf("SetDedup failed: %v", err)
+	}
+	items, _ = store.GetItems(ItemFilter{})
+	for _, item := range items {
+		if item.DuplicateOf != "" {
+			t.Errorf("expected %s item to be unlinked, got %q", item.Source, item.DuplicateOf)
+		}
+	}
+}
+
+func TestSetDedup_Unknown(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	if _, err := store.SetDedup("fuzzy"); err == nil {
+		t.Error("expected an error for an unknown dedup mode")
+	}
+}
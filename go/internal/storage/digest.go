@@ -0,0 +1,290 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// UntaggedTopic groups digest items that carry no tags
+const UntaggedTopic = "untagged"
+
+// DigestItem is an item picked for a digest, together with every source
+// that carried the same URL
+type DigestItem struct {
+	FeedItem
+	Sources []string `json:"sources"`
+}
+
+// DigestTopic is a section of a digest. Tag is its heading: the topic tag
+// its items share, or their source or score band when the digest is
+// grouped by those.
+type DigestTopic struct {
+	Tag   string       `json:"tag"`
+	Items []DigestItem `json:"items"`
+}
+
+// Ways GroupDigest can group items into sections
+const (
+	DigestByTag    = "tag"
+	DigestBySource = "source"
+	DigestByScore  = "score"
+)
+
+// DigestSortScore keeps a section's items in the order TopItems ranks
+// them; SortNewest, SortOldest and SortTitle reorder them
+const DigestSortScore = "score"
+
+// DefaultScoreBands are the lowest scores of the bands a digest grouped by
+// score has unless configured: 5 or more sources, 2 to 4, and 1
+var DefaultScoreBands = []int{5, 2}
+
+// DigestLayout arranges a digest's items into sections; the zero layout
+// groups them by topic in ranked order
+type DigestLayout struct {
+	// GroupBy is one of DigestByTag (the default), DigestBySource or
+	// DigestByScore
+	GroupBy string
+	// ScoreBands are the lowest scores of the DigestByScore sections; a
+	// band of 1 is added if missing. Empty means DefaultScoreBands.
+	ScoreBands []int
+	// SectionLimit caps the items of each section; 0 means no cap
+	SectionLimit int
+	// Sort orders each section's items: DigestSortScore (the default),
+	// SortNewest, SortOldest or SortTitle
+	Sort string
+}
+
+// GetItemsSince returns the items first stored within the last window,
+// newest first
+func (s *Storage) GetItemsSince(window time.Duration) ([]FeedItem, error) {
+	cutoff := s.clock.Now().Add(-window).UTC().Format(time.RFC3339)
+
+	rows, err := s.db.Query(`
+		SELECT id, title, url, source, timestamp, tags, created_at
+		FROM feed_items
+		WHERE julianday(created_at) >= julianday(?)
+		ORDER BY julianday(created_at) DESC, id
+	`, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []FeedItem
+	for rows.Next() {
+		var item FeedItem
+		var tags *string
+		var createdAt string
+		if err := rows.Scan(&item.ID, &item.Title, &item.URL, &item.Source, &item.Timestamp, &tags, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan item: %w", err)
+		}
+		if tags != nil {
+			if err := json.Unmarshal([]byte(*tags), &item.Tags); err != nil {
+				return nil, fmt.Errorf("invalid tags for item %s: %w", item.ID, err)
+			}
+		}
+		if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
+			item.CreatedAt = t
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating items: %w", err)
+	}
+
+	return items, nil
+}
+
+// TopItems ranks items for a digest and returns the best limit of them
+// (all if limit <= 0). Items don't carry a score, so an item's rank is
+// how many sources carried its URL, newest first among equals. Items
+// sharing a normalized URL are merged into the first one seen, with their
+// tags combined.
+func TopItems(items []FeedItem, limit int) []DigestItem {
+	var ranked []DigestItem
+	byURL := make(map[string]int)
+
+	for _, item := range items {
+		key := NormalizeURL(item.URL)
+		i, ok := byURL[key]
+		if !ok || key == "" {
+			byURL[key] = len(ranked)
+			ranked = append(ranked, DigestItem{FeedItem: item, Sources: []string{item.Source}})
+			continue
+		}
+
+		merged := &ranked[i]
+		if !containsString(merged.Sources, item.Source) {
+			merged.Sources = append(merged.Sources, item.Source)
+		}
+		for _, tag := range item.Tags {
+			if !containsString(merged.Tags, tag) {
+				merged.Tags = append(merged.Tags, tag)
+			}
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if len(ranked[i].Sources) != len(ranked[j].Sources) {
+			return len(ranked[i].Sources) > len(ranked[j].Sources)
+		}
+		return ranked[i].CreatedAt.After(ranked[j].CreatedAt)
+	})
+
+	if limit > 0 && len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
+
+// GroupByTopic groups digest items by their first tag, keeping their
+// order within each group. Larger groups come first; untagged items come
+// last.
+func GroupByTopic(items []DigestItem) []DigestTopic {
+	return GroupDigest(items, DigestLayout{})
+}
+
+// GroupDigest arranges items, as ranked by TopItems, into the sections
+// layout describes. Topic and source sections are ordered largest first,
+// with untagged items last; score bands highest first.
+func GroupDigest(items []DigestItem, layout DigestLayout) []DigestTopic {
+	items = append([]DigestItem(nil), items...)
+	switch layout.Sort {
+	case SortNewest:
+		sort.SliceStable(items, func(i, j int) bool { return items[i].CreatedAt.After(items[j].CreatedAt) })
+	case SortOldest:
+		sort.SliceStable(items, func(i, j int) bool { return items[i].CreatedAt.Before(items[j].CreatedAt) })
+	case SortTitle:
+		sort.SliceStable(items, func(i, j int) bool { return strings.ToLower(items[i].Title) < strings.ToLower(items[j].Title) })
+	}
+
+	var topics []DigestTopic
+	if layout.GroupBy == DigestByScore {
+		topics = groupByScore(items, layout.ScoreBands)
+	} else {
+		section := func(item DigestItem) string {
+			if layout.GroupBy == DigestBySource {
+				return item.Source
+			}
+			if len(item.Tags) > 0 {
+				return item.Tags[0]
+			}
+			return UntaggedTopic
+		}
+		topics = groupItems(items, section)
+
+		sort.SliceStable(topics, func(i, j int) bool {
+			if layout.GroupBy != DigestBySource && (topics[i].Tag == UntaggedTopic) != (topics[j].Tag == UntaggedTopic) {
+				return topics[j].Tag == UntaggedTopic
+			}
+			if len(topics[i].Items) != len(topics[j].Items) {
+				return len(topics[i].Items) > len(topics[j].Items)
+			}
+			return topics[i].Tag < topics[j].Tag
+		})
+	}
+
+	if layout.SectionLimit > 0 {
+		for i := range topics {
+			if len(topics[i].Items) > layout.SectionLimit {
+				topics[i].Items = topics[i].Items[:layout.SectionLimit]
+			}
+		}
+	}
+	return topics
+}
+
+// groupItems groups items by the section each belongs to, keeping their
+// order within each group and the order the groups first appear in
+func groupItems(items []DigestItem, section func(DigestItem) string) []DigestTopic {
+	var topics []DigestTopic
+	index := make(map[string]int)
+
+	for _, item := range items {
+		name := section(item)
+		i, ok := index[name]
+		if !ok {
+			i = len(topics)
+			index[name] = i
+			topics = append(topics, DigestTopic{Tag: name})
+		}
+		topics[i].Items = append(topics[i].Items, item)
+	}
+	return topics
+}
+
+// groupByScore groups items into bands by how many sources carried them,
+// highest band first, leaving out empty bands
+func groupByScore(items []DigestItem, bands []int) []DigestTopic {
+	if len(bands) == 0 {
+		bands = DefaultScoreBands
+	}
+	lows := []int{1}
+	for _, low := range bands {
+		if low > 1 && !containsInt(lows, low) {
+			lows = append(lows, low)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(lows)))
+
+	headings := make([]string, len(lows))
+	for i, low := range lows {
+		switch {
+		case i == 0:
+			headings[i] = fmt.Sprintf("%d+ sources", low)
+		case lows[i-1] == low+1 && low == 1:
+			headings[i] = "1 source"
+		case lows[i-1] == low+1:
+			headings[i] = fmt.Sprintf("%d sources", low)
+		default:
+			headings[i] = fmt.Sprintf("%d-%d sources", low, lows[i-1]-1)
+		}
+	}
+
+	topics := groupItems(items, func(item DigestItem) string {
+		for i, low := range lows {
+			if len(item.Sources) >= low {
+				return headings[i]
+			}
+		}
+		return headings[len(headings)-1]
+	})
+	sort.SliceStable(topics, func(i, j int) bool {
+		return indexOf(headings, topics[i].Tag) < indexOf(headings, topics[j].Tag)
+	})
+	return topics
+}
+
+// containsInt reports whether list contains n
+func containsInt(list []int, n int) bool {
+	for _, v := range list {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
+
+// indexOf returns the position of s in list, or -1
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
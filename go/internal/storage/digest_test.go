@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"feedpulse/internal/clock"
+)
+
+func TestGetItemsSince(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	store.SetClock(clock.Fixed(now))
+
+	store.SaveItems([]FeedItem{
+		{ID: "old", Title: "Old", URL: "https://example.com/old", Source: "HN", CreatedAt: now.Add(-48 * time.Hour)},
+		{ID: "a", Title: "A", URL: "https://example.com/a", Source: "HN", Tags: []string{"go"}, CreatedAt: now.Add(-2 * time.Hour)},
+		// Stored with a different offset, but still within the window
+		{ID: "b", Title: "B", URL: "https://example.com/b", Source: "Lobsters", CreatedAt: now.Add(-time.Hour).In(time.FixedZone("EST", -5*3600))},
+	})
+
+	items, err := store.GetItemsSince(24 * time.Hour)
+	if err != nil {
+		t.Fatalf("GetItemsSince failed: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d: %+v", len(items), items)
+	}
+	if items[0].ID != "b" || items[1].ID != "a" {
+		t.Errorf("expected newest first, got %s, %s", items[0].ID, items[1].ID)
+	}
+	if len(items[1].Tags) != 1 || items[1].Tags[0] != "go" {
+		t.Errorf("expected tags to be loaded, got %v", items[1].Tags)
+	}
+}
+
+func TestTopItems_RanksCrossPostedFirst(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	items := []FeedItem{
+		{ID: "hn1", URL: "https://example.com/new", Source: "HN", CreatedAt: now},
+		{ID: "hn2", URL: "https://example.com/shared", Source: "HN", Tags: []string{"go"}, CreatedAt: now.Add(-3 * time.Hour)},
+		{ID: "lo1", URL: "https://example.com/shared", Source: "Lobsters", Tags: []string{"go", "web"}, CreatedAt: now.Add(-2 * time.Hour)},
+		{ID: "hn3", URL: "https://example.com/older", Source: "HN", CreatedAt: now.Add(-time.Hour)},
+	}
+
+	top := TopItems(items, 2)
+	if len(top) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(top))
+	}
+	if top[0].ID != "hn2" || len(top[0].Sources) != 2 {
+		t.Errorf("expected the cross-posted item first, got %+v", top[0])
+	}
+	if len(top[0].Tags) != 2 {
+		t.Errorf("expected merged tags, got %v", top[0].Tags)
+	}
+	if top[1].ID != "hn1" {
+		t.Errorf("expected the newest single-source item next, got %s", top[1].ID)
+	}
+}
+
+func TestGroupByTopic(t *testing.T) {
+	items := []DigestItem{
+		{FeedItem: FeedItem{ID: "1"}},
+		{FeedItem: FeedItem{ID: "2", Tags: []string{"rust"}}},
+		{FeedItem: FeedItem{ID: "3", Tags: []string{"go"}}},
+		{FeedItem: FeedItem{ID: "4", Tags: []string{"go", "web"}}},
+	}
+
+	topics := GroupByTopic(items)
+
+	want := []string{"go", "rust", UntaggedTopic}
+	if len(topics) != len(want) {
+		t.Fatalf("expected %d topics, got %+v", len(want), topics)
+	}
+	for i, tag := range want {
+		if topics[i].Tag != tag {
+			t.Errorf("topic %d: expected %s, got %s", i, tag, topics[i].Tag)
+		}
+	}
+	if len(topics[0].Items) != 2 || topics[0].Items[0].ID != "3" {
+		t.Errorf("unexpected go topic: %+v", topics[0].Items)
+	}
+}
+
+func TestGroupDigest(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	// As TopItems ranks them: by sources, then newest
+	items := []DigestItem{
+		{FeedItem: FeedItem{ID: "wide", Title: "Wide", Source: "HN", CreatedAt: now.Add(-3 * time.Hour)}, Sources: []string{"HN", "Lobsters", "Reddit", "Tildes", "Slashdot"}},
+		{FeedItem: FeedItem{ID: "pair", Title: "pair", Source: "Lobsters", Tags: []string{"go"}, CreatedAt: now.Add(-2 * time.Hour)}, Sources: []string{"Lobsters", "HN"}},
+		{FeedItem: FeedItem{ID: "new", Title: "New", Source: "HN", Tags: []string{"go"}, CreatedAt: now}, Sources: []string{"HN"}},
+		{FeedItem: FeedItem{ID: "old", Title: "Alpha", Source: "HN", CreatedAt: now.Add(-5 * time.Hour)}, Sources: []string{"HN"}},
+	}
+
+	tests := []struct {
+		name   string
+		layout DigestLayout
+		want   map[string][]string
+		order  []string
+	}{
+		{
+			name:   "by source",
+			layout: DigestLayout{GroupBy: DigestBySource},
+			order:  []string{"HN", "Lobsters"},
+			want:   map[string][]string{"HN": {"wide", "new", "old"}, "Lobsters": {"pair"}},
+		},
+		{
+			name:   "by score",
+			layout: DigestLayout{GroupBy: DigestByScore},
+			order:  []string{"5+ sources", "2-4 sources", "1 source"},
+			want:   map[string][]string{"5+ sources": {"wide"}, "2-4 sources": {"pair"}, "1 source": {"new", "old"}},
+		},
+		{
+			name:   "by custom bands",
+			layout: DigestLayout{GroupBy: DigestByScore, ScoreBands: []int{2, 3}},
+			order:  []string{"3+ sources", "2 sources", "1 source"},
+			want:   map[string][]string{"3+ sources": {"wide"}, "2 sources": {"pair"}, "1 source": {"new", "old"}},
+		},
+		{
+			name:   "newest first, capped",
+			layout: DigestLayout{GroupBy: DigestBySource, Sort: SortNewest, SectionLimit: 2},
+			order:  []string{"HN", "Lobsters"},
+			want:   map[string][]string{"HN": {"new", "wide"}, "Lobsters": {"pair"}},
+		},
+		{
+			name:   "by title",
+			layout: DigestLayout{Sort: SortTitle},
+			order:  []string{"go", UntaggedTopic},
+			want:   map[string][]string{"go": {"new", "pair"}, UntaggedTopic: {"old", "wide"}},
+		},
+		// The empty 9+ band is left out
+		{
+			name:   "oldest first",
+			layout: DigestLayout{GroupBy: DigestByScore, Sort: SortOldest, ScoreBands: []int{9}},
+			order:  []string{"1-8 sources"},
+			want:   map[string][]string{"1-8 sources": {"old", "wide", "pair", "new"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topics := GroupDigest(items, tt.layout)
+			var order []string
+			got := make(map[string][]string)
+			for _, topic := range topics {
+				order = append(order, topic.Tag)
+				for _, item := range topic.Items {
+					got[topic.Tag] = append(got[topic.Tag], item.ID)
+				}
+			}
+			if !reflect.DeepEqual(order, tt.order) {
+				t.Errorf("sections = %v, want %v", order, tt.order)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("items = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if items[0].ID != "wide" {
+		t.Error("expected the ranked items to be left in order")
+	}
+}
@@ -0,0 +1,24 @@
+//go:build !purego
+
+package storage
+
+import (
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteDriver names the SQLite driver this build links
+const SQLiteDriver = "mattn/go-sqlite3 (cgo)"
+
+// SQLiteFeature is the build feature `feedpulse version` reports for the
+// SQLite driver
+const SQLiteFeature = "cgo-sqlite"
+
+// driverName is the database/sql driver to open databases with
+const driverName = "sqlite3"
+
+// dataSourceName returns the DSN opening dbPath. busy_timeout is
+// per-connection, so it goes in the DSN to reach every connection in the
+// pool rather than just the first.
+func dataSourceName(dbPath string) string {
+	return dbPath + "?_busy_timeout=5000"
+}
@@ -0,0 +1,27 @@
+//go:build purego
+
+package storage
+
+import (
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteDriver names the SQLite driver this build links
+const SQLiteDriver = "modernc.org/sqlite (pure Go)"
+
+// SQLiteFeature is the build feature `feedpulse version` reports for the
+// SQLite driver
+const SQLiteFeature = "purego-sqlite"
+
+// driverName is the database/sql driver to open databases with
+const driverName = "sqlite"
+
+// dataSourceName returns the DSN opening dbPath. busy_timeout is
+// per-connection, so it goes in the DSN to reach every connection in the
+// pool rather than just the first. Transactions take the write lock when
+// they begin: this driver fails a deferred transaction that upgrades to a
+// write while another connection writes, without waiting out the
+// timeout.
+func dataSourceName(dbPath string) string {
+	return dbPath + "?_pragma=busy_timeout(5000)&_txlock=immediate"
+}
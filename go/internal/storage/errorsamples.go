@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrorGroup is a distinct error a source's fetches failed with: every
+// logged error message that normalizes to Message
+type ErrorGroup struct {
+	Source  string `json:"source"`
+	Message string `json:"message"`
+	// Sample is the most recent message as it was logged
+	Sample    string    `json:"sample"`
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// ErrorFilter selects the failed fetches errors are grouped from; zero
+// fields match everything. Limit caps the number of groups.
+type ErrorFilter struct {
+	Source string
+	Since  time.Time
+	Limit  int
+}
+
+// errorNormalizers rewrite the parts of an error message that differ
+// between occurrences of the same error, in order
+var errorNormalizers = []struct {
+	pattern *regexp.Regexp
+	replace func(string) string
+}{
+	{regexp.MustCompile(`https?://[^\s"'<>]*[^\s"'<>:;,.)]`), func(string) string { return "<url>" }},
+	{regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}(:\d+)?\b`), func(string) string { return "<addr>" }},
+	{regexp.MustCompile(`(?i)\b[0-9a-f]{8,}(-[0-9a-f]{4,})*\b`), func(string) string { return "<id>" }},
+	// HTTP status codes tell errors apart; other numbers (retries,
+	// offsets, durations) don't
+	{regexp.MustCompile(`HTTP \d{3}|\d+(\.\d+)?`), func(m string) string {
+		if strings.HasPrefix(m, "HTTP ") {
+			return m
+		}
+		return "N"
+	}},
+}
+
+// NormalizeError reduces an error message to what's common to every
+// occurrence of the same error, replacing URLs, addresses, IDs and
+// numbers other than HTTP status codes with placeholders
+func NormalizeError(msg string) string {
+	for _, n := range errorNormalizers {
+		msg = n.pattern.ReplaceAllStringFunc(msg, n.replace)
+	}
+	return strings.TrimSpace(msg)
+}
+
+// GroupErrors folds failed fetches into groups by source and normalized
+// message, most recently seen first, keeping at most limit (0 for all).
+// logs must be in the order they were logged.
+func GroupErrors(logs []FetchLog, limit int) []ErrorGroup {
+	type key struct{ source, message string }
+	groups := make(map[key]*ErrorGroup)
+	var order []key
+
+	for _, log := range logs {
+		if log.ErrorMessage == nil {
+			continue
+		}
+		k := key{log.Source, NormalizeError(*log.ErrorMessage)}
+		g, ok := groups[k]
+		if !ok {
+			g = &ErrorGroup{Source: k.source, Message: k.message, FirstSeen: log.FetchedAt}
+			groups[k] = g
+			order = append(order, k)
+		}
+		g.Count++
+		g.Sample = *log.ErrorMessage
+		g.LastSeen = log.FetchedAt
+	}
+
+	result := make([]ErrorGroup, 0, len(order))
+	for _, k := range order {
+		result = append(result, *groups[k])
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].LastSeen.After(result[j].LastSeen)
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// ListErrors returns the distinct errors fetches failed with, matching
+// filter, most recently seen first
+func (s *Storage) ListErrors(filter ErrorFilter) ([]ErrorGroup, error) {
+	query := "SELECT source, fetched_at, error_message FROM fetch_log WHERE status = 'error' AND error_message IS NOT NULL"
+	var args []interface{}
+	if filter.Source != "" {
+		query += " AND source = ?"
+		args = append(args, filter.Source)
+	}
+	if !filter.Since.IsZero() {
+		query += " AND julianday(fetched_at) >= julianday(?)"
+		args = append(args, filter.Since.UTC().Format(time.RFC3339))
+	}
+	query += " ORDER BY id"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query errors: %w", err)
+	}
+	defer rows.Close()
+
+	var logs []FetchLog
+	for rows.Next() {
+		var log FetchLog
+		var fetchedAt string
+		if err := rows.Scan(&log.Source, &fetchedAt, &log.ErrorMessage); err != nil {
+			return nil, fmt.Errorf("failed to scan error: %w", err)
+		}
+		if t, err := time.Parse(time.RFC3339, fetchedAt); err == nil {
+			log.FetchedAt = t
+		}
+		logs = append(logs, log)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating errors: %w", err)
+	}
+
+	return GroupErrors(logs, filter.Limit), nil
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNormalizeError(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want string
+	}{
+		{
+			`failed after 3 retries: HTTP request failed: Get "https://example.com/feed?page=2": context deadline exceeded`,
+			`failed after N retries: HTTP request failed: Get "<url>": context deadline exceeded`,
+		},
+		{
+			"failed after 3 retries: HTTP 503: 503 Service Unavailable",
+			"failed after N retries: HTTP 503: N Service Unavailable",
+		},
+		{
+			"HTTP request failed: dial tcp 10.0.0.12:443: connect: connection refused",
+			"HTTP request failed: dial tcp <addr>: connect: connection refused",
+		},
+		{
+			"parse error: malformed JSON: invalid character '}' after object key at offset 1532",
+			"parse error: malformed JSON: invalid character '}' after object key at offset N",
+		},
+		{
+			"request 9f86d081884c7d65 rejected",
+			"request <id> rejected",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeError(tt.msg); got != tt.want {
+			t.Errorf("NormalizeError(%q) = %q, want %q", tt.msg, got, tt.want)
+		}
+	}
+
+	if NormalizeError("HTTP 503: Service Unavailable") == NormalizeError("HTTP 404: Not Found") {
+		t.Error("expected different HTTP statuses to stay distinct")
+	}
+}
+
+func TestListErrors(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	start := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	logs := []struct {
+		source, status, msg string
+		offset              time.Duration
+	}{
+		{"GitHub", "error", "failed after 3 retries: HTTP 503: 503 Service Unavailable", 0},
+		{"GitHub", "error", "failed after 2 retries: HTTP 503: 503 Service Unavailable", time.Hour},
+		{"GitHub", "error", "parse error: malformed JSON: unexpected end of JSON input", 2 * time.Hour},
+		{"GitHub", "degraded", "expected at least 5 items, got 1", 3 * time.Hour},
+		{"Reddit", "error", "failed after 3 retries: HTTP 429: 429 Too Many Requests", 4 * time.Hour},
+		{"GitHub", "error", "failed after 3 retries: HTTP 503: 503 Service Unavailable", 5 * time.Hour},
+	}
+	for _, l := range logs {
+		msg := l.msg
+		if err := store.LogFetch(FetchLog{Source: l.source, FetchedAt: start.Add(l.offset), Status: l.status, ErrorMessage: &msg}); err != nil {
+			t.Fatalf("LogFetch failed: %v", err)
+		}
+	}
+	if err := store.LogFetch(FetchLog{Source: "GitHub", FetchedAt: start, Status: "success"}); err != nil {
+		t.Fatalf("LogFetch failed: %v", err)
+	}
+
+	groups, err := store.ListErrors(ErrorFilter{Source: "GitHub"})
+	if err != nil {
+		t.Fatalf("ListErrors failed: %v", err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 distinct errors, got %+v", groups)
+	}
+	first := groups[0]
+	if first.Count != 3 || !first.FirstSeen.Equal(start) || !first.LastSeen.Equal(start.Add(5*time.Hour)) {
+		t.Errorf("expected the 503s grouped, seen 3 times from %v to %v, got %+v", start, start.Add(5*time.Hour), first)
+	}
+	if first.Sample != "failed after 3 retries: HTTP 503: 503 Service Unavailable" {
+		t.Errorf("expected the latest message as the sample, got %q", first.Sample)
+	}
+	if groups[1].Count != 1 || groups[1].Message != "parse error: malformed JSON: unexpected end of JSON input" {
+		t.Errorf("unexpected second group: %+v", groups[1])
+	}
+
+	// Across sources, most recently seen first, cut to the limit
+	groups, err = store.ListErrors(ErrorFilter{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListErrors failed: %v", err)
+	}
+	if len(groups) != 2 || groups[0].Source != "GitHub" || groups[1].Source != "Reddit" {
+		t.Errorf("expected GitHub's 503s then Reddit's 429s, got %+v", groups)
+	}
+
+	// Only occurrences in the window count
+	groups, err = store.ListErrors(ErrorFilter{Source: "GitHub", Since: start.Add(90 * time.Minute)})
+	if err != nil {
+		t.Fatalf("ListErrors failed: %v", err)
+	}
+	if len(groups) != 2 || groups[0].Count != 1 || !groups[0].FirstSeen.Equal(start.Add(5*time.Hour)) {
+		t.Errorf("expected only errors since %v, got %+v", start.Add(90*time.Minute), groups)
+	}
+}
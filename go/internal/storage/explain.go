@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// payloadContext is how many bytes of raw payload are kept on each side
+// of an item's URL in an explanation
+const payloadContext = 200
+
+// ItemExplanation is what the database records about how an item came to
+// be stored
+type ItemExplanation struct {
+	Item FeedItem
+
+	// FirstRun is the fetch that first stored the item: the source's first
+	// successful fetch logged at or after the item was created. It is nil
+	// if no such fetch was logged (e.g. the item was saved by recover).
+	FirstRun *FetchLog
+
+	// Revisions are the title/URL changes later fetches made
+	Revisions []ItemRevision
+
+	// Scope and IDHash are what the item's ID was derived with
+	Scope  string
+	IDHash string
+
+	// Duplicates are other stored items with the same URL, kept apart
+	// from this one because their uniqueness key differs under Scope
+	Duplicates []FeedItem
+
+	// Payload is the part of a raw response mentioning the item's URL,
+	// from its raw data or a journaled payload, if either was kept
+	Payload string
+}
+
+// PayloadFragment returns the part of payload around the first mention of
+// url, or false if payload doesn't mention it
+func PayloadFragment(payload []byte, url string) (string, bool) {
+	if url == "" {
+		return "", false
+	}
+	i := bytes.Index(payload, []byte(url))
+	if i < 0 {
+		// JSON encoders commonly escape slashes
+		i = bytes.Index(payload, bytes.ReplaceAll([]byte(url), []byte("/"), []byte(`\/`)))
+	}
+	if i < 0 {
+		return "", false
+	}
+
+	start, end := i-payloadContext, i+len(url)+payloadContext
+	if start < 0 {
+		start = 0
+	}
+	if end > len(payload) {
+		end = len(payload)
+	}
+	return string(payload[start:end]), true
+}
+
+// ExplainItem gathers the provenance of the item with itemID, or returns
+// nil if there is no such item
+func (s *Storage) ExplainItem(itemID string) (*ItemExplanation, error) {
+	var e ItemExplanation
+	var tags, rawData *string
+	var createdAt string
+	err := s.db.QueryRow(`
+		SELECT id, title, url, source, timestamp, tags, raw_data, created_at
+		FROM feed_items WHERE id = ?
+	`, itemID).Scan(&e.Item.ID, &e.Item.Title, &e.Item.URL, &e.Item.Source, &e.Item.Timestamp, &tags, &rawData, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+	if tags != nil {
+		if err := json.Unmarshal([]byte(*tags), &e.Item.Tags); err != nil {
+			return nil, fmt.Errorf("invalid tags for item %s: %w", e.Item.ID, err)
+		}
+	}
+	e.Item.RawData = rawData
+	if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
+		e.Item.CreatedAt = t
+	}
+
+	var run FetchLog
+	var fetchedAt string
+	var endpoint sql.NullString
+	err = s.db.QueryRow(`
+		SELECT id, source, fetched_at, status, items_count, error_message, duration_ms, endpoint, slow
+		FROM fetch_log
+		WHERE source = ? AND status IN ('success', 'degraded')
+			AND julianday(fetched_at) >= julianday(?)
+		ORDER BY julianday(fetched_at), id
+		LIMIT 1
+	`, e.Item.Source, createdAt).Scan(&run.ID, &run.Source, &fetchedAt, &run.Status, &run.ItemsCount, &run.ErrorMessage, &run.DurationMs, &endpoint, &run.Slow)
+	switch err {
+	case nil:
+		if t, err := time.Parse(time.RFC3339, fetchedAt); err == nil {
+			run.FetchedAt = t
+		}
+		run.Endpoint = endpoint.String
+		e.FirstRun = &run
+	case sql.ErrNoRows:
+	default:
+		return nil, fmt.Errorf("failed to find item's fetch: %w", err)
+	}
+
+	if e.Revisions, err = s.GetItemHistory(itemID); err != nil {
+		return nil, err
+	}
+	if e.Scope, err = s.UniquenessScope(); err != nil {
+		return nil, err
+	}
+	if e.IDHash, err = storedIDHash(s.db, e.Item.Source); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, title, source, created_at FROM feed_items
+		WHERE url = ? AND id != ?
+		ORDER BY source, id
+	`, e.Item.URL, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query duplicates: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		dup := FeedItem{URL: e.Item.URL}
+		if err := rows.Scan(&dup.ID, &dup.Title, &dup.Source, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan item: %w", err)
+		}
+		if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
+			dup.CreatedAt = t
+		}
+		e.Duplicates = append(e.Duplicates, dup)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating duplicates: %w", err)
+	}
+
+	if rawData != nil {
+		e.Payload = *rawData
+	} else if e.Payload, err = s.journaledFragment(e.Item.Source, e.Item.URL); err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// journaledFragment searches source's journaled payloads, newest first,
+// for one mentioning url
+func (s *Storage) journaledFragment(source, url string) (string, error) {
+	rows, err := s.db.Query(`
+		SELECT payload FROM fetch_journal WHERE source = ? ORDER BY id DESC
+	`, source)
+	if err != nil {
+		return "", fmt.Errorf("failed to query journal: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var payload []byte
+		if err := rows.Scan(&payload); err != nil {
+			return "", fmt.Errorf("failed to scan journal entry: %w", err)
+		}
+		if fragment, ok := PayloadFragment(payload, url); ok {
+			return fragment, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("error iterating journal: %w", err)
+	}
+	return "", nil
+}
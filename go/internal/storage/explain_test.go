@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExplainItem(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	item := FeedItem{ID: ItemID(ScopeSource, "HN", "https://example.com/a", ""), Title: "A", URL: "https://example.com/a", Source: "HN", CreatedAt: created}
+	other := FeedItem{ID: ItemID(ScopeSource, "Lobsters", "https://example.com/a", ""), Title: "A", URL: "https://example.com/a", Source: "Lobsters", CreatedAt: created}
+
+	// An earlier fetch and a failed one don't count as the item's run
+	store.LogFetch(FetchLog{Source: "HN", FetchedAt: created.Add(-time.Hour), Status: "success"})
+	store.LogFetch(FetchLog{Source: "HN", FetchedAt: created, Status: "error"})
+	if _, err := store.AppendJournal("HN", "json", []byte(`{"hits": [{"title": "A", "url": "https:\/\/example.com\/a"}]}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.SaveFetchResult(FetchLog{Source: "HN", FetchedAt: created.Add(2 * time.Second), Status: "success", ItemsCount: 1, Endpoint: "https://hn.example.com"}, []FeedItem{item}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.SaveFetchResult(FetchLog{Source: "Lobsters", FetchedAt: created, Status: "success", ItemsCount: 1}, []FeedItem{other}); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := store.ExplainItem(item.ID)
+	if err != nil {
+		t.Fatalf("ExplainItem failed: %v", err)
+	}
+	if e == nil {
+		t.Fatal("expected an explanation")
+	}
+
+	if e.FirstRun == nil || e.FirstRun.Endpoint != "https://hn.example.com" || !e.FirstRun.FetchedAt.Equal(created.Add(2*time.Second)) {
+		t.Errorf("expected the fetch that stored the item, got %+v", e.FirstRun)
+	}
+	if e.Scope != ScopeSource || e.IDHash != IDHashSHA256 {
+		t.Errorf("expected source scope with sha256, got %s/%s", e.Scope, e.IDHash)
+	}
+	if len(e.Duplicates) != 1 || e.Duplicates[0].Source != "Lobsters" {
+		t.Errorf("expected the Lobsters copy as a duplicate, got %+v", e.Duplicates)
+	}
+	if !strings.Contains(e.Payload, `"title": "A"`) {
+		t.Errorf("expected the journaled payload fragment, got %q", e.Payload)
+	}
+
+	missing, err := store.ExplainItem("nope")
+	if err != nil || missing != nil {
+		t.Errorf("expected nil for a missing item, got %+v, %v", missing, err)
+	}
+}
+
+func TestPayloadFragment(t *testing.T) {
+	payload := []byte(strings.Repeat("x", 500) + "https://example.com/a" + strings.Repeat("y", 500))
+
+	fragment, ok := PayloadFragment(payload, "https://example.com/a")
+	if !ok {
+		t.Fatal("expected the URL to be found")
+	}
+	if len(fragment) != 2*payloadContext+len("https://example.com/a") {
+		t.Errorf("unexpected fragment length %d", len(fragment))
+	}
+
+	if _, ok := PayloadFragment(payload, "https://example.com/b"); ok {
+		t.Error("expected a missing URL not to be found")
+	}
+}
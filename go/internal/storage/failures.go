@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"fmt"
+	"io"
+	"time"
+)
+
+// FailuresKept is how many of a source's most recent parse failures keep
+// their recorded response; older ones are deleted, leaving only the
+// fetch log entry that referenced them
+const FailuresKept = 10
+
+// CapturedResponse is the raw response of a fetch that failed to parse,
+// cut to a size cap, to be recorded with the fetch's log entry
+type CapturedResponse struct {
+	// Size is the length of the whole response; Payload holds its first
+	// bytes, up to the cap
+	Size    int
+	Payload []byte
+}
+
+// CaptureResponse keeps the first max bytes of payload for recording
+func CaptureResponse(payload []byte, max int) *CapturedResponse {
+	kept := payload
+	if len(kept) > max {
+		kept = kept[:max]
+	}
+	return &CapturedResponse{Size: len(payload), Payload: append([]byte(nil), kept...)}
+}
+
+// ParseFailure is a recorded response that failed to parse, and the fetch
+// it belonged to
+type ParseFailure struct {
+	ID         int64     `json:"id"`
+	FetchID    int64     `json:"fetch_id"`
+	Source     string    `json:"source"`
+	Error      string    `json:"error"`
+	CapturedAt time.Time `json:"captured_at"`
+	// Size is the length of the whole response, Stored how much of it was
+	// kept and Compressed what that takes up in the database
+	Size       int `json:"size"`
+	Stored     int `json:"stored"`
+	Compressed int `json:"compressed"`
+	// Payload is the stored response, only loaded by GetParseFailure
+	Payload []byte `json:"-"`
+}
+
+// Truncated reports whether the response was cut to the size cap
+func (f ParseFailure) Truncated() bool {
+	return f.Stored < f.Size
+}
+
+// recordFailure stores log's captured response, gzipped, as the parse
+// failure of the fetch fetchID, references it from the fetch's log entry
+// and forgets the source's failures beyond FailuresKept
+func recordFailure(tx *sql.Tx, fetchID int64, log FetchLog) error {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(log.Failure.Payload); err != nil {
+		return fmt.Errorf("failed to compress response: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to compress response: %w", err)
+	}
+
+	var id int64
+	err := tx.QueryRow(`
+		INSERT INTO parse_failures (fetch_id, source, captured_at, size, stored, payload)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, fetchID, log.Source, log.FetchedAt.Format(time.RFC3339), log.Failure.Size, len(log.Failure.Payload), buf.Bytes()).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("failed to record parse failure: %w", err)
+	}
+
+	if _, err := tx.Exec("UPDATE fetch_log SET failure_id = ? WHERE id = ?", id, fetchID); err != nil {
+		return fmt.Errorf("failed to reference parse failure: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM parse_failures WHERE source = ? AND id NOT IN (
+			SELECT id FROM parse_failures WHERE source = ? ORDER BY id DESC LIMIT ?
+		)
+	`, log.Source, log.Source, FailuresKept)
+	if err != nil {
+		return fmt.Errorf("failed to prune parse failures: %w", err)
+	}
+	return nil
+}
+
+// ListParseFailures returns up to limit recorded parse failures, newest
+// first, only source's unless source is empty. Payloads are not loaded.
+func (s *Storage) ListParseFailures(source string, limit int) ([]ParseFailure, error) {
+	rows, err := s.db.Query(`
+		SELECT p.id, p.fetch_id, p.source, COALESCE(f.error_message, ''), p.captured_at, p.size, p.stored, length(p.payload)
+		FROM parse_failures p
+		LEFT JOIN fetch_log f ON f.id = p.fetch_id
+		WHERE ? = '' OR p.source = ?
+		ORDER BY p.id DESC
+		LIMIT ?
+	`, source, source, noLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query parse failures: %w", err)
+	}
+	defer rows.Close()
+
+	var failures []ParseFailure
+	for rows.Next() {
+		var failure ParseFailure
+		var capturedAt string
+		if err := rows.Scan(&failure.ID, &failure.FetchID, &failure.Source, &failure.Error, &capturedAt, &failure.Size, &failure.Stored, &failure.Compressed); err != nil {
+			return nil, fmt.Errorf("failed to scan parse failure: %w", err)
+		}
+		if t, err := time.Parse(time.RFC3339, capturedAt); err == nil {
+			failure.CapturedAt = t
+		}
+		failures = append(failures, failure)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating parse failures: %w", err)
+	}
+
+	return failures, nil
+}
+
+// GetParseFailure returns the parse failure id with its decompressed
+// payload, or nil if there is none (it may have been pruned)
+func (s *Storage) GetParseFailure(id int64) (*ParseFailure, error) {
+	var failure ParseFailure
+	var capturedAt string
+	var compressed []byte
+	err := s.db.QueryRow(`
+		SELECT p.id, p.fetch_id, p.source, COALESCE(f.error_message, ''), p.captured_at, p.size, p.stored, p.payload
+		FROM parse_failures p
+		LEFT JOIN fetch_log f ON f.id = p.fetch_id
+		WHERE p.id = ?
+	`, id).Scan(&failure.ID, &failure.FetchID, &failure.Source, &failure.Error, &capturedAt, &failure.Size, &failure.Stored, &compressed)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get parse failure: %w", err)
+	}
+	if t, err := time.Parse(time.RFC3339, capturedAt); err == nil {
+		failure.CapturedAt = t
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress parse failure %d: %w", id, err)
+	}
+	defer zr.Close()
+	if failure.Payload, err = io.ReadAll(zr); err != nil {
+		return nil, fmt.Errorf("failed to decompress parse failure %d: %w", id, err)
+	}
+	failure.Compressed = len(compressed)
+
+	return &failure, nil
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFailures(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	payload := []byte(`{"items": [` + strings.Repeat(`{"title": "x"}, `, 1000))
+	msg := "failed after 3 retries: parse error: malformed JSON: unexpected end of JSON input"
+	err = store.LogFetch(FetchLog{
+		Source:       "GitHub",
+		FetchedAt:    now,
+		Status:       "error",
+		ErrorMessage: &msg,
+		Failure:      CaptureResponse(payload, 4096),
+	})
+	if err != nil {
+		t.Fatalf("LogFetch failed: %v", err)
+	}
+
+	failures, err := store.ListParseFailures("", 0)
+	if err != nil {
+		t.Fatalf("ListParseFailures failed: %v", err)
+	}
+	if len(failures) != 1 {
+		t.Fatalf("expected 1 failure, got %d", len(failures))
+	}
+	f := failures[0]
+	if f.Source != "GitHub" || f.Error != msg || !f.CapturedAt.Equal(now) {
+		t.Errorf("unexpected failure: %+v", f)
+	}
+	if f.Size != len(payload) || f.Stored != 4096 || !f.Truncated() {
+		t.Errorf("expected the first 4096 of %d bytes kept, got %+v", len(payload), f)
+	}
+	if f.Compressed >= f.Stored {
+		t.Errorf("expected the payload compressed, got %d bytes for %d", f.Compressed, f.Stored)
+	}
+	if f.Payload != nil {
+		t.Error("expected listing not to load payloads")
+	}
+
+	// The fetch log entry references the failure
+	var failureID int64
+	if err := store.db.QueryRow("SELECT failure_id FROM fetch_log WHERE id = ?", f.FetchID).Scan(&failureID); err != nil {
+		t.Fatalf("failed to read fetch log: %v", err)
+	}
+	if failureID != f.ID {
+		t.Errorf("expected fetch %d to reference failure %d, got %d", f.FetchID, f.ID, failureID)
+	}
+
+	got, err := store.GetParseFailure(f.ID)
+	if err != nil {
+		t.Fatalf("GetParseFailure failed: %v", err)
+	}
+	if got == nil || !bytes.Equal(got.Payload, payload[:4096]) {
+		t.Error("expected the stored payload back decompressed")
+	}
+
+	missing, err := store.GetParseFailure(f.ID + 1)
+	if err != nil || missing != nil {
+		t.Errorf("expected no failure, got %+v, %v", missing, err)
+	}
+}
+
+func TestParseFailures_Pruned(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	for i := 0; i < FailuresKept+3; i++ {
+		if err := store.LogFetch(FetchLog{Source: "A", FetchedAt: now, Status: "error", Failure: CaptureResponse([]byte("<rss"), 100)}); err != nil {
+			t.Fatalf("LogFetch failed: %v", err)
+		}
+	}
+	if err := store.LogFetch(FetchLog{Source: "B", FetchedAt: now, Status: "error", Failure: CaptureResponse([]byte("{"), 100)}); err != nil {
+		t.Fatalf("LogFetch failed: %v", err)
+	}
+
+	a, err := store.ListParseFailures("A", 0)
+	if err != nil {
+		t.Fatalf("ListParseFailures failed: %v", err)
+	}
+	if len(a) != FailuresKept || a[0].ID != FailuresKept+3 {
+		t.Errorf("expected the newest %d of A's failures, got %d starting at %d", FailuresKept, len(a), a[0].ID)
+	}
+
+	// Pruning one source leaves the others alone
+	b, err := store.ListParseFailures("B", 0)
+	if err != nil {
+		t.Fatalf("ListParseFailures failed: %v", err)
+	}
+	if len(b) != 1 || b[0].Truncated() {
+		t.Errorf("expected B's failure kept whole, got %+v", b)
+	}
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// GetFeedState returns the values stored for feed by previous runs, such
+// as API cursors. A feed with no stored state gets an empty map.
+func (s *Storage) GetFeedState(feed string) (map[string]string, error) {
+	rows, err := s.db.Query("SELECT key, value FROM feed_state WHERE feed = ?", feed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feed state: %w", err)
+	}
+	defer rows.Close()
+
+	state := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan feed state: %w", err)
+		}
+		state[key] = value
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating feed state: %w", err)
+	}
+
+	return state, nil
+}
+
+// SetFeedState stores values for feed, replacing any existing values with
+// the same keys and leaving other keys untouched
+func (s *Storage) SetFeedState(feed string, values map[string]string) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := s.clock.Now().Format(time.RFC3339)
+	for key, value := range values {
+		_, err := tx.Exec(`
+			INSERT INTO feed_state (feed, key, value, updated_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT(feed, key) DO UPDATE SET
+				value = excluded.value,
+				updated_at = excluded.updated_at
+		`, feed, key, value, now)
+		if err != nil {
+			return fmt.Errorf("failed to save feed state: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ClearFeedState forgets everything stored for feed
+func (s *Storage) ClearFeedState(feed string) error {
+	if _, err := s.db.Exec("DELETE FROM feed_state WHERE feed = ?", feed); err != nil {
+		return fmt.Errorf("failed to clear feed state: %w", err)
+	}
+	return nil
+}
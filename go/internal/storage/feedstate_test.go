@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFeedState_SetGetClear(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	state, err := store.GetFeedState("Reddit")
+	if err != nil {
+		t.Fatalf("GetFeedState failed: %v", err)
+	}
+	if len(state) != 0 {
+		t.Fatalf("expected no state for a fresh feed, got %v", state)
+	}
+
+	if err := store.SetFeedState("Reddit", map[string]string{"cursor": "t3_a", "extra": "x"}); err != nil {
+		t.Fatalf("SetFeedState failed: %v", err)
+	}
+	if err := store.SetFeedState("Reddit", map[string]string{"cursor": "t3_b"}); err != nil {
+		t.Fatalf("SetFeedState failed: %v", err)
+	}
+	if err := store.SetFeedState("GitHub", map[string]string{"cursor": "other"}); err != nil {
+		t.Fatalf("SetFeedState failed: %v", err)
+	}
+
+	state, err = store.GetFeedState("Reddit")
+	if err != nil {
+		t.Fatalf("GetFeedState failed: %v", err)
+	}
+	if state["cursor"] != "t3_b" || state["extra"] != "x" || len(state) != 2 {
+		t.Errorf("unexpected state after update: %v", state)
+	}
+
+	if err := store.ClearFeedState("Reddit"); err != nil {
+		t.Fatalf("ClearFeedState failed: %v", err)
+	}
+	if state, _ := store.GetFeedState("Reddit"); len(state) != 0 {
+		t.Errorf("expected state to be cleared, got %v", state)
+	}
+	if state, _ := store.GetFeedState("GitHub"); state["cursor"] != "other" {
+		t.Errorf("clearing one feed affected another: %v", state)
+	}
+}
@@ -0,0 +1,288 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Front page curation states
+const (
+	CurationPinned = "pinned"
+	CurationHidden = "hidden"
+)
+
+// Curation is an editor's decision about an item on the front page
+type Curation struct {
+	Item  FeedItem
+	State string
+	// Position orders pinned items, starting at 1; hidden items have 0
+	Position  int
+	UpdatedAt time.Time
+}
+
+// FrontPageItem is an item on the front page
+type FrontPageItem struct {
+	DigestItem
+	Pinned bool `json:"pinned"`
+}
+
+// Pin pins the stored item itemID to the front page at position (1 is
+// the top), shifting the pins below it down; a position of 0 or past the
+// last pin appends it. Pinning a pinned item moves it, and pinning a
+// hidden one shows it again.
+func (s *Storage) Pin(itemID string, position int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := curatedItemExists(tx, itemID); err != nil {
+		return err
+	}
+
+	pins, err := pinnedIDs(tx)
+	if err != nil {
+		return err
+	}
+	order := make([]string, 0, len(pins)+1)
+	for _, id := range pins {
+		if id != itemID {
+			order = append(order, id)
+		}
+	}
+	if position < 1 || position > len(order) {
+		order = append(order, itemID)
+	} else {
+		order = append(order[:position-1], append([]string{itemID}, order[position-1:]...)...)
+	}
+
+	now := s.clock.Now().Format(time.RFC3339)
+	for i, id := range order {
+		_, err := tx.Exec(`
+			INSERT INTO curations (item_id, state, position, updated_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT(item_id) DO UPDATE SET
+				state = excluded.state,
+				position = excluded.position,
+				updated_at = CASE WHEN curations.state = excluded.state AND curations.position = excluded.position
+					THEN curations.updated_at ELSE excluded.updated_at END
+		`, id, CurationPinned, i+1, now)
+		if err != nil {
+			return fmt.Errorf("failed to pin item: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Hide keeps the stored item itemID, and any item sharing its URL, off the
+// front page, unpinning it if it was pinned
+func (s *Storage) Hide(itemID string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := curatedItemExists(tx, itemID); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO curations (item_id, state, position, updated_at) VALUES (?, ?, 0, ?)
+		ON CONFLICT(item_id) DO UPDATE SET state = excluded.state, position = 0, updated_at = excluded.updated_at
+	`, itemID, CurationHidden, s.clock.Now().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("failed to hide item: %w", err)
+	}
+
+	if err := renumberPins(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Uncurate unpins or unhides itemID, returning false if it was neither
+func (s *Storage) Uncurate(itemID string) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("DELETE FROM curations WHERE item_id = ?", itemID)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove curation: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove curation: %w", err)
+	}
+
+	if err := renumberPins(tx); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ListCurations returns the pinned items in order, then the hidden ones,
+// oldest decision first. Curations of items no longer stored (e.g.
+// blocked since) are left out.
+func (s *Storage) ListCurations() ([]Curation, error) {
+	rows, err := s.db.Query(`
+		SELECT c.state, c.position, c.updated_at,
+			i.id, i.title, i.url, i.source, i.timestamp, i.tags, i.created_at
+		FROM curations c
+		JOIN feed_items i ON i.id = c.item_id
+		ORDER BY c.state = 'hidden', c.position, julianday(c.updated_at), c.item_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query curations: %w", err)
+	}
+	defer rows.Close()
+
+	var curations []Curation
+	for rows.Next() {
+		var c Curation
+		var updatedAt, createdAt string
+		var tags *string
+		if err := rows.Scan(&c.State, &c.Position, &updatedAt,
+			&c.Item.ID, &c.Item.Title, &c.Item.URL, &c.Item.Source, &c.Item.Timestamp, &tags, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan curation: %w", err)
+		}
+		if tags != nil {
+			if err := json.Unmarshal([]byte(*tags), &c.Item.Tags); err != nil {
+				return nil, fmt.Errorf("invalid tags for item %s: %w", c.Item.ID, err)
+			}
+		}
+		if t, err := time.Parse(time.RFC3339, updatedAt); err == nil {
+			c.UpdatedAt = t
+		}
+		if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
+			c.Item.CreatedAt = t
+		}
+		curations = append(curations, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating curations: %w", err)
+	}
+
+	return curations, nil
+}
+
+// curatedItemExists returns an error unless itemID is a stored item
+func curatedItemExists(tx *sql.Tx, itemID string) error {
+	var exists bool
+	if err := tx.QueryRow("SELECT COUNT(*) > 0 FROM feed_items WHERE id = ?", itemID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check item: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("item not found: %s", itemID)
+	}
+	return nil
+}
+
+// pinnedIDs returns the pinned item IDs in order
+func pinnedIDs(tx *sql.Tx) ([]string, error) {
+	rows, err := tx.Query("SELECT item_id FROM curations WHERE state = ? ORDER BY position, item_id", CurationPinned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pins: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan pin: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pins: %w", err)
+	}
+	return ids, nil
+}
+
+// renumberPins closes gaps in the pin positions left by a removed pin
+func renumberPins(tx *sql.Tx) error {
+	ids, err := pinnedIDs(tx)
+	if err != nil {
+		return err
+	}
+	for i, id := range ids {
+		if _, err := tx.Exec("UPDATE curations SET position = ? WHERE item_id = ?", i+1, id); err != nil {
+			return fmt.Errorf("failed to reorder pins: %w", err)
+		}
+	}
+	return nil
+}
+
+// FrontPage lays out the front page: pinned items in their order, then
+// the ranked items (see TopItems) that aren't pinned or hidden, up to
+// limit items in all (pins are always shown). An item is hidden or pinned
+// along with every item sharing its URL.
+func FrontPage(ranked []DigestItem, curations []Curation, limit int) []FrontPageItem {
+	hidden := make(map[string]bool)
+	pinned := make(map[string]bool)
+	for _, c := range curations {
+		key := c.Item.URL
+		if key == "" {
+			key = c.Item.ID
+		}
+		if c.State == CurationHidden {
+			hidden[key] = true
+		} else {
+			pinned[key] = true
+		}
+	}
+	keyOf := func(item FeedItem) string {
+		if item.URL == "" {
+			return item.ID
+		}
+		return item.URL
+	}
+
+	rankedByKey := make(map[string]DigestItem, len(ranked))
+	for _, item := range ranked {
+		rankedByKey[keyOf(item.FeedItem)] = item
+	}
+
+	var page []FrontPageItem
+	for _, c := range curations {
+		if c.State != CurationPinned {
+			continue
+		}
+		item, ok := rankedByKey[keyOf(c.Item)]
+		if !ok {
+			item = DigestItem{FeedItem: c.Item, Sources: []string{c.Item.Source}}
+		}
+		page = append(page, FrontPageItem{DigestItem: item, Pinned: true})
+	}
+
+	for _, item := range ranked {
+		if limit > 0 && len(page) >= limit {
+			break
+		}
+		key := keyOf(item.FeedItem)
+		if hidden[key] || pinned[key] {
+			continue
+		}
+		page = append(page, FrontPageItem{DigestItem: item})
+	}
+	return page
+}
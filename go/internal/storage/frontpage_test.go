@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCurations_PinHideReorder(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	var items []FeedItem
+	for _, id := range []string{"a", "b", "c"} {
+		items = append(items, FeedItem{ID: id, Title: id, URL: "https://example.com/" + id, Source: "HN", CreatedAt: now})
+	}
+	if err := store.SaveItems(items); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"a", "b"} {
+		if err := store.Pin(id, 0); err != nil {
+			t.Fatalf("Pin failed: %v", err)
+		}
+	}
+	// Pinning again moves: c goes on top, then b moves to the top
+	if err := store.Pin("c", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Pin("b", 1); err != nil {
+		t.Fatal(err)
+	}
+	assertPins(t, store, "b", "c", "a")
+
+	if err := store.Hide("c"); err != nil {
+		t.Fatalf("Hide failed: %v", err)
+	}
+	assertPins(t, store, "b", "a")
+
+	if ok, err := store.Uncurate("b"); err != nil || !ok {
+		t.Fatalf("expected b to be unpinned, got %v, %v", ok, err)
+	}
+	if ok, _ := store.Uncurate("b"); ok {
+		t.Error("expected a second Uncurate to report nothing removed")
+	}
+	assertPins(t, store, "a")
+
+	if err := store.Pin("missing", 0); err == nil {
+		t.Error("expected pinning a missing item to fail")
+	}
+
+	curations, err := store.ListCurations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := curations[len(curations)-1]
+	if last.Item.ID != "c" || last.State != CurationHidden {
+		t.Errorf("expected hidden c last, got %+v", last)
+	}
+}
+
+// assertPins checks the pinned item IDs in order
+func assertPins(t *testing.T, store *Storage, want ...string) {
+	t.Helper()
+	curations, err := store.ListCurations()
+	if err != nil {
+		t.Fatalf("ListCurations failed: %v", err)
+	}
+	var got []string
+	for i, c := range curations {
+		if c.State == CurationPinned {
+			if c.Position != i+1 {
+				t.Errorf("expected %s at position %d, got %d", c.Item.ID, i+1, c.Position)
+			}
+			got = append(got, c.Item.ID)
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected pins %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected pins %v, got %v", want, got)
+		}
+	}
+}
+
+func TestFrontPage(t *testing.T) {
+	now := time.Now()
+	item := func(id, source, url string) FeedItem {
+		return FeedItem{ID: id, Title: id, URL: url, Source: source, CreatedAt: now}
+	}
+	ranked := TopItems([]FeedItem{
+		item("hot", "HN", "https://example.com/hot"),
+		item("hot2", "Lobsters", "https://example.com/hot"),
+		item("spam", "HN", "https://example.com/spam"),
+		item("new", "HN", "https://example.com/new"),
+		item("other", "HN", "https://example.com/other"),
+	}, 0)
+
+	curations := []Curation{
+		{Item: item("old", "HN", "https://example.com/old"), State: CurationPinned, Position: 1},
+		{Item: item("hot2", "Lobsters", "https://example.com/hot"), State: CurationPinned, Position: 2},
+		{Item: item("spam", "HN", "https://example.com/spam"), State: CurationHidden},
+	}
+
+	page := FrontPage(ranked, curations, 3)
+	var ids []string
+	for _, p := range page {
+		ids = append(ids, p.ID)
+	}
+	// Pins first (an old pin outside the ranking included; a pinned copy
+	// takes the merged item's place), then the rest minus hidden
+	want := []string{"old", "hot", "new"}
+	if len(ids) != len(want) {
+		t.Fatalf("expected %v, got %v", want, ids)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, ids)
+		}
+	}
+	if !page[0].Pinned || !page[1].Pinned || page[2].Pinned {
+		t.Errorf("unexpected pinned flags: %+v", page)
+	}
+	if len(page[1].Sources) != 2 {
+		t.Errorf("expected the pinned item to keep its merged sources, got %v", page[1].Sources)
+	}
+}
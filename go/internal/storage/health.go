@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// busyMessages and corruptMessages are how SQLite reports a locked or
+// damaged database through either driver
+var (
+	busyMessages    = []string{"database is locked", "database table is locked", "SQLITE_BUSY", "SQLITE_LOCKED"}
+	corruptMessages = []string{"database disk image is malformed", "file is not a database", "SQLITE_CORRUPT", "SQLITE_NOTADB"}
+)
+
+// IsBusy reports whether err is SQLite giving up on a lock another
+// connection (usually another feedpulse process) held past busy_timeout
+func IsBusy(err error) bool {
+	return errorMentions(err, busyMessages)
+}
+
+// IsCorrupt reports whether err is SQLite finding the database file
+// damaged or not a database at all
+func IsCorrupt(err error) bool {
+	return errorMentions(err, corruptMessages)
+}
+
+// errorMentions reports whether err's message contains any of messages
+func errorMentions(err error, messages []string) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	for _, m := range messages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckIntegrity runs SQLite's integrity_check on the database at dbPath
+// and returns the problems it reports, none if the database is sound. It
+// opens the file without touching the schema, so it works on databases
+// NewStorage refuses.
+func CheckIntegrity(dbPath string) ([]string, error) {
+	db, err := openRaw(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query("PRAGMA integrity_check")
+	if err != nil {
+		if IsCorrupt(err) {
+			return []string{err.Error()}, nil
+		}
+		return nil, fmt.Errorf("failed to check database: %w", err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, fmt.Errorf("failed to scan integrity check: %w", err)
+		}
+		// Problems may come several to a row, under a "*** in database
+		// main ***" heading
+		for _, problem := range strings.Split(line, "\n") {
+			if problem != "ok" && problem != "" && !strings.HasPrefix(problem, "***") {
+				problems = append(problems, problem)
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		if IsCorrupt(err) {
+			return append(problems, err.Error()), nil
+		}
+		return nil, fmt.Errorf("error iterating integrity check: %w", err)
+	}
+
+	return problems, nil
+}
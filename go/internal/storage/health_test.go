@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsBusyIsCorrupt(t *testing.T) {
+	tests := []struct {
+		err           error
+		busy, corrupt bool
+	}{
+		{nil, false, false},
+		{errors.New("no such table: feed_items"), false, false},
+		{fmt.Errorf("failed to save: %w", errors.New("database is locked")), true, false},
+		{errors.New("database is locked (5) (SQLITE_BUSY)"), true, false},
+		{errors.New("database disk image is malformed"), false, true},
+		{errors.New("file is not a database (26)"), false, true},
+	}
+	for _, tt := range tests {
+		if got := IsBusy(tt.err); got != tt.busy {
+			t.Errorf("IsBusy(%v) = %v, want %v", tt.err, got, tt.busy)
+		}
+		if got := IsCorrupt(tt.err); got != tt.corrupt {
+			t.Errorf("IsCorrupt(%v) = %v, want %v", tt.err, got, tt.corrupt)
+		}
+	}
+}
+
+func TestCorruptDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	garbage := make([]byte, 8192)
+	for i := range garbage {
+		garbage[i] = byte(i * 7)
+	}
+	if err := os.WriteFile(path, garbage, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := NewStorage(path)
+	if !IsCorrupt(err) {
+		t.Errorf("NewStorage on a non-database = %v, want a corruption error", err)
+	}
+
+	problems, err := CheckIntegrity(path)
+	if err != nil {
+		t.Fatalf("CheckIntegrity failed: %v", err)
+	}
+	if len(problems) == 0 {
+		t.Error("expected CheckIntegrity to report problems")
+	}
+}
+
+func TestCheckIntegrity_Sound(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	store, err := NewStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	problems, err := CheckIntegrity(path)
+	if err != nil || len(problems) != 0 {
+		t.Errorf("CheckIntegrity = %v, %v; want no problems", problems, err)
+	}
+}
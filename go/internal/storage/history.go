@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// FetchLogFilter selects the fetch log entries ListFetchLog returns. Zero
+// fields don't filter.
+type FetchLogFilter struct {
+	Source string
+	Status string
+	Limit  int
+	// Oldest lists entries oldest first instead of newest first
+	Oldest bool
+	// After continues a listing past the entry it points at
+	After *FetchLogCursor
+}
+
+// FetchLogCursor is the last entry of a page, for the next page to start
+// after. Entries logged in the same second are ordered by ID.
+type FetchLogCursor struct {
+	FetchedAt time.Time
+	ID        int
+}
+
+// ListFetchLog returns the fetch log entries matching filter, newest first
+// unless filter.Oldest is set
+func (s *Storage) ListFetchLog(filter FetchLogFilter) ([]FetchLog, error) {
+	order, op := "julianday(fetched_at) DESC, id DESC", "<"
+	if filter.Oldest {
+		order, op = "julianday(fetched_at), id", ">"
+	}
+
+	query := "SELECT id, source, fetched_at, status, items_count, error_message, duration_ms, endpoint, slow FROM fetch_log WHERE 1 = 1"
+	var args []interface{}
+	if filter.Source != "" {
+		query += " AND source = ?"
+		args = append(args, filter.Source)
+	}
+	if filter.Status != "" {
+		query += " AND status = ?"
+		args = append(args, filter.Status)
+	}
+	if after := filter.After; after != nil {
+		at := after.FetchedAt.UTC().Format(time.RFC3339)
+		query += " AND (julianday(fetched_at) " + op + " julianday(?) OR (julianday(fetched_at) = julianday(?) AND id " + op + " ?))"
+		args = append(args, at, at, after.ID)
+	}
+	query += " ORDER BY " + order + " LIMIT ?"
+	args = append(args, noLimit(filter.Limit))
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query fetch log: %w", err)
+	}
+	defer rows.Close()
+
+	var logs []FetchLog
+	for rows.Next() {
+		var log FetchLog
+		var fetchedAt string
+		var endpoint sql.NullString
+		if err := rows.Scan(&log.ID, &log.Source, &fetchedAt, &log.Status, &log.ItemsCount, &log.ErrorMessage, &log.DurationMs, &endpoint, &log.Slow); err != nil {
+			return nil, fmt.Errorf("failed to scan fetch log entry: %w", err)
+		}
+		if t, err := time.Parse(time.RFC3339, fetchedAt); err == nil {
+			log.FetchedAt = t
+		}
+		log.Endpoint = endpoint.String
+		logs = append(logs, log)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating fetch log: %w", err)
+	}
+
+	return logs, nil
+}
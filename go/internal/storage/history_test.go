@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestListFetchLog(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, log := range []FetchLog{
+		{Source: "HN", FetchedAt: now.Add(-2 * time.Hour), Status: "success", ItemsCount: 30},
+		{Source: "Lobsters", FetchedAt: now.Add(-time.Hour), Status: "error"},
+		{Source: "HN", FetchedAt: now.Add(-time.Hour), Status: "success", ItemsCount: 28, Endpoint: "https://hn.example.com"},
+		{Source: "HN", FetchedAt: now, Status: "error"},
+	} {
+		if err := store.LogFetch(log); err != nil {
+			t.Fatalf("LogFetch failed: %v", err)
+		}
+	}
+
+	ids := func(logs []FetchLog) []int {
+		out := []int{}
+		for _, log := range logs {
+			out = append(out, log.ID)
+		}
+		return out
+	}
+	tests := []struct {
+		name   string
+		filter FetchLogFilter
+		want   []int
+	}{
+		{"all, newest first", FetchLogFilter{}, []int{4, 3, 2, 1}},
+		{"oldest first", FetchLogFilter{Oldest: true}, []int{1, 2, 3, 4}},
+		{"source and status", FetchLogFilter{Source: "HN", Status: "success"}, []int{3, 1}},
+		{"limit", FetchLogFilter{Limit: 2}, []int{4, 3}},
+		{"after a cursor", FetchLogFilter{Limit: 2, After: &FetchLogCursor{FetchedAt: now.Add(-time.Hour), ID: 3}}, []int{2, 1}},
+		{"after a cursor, oldest first", FetchLogFilter{Oldest: true, After: &FetchLogCursor{FetchedAt: now.Add(-time.Hour), ID: 2}}, []int{3, 4}},
+	}
+	for _, tt := range tests {
+		logs, err := store.ListFetchLog(tt.filter)
+		if err != nil {
+			t.Fatalf("%s: ListFetchLog failed: %v", tt.name, err)
+		}
+		if got := ids(logs); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: got entries %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	logs, err := store.ListFetchLog(FetchLogFilter{Source: "HN", Status: "success", Limit: 1})
+	if err != nil {
+		t.Fatalf("ListFetchLog failed: %v", err)
+	}
+	if log := logs[0]; log.ItemsCount != 28 || log.Endpoint != "https://hn.example.com" || !log.FetchedAt.Equal(now.Add(-time.Hour)) {
+		t.Errorf("unexpected entry: %+v", log)
+	}
+}
@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"sync"
+)
+
+// Item ID hashes. The hash turns an item's uniqueness key into its ID.
+const (
+	// IDHashSHA256 gives 64 hex digit IDs (the default)
+	IDHashSHA256 = "sha256"
+	// IDHashXXH64 gives 16 hex digit IDs, cheaper to compute and store.
+	// Collisions are only likely past billions of items per scope.
+	IDHashXXH64 = "xxhash64"
+)
+
+// keyBuffers holds buffers item uniqueness keys are assembled in, so
+// deriving an ID only allocates the returned string
+var keyBuffers = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 256)
+		return &b
+	},
+}
+
+// ItemID derives a deterministic item ID for the given uniqueness scope.
+// runID is only consulted for ScopeRun; an empty scope means ScopeSource.
+func ItemID(scope, source, url, runID string) string {
+	return ItemIDWith(IDHashSHA256, scope, source, url, runID)
+}
+
+// ItemIDWith derives an item ID like ItemID, using idHash (an empty
+// idHash means IDHashSHA256)
+func ItemIDWith(idHash, scope, source, url, runID string) string {
+	bp := keyBuffers.Get().(*[]byte)
+	key := (*bp)[:0]
+	switch scope {
+	case ScopeGlobal:
+		key = append(key, url...)
+	case ScopeRun:
+		key = append(key, source...)
+		key = append(key, url...)
+		key = append(key, 0)
+		key = append(key, runID...)
+	default:
+		key = append(key, source...)
+		key = append(key, url...)
+	}
+
+	var id string
+	if idHash == IDHashXXH64 {
+		var sum [8]byte
+		var out [16]byte
+		binary.BigEndian.PutUint64(sum[:], xxh64(key))
+		hex.Encode(out[:], sum[:])
+		id = string(out[:])
+	} else {
+		var out [64]byte
+		sum := sha256.Sum256(key)
+		hex.Encode(out[:], sum[:])
+		id = string(out[:])
+	}
+
+	*bp = key
+	keyBuffers.Put(bp)
+	return id
+}
+
+// idHashKey is the meta key recording the ID hash of source's items
+func idHashKey(source string) string {
+	return "id_hash:" + source
+}
+
+// storedIDHash returns the ID hash source's items were written with
+func storedIDHash(q interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}, source string) (string, error) {
+	var idHash string
+	err := q.QueryRow("SELECT value FROM meta WHERE key = ?", idHashKey(source)).Scan(&idHash)
+	if err == sql.ErrNoRows {
+		return IDHashSHA256, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read id hash: %w", err)
+	}
+	return idHash, nil
+}
+
+// SetIDHashes switches each source in hashes (source → ID hash, empty
+// meaning IDHashSHA256) to its ID hash, re-keying the source's stored
+// items when it changed. Items stored under the run scope keep their IDs,
+// since the run they were keyed with is gone. It returns the number of
+// rows removed as duplicates.
+func (s *Storage) SetIDHashes(hashes map[string]string) (int, error) {
+	scope, err := s.UniquenessScope()
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	dedup, err := storedDedup(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for source, idHash := range hashes {
+		switch idHash {
+		case "":
+			idHash = IDHashSHA256
+		case IDHashSHA256, IDHashXXH64:
+		default:
+			return 0, fmt.Errorf("unknown id hash: %s", idHash)
+		}
+
+		current, err := storedIDHash(tx, source)
+		if err != nil {
+			return 0, err
+		}
+		if current == idHash {
+			continue
+		}
+
+		if scope != ScopeRun {
+			idFor := func(source, url string) string {
+				return ItemIDWith(idHash, scope, source, KeyURL(dedup, url), "")
+			}
+			n, err := rekeyItems(tx, idFor, source)
+			if err != nil {
+				return 0, err
+			}
+			removed += n
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO meta (key, value) VALUES (?, ?)
+			ON CONFLICT(key) DO UPDATE SET value = excluded.value
+		`, idHashKey(source), idHash)
+		if err != nil {
+			return 0, fmt.Errorf("failed to record id hash: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if removed > 0 {
+		if err := s.RebuildSourceStats(); err != nil {
+			return removed, err
+		}
+	}
+
+	return removed, nil
+}
+
+// XXH64 primes
+const (
+	xxPrime1 uint64 = 11400714785074694791
+	xxPrime2 uint64 = 14029467366897019727
+	xxPrime3 uint64 = 1609587929392839161
+	xxPrime4 uint64 = 9650029242287828579
+	xxPrime5 uint64 = 2870177450012600261
+)
+
+// xxh64 returns the XXH64 hash of b with seed 0
+func xxh64(b []byte) uint64 {
+	n := len(b)
+	var h uint64
+
+	if n >= 32 {
+		p1, p2 := xxPrime1, xxPrime2
+		v1 := p1 + p2
+		v2 := p2
+		v3 := uint64(0)
+		v4 := -p1
+		for len(b) >= 32 {
+			v1 = xxRound(v1, binary.LittleEndian.Uint64(b[0:8]))
+			v2 = xxRound(v2, binary.LittleEndian.Uint64(b[8:16]))
+			v3 = xxRound(v3, binary.LittleEndian.Uint64(b[16:24]))
+			v4 = xxRound(v4, binary.LittleEndian.Uint64(b[24:32]))
+			b = b[32:]
+		}
+		h = bits.RotateLeft64(v1, 1) + bits.RotateLeft64(v2, 7) + bits.RotateLeft64(v3, 12) + bits.RotateLeft64(v4, 18)
+		h = xxMergeRound(h, v1)
+		h = xxMergeRound(h, v2)
+		h = xxMergeRound(h, v3)
+		h = xxMergeRound(h, v4)
+	} else {
+		h = xxPrime5
+	}
+
+	h += uint64(n)
+
+	for len(b) >= 8 {
+		h ^= xxRound(0, binary.LittleEndian.Uint64(b[:8]))
+		h = bits.RotateLeft64(h, 27)*xxPrime1 + xxPrime4
+		b = b[8:]
+	}
+	if len(b) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(b[:4])) * xxPrime1
+		h = bits.RotateLeft64(h, 23)*xxPrime2 + xxPrime3
+		b = b[4:]
+	}
+	for _, c := range b {
+		h ^= uint64(c) * xxPrime5
+		h = bits.RotateLeft64(h, 11) * xxPrime1
+	}
+
+	h ^= h >> 33
+	h *= xxPrime2
+	h ^= h >> 29
+	h *= xxPrime3
+	h ^= h >> 32
+	return h
+}
+
+// xxRound mixes one 8-byte lane into an accumulator
+func xxRound(acc, lane uint64) uint64 {
+	acc += lane * xxPrime2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * xxPrime1
+}
+
+// xxMergeRound folds an accumulator into the hash
+func xxMergeRound(h, v uint64) uint64 {
+	h ^= xxRound(0, v)
+	return h*xxPrime1 + xxPrime4
+}
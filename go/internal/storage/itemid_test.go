@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestXXH64_KnownVectors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  uint64
+	}{
+		{"", 0xef46db3751d8e999},
+		{"a", 0xd24ec4f1a98c6e5b},
+		{"abc", 0x44bc2cf5ad770999},
+		// Longer than 32 bytes, so it takes the striped path
+		{"The quick brown fox jumps over the lazy dog", 0x0b242d361fda71bc},
+	}
+
+	for _, tt := range tests {
+		if got := xxh64([]byte(tt.input)); got != tt.want {
+			t.Errorf("xxh64(%q) = %016x, want %016x", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestItemIDWith_MatchesItemID(t *testing.T) {
+	url := "https://example.com/a"
+	for _, scope := range []string{ScopeGlobal, ScopeSource, ScopeRun} {
+		if ItemIDWith("", scope, "A", url, "run1") != ItemID(scope, "A", url, "run1") {
+			t.Errorf("%s: default hash must match ItemID", scope)
+		}
+		if ItemIDWith(IDHashSHA256, scope, "A", url, "run1") != ItemID(scope, "A", url, "run1") {
+			t.Errorf("%s: sha256 must match ItemID", scope)
+		}
+	}
+
+	id := ItemIDWith(IDHashXXH64, ScopeSource, "A", url, "")
+	if len(id) != 16 {
+		t.Errorf("expected a 16 digit xxhash64 ID, got %q", id)
+	}
+	if id != ItemIDWith(IDHashXXH64, ScopeSource, "A", url, "") {
+		t.Error("xxhash64 IDs must be deterministic")
+	}
+}
+
+func TestItemIDWith_NoCollisions(t *testing.T) {
+	const n = 200000
+
+	for _, idHash := range []string{IDHashSHA256, IDHashXXH64} {
+		seen := make(map[string]string, 2*n)
+		for i := 0; i < n; i++ {
+			url := fmt.Sprintf("https://example.com/items/%d", i)
+			for _, source := range []string{"HackerNews", "Lobsters"} {
+				id := ItemIDWith(idHash, ScopeSource, source, url, "")
+				key := source + " " + url
+				if prev, ok := seen[id]; ok {
+					t.Fatalf("%s: %s and %s share ID %s", idHash, prev, key, id)
+				}
+				seen[id] = key
+			}
+		}
+	}
+}
+
+func TestItemIDWith_Allocations(t *testing.T) {
+	url := "https://example.com/items/12345?utm_source=feedpulse"
+
+	for _, idHash := range []string{IDHashSHA256, IDHashXXH64} {
+		allocs := testing.AllocsPerRun(100, func() {
+			ItemIDWith(idHash, ScopeRun, "HackerNews", url, "20260301T120000Z")
+		})
+		// The returned string is the only allocation
+		if allocs > 1 {
+			t.Errorf("%s: expected at most 1 allocation per ID, got %.1f", idHash, allocs)
+		}
+	}
+}
+
+func TestSetIDHashes_RekeysChangedSources(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	store.SaveItems([]FeedItem{
+		{ID: ItemID(ScopeSource, "A", "https://example.com/a", ""), Title: "a", URL: "https://example.com/a", Source: "A", CreatedAt: now},
+		{ID: ItemID(ScopeSource, "B", "https://example.com/b", ""), Title: "b", URL: "https://example.com/b", Source: "B", CreatedAt: now},
+	})
+
+	if _, err := store.SetIDHashes(map[string]string{"A": IDHashXXH64, "B": ""}); err != nil {
+		t.Fatalf("SetIDHashes failed: %v", err)
+	}
+
+	var title string
+	if err := store.db.QueryRow("SELECT title FROM feed_items WHERE id = ?", ItemIDWith(IDHashXXH64, ScopeSource, "A", "https://example.com/a", "")).Scan(&title); err != nil {
+		t.Errorf("A's item not found under its xxhash64 ID: %v", err)
+	}
+	if err := store.db.QueryRow("SELECT title FROM feed_items WHERE id = ?", ItemID(ScopeSource, "B", "https://example.com/b", "")).Scan(&title); err != nil {
+		t.Errorf("B's item should keep its sha256 ID: %v", err)
+	}
+
+	// Switching scope afterwards keeps each source's hash
+	if _, err := store.SetUniquenessScope(ScopeGlobal); err != nil {
+		t.Fatalf("SetUniquenessScope failed: %v", err)
+	}
+	if err := store.db.QueryRow("SELECT title FROM feed_items WHERE id = ?", ItemIDWith(IDHashXXH64, ScopeGlobal, "A", "https://example.com/a", "")).Scan(&title); err != nil {
+		t.Errorf("A's item not found under its global xxhash64 ID: %v", err)
+	}
+
+	// Switching back restores the sha256 ID
+	if _, err := store.SetIDHashes(map[string]string{"A": IDHashSHA256}); err != nil {
+		t.Fatalf("SetIDHashes failed: %v", err)
+	}
+	if err := store.db.QueryRow("SELECT title FROM feed_items WHERE id = ?", ItemID(ScopeGlobal, "A", "https://example.com/a", "")).Scan(&title); err != nil {
+		t.Errorf("A's item not found under its sha256 ID: %v", err)
+	}
+}
+
+func TestSetIDHashes_RejectsUnknown(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	if _, err := store.SetIDHashes(map[string]string{"A": "md5"}); err == nil {
+		t.Error("expected an error for an unknown id hash")
+	}
+}
+
+func BenchmarkItemIDWith(b *testing.B) {
+	url := "https://example.com/items/12345"
+	for _, idHash := range []string{IDHashSHA256, IDHashXXH64} {
+		b.Run(idHash, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				ItemIDWith(idHash, ScopeSource, "HackerNews", url, "")
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Orders GetItems can list items in
+const (
+	SortNewest = "newest"
+	SortOldest = "oldest"
+	SortTitle  = "title"
+)
+
+// ItemSorts lists the valid ItemFilter.Sort values
+var ItemSorts = []string{SortNewest, SortOldest, SortTitle}
+
+// ItemFilter selects the items GetItems returns. Zero fields don't
+// filter; times are compared to when items were stored.
+type ItemFilter struct {
+	Source string
+	// Tag matches case-insensitively
+	Tag   string
+	Since time.Time
+	// Until is exclusive
+	Until  time.Time
+	Limit  int
+	Offset int
+	// Sort is one of ItemSorts; empty means SortNewest
+	Sort string
+	// RawData includes each item's raw data, which is left out otherwise
+	RawData bool
+	// After continues a SortNewest or SortOldest listing past the item it
+	// points at
+	After *ItemCursor
+}
+
+// ItemCursor is the last item of a page, for the next page to start after.
+// Items stored in the same second are ordered by ID.
+type ItemCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// GetItems returns the stored items matching filter
+func (s *Storage) GetItems(filter ItemFilter) ([]FeedItem, error) {
+	var order string
+	switch filter.Sort {
+	case SortNewest, "":
+		order = "julianday(created_at) DESC, id"
+	case SortOldest:
+		order = "julianday(created_at), id"
+	case SortTitle:
+		if filter.After != nil {
+			return nil, fmt.Errorf("cursors need the %s or %s sort order", SortNewest, SortOldest)
+		}
+		order = "lower(title), id"
+	default:
+		return nil, fmt.Errorf("unknown sort order: %s", filter.Sort)
+	}
+
+	rawData := "NULL"
+	if filter.RawData {
+		rawData = "raw_data"
+	}
+	where, args := itemConditions(filter)
+	query := "SELECT id, title, url, source, timestamp, tags, " + rawData + ", created_at, read_at, starred, duplicate_of FROM feed_items WHERE " + where
+	if after := filter.After; after != nil {
+		op := "<"
+		if filter.Sort == SortOldest {
+			op = ">"
+		}
+		at := after.CreatedAt.UTC().Format(time.RFC3339)
+		query += " AND (julianday(created_at) " + op + " julianday(?) OR (julianday(created_at) = julianday(?) AND id > ?))"
+		args = append(args, at, at, after.ID)
+	}
+	query += " ORDER BY " + order + " LIMIT ? OFFSET ?"
+	args = append(args, noLimit(filter.Limit), filter.Offset)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []FeedItem
+	for rows.Next() {
+		var item FeedItem
+		var tags, readAt, duplicateOf *string
+		var createdAt string
+		if err := rows.Scan(&item.ID, &item.Title, &item.URL, &item.Source, &item.Timestamp, &tags, &item.RawData, &createdAt, &readAt, &item.Starred, &duplicateOf); err != nil {
+			return nil, fmt.Errorf("failed to scan item: %w", err)
+		}
+		if readAt != nil {
+			if t, err := time.Parse(time.RFC3339, *readAt); err == nil {
+				item.ReadAt = &t
+			}
+		}
+		if duplicateOf != nil {
+			item.DuplicateOf = *duplicateOf
+		}
+		if tags != nil {
+			if err := json.Unmarshal([]byte(*tags), &item.Tags); err != nil {
+				return nil, fmt.Errorf("invalid tags for item %s: %w", item.ID, err)
+			}
+		}
+		if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
+			item.CreatedAt = t
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating items: %w", err)
+	}
+
+	return items, nil
+}
+
+// itemConditions is the WHERE clause selecting the items filter matches
+// by source, tag and when they were stored, and its arguments
+func itemConditions(filter ItemFilter) (string, []interface{}) {
+	where := "1 = 1"
+	var args []interface{}
+	if filter.Source != "" {
+		where += " AND source = ?"
+		args = append(args, filter.Source)
+	}
+	if filter.Tag != "" {
+		where += " AND EXISTS (SELECT 1 FROM json_each(feed_items.tags) WHERE lower(value) = lower(?))"
+		args = append(args, filter.Tag)
+	}
+	if !filter.Since.IsZero() {
+		where += " AND julianday(created_at) >= julianday(?)"
+		args = append(args, filter.Since.UTC().Format(time.RFC3339))
+	}
+	if !filter.Until.IsZero() {
+		where += " AND julianday(created_at) < julianday(?)"
+		args = append(args, filter.Until.UTC().Format(time.RFC3339))
+	}
+	return where, args
+}
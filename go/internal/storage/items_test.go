@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGetItems_FiltersSortsAndPages(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	raw := `{"title":"gamma"}`
+	if err := store.SaveItems([]FeedItem{
+		{ID: "1", Title: "beta", URL: "https://example.com/1", Source: "HN", Tags: []string{"Go"}, CreatedAt: now.Add(-3 * time.Hour)},
+		{ID: "2", Title: "Alpha", URL: "https://example.com/2", Source: "HN", CreatedAt: now.Add(-2 * time.Hour)},
+		{ID: "3", Title: "gamma", URL: "https://example.com/3", Source: "Lobsters", Tags: []string{"go"}, RawData: &raw, CreatedAt: now.Add(-time.Hour)},
+	}); err != nil {
+		t.Fatalf("SaveItems failed: %v", err)
+	}
+
+	ids := func(items []FeedItem) string {
+		var s string
+		for _, item := range items {
+			s += item.ID
+		}
+		return s
+	}
+	tests := []struct {
+		name   string
+		filter ItemFilter
+		want   string
+	}{
+		{"all, newest first", ItemFilter{}, "321"},
+		{"oldest first", ItemFilter{Sort: SortOldest}, "123"},
+		{"by title", ItemFilter{Sort: SortTitle}, "213"},
+		{"source", ItemFilter{Source: "HN"}, "21"},
+		{"tag", ItemFilter{Tag: "GO"}, "31"},
+		{"time range", ItemFilter{Since: now.Add(-150 * time.Minute), Until: now.Add(-time.Hour)}, "2"},
+		{"page", ItemFilter{Limit: 1, Offset: 1}, "2"},
+		{"offset without limit", ItemFilter{Offset: 2}, "1"},
+		{"after a cursor", ItemFilter{After: &ItemCursor{CreatedAt: now.Add(-2 * time.Hour), ID: "2"}}, "1"},
+		{"after a cursor, oldest first", ItemFilter{Sort: SortOldest, After: &ItemCursor{CreatedAt: now.Add(-2 * time.Hour), ID: "2"}}, "3"},
+		{"cursor in the same second", ItemFilter{After: &ItemCursor{CreatedAt: now.Add(-2 * time.Hour), ID: "0"}}, "21"},
+	}
+	for _, tt := range tests {
+		items, err := store.GetItems(tt.filter)
+		if err != nil {
+			t.Fatalf("%s: GetItems failed: %v", tt.name, err)
+		}
+		if got := ids(items); got != tt.want {
+			t.Errorf("%s: got items %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	items, err := store.GetItems(ItemFilter{Source: "Lobsters"})
+	if err != nil {
+		t.Fatalf("GetItems failed: %v", err)
+	}
+	if items[0].RawData != nil {
+		t.Error("expected raw data to be left out")
+	}
+	items, err = store.GetItems(ItemFilter{Source: "Lobsters", RawData: true})
+	if err != nil {
+		t.Fatalf("GetItems failed: %v", err)
+	}
+	if items[0].RawData == nil || *items[0].RawData != raw {
+		t.Errorf("expected the raw data, got %v", items[0].RawData)
+	}
+
+	if _, err := store.GetItems(ItemFilter{Sort: "random"}); err == nil {
+		t.Error("expected an error for an unknown sort order")
+	}
+	if _, err := store.GetItems(ItemFilter{Sort: SortTitle, After: &ItemCursor{CreatedAt: now, ID: "1"}}); err == nil {
+		t.Error("expected an error for a cursor in title order")
+	}
+}
+
+func TestPreviewSave(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	if err := store.SaveItems([]FeedItem{{ID: "old", Title: "Old", URL: "https://example.com/old", Source: "HN", CreatedAt: now}}); err != nil {
+		t.Fatalf("SaveItems failed: %v", err)
+	}
+	if _, err := store.Block("https://spam.example.com/*"); err != nil {
+		t.Fatalf("Block failed: %v", err)
+	}
+
+	items := []FeedItem{
+		{ID: "old", Title: "Old, retitled", URL: "https://example.com/old", Source: "HN", CreatedAt: now},
+		{ID: "new", Title: "New", URL: "https://example.com/new", Source: "HN", CreatedAt: now},
+		{ID: "new", Title: "New again", URL: "https://example.com/new", Source: "HN", CreatedAt: now},
+		{ID: "spam", Title: "Spam", URL: "https://spam.example.com/1", Source: "HN", CreatedAt: now},
+	}
+	result, err := store.PreviewSave(items)
+	if err != nil {
+		t.Fatalf("PreviewSave failed: %v", err)
+	}
+	if result.Inserted != 1 || result.Updated != 2 || result.Blocked != 1 || len(result.New) != 1 || result.New[0] != "new" {
+		t.Errorf("unexpected preview: %+v", result)
+	}
+
+	// Nothing was saved: the new item is still new, the old one unchanged
+	if count, _ := store.GetItemCount("HN"); count != 1 {
+		t.Errorf("expected 1 stored item after the preview, got %d", count)
+	}
+	history, err := store.GetItemHistory("old")
+	if err != nil {
+		t.Fatalf("GetItemHistory failed: %v", err)
+	}
+	if len(history) != 0 {
+		t.Errorf("expected no revision recorded by the preview, got %+v", history)
+	}
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// JournalEntry represents a raw fetched payload awaiting processing
+type JournalEntry struct {
+	ID        int64
+	Source    string
+	FeedType  string
+	Payload   []byte
+	FetchedAt time.Time
+}
+
+// AppendJournal persists a raw payload before it is parsed and saved.
+// The returned ID is used to mark the entry processed once its items
+// and fetch log have been written.
+func (s *Storage) AppendJournal(source, feedType string, payload []byte) (int64, error) {
+	var id int64
+	err := s.db.QueryRow(`
+		INSERT INTO fetch_journal (source, feed_type, payload, fetched_at)
+		VALUES (?, ?, ?, ?)
+		RETURNING id
+	`,
+		source,
+		feedType,
+		payload,
+		s.clock.Now().Format(time.RFC3339),
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to append journal entry: %w", err)
+	}
+
+	return id, nil
+}
+
+// MarkJournalProcessed marks a journal entry as fully processed
+func (s *Storage) MarkJournalProcessed(id int64) error {
+	_, err := s.db.Exec("UPDATE fetch_journal SET processed = 1 WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to mark journal entry %d processed: %w", id, err)
+	}
+	return nil
+}
+
+// PendingJournal returns unprocessed journal entries in the order they were written
+func (s *Storage) PendingJournal() ([]JournalEntry, error) {
+	rows, err := s.db.Query(`
+		SELECT id, source, feed_type, payload, fetched_at
+		FROM fetch_journal
+		WHERE processed = 0
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query journal: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []JournalEntry
+	for rows.Next() {
+		var entry JournalEntry
+		var fetchedAt string
+
+		if err := rows.Scan(&entry.ID, &entry.Source, &entry.FeedType, &entry.Payload, &fetchedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan journal entry: %w", err)
+		}
+
+		if t, err := time.Parse(time.RFC3339, fetchedAt); err == nil {
+			entry.FetchedAt = t
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating journal: %w", err)
+	}
+
+	return entries, nil
+}
+
+// PruneJournal deletes processed journal entries and returns how many were removed
+func (s *Storage) PruneJournal() (int, error) {
+	res, err := s.db.Exec("DELETE FROM fetch_journal WHERE processed = 1")
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune journal: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count pruned journal entries: %w", err)
+	}
+
+	return int(n), nil
+}
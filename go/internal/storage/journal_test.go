@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestJournal_AppendAndPending(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	payload := []byte(`[1, 2, 3]`)
+	id, err := store.AppendJournal("HackerNews", "json", payload)
+	if err != nil {
+		t.Fatalf("failed to append journal: %v", err)
+	}
+	if id == 0 {
+		t.Error("expected non-zero journal id")
+	}
+
+	entries, err := store.PendingJournal()
+	if err != nil {
+		t.Fatalf("failed to get pending journal: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 pending entry, got %d", len(entries))
+	}
+
+	entry := entries[0]
+	if entry.Source != "HackerNews" || entry.FeedType != "json" {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+	if string(entry.Payload) != string(payload) {
+		t.Errorf("payload mismatch: got %q, want %q", entry.Payload, payload)
+	}
+	if entry.FetchedAt.IsZero() {
+		t.Error("expected FetchedAt to be set")
+	}
+}
+
+func TestJournal_MarkProcessedAndPrune(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	first, _ := store.AppendJournal("A", "json", []byte(`[1]`))
+	store.AppendJournal("B", "json", []byte(`[2]`))
+
+	if err := store.MarkJournalProcessed(first); err != nil {
+		t.Fatalf("failed to mark processed: %v", err)
+	}
+
+	entries, err := store.PendingJournal()
+	if err != nil {
+		t.Fatalf("failed to get pending journal: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Source != "B" {
+		t.Fatalf("expected only B pending, got %+v", entries)
+	}
+
+	pruned, err := store.PruneJournal()
+	if err != nil {
+		t.Fatalf("failed to prune journal: %v", err)
+	}
+	if pruned != 1 {
+		t.Errorf("expected 1 pruned entry, got %d", pruned)
+	}
+
+	entries, _ = store.PendingJournal()
+	if len(entries) != 1 {
+		t.Errorf("prune must keep pending entries, got %d", len(entries))
+	}
+}
@@ -0,0 +1,60 @@
+package storage
+
+import "fmt"
+
+// columnMigrations lists columns added to existing tables after their
+// first release. CREATE TABLE IF NOT EXISTS leaves older databases
+// without them, so they are added on open.
+var columnMigrations = []struct {
+	table, column, decl string
+}{
+	{"fetch_log", "endpoint", "TEXT"},
+	{"fetch_log", "slow", "INTEGER NOT NULL DEFAULT 0"},
+	{"fetch_log", "failure_id", "INTEGER"},
+	{"fetch_log", "run_kind", "TEXT"},
+	{"feed_items", "read_at", "TEXT"},
+	{"feed_items", "starred", "INTEGER NOT NULL DEFAULT 0"},
+	{"feed_items", "user_tags", "TEXT"},
+	{"feed_items", "url_key", "TEXT"},
+	{"feed_items", "duplicate_of", "TEXT"},
+}
+
+// migrateSchema adds any columns missing from databases created by older
+// versions
+func (s *Storage) migrateSchema() error {
+	for _, m := range columnMigrations {
+		if s.postgres {
+			// IF NOT EXISTS lets instances starting together both migrate
+			stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s", m.table, m.column, postgresTypes.Replace(m.decl))
+			if _, err := s.db.Exec(stmt); err != nil {
+				return fmt.Errorf("failed to add %s.%s: %w", m.table, m.column, err)
+			}
+			continue
+		}
+
+		var exists bool
+		err := s.db.QueryRow(
+			"SELECT COUNT(*) > 0 FROM pragma_table_info(?) WHERE name = ?",
+			m.table, m.column,
+		).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to inspect %s: %w", m.table, err)
+		}
+		if exists {
+			continue
+		}
+
+		if _, err := s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", m.table, m.column, m.decl)); err != nil {
+			return fmt.Errorf("failed to add %s.%s: %w", m.table, m.column, err)
+		}
+	}
+	return nil
+}
+
+// nullString maps "" to NULL
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMigrateSchema_AddsEndpointColumn(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	// A fetch_log table as created before the endpoint column existed
+	old, err := sql.Open(driverName, dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	_, err = old.Exec(`CREATE TABLE fetch_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		source TEXT NOT NULL,
+		fetched_at TEXT NOT NULL,
+		status TEXT NOT NULL,
+		items_count INTEGER DEFAULT 0,
+		error_message TEXT,
+		duration_ms INTEGER
+	)`)
+	old.Close()
+	if err != nil {
+		t.Fatalf("failed to create old schema: %v", err)
+	}
+
+	store, err := NewStorage(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open old database: %v", err)
+	}
+
+	err = store.LogFetch(FetchLog{Source: "A", FetchedAt: time.Now(), Status: "success", Endpoint: "https://mirror.example.com/feed"})
+	if err != nil {
+		t.Fatalf("LogFetch failed: %v", err)
+	}
+
+	var endpoint string
+	if err := store.db.QueryRow("SELECT endpoint FROM fetch_log WHERE source = 'A'").Scan(&endpoint); err != nil {
+		t.Fatalf("failed to read endpoint: %v", err)
+	}
+	if endpoint != "https://mirror.example.com/feed" {
+		t.Errorf("expected endpoint to be logged, got %q", endpoint)
+	}
+	store.Close()
+
+	// Reopening must not try to add the column again
+	reopened, err := NewStorage(dbPath)
+	if err != nil {
+		t.Fatalf("failed to reopen database: %v", err)
+	}
+	reopened.Close()
+}
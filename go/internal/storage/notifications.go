@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// notificationKey identifies an item for notifications: its normalized
+// URL, or its ID if it has none
+func notificationKey(item FeedItem) string {
+	if key := NormalizeURL(item.URL); key != "" {
+		return key
+	}
+	return item.ID
+}
+
+// ClaimNotifications returns the items of items that sink, a notifier,
+// hasn't been sent within window, one per normalized URL, and records
+// them as sent. The notifications table keeps the claims for as long as
+// they are coalesced, so an item a notifier was already sent isn't sent
+// again however many feeds or fetches it arrives in. A window of 0 only
+// coalesces items within the call. Claims whose send failed are given
+// back with ReleaseNotifications.
+func (s *Storage) ClaimNotifications(sink string, items []FeedItem, window time.Duration) ([]FeedItem, error) {
+	now := s.clock.Now().UTC()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM notifications WHERE sink = ? AND sent_at <= ?",
+		sink, now.Add(-window).Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prune notifications: %w", err)
+	}
+
+	var claimed []FeedItem
+	for _, item := range items {
+		res, err := tx.Exec(`
+			INSERT INTO notifications (sink, item_key, sent_at) VALUES (?, ?, ?)
+			ON CONFLICT(sink, item_key) DO NOTHING
+		`, sink, notificationKey(item), now.Format(time.RFC3339))
+		if err != nil {
+			return nil, fmt.Errorf("failed to record notification: %w", err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return nil, fmt.Errorf("failed to record notification: %w", err)
+		} else if n > 0 {
+			claimed = append(claimed, item)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return claimed, nil
+}
+
+// ReleaseNotifications drops the claims of sink on items, which it failed
+// to be sent, so a later fetch sends them again
+func (s *Storage) ReleaseNotifications(sink string, items []FeedItem) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, item := range items {
+		_, err := tx.Exec("DELETE FROM notifications WHERE sink = ? AND item_key = ?", sink, notificationKey(item))
+		if err != nil {
+			return fmt.Errorf("failed to release notification: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"feedpulse/internal/clock"
+)
+
+// claimedIDs claims items for sink and returns the IDs of those claimed
+func claimedIDs(t *testing.T, store *Storage, sink string, items []FeedItem, window time.Duration) []string {
+	t.Helper()
+	claimed, err := store.ClaimNotifications(sink, items, window)
+	if err != nil {
+		t.Fatalf("ClaimNotifications failed: %v", err)
+	}
+	ids := []string{}
+	for _, item := range claimed {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func TestClaimNotifications(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	store.SetClock(clock.Fixed(now))
+
+	items := []FeedItem{
+		{ID: "hn-1", Source: "HN", URL: "https://example.com/post?utm_source=hn"},
+		{ID: "lobsters-1", Source: "Lobsters", URL: "https://Example.com/post/"},
+		{ID: "hn-2", Source: "HN", URL: "https://example.com/other"},
+	}
+	if got := claimedIDs(t, store, "matrix", items, time.Hour); len(got) != 2 || got[0] != "hn-1" || got[1] != "hn-2" {
+		t.Fatalf("expected one item per URL, got %v", got)
+	}
+	if got := claimedIDs(t, store, "telegram", items[1:2], time.Hour); len(got) != 1 {
+		t.Errorf("expected another notifier to get the item, got %v", got)
+	}
+
+	later := []FeedItem{{ID: "reddit-1", Source: "Reddit", URL: "https://example.com/post"}}
+	store.SetClock(clock.Fixed(now.Add(30 * time.Minute)))
+	if got := claimedIDs(t, store, "matrix", later, time.Hour); len(got) != 0 {
+		t.Errorf("expected an item sent within the window to be coalesced, got %v", got)
+	}
+
+	store.SetClock(clock.Fixed(now.Add(2 * time.Hour)))
+	if got := claimedIDs(t, store, "matrix", later, time.Hour); len(got) != 1 {
+		t.Errorf("expected the item to be sent again after the window, got %v", got)
+	}
+	if got := claimedIDs(t, store, "matrix", later, 0); len(got) != 1 {
+		t.Errorf("expected no coalescing across calls with a zero window, got %v", got)
+	}
+	if got := claimedIDs(t, store, "matrix", append(later, later...), 0); len(got) != 1 {
+		t.Errorf("expected a zero window to coalesce within the call, got %v", got)
+	}
+}
+
+func TestReleaseNotifications(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+	store.SetClock(clock.Fixed(time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)))
+
+	items := []FeedItem{
+		{ID: "hn-1", Source: "HN", URL: "https://example.com/post"},
+		{ID: "hn-2", Source: "HN", URL: "https://example.com/other"},
+	}
+	if got := claimedIDs(t, store, "matrix", items, time.Hour); len(got) != 2 {
+		t.Fatalf("expected both items to be claimed, got %v", got)
+	}
+	if err := store.ReleaseNotifications("matrix", items[:1]); err != nil {
+		t.Fatalf("ReleaseNotifications failed: %v", err)
+	}
+	if got := claimedIDs(t, store, "matrix", items, time.Hour); len(got) != 1 || got[0] != "hn-1" {
+		t.Errorf("expected only the released item to be claimed again, got %v", got)
+	}
+}
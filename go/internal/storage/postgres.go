@@ -0,0 +1,272 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"feedpulse/internal/clock"
+)
+
+// Storage writes its queries in the SQL SQLite and PostgreSQL both accept,
+// e.g. INSERT ... ON CONFLICT DO NOTHING rather than INSERT OR IGNORE,
+// with ? placeholders. Against PostgreSQL they go through postgresDriver,
+// a thin wrapper around lib/pq that only numbers the placeholders, and the
+// schema adds the handful of SQLite functions the queries use, so a single
+// implementation serves both databases.
+
+// postgresDriverName is the database/sql driver PostgreSQL DSNs are
+// opened with
+const postgresDriverName = "feedpulse-postgres"
+
+func init() {
+	sql.Register(postgresDriverName, postgresDriver{})
+}
+
+// IsPostgresDSN reports whether dsn points at a PostgreSQL server rather
+// than naming a SQLite file
+func IsPostgresDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://")
+}
+
+// Open opens the database dsn names: a postgres:// or postgresql:// URL
+// connects to PostgreSQL, anything else is the path of a SQLite file
+func Open(dsn string) (*Storage, error) {
+	if IsPostgresDSN(dsn) {
+		return NewPostgresStorage(dsn)
+	}
+	return NewStorage(dsn)
+}
+
+// NewPostgresStorage connects to the PostgreSQL database at dsn, creating
+// the schema if needed. Any number of instances may share the database.
+func NewPostgresStorage(dsn string) (*Storage, error) {
+	db, err := sql.Open(postgresDriverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetMaxIdleConns(maxIdleConns)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	s := &Storage{db: db, clock: clock.System, postgres: true}
+	if err := s.setup(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Postgres reports whether s is backed by PostgreSQL rather than SQLite
+func (s *Storage) Postgres() bool {
+	return s.postgres
+}
+
+// schemaLockID is the advisory lock instances hold while creating or
+// migrating the schema, so ones starting together don't race on it
+const schemaLockID = 0x66656564 // "feed"
+
+// postgresTypes maps the SQLite column types of the schema to PostgreSQL's
+var postgresTypes = strings.NewReplacer(
+	"INTEGER PRIMARY KEY AUTOINCREMENT", "BIGSERIAL PRIMARY KEY",
+	"INTEGER", "BIGINT",
+	"BLOB", "BYTEA",
+)
+
+// postgresFunctions defines the SQLite functions the queries use:
+// julianday and strftime on RFC 3339 text, NULL when it doesn't parse,
+// and json_each over the JSON arrays tags are stored as. julianday checks
+// its input with pg_input_is_valid (PostgreSQL 16) instead of catching
+// the cast's error, which would start a subtransaction for every row.
+const postgresFunctions = `
+CREATE OR REPLACE FUNCTION julianday(t TEXT) RETURNS DOUBLE PRECISION AS $$
+    SELECT CASE WHEN pg_input_is_valid(t, 'timestamptz')
+        THEN (extract(epoch FROM t::timestamptz) / 86400.0 + 2440587.5)::double precision
+    END
+$$ LANGUAGE sql STABLE STRICT SET timezone = 'UTC';
+
+CREATE OR REPLACE FUNCTION strftime(fmt TEXT, t DOUBLE PRECISION) RETURNS TEXT AS $$
+    SELECT to_char(to_timestamp(round((t - 2440587.5) * 86400000.0) / 1000.0),
+        replace(replace(replace(replace(replace(replace(replace(replace(fmt,
+            'T', '"T"'), 'Z', '"Z"'),
+            '%Y', 'YYYY'), '%m', 'MM'), '%d', 'DD'),
+            '%H', 'HH24'), '%M', 'MI'), '%S', 'SS'))
+$$ LANGUAGE sql STABLE STRICT SET timezone = 'UTC';
+
+CREATE OR REPLACE FUNCTION strftime(fmt TEXT, t TEXT) RETURNS TEXT AS $$
+    SELECT strftime(fmt, julianday(t))
+$$ LANGUAGE sql STABLE STRICT;
+
+CREATE OR REPLACE FUNCTION json_each(doc TEXT) RETURNS TABLE (value TEXT) AS $$
+    SELECT json_array_elements_text(doc::json)
+$$ LANGUAGE sql IMMUTABLE STRICT;
+`
+
+// initPostgresSchema creates the schema and the SQLite functions, holding
+// schemaLockID until they are committed
+func (s *Storage) initPostgresSchema(schema string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock(?)", schemaLockID); err != nil {
+		return fmt.Errorf("failed to lock schema: %w", err)
+	}
+	if _, err := tx.Exec(postgresFunctions + postgresTypes.Replace(schema)); err != nil {
+		return fmt.Errorf("failed to initialize schema: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// rebind numbers the ? placeholders of query the way PostgreSQL wants
+// them, as $1, $2, ... Quoted strings and identifiers are left as they
+// are; nothing else about the query is changed.
+func rebind(query string) string {
+	var b strings.Builder
+	b.Grow(len(query) + 16)
+
+	n := 0
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// postgresDriver opens lib/pq connections that rebind every query
+type postgresDriver struct{}
+
+func (postgresDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := pq.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &postgresConn{conn: conn}, nil
+}
+
+// postgresConn is a lib/pq connection that rebinds queries and converts
+// their arguments. lib/pq implements every optional interface forwarded
+// here.
+type postgresConn struct {
+	conn driver.Conn
+}
+
+func (c *postgresConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *postgresConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.conn.(driver.ConnPrepareContext).PrepareContext(ctx, rebind(query))
+	if err != nil {
+		return nil, err
+	}
+	return &postgresStmt{stmt: stmt}, nil
+}
+
+func (c *postgresConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *postgresConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *postgresConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func (c *postgresConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.conn.(driver.ExecerContext).ExecContext(ctx, rebind(query), args)
+}
+
+func (c *postgresConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.conn.(driver.QueryerContext).QueryContext(ctx, rebind(query), args)
+}
+
+func (c *postgresConn) Ping(ctx context.Context) error {
+	return c.conn.(driver.Pinger).Ping(ctx)
+}
+
+func (c *postgresConn) ResetSession(ctx context.Context) error {
+	return c.conn.(driver.SessionResetter).ResetSession(ctx)
+}
+
+func (c *postgresConn) IsValid() bool {
+	return c.conn.(driver.Validator).IsValid()
+}
+
+// CheckNamedValue stores booleans as integers, the way SQLite does,
+// leaving every other argument to database/sql's default conversion
+func (c *postgresConn) CheckNamedValue(nv *driver.NamedValue) error {
+	b, ok := nv.Value.(bool)
+	if !ok {
+		return driver.ErrSkip
+	}
+	if b {
+		nv.Value = int64(1)
+	} else {
+		nv.Value = int64(0)
+	}
+	return nil
+}
+
+// postgresStmt is a prepared lib/pq statement; its arguments are
+// converted by the connection's CheckNamedValue
+type postgresStmt struct {
+	stmt driver.Stmt
+}
+
+func (s *postgresStmt) Close() error {
+	return s.stmt.Close()
+}
+
+func (s *postgresStmt) NumInput() int {
+	return s.stmt.NumInput()
+}
+
+func (s *postgresStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.stmt.Exec(args)
+}
+
+func (s *postgresStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.stmt.Query(args)
+}
+
+func (s *postgresStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.stmt.(driver.StmtExecContext).ExecContext(ctx, args)
+}
+
+func (s *postgresStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.stmt.(driver.StmtQueryContext).QueryContext(ctx, args)
+}
@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT * FROM feed_items WHERE id = ?", "SELECT * FROM feed_items WHERE id = $1"},
+		{"WHERE ? = '' OR source = ? LIMIT ?", "WHERE $1 = '' OR source = $2 LIMIT $3"},
+		{"SELECT '?', \"a?\" FROM t WHERE x = ?", "SELECT '?', \"a?\" FROM t WHERE x = $1"},
+		{"WHERE url LIKE ? ESCAPE '\\' AND id = ?", "WHERE url LIKE $1 ESCAPE '\\' AND id = $2"},
+		{
+			"\n\t\tINSERT INTO archives (url) VALUES (?)\n\t\tON CONFLICT DO NOTHING\n\t",
+			"\n\t\tINSERT INTO archives (url) VALUES ($1)\n\t\tON CONFLICT DO NOTHING\n\t",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := rebind(tt.query); got != tt.want {
+			t.Errorf("rebind(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestLikePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"https://spam.example.com/*", "https://spam.example.com/%"},
+		{"*/100%_off/*", `%/100\%\_off/%`},
+		{`https://example.com/a\b`, `https://example.com/a\\b`},
+	}
+
+	for _, tt := range tests {
+		if got := likePattern(tt.pattern); got != tt.want {
+			t.Errorf("likePattern(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestOpen_SQLite(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open storage: %v", err)
+	}
+	defer store.Close()
+
+	if store.Postgres() {
+		t.Error("expected a file path to open SQLite")
+	}
+	if IsPostgresDSN("feedpulse.db") || !IsPostgresDSN("postgresql://db.internal/feedpulse") {
+		t.Error("IsPostgresDSN told SQLite and PostgreSQL apart wrongly")
+	}
+}
+
+// TestPostgres runs against the database FEEDPULSE_TEST_DATABASE_URL points
+// at, which it fills with test data; it is skipped when that isn't set
+func TestPostgres(t *testing.T) {
+	dsn := os.Getenv("FEEDPULSE_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("FEEDPULSE_TEST_DATABASE_URL not set")
+	}
+
+	store, err := Open(dsn)
+	if err != nil {
+		t.Fatalf("failed to open storage: %v", err)
+	}
+	defer store.Close()
+
+	if !store.Postgres() {
+		t.Fatal("expected a postgres:// URL to open PostgreSQL")
+	}
+
+	source := "pg-test-" + time.Now().Format("20060102150405.000000000")
+	now := time.Now().UTC().Truncate(time.Second)
+	items := []FeedItem{
+		{ID: source + "-1", Title: "One", URL: "https://example.com/" + source + "/1", Source: source, Tags: []string{"Go"}, CreatedAt: now},
+		{ID: source + "-2", Title: "Two", URL: "https://example.com/" + source + "/2", Source: source, CreatedAt: now},
+	}
+	res, err := store.SaveFetchResult(FetchLog{Source: source, FetchedAt: now, Status: "success", ItemsCount: 2, Slow: true}, items)
+	if err != nil {
+		t.Fatalf("SaveFetchResult failed: %v", err)
+	}
+	if res.Inserted != 2 {
+		t.Errorf("expected 2 items inserted, got %d", res.Inserted)
+	}
+
+	if n, err := store.GetItemCount(source); err != nil || n != 2 {
+		t.Errorf("GetItemCount = %d, %v; want 2", n, err)
+	}
+
+	tagged, err := store.GetItems(ItemFilter{Source: source, Tag: "go"})
+	if err != nil {
+		t.Fatalf("GetItems failed: %v", err)
+	}
+	if len(tagged) != 1 || tagged[0].ID != source+"-1" {
+		t.Errorf("expected the tagged item, got %+v", tagged)
+	}
+
+	buckets, err := store.GetStatsBuckets(source, BucketHour, time.Hour)
+	if err != nil {
+		t.Fatalf("GetStatsBuckets failed: %v", err)
+	}
+	if len(buckets) != 1 || buckets[0].Fetches != 1 || !buckets[0].Start.Equal(now.Truncate(time.Hour)) {
+		t.Errorf("unexpected buckets: %+v", buckets)
+	}
+
+	runs, err := store.ListRuns(source, 0)
+	if err != nil {
+		t.Fatalf("ListRuns failed: %v", err)
+	}
+	if len(runs) != 1 || !runs[0].Slow {
+		t.Errorf("expected one slow run, got %+v", runs)
+	}
+
+	removed, err := store.Block("https://example.com/" + source + "/*")
+	if err != nil {
+		t.Fatalf("Block failed: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("expected the pattern to remove 2 items, removed %d", removed)
+	}
+	if _, err := store.Unblock("https://example.com/" + source + "/*"); err != nil {
+		t.Fatalf("Unblock failed: %v", err)
+	}
+}
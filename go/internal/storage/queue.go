@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// maxQueueLine bounds one queued result, items included
+const maxQueueLine = 64 * 1024 * 1024
+
+// QueuedResult is a fetch result that couldn't be written because the
+// database was locked or corrupt, kept in the queue file until a later
+// fetch saves it
+type QueuedResult struct {
+	Log   FetchLog          `json:"log"`
+	Items []FeedItem        `json:"items,omitempty"`
+	State map[string]string `json:"state,omitempty"`
+}
+
+// QueuePath returns the queue file kept next to the database at dbPath
+func QueuePath(dbPath string) string {
+	return dbPath + ".queue"
+}
+
+// LockQueue takes an exclusive lock on the queue at path, waiting while
+// another process holds it, and returns the func that releases it.
+// AppendQueue takes the same lock, so a replay holding it can read and
+// rewrite the queue without losing results queued in the meantime.
+func LockQueue(path string) (func(), error) {
+	f, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open queue lock: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock queue: %w", err)
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// AppendQueue adds result to the queue file at path, one JSON object per
+// line, creating the file if needed
+func AppendQueue(path string, result QueuedResult) error {
+	unlock, err := LockQueue(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return appendQueue(path, result)
+}
+
+// appendQueue adds result to the queue file at path without locking it
+func appendQueue(path string, result QueuedResult) error {
+	line, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode queued result: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open queue: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write queue: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write queue: %w", err)
+	}
+	return f.Close()
+}
+
+// ReadQueue returns the results queued at path, oldest first, and the
+// numbers of the lines that couldn't be decoded, such as one cut short by
+// a crash mid-write. Damaged lines are skipped, so every entry after them
+// is still returned. A missing file is an empty queue.
+func ReadQueue(path string) ([]QueuedResult, []int, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open queue: %w", err)
+	}
+	defer f.Close()
+
+	var results []QueuedResult
+	var damaged []int
+	reader := bufio.NewReaderSize(f, 64*1024)
+	for line := 1; ; line++ {
+		data, tooLong, err := readQueueLine(reader)
+		if err != nil && err != io.EOF {
+			return nil, nil, fmt.Errorf("failed to read queue: %w", err)
+		}
+		if tooLong {
+			damaged = append(damaged, line)
+		} else if len(bytes.TrimSpace(data)) > 0 {
+			var r QueuedResult
+			if json.Unmarshal(data, &r) != nil {
+				damaged = append(damaged, line)
+			} else {
+				results = append(results, r)
+			}
+		}
+		if err == io.EOF {
+			return results, damaged, nil
+		}
+	}
+}
+
+// readQueueLine reads the next line from r, without its newline. A line
+// longer than maxQueueLine is read to its end but not kept.
+func readQueueLine(r *bufio.Reader) ([]byte, bool, error) {
+	var line []byte
+	tooLong := false
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if !tooLong && len(line)+len(chunk) > maxQueueLine {
+			tooLong, line = true, nil
+		}
+		if !tooLong {
+			line = append(line, chunk...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		return bytes.TrimSuffix(line, []byte("\n")), tooLong, err
+	}
+}
+
+// WriteQueue replaces the queue file at path with results, removing it
+// when there are none. The caller must hold the queue's lock.
+func WriteQueue(path string, results []QueuedResult) error {
+	if len(results) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove queue: %w", err)
+		}
+		return nil
+	}
+
+	tmp := path + ".tmp"
+	if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to write queue: %w", err)
+	}
+	for _, r := range results {
+		if err := appendQueue(tmp, r); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write queue: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestQueue_AppendReadWrite(t *testing.T) {
+	path := QueuePath(filepath.Join(t.TempDir(), "test.db"))
+
+	if results, _, err := ReadQueue(path); err != nil || results != nil {
+		t.Fatalf("missing queue = %v, %v; want empty", results, err)
+	}
+
+	fetchedAt := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	msg := "timeout"
+	queued := []QueuedResult{
+		{
+			Log:   FetchLog{Source: "HN", FetchedAt: fetchedAt, Status: "success", ItemsCount: 1, DurationMs: 40},
+			Items: []FeedItem{{ID: "1", Title: "One", URL: "https://example.com/1", Source: "HN", CreatedAt: fetchedAt}},
+			State: map[string]string{"cursor": "42"},
+		},
+		{Log: FetchLog{Source: "Lobsters", FetchedAt: fetchedAt, Status: "error", ErrorMessage: &msg}},
+	}
+	for _, r := range queued {
+		if err := AppendQueue(path, r); err != nil {
+			t.Fatalf("AppendQueue failed: %v", err)
+		}
+	}
+
+	got, damaged, err := ReadQueue(path)
+	if err != nil || damaged != nil {
+		t.Fatalf("ReadQueue = %v, %v", damaged, err)
+	}
+	if !reflect.DeepEqual(got, queued) {
+		t.Errorf("round trip mismatch:\n got  %+v\n want %+v", got, queued)
+	}
+
+	if err := WriteQueue(path, got[1:]); err != nil {
+		t.Fatalf("WriteQueue failed: %v", err)
+	}
+	if got, _, _ := ReadQueue(path); len(got) != 1 || got[0].Log.Source != "Lobsters" {
+		t.Errorf("expected only the Lobsters result left, got %+v", got)
+	}
+
+	if err := WriteQueue(path, nil); err != nil {
+		t.Fatalf("WriteQueue failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("expected an emptied queue to be removed")
+	}
+}
+
+func TestQueue_DamagedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db.queue")
+	if err := AppendQueue(path, QueuedResult{Log: FetchLog{Source: "HN", Status: "unchanged"}}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"log": {"Source": "Lob` + "\n")
+	f.Close()
+	if err := AppendQueue(path, QueuedResult{Log: FetchLog{Source: "Lobsters", Status: "unchanged"}}); err != nil {
+		t.Fatal(err)
+	}
+	f, err = os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"log": {"Source": "Red`)
+	f.Close()
+
+	got, damaged, err := ReadQueue(path)
+	if err != nil {
+		t.Fatalf("ReadQueue failed: %v", err)
+	}
+	if !reflect.DeepEqual(damaged, []int{2, 4}) {
+		t.Errorf("expected lines 2 and 4 reported damaged, got %v", damaged)
+	}
+	if len(got) != 2 || got[0].Log.Source != "HN" || got[1].Log.Source != "Lobsters" {
+		t.Errorf("expected the entries around the damaged lines, got %+v", got)
+	}
+}
+
+func TestLockQueue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db.queue")
+	unlock, err := LockQueue(path)
+	if err != nil {
+		t.Fatalf("LockQueue failed: %v", err)
+	}
+
+	appended := make(chan error, 1)
+	go func() {
+		appended <- AppendQueue(path, QueuedResult{Log: FetchLog{Source: "HN", Status: "unchanged"}})
+	}()
+	select {
+	case <-appended:
+		t.Fatal("expected AppendQueue to wait for the lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// A replay rewriting the queue while locked doesn't lose the append
+	if err := WriteQueue(path, nil); err != nil {
+		t.Fatalf("WriteQueue failed: %v", err)
+	}
+	unlock()
+	if err := <-appended; err != nil {
+		t.Fatalf("AppendQueue failed: %v", err)
+	}
+	if got, _, _ := ReadQueue(path); len(got) != 1 {
+		t.Errorf("expected the append kept, got %+v", got)
+	}
+}
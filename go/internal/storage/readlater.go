@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Read-later save states. Queued saves are sent by the next fetch or
+// `feedpulse save --queued`; a save that keeps failing is marked failed.
+const (
+	SaveQueued = "queued"
+	SaveDone   = "saved"
+	SaveFailed = "failed"
+)
+
+// SaveEntry is an item URL saved, or queued to be saved, to a read-later
+// service. Each URL is saved to a service once, whichever items share it.
+type SaveEntry struct {
+	Service     string    `json:"service"`
+	URL         string    `json:"url"`
+	Source      string    `json:"source"`
+	Title       string    `json:"title"`
+	Tags        []string  `json:"tags,omitempty"`
+	Status      string    `json:"status"`
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"last_error,omitempty"`
+	RequestedAt time.Time `json:"requested_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// QueueSave queues items to be saved to service, returning how many
+// weren't queued (or saved) before
+func (s *Storage) QueueSave(service string, items []FeedItem) (int, error) {
+	if len(items) == 0 {
+		return 0, nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := s.clock.Now().Format(time.RFC3339)
+	queued := 0
+	for _, item := range items {
+		if item.URL == "" {
+			continue
+		}
+		var tags *string
+		if len(item.Tags) > 0 {
+			b, err := json.Marshal(item.Tags)
+			if err != nil {
+				return 0, fmt.Errorf("failed to encode tags: %w", err)
+			}
+			encoded := string(b)
+			tags = &encoded
+		}
+		res, err := tx.Exec(`
+			INSERT INTO read_later (service, url, source, title, tags, status, attempts, requested_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, 0, ?, ?)
+			ON CONFLICT DO NOTHING
+		`, service, item.URL, item.Source, item.Title, tags, SaveQueued, now, now)
+		if err != nil {
+			return 0, fmt.Errorf("failed to queue save: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to queue save: %w", err)
+		}
+		queued += int(n)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return queued, nil
+}
+
+// QueuedSaves returns up to limit saves still to send, oldest first
+func (s *Storage) QueuedSaves(limit int) ([]SaveEntry, error) {
+	return s.querySaves(`
+		WHERE status = 'queued'
+		ORDER BY julianday(updated_at), service, url
+		LIMIT ?
+	`, noLimit(limit))
+}
+
+// GetSave returns the save of url to service, or nil if there is none
+func (s *Storage) GetSave(service, url string) (*SaveEntry, error) {
+	entries, err := s.querySaves("WHERE service = ? AND url = ?", service, url)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	return &entries[0], nil
+}
+
+// UpdateSave stores the new state of entry
+func (s *Storage) UpdateSave(entry SaveEntry) error {
+	res, err := s.db.Exec(`
+		UPDATE read_later SET status = ?, attempts = ?, last_error = ?, updated_at = ?
+		WHERE service = ? AND url = ?
+	`, entry.Status, entry.Attempts, nullString(entry.LastError), s.clock.Now().Format(time.RFC3339), entry.Service, entry.URL)
+	if err != nil {
+		return fmt.Errorf("failed to update save: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("save not queued: %s to %s", entry.URL, entry.Service)
+	}
+	return nil
+}
+
+// querySaves returns the read-later entries selected by clause
+func (s *Storage) querySaves(clause string, args ...interface{}) ([]SaveEntry, error) {
+	rows, err := s.db.Query(`
+		SELECT service, url, source, title, tags, status, attempts, last_error, requested_at, updated_at
+		FROM read_later
+	`+clause, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query saves: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []SaveEntry
+	for rows.Next() {
+		var e SaveEntry
+		var tags, lastError sql.NullString
+		var requestedAt, updatedAt string
+		if err := rows.Scan(&e.Service, &e.URL, &e.Source, &e.Title, &tags, &e.Status, &e.Attempts, &lastError, &requestedAt, &updatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan save: %w", err)
+		}
+		if tags.Valid {
+			if err := json.Unmarshal([]byte(tags.String), &e.Tags); err != nil {
+				return nil, fmt.Errorf("invalid tags for save of %s: %w", e.URL, err)
+			}
+		}
+		e.LastError = lastError.String
+		if t, err := time.Parse(time.RFC3339, requestedAt); err == nil {
+			e.RequestedAt = t
+		}
+		if t, err := time.Parse(time.RFC3339, updatedAt); err == nil {
+			e.UpdatedAt = t
+		}
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating saves: %w", err)
+	}
+
+	return entries, nil
+}
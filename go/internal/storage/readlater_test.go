@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"feedpulse/internal/clock"
+)
+
+func TestReadLater_QueueAndUpdate(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	store.SetClock(clock.Fixed(now))
+
+	items := []FeedItem{
+		{ID: "1", Title: "A", URL: "https://example.com/a", Source: "HN", Tags: []string{"go", "db"}},
+		{ID: "2", Title: "B", URL: "https://example.com/b", Source: "HN"},
+		{ID: "3", Title: "A again", URL: "https://example.com/a", Source: "Lobsters"},
+	}
+	queued, err := store.QueueSave("pocket", items)
+	if err != nil || queued != 2 {
+		t.Fatalf("QueueSave = %d, %v; want 2 URLs queued", queued, err)
+	}
+	if queued, _ := store.QueueSave("pocket", items); queued != 0 {
+		t.Errorf("queueing again added %d", queued)
+	}
+	// Each service saves the URL separately
+	if queued, _ := store.QueueSave("instapaper", items[:1]); queued != 1 {
+		t.Errorf("queueing for another service added %d, want 1", queued)
+	}
+
+	store.SetClock(clock.Fixed(now.Add(time.Minute)))
+	if err := store.UpdateSave(SaveEntry{Service: "pocket", URL: "https://example.com/a", Status: SaveDone, Attempts: 1}); err != nil {
+		t.Fatalf("UpdateSave failed: %v", err)
+	}
+	if err := store.UpdateSave(SaveEntry{Service: "wallabag", URL: "https://example.com/a"}); err == nil {
+		t.Error("expected updating an unqueued save to fail")
+	}
+
+	queuedSaves, err := store.QueuedSaves(10)
+	if err != nil || len(queuedSaves) != 2 || queuedSaves[0].Service != "instapaper" || queuedSaves[1].URL != "https://example.com/b" {
+		t.Fatalf("unexpected queued saves: %+v (%v)", queuedSaves, err)
+	}
+
+	got, err := store.GetSave("pocket", "https://example.com/a")
+	if err != nil || got == nil || got.Status != SaveDone || got.Title != "A" || !reflect.DeepEqual(got.Tags, []string{"go", "db"}) || !got.UpdatedAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("unexpected save: %+v (%v)", got, err)
+	}
+	if got, err := store.GetSave("wallabag", "https://example.com/a"); err != nil || got != nil {
+		t.Errorf("expected no wallabag save, got %+v (%v)", got, err)
+	}
+}
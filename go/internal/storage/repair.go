@@ -0,0 +1,352 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Ways RecoverDatabase can salvage a database
+const (
+	RecoverSQLiteCLI = "sqlite3 .recover"
+	RecoverRowCopy   = "row copy"
+)
+
+// maxRowSkips bounds how often a row copy skips past unreadable rows in
+// one table; the skip doubles each time, so this covers every rowid
+const maxRowSkips = 64
+
+// TableCounts returns the number of rows in each table of the database at
+// dbPath, with -1 for tables that can't be counted
+func TableCounts(dbPath string) (map[string]int, error) {
+	db, err := openRaw(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	tables, err := tableNames(db)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(tables))
+	for _, table := range tables {
+		var n int
+		if err := db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %q", table)).Scan(&n); err != nil {
+			n = -1
+		}
+		counts[table] = n
+	}
+	return counts, nil
+}
+
+// RecoverDatabase salvages what it can of the database at src into a new
+// database at dst, returning how. It uses the sqlite3 shell's .recover
+// when sqlite3 is installed, and otherwise copies every row it can read,
+// table by table, into a database created with the current schema. src is
+// only read.
+func RecoverDatabase(src, dst string) (string, error) {
+	if _, err := os.Stat(dst); err == nil {
+		return "", fmt.Errorf("%s already exists", dst)
+	}
+
+	if path, err := exec.LookPath("sqlite3"); err == nil {
+		if err := recoverWithCLI(path, src, dst); err == nil {
+			return RecoverSQLiteCLI, finishRecovery(dst)
+		}
+		removeDatabase(dst)
+	}
+
+	if err := recoverByRowCopy(src, dst); err != nil {
+		removeDatabase(dst)
+		return "", err
+	}
+	return RecoverRowCopy, finishRecovery(dst)
+}
+
+// recoverWithCLI pipes `sqlite3 src .recover` into `sqlite3 dst`
+func recoverWithCLI(sqlite3, src, dst string) error {
+	dump := exec.Command(sqlite3, src, ".recover")
+	load := exec.Command(sqlite3, "-bail", dst)
+
+	pipe, err := dump.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	load.Stdin = pipe
+	var loadErr strings.Builder
+	load.Stderr = &loadErr
+
+	if err := dump.Start(); err != nil {
+		return err
+	}
+	if err := load.Run(); err != nil {
+		io.Copy(io.Discard, pipe)
+		dump.Wait()
+		return fmt.Errorf("sqlite3 failed to load recovered data: %v: %s", err, strings.TrimSpace(loadErr.String()))
+	}
+	return dump.Wait()
+}
+
+// recoverByRowCopy creates dst with the current schema and copies every
+// readable row of src's known tables into it
+func recoverByRowCopy(src, dst string) error {
+	created, err := NewStorage(dst)
+	if err != nil {
+		return fmt.Errorf("failed to create repaired database: %w", err)
+	}
+	created.Close()
+
+	from, err := openRaw(src)
+	if err != nil {
+		return err
+	}
+	defer from.Close()
+
+	to, err := openRaw(dst)
+	if err != nil {
+		return err
+	}
+	defer to.Close()
+
+	tables, err := tableNames(to)
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		if err := copyReadableRows(from, to, table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyReadableRows copies table's rows from src to dst in rowid order.
+// When a read fails partway, it resumes past the rowid it got to, skipping
+// further each time, so rows beyond a damaged page are still reached.
+func copyReadableRows(src, dst *sql.DB, table string) error {
+	srcCols, err := columnNames(src, table)
+	if err != nil || len(srcCols) == 0 {
+		// The table itself is unreadable (or new in this version)
+		return nil
+	}
+	dstCols, err := columnNames(dst, table)
+	if err != nil {
+		return err
+	}
+	var cols []string
+	for _, c := range srcCols {
+		for _, d := range dstCols {
+			if c == d {
+				cols = append(cols, fmt.Sprintf("%q", c))
+			}
+		}
+	}
+	if len(cols) == 0 {
+		return nil
+	}
+
+	selectSQL := fmt.Sprintf("SELECT rowid, %s FROM %q WHERE rowid > ? ORDER BY rowid", strings.Join(cols, ", "), table)
+	insertSQL := fmt.Sprintf("INSERT OR IGNORE INTO %q (%s) VALUES (%s)", table, strings.Join(cols, ", "),
+		strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", "))
+
+	tx, err := dst.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	insert, err := tx.Prepare(insertSQL)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer insert.Close()
+
+	last := int64(-1 << 63)
+	skip := int64(1)
+	for skips := 0; skips < maxRowSkips; {
+		copied, err := copyRowsAfter(src, insert, selectSQL, &last, len(cols))
+		if err == nil {
+			break
+		}
+		if !IsCorrupt(err) {
+			return fmt.Errorf("failed to copy %s: %w", table, err)
+		}
+		if copied > 0 {
+			skip = 1
+		}
+		if last > (1<<63-1)-skip {
+			break
+		}
+		last += skip
+		skip *= 2
+		skips++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// copyRowsAfter copies the rows selectSQL returns after *last, advancing
+// *last as it goes, and returns how many it copied before any error
+func copyRowsAfter(src *sql.DB, insert *sql.Stmt, selectSQL string, last *int64, ncols int) (int, error) {
+	rows, err := src.Query(selectSQL, *last)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	copied := 0
+	values := make([]interface{}, ncols+1)
+	ptrs := make([]interface{}, ncols+1)
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return copied, err
+		}
+		rowid, ok := values[0].(int64)
+		if !ok {
+			return copied, fmt.Errorf("unexpected rowid %v", values[0])
+		}
+		if _, err := insert.Exec(values[1:]...); err != nil {
+			return copied, fmt.Errorf("failed to insert row: %w", err)
+		}
+		*last = rowid
+		copied++
+	}
+	return copied, rows.Err()
+}
+
+// finishRecovery brings a recovered database up to the current schema and
+// recomputes the stats derived from its tables
+func finishRecovery(dst string) error {
+	s, err := NewStorage(dst)
+	if err != nil {
+		return fmt.Errorf("failed to open repaired database: %w", err)
+	}
+	defer s.Close()
+	return s.RebuildSourceStats()
+}
+
+// SwapDatabase replaces the database at dbPath with the one at repaired,
+// keeping the original (with its WAL) at backup. The replacement itself
+// is a single rename, so dbPath is never missing.
+func SwapDatabase(dbPath, repaired, backup string) error {
+	if _, err := os.Stat(backup); err == nil {
+		return fmt.Errorf("backup %s already exists", backup)
+	}
+	if err := linkOrCopy(dbPath, backup); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	// A leftover WAL would be replayed into the repaired database
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Rename(dbPath+suffix, backup+suffix); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to back up database: %w", err)
+		}
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		os.Remove(repaired + suffix)
+	}
+	if err := os.Rename(repaired, dbPath); err != nil {
+		return fmt.Errorf("failed to replace database: %w", err)
+	}
+	return nil
+}
+
+// linkOrCopy makes dst a hard link to src, or a copy where links aren't
+// supported
+func linkOrCopy(src, dst string) error {
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// removeDatabase deletes the database at path with its WAL files
+func removeDatabase(path string) {
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		os.Remove(path + suffix)
+	}
+}
+
+// openRaw opens the database at dbPath without touching its schema
+func openRaw(dbPath string) (*sql.DB, error) {
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	db, err := sql.Open(driverName, dataSourceName(dbPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return db, nil
+}
+
+// tableNames lists the tables of db, excluding SQLite's own
+func tableNames(db *sql.DB) ([]string, error) {
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan table name: %w", err)
+		}
+		tables = append(tables, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tables: %w", err)
+	}
+	return tables, nil
+}
+
+// columnNames lists table's columns in db
+func columnNames(db *sql.DB, table string) ([]string, error) {
+	rows, err := db.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var cols []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to inspect %s: %w", table, err)
+		}
+		cols = append(cols, name)
+	}
+	return cols, rows.Err()
+}
@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// corruptDatabase creates a database at path holding n items and
+// overwrites a run of pages in the middle of the file
+func corruptDatabase(t *testing.T, path string, n int) {
+	t.Helper()
+
+	store, err := NewStorage(path)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	var items []FeedItem
+	for i := 0; i < n; i++ {
+		items = append(items, FeedItem{
+			ID:        fmt.Sprintf("item-%05d", i),
+			Title:     strings.Repeat("title ", 20),
+			URL:       fmt.Sprintf("https://example.com/%d", i),
+			Source:    "HN",
+			CreatedAt: time.Now(),
+		})
+	}
+	if err := store.SaveItems(items); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const pageSize = 4096
+	mid := len(data) / pageSize / 2 * pageSize
+	for i := mid; i < mid+2*pageSize && i < len(data); i++ {
+		data[i] = 0xA5
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecoverDatabase_RowCopy(t *testing.T) {
+	// Without sqlite3 on PATH the built-in row copy is used
+	t.Setenv("PATH", "")
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "test.db")
+	corruptDatabase(t, src, 2000)
+
+	if problems, err := CheckIntegrity(src); err != nil || len(problems) == 0 {
+		t.Fatalf("expected the test database to be corrupt, got %v, %v", problems, err)
+	}
+
+	dst := filepath.Join(dir, "repaired.db")
+	method, err := RecoverDatabase(src, dst)
+	if err != nil {
+		t.Fatalf("RecoverDatabase failed: %v", err)
+	}
+	if method != RecoverRowCopy {
+		t.Errorf("method = %q, want %q", method, RecoverRowCopy)
+	}
+
+	if problems, err := CheckIntegrity(dst); err != nil || len(problems) != 0 {
+		t.Errorf("repaired database has problems: %v, %v", problems, err)
+	}
+	counts, err := TableCounts(dst)
+	if err != nil {
+		t.Fatalf("TableCounts failed: %v", err)
+	}
+	if counts["feed_items"] <= 0 || counts["feed_items"] > 2000 {
+		t.Errorf("recovered %d items, want some of 2000", counts["feed_items"])
+	}
+
+	store, err := NewStorage(dst)
+	if err != nil {
+		t.Fatalf("repaired database doesn't open: %v", err)
+	}
+	defer store.Close()
+	stats, err := store.GetFetchStats()
+	if err != nil || len(stats) != 1 || stats[0].ItemsCount != counts["feed_items"] {
+		t.Errorf("expected source stats rebuilt from the recovered items, got %+v (%v)", stats, err)
+	}
+}
+
+func TestRecoverDatabase_ExistingDestination(t *testing.T) {
+	dir := t.TempDir()
+	dst := filepath.Join(dir, "repaired.db")
+	if err := os.WriteFile(dst, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RecoverDatabase(filepath.Join(dir, "test.db"), dst); err == nil {
+		t.Error("expected an existing destination to be refused")
+	}
+}
+
+func TestSwapDatabase(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.db")
+	repaired := filepath.Join(dir, "repaired.db")
+	backup := filepath.Join(dir, "test.db.bak")
+	for path, content := range map[string]string{dbPath: "old", dbPath + "-wal": "wal", repaired: "new"} {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := SwapDatabase(dbPath, repaired, backup); err != nil {
+		t.Fatalf("SwapDatabase failed: %v", err)
+	}
+
+	for path, want := range map[string]string{dbPath: "new", backup: "old", backup + "-wal": "wal"} {
+		if got, err := os.ReadFile(path); err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", filepath.Base(path), got, err, want)
+		}
+	}
+	for _, path := range []string{repaired, dbPath + "-wal"} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be gone", filepath.Base(path))
+		}
+	}
+
+	if err := SwapDatabase(dbPath, repaired, backup); err == nil {
+		t.Error("expected an existing backup to be refused")
+	}
+}
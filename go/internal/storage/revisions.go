@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ItemRevision records a change to an item's title or URL made by an upsert
+type ItemRevision struct {
+	ItemID    string
+	OldTitle  string
+	OldURL    string
+	NewTitle  string
+	NewURL    string
+	ChangedAt time.Time
+}
+
+// recordRevision stores the values item is about to overwrite
+func recordRevision(tx *sql.Tx, item FeedItem, oldTitle, oldURL string, changedAt time.Time) error {
+	_, err := tx.Exec(`
+		INSERT INTO item_revisions (item_id, old_title, old_url, new_title, new_url, changed_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, item.ID, oldTitle, oldURL, item.Title, item.URL, changedAt.Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("failed to record item revision: %w", err)
+	}
+	return nil
+}
+
+// GetItemHistory returns the recorded title/URL changes for an item, oldest first
+func (s *Storage) GetItemHistory(itemID string) ([]ItemRevision, error) {
+	rows, err := s.db.Query(`
+		SELECT item_id, old_title, old_url, new_title, new_url, changed_at
+		FROM item_revisions
+		WHERE item_id = ?
+		ORDER BY id
+	`, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query item history: %w", err)
+	}
+	defer rows.Close()
+
+	var revisions []ItemRevision
+	for rows.Next() {
+		var rev ItemRevision
+		var changedAt string
+		if err := rows.Scan(&rev.ItemID, &rev.OldTitle, &rev.OldURL, &rev.NewTitle, &rev.NewURL, &changedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan item revision: %w", err)
+		}
+		if t, err := time.Parse(time.RFC3339, changedAt); err == nil {
+			rev.ChangedAt = t
+		}
+		revisions = append(revisions, rev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating item history: %w", err)
+	}
+
+	return revisions, nil
+}
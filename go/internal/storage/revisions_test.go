@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"feedpulse/internal/clock"
+)
+
+func TestGetItemHistory_RecordsChanges(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	item := FeedItem{ID: "hn1", Title: "Original title", URL: "https://example.com", Source: "HN", CreatedAt: time.Now()}
+	store.SaveItems([]FeedItem{item})
+
+	// Re-saving unchanged values must not create a revision
+	store.SaveItems([]FeedItem{item})
+
+	item.Title = "Editorialized title"
+	store.SaveItems([]FeedItem{item})
+
+	item.URL = "https://example.com/moved"
+	store.SaveItems([]FeedItem{item})
+
+	history, err := store.GetItemHistory("hn1")
+	if err != nil {
+		t.Fatalf("GetItemHistory failed: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected 2 revisions, got %d: %+v", len(history), history)
+	}
+
+	first := history[0]
+	if first.OldTitle != "Original title" || first.NewTitle != "Editorialized title" {
+		t.Errorf("unexpected first revision: %+v", first)
+	}
+	if first.ChangedAt.IsZero() {
+		t.Error("expected ChangedAt to be set")
+	}
+
+	second := history[1]
+	if second.OldURL != "https://example.com" || second.NewURL != "https://example.com/moved" {
+		t.Errorf("unexpected second revision: %+v", second)
+	}
+}
+
+func TestGetItemHistory_Unknown(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	history, err := store.GetItemHistory("missing")
+	if err != nil {
+		t.Fatalf("GetItemHistory failed: %v", err)
+	}
+	if len(history) != 0 {
+		t.Errorf("expected no history, got %d", len(history))
+	}
+}
+
+func TestGetItemHistory_UsesInjectedClock(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	at := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	store.SetClock(clock.Fixed(at))
+
+	item := FeedItem{ID: "x", Title: "Before", URL: "u", Source: "S", CreatedAt: at}
+	store.SaveItems([]FeedItem{item})
+	item.Title = "After"
+	store.SaveItems([]FeedItem{item})
+
+	history, _ := store.GetItemHistory("x")
+	if len(history) != 1 || !history[0].ChangedAt.Equal(at) {
+		t.Errorf("expected one revision stamped %v, got %+v", at, history)
+	}
+}
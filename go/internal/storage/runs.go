@@ -0,0 +1,236 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// RunsKept is how many of a source's most recent runs keep their item
+// lists for diffing; older runs keep only their fetch log entry
+const RunsKept = 20
+
+// Kinds of run, as fetch_log.run_kind records them. Entries that aren't
+// runs have none; runs recorded before kinds were are full.
+const (
+	runFull    = "full"
+	runPartial = "partial"
+)
+
+// isRun is the condition selecting the fetch_log entries that are runs
+const isRun = "(run_kind IS NOT NULL OR id IN (SELECT fetch_id FROM run_items))"
+
+// RunItem is an item as one fetch run of its source saw it
+type RunItem struct {
+	ItemID string
+	Title  string
+	URL    string
+}
+
+// RunChange is an item present in both runs with a different title or URL
+type RunChange struct {
+	ItemID   string
+	OldTitle string
+	OldURL   string
+	NewTitle string
+	NewURL   string
+}
+
+// RunDiff is what changed in a source's feed between two runs
+type RunDiff struct {
+	From FetchLog
+	To   FetchLog
+
+	// Added are items only in To, Removed items that fell out of the feed
+	// after From, and Changed items whose title or URL was edited.
+	// Removed stays empty when To is partial.
+	Added   []RunItem
+	Removed []RunItem
+	Changed []RunChange
+}
+
+// recordRunItems records the successful fetch fetchID as a run, full or
+// partial, with the items it saw in feed order, skipping blocklisted and
+// deleted ones. A run that saw no items is recorded too. The item lists
+// of the source's runs beyond RunsKept are forgotten.
+func recordRunItems(tx *sql.Tx, fetchID int64, log FetchLog, items []FeedItem) error {
+	if log.Status != "success" && log.Status != "degraded" {
+		return nil
+	}
+
+	kind := runFull
+	if log.Partial {
+		kind = runPartial
+	}
+	if _, err := tx.Exec("UPDATE fetch_log SET run_kind = ? WHERE id = ?", kind, fetchID); err != nil {
+		return fmt.Errorf("failed to record run: %w", err)
+	}
+
+	bl, err := loadBlocklist(tx)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO run_items (fetch_id, position, item_id, title, url) VALUES (?, ?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	position := 0
+	for _, item := range items {
+		if bl.blocked(item) {
+			continue
+		}
+		deleted, err := itemDeleted(tx, item.ID)
+		if err != nil {
+			return err
+		}
+		if deleted {
+			continue
+		}
+		if _, err := stmt.Exec(fetchID, position, item.ID, item.Title, item.URL); err != nil {
+			return fmt.Errorf("failed to record run item: %w", err)
+		}
+		position++
+	}
+
+	rows, err := tx.Query(`
+		SELECT id FROM fetch_log
+		WHERE source = ? AND `+isRun+`
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?
+	`, log.Source, noLimit(0), RunsKept)
+	if err != nil {
+		return fmt.Errorf("failed to query old runs: %w", err)
+	}
+	var pruned []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan run: %w", err)
+		}
+		pruned = append(pruned, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating runs: %w", err)
+	}
+
+	for _, id := range pruned {
+		if _, err := tx.Exec("DELETE FROM run_items WHERE fetch_id = ?", id); err != nil {
+			return fmt.Errorf("failed to prune run items: %w", err)
+		}
+		if _, err := tx.Exec("UPDATE fetch_log SET run_kind = NULL WHERE id = ?", id); err != nil {
+			return fmt.Errorf("failed to prune run: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListRuns returns up to limit of source's runs that still have their item
+// lists, newest first, with Partial set on partial ones
+func (s *Storage) ListRuns(source string, limit int) ([]FetchLog, error) {
+	rows, err := s.db.Query(`
+		SELECT id, source, fetched_at, status, items_count, error_message, duration_ms, endpoint, slow, run_kind
+		FROM fetch_log
+		WHERE source = ? AND `+isRun+`
+		ORDER BY id DESC
+		LIMIT ?
+	`, source, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []FetchLog
+	for rows.Next() {
+		var run FetchLog
+		var fetchedAt string
+		var endpoint, kind sql.NullString
+		if err := rows.Scan(&run.ID, &run.Source, &fetchedAt, &run.Status, &run.ItemsCount, &run.ErrorMessage, &run.DurationMs, &endpoint, &run.Slow, &kind); err != nil {
+			return nil, fmt.Errorf("failed to scan run: %w", err)
+		}
+		run.Partial = kind.String == runPartial
+		if t, err := time.Parse(time.RFC3339, fetchedAt); err == nil {
+			run.FetchedAt = t
+		}
+		run.Endpoint = endpoint.String
+		runs = append(runs, run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating runs: %w", err)
+	}
+
+	return runs, nil
+}
+
+// GetRunItems returns the items the run fetchID saw, in feed order
+func (s *Storage) GetRunItems(fetchID int) ([]RunItem, error) {
+	rows, err := s.db.Query(`
+		SELECT item_id, title, url FROM run_items WHERE fetch_id = ? ORDER BY position
+	`, fetchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query run items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []RunItem
+	for rows.Next() {
+		var item RunItem
+		if err := rows.Scan(&item.ItemID, &item.Title, &item.URL); err != nil {
+			return nil, fmt.Errorf("failed to scan run item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating run items: %w", err)
+	}
+
+	return items, nil
+}
+
+// DiffRunItems compares the items of an older run with a newer one's,
+// filling the Added, Removed and Changed lists of a RunDiff. Added and
+// Changed follow the newer run's order, Removed the older's. If the newer
+// run is partial, items it lacks may still be in the feed, so none count
+// as removed.
+func DiffRunItems(older, newer []RunItem, partial bool) RunDiff {
+	var diff RunDiff
+	before := make(map[string]RunItem, len(older))
+	for _, item := range older {
+		before[item.ItemID] = item
+	}
+	after := make(map[string]bool, len(newer))
+
+	for _, item := range newer {
+		after[item.ItemID] = true
+		old, ok := before[item.ItemID]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, item)
+		case old.Title != item.Title || old.URL != item.URL:
+			diff.Changed = append(diff.Changed, RunChange{
+				ItemID:   item.ItemID,
+				OldTitle: old.Title,
+				OldURL:   old.URL,
+				NewTitle: item.Title,
+				NewURL:   item.URL,
+			})
+		}
+	}
+
+	if partial {
+		return diff
+	}
+	for _, item := range older {
+		if !after[item.ItemID] {
+			diff.Removed = append(diff.Removed, item)
+		}
+	}
+
+	return diff
+}
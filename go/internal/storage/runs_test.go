@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRuns_RecordsItemsPerFetch(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	item := func(url, title string) FeedItem {
+		return FeedItem{ID: ItemID(ScopeSource, "GitHub", url, ""), Title: title, URL: url, Source: "GitHub", CreatedAt: now}
+	}
+
+	if _, err := store.Block("https://example.com/spam"); err != nil {
+		t.Fatal(err)
+	}
+	first := []FeedItem{item("https://example.com/a", "A"), item("https://example.com/b", "B"), item("https://example.com/spam", "Spam")}
+	second := []FeedItem{item("https://example.com/c", "C"), item("https://example.com/a", "A (edited)")}
+
+	if _, err := store.SaveFetchResult(FetchLog{Source: "GitHub", FetchedAt: now, Status: "success", ItemsCount: 3}, first); err != nil {
+		t.Fatal(err)
+	}
+	// Failed fetches and other sources don't count as runs
+	store.LogFetch(FetchLog{Source: "GitHub", FetchedAt: now, Status: "error"})
+	if _, err := store.SaveFetchResult(FetchLog{Source: "HN", FetchedAt: now, Status: "success"}, []FeedItem{{ID: "hn", Title: "HN", URL: "https://hn", Source: "HN", CreatedAt: now}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.SaveFetchResult(FetchLog{Source: "GitHub", FetchedAt: now, Status: "degraded", ItemsCount: 2}, second); err != nil {
+		t.Fatal(err)
+	}
+
+	runs, err := store.ListRuns("GitHub", 10)
+	if err != nil {
+		t.Fatalf("ListRuns failed: %v", err)
+	}
+	if len(runs) != 2 || runs[0].Status != "degraded" || runs[1].Status != "success" {
+		t.Fatalf("expected the two GitHub runs newest first, got %+v", runs)
+	}
+
+	older, err := store.GetRunItems(runs[1].ID)
+	if err != nil {
+		t.Fatalf("GetRunItems failed: %v", err)
+	}
+	if len(older) != 2 || older[0].Title != "A" || older[1].Title != "B" {
+		t.Errorf("expected the unblocked items in feed order, got %+v", older)
+	}
+	newer, err := store.GetRunItems(runs[0].ID)
+	if err != nil {
+		t.Fatalf("GetRunItems failed: %v", err)
+	}
+
+	diff := DiffRunItems(older, newer, runs[0].Partial)
+	if len(diff.Added) != 1 || diff.Added[0].Title != "C" {
+		t.Errorf("expected C added, got %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Title != "B" {
+		t.Errorf("expected B removed, got %+v", diff.Removed)
+	}
+	want := []RunChange{{ItemID: item("https://example.com/a", "").ID, OldTitle: "A", OldURL: "https://example.com/a", NewTitle: "A (edited)", NewURL: "https://example.com/a"}}
+	if !reflect.DeepEqual(diff.Changed, want) {
+		t.Errorf("expected A changed, got %+v", diff.Changed)
+	}
+}
+
+func TestRuns_EmptyAndPartialRuns(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	items := []FeedItem{
+		{ID: "a", Title: "A", URL: "https://example.com/a", Source: "GitHub", CreatedAt: now},
+		{ID: "b", Title: "B", URL: "https://example.com/b", Source: "GitHub", CreatedAt: now},
+	}
+	save := func(log FetchLog, items []FeedItem) []RunItem {
+		t.Helper()
+		log.Source, log.FetchedAt, log.Status = "GitHub", now, "success"
+		if _, err := store.SaveFetchResult(log, items); err != nil {
+			t.Fatal(err)
+		}
+		runs, err := store.ListRuns("GitHub", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(runs) != 1 || runs[0].Partial != log.Partial {
+			t.Fatalf("expected the run recorded with partial=%v, got %+v", log.Partial, runs)
+		}
+		runItems, err := store.GetRunItems(runs[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return runItems
+	}
+
+	full := save(FetchLog{ItemsCount: 2}, items)
+
+	// An incremental run only lists what is new, so nothing is removed
+	partial := save(FetchLog{ItemsCount: 1, Partial: true}, items[1:])
+	if diff := DiffRunItems(full, partial, true); len(diff.Removed) != 0 {
+		t.Errorf("expected no removals after a partial run, got %+v", diff.Removed)
+	}
+
+	// A full run that saw nothing is recorded, and everything fell out
+	empty := save(FetchLog{}, nil)
+	if len(empty) != 0 {
+		t.Errorf("expected no items in the empty run, got %+v", empty)
+	}
+	if diff := DiffRunItems(full, empty, false); len(diff.Removed) != 2 {
+		t.Errorf("expected both items removed, got %+v", diff.Removed)
+	}
+
+	if runs, _ := store.ListRuns("GitHub", 10); len(runs) != 3 {
+		t.Errorf("expected 3 runs, got %d", len(runs))
+	}
+}
+
+func TestRuns_KeepsRecentRuns(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	var firstID int
+	for i := 0; i < RunsKept+5; i++ {
+		url := fmt.Sprintf("https://example.com/%d", i)
+		if _, err := store.SaveFetchResult(FetchLog{Source: "HN", FetchedAt: now, Status: "success", ItemsCount: 1}, []FeedItem{{ID: url, Title: url, URL: url, Source: "HN", CreatedAt: now}}); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			runs, _ := store.ListRuns("HN", 1)
+			firstID = runs[0].ID
+		}
+	}
+
+	runs, err := store.ListRuns("HN", 100)
+	if err != nil {
+		t.Fatalf("ListRuns failed: %v", err)
+	}
+	if len(runs) != RunsKept {
+		t.Errorf("expected %d runs kept, got %d", RunsKept, len(runs))
+	}
+	if items, _ := store.GetRunItems(firstID); len(items) != 0 {
+		t.Errorf("expected the oldest run's items to be forgotten, got %+v", items)
+	}
+}
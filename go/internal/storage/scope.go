@@ -0,0 +1,217 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Item uniqueness scopes. The scope decides what an item's ID is derived
+// from, and therefore when two fetched items are the same stored row.
+const (
+	// ScopeGlobal stores each URL once, regardless of source
+	ScopeGlobal = "global"
+	// ScopeSource stores each URL once per source (the default)
+	ScopeSource = "source"
+	// ScopeRun stores each URL once per source per fetch run
+	ScopeRun = "run"
+)
+
+// UniquenessScope returns the scope the stored items were written under
+func (s *Storage) UniquenessScope() (string, error) {
+	var scope string
+	err := s.db.QueryRow("SELECT value FROM meta WHERE key = 'uniqueness_scope'").Scan(&scope)
+	if err == sql.ErrNoRows {
+		return ScopeSource, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read uniqueness scope: %w", err)
+	}
+	return scope, nil
+}
+
+// SetUniquenessScope switches the database to scope, migrating existing
+// items when needed. Narrowing the scope (run → source → global) re-keys
+// items and collapses rows that now share an ID, keeping the oldest.
+// Widening it only relaxes the unique index. It returns the number of
+// rows removed as duplicates.
+func (s *Storage) SetUniquenessScope(scope string) (int, error) {
+	switch scope {
+	case ScopeGlobal, ScopeSource, ScopeRun:
+	default:
+		return 0, fmt.Errorf("unknown uniqueness scope: %s", scope)
+	}
+
+	current, err := s.UniquenessScope()
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	removed := 0
+	if scope != current && scope != ScopeRun {
+		dedup, err := storedDedup(tx)
+		if err != nil {
+			return 0, err
+		}
+		ids := newIDDeriver(tx, scope, dedup)
+		removed, err = rekeyItems(tx, ids.id, "")
+		if err == nil {
+			err = ids.err
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := applyScopeIndex(tx, scope); err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO meta (key, value) VALUES ('uniqueness_scope', ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value
+	`, scope)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record uniqueness scope: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if removed > 0 {
+		if err := s.RebuildSourceStats(); err != nil {
+			return removed, err
+		}
+	}
+
+	return removed, nil
+}
+
+// rekeyItems recomputes the IDs of source's items (every item if source is
+// empty) with idFor, deleting rows whose new ID was already claimed by an
+// older row
+func rekeyItems(tx *sql.Tx, idFor func(source, url string) string, source string) (int, error) {
+	rows, err := tx.Query(`
+		SELECT id, source, url FROM feed_items
+		WHERE ? = '' OR source = ?
+		ORDER BY created_at, id
+	`, source, source)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query items: %w", err)
+	}
+
+	type rekey struct{ oldID, newID string }
+	var changes []rekey
+	var dupes []string
+	seen := make(map[string]bool)
+
+	for rows.Next() {
+		var id, source, url string
+		if err := rows.Scan(&id, &source, &url); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan item: %w", err)
+		}
+
+		newID := idFor(source, url)
+		switch {
+		case seen[newID]:
+			dupes = append(dupes, id)
+		case newID != id:
+			seen[newID] = true
+			changes = append(changes, rekey{id, newID})
+		default:
+			seen[newID] = true
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating items: %w", err)
+	}
+
+	for _, id := range dupes {
+		if _, err := tx.Exec("DELETE FROM feed_items WHERE id = ?", id); err != nil {
+			return 0, fmt.Errorf("failed to remove duplicate item: %w", err)
+		}
+		if _, err := tx.Exec("DELETE FROM item_revisions WHERE item_id = ?", id); err != nil {
+			return 0, fmt.Errorf("failed to remove duplicate item history: %w", err)
+		}
+		if _, err := tx.Exec("DELETE FROM run_items WHERE item_id = ?", id); err != nil {
+			return 0, fmt.Errorf("failed to remove duplicate item runs: %w", err)
+		}
+		if _, err := tx.Exec("DELETE FROM curations WHERE item_id = ?", id); err != nil {
+			return 0, fmt.Errorf("failed to remove duplicate item curation: %w", err)
+		}
+	}
+
+	// Move changed rows through a temporary key first so a new ID never
+	// collides with a row that still holds it under the old scheme
+	for _, c := range changes {
+		if _, err := tx.Exec("UPDATE feed_items SET id = ? WHERE id = ?", "~"+c.newID, c.oldID); err != nil {
+			return 0, fmt.Errorf("failed to rekey item: %w", err)
+		}
+		if _, err := tx.Exec("UPDATE item_revisions SET item_id = ? WHERE item_id = ?", "~"+c.newID, c.oldID); err != nil {
+			return 0, fmt.Errorf("failed to rekey item history: %w", err)
+		}
+		if _, err := tx.Exec("UPDATE run_items SET item_id = ? WHERE item_id = ?", "~"+c.newID, c.oldID); err != nil {
+			return 0, fmt.Errorf("failed to rekey item runs: %w", err)
+		}
+		if _, err := tx.Exec("UPDATE curations SET item_id = ? WHERE item_id = ?", "~"+c.newID, c.oldID); err != nil {
+			return 0, fmt.Errorf("failed to rekey item curation: %w", err)
+		}
+		if _, err := tx.Exec("UPDATE feed_items SET duplicate_of = ? WHERE duplicate_of = ?", "~"+c.newID, c.oldID); err != nil {
+			return 0, fmt.Errorf("failed to rekey item duplicates: %w", err)
+		}
+	}
+	if _, err := tx.Exec("UPDATE feed_items SET id = substr(id, 2) WHERE id LIKE '~%'"); err != nil {
+		return 0, fmt.Errorf("failed to rekey items: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE item_revisions SET item_id = substr(item_id, 2) WHERE item_id LIKE '~%'"); err != nil {
+		return 0, fmt.Errorf("failed to rekey item history: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE run_items SET item_id = substr(item_id, 2) WHERE item_id LIKE '~%'"); err != nil {
+		return 0, fmt.Errorf("failed to rekey item runs: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE curations SET item_id = substr(item_id, 2) WHERE item_id LIKE '~%'"); err != nil {
+		return 0, fmt.Errorf("failed to rekey item curations: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE feed_items SET duplicate_of = substr(duplicate_of, 2) WHERE duplicate_of LIKE '~%'"); err != nil {
+		return 0, fmt.Errorf("failed to rekey item duplicates: %w", err)
+	}
+	if err := renumberPins(tx); err != nil {
+		return 0, err
+	}
+	if err := unlinkOrphans(tx); err != nil {
+		return 0, err
+	}
+
+	return len(dupes), nil
+}
+
+// applyScopeIndex replaces the unique URL index with the one scope requires
+func applyScopeIndex(tx *sql.Tx, scope string) error {
+	stmts := []string{
+		"DROP INDEX IF EXISTS idx_feed_items_unique_url",
+		"DROP INDEX IF EXISTS idx_feed_items_unique_source_url",
+	}
+
+	switch scope {
+	case ScopeGlobal:
+		stmts = append(stmts, "CREATE UNIQUE INDEX idx_feed_items_unique_url ON feed_items(url)")
+	case ScopeSource:
+		stmts = append(stmts, "CREATE UNIQUE INDEX idx_feed_items_unique_source_url ON feed_items(source, url)")
+	}
+
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to apply uniqueness index: %w", err)
+		}
+	}
+
+	return nil
+}
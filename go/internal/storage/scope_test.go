@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestItemID_Scopes(t *testing.T) {
+	url := "https://example.com/a"
+
+	if ItemID(ScopeGlobal, "A", url, "") != ItemID(ScopeGlobal, "B", url, "") {
+		t.Error("global scope must ignore source")
+	}
+	if ItemID(ScopeSource, "A", url, "") == ItemID(ScopeSource, "B", url, "") {
+		t.Error("source scope must distinguish sources")
+	}
+	if ItemID(ScopeSource, "A", url, "run1") != ItemID(ScopeSource, "A", url, "run2") {
+		t.Error("source scope must ignore run")
+	}
+	if ItemID(ScopeRun, "A", url, "run1") == ItemID(ScopeRun, "A", url, "run2") {
+		t.Error("run scope must distinguish runs")
+	}
+	if ItemID("", "A", url, "") != ItemID(ScopeSource, "A", url, "") {
+		t.Error("empty scope must default to source")
+	}
+}
+
+func TestSetUniquenessScope_MigratesToGlobal(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	url := "https://example.com/shared"
+	now := time.Now()
+	store.SaveItems([]FeedItem{
+		{ID: ItemID(ScopeSource, "A", url, ""), Title: "from A", URL: url, Source: "A", CreatedAt: now},
+		{ID: ItemID(ScopeSource, "B", url, ""), Title: "from B", URL: url, Source: "B", CreatedAt: now.Add(time.Second)},
+		{ID: ItemID(ScopeSource, "B", "https://example.com/other", ""), Title: "other", URL: "https://example.com/other", Source: "B", CreatedAt: now},
+	})
+
+	removed, err := store.SetUniquenessScope(ScopeGlobal)
+	if err != nil {
+		t.Fatalf("SetUniquenessScope failed: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("expected 1 duplicate removed, got %d", removed)
+	}
+
+	total, _ := store.GetAllItemsCount()
+	if total != 2 {
+		t.Errorf("expected 2 items after migration, got %d", total)
+	}
+
+	// The oldest row wins and is reachable under its global ID
+	var title string
+	if err := store.db.QueryRow("SELECT title FROM feed_items WHERE id = ?", ItemID(ScopeGlobal, "", url, "")).Scan(&title); err != nil {
+		t.Fatalf("migrated item not found under global ID: %v", err)
+	}
+	if title != "from A" {
+		t.Errorf("expected oldest item to survive, got %q", title)
+	}
+
+	stats, _ := store.GetFetchStats()
+	for _, stat := range stats {
+		if stat.Source == "B" && stat.ItemsCount != 1 {
+			t.Errorf("expected source stats to be rebuilt, got %+v", stat)
+		}
+	}
+
+	scope, _ := store.UniquenessScope()
+	if scope != ScopeGlobal {
+		t.Errorf("expected recorded scope %q, got %q", ScopeGlobal, scope)
+	}
+}
+
+func TestSetUniquenessScope_IndexEnforcesScope(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	if _, err := store.SetUniquenessScope(ScopeSource); err != nil {
+		t.Fatalf("SetUniquenessScope failed: %v", err)
+	}
+
+	url := "https://example.com/a"
+	err = store.SaveItems([]FeedItem{
+		{ID: "one", Title: "T", URL: url, Source: "A", CreatedAt: time.Now()},
+		{ID: "two", Title: "T", URL: url, Source: "A", CreatedAt: time.Now()},
+	})
+	if err == nil {
+		t.Error("expected unique index to reject the same URL twice in one source")
+	}
+
+	if _, err := store.SetUniquenessScope(ScopeRun); err != nil {
+		t.Fatalf("SetUniquenessScope failed: %v", err)
+	}
+	err = store.SaveItems([]FeedItem{
+		{ID: "one", Title: "T", URL: url, Source: "A", CreatedAt: time.Now()},
+		{ID: "two", Title: "T", URL: url, Source: "A", CreatedAt: time.Now()},
+	})
+	if err != nil {
+		t.Errorf("run scope should allow repeated URLs: %v", err)
+	}
+}
+
+func TestSetUniquenessScope_RejectsUnknown(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	if _, err := store.SetUniquenessScope("feed"); err == nil {
+		t.Error("expected error for unknown scope")
+	}
+}
@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// maxReportSnapshots is how many report snapshots are kept
+const maxReportSnapshots = 50
+
+// errorRateJump is the rise in error rate, in percentage points, that
+// DiffStats reports as a change
+const errorRateJump = 20.0
+
+// Report delta statuses
+const (
+	DeltaNew     = "new"
+	DeltaRemoved = "removed"
+	DeltaFailing = "failing"
+	DeltaErrors  = "error rate up"
+	DeltaItems   = "items"
+)
+
+// ReportSnapshot is the per-source stats a report run saw
+type ReportSnapshot struct {
+	TakenAt time.Time
+	Stats   []FetchStats
+}
+
+// SourceDelta is how one source changed between two report snapshots.
+// ErrorRate covers only the fetches made in between; PrevErrorRate is the
+// cumulative rate at the earlier snapshot.
+type SourceDelta struct {
+	Source        string
+	Status        string
+	ItemsDelta    int
+	FetchesDelta  int
+	ErrorsDelta   int
+	PrevErrorRate float64
+	ErrorRate     float64
+}
+
+// SaveReportSnapshot stores stats as the snapshot of a report run, dropping
+// all but the most recent snapshots
+func (s *Storage) SaveReportSnapshot(stats []FetchStats) error {
+	takenAt := s.clock.Now().UTC().Format(time.RFC3339)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Two reports within a second share a timestamp; the later one wins
+	if _, err := tx.Exec("DELETE FROM report_snapshots WHERE taken_at = ?", takenAt); err != nil {
+		return fmt.Errorf("failed to replace report snapshot: %w", err)
+	}
+
+	// An empty report is still a snapshot, recorded by a row with no source
+	if len(stats) == 0 {
+		stats = []FetchStats{{}}
+	}
+	for _, stat := range stats {
+		_, err := tx.Exec(`
+			INSERT INTO report_snapshots (taken_at, source, items_count, error_count, total_fetches, last_success)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, takenAt, stat.Source, stat.ItemsCount, stat.ErrorCount, stat.TotalFetches, stat.LastSuccess)
+		if err != nil {
+			return fmt.Errorf("failed to save report snapshot: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM report_snapshots WHERE taken_at NOT IN (
+			SELECT DISTINCT taken_at FROM report_snapshots ORDER BY taken_at DESC LIMIT ?
+		)
+	`, maxReportSnapshots)
+	if err != nil {
+		return fmt.Errorf("failed to prune report snapshots: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// LatestReportSnapshot returns the most recent report snapshot, or nil if
+// no report has been run yet
+func (s *Storage) LatestReportSnapshot() (*ReportSnapshot, error) {
+	rows, err := s.db.Query(`
+		SELECT taken_at, source, items_count, error_count, total_fetches, last_success
+		FROM report_snapshots
+		WHERE taken_at = (SELECT MAX(taken_at) FROM report_snapshots)
+		ORDER BY source
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query report snapshot: %w", err)
+	}
+	defer rows.Close()
+
+	var snapshot *ReportSnapshot
+	for rows.Next() {
+		var takenAt string
+		var stat FetchStats
+		if err := rows.Scan(&takenAt, &stat.Source, &stat.ItemsCount, &stat.ErrorCount, &stat.TotalFetches, &stat.LastSuccess); err != nil {
+			return nil, fmt.Errorf("failed to scan report snapshot: %w", err)
+		}
+		if snapshot == nil {
+			snapshot = &ReportSnapshot{}
+			if t, err := time.Parse(time.RFC3339, takenAt); err == nil {
+				snapshot.TakenAt = t
+			}
+		}
+		if stat.Source != "" {
+			snapshot.Stats = append(snapshot.Stats, stat)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating report snapshot: %w", err)
+	}
+
+	return snapshot, nil
+}
+
+// DiffStats compares two sets of stats and returns the sources that
+// changed, in source order: sources that appeared or disappeared, sources
+// whose every fetch since prev failed, sources whose error rate since prev
+// jumped, and sources that gained or lost items. Sources that only
+// fetched successfully without new items are left out.
+func DiffStats(prev, cur []FetchStats) []SourceDelta {
+	before := make(map[string]FetchStats, len(prev))
+	for _, stat := range prev {
+		before[stat.Source] = stat
+	}
+
+	var deltas []SourceDelta
+	seen := make(map[string]bool, len(cur))
+	for _, stat := range cur {
+		seen[stat.Source] = true
+		old, existed := before[stat.Source]
+
+		d := SourceDelta{
+			Source:        stat.Source,
+			ItemsDelta:    stat.ItemsCount - old.ItemsCount,
+			FetchesDelta:  stat.TotalFetches - old.TotalFetches,
+			ErrorsDelta:   stat.ErrorCount - old.ErrorCount,
+			PrevErrorRate: errorRate(old.ErrorCount, old.TotalFetches),
+		}
+		d.ErrorRate = errorRate(d.ErrorsDelta, d.FetchesDelta)
+
+		switch {
+		case !existed:
+			d.Status = DeltaNew
+		case d.FetchesDelta > 0 && d.ErrorsDelta >= d.FetchesDelta:
+			d.Status = DeltaFailing
+		case d.ErrorsDelta > 0 && d.ErrorRate-d.PrevErrorRate >= errorRateJump:
+			d.Status = DeltaErrors
+		case d.ItemsDelta != 0:
+			d.Status = DeltaItems
+		default:
+			continue
+		}
+		deltas = append(deltas, d)
+	}
+
+	for _, old := range prev {
+		if seen[old.Source] {
+			continue
+		}
+		deltas = append(deltas, SourceDelta{
+			Source:        old.Source,
+			Status:        DeltaRemoved,
+			ItemsDelta:    -old.ItemsCount,
+			PrevErrorRate: errorRate(old.ErrorCount, old.TotalFetches),
+		})
+	}
+
+	sort.Slice(deltas, func(i, j int) bool {
+		return deltas[i].Source < deltas[j].Source
+	})
+	return deltas
+}
+
+// errorRate returns errors as a percentage of fetches
+func errorRate(errors, fetches int) float64 {
+	if fetches <= 0 {
+		return 0
+	}
+	return float64(errors) / float64(fetches) * 100
+}
@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"feedpulse/internal/clock"
+)
+
+func TestReportSnapshots_SaveAndLatest(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	snapshot, err := store.LatestReportSnapshot()
+	if err != nil {
+		t.Fatalf("LatestReportSnapshot failed: %v", err)
+	}
+	if snapshot != nil {
+		t.Fatalf("expected no snapshot before the first report, got %+v", snapshot)
+	}
+
+	first := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	store.SetClock(clock.Fixed(first))
+	if err := store.SaveReportSnapshot([]FetchStats{{Source: "HN", ItemsCount: 10, TotalFetches: 2}}); err != nil {
+		t.Fatalf("SaveReportSnapshot failed: %v", err)
+	}
+
+	second := first.Add(time.Hour)
+	store.SetClock(clock.Fixed(second))
+	if err := store.SaveReportSnapshot([]FetchStats{
+		{Source: "HN", ItemsCount: 15, TotalFetches: 3},
+		{Source: "Lobsters", ItemsCount: 4, TotalFetches: 1},
+	}); err != nil {
+		t.Fatalf("SaveReportSnapshot failed: %v", err)
+	}
+
+	snapshot, err = store.LatestReportSnapshot()
+	if err != nil {
+		t.Fatalf("LatestReportSnapshot failed: %v", err)
+	}
+	if snapshot == nil || !snapshot.TakenAt.Equal(second) {
+		t.Fatalf("expected the snapshot taken at %v, got %+v", second, snapshot)
+	}
+	if len(snapshot.Stats) != 2 || snapshot.Stats[0].ItemsCount != 15 {
+		t.Errorf("unexpected snapshot stats: %+v", snapshot.Stats)
+	}
+}
+
+func TestReportSnapshots_Empty(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	if err := store.SaveReportSnapshot(nil); err != nil {
+		t.Fatalf("SaveReportSnapshot failed: %v", err)
+	}
+
+	snapshot, err := store.LatestReportSnapshot()
+	if err != nil {
+		t.Fatalf("LatestReportSnapshot failed: %v", err)
+	}
+	if snapshot == nil || len(snapshot.Stats) != 0 {
+		t.Errorf("expected an empty snapshot, got %+v", snapshot)
+	}
+}
+
+func TestReportSnapshots_Pruned(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < maxReportSnapshots+5; i++ {
+		store.SetClock(clock.Fixed(start.Add(time.Duration(i) * time.Minute)))
+		if err := store.SaveReportSnapshot([]FetchStats{{Source: "HN", ItemsCount: i}}); err != nil {
+			t.Fatalf("SaveReportSnapshot failed: %v", err)
+		}
+	}
+
+	var count int
+	if err := store.db.QueryRow("SELECT COUNT(DISTINCT taken_at) FROM report_snapshots").Scan(&count); err != nil {
+		t.Fatalf("failed to count snapshots: %v", err)
+	}
+	if count != maxReportSnapshots {
+		t.Errorf("expected %d snapshots after pruning, got %d", maxReportSnapshots, count)
+	}
+}
+
+func TestDiffStats(t *testing.T) {
+	prev := []FetchStats{
+		{Source: "Flaky", ItemsCount: 50, ErrorCount: 1, TotalFetches: 10},
+		{Source: "Gone", ItemsCount: 7, TotalFetches: 3},
+		{Source: "Quiet", ItemsCount: 20, TotalFetches: 5},
+		{Source: "Steady", ItemsCount: 30, TotalFetches: 5},
+		{Source: "Broken", ItemsCount: 5, TotalFetches: 5},
+	}
+	cur := []FetchStats{
+		{Source: "Broken", ItemsCount: 5, ErrorCount: 2, TotalFetches: 7},
+		{Source: "Flaky", ItemsCount: 52, ErrorCount: 3, TotalFetches: 14},
+		{Source: "Fresh", ItemsCount: 3, TotalFetches: 1},
+		{Source: "Quiet", ItemsCount: 20, TotalFetches: 6},
+		{Source: "Steady", ItemsCount: 34, TotalFetches: 6},
+	}
+
+	deltas := DiffStats(prev, cur)
+
+	want := map[string]string{
+		"Broken": DeltaFailing,
+		"Flaky":  DeltaErrors,
+		"Fresh":  DeltaNew,
+		"Gone":   DeltaRemoved,
+		"Steady": DeltaItems,
+	}
+	if len(deltas) != len(want) {
+		t.Fatalf("expected %d deltas, got %d: %+v", len(want), len(deltas), deltas)
+	}
+	for i, d := range deltas {
+		if i > 0 && deltas[i-1].Source > d.Source {
+			t.Errorf("deltas not sorted by source: %+v", deltas)
+		}
+		if want[d.Source] != d.Status {
+			t.Errorf("%s: expected status %q, got %q", d.Source, want[d.Source], d.Status)
+		}
+	}
+
+	flaky := deltas[1]
+	if flaky.ItemsDelta != 2 || flaky.FetchesDelta != 4 || flaky.ErrorsDelta != 2 {
+		t.Errorf("unexpected Flaky delta: %+v", flaky)
+	}
+	if flaky.ErrorRate != 50 || flaky.PrevErrorRate != 10 {
+		t.Errorf("expected error rate 50%% (was 10%%), got %.1f%% (was %.1f%%)", flaky.ErrorRate, flaky.PrevErrorRate)
+	}
+
+	if gone := deltas[3]; gone.ItemsDelta != -7 {
+		t.Errorf("expected removed source to lose its items, got %+v", gone)
+	}
+}
@@ -0,0 +1,498 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// source_stats is a materialized copy of what GetFetchStatsExact computes,
+// maintained incrementally inside the same transactions that write
+// feed_items and fetch_log so the two can never drift apart.
+
+// addItemCounts bumps items_count for each source by its number of new items
+func addItemCounts(tx *sql.Tx, newBySource map[string]int) error {
+	for source, n := range newBySource {
+		_, err := tx.Exec(`
+			INSERT INTO source_stats (source, items_count) VALUES (?, ?)
+			ON CONFLICT(source) DO UPDATE SET items_count = source_stats.items_count + excluded.items_count
+		`, source, n)
+		if err != nil {
+			return fmt.Errorf("failed to update source stats: %w", err)
+		}
+	}
+	return nil
+}
+
+// addFetchStats folds a single fetch log entry into source_stats
+func addFetchStats(tx *sql.Tx, source, status, fetchedAt string) error {
+	errorCount := 0
+	if status == "error" {
+		errorCount = 1
+	}
+
+	// A degraded fetch still delivered data, and an unchanged one
+	// confirmed the stored data is current, so both count as a success
+	var lastSuccess *string
+	if status == "success" || status == "degraded" || status == "unchanged" {
+		lastSuccess = &fetchedAt
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO source_stats (source, error_count, total_fetches, last_success) VALUES (?, ?, 1, ?)
+		ON CONFLICT(source) DO UPDATE SET
+			error_count = source_stats.error_count + excluded.error_count,
+			total_fetches = source_stats.total_fetches + 1,
+			last_success = CASE
+				WHEN excluded.last_success IS NULL THEN source_stats.last_success
+				WHEN source_stats.last_success IS NULL OR excluded.last_success > source_stats.last_success THEN excluded.last_success
+				ELSE source_stats.last_success
+			END
+	`, source, errorCount, lastSuccess)
+	if err != nil {
+		return fmt.Errorf("failed to update source stats: %w", err)
+	}
+	return nil
+}
+
+// ensureSourceStats populates source_stats for databases created before it
+// existed. An empty stats table next to a non-empty log or item table can
+// only mean the table is new, so it is rebuilt once from the exact query.
+func (s *Storage) ensureSourceStats() error {
+	var populated, needed bool
+	err := s.db.QueryRow(`
+		SELECT
+			EXISTS(SELECT 1 FROM source_stats),
+			EXISTS(SELECT 1 FROM fetch_log) OR EXISTS(SELECT 1 FROM feed_items)
+	`).Scan(&populated, &needed)
+	if err != nil {
+		return fmt.Errorf("failed to inspect source stats: %w", err)
+	}
+
+	if populated || !needed {
+		return nil
+	}
+
+	return s.RebuildSourceStats()
+}
+
+// RebuildSourceStats recomputes source_stats from fetch_log and feed_items
+func (s *Storage) RebuildSourceStats() error {
+	exact, err := s.GetFetchStatsExact()
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM source_stats"); err != nil {
+		return fmt.Errorf("failed to clear source stats: %w", err)
+	}
+
+	for _, stat := range exact {
+		_, err := tx.Exec(`
+			INSERT INTO source_stats (source, items_count, error_count, total_fetches, last_success)
+			VALUES (?, ?, ?, ?, ?)
+		`, stat.Source, stat.ItemsCount, stat.ErrorCount, stat.TotalFetches, stat.LastSuccess)
+		if err != nil {
+			return fmt.Errorf("failed to rebuild source stats: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetFetchStats returns fetch statistics for all sources from the
+// materialized source_stats table
+func (s *Storage) GetFetchStats() ([]FetchStats, error) {
+	rows, err := s.db.Query(`
+		SELECT source, items_count, error_count, total_fetches, last_success
+		FROM source_stats
+		ORDER BY source
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query fetch stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []FetchStats
+	for rows.Next() {
+		var stat FetchStats
+		if err := rows.Scan(&stat.Source, &stat.ItemsCount, &stat.ErrorCount, &stat.TotalFetches, &stat.LastSuccess); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		stats = append(stats, stat)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return stats, nil
+}
+
+// Bucket sizes accepted by GetStatsBuckets and GetItemBuckets
+const (
+	BucketHour = "hour"
+	BucketDay  = "day"
+)
+
+// StatsBucket holds fetch activity aggregated over one time bucket
+type StatsBucket struct {
+	Start         time.Time
+	Fetches       int
+	Errors        int
+	Items         int
+	AvgDurationMs float64
+	MaxDurationMs int64
+}
+
+// GetStatsBuckets aggregates fetch_log into hourly or daily buckets
+// covering the last window, oldest first. Buckets are aligned to UTC and
+// only returned when they contain at least one fetch. An empty source
+// aggregates across all sources.
+func (s *Storage) GetStatsBuckets(source, bucket string, window time.Duration) ([]StatsBucket, error) {
+	format, err := bucketFormat(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := s.clock.Now().Add(-window).UTC().Format(time.RFC3339)
+
+	rows, err := s.db.Query(`
+		SELECT
+			strftime(?, fetched_at) AS bucket,
+			COUNT(*),
+			SUM(CASE WHEN status = 'error' THEN 1 ELSE 0 END),
+			COALESCE(SUM(items_count), 0),
+			COALESCE(AVG(duration_ms), 0),
+			COALESCE(MAX(duration_ms), 0)
+		FROM fetch_log
+		WHERE julianday(fetched_at) >= julianday(?)
+			AND (? = '' OR source = ?)
+		GROUP BY bucket
+		ORDER BY bucket
+	`, format, cutoff, source, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stats buckets: %w", err)
+	}
+	defer rows.Close()
+
+	var buckets []StatsBucket
+	for rows.Next() {
+		var b StatsBucket
+		var start string
+		if err := rows.Scan(&start, &b.Fetches, &b.Errors, &b.Items, &b.AvgDurationMs, &b.MaxDurationMs); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		if t, err := time.Parse(time.RFC3339, start); err == nil {
+			b.Start = t
+		}
+		buckets = append(buckets, b)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return buckets, nil
+}
+
+// GetItemBuckets counts the items published within the last window in
+// hourly or daily buckets, oldest first, filling in only Start and Items.
+// Items are bucketed by their own timestamp, falling back to when they
+// were stored if it is missing or unparseable. Buckets are aligned to UTC
+// and only returned when they contain at least one item. An empty source
+// counts across all sources.
+func (s *Storage) GetItemBuckets(source, bucket string, window time.Duration) ([]StatsBucket, error) {
+	format, err := bucketFormat(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := s.clock.Now().Add(-window).UTC().Format(time.RFC3339)
+
+	rows, err := s.db.Query(`
+		SELECT strftime(?, published) AS bucket, COUNT(*)
+		FROM (
+			SELECT CASE WHEN julianday(timestamp) IS NOT NULL THEN timestamp ELSE created_at END AS published
+			FROM feed_items
+			WHERE ? = '' OR source = ?
+		) AS items
+		WHERE julianday(published) >= julianday(?)
+		GROUP BY bucket
+		ORDER BY bucket
+	`, format, source, source, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query item buckets: %w", err)
+	}
+	defer rows.Close()
+
+	var buckets []StatsBucket
+	for rows.Next() {
+		var b StatsBucket
+		var start string
+		if err := rows.Scan(&start, &b.Items); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		if t, err := time.Parse(time.RFC3339, start); err == nil {
+			b.Start = t
+		}
+		buckets = append(buckets, b)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return buckets, nil
+}
+
+// bucketFormat returns the strftime format truncating a time to bucket
+func bucketFormat(bucket string) (string, error) {
+	switch bucket {
+	case BucketHour:
+		return "%Y-%m-%dT%H:00:00Z", nil
+	case BucketDay:
+		return "%Y-%m-%dT00:00:00Z", nil
+	default:
+		return "", fmt.Errorf("unknown bucket size: %s (expected %s or %s)", bucket, BucketHour, BucketDay)
+	}
+}
+
+// LastSuccess returns when source was last fetched successfully
+func (s *Storage) LastSuccess(source string) (time.Time, bool, error) {
+	var lastSuccess *string
+	err := s.db.QueryRow("SELECT last_success FROM source_stats WHERE source = ?", source).Scan(&lastSuccess)
+	if err == sql.ErrNoRows || (err == nil && lastSuccess == nil) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to get last success: %w", err)
+	}
+
+	t, err := time.Parse(time.RFC3339, *lastSuccess)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid last success time %q: %w", *lastSuccess, err)
+	}
+	return t, true, nil
+}
+
+// GetConsecutiveFailures returns, for each source whose latest fetches
+// failed, how many failed in a row since its last successful fetch
+func (s *Storage) GetConsecutiveFailures() (map[string]int, error) {
+	rows, err := s.db.Query(`
+		SELECT source, COUNT(*)
+		FROM fetch_log AS f
+		WHERE status = 'error'
+			AND id > COALESCE((
+				SELECT MAX(id) FROM fetch_log
+				WHERE source = f.source AND status IN ('success', 'degraded', 'unchanged')
+			), 0)
+		GROUP BY source
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query consecutive failures: %w", err)
+	}
+	defer rows.Close()
+
+	failures := make(map[string]int)
+	for rows.Next() {
+		var source string
+		var n int
+		if err := rows.Scan(&source, &n); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		failures[source] = n
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return failures, nil
+}
+
+// NewestItemTime returns the publish time of source's newest stored item,
+// falling back to when items were stored if their timestamp is missing or
+// unparseable
+func (s *Storage) NewestItemTime(source string) (time.Time, bool, error) {
+	var newest *string
+	err := s.db.QueryRow(`
+		SELECT strftime('%Y-%m-%dT%H:%M:%SZ', MAX(
+			CASE WHEN julianday(timestamp) IS NOT NULL THEN julianday(timestamp) ELSE julianday(created_at) END
+		))
+		FROM feed_items
+		WHERE source = ?
+	`, source).Scan(&newest)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to get newest item: %w", err)
+	}
+	if newest == nil {
+		return time.Time{}, false, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, *newest)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid newest item time %q: %w", *newest, err)
+	}
+	return t, true, nil
+}
+
+// TagStats holds the items stored under one tag, compared case-insensitively
+type TagStats struct {
+	Tag        string    `json:"tag"`
+	ItemsCount int       `json:"items_count"`
+	Sources    int       `json:"sources"`
+	LastStored time.Time `json:"last_stored"`
+}
+
+// GetTagStats returns item counts per tag of the items filter matches by
+// source, tag and when they were stored, most used first. Tags are
+// lowercased; untagged items are left out, and items linked as duplicates
+// count once with their original.
+func (s *Storage) GetTagStats(filter ItemFilter) ([]TagStats, error) {
+	where, args := itemConditions(filter)
+	rows, err := s.db.Query(`
+		SELECT lower(tag.value), COUNT(DISTINCT COALESCE(feed_items.duplicate_of, feed_items.id)), COUNT(DISTINCT feed_items.source),
+			strftime('%Y-%m-%dT%H:%M:%SZ', MAX(julianday(feed_items.created_at)))
+		FROM feed_items, json_each(feed_items.tags) AS tag
+		WHERE feed_items.tags IS NOT NULL AND `+where+`
+		GROUP BY lower(tag.value)
+		ORDER BY 2 DESC, 1
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tag stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []TagStats
+	for rows.Next() {
+		var stat TagStats
+		var lastStored string
+		if err := rows.Scan(&stat.Tag, &stat.ItemsCount, &stat.Sources, &lastStored); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		if t, err := time.Parse(time.RFC3339, lastStored); err == nil {
+			stat.LastStored = t
+		}
+		stats = append(stats, stat)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return stats, nil
+}
+
+// GroupFetchStats adds up per-source stats into one row per group, in
+// group order. groups maps sources to their group; the Source of each row
+// is the group name, and sources without one are grouped under
+// ungrouped. A group's last success is its most recent source's.
+func GroupFetchStats(stats []FetchStats, groups map[string]string, ungrouped string) []FetchStats {
+	byGroup := make(map[string]*FetchStats)
+	var names []string
+	for _, stat := range stats {
+		name, ok := groups[stat.Source]
+		if !ok || name == "" {
+			name = ungrouped
+		}
+		group, ok := byGroup[name]
+		if !ok {
+			group = &FetchStats{Source: name}
+			byGroup[name] = group
+			names = append(names, name)
+		}
+		group.ItemsCount += stat.ItemsCount
+		group.ErrorCount += stat.ErrorCount
+		group.TotalFetches += stat.TotalFetches
+		if stat.LastSuccess != nil && (group.LastSuccess == nil || laterTimestamp(*stat.LastSuccess, *group.LastSuccess)) {
+			last := *stat.LastSuccess
+			group.LastSuccess = &last
+		}
+	}
+
+	sort.Strings(names)
+	grouped := make([]FetchStats, 0, len(names))
+	for _, name := range names {
+		grouped = append(grouped, *byGroup[name])
+	}
+	return grouped
+}
+
+// laterTimestamp reports whether RFC 3339 timestamp a is after b; ones
+// that don't parse compare as text
+func laterTimestamp(a, b string) bool {
+	ta, errA := time.Parse(time.RFC3339, a)
+	tb, errB := time.Parse(time.RFC3339, b)
+	if errA != nil || errB != nil {
+		return a > b
+	}
+	return ta.After(tb)
+}
+
+// Slow fetch detection: a successful fetch is slow when it took longer
+// than SlowPercentile of its source's last SlowHistory successful fetches.
+// Sources with fewer than SlowMinHistory of them have no threshold yet.
+const (
+	SlowPercentile = 0.99
+	SlowHistory    = 200
+	SlowMinHistory = 20
+)
+
+// SlowThreshold returns the duration above which a source's next fetch
+// counts as slow, or false while its history is too short to tell
+func (s *Storage) SlowThreshold(source string) (int64, bool, error) {
+	rows, err := s.db.Query(`
+		SELECT duration_ms FROM fetch_log
+		WHERE source = ? AND status IN ('success', 'degraded', 'unchanged') AND duration_ms IS NOT NULL
+		ORDER BY id DESC
+		LIMIT ?
+	`, source, SlowHistory)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to query fetch durations: %w", err)
+	}
+	defer rows.Close()
+
+	var durations []int64
+	for rows.Next() {
+		var d int64
+		if err := rows.Scan(&d); err != nil {
+			return 0, false, fmt.Errorf("failed to scan row: %w", err)
+		}
+		durations = append(durations, d)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, false, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	if len(durations) < SlowMinHistory {
+		return 0, false, nil
+	}
+	return Percentile(durations, SlowPercentile), true, nil
+}
+
+// Percentile returns the nearest-rank p-th percentile (0 < p <= 1) of
+// values, which it sorts in place
+func Percentile(values []int64, p float64) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	rank := int(math.Ceil(p * float64(len(values))))
+	if rank < 1 {
+		rank = 1
+	}
+	return values[rank-1]
+}
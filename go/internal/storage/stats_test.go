@@ -0,0 +1,421 @@
+package storage
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"feedpulse/internal/clock"
+)
+
+func TestGetFetchStats_MatchesExact(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	errMsg := "boom"
+
+	store.SaveItems([]FeedItem{
+		{ID: "1", Title: "T1", URL: "u1", Source: "A", CreatedAt: now},
+		{ID: "2", Title: "T2", URL: "u2", Source: "A", CreatedAt: now},
+	})
+	store.SaveFetchResult(FetchLog{Source: "B", FetchedAt: now, Status: "success"}, []FeedItem{
+		{ID: "3", Title: "T3", URL: "u3", Source: "B", CreatedAt: now},
+		{ID: "1", Title: "T1 again", URL: "u1", Source: "A", CreatedAt: now},
+	})
+	store.LogFetch(FetchLog{Source: "A", FetchedAt: now.Add(-time.Hour), Status: "success"})
+	store.LogFetch(FetchLog{Source: "A", FetchedAt: now, Status: "error", ErrorMessage: &errMsg})
+	store.LogFetch(FetchLog{Source: "C", FetchedAt: now, Status: "error", ErrorMessage: &errMsg})
+	store.LogFetch(FetchLog{Source: "D", FetchedAt: now, Status: "degraded", ErrorMessage: &errMsg})
+
+	got, err := store.GetFetchStats()
+	if err != nil {
+		t.Fatalf("GetFetchStats failed: %v", err)
+	}
+	want, err := store.GetFetchStatsExact()
+	if err != nil {
+		t.Fatalf("GetFetchStatsExact failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("materialized stats diverged from exact:\n got  %+v\n want %+v", got, want)
+	}
+
+	// A degraded fetch delivered data: it is a success, not an error
+	for _, stat := range got {
+		if stat.Source == "D" && (stat.LastSuccess == nil || stat.ErrorCount != 0) {
+			t.Errorf("expected degraded fetch to count as a success, got %+v", stat)
+		}
+	}
+}
+
+func TestGetFetchStats_BackfillsExistingDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	store, err := NewStorage(dbPath)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+
+	store.SaveItems([]FeedItem{{ID: "1", Title: "T1", URL: "u1", Source: "A", CreatedAt: time.Now()}})
+	store.LogFetch(FetchLog{Source: "A", FetchedAt: time.Now(), Status: "success"})
+
+	// Simulate a database from before source_stats existed
+	if _, err := store.db.Exec("DELETE FROM source_stats"); err != nil {
+		t.Fatalf("failed to clear source_stats: %v", err)
+	}
+	store.Close()
+
+	store, err = NewStorage(dbPath)
+	if err != nil {
+		t.Fatalf("failed to reopen storage: %v", err)
+	}
+	defer store.Close()
+
+	stats, err := store.GetFetchStats()
+	if err != nil {
+		t.Fatalf("GetFetchStats failed: %v", err)
+	}
+	if len(stats) != 1 || stats[0].ItemsCount != 1 || stats[0].TotalFetches != 1 {
+		t.Errorf("expected stats to be rebuilt on open, got %+v", stats)
+	}
+}
+
+func TestGetStatsBuckets_Hourly(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	hour := time.Now().UTC().Truncate(time.Hour)
+	errMsg := "boom"
+
+	store.LogFetch(FetchLog{Source: "A", FetchedAt: hour.Add(-2 * time.Hour), Status: "success", ItemsCount: 5, DurationMs: 100})
+	store.LogFetch(FetchLog{Source: "A", FetchedAt: hour.Add(time.Minute), Status: "success", ItemsCount: 3, DurationMs: 200})
+	store.LogFetch(FetchLog{Source: "A", FetchedAt: hour.Add(2 * time.Minute), Status: "error", ErrorMessage: &errMsg, DurationMs: 400})
+	store.LogFetch(FetchLog{Source: "B", FetchedAt: hour.Add(time.Minute), Status: "success", ItemsCount: 7, DurationMs: 50})
+	store.LogFetch(FetchLog{Source: "A", FetchedAt: hour.Add(-48 * time.Hour), Status: "success", ItemsCount: 1})
+
+	buckets, err := store.GetStatsBuckets("A", BucketHour, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("GetStatsBuckets failed: %v", err)
+	}
+	if len(buckets) != 2 {
+		t.Fatalf("expected 2 buckets inside the window, got %d: %+v", len(buckets), buckets)
+	}
+
+	latest := buckets[1]
+	if !latest.Start.Equal(hour) {
+		t.Errorf("expected bucket start %v, got %v", hour, latest.Start)
+	}
+	if latest.Fetches != 2 || latest.Errors != 1 || latest.Items != 3 {
+		t.Errorf("unexpected bucket counts: %+v", latest)
+	}
+	if latest.AvgDurationMs != 300 || latest.MaxDurationMs != 400 {
+		t.Errorf("unexpected bucket latency: %+v", latest)
+	}
+
+	all, err := store.GetStatsBuckets("", BucketDay, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("GetStatsBuckets failed: %v", err)
+	}
+	fetches := 0
+	for _, b := range all {
+		fetches += b.Fetches
+	}
+	if fetches != 4 {
+		t.Errorf("expected 4 fetches across sources in window, got %d", fetches)
+	}
+}
+
+func TestGetStatsBuckets_UnknownBucket(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	if _, err := store.GetStatsBuckets("", "week", time.Hour); err == nil {
+		t.Error("expected error for unknown bucket size")
+	}
+}
+
+func TestLastSuccess(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	if _, ok, err := store.LastSuccess("A"); ok || err != nil {
+		t.Errorf("expected no last success for unknown source, got ok=%v err=%v", ok, err)
+	}
+
+	errMsg := "boom"
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.LogFetch(FetchLog{Source: "A", FetchedAt: at, Status: "success"})
+	store.LogFetch(FetchLog{Source: "A", FetchedAt: at.Add(time.Hour), Status: "error", ErrorMessage: &errMsg})
+
+	got, ok, err := store.LastSuccess("A")
+	if err != nil || !ok {
+		t.Fatalf("LastSuccess() ok=%v err=%v", ok, err)
+	}
+	if !got.Equal(at) {
+		t.Errorf("expected last success %v, got %v", at, got)
+	}
+
+	store.LogFetch(FetchLog{Source: "B", FetchedAt: at, Status: "error", ErrorMessage: &errMsg})
+	if _, ok, _ := store.LastSuccess("B"); ok {
+		t.Error("expected no last success for a source that only failed")
+	}
+}
+
+func TestGetConsecutiveFailures(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	errMsg := "boom"
+	for _, entry := range []FetchLog{
+		{Source: "A", Status: "error"},
+		{Source: "A", Status: "success"},
+		{Source: "A", Status: "error"},
+		{Source: "A", Status: "error"},
+		{Source: "B", Status: "error"},
+		{Source: "B", Status: "unchanged"},
+		{Source: "C", Status: "error"},
+		{Source: "D", Status: "error"},
+		{Source: "C", Status: "degraded"},
+	} {
+		entry.FetchedAt = now
+		if entry.Status == "error" || entry.Status == "degraded" {
+			entry.ErrorMessage = &errMsg
+		}
+		store.LogFetch(entry)
+	}
+
+	failures, err := store.GetConsecutiveFailures()
+	if err != nil {
+		t.Fatalf("GetConsecutiveFailures failed: %v", err)
+	}
+
+	want := map[string]int{"A": 2, "D": 1}
+	if !reflect.DeepEqual(failures, want) {
+		t.Errorf("expected %v, got %v", want, failures)
+	}
+}
+
+func TestGetItemBuckets_Daily(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	store.SetClock(clock.Fixed(now))
+
+	ts := func(s string) *string { return &s }
+	store.SaveItems([]FeedItem{
+		{ID: "1", Title: "T1", URL: "u1", Source: "A", Timestamp: ts("2026-03-09T08:00:00Z"), CreatedAt: now},
+		{ID: "2", Title: "T2", URL: "u2", Source: "A", Timestamp: ts("2026-03-09T22:00:00-05:00"), CreatedAt: now},
+		{ID: "3", Title: "T3", URL: "u3", Source: "A", Timestamp: ts("not a date"), CreatedAt: now},
+		{ID: "4", Title: "T4", URL: "u4", Source: "A", Timestamp: ts("2026-01-01T00:00:00Z"), CreatedAt: now},
+		{ID: "5", Title: "T5", URL: "u5", Source: "B", Timestamp: ts("2026-03-09T09:00:00Z"), CreatedAt: now},
+	})
+
+	buckets, err := store.GetItemBuckets("A", BucketDay, 7*24*time.Hour)
+	if err != nil {
+		t.Fatalf("GetItemBuckets failed: %v", err)
+	}
+
+	// The -05:00 timestamp falls on the 10th in UTC, next to the item
+	// without a usable timestamp
+	want := []StatsBucket{
+		{Start: time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC), Items: 1},
+		{Start: time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC), Items: 2},
+	}
+	if !reflect.DeepEqual(buckets, want) {
+		t.Errorf("expected %+v, got %+v", want, buckets)
+	}
+
+	all, err := store.GetItemBuckets("", BucketDay, 7*24*time.Hour)
+	if err != nil {
+		t.Fatalf("GetItemBuckets failed: %v", err)
+	}
+	if len(all) != 2 || all[0].Items != 2 {
+		t.Errorf("expected B's item in the first bucket, got %+v", all)
+	}
+}
+
+func TestNewestItemTime(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	if _, ok, err := store.NewestItemTime("A"); err != nil || ok {
+		t.Fatalf("expected no newest item for an empty source, got ok=%v err=%v", ok, err)
+	}
+
+	stored := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	ts := func(s string) *string { return &s }
+	store.SaveItems([]FeedItem{
+		{ID: "1", Title: "T1", URL: "u1", Source: "A", Timestamp: ts("2026-03-01T10:00:00+02:00"), CreatedAt: stored},
+		{ID: "2", Title: "T2", URL: "u2", Source: "A", Timestamp: ts("2026-02-27T00:00:00Z"), CreatedAt: stored},
+		{ID: "3", Title: "T3", URL: "u3", Source: "B", CreatedAt: stored},
+	})
+
+	newest, ok, err := store.NewestItemTime("A")
+	if err != nil || !ok {
+		t.Fatalf("NewestItemTime failed: ok=%v err=%v", ok, err)
+	}
+	if want := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC); !newest.Equal(want) {
+		t.Errorf("expected %v, got %v", want, newest)
+	}
+
+	// Without a timestamp, the time the item was stored counts
+	newest, _, _ = store.NewestItemTime("B")
+	if !newest.Equal(stored) {
+		t.Errorf("expected %v, got %v", stored, newest)
+	}
+}
+
+func TestGetTagStats(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	if err := store.SaveItems([]FeedItem{
+		{ID: "1", Title: "A", URL: "https://example.com/1", Source: "HN", Tags: []string{"Go", "go"}, CreatedAt: now.Add(-3 * time.Hour)},
+		{ID: "2", Title: "B", URL: "https://example.com/2", Source: "Lobsters", Tags: []string{"go", "rust"}, CreatedAt: now.Add(-time.Hour)},
+		{ID: "3", Title: "C", URL: "https://example.com/3", Source: "HN", CreatedAt: now},
+	}); err != nil {
+		t.Fatalf("SaveItems failed: %v", err)
+	}
+
+	stats, err := store.GetTagStats(ItemFilter{})
+	if err != nil {
+		t.Fatalf("GetTagStats failed: %v", err)
+	}
+	want := []TagStats{
+		{Tag: "go", ItemsCount: 2, Sources: 2, LastStored: now.Add(-time.Hour)},
+		{Tag: "rust", ItemsCount: 1, Sources: 1, LastStored: now.Add(-time.Hour)},
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("got %+v, want %+v", stats, want)
+	}
+
+	stats, err = store.GetTagStats(ItemFilter{Source: "HN"})
+	if err != nil {
+		t.Fatalf("GetTagStats failed: %v", err)
+	}
+	if want := []TagStats{{Tag: "go", ItemsCount: 1, Sources: 1, LastStored: now.Add(-3 * time.Hour)}}; !reflect.DeepEqual(stats, want) {
+		t.Errorf("by source: got %+v, want %+v", stats, want)
+	}
+	stats, err = store.GetTagStats(ItemFilter{Since: now.Add(-2 * time.Hour)})
+	if err != nil {
+		t.Fatalf("GetTagStats failed: %v", err)
+	}
+	if len(stats) != 2 || stats[0].ItemsCount != 1 || stats[1].ItemsCount != 1 {
+		t.Errorf("since: expected only the newer item counted, got %+v", stats)
+	}
+}
+
+func TestGroupFetchStats(t *testing.T) {
+	ts := func(s string) *string { return &s }
+	stats := []FetchStats{
+		{Source: "HN", ItemsCount: 10, ErrorCount: 1, TotalFetches: 5, LastSuccess: ts("2024-01-02T10:00:00Z")},
+		{Source: "Lobsters", ItemsCount: 4, TotalFetches: 3, LastSuccess: ts("2024-01-02T12:00:00Z")},
+		{Source: "Reddit", ItemsCount: 2, ErrorCount: 2, TotalFetches: 2},
+	}
+	groups := map[string]string{"HN": "news", "Lobsters": "news"}
+
+	got := GroupFetchStats(stats, groups, "(none)")
+	want := []FetchStats{
+		{Source: "(none)", ItemsCount: 2, ErrorCount: 2, TotalFetches: 2},
+		{Source: "news", ItemsCount: 14, ErrorCount: 1, TotalFetches: 8, LastSuccess: ts("2024-01-02T12:00:00Z")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := func() []int64 {
+		v := make([]int64, 100)
+		for i := range v {
+			v[i] = int64(100 - i)
+		}
+		return v
+	}
+	tests := []struct {
+		values []int64
+		p      float64
+		want   int64
+	}{
+		{values(), 0.99, 99},
+		{values(), 0.5, 50},
+		{values(), 1, 100},
+		{[]int64{7}, 0.99, 7},
+		{[]int64{30, 10, 20}, 0.99, 30},
+		{nil, 0.99, 0},
+	}
+	for _, tt := range tests {
+		if got := Percentile(tt.values, tt.p); got != tt.want {
+			t.Errorf("Percentile(%d values, %g) = %d, want %d", len(tt.values), tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestSlowThreshold(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	logN := func(n int, status string, duration int64) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			if err := store.LogFetch(FetchLog{Source: "HN", FetchedAt: now, Status: status, DurationMs: duration}); err != nil {
+				t.Fatalf("LogFetch failed: %v", err)
+			}
+		}
+	}
+
+	logN(SlowMinHistory-1, "success", 100)
+	logN(5, "error", 30000)
+	if _, ok, err := store.SlowThreshold("HN"); err != nil || ok {
+		t.Fatalf("expected no threshold before %d successful fetches, got ok=%v err=%v", SlowMinHistory, ok, err)
+	}
+
+	logN(1, "success", 400)
+	threshold, ok, err := store.SlowThreshold("HN")
+	if err != nil || !ok {
+		t.Fatalf("SlowThreshold failed: ok=%v err=%v", ok, err)
+	}
+	if threshold != 400 {
+		t.Errorf("expected p99 of 400ms, errors ignored, got %d", threshold)
+	}
+
+	if err := store.LogFetch(FetchLog{Source: "HN", FetchedAt: now, Status: "success", DurationMs: 900, Slow: true}); err != nil {
+		t.Fatalf("LogFetch failed: %v", err)
+	}
+	var slow bool
+	if err := store.db.QueryRow("SELECT slow FROM fetch_log ORDER BY id DESC LIMIT 1").Scan(&slow); err != nil || !slow {
+		t.Errorf("expected the slow flag to be stored, got %v (%v)", slow, err)
+	}
+}
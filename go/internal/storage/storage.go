@@ -6,7 +6,7 @@ import (
 	"fmt"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"feedpulse/internal/clock"
 )
 
 // FeedItem represents a normalized feed item
@@ -19,6 +19,14 @@ type FeedItem struct {
 	Tags      []string  `json:"tags,omitempty"`
 	RawData   *string   `json:"raw_data,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
+	// ReadAt and Starred are set with ApplyItemBatch; only GetItems
+	// reads them back
+	ReadAt  *time.Time `json:"read_at,omitempty"`
+	Starred bool       `json:"starred,omitempty"`
+	// DuplicateOf is the ID of the item another source stored first with
+	// the same normalized URL, under DedupCrossSource. Only GetItems
+	// reads it back.
+	DuplicateOf string `json:"duplicate_of,omitempty"`
 }
 
 // FetchLog represents a fetch operation log entry
@@ -30,6 +38,17 @@ type FetchLog struct {
 	ItemsCount   int
 	ErrorMessage *string
 	DurationMs   int64
+	Endpoint     string
+	// Slow marks a fetch that took longer than SlowPercentile of its
+	// source's recent fetches
+	Slow bool
+	// Failure is the response of a fetch that failed to parse, recorded
+	// in parse_failures when the entry is logged
+	Failure *CapturedResponse
+	// Partial marks a successful fetch that saw only part of the feed,
+	// e.g. an incremental one continuing from its cursor, so the items it
+	// lacks didn't fall out of the feed. It is kept for runs only.
+	Partial bool
 }
 
 // FetchStats represents statistics for a feed source
@@ -41,18 +60,42 @@ type FetchStats struct {
 	LastSuccess  *string
 }
 
-// Storage handles database operations
+// Connection pool sizing. SQLite allows a single writer, but WAL mode lets
+// readers proceed alongside it, so a small pool of long-lived connections
+// keeps reads concurrent while letting prepared statements stay warm.
+const (
+	maxOpenConns = 4
+	maxIdleConns = 4
+)
+
+// Storage handles database operations, on a SQLite file or, opened with
+// NewPostgresStorage, a PostgreSQL database
 type Storage struct {
-	db *sql.DB
+	db        *sql.DB
+	stmts     statements
+	clock     clock.Clock
+	cookieKey []byte
+	postgres  bool
+}
+
+// statements holds prepared statements reused across calls
+type statements struct {
+	upsertItem *sql.Stmt
+	itemLookup *sql.Stmt
+	logFetch   *sql.Stmt
 }
 
 // NewStorage creates a new storage instance
 func NewStorage(dbPath string) (*Storage, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sql.Open(driverName, dataSourceName(dbPath))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetMaxIdleConns(maxIdleConns)
+	db.SetConnMaxLifetime(0)
+
 	// Test connection
 	if err := db.Ping(); err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
@@ -63,26 +106,85 @@ func NewStorage(dbPath string) (*Storage, error) {
 		return nil, fmt.Errorf("failed to enable WAL mode: %w", err)
 	}
 
-	// Set busy timeout
-	if _, err := db.Exec("PRAGMA busy_timeout=5000"); err != nil {
-		return nil, fmt.Errorf("failed to set busy timeout: %w", err)
+	s := &Storage{db: db, clock: clock.System}
+	if err := s.setup(); err != nil {
+		return nil, err
 	}
 
-	s := &Storage{db: db}
+	return s, nil
+}
 
+// setup brings the schema of a newly opened database up to date and
+// prepares the statements used on the write path
+func (s *Storage) setup() error {
 	// Initialize schema
 	if err := s.initSchema(); err != nil {
-		return nil, err
+		return err
 	}
 
-	return s, nil
+	if err := s.migrateSchema(); err != nil {
+		return err
+	}
+
+	if err := s.prepareStatements(); err != nil {
+		return err
+	}
+
+	return s.ensureSourceStats()
+}
+
+// SetClock sets the clock used to timestamp journal, blocklist and
+// revision records
+func (s *Storage) SetClock(c clock.Clock) {
+	s.clock = c
 }
 
 // Close closes the database connection
 func (s *Storage) Close() error {
+	for _, stmt := range []*sql.Stmt{s.stmts.upsertItem, s.stmts.itemLookup, s.stmts.logFetch} {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
 	return s.db.Close()
 }
 
+// prepareStatements prepares the statements used on the write path once,
+// so repeated small batches don't pay for re-parsing the SQL
+func (s *Storage) prepareStatements() error {
+	var err error
+
+	s.stmts.upsertItem, err = s.db.Prepare(`
+		INSERT INTO feed_items (id, title, url, source, timestamp, tags, raw_data, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			title = excluded.title,
+			url = excluded.url,
+			timestamp = excluded.timestamp,
+			tags = excluded.tags,
+			raw_data = excluded.raw_data
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+
+	s.stmts.itemLookup, err = s.db.Prepare("SELECT title, url, user_tags FROM feed_items WHERE id = ?")
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+
+	s.stmts.logFetch, err = s.db.Prepare(`
+		INSERT INTO fetch_log (source, fetched_at, status, items_count, error_message, duration_ms, endpoint, slow)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+
+	return nil
+}
+
 // initSchema creates tables and indexes if they don't exist
 func (s *Storage) initSchema() error {
 	schema := `
@@ -94,7 +196,12 @@ CREATE TABLE IF NOT EXISTS feed_items (
     timestamp TEXT,
     tags TEXT,
     raw_data TEXT,
-    created_at TEXT NOT NULL
+    created_at TEXT NOT NULL,
+    read_at TEXT,
+    starred INTEGER NOT NULL DEFAULT 0,
+    user_tags TEXT,
+    url_key TEXT,
+    duplicate_of TEXT
 );
 
 CREATE TABLE IF NOT EXISTS fetch_log (
@@ -104,14 +211,189 @@ CREATE TABLE IF NOT EXISTS fetch_log (
     status TEXT NOT NULL,
     items_count INTEGER DEFAULT 0,
     error_message TEXT,
-    duration_ms INTEGER
+    duration_ms INTEGER,
+    endpoint TEXT,
+    slow INTEGER NOT NULL DEFAULT 0,
+    failure_id INTEGER,
+    run_kind TEXT
+);
+
+CREATE TABLE IF NOT EXISTS fetch_journal (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    source TEXT NOT NULL,
+    feed_type TEXT NOT NULL,
+    payload BLOB NOT NULL,
+    fetched_at TEXT NOT NULL,
+    processed INTEGER NOT NULL DEFAULT 0
 );
 
 CREATE INDEX IF NOT EXISTS idx_feed_items_source ON feed_items(source);
 CREATE INDEX IF NOT EXISTS idx_feed_items_timestamp ON feed_items(timestamp);
+CREATE TABLE IF NOT EXISTS blocklist (
+    value TEXT PRIMARY KEY,
+    kind TEXT NOT NULL,
+    created_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS item_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    item_id TEXT NOT NULL,
+    old_title TEXT NOT NULL,
+    old_url TEXT NOT NULL,
+    new_title TEXT NOT NULL,
+    new_url TEXT NOT NULL,
+    changed_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS backfill_cursors (
+    feed TEXT PRIMARY KEY,
+    page INTEGER NOT NULL DEFAULT 0,
+    cursor TEXT NOT NULL DEFAULT '',
+    done INTEGER NOT NULL DEFAULT 0,
+    updated_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS feed_state (
+    feed TEXT NOT NULL,
+    key TEXT NOT NULL,
+    value TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    PRIMARY KEY (feed, key)
+);
+
+CREATE TABLE IF NOT EXISTS cookie_jars (
+    feed TEXT PRIMARY KEY,
+    sealed BLOB NOT NULL,
+    updated_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS meta (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS report_snapshots (
+    taken_at TEXT NOT NULL,
+    source TEXT NOT NULL,
+    items_count INTEGER NOT NULL DEFAULT 0,
+    error_count INTEGER NOT NULL DEFAULT 0,
+    total_fetches INTEGER NOT NULL DEFAULT 0,
+    last_success TEXT,
+    PRIMARY KEY (taken_at, source)
+);
+
+CREATE TABLE IF NOT EXISTS source_stats (
+    source TEXT PRIMARY KEY,
+    items_count INTEGER NOT NULL DEFAULT 0,
+    error_count INTEGER NOT NULL DEFAULT 0,
+    total_fetches INTEGER NOT NULL DEFAULT 0,
+    last_success TEXT
+);
+
 CREATE INDEX IF NOT EXISTS idx_fetch_log_source ON fetch_log(source);
+CREATE INDEX IF NOT EXISTS idx_fetch_log_source_status ON fetch_log(source, status, fetched_at);
+CREATE INDEX IF NOT EXISTS idx_feed_items_source_id ON feed_items(source, id);
+CREATE INDEX IF NOT EXISTS idx_item_revisions_item ON item_revisions(item_id);
+CREATE INDEX IF NOT EXISTS idx_fetch_journal_processed ON fetch_journal(processed);
+
+CREATE TABLE IF NOT EXISTS run_items (
+    fetch_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    item_id TEXT NOT NULL,
+    title TEXT NOT NULL,
+    url TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_run_items_fetch ON run_items(fetch_id);
+
+CREATE TABLE IF NOT EXISTS curations (
+    item_id TEXT PRIMARY KEY,
+    state TEXT NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    updated_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    scope TEXT NOT NULL,
+    hash TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    last_used_at TEXT
+);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    at TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    action TEXT NOT NULL,
+    params TEXT
+);
+
+CREATE TABLE IF NOT EXISTS archives (
+    url TEXT PRIMARY KEY,
+    source TEXT NOT NULL,
+    status TEXT NOT NULL,
+    job_id TEXT,
+    archived_url TEXT,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    requested_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_archives_status ON archives(status, updated_at);
+
+CREATE TABLE IF NOT EXISTS read_later (
+    service TEXT NOT NULL,
+    url TEXT NOT NULL,
+    source TEXT NOT NULL,
+    title TEXT NOT NULL,
+    tags TEXT,
+    status TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    requested_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    PRIMARY KEY (service, url)
+);
+
+CREATE TABLE IF NOT EXISTS host_requests (
+    host TEXT NOT NULL,
+    minute TEXT NOT NULL,
+    requests INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (host, minute)
+);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    sink TEXT NOT NULL,
+    item_key TEXT NOT NULL,
+    sent_at TEXT NOT NULL,
+    PRIMARY KEY (sink, item_key)
+);
+
+CREATE TABLE IF NOT EXISTS parse_failures (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    fetch_id INTEGER NOT NULL,
+    source TEXT NOT NULL,
+    captured_at TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    stored INTEGER NOT NULL,
+    payload BLOB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_parse_failures_source ON parse_failures(source);
+
+CREATE TABLE IF NOT EXISTS deleted_items (
+    item_id TEXT PRIMARY KEY,
+    deleted_at TEXT NOT NULL
+);
 `
 
+	if s.postgres {
+		return s.initPostgresSchema(schema)
+	}
+
 	_, err := s.db.Exec(schema)
 	if err != nil {
 		return fmt.Errorf("failed to initialize schema: %w", err)
@@ -120,6 +402,17 @@ CREATE INDEX IF NOT EXISTS idx_fetch_log_source ON fetch_log(source);
 	return nil
 }
 
+// SaveResult reports the exact outcome of a SaveFetchResult call
+type SaveResult struct {
+	Inserted int
+	Updated  int
+	// Blocked counts the items skipped because they are blocklisted or
+	// were deleted
+	Blocked int
+	// New holds the IDs of the inserted items, in the order given
+	New []string
+}
+
 // SaveItems saves feed items in a transaction
 func (s *Storage) SaveItems(items []FeedItem) error {
 	if len(items) == 0 {
@@ -132,28 +425,152 @@ func (s *Storage) SaveItems(items []FeedItem) error {
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.Prepare(`
-		INSERT INTO feed_items (id, title, url, source, timestamp, tags, raw_data, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
-		ON CONFLICT(id) DO UPDATE SET
-			title = excluded.title,
-			url = excluded.url,
-			timestamp = excluded.timestamp,
-			tags = excluded.tags,
-			raw_data = excluded.raw_data
-	`)
+	if _, _, err := s.saveItemsTx(tx, items); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// SaveFetchResult writes a fetch's items and its log entry in a single
+// transaction, so a crash can never leave items without a matching log
+// entry or vice versa. The returned counts are exact: Inserted counts
+// items that did not exist before this call.
+func (s *Storage) SaveFetchResult(log FetchLog, items []FeedItem) (SaveResult, error) {
+	tx, err := s.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to prepare statement: %w", err)
+		return SaveResult{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	inserted, blocked, err := s.saveItemsTx(tx, items)
+	if err != nil {
+		return SaveResult{}, err
+	}
+
+	fetchID, err := s.logFetchTx(tx, log)
+	if err != nil {
+		return SaveResult{}, err
 	}
+
+	if err := recordRunItems(tx, fetchID, log, items); err != nil {
+		return SaveResult{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return SaveResult{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return newSaveResult(len(items), inserted, blocked), nil
+}
+
+// PreviewSave reports what saving items would do, which are new, which
+// already exist and which are blocked, without saving them
+func (s *Storage) PreviewSave(items []FeedItem) (SaveResult, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return SaveResult{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	// Saving for real and rolling back counts exactly as a save would,
+	// including duplicates within items
+	defer tx.Rollback()
+
+	inserted, blocked, err := s.saveItemsTx(tx, items)
+	if err != nil {
+		return SaveResult{}, err
+	}
+	return newSaveResult(len(items), inserted, blocked), nil
+}
+
+// newSaveResult builds the result of saving total items, of which the
+// inserted ones were new and blocked were skipped
+func newSaveResult(total int, inserted []string, blocked int) SaveResult {
+	return SaveResult{
+		Inserted: len(inserted),
+		Updated:  total - len(inserted) - blocked,
+		Blocked:  blocked,
+		New:      inserted,
+	}
+}
+
+// saveItemsTx upserts items within tx, skipping blocklisted and deleted
+// ones, and returns the IDs of the new items and how many were skipped
+func (s *Storage) saveItemsTx(tx *sql.Tx, items []FeedItem) ([]string, int, error) {
+	if len(items) == 0 {
+		return nil, 0, nil
+	}
+
+	bl, err := loadBlocklist(tx)
+	if err != nil {
+		return nil, 0, err
+	}
+	dedup, err := storedDedup(tx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	lookup := tx.Stmt(s.stmts.itemLookup)
+	defer lookup.Close()
+
+	stmt := tx.Stmt(s.stmts.upsertItem)
 	defer stmt.Close()
 
+	var inserted []string
+	blocked := 0
+	newBySource := make(map[string]int)
 	for _, item := range items {
+		if bl.blocked(item) {
+			blocked++
+			continue
+		}
+
+		var oldTitle, oldURL string
+		var userTags *string
+		isNew := false
+		switch err := lookup.QueryRow(item.ID).Scan(&oldTitle, &oldURL, &userTags); err {
+		case nil:
+			if oldTitle != item.Title || oldURL != item.URL {
+				if err := recordRevision(tx, item, oldTitle, oldURL, s.clock.Now()); err != nil {
+					return nil, 0, err
+				}
+			}
+		case sql.ErrNoRows:
+			// Items deleted by hand stay deleted when fetched again
+			deleted, err := itemDeleted(tx, item.ID)
+			if err != nil {
+				return nil, 0, err
+			}
+			if deleted {
+				blocked++
+				continue
+			}
+			isNew = true
+			inserted = append(inserted, item.ID)
+			newBySource[item.Source]++
+		default:
+			return nil, 0, fmt.Errorf("failed to check item: %w", err)
+		}
+
+		// Tags added by hand outlive the feed's own changing
+		tags := item.Tags
+		if userTags != nil {
+			var added []string
+			if err := json.Unmarshal([]byte(*userTags), &added); err != nil {
+				return nil, 0, fmt.Errorf("invalid user tags for item %s: %w", item.ID, err)
+			}
+			tags = mergeTags(tags, added)
+		}
+
 		// Serialize tags as JSON
 		var tagsJSON *string
-		if len(item.Tags) > 0 {
-			tagsBytes, err := json.Marshal(item.Tags)
+		if len(tags) > 0 {
+			tagsBytes, err := json.Marshal(tags)
 			if err != nil {
-				return fmt.Errorf("failed to marshal tags: %w", err)
+				return nil, 0, fmt.Errorf("failed to marshal tags: %w", err)
 			}
 			tagsStr := string(tagsBytes)
 			tagsJSON = &tagsStr
@@ -170,8 +587,33 @@ func (s *Storage) SaveItems(items []FeedItem) error {
 			item.CreatedAt.Format(time.RFC3339),
 		)
 		if err != nil {
-			return fmt.Errorf("failed to insert item: %w", err)
+			return nil, 0, fmt.Errorf("failed to insert item: %w", err)
 		}
+
+		if dedup == DedupCrossSource {
+			if err := linkItem(tx, item, isNew); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+
+	if err := addItemCounts(tx, newBySource); err != nil {
+		return nil, 0, err
+	}
+
+	return inserted, blocked, nil
+}
+
+// LogFetch logs a fetch operation
+func (s *Storage) LogFetch(log FetchLog) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := s.logFetchTx(tx, log); err != nil {
+		return err
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -181,24 +623,33 @@ func (s *Storage) SaveItems(items []FeedItem) error {
 	return nil
 }
 
-// LogFetch logs a fetch operation
-func (s *Storage) LogFetch(log FetchLog) error {
-	_, err := s.db.Exec(`
-		INSERT INTO fetch_log (source, fetched_at, status, items_count, error_message, duration_ms)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`,
+// logFetchTx inserts a fetch log entry, with its parse failure if any,
+// folds it into source_stats and returns the entry's ID
+func (s *Storage) logFetchTx(tx *sql.Tx, log FetchLog) (int64, error) {
+	fetchedAt := log.FetchedAt.Format(time.RFC3339)
+
+	var id int64
+	err := tx.Stmt(s.stmts.logFetch).QueryRow(
 		log.Source,
-		log.FetchedAt.Format(time.RFC3339),
+		fetchedAt,
 		log.Status,
 		log.ItemsCount,
 		log.ErrorMessage,
 		log.DurationMs,
-	)
+		nullString(log.Endpoint),
+		log.Slow,
+	).Scan(&id)
 	if err != nil {
-		return fmt.Errorf("failed to log fetch: %w", err)
+		return 0, fmt.Errorf("failed to log fetch: %w", err)
 	}
 
-	return nil
+	if log.Failure != nil {
+		if err := recordFailure(tx, id, log); err != nil {
+			return 0, err
+		}
+	}
+
+	return id, addFetchStats(tx, log.Source, log.Status, fetchedAt)
 }
 
 // GetItemCount returns the total number of items for a source
@@ -211,25 +662,137 @@ func (s *Storage) GetItemCount(source string) (int, error) {
 	return count, nil
 }
 
-// GetAllItemsCount returns the total number of items across all sources
+// GetItemCountsBySource returns the number of items of every source that
+// has any, in a single query
+func (s *Storage) GetItemCountsBySource() (map[string]int, error) {
+	rows, err := s.db.Query("SELECT source, COUNT(*) FROM feed_items GROUP BY source")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item counts: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var source string
+		var n int
+		if err := rows.Scan(&source, &n); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		counts[source] = n
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return counts, nil
+}
+
+// GetAllItemsCount returns the total number of items across all sources.
+// Items linked as duplicates of another source's aren't counted again.
 func (s *Storage) GetAllItemsCount() (int, error) {
 	var count int
-	err := s.db.QueryRow("SELECT COUNT(*) FROM feed_items").Scan(&count)
+	err := s.db.QueryRow("SELECT COUNT(*) FROM feed_items WHERE duplicate_of IS NULL").Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get total item count: %w", err)
 	}
 	return count, nil
 }
 
-// GetFetchStats returns fetch statistics for all sources
-func (s *Storage) GetFetchStats() ([]FetchStats, error) {
+// GetRecentItems returns the perSource most recently stored items of each
+// source, newest first
+func (s *Storage) GetRecentItems(perSource int) (map[string][]FeedItem, error) {
+	rows, err := s.db.Query(`
+		SELECT id, title, url, source, timestamp, created_at
+		FROM (
+			SELECT *, ROW_NUMBER() OVER (
+				PARTITION BY source ORDER BY julianday(created_at) DESC, id
+			) AS rank
+			FROM feed_items
+		) AS ranked
+		WHERE rank <= ?
+		ORDER BY source, rank
+	`, perSource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recent items: %w", err)
+	}
+	defer rows.Close()
+
+	recent := make(map[string][]FeedItem)
+	for rows.Next() {
+		var item FeedItem
+		var createdAt string
+		if err := rows.Scan(&item.ID, &item.Title, &item.URL, &item.Source, &item.Timestamp, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan item: %w", err)
+		}
+		if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
+			item.CreatedAt = t
+		}
+		recent[item.Source] = append(recent[item.Source], item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating recent items: %w", err)
+	}
+
+	return recent, nil
+}
+
+// LatestItems returns the limit most recently stored items, newest first,
+// only those tagged tag (case-insensitively) unless tag is empty
+func (s *Storage) LatestItems(tag string, limit int) ([]FeedItem, error) {
+	rows, err := s.db.Query(`
+		SELECT id, title, url, source, timestamp, tags, created_at
+		FROM feed_items
+		WHERE ? = '' OR EXISTS (
+			SELECT 1 FROM json_each(feed_items.tags) WHERE lower(value) = lower(?)
+		)
+		ORDER BY julianday(created_at) DESC, id
+		LIMIT ?
+	`, tag, tag, noLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query latest items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []FeedItem
+	for rows.Next() {
+		var item FeedItem
+		var tags *string
+		var createdAt string
+		if err := rows.Scan(&item.ID, &item.Title, &item.URL, &item.Source, &item.Timestamp, &tags, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan item: %w", err)
+		}
+		if tags != nil {
+			if err := json.Unmarshal([]byte(*tags), &item.Tags); err != nil {
+				return nil, fmt.Errorf("invalid tags for item %s: %w", item.ID, err)
+			}
+		}
+		if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
+			item.CreatedAt = t
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating latest items: %w", err)
+	}
+
+	return items, nil
+}
+
+// GetFetchStatsExact computes fetch statistics for all sources directly
+// from fetch_log and feed_items. It scans both tables, so GetFetchStats
+// should be preferred; this remains as the reference the materialized
+// stats are rebuilt from.
+func (s *Storage) GetFetchStatsExact() ([]FetchStats, error) {
 	query := `
 		WITH source_stats AS (
 			SELECT 
 				source,
 				COUNT(*) as total_fetches,
 				SUM(CASE WHEN status = 'error' THEN 1 ELSE 0 END) as error_count,
-				MAX(CASE WHEN status = 'success' THEN fetched_at ELSE NULL END) as last_success
+				MAX(CASE WHEN status IN ('success', 'degraded', 'unchanged') THEN fetched_at ELSE NULL END) as last_success
 			FROM fetch_log
 			GROUP BY source
 		)
@@ -241,7 +804,7 @@ func (s *Storage) GetFetchStats() ([]FetchStats, error) {
 			ss.last_success
 		FROM feed_items fi
 		LEFT JOIN source_stats ss ON fi.source = ss.source
-		GROUP BY fi.source
+		GROUP BY fi.source, ss.source, ss.error_count, ss.total_fetches, ss.last_success
 		UNION
 		SELECT 
 			source,
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -276,6 +277,33 @@ func TestGetAllItemsCount(t *testing.T) {
 	}
 }
 
+func TestGetItemCountsBySource(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	store.SaveItems([]FeedItem{
+		{ID: "1", Title: "T1", URL: "u1", Source: "S1", CreatedAt: time.Now()},
+		{ID: "2", Title: "T2", URL: "u2", Source: "S1", CreatedAt: time.Now()},
+		{ID: "3", Title: "T3", URL: "u3", Source: "S2", CreatedAt: time.Now()},
+	})
+
+	counts, err := store.GetItemCountsBySource()
+	if err != nil {
+		t.Fatalf("GetItemCountsBySource failed: %v", err)
+	}
+	if len(counts) != 2 || counts["S1"] != 2 || counts["S2"] != 1 {
+		t.Errorf("unexpected counts: %v", counts)
+	}
+	for source, n := range counts {
+		if single, _ := store.GetItemCount(source); single != n {
+			t.Errorf("%s: bulk count %d disagrees with GetItemCount %d", source, n, single)
+		}
+	}
+}
+
 func TestGetFetchStats(t *testing.T) {
 	tmpDir := t.TempDir()
 	store, err := NewStorage(filepath.Join(tmpDir, "test.db"))
@@ -393,3 +421,226 @@ func TestConcurrentWrites(t *testing.T) {
 		t.Errorf("expected 10 items after concurrent writes, got %d", count)
 	}
 }
+
+func TestSaveFetchResult_ExactCounts(t *testing.T) {
+	tmpDir := t.TempDir()
+	store, err := NewStorage(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	store.SaveItems([]FeedItem{
+		{ID: "1", Title: "T1", URL: "u1", Source: "S1", CreatedAt: time.Now()},
+	})
+
+	items := []FeedItem{
+		{ID: "1", Title: "T1 updated", URL: "u1", Source: "S1", CreatedAt: time.Now()},
+		{ID: "2", Title: "T2", URL: "u2", Source: "S1", CreatedAt: time.Now()},
+		{ID: "2", Title: "T2 again", URL: "u2", Source: "S1", CreatedAt: time.Now()},
+	}
+
+	result, err := store.SaveFetchResult(FetchLog{
+		Source:     "S1",
+		FetchedAt:  time.Now(),
+		Status:     "success",
+		ItemsCount: len(items),
+	}, items)
+	if err != nil {
+		t.Fatalf("failed to save fetch result: %v", err)
+	}
+
+	if result.Inserted != 1 {
+		t.Errorf("expected 1 inserted, got %d", result.Inserted)
+	}
+	if result.Updated != 2 {
+		t.Errorf("expected 2 updated, got %d", result.Updated)
+	}
+	if len(result.New) != 1 || result.New[0] != "2" {
+		t.Errorf("expected item 2 reported as new, got %v", result.New)
+	}
+
+	stats, err := store.GetFetchStats()
+	if err != nil {
+		t.Fatalf("failed to get stats: %v", err)
+	}
+	if len(stats) != 1 || stats[0].TotalFetches != 1 {
+		t.Errorf("expected fetch to be logged once, got %+v", stats)
+	}
+}
+
+func TestSaveFetchResult_RollsBackOnFailure(t *testing.T) {
+	tmpDir := t.TempDir()
+	store, err := NewStorage(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	// Drop the log table so the log insert fails after items are written
+	if _, err := store.db.Exec("DROP TABLE fetch_log"); err != nil {
+		t.Fatalf("failed to drop fetch_log: %v", err)
+	}
+
+	_, err = store.SaveFetchResult(FetchLog{
+		Source:    "S1",
+		FetchedAt: time.Now(),
+		Status:    "success",
+	}, []FeedItem{
+		{ID: "1", Title: "T1", URL: "u1", Source: "S1", CreatedAt: time.Now()},
+	})
+	if err == nil {
+		t.Fatal("expected error when fetch_log is missing")
+	}
+
+	count, err := store.GetItemCount("S1")
+	if err != nil {
+		t.Fatalf("failed to get count: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("expected items to be rolled back, got %d", count)
+	}
+}
+
+// benchBatch returns a small batch of distinct items for benchmark iteration n
+func benchBatch(n int) []FeedItem {
+	items := make([]FeedItem, 5)
+	for i := range items {
+		id := fmt.Sprintf("bench-%d-%d", n, i)
+		items[i] = FeedItem{ID: id, Title: id, URL: "https://example.com/" + id, Source: "Bench", CreatedAt: time.Now()}
+	}
+	return items
+}
+
+func BenchmarkSaveItems_SmallBatches(b *testing.B) {
+	store, err := NewStorage(filepath.Join(b.TempDir(), "bench.db"))
+	if err != nil {
+		b.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := store.SaveItems(benchBatch(i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkSaveItems_SmallBatchesUncached is the baseline for the cached
+// statements above: it re-prepares the same statements inside every
+// transaction, as SaveItems used to.
+func BenchmarkSaveItems_SmallBatchesUncached(b *testing.B) {
+	store, err := NewStorage(filepath.Join(b.TempDir(), "bench.db"))
+	if err != nil {
+		b.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tx, err := store.db.Begin()
+		if err != nil {
+			b.Fatal(err)
+		}
+		exists, err := tx.Prepare("SELECT title, url FROM feed_items WHERE id = ?")
+		if err != nil {
+			b.Fatal(err)
+		}
+		stmt, err := tx.Prepare(`
+			INSERT INTO feed_items (id, title, url, source, timestamp, tags, raw_data, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET
+				title = excluded.title,
+				url = excluded.url,
+				timestamp = excluded.timestamp,
+				tags = excluded.tags,
+				raw_data = excluded.raw_data
+		`)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, item := range benchBatch(i) {
+			var title, url string
+			exists.QueryRow(item.ID).Scan(&title, &url)
+			if _, err := stmt.Exec(item.ID, item.Title, item.URL, item.Source, nil, nil, nil, item.CreatedAt.Format(time.RFC3339)); err != nil {
+				b.Fatal(err)
+			}
+		}
+		exists.Close()
+		stmt.Close()
+		if err := tx.Commit(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestGetRecentItems(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	var items []FeedItem
+	for i := 0; i < 5; i++ {
+		items = append(items, FeedItem{
+			ID:        fmt.Sprintf("hn%d", i),
+			Title:     fmt.Sprintf("HN %d", i),
+			URL:       fmt.Sprintf("https://example.com/%d", i),
+			Source:    "HN",
+			CreatedAt: base.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	items = append(items, FeedItem{ID: "lo1", Title: "Lobsters 1", URL: "https://example.com/lo", Source: "Lobsters", CreatedAt: base})
+	if err := store.SaveItems(items); err != nil {
+		t.Fatalf("SaveItems failed: %v", err)
+	}
+
+	recent, err := store.GetRecentItems(2)
+	if err != nil {
+		t.Fatalf("GetRecentItems failed: %v", err)
+	}
+
+	hn := recent["HN"]
+	if len(hn) != 2 || hn[0].Title != "HN 4" || hn[1].Title != "HN 3" {
+		t.Errorf("expected the two newest HN items, got %+v", hn)
+	}
+	if len(recent["Lobsters"]) != 1 {
+		t.Errorf("expected 1 Lobsters item, got %+v", recent["Lobsters"])
+	}
+}
+
+func TestLatestItems(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	store.SaveItems([]FeedItem{
+		{ID: "a", Title: "A", URL: "https://example.com/a", Source: "HN", Tags: []string{"Golang"}, CreatedAt: now.Add(-3 * time.Hour)},
+		{ID: "b", Title: "B", URL: "https://example.com/b", Source: "HN", CreatedAt: now.Add(-2 * time.Hour)},
+		{ID: "c", Title: "C", URL: "https://example.com/c", Source: "Lobsters", Tags: []string{"rust", "golang"}, CreatedAt: now.Add(-time.Hour)},
+	})
+
+	items, err := store.LatestItems("golang", 0)
+	if err != nil {
+		t.Fatalf("LatestItems failed: %v", err)
+	}
+	if len(items) != 2 || items[0].ID != "c" || items[1].ID != "a" {
+		t.Errorf("expected the golang items newest first, got %+v", items)
+	}
+
+	items, err = store.LatestItems("", 2)
+	if err != nil {
+		t.Fatalf("LatestItems failed: %v", err)
+	}
+	if len(items) != 2 || items[0].ID != "c" || items[1].ID != "b" {
+		t.Errorf("expected the two newest items, got %+v", items)
+	}
+}
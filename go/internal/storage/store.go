@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"time"
+
+	"feedpulse/internal/clock"
+)
+
+// Store is every operation feedpulse performs on its database. Storage
+// implements it on SQLite or PostgreSQL; code that only needs to read and
+// write data should take a Store so it can run against testutil.MockStore
+// or another backend.
+type Store interface {
+	SetClock(c clock.Clock)
+	Close() error
+
+	// Items
+	SaveItems(items []FeedItem) error
+	SaveFetchResult(log FetchLog, items []FeedItem) (SaveResult, error)
+	PreviewSave(items []FeedItem) (SaveResult, error)
+	GetItemCount(source string) (int, error)
+	GetItemCountsBySource() (map[string]int, error)
+	GetAllItemsCount() (int, error)
+	GetRecentItems(perSource int) (map[string][]FeedItem, error)
+	GetItemsSince(window time.Duration) ([]FeedItem, error)
+	LatestItems(tag string, limit int) ([]FeedItem, error)
+	GetItems(filter ItemFilter) ([]FeedItem, error)
+	ApplyItemBatch(batch ItemBatch) (int, error)
+	GetItemHistory(itemID string) ([]ItemRevision, error)
+	ExplainItem(itemID string) (*ItemExplanation, error)
+	NewestItemTime(source string) (time.Time, bool, error)
+
+	// Item identity
+	UniquenessScope() (string, error)
+	SetUniquenessScope(scope string) (int, error)
+	SetIDHashes(hashes map[string]string) (int, error)
+	Dedup() (string, error)
+	SetDedup(dedup string) (int, error)
+
+	// Fetch log and stats
+	LogFetch(log FetchLog) error
+	ListFetchLog(filter FetchLogFilter) ([]FetchLog, error)
+	GetFetchStats() ([]FetchStats, error)
+	GetFetchStatsExact() ([]FetchStats, error)
+	GetTagStats(filter ItemFilter) ([]TagStats, error)
+	RebuildSourceStats() error
+	GetStatsBuckets(source, bucket string, window time.Duration) ([]StatsBucket, error)
+	GetItemBuckets(source, bucket string, window time.Duration) ([]StatsBucket, error)
+	LastSuccess(source string) (time.Time, bool, error)
+	GetConsecutiveFailures() (map[string]int, error)
+	ListErrors(filter ErrorFilter) ([]ErrorGroup, error)
+	SlowThreshold(source string) (int64, bool, error)
+	RecordHostRequest(host string, at time.Time) error
+	HostUsage(host string, now time.Time) (HostUsage, error)
+	ReserveHostRequests(host string, n, perHour, perDay int, at time.Time) (bool, error)
+	ReleaseHostRequests(host string, n int, at time.Time) error
+	SaveReportSnapshot(stats []FetchStats) error
+	LatestReportSnapshot() (*ReportSnapshot, error)
+
+	// Parse failures
+	ListParseFailures(source string, limit int) ([]ParseFailure, error)
+	GetParseFailure(id int64) (*ParseFailure, error)
+
+	// Runs
+	ListRuns(source string, limit int) ([]FetchLog, error)
+	GetRunItems(fetchID int) ([]RunItem, error)
+
+	// Front page curation
+	Pin(itemID string, position int) error
+	Hide(itemID string) error
+	Uncurate(itemID string) (bool, error)
+	ListCurations() ([]Curation, error)
+
+	// API tokens
+	CreateAPIToken(name, scope string) (string, APIToken, error)
+	ListAPITokens() ([]APIToken, error)
+	RevokeAPIToken(id string) (bool, error)
+	AuthenticateAPIToken(token string) (*APIToken, error)
+
+	// Audit log
+	RecordAudit(actor, action string, params map[string]string) error
+	ListAudit(filter AuditFilter) ([]AuditEntry, error)
+
+	// Web archiving
+	EnqueueArchive(items []FeedItem) (int, error)
+	DueArchives(limit int) ([]ArchiveEntry, error)
+	RecentArchives(limit int) ([]ArchiveEntry, error)
+	GetArchive(url string) (*ArchiveEntry, error)
+	UpdateArchive(entry ArchiveEntry) error
+	ArchiveCounts() (map[string]int, error)
+
+	// Read-later saves
+	QueueSave(service string, items []FeedItem) (int, error)
+	QueuedSaves(limit int) ([]SaveEntry, error)
+	GetSave(service, url string) (*SaveEntry, error)
+	UpdateSave(entry SaveEntry) error
+
+	// Feed state
+	GetFeedState(feed string) (map[string]string, error)
+	SetFeedState(feed string, values map[string]string) error
+	ClearFeedState(feed string) error
+
+	// Journal
+	AppendJournal(source, feedType string, payload []byte) (int64, error)
+	MarkJournalProcessed(id int64) error
+	PendingJournal() ([]JournalEntry, error)
+	PruneJournal() (int, error)
+
+	// Backfill
+	GetBackfillCursor(feed string) (*BackfillCursor, error)
+	ResetBackfillCursor(feed string) error
+	SaveBackfillPage(log FetchLog, items []FeedItem, next BackfillCursor) (SaveResult, error)
+
+	// Notifications
+	ClaimNotifications(sink string, items []FeedItem, window time.Duration) ([]FeedItem, error)
+	ReleaseNotifications(sink string, items []FeedItem) error
+
+	// Blocklist
+	Block(value string) (int, error)
+	Unblock(value string) (bool, error)
+	ListBlocked() ([]BlockEntry, error)
+
+	// Cookie jars
+	SetCookieKey(secret string)
+	SaveCookies(feed string, cookies []Cookie) error
+	LoadCookies(feed string) ([]Cookie, error)
+	ClearCookies(feed string) error
+}
+
+var _ Store = (*Storage)(nil)
+
+// ItemBlocked reports whether item matches any of entries, the way saving
+// skips blocklisted items
+func ItemBlocked(entries []BlockEntry, item FeedItem) bool {
+	for _, entry := range entries {
+		switch entry.Kind {
+		case BlockID:
+			if item.ID == entry.Value {
+				return true
+			}
+		case BlockURL:
+			if item.URL == entry.Value {
+				return true
+			}
+		case BlockPattern:
+			if globToRegexp(entry.Value).MatchString(item.URL) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// API token scopes. Admin tokens may also do everything read tokens can.
+const (
+	ScopeRead  = "read"
+	ScopeAdmin = "admin"
+)
+
+// apiTokenPrefix starts every API token, so leaked tokens are easy to grep for
+const apiTokenPrefix = "fp_"
+
+// TokenUseInterval is how stale a token's last use may get before it is
+// recorded again, so most authenticated requests don't write
+const TokenUseInterval = time.Minute
+
+// APIToken describes an API token. The token itself is only shown when it
+// is created; the database keeps a hash.
+type APIToken struct {
+	ID         string
+	Name       string
+	Scope      string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+}
+
+// Allows reports whether the token may perform an action needing scope
+func (t *APIToken) Allows(scope string) bool {
+	return t.Scope == ScopeAdmin || t.Scope == scope
+}
+
+// GenerateAPIToken returns a new random token and the ID it is looked up
+// by, which is embedded in the token
+func GenerateAPIToken() (id, token string, err error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	id = hex.EncodeToString(b[:4])
+	return id, apiTokenPrefix + id + "_" + hex.EncodeToString(b[4:]), nil
+}
+
+// ParseAPIToken returns the ID embedded in token
+func ParseAPIToken(token string) (string, bool) {
+	rest := strings.TrimPrefix(token, apiTokenPrefix)
+	if rest == token {
+		return "", false
+	}
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok || id == "" || secret == "" {
+		return "", false
+	}
+	return id, true
+}
+
+// HashAPIToken returns the hash a token is stored as
+func HashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// validTokenScope returns an error unless scope is a known scope
+func validTokenScope(scope string) error {
+	if scope != ScopeRead && scope != ScopeAdmin {
+		return fmt.Errorf("unknown token scope: %s (must be %s or %s)", scope, ScopeRead, ScopeAdmin)
+	}
+	return nil
+}
+
+// CreateAPIToken stores a new token with scope and returns it; only its
+// hash is kept
+func (s *Storage) CreateAPIToken(name, scope string) (string, APIToken, error) {
+	if err := validTokenScope(scope); err != nil {
+		return "", APIToken{}, err
+	}
+
+	id, token, err := GenerateAPIToken()
+	if err != nil {
+		return "", APIToken{}, err
+	}
+
+	t := APIToken{ID: id, Name: name, Scope: scope, CreatedAt: s.clock.Now().Truncate(time.Second)}
+	_, err = s.db.Exec(`
+		INSERT INTO api_tokens (id, name, scope, hash, created_at) VALUES (?, ?, ?, ?, ?)
+	`, t.ID, t.Name, t.Scope, HashAPIToken(token), t.CreatedAt.Format(time.RFC3339))
+	if err != nil {
+		return "", APIToken{}, fmt.Errorf("failed to save token: %w", err)
+	}
+
+	return token, t, nil
+}
+
+// ListAPITokens returns all tokens, oldest first
+func (s *Storage) ListAPITokens() ([]APIToken, error) {
+	rows, err := s.db.Query("SELECT id, name, scope, created_at, last_used_at FROM api_tokens ORDER BY created_at, id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []APIToken
+	for rows.Next() {
+		var t APIToken
+		var createdAt string
+		var lastUsed sql.NullString
+		if err := rows.Scan(&t.ID, &t.Name, &t.Scope, &createdAt, &lastUsed); err != nil {
+			return nil, fmt.Errorf("failed to scan token: %w", err)
+		}
+		if ts, err := time.Parse(time.RFC3339, createdAt); err == nil {
+			t.CreatedAt = ts
+		}
+		if lastUsed.Valid {
+			if ts, err := time.Parse(time.RFC3339, lastUsed.String); err == nil {
+				t.LastUsedAt = &ts
+			}
+		}
+		tokens = append(tokens, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// RevokeAPIToken deletes the token with id, returning false if there was none
+func (s *Storage) RevokeAPIToken(id string) (bool, error) {
+	res, err := s.db.Exec("DELETE FROM api_tokens WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	return n > 0, nil
+}
+
+// AuthenticateAPIToken returns the stored token matching token, recording
+// that it was used if its last use is older than TokenUseInterval, or nil
+// if it is unknown or revoked
+func (s *Storage) AuthenticateAPIToken(token string) (*APIToken, error) {
+	id, ok := ParseAPIToken(token)
+	if !ok {
+		return nil, nil
+	}
+
+	var t APIToken
+	var hash, createdAt string
+	var lastUsedAt *string
+	err := s.db.QueryRow("SELECT id, name, scope, hash, created_at, last_used_at FROM api_tokens WHERE id = ?", id).
+		Scan(&t.ID, &t.Name, &t.Scope, &hash, &createdAt, &lastUsedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up token: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(HashAPIToken(token))) != 1 {
+		return nil, nil
+	}
+	if ts, err := time.Parse(time.RFC3339, createdAt); err == nil {
+		t.CreatedAt = ts
+	}
+
+	now := s.clock.Now().Truncate(time.Second)
+	if lastUsedAt != nil {
+		if ts, err := time.Parse(time.RFC3339, *lastUsedAt); err == nil && now.Sub(ts) < TokenUseInterval {
+			t.LastUsedAt = &ts
+			return &t, nil
+		}
+	}
+	if _, err := s.db.Exec("UPDATE api_tokens SET last_used_at = ? WHERE id = ?", now.Format(time.RFC3339), t.ID); err != nil {
+		return nil, fmt.Errorf("failed to record token use: %w", err)
+	}
+	t.LastUsedAt = &now
+
+	return &t, nil
+}
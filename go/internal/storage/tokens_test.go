@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"feedpulse/internal/clock"
+)
+
+func TestAPITokens_CreateAuthenticateRevoke(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	token, created, err := store.CreateAPIToken("dashboard", ScopeRead)
+	if err != nil {
+		t.Fatalf("CreateAPIToken failed: %v", err)
+	}
+	if !strings.HasPrefix(token, "fp_"+created.ID+"_") {
+		t.Errorf("token %q does not embed its ID %q", token, created.ID)
+	}
+
+	got, err := store.AuthenticateAPIToken(token)
+	if err != nil {
+		t.Fatalf("AuthenticateAPIToken failed: %v", err)
+	}
+	if got == nil || got.ID != created.ID || got.Scope != ScopeRead || got.LastUsedAt == nil {
+		t.Fatalf("unexpected token: %+v", got)
+	}
+	if got.Allows(ScopeAdmin) {
+		t.Error("read token should not allow admin actions")
+	}
+
+	// Right ID, wrong secret
+	if got, _ := store.AuthenticateAPIToken(token[:len(token)-1] + "x"); got != nil {
+		t.Error("expected a tampered token to be rejected")
+	}
+	if got, _ := store.AuthenticateAPIToken("not-a-token"); got != nil {
+		t.Error("expected a malformed token to be rejected")
+	}
+
+	tokens, err := store.ListAPITokens()
+	if err != nil || len(tokens) != 1 || tokens[0].LastUsedAt == nil {
+		t.Fatalf("unexpected tokens: %+v (%v)", tokens, err)
+	}
+
+	if ok, err := store.RevokeAPIToken(created.ID); err != nil || !ok {
+		t.Fatalf("RevokeAPIToken = %v, %v", ok, err)
+	}
+	if got, _ := store.AuthenticateAPIToken(token); got != nil {
+		t.Error("expected a revoked token to be rejected")
+	}
+	if ok, _ := store.RevokeAPIToken(created.ID); ok {
+		t.Error("expected revoking twice to report no token")
+	}
+}
+
+func TestAuthenticateAPIToken_ThrottlesLastUse(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	store.SetClock(clock.Fixed(now))
+	token, _, err := store.CreateAPIToken("dashboard", ScopeRead)
+	if err != nil {
+		t.Fatalf("CreateAPIToken failed: %v", err)
+	}
+
+	for _, tt := range []struct {
+		after time.Duration
+		want  time.Duration
+	}{
+		{0, 0},
+		{30 * time.Second, 0},
+		{TokenUseInterval, TokenUseInterval},
+		{TokenUseInterval + 10*time.Second, TokenUseInterval},
+	} {
+		store.SetClock(clock.Fixed(now.Add(tt.after)))
+		got, err := store.AuthenticateAPIToken(token)
+		if err != nil || got == nil {
+			t.Fatalf("AuthenticateAPIToken = %v, %v", got, err)
+		}
+		if want := now.Add(tt.want); got.LastUsedAt == nil || !got.LastUsedAt.Equal(want) {
+			t.Errorf("after %v: last used %v, want %v", tt.after, got.LastUsedAt, want)
+		}
+	}
+}
+
+func TestAPITokens_AdminAllowsRead(t *testing.T) {
+	admin := APIToken{Scope: ScopeAdmin}
+	if !admin.Allows(ScopeRead) || !admin.Allows(ScopeAdmin) {
+		t.Error("admin token should allow read and admin actions")
+	}
+}
+
+func TestAPITokens_UnknownScope(t *testing.T) {
+	store, err := NewStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	if _, _, err := store.CreateAPIToken("x", "write"); err == nil {
+		t.Error("expected an unknown scope to fail")
+	}
+}
@@ -0,0 +1,240 @@
+// Package syndication writes stored items as an RSS 2.0 or Atom 1.0 feed,
+// so feedpulse can combine its sources into one feed for a feed reader.
+package syndication
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"feedpulse/internal/storage"
+)
+
+// Output formats
+const (
+	FormatRSS  = "rss"
+	FormatAtom = "atom"
+)
+
+// ContentType returns the media type of feeds in format
+func ContentType(format string) string {
+	if format == FormatAtom {
+		return "application/atom+xml; charset=utf-8"
+	}
+	return "application/rss+xml; charset=utf-8"
+}
+
+// Feed describes the combined feed
+type Feed struct {
+	Title string
+	// Link is the page the feed belongs to, SelfURL where the feed itself
+	// is served; either may be empty
+	Link    string
+	SelfURL string
+	// Updated is used when there are no items to date the feed by
+	Updated time.Time
+}
+
+// Title names a feed of the items of source and tag, either of which may
+// be empty
+func Title(source, tag string) string {
+	title := "feedpulse"
+	if source != "" {
+		title += ": " + source
+	}
+	if tag != "" {
+		title += " #" + tag
+	}
+	return title
+}
+
+// Write writes items, newest first, as a feed in format (FormatRSS or
+// FormatAtom)
+func Write(w io.Writer, format string, feed Feed, items []storage.FeedItem) error {
+	var doc interface{}
+	switch format {
+	case FormatRSS:
+		doc = rssDocument(feed, items)
+	case FormatAtom:
+		doc = atomDocument(feed, items)
+	default:
+		return fmt.Errorf("unknown feed format: %s", format)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode feed: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// published returns when item was published: its timestamp if it parses,
+// otherwise when it was stored
+func published(item storage.FeedItem) time.Time {
+	if item.Timestamp != nil {
+		if t, err := time.Parse(time.RFC3339, *item.Timestamp); err == nil {
+			return t
+		}
+	}
+	return item.CreatedAt
+}
+
+// updated returns the newest publication time among items, or fallback
+func updated(items []storage.FeedItem, fallback time.Time) time.Time {
+	latest := fallback
+	for i, item := range items {
+		if t := published(item); i == 0 || t.After(latest) {
+			latest = t
+		}
+	}
+	return latest.UTC()
+}
+
+// itemURN identifies an item in feeds; item IDs are stable across fetches
+func itemURN(item storage.FeedItem) string {
+	return "urn:feedpulse:item:" + item.ID
+}
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	DC      string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Self          *atomLink `xml:"atom:link,omitempty"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Generator     string    `xml:"generator"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title      string   `xml:"title"`
+	Link       string   `xml:"link,omitempty"`
+	GUID       rssGUID  `xml:"guid"`
+	PubDate    string   `xml:"pubDate"`
+	Creator    string   `xml:"dc:creator"`
+	Categories []string `xml:"category"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func rssDocument(feed Feed, items []storage.FeedItem) rss {
+	channel := rssChannel{
+		Title:         feed.Title,
+		Link:          feed.Link,
+		Description:   "The newest items collected by feedpulse",
+		LastBuildDate: updated(items, feed.Updated).Format(time.RFC1123Z),
+		Generator:     "feedpulse",
+	}
+	if channel.Link == "" {
+		channel.Link = feed.SelfURL
+	}
+	if feed.SelfURL != "" {
+		channel.Self = &atomLink{Href: feed.SelfURL, Rel: "self", Type: "application/rss+xml"}
+	}
+	for _, item := range items {
+		channel.Items = append(channel.Items, rssItem{
+			Title:      item.Title,
+			Link:       item.URL,
+			GUID:       rssGUID{Value: itemURN(item)},
+			PubDate:    published(item).UTC().Format(time.RFC1123Z),
+			Creator:    item.Source,
+			Categories: item.Tags,
+		})
+	}
+	return rss{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		DC:      "http://purl.org/dc/elements/1.1/",
+		Channel: channel,
+	}
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomPerson  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Links      []atomLink     `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Author     atomPerson     `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+}
+
+func atomDocument(feed Feed, items []storage.FeedItem) atomFeed {
+	doc := atomFeed{
+		Title:   feed.Title,
+		ID:      "urn:feedpulse:feed",
+		Updated: updated(items, feed.Updated).Format(time.RFC3339),
+		Author:  atomPerson{Name: "feedpulse"},
+	}
+	// The feed's own URL identifies it best, if it is known
+	if feed.SelfURL != "" {
+		doc.ID = feed.SelfURL
+		doc.Links = append(doc.Links, atomLink{Href: feed.SelfURL, Rel: "self", Type: "application/atom+xml"})
+	}
+	if feed.Link != "" {
+		doc.Links = append(doc.Links, atomLink{Href: feed.Link, Rel: "alternate"})
+	}
+	for _, item := range items {
+		// An entry is updated when stored, never before it was published
+		pub := published(item)
+		upd := item.CreatedAt
+		if upd.Before(pub) {
+			upd = pub
+		}
+		entry := atomEntry{
+			Title:     item.Title,
+			ID:        itemURN(item),
+			Published: pub.UTC().Format(time.RFC3339),
+			Updated:   upd.UTC().Format(time.RFC3339),
+			Author:    atomPerson{Name: item.Source},
+		}
+		if item.URL != "" {
+			entry.Links = []atomLink{{Href: item.URL, Rel: "alternate"}}
+		}
+		for _, tag := range item.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return doc
+}
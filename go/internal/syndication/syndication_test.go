@@ -0,0 +1,110 @@
+package syndication
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"feedpulse/internal/storage"
+)
+
+func testItems() []storage.FeedItem {
+	stored := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	published := "2026-03-02T10:30:00Z"
+	return []storage.FeedItem{
+		{ID: "a1", Title: "Go 1.30 & friends", URL: "https://example.com/go?x=1&y=2", Source: "HN", Tags: []string{"go", "release"}, Timestamp: &published, CreatedAt: stored},
+		{ID: "b2", Title: "No link", Source: "Lobsters", CreatedAt: stored.Add(-time.Hour)},
+	}
+}
+
+func TestWrite_RSS(t *testing.T) {
+	var out strings.Builder
+	feed := Feed{Title: "feedpulse", SelfURL: "https://feeds.example.com/api/feed.rss"}
+	if err := Write(&out, FormatRSS, feed, testItems()); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	text := out.String()
+
+	for _, want := range []string{
+		`<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:dc="http://purl.org/dc/elements/1.1/">`,
+		`<atom:link href="https://feeds.example.com/api/feed.rss" rel="self" type="application/rss+xml"></atom:link>`,
+		`<title>Go 1.30 &amp; friends</title>`,
+		`<link>https://example.com/go?x=1&amp;y=2</link>`,
+		`<guid isPermaLink="false">urn:feedpulse:item:a1</guid>`,
+		`<pubDate>Mon, 02 Mar 2026 10:30:00 +0000</pubDate>`,
+		`<dc:creator>HN</dc:creator>`,
+		`<category>release</category>`,
+		`<lastBuildDate>Mon, 02 Mar 2026 11:00:00 +0000</lastBuildDate>`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %s in:\n%s", want, text)
+		}
+	}
+	if strings.Count(text, "<item>") != 2 {
+		t.Errorf("expected 2 items in:\n%s", text)
+	}
+
+	var doc struct {
+		Channel struct {
+			Items []struct {
+				Title string `xml:"title"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal([]byte(text), &doc); err != nil || doc.Channel.Items[1].Title != "No link" {
+		t.Errorf("expected well-formed XML, got %v", err)
+	}
+}
+
+func TestWrite_Atom(t *testing.T) {
+	var out strings.Builder
+	feed := Feed{Title: "feedpulse: HN", Link: "https://feeds.example.com/", SelfURL: "https://feeds.example.com/api/feed.atom"}
+	if err := Write(&out, FormatAtom, feed, testItems()); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	var doc struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		ID      string   `xml:"id"`
+		Updated string   `xml:"updated"`
+		Entries []struct {
+			Title     string `xml:"title"`
+			ID        string `xml:"id"`
+			Published string `xml:"published"`
+			Updated   string `xml:"updated"`
+			Author    string `xml:"author>name"`
+			Link      struct {
+				Href string `xml:"href,attr"`
+			} `xml:"link"`
+			Categories []struct {
+				Term string `xml:"term,attr"`
+			} `xml:"category"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal([]byte(out.String()), &doc); err != nil {
+		t.Fatalf("expected well-formed Atom, got %v:\n%s", err, out.String())
+	}
+	if doc.ID != feed.SelfURL || doc.Updated != "2026-03-02T11:00:00Z" {
+		t.Errorf("unexpected feed id/updated: %s %s", doc.ID, doc.Updated)
+	}
+	if len(doc.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(doc.Entries))
+	}
+	first := doc.Entries[0]
+	if first.ID != "urn:feedpulse:item:a1" || first.Published != "2026-03-02T10:30:00Z" || first.Updated != "2026-03-02T12:00:00Z" {
+		t.Errorf("unexpected entry: %+v", first)
+	}
+	if first.Author != "HN" || first.Link.Href != "https://example.com/go?x=1&y=2" || len(first.Categories) != 2 {
+		t.Errorf("unexpected entry: %+v", first)
+	}
+	if doc.Entries[1].Link.Href != "" {
+		t.Errorf("expected no link for an item without a URL, got %q", doc.Entries[1].Link.Href)
+	}
+}
+
+func TestWrite_UnknownFormat(t *testing.T) {
+	if err := Write(&strings.Builder{}, "json", Feed{}, nil); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,2344 @@
+package testutil
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"feedpulse/internal/clock"
+	"feedpulse/internal/storage"
+)
+
+// MockStore is an in-memory storage.Store. It follows the SQLite
+// Storage's semantics (upserts, revisions, blocklist, uniqueness scope,
+// stats) closely enough to unit test code that takes a Store, without cgo
+// or a database file. Times are kept at second precision, like Storage.
+type MockStore struct {
+	mu sync.Mutex
+
+	clock      clock.Clock
+	items      map[string]*mockItem
+	revisions  []storage.ItemRevision
+	logs       []storage.FetchLog
+	runItems   map[int][]storage.RunItem
+	curations  map[string]*mockCuration
+	tokens     []mockToken
+	audit      []storage.AuditEntry
+	archives   map[string]*storage.ArchiveEntry
+	saves      map[mockSaveKey]*storage.SaveEntry
+	scope      string
+	idHashes   map[string]string
+	dedup      string
+	snapshots  []storage.ReportSnapshot
+	feedState  map[string]map[string]string
+	journal    []mockJournalEntry
+	cursors    map[string]storage.BackfillCursor
+	blocked    []storage.BlockEntry
+	cookieKey  string
+	cookieJars map[string]mockCookieJar
+	// hostRequests counts requests per host per minute
+	hostRequests map[string]map[time.Time]int
+	// notified maps each notifier to when each item key was sent
+	notified map[string]map[string]time.Time
+	failures []storage.ParseFailure
+	// deleted holds the IDs of the items ApplyItemBatch deleted
+	deleted map[string]bool
+	// partialRuns marks the runs that saw only part of their feed
+	partialRuns map[int]bool
+	closed      bool
+}
+
+// mockItem is a stored item and the tags added to it by hand
+type mockItem struct {
+	storage.FeedItem
+	userTags []string
+}
+
+// mockJournalEntry is a journal entry and whether it was processed
+type mockJournalEntry struct {
+	storage.JournalEntry
+	processed bool
+}
+
+// mockCuration is a front page decision about an item
+type mockCuration struct {
+	state     string
+	position  int
+	updatedAt time.Time
+}
+
+// mockToken is an API token and the hash it is checked against
+type mockToken struct {
+	storage.APIToken
+	hash string
+}
+
+// mockSaveKey identifies a read-later save
+type mockSaveKey struct {
+	service, url string
+}
+
+// mockCookieJar is a cookie jar and the secret it was saved with
+type mockCookieJar struct {
+	key     string
+	cookies []storage.Cookie
+}
+
+var _ storage.Store = (*MockStore)(nil)
+
+// maxReportSnapshots is how many report snapshots are kept, as in Storage
+const maxReportSnapshots = 50
+
+// NewMockStore creates an empty in-memory store
+func NewMockStore() *MockStore {
+	return &MockStore{
+		clock:      clock.System,
+		items:      make(map[string]*mockItem),
+		runItems:   make(map[int][]storage.RunItem),
+		curations:  make(map[string]*mockCuration),
+		archives:   make(map[string]*storage.ArchiveEntry),
+		saves:      make(map[mockSaveKey]*storage.SaveEntry),
+		scope:      storage.ScopeSource,
+		idHashes:   make(map[string]string),
+		dedup:      storage.DedupOff,
+		feedState:  make(map[string]map[string]string),
+		cursors:    make(map[string]storage.BackfillCursor),
+		cookieJars: make(map[string]mockCookieJar),
+
+		hostRequests: make(map[string]map[time.Time]int),
+		notified:     make(map[string]map[string]time.Time),
+		deleted:      make(map[string]bool),
+		partialRuns:  make(map[int]bool),
+	}
+}
+
+// SetClock sets the clock used to timestamp records
+func (m *MockStore) SetClock(c clock.Clock) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clock = c
+}
+
+// Close marks the store closed; later calls fail
+func (m *MockStore) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	return nil
+}
+
+// check returns an error if the store was closed
+func (m *MockStore) check() error {
+	if m.closed {
+		return fmt.Errorf("store is closed")
+	}
+	return nil
+}
+
+// now returns the clock's time truncated like a stored timestamp
+func (m *MockStore) now() time.Time {
+	return m.clock.Now().Truncate(time.Second)
+}
+
+// SaveItems upserts items, skipping blocklisted ones
+func (m *MockStore) SaveItems(items []storage.FeedItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return err
+	}
+	m.saveItems(items)
+	return nil
+}
+
+// SaveFetchResult upserts items and logs the fetch
+func (m *MockStore) SaveFetchResult(log storage.FetchLog, items []storage.FeedItem) (storage.SaveResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return storage.SaveResult{}, err
+	}
+	result := m.saveItems(items)
+	m.logFetch(log)
+	run := m.logs[len(m.logs)-1]
+	run.Partial = log.Partial
+	m.recordRunItems(run, items)
+	return result, nil
+}
+
+// PreviewSave counts items the way SaveFetchResult would, without saving
+// them
+func (m *MockStore) PreviewSave(items []storage.FeedItem) (storage.SaveResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return storage.SaveResult{}, err
+	}
+
+	var result storage.SaveResult
+	seen := make(map[string]bool)
+	for _, item := range items {
+		switch {
+		case m.skipped(item):
+			result.Blocked++
+		case m.items[item.ID] != nil || seen[item.ID]:
+			result.Updated++
+		default:
+			result.Inserted++
+			result.New = append(result.New, item.ID)
+		}
+		seen[item.ID] = true
+	}
+	return result, nil
+}
+
+// recordRunItems records a successful fetch as a run with the items it
+// saw that aren't skipped, even none, and forgets the item lists of the
+// source's runs beyond storage.RunsKept
+func (m *MockStore) recordRunItems(log storage.FetchLog, items []storage.FeedItem) {
+	if log.Status != "success" && log.Status != "degraded" {
+		return
+	}
+	m.partialRuns[log.ID] = log.Partial
+
+	seen := []storage.RunItem{}
+	for _, item := range items {
+		if !m.skipped(item) {
+			seen = append(seen, storage.RunItem{ItemID: item.ID, Title: item.Title, URL: item.URL})
+		}
+	}
+	m.runItems[log.ID] = seen
+
+	kept := 0
+	for i := len(m.logs) - 1; i >= 0; i-- {
+		id := m.logs[i].ID
+		if m.logs[i].Source != log.Source || m.runItems[id] == nil {
+			continue
+		}
+		if kept++; kept > storage.RunsKept {
+			delete(m.runItems, id)
+			delete(m.partialRuns, id)
+		}
+	}
+}
+
+// skipped reports whether saving item skips it, because it is blocklisted
+// or was deleted
+func (m *MockStore) skipped(item storage.FeedItem) bool {
+	return storage.ItemBlocked(m.blocked, item) || m.deleted[item.ID]
+}
+
+// saveItems upserts items the way Storage does: existing rows keep their
+// source and created_at, and title or URL changes are recorded as revisions
+func (m *MockStore) saveItems(items []storage.FeedItem) storage.SaveResult {
+	var result storage.SaveResult
+	for _, item := range items {
+		if m.skipped(item) {
+			result.Blocked++
+			continue
+		}
+
+		item.Tags = append([]string(nil), item.Tags...)
+		item.CreatedAt = item.CreatedAt.Truncate(time.Second)
+
+		existing, ok := m.items[item.ID]
+		if !ok {
+			item.DuplicateOf = ""
+			if m.dedup == storage.DedupCrossSource {
+				item.DuplicateOf = m.firstWithURL(item)
+			}
+			m.items[item.ID] = &mockItem{FeedItem: item}
+			result.Inserted++
+			result.New = append(result.New, item.ID)
+			continue
+		}
+
+		if existing.Title != item.Title || existing.URL != item.URL {
+			m.revisions = append(m.revisions, storage.ItemRevision{
+				ItemID:    item.ID,
+				OldTitle:  existing.Title,
+				OldURL:    existing.URL,
+				NewTitle:  item.Title,
+				NewURL:    item.URL,
+				ChangedAt: m.now(),
+			})
+		}
+		existing.Title = item.Title
+		existing.URL = item.URL
+		existing.Timestamp = item.Timestamp
+		existing.Tags = item.Tags
+		for _, tag := range existing.userTags {
+			if !hasTag(existing.Tags, tag) {
+				existing.Tags = append(existing.Tags, tag)
+			}
+		}
+		existing.RawData = item.RawData
+		result.Updated++
+	}
+	return result
+}
+
+// firstWithURL returns the ID of the oldest original item of another
+// source stored before item with its normalized URL, if any
+func (m *MockStore) firstWithURL(item storage.FeedItem) string {
+	key := storage.NormalizeURL(item.URL)
+	for _, other := range m.sortedItems(nil) {
+		if other.ID == item.ID {
+			break
+		}
+		if other.Source != item.Source && other.DuplicateOf == "" && storage.NormalizeURL(other.URL) == key {
+			return other.ID
+		}
+	}
+	return ""
+}
+
+// relink points duplicates at the renamed IDs of their originals and
+// unlinks those whose original is gone
+func (m *MockStore) relink(renamed map[string]string) {
+	for _, item := range m.items {
+		if item.DuplicateOf == "" {
+			continue
+		}
+		if newID, ok := renamed[item.DuplicateOf]; ok {
+			item.DuplicateOf = newID
+		}
+		if m.items[item.DuplicateOf] == nil {
+			item.DuplicateOf = ""
+		}
+	}
+}
+
+// sortedItems returns stored items matching keep, oldest first
+func (m *MockStore) sortedItems(keep func(storage.FeedItem) bool) []storage.FeedItem {
+	var items []*mockItem
+	for _, item := range m.items {
+		if keep == nil || keep(item.FeedItem) {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].CreatedAt.Equal(items[j].CreatedAt) {
+			return items[i].CreatedAt.Before(items[j].CreatedAt)
+		}
+		return items[i].ID < items[j].ID
+	})
+
+	out := make([]storage.FeedItem, len(items))
+	for i, item := range items {
+		out[i] = item.FeedItem
+	}
+	return out
+}
+
+// GetItemCount returns the number of items for a source
+func (m *MockStore) GetItemCount(source string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, item := range m.items {
+		if item.Source == source {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetItemCountsBySource returns the number of items of every source that
+// has any
+func (m *MockStore) GetItemCountsBySource() (map[string]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, item := range m.items {
+		counts[item.Source]++
+	}
+	return counts, nil
+}
+
+// GetAllItemsCount returns the total number of items
+func (m *MockStore) GetAllItemsCount() (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, item := range m.items {
+		if item.DuplicateOf == "" {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetRecentItems returns the perSource most recently stored items of each
+// source, newest first
+func (m *MockStore) GetRecentItems(perSource int) (map[string][]storage.FeedItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+
+	items := m.sortedItems(nil)
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].CreatedAt.After(items[j].CreatedAt)
+	})
+
+	recent := make(map[string][]storage.FeedItem)
+	for _, item := range items {
+		if len(recent[item.Source]) < perSource {
+			item.Tags = nil
+			item.RawData = nil
+			recent[item.Source] = append(recent[item.Source], item)
+		}
+	}
+	return recent, nil
+}
+
+// GetItemsSince returns the items first stored within the last window,
+// newest first
+func (m *MockStore) GetItemsSince(window time.Duration) ([]storage.FeedItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+
+	cutoff := m.clock.Now().Add(-window).Truncate(time.Second)
+	items := m.sortedItems(func(item storage.FeedItem) bool {
+		return !item.CreatedAt.Before(cutoff)
+	})
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].CreatedAt.After(items[j].CreatedAt)
+	})
+	for i := range items {
+		items[i].RawData = nil
+	}
+	return items, nil
+}
+
+// LatestItems returns the limit most recently stored items, newest first,
+// only those tagged tag (case-insensitively) unless tag is empty
+func (m *MockStore) LatestItems(tag string, limit int) ([]storage.FeedItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+
+	items := m.sortedItems(func(item storage.FeedItem) bool {
+		if tag == "" {
+			return true
+		}
+		for _, t := range item.Tags {
+			if strings.EqualFold(t, tag) {
+				return true
+			}
+		}
+		return false
+	})
+	if len(items) == 0 {
+		return nil, nil
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].CreatedAt.After(items[j].CreatedAt)
+	})
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	for i := range items {
+		items[i].RawData = nil
+	}
+	return items, nil
+}
+
+// GetItems returns the stored items matching filter
+func (m *MockStore) GetItems(filter storage.ItemFilter) ([]storage.FeedItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+
+	switch filter.Sort {
+	case storage.SortNewest, storage.SortOldest, "":
+	case storage.SortTitle:
+		if filter.After != nil {
+			return nil, fmt.Errorf("cursors need the %s or %s sort order", storage.SortNewest, storage.SortOldest)
+		}
+	default:
+		return nil, fmt.Errorf("unknown sort order: %s", filter.Sort)
+	}
+
+	items := m.sortedItems(func(item storage.FeedItem) bool {
+		if !matchesItemFilter(item, filter) {
+			return false
+		}
+		if after := filter.After; after != nil {
+			at := after.CreatedAt.Truncate(time.Second)
+			created := item.CreatedAt.Truncate(time.Second)
+			if created.Equal(at) {
+				if item.ID <= after.ID {
+					return false
+				}
+			} else if created.After(at) != (filter.Sort == storage.SortOldest) {
+				return false
+			}
+		}
+		return true
+	})
+	// sortedItems lists them oldest first
+	switch filter.Sort {
+	case storage.SortNewest, "":
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].CreatedAt.After(items[j].CreatedAt)
+		})
+	case storage.SortTitle:
+		sort.Slice(items, func(i, j int) bool {
+			a, b := strings.ToLower(items[i].Title), strings.ToLower(items[j].Title)
+			if a != b {
+				return a < b
+			}
+			return items[i].ID < items[j].ID
+		})
+	}
+
+	if filter.Offset > 0 {
+		if filter.Offset >= len(items) {
+			items = nil
+		} else {
+			items = items[filter.Offset:]
+		}
+	}
+	if filter.Limit > 0 && len(items) > filter.Limit {
+		items = items[:filter.Limit]
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	if !filter.RawData {
+		for i := range items {
+			items[i].RawData = nil
+		}
+	}
+	return items, nil
+}
+
+// matchesItemFilter reports whether item has filter's source and tag and
+// was stored within its window
+func matchesItemFilter(item storage.FeedItem, filter storage.ItemFilter) bool {
+	if filter.Source != "" && item.Source != filter.Source {
+		return false
+	}
+	if !filter.Since.IsZero() && item.CreatedAt.Before(filter.Since.Truncate(time.Second)) {
+		return false
+	}
+	if !filter.Until.IsZero() && !item.CreatedAt.Before(filter.Until.Truncate(time.Second)) {
+		return false
+	}
+	return filter.Tag == "" || hasTag(item.Tags, filter.Tag)
+}
+
+// hasTag reports whether tags has tag, ignoring case
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyItemBatch makes batch's change to the matching items and returns
+// how many it changed
+func (m *MockStore) ApplyItemBatch(batch storage.ItemBatch) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return 0, err
+	}
+	if err := batch.Validate(); err != nil {
+		return 0, err
+	}
+
+	tag := strings.TrimSpace(batch.Tag)
+	changed := 0
+	for id, item := range m.items {
+		if !matchesItemFilter(item.FeedItem, batch.Filter) {
+			continue
+		}
+		switch batch.Action {
+		case storage.BatchMarkRead:
+			if item.ReadAt != nil {
+				continue
+			}
+			now := m.now().UTC()
+			item.ReadAt = &now
+		case storage.BatchStar:
+			if item.Starred {
+				continue
+			}
+			item.Starred = true
+		case storage.BatchTag:
+			if hasTag(item.Tags, tag) {
+				continue
+			}
+			item.Tags = append(append([]string(nil), item.Tags...), tag)
+			if !hasTag(item.userTags, tag) {
+				item.userTags = append(item.userTags, tag)
+			}
+		case storage.BatchDelete:
+			delete(m.items, id)
+			delete(m.curations, id)
+			m.deleted[id] = true
+		}
+		changed++
+	}
+	if batch.Action == storage.BatchDelete {
+		m.renumberPins()
+		m.relink(nil)
+	}
+	return changed, nil
+}
+
+// GetItemHistory returns the recorded changes to an item, oldest first
+func (m *MockStore) GetItemHistory(itemID string) ([]storage.ItemRevision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	var revisions []storage.ItemRevision
+	for _, rev := range m.revisions {
+		if rev.ItemID == itemID {
+			revisions = append(revisions, rev)
+		}
+	}
+	return revisions, nil
+}
+
+// ExplainItem gathers the provenance of an item, or returns nil if there
+// is no such item
+func (m *MockStore) ExplainItem(itemID string) (*storage.ItemExplanation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+
+	stored, ok := m.items[itemID]
+	if !ok {
+		return nil, nil
+	}
+	e := &storage.ItemExplanation{
+		Item:   stored.FeedItem,
+		Scope:  m.scope,
+		IDHash: m.idHash(stored.Source),
+	}
+	e.Item.Tags = append([]string(nil), stored.Tags...)
+	if len(e.Item.Tags) == 0 {
+		e.Item.Tags = nil
+	}
+
+	for i := range m.logs {
+		log := m.logs[i]
+		if log.Source != e.Item.Source || (log.Status != "success" && log.Status != "degraded") || log.FetchedAt.Before(e.Item.CreatedAt) {
+			continue
+		}
+		if e.FirstRun == nil || log.FetchedAt.Before(e.FirstRun.FetchedAt) {
+			e.FirstRun = &log
+		}
+	}
+
+	for _, rev := range m.revisions {
+		if rev.ItemID == itemID {
+			e.Revisions = append(e.Revisions, rev)
+		}
+	}
+
+	for _, item := range m.sortedItems(nil) {
+		if item.URL == e.Item.URL && item.ID != itemID {
+			e.Duplicates = append(e.Duplicates, storage.FeedItem{ID: item.ID, Title: item.Title, URL: item.URL, Source: item.Source, CreatedAt: item.CreatedAt})
+		}
+	}
+	sort.Slice(e.Duplicates, func(i, j int) bool {
+		if e.Duplicates[i].Source != e.Duplicates[j].Source {
+			return e.Duplicates[i].Source < e.Duplicates[j].Source
+		}
+		return e.Duplicates[i].ID < e.Duplicates[j].ID
+	})
+
+	if e.Item.RawData != nil {
+		e.Payload = *e.Item.RawData
+	} else {
+		for i := len(m.journal) - 1; i >= 0; i-- {
+			if m.journal[i].Source != e.Item.Source {
+				continue
+			}
+			if fragment, ok := storage.PayloadFragment(m.journal[i].Payload, e.Item.URL); ok {
+				e.Payload = fragment
+				break
+			}
+		}
+	}
+
+	return e, nil
+}
+
+// published returns when item was published, falling back to when it was
+// stored
+func published(item storage.FeedItem) time.Time {
+	if item.Timestamp != nil {
+		if t, err := time.Parse(time.RFC3339, *item.Timestamp); err == nil {
+			return t
+		}
+	}
+	return item.CreatedAt
+}
+
+// NewestItemTime returns the publish time of source's newest item
+func (m *MockStore) NewestItemTime(source string) (time.Time, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return time.Time{}, false, err
+	}
+
+	var newest time.Time
+	found := false
+	for _, item := range m.items {
+		if item.Source != source {
+			continue
+		}
+		if t := published(item.FeedItem); !found || t.After(newest) {
+			newest, found = t, true
+		}
+	}
+	if !found {
+		return time.Time{}, false, nil
+	}
+	return newest.UTC().Truncate(time.Second), true, nil
+}
+
+// UniquenessScope returns the scope items were written under
+func (m *MockStore) UniquenessScope() (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return "", err
+	}
+	return m.scope, nil
+}
+
+// SetUniquenessScope switches to scope, re-keying items when it narrows
+func (m *MockStore) SetUniquenessScope(scope string) (int, error) {
+	switch scope {
+	case storage.ScopeGlobal, storage.ScopeSource, storage.ScopeRun:
+	default:
+		return 0, fmt.Errorf("unknown uniqueness scope: %s", scope)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	if scope != m.scope && scope != storage.ScopeRun {
+		removed = m.rekey(func(source, url string) string {
+			return storage.ItemIDWith(m.idHash(source), scope, source, storage.KeyURL(m.dedup, url), "")
+		}, "")
+	}
+	m.scope = scope
+	return removed, nil
+}
+
+// SetIDHashes switches sources to their ID hashes, re-keying their items
+func (m *MockStore) SetIDHashes(hashes map[string]string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for source, idHash := range hashes {
+		switch idHash {
+		case "":
+			idHash = storage.IDHashSHA256
+		case storage.IDHashSHA256, storage.IDHashXXH64:
+		default:
+			return 0, fmt.Errorf("unknown id hash: %s", idHash)
+		}
+		if m.idHash(source) == idHash {
+			continue
+		}
+
+		if m.scope != storage.ScopeRun {
+			removed += m.rekey(func(source, url string) string {
+				return storage.ItemIDWith(idHash, m.scope, source, storage.KeyURL(m.dedup, url), "")
+			}, source)
+		}
+		m.idHashes[source] = idHash
+	}
+	return removed, nil
+}
+
+// Dedup returns the deduplication mode items were written under
+func (m *MockStore) Dedup() (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return "", err
+	}
+	return m.dedup, nil
+}
+
+// SetDedup switches to the deduplication mode dedup, re-keying items when
+// normalization is turned on or off and linking or unlinking duplicates
+func (m *MockStore) SetDedup(dedup string) (int, error) {
+	switch dedup {
+	case storage.DedupOff, storage.DedupNormalized, storage.DedupCrossSource:
+	default:
+		return 0, fmt.Errorf("unknown dedup mode: %s", dedup)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return 0, err
+	}
+	if dedup == m.dedup {
+		return 0, nil
+	}
+
+	removed := 0
+	if (m.dedup == storage.DedupOff) != (dedup == storage.DedupOff) && m.scope != storage.ScopeRun {
+		removed = m.rekey(func(source, url string) string {
+			return storage.ItemIDWith(m.idHash(source), m.scope, source, storage.KeyURL(dedup, url), "")
+		}, "")
+	}
+
+	for _, item := range m.items {
+		item.DuplicateOf = ""
+	}
+	if dedup == storage.DedupCrossSource {
+		for _, item := range m.sortedItems(nil) {
+			m.items[item.ID].DuplicateOf = m.firstWithURL(item)
+		}
+	}
+	m.dedup = dedup
+	return removed, nil
+}
+
+// idHash returns the ID hash source's items were written with
+func (m *MockStore) idHash(source string) string {
+	if idHash, ok := m.idHashes[source]; ok {
+		return idHash
+	}
+	return storage.IDHashSHA256
+}
+
+// rekey recomputes the IDs of source's items (all items if source is
+// empty) with idFor, keeping the oldest row when IDs collide
+func (m *MockStore) rekey(idFor func(source, url string) string, source string) int {
+	items := m.sortedItems(func(item storage.FeedItem) bool {
+		return source == "" || item.Source == source
+	})
+
+	renamed := make(map[string]string, len(items))
+	rekeyed := make(map[string]*mockItem, len(items))
+	for _, item := range items {
+		stored := m.items[item.ID]
+		delete(m.items, item.ID)
+
+		newID := idFor(item.Source, item.URL)
+		if _, taken := rekeyed[newID]; taken {
+			continue
+		}
+		renamed[item.ID] = newID
+		stored.ID = newID
+		rekeyed[newID] = stored
+	}
+	for id, item := range rekeyed {
+		m.items[id] = item
+	}
+
+	// Revisions follow their item; a merged-away item's history goes with it
+	kept := m.revisions[:0]
+	for _, rev := range m.revisions {
+		newID, ok := renamed[rev.ItemID]
+		if !ok && m.items[rev.ItemID] == nil {
+			continue
+		}
+		if ok {
+			rev.ItemID = newID
+		}
+		kept = append(kept, rev)
+	}
+	m.revisions = kept
+
+	// So do run item lists, except a merged-away item's entries
+	for fetchID, items := range m.runItems {
+		keptItems := items[:0]
+		for _, item := range items {
+			newID, ok := renamed[item.ItemID]
+			if !ok && m.items[item.ItemID] == nil {
+				continue
+			}
+			if ok {
+				item.ItemID = newID
+			}
+			keptItems = append(keptItems, item)
+		}
+		m.runItems[fetchID] = keptItems
+	}
+
+	// So do curations
+	curations := make(map[string]*mockCuration, len(m.curations))
+	for id, c := range m.curations {
+		newID, ok := renamed[id]
+		if !ok && m.items[id] == nil {
+			continue
+		}
+		if ok {
+			id = newID
+		}
+		curations[id] = c
+	}
+	m.curations = curations
+	m.renumberPins()
+	m.relink(renamed)
+
+	return len(items) - len(rekeyed)
+}
+
+// LogFetch logs a fetch operation
+func (m *MockStore) LogFetch(log storage.FetchLog) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return err
+	}
+	m.logFetch(log)
+	return nil
+}
+
+// ListFetchLog returns the fetch log entries matching filter, newest
+// first unless filter.Oldest is set
+func (m *MockStore) ListFetchLog(filter storage.FetchLogFilter) ([]storage.FetchLog, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+
+	logs := make([]storage.FetchLog, 0, len(m.logs))
+	for _, log := range m.logs {
+		if filter.Source != "" && log.Source != filter.Source {
+			continue
+		}
+		if filter.Status != "" && log.Status != filter.Status {
+			continue
+		}
+		logs = append(logs, log)
+	}
+	sort.SliceStable(logs, func(i, j int) bool {
+		a, b := logs[i], logs[j]
+		if filter.Oldest {
+			a, b = b, a
+		}
+		if !a.FetchedAt.Equal(b.FetchedAt) {
+			return a.FetchedAt.After(b.FetchedAt)
+		}
+		return a.ID > b.ID
+	})
+
+	if after := filter.After; after != nil {
+		at := after.FetchedAt.Truncate(time.Second)
+		for len(logs) > 0 {
+			log := logs[0]
+			past := log.FetchedAt.Before(at) || log.FetchedAt.Equal(at) && log.ID < after.ID
+			if filter.Oldest {
+				past = log.FetchedAt.After(at) || log.FetchedAt.Equal(at) && log.ID > after.ID
+			}
+			if past {
+				break
+			}
+			logs = logs[1:]
+		}
+	}
+	if filter.Limit > 0 && len(logs) > filter.Limit {
+		logs = logs[:filter.Limit]
+	}
+	if len(logs) == 0 {
+		return nil, nil
+	}
+	return logs, nil
+}
+
+// logFetch appends log with its ID and time set like a stored row,
+// recording its parse failure if it has one
+func (m *MockStore) logFetch(log storage.FetchLog) {
+	log.ID = len(m.logs) + 1
+	log.Partial = false
+	log.FetchedAt = log.FetchedAt.Truncate(time.Second)
+	if log.Failure != nil {
+		m.recordFailure(log)
+		log.Failure = nil
+	}
+	m.logs = append(m.logs, log)
+}
+
+// recordFailure keeps log's captured response, forgetting the source's
+// failures beyond storage.FailuresKept
+func (m *MockStore) recordFailure(log storage.FetchLog) {
+	id := int64(1)
+	if n := len(m.failures); n > 0 {
+		id = m.failures[n-1].ID + 1
+	}
+	failure := storage.ParseFailure{
+		ID:         id,
+		FetchID:    int64(log.ID),
+		Source:     log.Source,
+		CapturedAt: log.FetchedAt,
+		Size:       log.Failure.Size,
+		Stored:     len(log.Failure.Payload),
+		Compressed: gzipSize(log.Failure.Payload),
+		Payload:    append([]byte(nil), log.Failure.Payload...),
+	}
+	if log.ErrorMessage != nil {
+		failure.Error = *log.ErrorMessage
+	}
+	m.failures = append(m.failures, failure)
+
+	kept := 0
+	for i := len(m.failures) - 1; i >= 0; i-- {
+		if m.failures[i].Source != log.Source {
+			continue
+		}
+		if kept++; kept > storage.FailuresKept {
+			m.failures = append(m.failures[:i], m.failures[i+1:]...)
+		}
+	}
+}
+
+// gzipSize returns how long payload is gzipped, as Storage stores it
+func gzipSize(payload []byte) int {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(payload)
+	zw.Close()
+	return buf.Len()
+}
+
+// ListErrors groups the failed fetches matching filter by source and
+// normalized message
+func (m *MockStore) ListErrors(filter storage.ErrorFilter) ([]storage.ErrorGroup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	var logs []storage.FetchLog
+	for _, log := range m.logs {
+		if log.Status != "error" || (filter.Source != "" && log.Source != filter.Source) {
+			continue
+		}
+		if !filter.Since.IsZero() && log.FetchedAt.Before(filter.Since.Truncate(time.Second)) {
+			continue
+		}
+		logs = append(logs, log)
+	}
+	return storage.GroupErrors(logs, filter.Limit), nil
+}
+
+// ListParseFailures returns up to limit parse failures, newest first,
+// only source's unless source is empty, without their payloads
+func (m *MockStore) ListParseFailures(source string, limit int) ([]storage.ParseFailure, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	var failures []storage.ParseFailure
+	for i := len(m.failures) - 1; i >= 0 && (limit <= 0 || len(failures) < limit); i-- {
+		if source != "" && m.failures[i].Source != source {
+			continue
+		}
+		failure := m.failures[i]
+		failure.Payload = nil
+		failures = append(failures, failure)
+	}
+	return failures, nil
+}
+
+// GetParseFailure returns the parse failure id with its payload, or nil
+func (m *MockStore) GetParseFailure(id int64) (*storage.ParseFailure, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	for _, failure := range m.failures {
+		if failure.ID == id {
+			failure.Payload = append([]byte(nil), failure.Payload...)
+			return &failure, nil
+		}
+	}
+	return nil, nil
+}
+
+// successful reports whether a fetch log status counts as a success
+func successful(status string) bool {
+	return status == "success" || status == "degraded" || status == "unchanged"
+}
+
+// GetFetchStats returns fetch statistics for all sources
+func (m *MockStore) GetFetchStats() ([]storage.FetchStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	return m.fetchStats(), nil
+}
+
+// GetFetchStatsExact returns the same as GetFetchStats; the mock has no
+// materialized stats to drift
+func (m *MockStore) GetFetchStatsExact() ([]storage.FetchStats, error) {
+	return m.GetFetchStats()
+}
+
+// GetTagStats returns item counts per lowercased tag of the items filter
+// matches, most used first
+func (m *MockStore) GetTagStats(filter storage.ItemFilter) ([]storage.TagStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+
+	byTag := make(map[string]*storage.TagStats)
+	sources := make(map[string]map[string]bool)
+	originals := make(map[string]map[string]bool)
+	for _, item := range m.sortedItems(func(item storage.FeedItem) bool { return matchesItemFilter(item, filter) }) {
+		original := item.ID
+		if item.DuplicateOf != "" {
+			original = item.DuplicateOf
+		}
+		seen := make(map[string]bool)
+		for _, t := range item.Tags {
+			tag := strings.ToLower(t)
+			if seen[tag] {
+				continue
+			}
+			seen[tag] = true
+			stat, ok := byTag[tag]
+			if !ok {
+				stat = &storage.TagStats{Tag: tag}
+				byTag[tag] = stat
+				sources[tag] = make(map[string]bool)
+				originals[tag] = make(map[string]bool)
+			}
+			originals[tag][original] = true
+			stat.ItemsCount = len(originals[tag])
+			sources[tag][item.Source] = true
+			stat.Sources = len(sources[tag])
+			if item.CreatedAt.After(stat.LastStored) {
+				stat.LastStored = item.CreatedAt.UTC()
+			}
+		}
+	}
+
+	var stats []storage.TagStats
+	for _, stat := range byTag {
+		stats = append(stats, *stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].ItemsCount != stats[j].ItemsCount {
+			return stats[i].ItemsCount > stats[j].ItemsCount
+		}
+		return stats[i].Tag < stats[j].Tag
+	})
+	return stats, nil
+}
+
+// RebuildSourceStats does nothing; stats are always computed on demand
+func (m *MockStore) RebuildSourceStats() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.check()
+}
+
+// fetchStats computes per-source stats from the log and items, in source
+// order
+func (m *MockStore) fetchStats() []storage.FetchStats {
+	bySource := make(map[string]*storage.FetchStats)
+	get := func(source string) *storage.FetchStats {
+		stat, ok := bySource[source]
+		if !ok {
+			stat = &storage.FetchStats{Source: source}
+			bySource[source] = stat
+		}
+		return stat
+	}
+
+	for _, item := range m.items {
+		get(item.Source).ItemsCount++
+	}
+	for _, log := range m.logs {
+		stat := get(log.Source)
+		stat.TotalFetches++
+		if log.Status == "error" {
+			stat.ErrorCount++
+		}
+		if successful(log.Status) {
+			at := log.FetchedAt.Format(time.RFC3339)
+			if stat.LastSuccess == nil || at > *stat.LastSuccess {
+				stat.LastSuccess = &at
+			}
+		}
+	}
+
+	stats := make([]storage.FetchStats, 0, len(bySource))
+	for _, stat := range bySource {
+		stats = append(stats, *stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Source < stats[j].Source
+	})
+	return stats
+}
+
+// bucketStart truncates t to the start of its UTC bucket
+func bucketStart(t time.Time, bucket string) (time.Time, error) {
+	t = t.UTC()
+	switch bucket {
+	case storage.BucketHour:
+		return t.Truncate(time.Hour), nil
+	case storage.BucketDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+	default:
+		return time.Time{}, fmt.Errorf("unknown bucket size: %s (expected %s or %s)", bucket, storage.BucketHour, storage.BucketDay)
+	}
+}
+
+// sortBuckets returns the buckets in m, oldest first
+func sortBuckets(m map[time.Time]*storage.StatsBucket) []storage.StatsBucket {
+	buckets := make([]storage.StatsBucket, 0, len(m))
+	for _, b := range m {
+		buckets = append(buckets, *b)
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].Start.Before(buckets[j].Start)
+	})
+	return buckets
+}
+
+// GetStatsBuckets aggregates the fetch log into hourly or daily buckets
+func (m *MockStore) GetStatsBuckets(source, bucket string, window time.Duration) ([]storage.StatsBucket, error) {
+	if _, err := bucketStart(time.Time{}, bucket); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+
+	cutoff := m.clock.Now().Add(-window).Truncate(time.Second)
+	byStart := make(map[time.Time]*storage.StatsBucket)
+	durations := make(map[time.Time]int64)
+	for _, log := range m.logs {
+		if log.FetchedAt.Before(cutoff) || (source != "" && log.Source != source) {
+			continue
+		}
+		start, _ := bucketStart(log.FetchedAt, bucket)
+		b, ok := byStart[start]
+		if !ok {
+			b = &storage.StatsBucket{Start: start}
+			byStart[start] = b
+		}
+		b.Fetches++
+		if log.Status == "error" {
+			b.Errors++
+		}
+		b.Items += log.ItemsCount
+		durations[start] += log.DurationMs
+		if log.DurationMs > b.MaxDurationMs {
+			b.MaxDurationMs = log.DurationMs
+		}
+	}
+	for start, b := range byStart {
+		b.AvgDurationMs = float64(durations[start]) / float64(b.Fetches)
+	}
+	return sortBuckets(byStart), nil
+}
+
+// GetItemBuckets counts items published within the last window in hourly
+// or daily buckets
+func (m *MockStore) GetItemBuckets(source, bucket string, window time.Duration) ([]storage.StatsBucket, error) {
+	if _, err := bucketStart(time.Time{}, bucket); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+
+	cutoff := m.clock.Now().Add(-window).Truncate(time.Second)
+	byStart := make(map[time.Time]*storage.StatsBucket)
+	for _, item := range m.items {
+		at := published(item.FeedItem)
+		if at.Before(cutoff) || (source != "" && item.Source != source) {
+			continue
+		}
+		start, _ := bucketStart(at, bucket)
+		b, ok := byStart[start]
+		if !ok {
+			b = &storage.StatsBucket{Start: start}
+			byStart[start] = b
+		}
+		b.Items++
+	}
+	return sortBuckets(byStart), nil
+}
+
+// LastSuccess returns when source was last fetched successfully
+func (m *MockStore) LastSuccess(source string) (time.Time, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return time.Time{}, false, err
+	}
+
+	var last time.Time
+	found := false
+	for _, log := range m.logs {
+		if log.Source == source && successful(log.Status) && (!found || log.FetchedAt.After(last)) {
+			last, found = log.FetchedAt, true
+		}
+	}
+	return last, found, nil
+}
+
+// GetConsecutiveFailures returns how many fetches failed in a row since
+// each failing source's last success
+func (m *MockStore) GetConsecutiveFailures() (map[string]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+
+	failures := make(map[string]int)
+	for _, log := range m.logs {
+		switch {
+		case log.Status == "error":
+			failures[log.Source]++
+		case successful(log.Status):
+			delete(failures, log.Source)
+		}
+	}
+	return failures, nil
+}
+
+// SlowThreshold returns the SlowPercentile of a source's recent
+// successful fetch durations, once there are enough of them
+func (m *MockStore) SlowThreshold(source string) (int64, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return 0, false, err
+	}
+
+	var durations []int64
+	for i := len(m.logs) - 1; i >= 0 && len(durations) < storage.SlowHistory; i-- {
+		if m.logs[i].Source == source && successful(m.logs[i].Status) {
+			durations = append(durations, m.logs[i].DurationMs)
+		}
+	}
+	if len(durations) < storage.SlowMinHistory {
+		return 0, false, nil
+	}
+	return storage.Percentile(durations, storage.SlowPercentile), true, nil
+}
+
+// RecordHostRequest counts one request to host in its minute, forgetting
+// the host's minutes older than a day
+func (m *MockStore) RecordHostRequest(host string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return err
+	}
+
+	minute := at.UTC().Truncate(time.Minute)
+	if m.hostRequests[host] == nil {
+		m.hostRequests[host] = make(map[time.Time]int)
+	}
+	m.hostRequests[host][minute]++
+	for t := range m.hostRequests[host] {
+		if t.Before(minute.Add(-24 * time.Hour)) {
+			delete(m.hostRequests[host], t)
+		}
+	}
+	return nil
+}
+
+// HostUsage returns how many requests host got in the hour and the day
+// before now
+func (m *MockStore) HostUsage(host string, now time.Time) (storage.HostUsage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return storage.HostUsage{}, err
+	}
+
+	minute := now.UTC().Truncate(time.Minute)
+	usage := storage.HostUsage{Host: host}
+	for t, n := range m.hostRequests[host] {
+		if t.After(minute.Add(-24 * time.Hour)) {
+			usage.LastDay += n
+		}
+		if t.After(minute.Add(-time.Hour)) {
+			usage.LastHour += n
+		}
+	}
+	return usage, nil
+}
+
+// ReserveHostRequests counts n requests to host in at's minute unless
+// that would exceed perHour or perDay (0 is no limit)
+func (m *MockStore) ReserveHostRequests(host string, n, perHour, perDay int, at time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return false, err
+	}
+
+	minute := at.UTC().Truncate(time.Minute)
+	lastHour, lastDay := 0, 0
+	for t, count := range m.hostRequests[host] {
+		if t.After(minute.Add(-24 * time.Hour)) {
+			lastDay += count
+		}
+		if t.After(minute.Add(-time.Hour)) {
+			lastHour += count
+		}
+	}
+	if (perHour > 0 && lastHour+n > perHour) || (perDay > 0 && lastDay+n > perDay) {
+		return false, nil
+	}
+	if m.hostRequests[host] == nil {
+		m.hostRequests[host] = make(map[time.Time]int)
+	}
+	m.hostRequests[host][minute] += n
+	return true, nil
+}
+
+// ReleaseHostRequests takes n requests off host's count in at's minute
+func (m *MockStore) ReleaseHostRequests(host string, n int, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return err
+	}
+
+	minute := at.UTC().Truncate(time.Minute)
+	if count, ok := m.hostRequests[host][minute]; ok {
+		m.hostRequests[host][minute] = max(count-n, 0)
+	}
+	return nil
+}
+
+// ClaimNotifications returns the items sink wasn't sent within window,
+// one per normalized URL, and records them as sent
+func (m *MockStore) ClaimNotifications(sink string, items []storage.FeedItem, window time.Duration) ([]storage.FeedItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+
+	now := m.clock.Now().UTC().Truncate(time.Second)
+	if m.notified[sink] == nil {
+		m.notified[sink] = make(map[string]time.Time)
+	}
+	sent := m.notified[sink]
+	for key, at := range sent {
+		if !at.After(now.Add(-window)) {
+			delete(sent, key)
+		}
+	}
+
+	var claimed []storage.FeedItem
+	for _, item := range items {
+		key := storage.NormalizeURL(item.URL)
+		if key == "" {
+			key = item.ID
+		}
+		if _, ok := sent[key]; ok {
+			continue
+		}
+		sent[key] = now
+		claimed = append(claimed, item)
+	}
+	return claimed, nil
+}
+
+// ReleaseNotifications drops the claims of sink on items
+func (m *MockStore) ReleaseNotifications(sink string, items []storage.FeedItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		key := storage.NormalizeURL(item.URL)
+		if key == "" {
+			key = item.ID
+		}
+		delete(m.notified[sink], key)
+	}
+	return nil
+}
+
+// SaveReportSnapshot stores stats as the snapshot of a report run
+func (m *MockStore) SaveReportSnapshot(stats []storage.FetchStats) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return err
+	}
+
+	snapshot := storage.ReportSnapshot{
+		TakenAt: m.now().UTC(),
+		Stats:   append([]storage.FetchStats(nil), stats...),
+	}
+	sort.Slice(snapshot.Stats, func(i, j int) bool {
+		return snapshot.Stats[i].Source < snapshot.Stats[j].Source
+	})
+
+	// Two reports within a second share a timestamp; the later one wins
+	if n := len(m.snapshots); n > 0 && m.snapshots[n-1].TakenAt.Equal(snapshot.TakenAt) {
+		m.snapshots = m.snapshots[:n-1]
+	}
+	m.snapshots = append(m.snapshots, snapshot)
+	if n := len(m.snapshots); n > maxReportSnapshots {
+		m.snapshots = m.snapshots[n-maxReportSnapshots:]
+	}
+	return nil
+}
+
+// LatestReportSnapshot returns the most recent report snapshot, or nil
+func (m *MockStore) LatestReportSnapshot() (*storage.ReportSnapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	if len(m.snapshots) == 0 {
+		return nil, nil
+	}
+	latest := m.snapshots[len(m.snapshots)-1]
+	return &latest, nil
+}
+
+// ListRuns returns up to limit of source's runs that still have their
+// item lists, newest first, with Partial set on partial ones
+func (m *MockStore) ListRuns(source string, limit int) ([]storage.FetchLog, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	var runs []storage.FetchLog
+	for i := len(m.logs) - 1; i >= 0 && len(runs) < limit; i-- {
+		if m.logs[i].Source == source && m.runItems[m.logs[i].ID] != nil {
+			run := m.logs[i]
+			run.Partial = m.partialRuns[run.ID]
+			runs = append(runs, run)
+		}
+	}
+	return runs, nil
+}
+
+// GetRunItems returns the items a run saw, in feed order
+func (m *MockStore) GetRunItems(fetchID int) ([]storage.RunItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	return append([]storage.RunItem(nil), m.runItems[fetchID]...), nil
+}
+
+// Pin pins a stored item at position, moving the pins below it down
+func (m *MockStore) Pin(itemID string, position int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return err
+	}
+	if m.items[itemID] == nil {
+		return fmt.Errorf("item not found: %s", itemID)
+	}
+
+	var order []string
+	for _, id := range m.pinnedIDs() {
+		if id != itemID {
+			order = append(order, id)
+		}
+	}
+	if position < 1 || position > len(order) {
+		order = append(order, itemID)
+	} else {
+		order = append(order[:position-1], append([]string{itemID}, order[position-1:]...)...)
+	}
+
+	now := m.now()
+	for i, id := range order {
+		c := m.curations[id]
+		if c == nil {
+			c = &mockCuration{}
+			m.curations[id] = c
+		}
+		if c.state != storage.CurationPinned || c.position != i+1 {
+			c.updatedAt = now
+		}
+		c.state, c.position = storage.CurationPinned, i+1
+	}
+	return nil
+}
+
+// Hide keeps a stored item off the front page
+func (m *MockStore) Hide(itemID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return err
+	}
+	if m.items[itemID] == nil {
+		return fmt.Errorf("item not found: %s", itemID)
+	}
+	m.curations[itemID] = &mockCuration{state: storage.CurationHidden, updatedAt: m.now()}
+	m.renumberPins()
+	return nil
+}
+
+// Uncurate unpins or unhides an item
+func (m *MockStore) Uncurate(itemID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return false, err
+	}
+	_, ok := m.curations[itemID]
+	delete(m.curations, itemID)
+	m.renumberPins()
+	return ok, nil
+}
+
+// ListCurations returns pinned items in order, then hidden ones
+func (m *MockStore) ListCurations() ([]storage.Curation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+
+	var curations []storage.Curation
+	for id, c := range m.curations {
+		stored := m.items[id]
+		if stored == nil {
+			continue
+		}
+		item := stored.FeedItem
+		item.RawData = nil
+		curations = append(curations, storage.Curation{Item: item, State: c.state, Position: c.position, UpdatedAt: c.updatedAt})
+	}
+	sort.Slice(curations, func(i, j int) bool {
+		a, b := curations[i], curations[j]
+		if (a.State == storage.CurationHidden) != (b.State == storage.CurationHidden) {
+			return b.State == storage.CurationHidden
+		}
+		if a.Position != b.Position {
+			return a.Position < b.Position
+		}
+		if !a.UpdatedAt.Equal(b.UpdatedAt) {
+			return a.UpdatedAt.Before(b.UpdatedAt)
+		}
+		return a.Item.ID < b.Item.ID
+	})
+	return curations, nil
+}
+
+// pinnedIDs returns the pinned item IDs in order
+func (m *MockStore) pinnedIDs() []string {
+	var ids []string
+	for id, c := range m.curations {
+		if c.state == storage.CurationPinned {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := m.curations[ids[i]], m.curations[ids[j]]
+		if a.position != b.position {
+			return a.position < b.position
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// renumberPins closes gaps in the pin positions
+func (m *MockStore) renumberPins() {
+	for i, id := range m.pinnedIDs() {
+		m.curations[id].position = i + 1
+	}
+}
+
+// CreateAPIToken stores a new token and returns it
+func (m *MockStore) CreateAPIToken(name, scope string) (string, storage.APIToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return "", storage.APIToken{}, err
+	}
+	if scope != storage.ScopeRead && scope != storage.ScopeAdmin {
+		return "", storage.APIToken{}, fmt.Errorf("unknown token scope: %s (must be %s or %s)", scope, storage.ScopeRead, storage.ScopeAdmin)
+	}
+
+	id, token, err := storage.GenerateAPIToken()
+	if err != nil {
+		return "", storage.APIToken{}, err
+	}
+	t := storage.APIToken{ID: id, Name: name, Scope: scope, CreatedAt: m.now()}
+	m.tokens = append(m.tokens, mockToken{APIToken: t, hash: storage.HashAPIToken(token)})
+	return token, t, nil
+}
+
+// ListAPITokens returns all tokens, oldest first
+func (m *MockStore) ListAPITokens() ([]storage.APIToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+
+	var tokens []storage.APIToken
+	for _, t := range m.tokens {
+		tokens = append(tokens, t.APIToken)
+	}
+	sort.SliceStable(tokens, func(i, j int) bool {
+		if !tokens[i].CreatedAt.Equal(tokens[j].CreatedAt) {
+			return tokens[i].CreatedAt.Before(tokens[j].CreatedAt)
+		}
+		return tokens[i].ID < tokens[j].ID
+	})
+	return tokens, nil
+}
+
+// RevokeAPIToken deletes a token
+func (m *MockStore) RevokeAPIToken(id string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return false, err
+	}
+	for i, t := range m.tokens {
+		if t.ID == id {
+			m.tokens = append(m.tokens[:i], m.tokens[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// AuthenticateAPIToken returns the token matching token, recording its use
+func (m *MockStore) AuthenticateAPIToken(token string) (*storage.APIToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	id, ok := storage.ParseAPIToken(token)
+	if !ok {
+		return nil, nil
+	}
+	for i := range m.tokens {
+		t := &m.tokens[i]
+		if t.ID == id && t.hash == storage.HashAPIToken(token) {
+			now := m.now()
+			if t.LastUsedAt == nil || now.Sub(*t.LastUsedAt) >= storage.TokenUseInterval {
+				t.LastUsedAt = &now
+			}
+			found := t.APIToken
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+// RecordAudit logs a mutating action
+func (m *MockStore) RecordAudit(actor, action string, params map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return err
+	}
+	var copied map[string]string
+	if len(params) > 0 {
+		copied = make(map[string]string, len(params))
+		for k, v := range params {
+			copied[k] = v
+		}
+	}
+	m.audit = append(m.audit, storage.AuditEntry{ID: len(m.audit) + 1, At: m.now(), Actor: actor, Action: action, Params: copied})
+	return nil
+}
+
+// ListAudit returns matching audit entries, newest first
+func (m *MockStore) ListAudit(filter storage.AuditFilter) ([]storage.AuditEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+
+	var entries []storage.AuditEntry
+	for i := len(m.audit) - 1; i >= 0; i-- {
+		e := m.audit[i]
+		if filter.Actor != "" && e.Actor != filter.Actor {
+			continue
+		}
+		if strings.HasSuffix(filter.Action, ".") {
+			if !strings.HasPrefix(e.Action, filter.Action) {
+				continue
+			}
+		} else if filter.Action != "" && e.Action != filter.Action {
+			continue
+		}
+		if !filter.Since.IsZero() && e.At.Before(filter.Since) {
+			continue
+		}
+		if filter.Limit > 0 && len(entries) == filter.Limit {
+			break
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// EnqueueArchive queues item URLs for archiving
+func (m *MockStore) EnqueueArchive(items []storage.FeedItem) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return 0, err
+	}
+
+	queued := 0
+	for _, item := range items {
+		if item.URL == "" || m.archives[item.URL] != nil {
+			continue
+		}
+		now := m.now()
+		m.archives[item.URL] = &storage.ArchiveEntry{
+			URL:         item.URL,
+			Source:      item.Source,
+			Status:      storage.ArchivePending,
+			RequestedAt: now,
+			UpdatedAt:   now,
+		}
+		queued++
+	}
+	return queued, nil
+}
+
+// DueArchives returns pending and submitted entries, least recently touched first
+func (m *MockStore) DueArchives(limit int) ([]storage.ArchiveEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	return m.sortedArchives(func(e *storage.ArchiveEntry) bool {
+		return e.Status == storage.ArchivePending || e.Status == storage.ArchiveSubmitted
+	}, func(a, b *storage.ArchiveEntry) bool {
+		return a.UpdatedAt.Before(b.UpdatedAt)
+	}, limit), nil
+}
+
+// RecentArchives returns entries, most recently updated first
+func (m *MockStore) RecentArchives(limit int) ([]storage.ArchiveEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	return m.sortedArchives(func(*storage.ArchiveEntry) bool { return true }, func(a, b *storage.ArchiveEntry) bool {
+		return a.UpdatedAt.After(b.UpdatedAt)
+	}, limit), nil
+}
+
+// GetArchive returns the entry for url
+func (m *MockStore) GetArchive(url string) (*storage.ArchiveEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	e := m.archives[url]
+	if e == nil {
+		return nil, nil
+	}
+	found := *e
+	return &found, nil
+}
+
+// UpdateArchive stores an entry's new state
+func (m *MockStore) UpdateArchive(entry storage.ArchiveEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return err
+	}
+	e := m.archives[entry.URL]
+	if e == nil {
+		return fmt.Errorf("archive not queued: %s", entry.URL)
+	}
+	e.Status, e.JobID, e.ArchivedURL = entry.Status, entry.JobID, entry.ArchivedURL
+	e.Attempts, e.LastError, e.UpdatedAt = entry.Attempts, entry.LastError, m.now()
+	return nil
+}
+
+// ArchiveCounts returns how many entries are in each state
+func (m *MockStore) ArchiveCounts() (map[string]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, e := range m.archives {
+		counts[e.Status]++
+	}
+	return counts, nil
+}
+
+// QueueSave queues items to be saved to service
+func (m *MockStore) QueueSave(service string, items []storage.FeedItem) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return 0, err
+	}
+
+	queued := 0
+	for _, item := range items {
+		key := mockSaveKey{service, item.URL}
+		if item.URL == "" || m.saves[key] != nil {
+			continue
+		}
+		now := m.now()
+		m.saves[key] = &storage.SaveEntry{
+			Service:     service,
+			URL:         item.URL,
+			Source:      item.Source,
+			Title:       item.Title,
+			Tags:        append([]string(nil), item.Tags...),
+			Status:      storage.SaveQueued,
+			RequestedAt: now,
+			UpdatedAt:   now,
+		}
+		if len(item.Tags) == 0 {
+			m.saves[key].Tags = nil
+		}
+		queued++
+	}
+	return queued, nil
+}
+
+// QueuedSaves returns saves still to send, oldest first
+func (m *MockStore) QueuedSaves(limit int) ([]storage.SaveEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+
+	var entries []*storage.SaveEntry
+	for _, e := range m.saves {
+		if e.Status == storage.SaveQueued {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if !a.UpdatedAt.Equal(b.UpdatedAt) {
+			return a.UpdatedAt.Before(b.UpdatedAt)
+		}
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		return a.URL < b.URL
+	})
+
+	var out []storage.SaveEntry
+	for _, e := range entries {
+		if limit > 0 && len(out) == limit {
+			break
+		}
+		out = append(out, *e)
+	}
+	return out, nil
+}
+
+// GetSave returns the save of url to service
+func (m *MockStore) GetSave(service, url string) (*storage.SaveEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	e := m.saves[mockSaveKey{service, url}]
+	if e == nil {
+		return nil, nil
+	}
+	found := *e
+	return &found, nil
+}
+
+// UpdateSave stores a save's new state
+func (m *MockStore) UpdateSave(entry storage.SaveEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return err
+	}
+	e := m.saves[mockSaveKey{entry.Service, entry.URL}]
+	if e == nil {
+		return fmt.Errorf("save not queued: %s to %s", entry.URL, entry.Service)
+	}
+	e.Status, e.Attempts, e.LastError, e.UpdatedAt = entry.Status, entry.Attempts, entry.LastError, m.now()
+	return nil
+}
+
+// sortedArchives returns up to limit entries matching keep, ordered by
+// before and then URL
+func (m *MockStore) sortedArchives(keep func(*storage.ArchiveEntry) bool, before func(a, b *storage.ArchiveEntry) bool, limit int) []storage.ArchiveEntry {
+	var entries []*storage.ArchiveEntry
+	for _, e := range m.archives {
+		if keep(e) {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if !a.UpdatedAt.Equal(b.UpdatedAt) {
+			return before(a, b)
+		}
+		return a.URL < b.URL
+	})
+
+	var out []storage.ArchiveEntry
+	for _, e := range entries {
+		if limit > 0 && len(out) == limit {
+			break
+		}
+		out = append(out, *e)
+	}
+	return out
+}
+
+// GetFeedState returns the values stored for feed
+func (m *MockStore) GetFeedState(feed string) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	state := make(map[string]string, len(m.feedState[feed]))
+	for k, v := range m.feedState[feed] {
+		state[k] = v
+	}
+	return state, nil
+}
+
+// SetFeedState stores values for feed, leaving other keys untouched
+func (m *MockStore) SetFeedState(feed string, values map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return err
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	if m.feedState[feed] == nil {
+		m.feedState[feed] = make(map[string]string)
+	}
+	for k, v := range values {
+		m.feedState[feed][k] = v
+	}
+	return nil
+}
+
+// ClearFeedState forgets everything stored for feed
+func (m *MockStore) ClearFeedState(feed string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return err
+	}
+	delete(m.feedState, feed)
+	return nil
+}
+
+// AppendJournal records a raw payload and returns its entry ID
+func (m *MockStore) AppendJournal(source, feedType string, payload []byte) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return 0, err
+	}
+
+	var id int64 = 1
+	if n := len(m.journal); n > 0 {
+		id = m.journal[n-1].ID + 1
+	}
+	m.journal = append(m.journal, mockJournalEntry{JournalEntry: storage.JournalEntry{
+		ID:        id,
+		Source:    source,
+		FeedType:  feedType,
+		Payload:   append([]byte(nil), payload...),
+		FetchedAt: m.now(),
+	}})
+	return id, nil
+}
+
+// MarkJournalProcessed marks a journal entry as processed
+func (m *MockStore) MarkJournalProcessed(id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return err
+	}
+	for i := range m.journal {
+		if m.journal[i].ID == id {
+			m.journal[i].processed = true
+		}
+	}
+	return nil
+}
+
+// PendingJournal returns unprocessed journal entries in the order they
+// were written
+func (m *MockStore) PendingJournal() ([]storage.JournalEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	var entries []storage.JournalEntry
+	for _, entry := range m.journal {
+		if !entry.processed {
+			entries = append(entries, entry.JournalEntry)
+		}
+	}
+	return entries, nil
+}
+
+// PruneJournal deletes processed journal entries
+func (m *MockStore) PruneJournal() (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return 0, err
+	}
+	kept := m.journal[:0]
+	for _, entry := range m.journal {
+		if !entry.processed {
+			kept = append(kept, entry)
+		}
+	}
+	pruned := len(m.journal) - len(kept)
+	m.journal = kept
+	return pruned, nil
+}
+
+// GetBackfillCursor returns the stored cursor for feed, or nil
+func (m *MockStore) GetBackfillCursor(feed string) (*storage.BackfillCursor, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	c, ok := m.cursors[feed]
+	if !ok {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+// ResetBackfillCursor forgets a feed's backfill progress
+func (m *MockStore) ResetBackfillCursor(feed string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return err
+	}
+	delete(m.cursors, feed)
+	return nil
+}
+
+// SaveBackfillPage stores a page of items, its fetch log entry and the
+// cursor for the next page
+func (m *MockStore) SaveBackfillPage(log storage.FetchLog, items []storage.FeedItem, next storage.BackfillCursor) (storage.SaveResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return storage.SaveResult{}, err
+	}
+	result := m.saveItems(items)
+	m.logFetch(log)
+	next.UpdatedAt = m.now()
+	m.cursors[next.Feed] = next
+	return result, nil
+}
+
+// Block adds value to the blocklist and deletes the items it matches
+func (m *MockStore) Block(value string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return 0, err
+	}
+
+	entry := storage.BlockEntry{Value: value, Kind: storage.BlockKind(value), CreatedAt: m.now()}
+	exists := false
+	for _, b := range m.blocked {
+		if b.Value == value {
+			exists = true
+		}
+	}
+	if !exists {
+		m.blocked = append(m.blocked, entry)
+	}
+
+	removed := 0
+	for id, item := range m.items {
+		if storage.ItemBlocked([]storage.BlockEntry{entry}, item.FeedItem) {
+			delete(m.items, id)
+			removed++
+		}
+	}
+	m.relink(nil)
+	return removed, nil
+}
+
+// Unblock removes value from the blocklist
+func (m *MockStore) Unblock(value string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return false, err
+	}
+	for i, b := range m.blocked {
+		if b.Value == value {
+			m.blocked = append(m.blocked[:i], m.blocked[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ListBlocked returns all blocklist entries, oldest first
+func (m *MockStore) ListBlocked() ([]storage.BlockEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	entries := append([]storage.BlockEntry(nil), m.blocked...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+		}
+		return entries[i].Value < entries[j].Value
+	})
+	return entries, nil
+}
+
+// SetCookieKey sets the secret cookie jars are saved with
+func (m *MockStore) SetCookieKey(secret string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cookieKey = secret
+}
+
+// SaveCookies stores feed's cookie jar under the current secret
+func (m *MockStore) SaveCookies(feed string, cookies []storage.Cookie) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return err
+	}
+	if m.cookieKey == "" {
+		return fmt.Errorf("no cookie encryption secret set")
+	}
+	m.cookieJars[feed] = mockCookieJar{key: m.cookieKey, cookies: append([]storage.Cookie(nil), cookies...)}
+	return nil
+}
+
+// LoadCookies returns feed's cookie jar, which only the secret it was
+// saved with can read
+func (m *MockStore) LoadCookies(feed string) ([]storage.Cookie, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	jar, ok := m.cookieJars[feed]
+	if !ok {
+		return nil, nil
+	}
+	if m.cookieKey == "" {
+		return nil, fmt.Errorf("no cookie encryption secret set")
+	}
+	if jar.key != m.cookieKey {
+		return nil, fmt.Errorf("failed to decrypt cookies (was the secret changed?)")
+	}
+	return append([]storage.Cookie(nil), jar.cookies...), nil
+}
+
+// ClearCookies deletes feed's cookie jar
+func (m *MockStore) ClearCookies(feed string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check(); err != nil {
+		return err
+	}
+	delete(m.cookieJars, feed)
+	return nil
+}
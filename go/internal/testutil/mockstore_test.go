@@ -0,0 +1,443 @@
+package testutil
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"feedpulse/internal/clock"
+	"feedpulse/internal/storage"
+)
+
+// exerciseStore runs the same sequence of operations against s and
+// returns every result by name, so implementations can be compared
+func exerciseStore(t *testing.T, s storage.Store) map[string]interface{} {
+	t.Helper()
+
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	s.SetClock(clock.Fixed(now))
+	ts := func(d time.Duration) *string {
+		v := now.Add(d).Format(time.RFC3339)
+		return &v
+	}
+	item := func(source, url, title string, age time.Duration) storage.FeedItem {
+		return storage.FeedItem{
+			ID:        storage.ItemID(storage.ScopeSource, source, url, ""),
+			Title:     title,
+			URL:       url,
+			Source:    source,
+			Timestamp: ts(-age - time.Hour),
+			Tags:      []string{"go"},
+			CreatedAt: now.Add(-age),
+		}
+	}
+
+	results := make(map[string]interface{})
+	record := func(name string, v interface{}, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%s failed: %v", name, err)
+		}
+		results[name] = v
+	}
+
+	blocked, err := s.Block("https://spam.example.com/*")
+	record("Block", blocked, err)
+
+	saved, err := s.SaveFetchResult(storage.FetchLog{Source: "HN", FetchedAt: now.Add(-3 * time.Hour), Status: "success", ItemsCount: 3, DurationMs: 120}, []storage.FeedItem{
+		item("HN", "https://example.com/a", "A", 3*time.Hour),
+		item("HN", "https://example.com/shared", "Shared", 2*time.Hour),
+		item("HN", "https://spam.example.com/x", "Spam", 2*time.Hour),
+	})
+	record("SaveFetchResult", saved, err)
+
+	saved, err = s.SaveFetchResult(storage.FetchLog{Source: "Lobsters", FetchedAt: now.Add(-2 * time.Hour), Status: "success", ItemsCount: 1, DurationMs: 80}, []storage.FeedItem{
+		item("Lobsters", "https://example.com/shared", "Shared", time.Hour),
+	})
+	record("SaveFetchResult 2", saved, err)
+
+	// A retitled item is an update with a revision
+	saved, err = s.SaveFetchResult(storage.FetchLog{Source: "HN", FetchedAt: now.Add(-time.Hour), Status: "success", ItemsCount: 1, DurationMs: 100}, []storage.FeedItem{
+		item("HN", "https://example.com/a", "A (updated)", 3*time.Hour),
+	})
+	record("SaveFetchResult 3", saved, err)
+
+	runs, err := s.ListRuns("HN", storage.RunsKept)
+	record("ListRuns", runs, err)
+	runItems, err := s.GetRunItems(runs[len(runs)-1].ID)
+	record("GetRunItems", runItems, err)
+
+	// Runs that saw nothing or only part of the feed are recorded too
+	saved, err = s.SaveFetchResult(storage.FetchLog{Source: "Partial", FetchedAt: now.Add(-time.Hour), Status: "success", DurationMs: 10, Partial: true}, nil)
+	record("SaveFetchResult partial", saved, err)
+	runs, err = s.ListRuns("Partial", storage.RunsKept)
+	record("ListRuns partial", runs, err)
+	runItems, err = s.GetRunItems(runs[0].ID)
+	record("GetRunItems partial", runItems, err)
+
+	for _, status := range []string{"error", "error"} {
+		msg := "boom"
+		record("LogFetch", nil, s.LogFetch(storage.FetchLog{Source: "Lobsters", FetchedAt: now.Add(-30 * time.Minute), Status: status, ErrorMessage: &msg, DurationMs: 5000}))
+	}
+
+	count, err := s.GetItemCount("HN")
+	record("GetItemCount", count, err)
+	counts, err := s.GetItemCountsBySource()
+	record("GetItemCountsBySource", counts, err)
+	total, err := s.GetAllItemsCount()
+	record("GetAllItemsCount", total, err)
+	recent, err := s.GetRecentItems(1)
+	record("GetRecentItems", recent, err)
+	since, err := s.GetItemsSince(150 * time.Minute)
+	record("GetItemsSince", since, err)
+	latest, err := s.LatestItems("GO", 2)
+	record("LatestItems", latest, err)
+	latest, err = s.LatestItems("rust", 0)
+	record("LatestItems untagged", latest, err)
+	queried, err := s.GetItems(storage.ItemFilter{Source: "HN", Tag: "GO", Since: now.Add(-4 * time.Hour), Until: now, Sort: storage.SortTitle})
+	record("GetItems", queried, err)
+	queried, err = s.GetItems(storage.ItemFilter{Sort: storage.SortOldest, Limit: 1, Offset: 1})
+	record("GetItems page", queried, err)
+	queried, err = s.GetItems(storage.ItemFilter{Offset: 10})
+	record("GetItems past the end", queried, err)
+	history, err := s.GetItemHistory(storage.ItemID(storage.ScopeSource, "HN", "https://example.com/a", ""))
+	record("GetItemHistory", history, err)
+	newest, ok, err := s.NewestItemTime("HN")
+	record("NewestItemTime", []interface{}{newest, ok}, err)
+
+	stats, err := s.GetFetchStats()
+	record("GetFetchStats", stats, err)
+	exact, err := s.GetFetchStatsExact()
+	record("GetFetchStatsExact", exact, err)
+	tagStats, err := s.GetTagStats(storage.ItemFilter{})
+	record("GetTagStats", tagStats, err)
+	tagStats, err = s.GetTagStats(storage.ItemFilter{Source: "HN", Since: now.Add(-2 * time.Hour)})
+	record("GetTagStats filtered", tagStats, err)
+	buckets, err := s.GetStatsBuckets("", storage.BucketHour, 24*time.Hour)
+	record("GetStatsBuckets", buckets, err)
+	itemBuckets, err := s.GetItemBuckets("HN", storage.BucketDay, 24*time.Hour)
+	record("GetItemBuckets", itemBuckets, err)
+	last, ok, err := s.LastSuccess("Lobsters")
+	record("LastSuccess", []interface{}{last, ok}, err)
+	failures, err := s.GetConsecutiveFailures()
+	record("GetConsecutiveFailures", failures, err)
+
+	record("SaveReportSnapshot", nil, s.SaveReportSnapshot(stats))
+	snapshot, err := s.LatestReportSnapshot()
+	record("LatestReportSnapshot", snapshot, err)
+
+	record("SetFeedState", nil, s.SetFeedState("HN", map[string]string{"cursor": "42", "etag": "x"}))
+	record("SetFeedState 2", nil, s.SetFeedState("HN", map[string]string{"cursor": "43"}))
+	state, err := s.GetFeedState("HN")
+	record("GetFeedState", state, err)
+	record("ClearFeedState", nil, s.ClearFeedState("HN"))
+	state, err = s.GetFeedState("HN")
+	record("GetFeedState cleared", state, err)
+
+	id1, err := s.AppendJournal("HN", "json", []byte("[1]"))
+	record("AppendJournal", id1, err)
+	id2, err := s.AppendJournal("HN", "json", []byte("[2]"))
+	record("AppendJournal 2", id2, err)
+	record("MarkJournalProcessed", nil, s.MarkJournalProcessed(id1))
+	pending, err := s.PendingJournal()
+	record("PendingJournal", pending, err)
+	pruned, err := s.PruneJournal()
+	record("PruneJournal", pruned, err)
+
+	cursor, err := s.GetBackfillCursor("HN")
+	record("GetBackfillCursor", cursor, err)
+	saved, err = s.SaveBackfillPage(storage.FetchLog{Source: "HN", FetchedAt: now, Status: "success", ItemsCount: 1}, []storage.FeedItem{
+		item("HN", "https://example.com/old", "Old", 48*time.Hour),
+	}, storage.BackfillCursor{Feed: "HN", Page: 2})
+	record("SaveBackfillPage", saved, err)
+	cursor, err = s.GetBackfillCursor("HN")
+	record("GetBackfillCursor 2", cursor, err)
+	record("ResetBackfillCursor", nil, s.ResetBackfillCursor("HN"))
+
+	unblocked, err := s.Unblock("https://spam.example.com/*")
+	record("Unblock", unblocked, err)
+	removed, err := s.Block("https://example.com/old")
+	record("Block URL", removed, err)
+	entries, err := s.ListBlocked()
+	record("ListBlocked", entries, err)
+
+	s.SetCookieKey("secret")
+	cookies := []storage.Cookie{{URL: "https://example.com", Name: "session", Value: "abc"}}
+	record("SaveCookies", nil, s.SaveCookies("HN", cookies))
+	loaded, err := s.LoadCookies("HN")
+	record("LoadCookies", loaded, err)
+	s.SetCookieKey("other")
+	if _, err := s.LoadCookies("HN"); err == nil {
+		t.Error("expected loading cookies with another secret to fail")
+	}
+	record("ClearCookies", nil, s.ClearCookies("HN"))
+
+	_, err = s.AppendJournal("HN", "json", []byte(`[{"url": "https:\/\/example.com\/shared"}]`))
+	record("AppendJournal 3", nil, err)
+	explained, err := s.ExplainItem(storage.ItemID(storage.ScopeSource, "HN", "https://example.com/shared", ""))
+	record("ExplainItem", explained, err)
+	explained, err = s.ExplainItem(storage.ItemID(storage.ScopeSource, "HN", "https://example.com/a", ""))
+	record("ExplainItem revised", explained, err)
+	explained, err = s.ExplainItem("missing")
+	record("ExplainItem missing", explained, err)
+
+	record("Pin", nil, s.Pin(storage.ItemID(storage.ScopeSource, "HN", "https://example.com/shared", ""), 0))
+	record("Pin 2", nil, s.Pin(storage.ItemID(storage.ScopeSource, "Lobsters", "https://example.com/shared", ""), 1))
+	record("Hide", nil, s.Hide(storage.ItemID(storage.ScopeSource, "HN", "https://example.com/a", "")))
+	if err := s.Pin("missing", 0); err == nil {
+		t.Error("expected pinning a missing item to fail")
+	}
+	curations, err := s.ListCurations()
+	record("ListCurations", curations, err)
+
+	// Token IDs are random, so only what's derived from the inputs is compared
+	readToken, _, err := s.CreateAPIToken("dashboard", storage.ScopeRead)
+	record("CreateAPIToken", nil, err)
+	_, admin, err := s.CreateAPIToken("ops", storage.ScopeAdmin)
+	record("CreateAPIToken admin", nil, err)
+	if _, _, err := s.CreateAPIToken("bad", "write"); err == nil {
+		t.Error("expected an unknown scope to fail")
+	}
+	authed, err := s.AuthenticateAPIToken(readToken)
+	if authed != nil {
+		record("AuthenticateAPIToken", []interface{}{authed.Name, authed.Scope, authed.LastUsedAt}, err)
+	} else {
+		t.Error("expected the read token to authenticate")
+	}
+	for _, after := range []time.Duration{30 * time.Second, 2 * time.Minute} {
+		s.SetClock(clock.Fixed(now.Add(after)))
+		if authed, err := s.AuthenticateAPIToken(readToken); authed != nil {
+			record("AuthenticateAPIToken later", authed.LastUsedAt, err)
+		} else {
+			t.Error("expected the read token to authenticate")
+		}
+	}
+	s.SetClock(clock.Fixed(now))
+	authed, err = s.AuthenticateAPIToken(readToken + "0")
+	record("AuthenticateAPIToken wrong", authed, err)
+	revoked, err := s.RevokeAPIToken(admin.ID)
+	record("RevokeAPIToken", revoked, err)
+	tokens, err := s.ListAPITokens()
+	var tokenNames []string
+	for _, tok := range tokens {
+		tokenNames = append(tokenNames, tok.Name+"/"+tok.Scope)
+	}
+	record("ListAPITokens", tokenNames, err)
+
+	record("RecordAudit", nil, s.RecordAudit("cli:alice", storage.AuditBlock, map[string]string{"value": "example.com"}))
+	record("RecordAudit 2", nil, s.RecordAudit("token:ops", storage.AuditTokenCreate, map[string]string{"scope": "read"}))
+	record("RecordAudit 3", nil, s.RecordAudit("cli:alice", storage.AuditPrune, nil))
+	audit, err := s.ListAudit(storage.AuditFilter{})
+	record("ListAudit", audit, err)
+	audit, err = s.ListAudit(storage.AuditFilter{Actor: "cli:alice", Limit: 1})
+	record("ListAudit actor", audit, err)
+	audit, err = s.ListAudit(storage.AuditFilter{Action: "token.", Since: now.Add(-time.Minute)})
+	record("ListAudit prefix", audit, err)
+
+	queued, err := s.EnqueueArchive([]storage.FeedItem{
+		item("HN", "https://example.com/a", "A", 0),
+		item("Lobsters", "https://example.com/a", "A", 0),
+		item("HN", "https://example.com/b", "B", 0),
+		{ID: "no-url", Source: "HN"},
+	})
+	record("EnqueueArchive", queued, err)
+	s.SetClock(clock.Fixed(now.Add(time.Minute)))
+	record("UpdateArchive", nil, s.UpdateArchive(storage.ArchiveEntry{
+		URL: "https://example.com/a", Status: storage.ArchiveDone, Attempts: 1,
+		ArchivedURL: "https://web.archive.org/web/20260302120000/https://example.com/a",
+	}))
+	if err := s.UpdateArchive(storage.ArchiveEntry{URL: "https://example.com/missing"}); err == nil {
+		t.Error("expected updating an unqueued archive to fail")
+	}
+	s.SetClock(clock.Fixed(now))
+	due, err := s.DueArchives(10)
+	record("DueArchives", due, err)
+	archives, err := s.RecentArchives(10)
+	record("RecentArchives", archives, err)
+	archive, err := s.GetArchive("https://example.com/a")
+	record("GetArchive", archive, err)
+	archive, err = s.GetArchive("https://example.com/missing")
+	record("GetArchive missing", archive, err)
+	archiveCounts, err := s.ArchiveCounts()
+	record("ArchiveCounts", archiveCounts, err)
+
+	tagged := item("HN", "https://example.com/a", "A", 0)
+	tagged.Tags = []string{"go"}
+	queued, err = s.QueueSave("pocket", []storage.FeedItem{tagged, item("HN", "https://example.com/b", "B", 0)})
+	record("QueueSave", queued, err)
+	queued, err = s.QueueSave("wallabag", []storage.FeedItem{tagged, tagged})
+	record("QueueSave again", queued, err)
+	s.SetClock(clock.Fixed(now.Add(time.Minute)))
+	record("UpdateSave", nil, s.UpdateSave(storage.SaveEntry{Service: "pocket", URL: "https://example.com/a", Status: storage.SaveDone, Attempts: 1}))
+	if err := s.UpdateSave(storage.SaveEntry{Service: "instapaper", URL: "https://example.com/a"}); err == nil {
+		t.Error("expected updating an unqueued save to fail")
+	}
+	s.SetClock(clock.Fixed(now))
+	saves, err := s.QueuedSaves(10)
+	record("QueuedSaves", saves, err)
+	save, err := s.GetSave("pocket", "https://example.com/a")
+	record("GetSave", save, err)
+	save, err = s.GetSave("instapaper", "https://example.com/a")
+	record("GetSave missing", save, err)
+
+	merged, err := s.SetUniquenessScope(storage.ScopeGlobal)
+	record("SetUniquenessScope", merged, err)
+	scope, err := s.UniquenessScope()
+	record("UniquenessScope", scope, err)
+	merged, err = s.SetIDHashes(map[string]string{"HN": storage.IDHashXXH64, "Lobsters": storage.IDHashXXH64})
+	record("SetIDHashes", merged, err)
+	recent, err = s.GetRecentItems(10)
+	record("GetRecentItems rekeyed", recent, err)
+	runItems, err = s.GetRunItems(runs[len(runs)-1].ID)
+	record("GetRunItems rekeyed", runItems, err)
+	curations, err = s.ListCurations()
+	record("ListCurations rekeyed", curations, err)
+	history, err = s.GetItemHistory(storage.ItemIDWith(storage.IDHashXXH64, storage.ScopeGlobal, "HN", "https://example.com/a", ""))
+	record("GetItemHistory rekeyed", history, err)
+
+	threshold, ok, err := s.SlowThreshold("Timed")
+	record("SlowThreshold without history", []interface{}{threshold, ok}, err)
+	for i := 1; i <= storage.SlowMinHistory; i++ {
+		status := "success"
+		if i%5 == 0 {
+			status = "error"
+		}
+		if err := s.LogFetch(storage.FetchLog{Source: "Timed", FetchedAt: now, Status: status, DurationMs: int64(i * 10), Slow: i == 3}); err != nil {
+			t.Fatalf("LogFetch failed: %v", err)
+		}
+	}
+	threshold, ok, err = s.SlowThreshold("Timed")
+	record("SlowThreshold too short", []interface{}{threshold, ok}, err)
+	for i := 0; i < 5; i++ {
+		if err := s.LogFetch(storage.FetchLog{Source: "Timed", FetchedAt: now, Status: "unchanged", DurationMs: 1000}); err != nil {
+			t.Fatalf("LogFetch failed: %v", err)
+		}
+	}
+	threshold, ok, err = s.SlowThreshold("Timed")
+	record("SlowThreshold", []interface{}{threshold, ok}, err)
+
+	for _, at := range []time.Time{now.Add(-25 * time.Hour), now.Add(-3 * time.Hour), now.Add(-30 * time.Minute), now.Add(-30 * time.Minute), now} {
+		record("RecordHostRequest", nil, s.RecordHostRequest("api.github.com", at))
+	}
+	usage, err := s.HostUsage("api.github.com", now)
+	record("HostUsage", usage, err)
+	usage, err = s.HostUsage("api.example.com", now)
+	record("HostUsage unused", usage, err)
+	for _, limits := range [][2]int{{5, 0}, {5, 0}, {0, 7}, {0, 0}} {
+		reserved, err := s.ReserveHostRequests("api.github.com", 2, limits[0], limits[1], now)
+		record("ReserveHostRequests", reserved, err)
+	}
+	record("ReleaseHostRequests", nil, s.ReleaseHostRequests("api.github.com", 3, now))
+	usage, err = s.HostUsage("api.github.com", now)
+	record("HostUsage after release", usage, err)
+
+	notified := []storage.FeedItem{
+		{ID: "n1", Source: "HN", URL: "https://example.com/n?utm_source=hn"},
+		{ID: "n2", Source: "Lobsters", URL: "https://EXAMPLE.com/n/"},
+		{ID: "n3", Source: "HN", URL: "https://example.com/m"},
+	}
+	claimed, err := s.ClaimNotifications("matrix", notified, time.Hour)
+	record("ClaimNotifications", claimed, err)
+	claimed, err = s.ClaimNotifications("matrix", notified[1:], time.Hour)
+	record("ClaimNotifications again", claimed, err)
+	record("ReleaseNotifications", nil, s.ReleaseNotifications("matrix", notified[2:]))
+	claimed, err = s.ClaimNotifications("matrix", notified, time.Hour)
+	record("ClaimNotifications released", claimed, err)
+	s.SetClock(clock.Fixed(now.Add(2 * time.Hour)))
+	claimed, err = s.ClaimNotifications("matrix", notified[1:], time.Hour)
+	record("ClaimNotifications expired", claimed, err)
+	s.SetClock(clock.Fixed(now))
+
+	malformed := "malformed JSON: unexpected end of JSON input"
+	for i := 0; i < storage.FailuresKept+2; i++ {
+		payload := []byte(fmt.Sprintf(`{"items": [%d`, i))
+		err := s.LogFetch(storage.FetchLog{Source: "Broken", FetchedAt: now, Status: "error", ErrorMessage: &malformed, Failure: storage.CaptureResponse(payload, 8)})
+		record("LogFetch with failure", nil, err)
+	}
+	parseFailures, err := s.ListParseFailures("Broken", 3)
+	record("ListParseFailures", parseFailures, err)
+	parseFailures, err = s.ListParseFailures("", 0)
+	record("ListParseFailures all", parseFailures, err)
+	failure, err := s.GetParseFailure(parseFailures[0].ID)
+	record("GetParseFailure", failure, err)
+	failure, err = s.GetParseFailure(1)
+	record("GetParseFailure pruned", failure, err)
+
+	for i, msg := range []string{"failed after 3 retries: HTTP 503: Service Unavailable", "failed after 2 retries: HTTP 503: Service Unavailable", "HTTP request failed: dial tcp 10.0.0.1:443: connection refused"} {
+		msg := msg
+		if err := s.LogFetch(storage.FetchLog{Source: "Flaky", FetchedAt: now.Add(time.Duration(i) * time.Minute), Status: "error", ErrorMessage: &msg}); err != nil {
+			t.Fatalf("LogFetch failed: %v", err)
+		}
+	}
+	errorGroups, err := s.ListErrors(storage.ErrorFilter{Source: "Flaky"})
+	record("ListErrors", errorGroups, err)
+	errorGroups, err = s.ListErrors(storage.ErrorFilter{Since: now.Add(time.Minute), Limit: 1})
+	record("ListErrors since", errorGroups, err)
+
+	merged, err = s.SetDedup(storage.DedupCrossSource)
+	record("SetDedup", merged, err)
+	tracked := "https://Example.com/shared/?utm_source=reddit"
+	reddit := item("Reddit", tracked, "Shared", 0)
+	reddit.ID = storage.ItemID(storage.ScopeSource, "Reddit", storage.NormalizeURL(tracked), "")
+	record("SaveItems duplicate", nil, s.SaveItems([]storage.FeedItem{reddit}))
+	linked, err := s.GetItems(storage.ItemFilter{Sort: storage.SortOldest})
+	record("GetItems linked", linked, err)
+	total, err = s.GetAllItemsCount()
+	record("GetAllItemsCount linked", total, err)
+	tagStats, err = s.GetTagStats(storage.ItemFilter{})
+	record("GetTagStats linked", tagStats, err)
+
+	// Deleted items aren't saved again
+	gone := []storage.FeedItem{item("Gone", "https://example.com/gone", "Gone", time.Hour)}
+	record("SaveItems gone", nil, s.SaveItems(gone))
+	deleted, err := s.ApplyItemBatch(storage.ItemBatch{Action: storage.BatchDelete, Filter: storage.ItemFilter{Source: "Gone"}})
+	record("ApplyItemBatch delete", deleted, err)
+	saved, err = s.SaveFetchResult(storage.FetchLog{Source: "Gone", FetchedAt: now, Status: "success", ItemsCount: 1, DurationMs: 10}, gone)
+	record("SaveFetchResult deleted", saved, err)
+	count, err = s.GetItemCount("Gone")
+	record("GetItemCount deleted", count, err)
+
+	return results
+}
+
+func TestMockStore_MatchesStorage(t *testing.T) {
+	db := NewTestDB(t)
+	defer db.Close()
+
+	want := exerciseStore(t, db)
+	got := exerciseStore(t, NewMockStore())
+
+	for name, w := range want {
+		if g := got[name]; !reflect.DeepEqual(g, w) {
+			t.Errorf("%s:\n  mock:    %#v\n  storage: %#v", name, g, w)
+		}
+	}
+}
+
+// TestPostgres_MatchesSQLite runs the same calls against PostgreSQL as
+// against SQLite; it is skipped unless FEEDPULSE_TEST_DATABASE_URL is set
+func TestPostgres_MatchesSQLite(t *testing.T) {
+	pg := NewPostgresTestDB(t)
+	defer pg.Close()
+	db := NewTestDB(t)
+	defer db.Close()
+
+	want := exerciseStore(t, db)
+	got := exerciseStore(t, pg)
+
+	for name, w := range want {
+		if g := got[name]; !reflect.DeepEqual(g, w) {
+			t.Errorf("%s:\n  postgres: %#v\n  sqlite:   %#v", name, g, w)
+		}
+	}
+}
+
+func TestMockStore_Close(t *testing.T) {
+	m := NewMockStore()
+	m.Close()
+
+	if err := m.SaveItems([]storage.FeedItem{{ID: "1"}}); err == nil {
+		t.Error("expected an error after Close")
+	}
+}
@@ -5,13 +5,16 @@
 package testutil
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"feedpulse/internal/storage"
 )
@@ -32,6 +35,47 @@ func NewTestDB(t *testing.T) *storage.Storage {
 	return db
 }
 
+// NewPostgresTestDB creates an empty PostgreSQL database for testing, as a
+// schema of its own in the database FEEDPULSE_TEST_DATABASE_URL points
+// at. The test is skipped when that isn't set; the schema is dropped when
+// it completes.
+func NewPostgresTestDB(t *testing.T) *storage.Storage {
+	t.Helper()
+
+	dsn := os.Getenv("FEEDPULSE_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("FEEDPULSE_TEST_DATABASE_URL not set")
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("invalid FEEDPULSE_TEST_DATABASE_URL: %v", err)
+	}
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	schema := fmt.Sprintf("feedpulse_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		admin.Close()
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		admin.Close()
+	})
+
+	// lib/pq sends parameters it doesn't know itself to the server
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+	db, err := storage.NewPostgresStorage(u.String())
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	return db
+}
+
 // MockServer creates a test HTTP server that returns the specified response.
 // The server is automatically closed when the test completes.
 func MockServer(t *testing.T, statusCode int, body string) *httptest.Server {
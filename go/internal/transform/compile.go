@@ -0,0 +1,324 @@
+package transform
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Compile parses a script, reporting the first line that doesn't parse
+func Compile(src string) (*Script, error) {
+	var script Script
+	for i, line := range strings.Split(src, "\n") {
+		toks, err := lex(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		if len(toks) == 0 {
+			continue
+		}
+
+		p := &compiler{toks: toks}
+		st, err := p.stmt()
+		if err == nil && !p.done() {
+			err = fmt.Errorf("unexpected %s", p.peek())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		st.line = i + 1
+		script.stmts = append(script.stmts, st)
+	}
+	return &script, nil
+}
+
+// token is a lexed word, operator or string literal
+type token struct {
+	text string
+	// str is set for string literals, whose text is unquoted
+	str bool
+}
+
+func (t token) String() string {
+	if t.str {
+		return strconv.Quote(t.text)
+	}
+	return "'" + t.text + "'"
+}
+
+// lex splits a line into tokens, stopping at a # outside a string
+func lex(line string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(line); {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#':
+			return toks, nil
+		case c == '"':
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			s, err := strconv.Unquote(line[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s", line[i:end+1])
+			}
+			toks = append(toks, token{text: s, str: true})
+			i = end + 1
+		case c == '=' || c == '!':
+			if i+1 < len(line) && line[i+1] == '=' {
+				toks = append(toks, token{text: line[i : i+2]})
+				i += 2
+			} else if c == '=' {
+				toks = append(toks, token{text: "="})
+				i++
+			} else {
+				return nil, fmt.Errorf("unexpected '!'")
+			}
+		case strings.IndexByte("(),+", c) >= 0:
+			toks = append(toks, token{text: string(c)})
+			i++
+		case c == '_' || unicode.IsLetter(rune(c)):
+			end := i
+			for end < len(line) && (line[end] == '_' || unicode.IsLetter(rune(line[end])) || unicode.IsDigit(rune(line[end]))) {
+				end++
+			}
+			toks = append(toks, token{text: line[i:end]})
+			i = end
+		default:
+			return nil, fmt.Errorf("unexpected %q", c)
+		}
+	}
+	return toks, nil
+}
+
+// compiler parses the tokens of one statement
+type compiler struct {
+	toks []token
+	pos  int
+}
+
+func (p *compiler) done() bool {
+	return p.pos >= len(p.toks)
+}
+
+// peek describes the next token for error messages
+func (p *compiler) peek() string {
+	if p.done() {
+		return "end of line"
+	}
+	return p.toks[p.pos].String()
+}
+
+// accept consumes the next token if it is the word or operator text
+func (p *compiler) accept(text string) bool {
+	if p.done() || p.toks[p.pos].str || p.toks[p.pos].text != text {
+		return false
+	}
+	p.pos++
+	return true
+}
+
+func (p *compiler) expect(text string) error {
+	if !p.accept(text) {
+		return fmt.Errorf("expected '%s', got %s", text, p.peek())
+	}
+	return nil
+}
+
+// word consumes the next token if it is a word, returning it
+func (p *compiler) word() (string, bool) {
+	if p.done() || p.toks[p.pos].str {
+		return "", false
+	}
+	p.pos++
+	return p.toks[p.pos-1].text, true
+}
+
+func (p *compiler) stmt() (stmt, error) {
+	var st stmt
+	got := p.peek()
+	action, _ := p.word()
+	st.action = action
+	switch action {
+	case "drop":
+	case "set":
+		got := p.peek()
+		name, _ := p.word()
+		switch name {
+		case "title", "url", "timestamp":
+			st.field = name
+		default:
+			return st, fmt.Errorf("set needs title, url or timestamp, got %s", got)
+		}
+		if err := p.expect("="); err != nil {
+			return st, err
+		}
+		fallthrough
+	case "tag", "untag":
+		value, err := p.expr()
+		if err != nil {
+			return st, err
+		}
+		st.value = value
+	default:
+		return st, fmt.Errorf("unknown statement %s (must be drop, set, tag or untag)", got)
+	}
+
+	if p.accept("if") {
+		c, err := p.or()
+		if err != nil {
+			return st, err
+		}
+		st.cond = c
+	}
+	return st, nil
+}
+
+func (p *compiler) or() (cond, error) {
+	left, err := p.and()
+	for err == nil && p.accept("or") {
+		var right cond
+		if right, err = p.and(); err == nil {
+			left = or{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *compiler) and() (cond, error) {
+	left, err := p.unary()
+	for err == nil && p.accept("and") {
+		var right cond
+		if right, err = p.unary(); err == nil {
+			left = and{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *compiler) unary() (cond, error) {
+	if p.accept("not") {
+		c, err := p.unary()
+		return not{c}, err
+	}
+	if p.accept("(") {
+		c, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return c, p.expect(")")
+	}
+	if p.accept("tags") {
+		if err := p.expect("has"); err != nil {
+			return nil, err
+		}
+		tag, err := p.expr()
+		return hasTagCond{tag}, err
+	}
+
+	left, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	got := p.peek()
+	op, _ := p.word()
+	switch op {
+	case "==", "!=", "contains", "startswith", "endswith":
+		right, err := p.expr()
+		return &compare{op: op, left: left, right: right}, err
+	case "matches":
+		re, err := p.pattern()
+		return &compare{op: op, left: left, re: re}, err
+	}
+	return nil, fmt.Errorf("expected a comparison (==, !=, contains, startswith, endswith or matches), got %s", got)
+}
+
+// pattern reads a string literal regular expression
+func (p *compiler) pattern() (*regexp.Regexp, error) {
+	if p.done() || !p.toks[p.pos].str {
+		return nil, fmt.Errorf("expected a quoted regular expression, got %s", p.peek())
+	}
+	p.pos++
+	re, err := regexp.Compile(p.toks[p.pos-1].text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %w", err)
+	}
+	return re, nil
+}
+
+func (p *compiler) expr() (expr, error) {
+	first, err := p.term()
+	if err != nil || !p.accept("+") {
+		return first, err
+	}
+	parts := concat{first}
+	for {
+		next, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, next)
+		if !p.accept("+") {
+			return parts, nil
+		}
+	}
+}
+
+func (p *compiler) term() (expr, error) {
+	if p.done() {
+		return nil, fmt.Errorf("expected a value, got end of line")
+	}
+	if tok := p.toks[p.pos]; tok.str {
+		p.pos++
+		return literal(tok.text), nil
+	}
+
+	got := p.peek()
+	name, _ := p.word()
+	switch name {
+	case "title", "url", "timestamp", "source":
+		return field(name), nil
+	}
+	arity, ok := functions[name]
+	if !ok {
+		return nil, fmt.Errorf("expected a value, got %s", got)
+	}
+
+	c := &call{fn: name}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	for i := 0; i < arity; i++ {
+		if i > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		if name == "sub" && i == 1 {
+			re, err := p.pattern()
+			if err != nil {
+				return nil, err
+			}
+			c.re = re
+			c.args = append(c.args, literal(""))
+			continue
+		}
+		arg, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		c.args = append(c.args, arg)
+	}
+	return c, p.expect(")")
+}
@@ -0,0 +1,320 @@
+// Package transform runs per-feed transform scripts, which rewrite or drop
+// parsed items before they are stored.
+//
+// A script has one statement per line; blank lines and lines starting
+// with # are ignored:
+//
+//	drop if title contains "[sponsored]"
+//	set title = trim(replace(title, " | Example Blog", ""))
+//	set url = sub(url, "^http://", "https://")
+//	tag "video" if host(url) == "www.youtube.com"
+//	untag "misc"
+//
+// drop discards the item and ends the script; set changes title, url or
+// timestamp; tag and untag add or remove a tag. Any statement may end in
+// "if" and a condition. Conditions compare values with ==, !=, contains,
+// startswith, endswith or matches (a regular expression), test tags with
+// "tags has", and combine with and, or, not and parentheses. Values are
+// string literals, the fields title, url, timestamp and source, "+" to
+// join them, and the functions lower, upper, trim, replace(s, old, new),
+// sub(s, regexp, replacement) and host(url).
+//
+// Scripts are sandboxed by construction: they see only the item they are
+// given, have no loops, and fail instead of producing a value longer than
+// MaxValue.
+package transform
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"feedpulse/internal/storage"
+)
+
+// MaxValue is the longest string a script may produce
+const MaxValue = 64 << 10
+
+// Script is a compiled transform script
+type Script struct {
+	stmts []stmt
+}
+
+// stmt is one line of a script
+type stmt struct {
+	line   int
+	action string
+	// field is the field set assigns
+	field string
+	value expr
+	cond  cond
+}
+
+// Apply runs s on item and returns the changed item, and false if the
+// script dropped it. item itself is left unchanged, so callers can keep it
+// as it was when the script fails.
+func (s *Script) Apply(item storage.FeedItem) (storage.FeedItem, bool, error) {
+	item.Tags = append([]string(nil), item.Tags...)
+	if item.Timestamp != nil {
+		ts := *item.Timestamp
+		item.Timestamp = &ts
+	}
+
+	for _, st := range s.stmts {
+		if st.cond != nil {
+			ok, err := st.cond.test(&item)
+			if err != nil {
+				return item, true, fmt.Errorf("line %d: %w", st.line, err)
+			}
+			if !ok {
+				continue
+			}
+		}
+
+		var v string
+		if st.value != nil {
+			var err error
+			if v, err = st.value.eval(&item); err != nil {
+				return item, true, fmt.Errorf("line %d: %w", st.line, err)
+			}
+		}
+
+		switch st.action {
+		case "drop":
+			return item, false, nil
+		case "set":
+			switch st.field {
+			case "title":
+				item.Title = v
+			case "url":
+				item.URL = v
+			case "timestamp":
+				item.Timestamp = nil
+				if v != "" {
+					item.Timestamp = &v
+				}
+			}
+		case "tag":
+			if v != "" && !hasTag(item.Tags, v) {
+				item.Tags = append(item.Tags, v)
+			}
+		case "untag":
+			kept := item.Tags[:0]
+			for _, tag := range item.Tags {
+				if !strings.EqualFold(tag, v) {
+					kept = append(kept, tag)
+				}
+			}
+			item.Tags = kept
+		}
+	}
+
+	if strings.TrimSpace(item.Title) == "" || strings.TrimSpace(item.URL) == "" {
+		return item, true, fmt.Errorf("the item was left without a title or url")
+	}
+	if len(item.Tags) == 0 {
+		item.Tags = nil
+	}
+	return item, true, nil
+}
+
+// hasTag reports whether tags has tag, ignoring case
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// expr is a string-valued expression
+type expr interface {
+	eval(item *storage.FeedItem) (string, error)
+}
+
+// literal is a quoted string
+type literal string
+
+func (l literal) eval(*storage.FeedItem) (string, error) {
+	return string(l), nil
+}
+
+// field reads one of the item's fields
+type field string
+
+func (f field) eval(item *storage.FeedItem) (string, error) {
+	switch f {
+	case "title":
+		return item.Title, nil
+	case "url":
+		return item.URL, nil
+	case "source":
+		return item.Source, nil
+	case "timestamp":
+		if item.Timestamp != nil {
+			return *item.Timestamp, nil
+		}
+	}
+	return "", nil
+}
+
+// concat joins its parts
+type concat []expr
+
+func (c concat) eval(item *storage.FeedItem) (string, error) {
+	var b strings.Builder
+	for _, part := range c {
+		v, err := part.eval(item)
+		if err != nil {
+			return "", err
+		}
+		if b.Len()+len(v) > MaxValue {
+			return "", errTooLong
+		}
+		b.WriteString(v)
+	}
+	return b.String(), nil
+}
+
+// call applies a function to its arguments; re is the compiled pattern of
+// sub
+type call struct {
+	fn   string
+	args []expr
+	re   *regexp.Regexp
+}
+
+// functions maps each function to its number of arguments
+var functions = map[string]int{
+	"lower":   1,
+	"upper":   1,
+	"trim":    1,
+	"host":    1,
+	"replace": 3,
+	"sub":     3,
+}
+
+var errTooLong = fmt.Errorf("value longer than %d bytes", MaxValue)
+
+func (c *call) eval(item *storage.FeedItem) (string, error) {
+	args := make([]string, len(c.args))
+	for i, arg := range c.args {
+		v, err := arg.eval(item)
+		if err != nil {
+			return "", err
+		}
+		args[i] = v
+	}
+
+	var v string
+	switch c.fn {
+	case "lower":
+		v = strings.ToLower(args[0])
+	case "upper":
+		v = strings.ToUpper(args[0])
+	case "trim":
+		v = strings.TrimSpace(args[0])
+	case "host":
+		if u, err := url.Parse(args[0]); err == nil {
+			v = u.Hostname()
+		}
+	case "replace":
+		// Checked first, as an empty old inserts new around every rune
+		if len(args[0])+strings.Count(args[0], args[1])*len(args[2]) > MaxValue {
+			return "", errTooLong
+		}
+		v = strings.ReplaceAll(args[0], args[1], args[2])
+	case "sub":
+		// Matches don't overlap, so each $ reference adds at most the
+		// input's length over all of them
+		matches := len(c.re.FindAllStringIndex(args[0], -1))
+		if len(args[0])+matches*len(args[2])+strings.Count(args[2], "$")*len(args[0]) > MaxValue {
+			return "", errTooLong
+		}
+		v = c.re.ReplaceAllString(args[0], args[2])
+	}
+	if len(v) > MaxValue {
+		return "", errTooLong
+	}
+	return v, nil
+}
+
+// cond is a condition
+type cond interface {
+	test(item *storage.FeedItem) (bool, error)
+}
+
+type and struct{ left, right cond }
+
+func (c and) test(item *storage.FeedItem) (bool, error) {
+	ok, err := c.left.test(item)
+	if err != nil || !ok {
+		return false, err
+	}
+	return c.right.test(item)
+}
+
+type or struct{ left, right cond }
+
+func (c or) test(item *storage.FeedItem) (bool, error) {
+	ok, err := c.left.test(item)
+	if err != nil || ok {
+		return ok, err
+	}
+	return c.right.test(item)
+}
+
+type not struct{ c cond }
+
+func (c not) test(item *storage.FeedItem) (bool, error) {
+	ok, err := c.c.test(item)
+	return !ok, err
+}
+
+// hasTagCond tests whether the item has a tag
+type hasTagCond struct{ tag expr }
+
+func (c hasTagCond) test(item *storage.FeedItem) (bool, error) {
+	tag, err := c.tag.eval(item)
+	if err != nil {
+		return false, err
+	}
+	return hasTag(item.Tags, tag), nil
+}
+
+// compare compares two values; re is the compiled pattern of matches
+type compare struct {
+	op          string
+	left, right expr
+	re          *regexp.Regexp
+}
+
+func (c *compare) test(item *storage.FeedItem) (bool, error) {
+	left, err := c.left.eval(item)
+	if err != nil {
+		return false, err
+	}
+	if c.op == "matches" {
+		return c.re.MatchString(left), nil
+	}
+	right, err := c.right.eval(item)
+	if err != nil {
+		return false, err
+	}
+
+	switch c.op {
+	case "==":
+		return left == right, nil
+	case "!=":
+		return left != right, nil
+	case "contains":
+		return strings.Contains(strings.ToLower(left), strings.ToLower(right)), nil
+	case "startswith":
+		return strings.HasPrefix(left, right), nil
+	case "endswith":
+		return strings.HasSuffix(left, right), nil
+	}
+	return false, fmt.Errorf("unknown operator %s", c.op)
+}
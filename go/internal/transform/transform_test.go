@@ -0,0 +1,160 @@
+package transform
+
+import (
+	"strings"
+	"testing"
+
+	"feedpulse/internal/storage"
+)
+
+func testItem() storage.FeedItem {
+	ts := "2024-01-01T00:00:00Z"
+	return storage.FeedItem{
+		ID:        "1",
+		Title:     "  Go 1.23 released | Example Blog ",
+		URL:       "http://example.com/go?utm_source=feed",
+		Source:    "Example",
+		Timestamp: &ts,
+		Tags:      []string{"golang", "misc"},
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		check  func(t *testing.T, item storage.FeedItem)
+	}{
+		{"set title", `set title = trim(replace(title, " | Example Blog", ""))`, func(t *testing.T, item storage.FeedItem) {
+			if item.Title != "Go 1.23 released" {
+				t.Errorf("unexpected title %q", item.Title)
+			}
+		}},
+		{"sub url", `set url = sub(url, "^http://", "https://")`, func(t *testing.T, item storage.FeedItem) {
+			if item.URL != "https://example.com/go?utm_source=feed" {
+				t.Errorf("unexpected url %q", item.URL)
+			}
+		}},
+		{"concat", `set title = upper(source) + ": " + trim(title)`, func(t *testing.T, item storage.FeedItem) {
+			if item.Title != "EXAMPLE: Go 1.23 released | Example Blog" {
+				t.Errorf("unexpected title %q", item.Title)
+			}
+		}},
+		{"tag if", `tag "release" if title matches "(?i)released"`, func(t *testing.T, item storage.FeedItem) {
+			if len(item.Tags) != 3 || item.Tags[2] != "release" {
+				t.Errorf("unexpected tags %v", item.Tags)
+			}
+		}},
+		{"tag skipped", `tag "video" if host(url) == "www.youtube.com"`, func(t *testing.T, item storage.FeedItem) {
+			if len(item.Tags) != 2 {
+				t.Errorf("unexpected tags %v", item.Tags)
+			}
+		}},
+		{"untag", `untag "MISC"`, func(t *testing.T, item storage.FeedItem) {
+			if len(item.Tags) != 1 || item.Tags[0] != "golang" {
+				t.Errorf("unexpected tags %v", item.Tags)
+			}
+		}},
+		{"clear timestamp", `set timestamp = "" if not (tags has "news" or source != "Example")`, func(t *testing.T, item storage.FeedItem) {
+			if item.Timestamp != nil {
+				t.Errorf("expected no timestamp, got %q", *item.Timestamp)
+			}
+		}},
+		{"comments", "# keep everything\n\n  # indented too\n", func(t *testing.T, item storage.FeedItem) {
+			if item.Title != testItem().Title {
+				t.Errorf("unexpected title %q", item.Title)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, err := Compile(tt.script)
+			if err != nil {
+				t.Fatalf("Compile failed: %v", err)
+			}
+			item := testItem()
+			out, keep, err := script.Apply(item)
+			if err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			if !keep {
+				t.Fatal("expected the item to be kept")
+			}
+			tt.check(t, out)
+			if item.Tags[1] != "misc" || *item.Timestamp != "2024-01-01T00:00:00Z" {
+				t.Error("Apply changed its argument")
+			}
+		})
+	}
+}
+
+func TestApply_Drop(t *testing.T) {
+	script, err := Compile("drop if title contains \"example blog\" and url startswith \"http://\"\nset title = \"kept\"")
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	out, keep, err := script.Apply(testItem())
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if keep {
+		t.Errorf("expected the item to be dropped, got %+v", out)
+	}
+}
+
+func TestApply_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"empty title", `set title = ""`, "without a title"},
+		{"too long", `set title = replace(title, "", title)`, "longer than"},
+		{"too long sub", `set title = sub(title, "", "0123456789")`, "longer than"},
+		{"too long concat", `set title = title + title + title + title + title + title + title + title + title`, "longer than"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, err := Compile(tt.script)
+			if err != nil {
+				t.Fatalf("Compile failed: %v", err)
+			}
+			item := testItem()
+			item.Title = strings.Repeat("a", 8000)
+			_, _, err = script.Apply(item)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"unknown statement", `delete`, "line 1: unknown statement 'delete'"},
+		{"set source", `set source = "x"`, "set needs title, url or timestamp"},
+		{"missing value", "# ok\ntag", "line 2: expected a value, got end of line"},
+		{"unknown function", `set title = reverse(title)`, "expected a value, got 'reverse'"},
+		{"bad regexp", `drop if url matches "("`, "invalid regular expression"},
+		{"regexp not literal", `drop if url matches title`, "expected a quoted regular expression"},
+		{"missing comparison", `drop if title`, "expected a comparison"},
+		{"unterminated string", `tag "video`, "unterminated string"},
+		{"trailing tokens", `drop drop`, "unexpected 'drop'"},
+		{"unbalanced parens", `drop if (title == "a"`, "expected ')'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(tt.script)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
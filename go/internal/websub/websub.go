@@ -0,0 +1,286 @@
+// Package websub implements the subscriber side of WebSub (formerly
+// PubSubHubbub): subscribing to hubs and receiving their notifications.
+package websub
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Subscription modes sent to hubs
+const (
+	ModeSubscribe   = "subscribe"
+	ModeUnsubscribe = "unsubscribe"
+)
+
+// maxNotificationSize bounds the body read from a notification
+const maxNotificationSize = 10 * 1024 * 1024
+
+// Subscription is one feed's subscription at a hub. Callback must reach
+// the Handler serving it; Secret signs the hub's notifications.
+type Subscription struct {
+	Feed       string
+	Hub        string
+	Topic      string
+	Callback   string
+	Secret     string
+	LeaseUntil time.Time
+}
+
+// NewSecret returns a random secret for a new subscription
+func NewSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Request sends a subscribe or unsubscribe request for sub to its hub,
+// asking for a lease of lease (0 leaves it to the hub). Hubs verify the
+// intent asynchronously by calling the Handler, so success here only
+// means the hub accepted the request.
+func Request(ctx context.Context, client *http.Client, sub Subscription, mode string, lease time.Duration) error {
+	form := url.Values{}
+	form.Set("hub.mode", mode)
+	form.Set("hub.topic", sub.Topic)
+	form.Set("hub.callback", sub.Callback)
+	if sub.Secret != "" && mode == ModeSubscribe {
+		form.Set("hub.secret", sub.Secret)
+	}
+	if lease > 0 {
+		form.Set("hub.lease_seconds", strconv.Itoa(int(lease.Seconds())))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Hub, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "feedpulse/1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("hub request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("hub rejected %s: %s %s", mode, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// Handler serves the callback URLs of a set of subscriptions. Each
+// subscription's callback is the handler's prefix followed by the
+// path-escaped feed name. It answers hubs' intent verification for
+// requests it was told are pending and reports authentic notifications on
+// Notifications.
+type Handler struct {
+	prefix     string
+	onVerified func(Subscription)
+
+	mu            sync.Mutex
+	subscriptions map[string]Subscription
+	pending       map[string]string
+	queued        map[string]bool
+	notifications chan string
+}
+
+// NewHandler creates a handler for callbacks under prefix (a URL path).
+// onVerified, if set, is called when a hub confirms a subscription, with
+// its lease filled in.
+func NewHandler(prefix string, onVerified func(Subscription)) *Handler {
+	return &Handler{
+		prefix:        strings.TrimSuffix(prefix, "/") + "/",
+		onVerified:    onVerified,
+		subscriptions: make(map[string]Subscription),
+		pending:       make(map[string]string),
+		queued:        make(map[string]bool),
+		notifications: make(chan string, 64),
+	}
+}
+
+// CallbackURL returns the callback for feed under base, the public URL
+// the handler's prefix is reachable at
+func CallbackURL(base, feed string) string {
+	return strings.TrimSuffix(base, "/") + "/" + url.PathEscape(feed)
+}
+
+// Expect registers sub, so the handler confirms its verification and
+// accepts notifications for it
+func (h *Handler) Expect(sub Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subscriptions[sub.Feed] = sub
+}
+
+// Pending registers sub and records that a mode request for it is about
+// to be sent to its hub, so the hub's verification of that request is
+// confirmed. Verifications the handler wasn't told to expect are refused,
+// so whoever can reach the callback can't subscribe or unsubscribe it.
+func (h *Handler) Pending(sub Subscription, mode string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subscriptions[sub.Feed] = sub
+	h.pending[sub.Feed] = mode
+}
+
+// Notifications delivers the names of feeds a hub reported as updated.
+// Repeated notifications for a feed are coalesced until Done is called
+// for it.
+func (h *Handler) Notifications() <-chan string {
+	return h.notifications
+}
+
+// Done marks feed's notification as handled, so the next one is
+// delivered again
+func (h *Handler) Done(feed string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.queued, feed)
+}
+
+// ServeHTTP handles verification requests (GET) and notifications (POST)
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	feed, err := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), h.prefix))
+	if err != nil || !strings.HasPrefix(r.URL.Path, h.prefix) {
+		http.NotFound(w, r)
+		return
+	}
+
+	h.mu.Lock()
+	sub, ok := h.subscriptions[feed]
+	h.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.verify(w, r, sub)
+	case http.MethodPost:
+		h.notify(w, r, sub)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// verify answers a hub's verification of intent by echoing its challenge
+// if the request matches the subscription and a pending request of the
+// same mode
+func (h *Handler) verify(w http.ResponseWriter, r *http.Request, sub Subscription) {
+	q := r.URL.Query()
+	mode := q.Get("hub.mode")
+
+	if mode == "denied" {
+		// The hub refused the subscription; nothing to confirm
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if (mode != ModeSubscribe && mode != ModeUnsubscribe) || q.Get("hub.topic") != sub.Topic || q.Get("hub.challenge") == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	h.mu.Lock()
+	if h.pending[sub.Feed] != mode {
+		h.mu.Unlock()
+		http.NotFound(w, r)
+		return
+	}
+	delete(h.pending, sub.Feed)
+	if mode == ModeUnsubscribe {
+		delete(h.subscriptions, sub.Feed)
+	}
+	h.mu.Unlock()
+
+	if mode == ModeSubscribe {
+		if secs, err := strconv.Atoi(q.Get("hub.lease_seconds")); err == nil && secs > 0 {
+			sub.LeaseUntil = time.Now().Add(time.Duration(secs) * time.Second)
+		}
+		h.Expect(sub)
+		if h.onVerified != nil {
+			h.onVerified(sub)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, q.Get("hub.challenge"))
+}
+
+// notify accepts a content distribution request. Hubs expect a 2xx even
+// for notifications that fail authentication, so those are dropped
+// silently.
+func (h *Handler) notify(w http.ResponseWriter, r *http.Request, sub Subscription) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxNotificationSize))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+
+	if sub.Secret != "" && !validSignature(sub.Secret, r.Header.Get("X-Hub-Signature"), body) {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.queued[sub.Feed] {
+		return
+	}
+	select {
+	case h.notifications <- sub.Feed:
+		h.queued[sub.Feed] = true
+	default:
+	}
+}
+
+// validSignature checks an X-Hub-Signature header ("method=hexdigest")
+// against the HMAC of body under secret
+func validSignature(secret, header string, body []byte) bool {
+	method, digest, ok := strings.Cut(header, "=")
+	if !ok {
+		return false
+	}
+
+	var newHash func() hash.Hash
+	switch method {
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	case "sha384":
+		newHash = sha512.New384
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return false
+	}
+
+	want, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), want)
+}
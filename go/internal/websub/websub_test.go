@@ -0,0 +1,176 @@
+package websub
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestSubscription(server *httptest.Server) Subscription {
+	return Subscription{
+		Feed:     "Go Blog",
+		Hub:      server.URL + "/hub",
+		Topic:    "https://example.com/feed",
+		Callback: CallbackURL(server.URL+"/websub", "Go Blog"),
+		Secret:   "s3cret",
+	}
+}
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestCallbackURL(t *testing.T) {
+	got := CallbackURL("https://feeds.example.com/websub/", "Go Blog/News")
+	if got != "https://feeds.example.com/websub/Go%20Blog%2FNews" {
+		t.Errorf("unexpected callback URL: %s", got)
+	}
+}
+
+func TestRequest(t *testing.T) {
+	var form url.Values
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.PostForm
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer hub.Close()
+
+	sub := newTestSubscription(hub)
+	if err := Request(context.Background(), hub.Client(), sub, ModeSubscribe, time.Hour); err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if form.Get("hub.mode") != "subscribe" || form.Get("hub.topic") != sub.Topic || form.Get("hub.callback") != sub.Callback {
+		t.Errorf("unexpected subscription form: %v", form)
+	}
+	if form.Get("hub.secret") != "s3cret" || form.Get("hub.lease_seconds") != "3600" {
+		t.Errorf("expected secret and lease, got %v", form)
+	}
+}
+
+func TestRequest_Rejected(t *testing.T) {
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unknown topic", http.StatusBadRequest)
+	}))
+	defer hub.Close()
+
+	err := Request(context.Background(), hub.Client(), newTestSubscription(hub), ModeSubscribe, 0)
+	if err == nil || !strings.Contains(err.Error(), "unknown topic") {
+		t.Errorf("expected the hub's rejection, got %v", err)
+	}
+}
+
+func TestHandler_Verification(t *testing.T) {
+	var verified Subscription
+	h := NewHandler("/websub", func(sub Subscription) { verified = sub })
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	sub := newTestSubscription(server)
+	h.Pending(sub, ModeSubscribe)
+
+	verify := func(mode, topic string) (int, string) {
+		q := url.Values{"hub.mode": {mode}, "hub.topic": {topic}, "hub.challenge": {"abc123"}, "hub.lease_seconds": {"600"}}
+		resp, err := http.Get(sub.Callback + "?" + q.Encode())
+		if err != nil {
+			t.Fatalf("GET failed: %v", err)
+		}
+		defer resp.Body.Close()
+		body := make([]byte, 64)
+		n, _ := resp.Body.Read(body)
+		return resp.StatusCode, string(body[:n])
+	}
+
+	if status, _ := verify(ModeSubscribe, "https://example.com/other"); status != http.StatusNotFound {
+		t.Errorf("expected 404 for a different topic, got %d", status)
+	}
+	if status, _ := verify(ModeUnsubscribe, sub.Topic); status != http.StatusNotFound {
+		t.Errorf("expected 404 for an unsubscribe that wasn't requested, got %d", status)
+	}
+
+	if status, body := verify(ModeSubscribe, sub.Topic); status != http.StatusOK || body != "abc123" {
+		t.Errorf("expected challenge echoed, got %d %q", status, body)
+	}
+	if verified.Feed != "Go Blog" || time.Until(verified.LeaseUntil) < 9*time.Minute {
+		t.Errorf("expected verified subscription with lease, got %+v", verified)
+	}
+
+	// Each request is verified once
+	if status, _ := verify(ModeSubscribe, sub.Topic); status != http.StatusNotFound {
+		t.Errorf("expected 404 for a repeated verification, got %d", status)
+	}
+
+	h.Pending(sub, ModeUnsubscribe)
+	if status, _ := verify(ModeUnsubscribe, sub.Topic); status != http.StatusOK {
+		t.Errorf("expected a requested unsubscribe confirmed, got %d", status)
+	}
+	if status, _ := verify(ModeSubscribe, sub.Topic); status != http.StatusNotFound {
+		t.Errorf("expected 404 once unsubscribed, got %d", status)
+	}
+}
+
+func TestHandler_Notifications(t *testing.T) {
+	h := NewHandler("/websub", nil)
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	sub := newTestSubscription(server)
+	h.Expect(sub)
+
+	post := func(callback, signature string) int {
+		body := []byte(`{"items": []}`)
+		req, _ := http.NewRequest(http.MethodPost, callback, strings.NewReader(string(body)))
+		if signature == "" {
+			signature = sign("s3cret", body)
+		}
+		req.Header.Set("X-Hub-Signature", signature)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// A forged notification is acknowledged but ignored
+	if status := post(sub.Callback, "sha256=00"); status != http.StatusAccepted {
+		t.Errorf("expected 202 for a bad signature, got %d", status)
+	}
+	select {
+	case feed := <-h.Notifications():
+		t.Fatalf("unexpected notification for %s", feed)
+	default:
+	}
+
+	// Authentic notifications are delivered once until handled
+	post(sub.Callback, "")
+	post(sub.Callback, "")
+	if feed := <-h.Notifications(); feed != "Go Blog" {
+		t.Errorf("expected notification for Go Blog, got %s", feed)
+	}
+	select {
+	case <-h.Notifications():
+		t.Error("expected repeated notifications to be coalesced")
+	default:
+	}
+
+	h.Done("Go Blog")
+	post(sub.Callback, "")
+	if feed := <-h.Notifications(); feed != "Go Blog" {
+		t.Errorf("expected a new notification after Done, got %s", feed)
+	}
+
+	if status := post(CallbackURL(server.URL+"/websub", "Unknown"), ""); status != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown feed, got %d", status)
+	}
+}